@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/rancher/machine/commands"
 	"github.com/rancher/machine/commands/mcndirs"
@@ -25,6 +26,7 @@ import (
 	"github.com/rancher/machine/drivers/vmwarefusion"
 	"github.com/rancher/machine/drivers/vmwarevcloudair"
 	"github.com/rancher/machine/drivers/vmwarevsphere"
+	"github.com/rancher/machine/drivers/vultr"
 	"github.com/rancher/machine/libmachine/drivers/plugin"
 	"github.com/rancher/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/rancher/machine/libmachine/log"
@@ -114,6 +116,11 @@ func main() {
 			Name:  "debug, D",
 			Usage: "Enable debug mode",
 		},
+		cli.BoolFlag{
+			EnvVar: "MACHINE_QUIET",
+			Name:   "quiet, silent",
+			Usage:  "Only print warnings, errors and the result of commands",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_STORAGE_PATH",
 			Name:   "storage-path, s",
@@ -150,11 +157,45 @@ func main() {
 			Usage:  "Token to use for requests to the Github API",
 			Value:  "",
 		},
+		cli.DurationFlag{
+			EnvVar: "MACHINE_LOCK_TIMEOUT",
+			Name:   "lock-timeout",
+			Usage:  "How long to wait for a machine locked by another process, e.g. 30s",
+			Value:  time.Minute,
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_ENV_PREFIX",
+			Name:   "env-prefix",
+			Usage:  "Prefix of environment variables read before the ones of driver flags, e.g. MYAPP_ reads MYAPP_AMAZONEC2_REGION, then AMAZONEC2_REGION. Flags on the command line take precedence over both",
+			Value:  "",
+		},
+		cli.BoolFlag{
+			Name:  "exit-code-map",
+			Usage: "Print the exit codes of the commands and the failures they are used for as JSON, and exit",
+		},
 		cli.BoolFlag{
 			EnvVar: "MACHINE_NATIVE_SSH",
 			Name:   "native-ssh",
 			Usage:  "Use the native (Go-based) SSH implementation.",
 		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_SSH_CIPHERS",
+			Name:   "ssh-ciphers",
+			Usage:  "Comma separated SSH ciphers to offer, e.g. aes256-gcm@openssh.com,aes256-ctr for FIPS hosts",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_SSH_KEX",
+			Name:   "ssh-kex",
+			Usage:  "Comma separated SSH key exchange algorithms to offer",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_SSH_MACS",
+			Name:   "ssh-macs",
+			Usage:  "Comma separated SSH MAC algorithms to offer",
+			Value:  "",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_BUGSNAG_API_TOKEN",
 			Name:   "bugsnag-api-token",
@@ -181,6 +222,16 @@ func main() {
 		},
 	}
 
+	app.Before = func(c *cli.Context) error {
+		if c.GlobalBool("exit-code-map") {
+			if err := commands.PrintExitCodeMap(os.Stdout); err != nil {
+				return err
+			}
+			os.Exit(0)
+		}
+		return nil
+	}
+
 	if err := app.Run(os.Args); err != nil {
 		log.Error(err)
 		os.Exit(1)
@@ -219,6 +270,8 @@ func runDriver(driverName string) {
 		plugin.RegisterDriver(vmwarevcloudair.NewDriver("", ""))
 	case "vmwarevsphere":
 		plugin.RegisterDriver(vmwarevsphere.NewDriver("", ""))
+	case "vultr":
+		plugin.RegisterDriver(vultr.NewDriver("", ""))
 	case "pod":
 		plugin.RegisterDriver(pod.NewDriver("", ""))
 	case "noop":
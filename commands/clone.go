@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rancher/machine/commands/mcndirs"
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/mcnflag"
+)
+
+var errCloneArgs = errors.New("Error: Expected the name of the machine to clone and the name of the new machine as arguments")
+
+// cloneUniqueFlags are the driver flags identifying a single instance. They
+// are not copied from the cloned machine, but can be given again.
+var cloneUniqueFlags = []string{
+	"generic-ip-address",
+	"google-address",
+	"hyperv-static-macaddress",
+	"softlayer-hostname",
+	"vmwarevcloudair-publicip",
+}
+
+// withCloneDriverFlags adds the flags of the driver of the cloned machine to
+// the clone command, so that they can override the cloned values.
+func withCloneDriverFlags(handler cmdHandler) cmdHandler {
+	return func(c CommandLine, api libmachine.API) error {
+		// Options come before the arguments, the cloned machine is the
+		// second to last argument.
+		args := c.Args()
+		if len(args) < 2 {
+			return updateAndRunCommand(c, "clone", nil, handler)
+		}
+
+		source := args[len(args)-2]
+		h, err := api.Load(source)
+		if err != nil {
+			return fmt.Errorf("error loading host %s: %w", source, err)
+		}
+
+		return runWithDriverFlags(c, api, "clone", h.DriverName, handler)
+	}
+}
+
+func cmdClone(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 2 {
+		return errCloneArgs
+	}
+
+	source, name := c.Args()[0], c.Args()[1]
+	if !host.ValidateHostName(name) {
+		return fmt.Errorf("error creating machine: [%s]", mcnerror.ErrInvalidHostname)
+	}
+
+	release, err := lockHosts(api, "clone", []string{source, name})
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	src, err := api.Load(source)
+	if err != nil {
+		return err
+	}
+
+	if src.HostOptions == nil || src.HostOptions.DriverOptions == nil {
+		return fmt.Errorf("machine %s was created without storing its driver options and can't be cloned", source)
+	}
+
+	exists, err := api.Exists(name)
+	if err != nil {
+		return fmt.Errorf("error checking if host exists: %s", err)
+	}
+	if exists {
+		return mcnerror.ErrHostAlreadyExists{
+			Name: name,
+		}
+	}
+
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: name,
+		StorePath:   c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return fmt.Errorf("error attempting to marshal bare driver data: %s", err)
+	}
+
+	h, err := api.NewHost(src.DriverName, rawDriver)
+	if err != nil {
+		return fmt.Errorf("error getting new host: %s", err)
+	}
+
+	h.HostOptions, err = cloneHostOptions(src.HostOptions, name)
+	if err != nil {
+		return fmt.Errorf("error copying the options of %s: %s", source, err)
+	}
+
+	driverOpts := cloneDriverOpts(src.HostOptions.DriverOptions, h.Driver.GetCreateFlags(), c)
+	h.HostOptions.DriverOptions = copyDriverOptions(driverOpts.Values)
+
+	customInstallScript := h.HostOptions.CustomInstallScript
+	if userdataFlag := drivers.DriverUserdataFlag(h.Driver); customInstallScript != "" && userdataFlag != "" {
+		osFlag := drivers.DriverOSFlag(h.Driver)
+		if err := updateUserdataFile(driverOpts, name, "", userdataFlag, osFlag, customInstallScript); err != nil {
+			return fmt.Errorf("could not alter cloud-init file: %v", err)
+		}
+	}
+
+	log.Infof("Cloning %s to %s", source, name)
+
+	if err := createHost(api, h, driverOpts, false, ""); err != nil {
+		return err
+	}
+
+	if customInstallScript == "" {
+		log.Infof("to see how to connect your Docker Client to the Docker Engine running on this virtual machine, run: %s env %s", os.Args[0], name)
+	}
+
+	return nil
+}
+
+// cloneHostOptions copies the options of a machine for a new machine with the
+// given name. The server certs get new paths so that fresh ones are generated,
+// and the options tied to a single instance are cleared.
+func cloneHostOptions(options *host.Options, name string) (*host.Options, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := &host.Options{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+
+	if clone.EngineOptions != nil && clone.EngineOptions.Hostname != "" {
+		if clone.AuthOptions != nil {
+			sans := []string{}
+			for _, san := range clone.AuthOptions.ServerCertSANs {
+				if san != clone.EngineOptions.Hostname {
+					sans = append(sans, san)
+				}
+			}
+			clone.AuthOptions.ServerCertSANs = sans
+		}
+		clone.EngineOptions.Hostname = ""
+	}
+
+	if clone.AuthOptions != nil {
+		clone.AuthOptions.ServerCertPath = filepath.Join(mcndirs.GetMachineDir(), name, "server.pem")
+		clone.AuthOptions.ServerKeyPath = filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem")
+		clone.AuthOptions.StorePath = filepath.Join(mcndirs.GetMachineDir(), name)
+	}
+
+	clone.HostnameOverride = ""
+	clone.ExpectIP = ""
+	clone.DriverOptions = nil
+
+	return clone, nil
+}
+
+// cloneDriverOpts returns the driver flag values of a new machine from the
+// stored values of the cloned one and the driver flags set on the command
+// line.
+func cloneDriverOpts(stored map[string]interface{}, mcnFlags []mcnflag.Flag, c CommandLine) *rpcdriver.RPCFlags {
+	driverOpts := &rpcdriver.RPCFlags{
+		Values: make(map[string]interface{}),
+	}
+
+	unique := map[string]bool{}
+	for _, name := range cloneUniqueFlags {
+		unique[name] = true
+	}
+
+	for _, f := range mcnFlags {
+		name := f.String()
+		driverOpts.Values[name] = f.Default()
+
+		if c.IsSet(name) {
+			driverOpts.Values[name] = flagValue(c, f)
+		} else if value, ok := stored[name]; ok && !unique[name] {
+			driverOpts.Values[name] = storedFlagValue(f, value)
+		}
+	}
+
+	return driverOpts
+}
+
+// flagValue returns the value of a driver flag on the command line.
+func flagValue(c CommandLine, f mcnflag.Flag) interface{} {
+	name := f.String()
+
+	switch f.(type) {
+	case *mcnflag.IntFlag, mcnflag.IntFlag:
+		return c.Int(name)
+	case *mcnflag.BoolFlag, mcnflag.BoolFlag:
+		return c.Bool(name)
+	case *mcnflag.StringSliceFlag, mcnflag.StringSliceFlag:
+		return c.StringSlice(name)
+	default:
+		return c.String(name)
+	}
+}
+
+// storedFlagValue restores the type of a driver flag value read back from
+// the JSON config of a machine.
+func storedFlagValue(f mcnflag.Flag, value interface{}) interface{} {
+	switch f.(type) {
+	case *mcnflag.IntFlag, mcnflag.IntFlag:
+		if number, ok := value.(float64); ok {
+			return int(number)
+		}
+	case *mcnflag.StringSliceFlag, mcnflag.StringSliceFlag:
+		if items, ok := value.([]interface{}); ok {
+			values := []string{}
+			for _, item := range items {
+				values = append(values, fmt.Sprint(item))
+			}
+			return values
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneDriverOpts(t *testing.T) {
+	mcnFlags := []mcnflag.Flag{
+		mcnflag.StringFlag{Name: "google-zone", Value: "us-central1-a"},
+		mcnflag.StringFlag{Name: "google-address"},
+		mcnflag.IntFlag{Name: "google-disk-size", Value: 10},
+		mcnflag.StringSliceFlag{Name: "google-tags"},
+		mcnflag.BoolFlag{Name: "google-preemptible"},
+	}
+
+	// The stored values are read back from the JSON config of the machine.
+	var stored map[string]interface{}
+	data, _ := json.Marshal(map[string]interface{}{
+		"google-zone":        "europe-west1-b",
+		"google-address":     "1.2.3.4",
+		"google-disk-size":   50,
+		"google-tags":        []string{"a", "b"},
+		"google-preemptible": true,
+	})
+	assert.NoError(t, json.Unmarshal(data, &stored))
+
+	c := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"google-zone": "asia-east1-a",
+			},
+		},
+	}
+
+	driverOpts := cloneDriverOpts(stored, mcnFlags, c)
+
+	assert.Equal(t, "asia-east1-a", driverOpts.String("google-zone"))
+	assert.Equal(t, "", driverOpts.String("google-address"))
+	assert.Equal(t, 50, driverOpts.Int("google-disk-size"))
+	assert.Equal(t, []string{"a", "b"}, driverOpts.StringSlice("google-tags"))
+	assert.True(t, driverOpts.Bool("google-preemptible"))
+}
+
+func TestCopyDriverOptions(t *testing.T) {
+	values := map[string]interface{}{
+		"amazonec2-region":         "eu-west-1",
+		"amazonec2-secret-key":     "s3cr3t",
+		"amazonec2-access-key":     "AKIA",
+		"amazonec2-session-token":  "T0K3N",
+		"vsphere-password":         "hunter2",
+		"google-auth-encoded-json": "{}",
+		"openstack-auth-url":       "https://keystone.example.com/v3",
+		"amazonec2-ssh-keypath":    "/keys/id_rsa",
+	}
+
+	stored := copyDriverOptions(values)
+
+	assert.Equal(t, map[string]interface{}{
+		"amazonec2-region":      "eu-west-1",
+		"openstack-auth-url":    "https://keystone.example.com/v3",
+		"amazonec2-ssh-keypath": "/keys/id_rsa",
+	}, stored)
+	assert.Len(t, values, 8)
+}
+
+func TestCloneHostOptions(t *testing.T) {
+	options := &host.Options{
+		HostnameOverride: "fixed",
+		ExpectIP:         "10.0.0.1",
+		DriverOptions:    map[string]interface{}{"google-zone": "europe-west1-b"},
+		AuthOptions: &auth.Options{
+			CaCertPath:     "/certs/ca.pem",
+			ServerCertPath: "/machines/source/server.pem",
+			ServerCertSANs: []string{"docker.example.com", "web-1.example.com"},
+		},
+		EngineOptions: &engine.Options{
+			Labels:   []string{"env=prod"},
+			Hostname: "web-1.example.com",
+		},
+	}
+
+	clone, err := cloneHostOptions(options, "copy")
+
+	assert.NoError(t, err)
+	assert.Empty(t, clone.HostnameOverride)
+	assert.Empty(t, clone.ExpectIP)
+	assert.Nil(t, clone.DriverOptions)
+	assert.Equal(t, "/certs/ca.pem", clone.AuthOptions.CaCertPath)
+	assert.Contains(t, clone.AuthOptions.ServerCertPath, "copy")
+	assert.Equal(t, []string{"env=prod"}, clone.EngineOptions.Labels)
+	assert.Empty(t, clone.EngineOptions.Hostname)
+	assert.Equal(t, []string{"docker.example.com"}, clone.AuthOptions.ServerCertSANs)
+
+	clone.EngineOptions.Labels[0] = "env=dev"
+	assert.Equal(t, "env=prod", options.EngineOptions.Labels[0])
+}
+
+func TestCmdCloneRequiresTwoArgs(t *testing.T) {
+	c := &commandstest.FakeCommandLine{CliArgs: []string{"source"}}
+
+	assert.Equal(t, errCloneArgs, cmdClone(c, nil))
+}
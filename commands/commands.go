@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/moby/term"
 	"github.com/rancher/machine/commands/mcndirs"
 	"github.com/rancher/machine/libmachine"
 	"github.com/rancher/machine/libmachine/crashreport"
@@ -42,6 +45,10 @@ var (
 	updateConfigBoolFlag = cli.BoolFlag{
 		Name: "update-config",
 	}
+	sshAgentForwardFlag = cli.BoolFlag{
+		Name:  "ssh-agent-forward",
+		Usage: "Forward the local SSH agent to the SSH sessions of the provisioning, e.g. to clone private repositories. Anyone with root access to the machine can use the agent to authenticate as you while they are open",
+	}
 )
 
 // cmdHandler is a function that handles a command.
@@ -69,6 +76,8 @@ type CommandLine interface {
 
 	GlobalString(name string) string
 
+	GlobalBool(name string) bool
+
 	FlagNames() (names []string)
 
 	Generic(name string) interface{}
@@ -94,22 +103,33 @@ func (c *contextCommandLine) Application() *cli.App {
 // arg, or the default host name if no host is specified.
 func targetHost(c CommandLine, api libmachine.API) (string, error) {
 	if len(c.Args()) == 0 {
-		defaultExists, err := api.Exists(defaultMachineName)
-		if err != nil {
-			return "", fmt.Errorf("Error checking if host %q exists: %s", defaultMachineName, err)
-		}
+		return defaultHost(api)
+	}
 
-		if defaultExists {
-			return defaultMachineName, nil
-		}
+	return c.Args()[0], nil
+}
 
-		return "", ErrNoDefault
+// defaultHost returns the name of the 'default' machine if it exists.
+func defaultHost(api libmachine.API) (string, error) {
+	defaultExists, err := api.Exists(defaultMachineName)
+	if err != nil {
+		return "", fmt.Errorf("Error checking if host %q exists: %s", defaultMachineName, err)
 	}
 
-	return c.Args()[0], nil
+	if defaultExists {
+		return defaultMachineName, nil
+	}
+
+	return "", ErrNoDefault
 }
 
 func runAction(actionName string, c CommandLine, api libmachine.API) error {
+	return runActionPreparing(actionName, c, api, func(*host.Host) {})
+}
+
+// runActionPreparing works like runAction, calling prepare on each machine
+// before the action runs on it.
+func runActionPreparing(actionName string, c CommandLine, api libmachine.API, prepare func(h *host.Host)) error {
 	var (
 		hostsToLoad []string
 	)
@@ -142,16 +162,24 @@ func runAction(actionName string, c CommandLine, api libmachine.API) error {
 		return ErrHostLoad
 	}
 
-	if errs := runActionForeachMachine(actionName, hosts); len(errs) > 0 {
-		return consolidateErrs(errs)
+	for _, h := range hosts {
+		prepare(h)
 	}
 
+	errs := runActionForeachMachine(actionName, hosts)
+
+	// The hosts are saved even when the action failed, so that what it
+	// changed, e.g. the provisioning status, is kept.
 	for _, h := range hosts {
 		if err := api.Save(h); err != nil {
 			return fmt.Errorf("Error saving host to store: %s", err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return fleetError(len(errs), len(hosts), consolidateErrs(errs))
+	}
+
 	return nil
 }
 
@@ -164,6 +192,7 @@ func runCommand(command func(commandLine CommandLine, api libmachine.API) error)
 			api.SSHClientType = ssh.Native
 		}
 		api.GithubAPIToken = context.GlobalString("github-api-token")
+		log.SetQuiet(context.GlobalBool("quiet"))
 
 		// TODO (nathanleclaire): These should ultimately be accessed
 		// through the libmachine client by the rest of the code and
@@ -171,8 +200,20 @@ func runCommand(command func(commandLine CommandLine, api libmachine.API) error)
 		// they are also being set the way that they originally were
 		// set to preserve backwards compatibility.
 		mcndirs.BaseDir = context.GlobalString("storage-path")
+		if lockTimeout := context.GlobalDuration("lock-timeout"); lockTimeout > 0 {
+			hostLockTimeout = lockTimeout
+		}
 		mcnutils.GithubAPIToken = api.GithubAPIToken
 		ssh.SetDefaultClient(api.SSHClientType)
+		if err := ssh.SetAlgorithms(ssh.Algorithms{
+			Ciphers:      ssh.ParseAlgorithmList(context.GlobalString("ssh-ciphers")),
+			KeyExchanges: ssh.ParseAlgorithmList(context.GlobalString("ssh-kex")),
+			MACs:         ssh.ParseAlgorithmList(context.GlobalString("ssh-macs")),
+		}); err != nil {
+			log.Error(err)
+			osExit(exitInvalidArguments)
+			return
+		}
 
 		secretName, secretNamespace := context.GlobalString("secret-name"), context.GlobalString("secret-namespace")
 		if secretName != "" {
@@ -192,17 +233,9 @@ func runCommand(command func(commandLine CommandLine, api libmachine.API) error)
 			if crashErr, ok := err.(crashreport.CrashError); ok {
 				crashReporter := crashreport.NewCrashReporter(mcndirs.GetBaseDir(), context.GlobalString("bugsnag-api-token"))
 				crashReporter.Send(crashErr)
-
-				if _, ok := crashErr.Cause.(mcnerror.ErrDuringPreCreate); ok {
-					osExit(3)
-					return
-				}
-			} else if _, ok := err.(notFoundError); ok {
-				osExit(4)
-				return
 			}
 
-			osExit(1)
+			osExit(exitCode(err))
 			return
 		}
 	}
@@ -234,6 +267,13 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:            "clone",
+		Usage:           "Create a new machine with the configuration of an existing one",
+		Description:     "Arguments are the name of the machine to clone and the name of the new machine. Driver flags override the cloned values. The driver flags holding credentials aren't stored and must be set again, with their flags or environment variables.",
+		Action:          runCommand(withCloneDriverFlags(cmdClone)),
+		SkipFlagParsing: true,
+	},
 	{
 		Name:        "config",
 		Usage:       "Print the connection config for machine",
@@ -244,6 +284,15 @@ var Commands = []cli.Command{
 				Name:  "swarm",
 				Usage: "Display the Swarm config instead of the Docker daemon",
 			},
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Format the output using the given go template.",
+				Value: "",
+			},
+			cli.StringFlag{
+				Name:  "template-file",
+				Usage: "Format the output using the go template of a file, like --format",
+			},
 		},
 	},
 	{
@@ -251,12 +300,18 @@ var Commands = []cli.Command{
 		Name:        "create",
 		Usage:       "Create a machine",
 		Description: fmt.Sprintf("Run '%s create --driver name --help' to include the create flags for that driver in the help text.", os.Args[0]),
-		Action: runCommand(withDriverFlags("create", false, &cli.GenericFlag{
+		Action: runCommand(withEnvFile(withProfile(withDriverFlags("create", false, &cli.GenericFlag{
 			Name:   "driver, d",
 			EnvVar: "MACHINE_DRIVER",
-		}, cmdCreate)),
+		}, cmdCreate)))),
 		SkipFlagParsing: true,
 	},
+	{
+		Name:        "diff",
+		Usage:       "Compare the stored config of a machine with its instance",
+		Description: "Argument is a machine name. Prints the instance type, IP, tags and open ports changed outside of machine.",
+		Action:      runCommand(cmdDiff),
+	},
 	{
 		Name:        "env",
 		Usage:       "Display the commands to set up the environment for the Docker client",
@@ -269,7 +324,7 @@ var Commands = []cli.Command{
 			},
 			cli.StringFlag{
 				Name:  "shell",
-				Usage: "Force environment to be configured for a specified shell: [fish, cmd, powershell, tcsh, emacs], default is auto-detect",
+				Usage: "Force environment to be configured for a specified shell: [fish, cmd, powershell, tcsh, emacs], or printed as a JSON object with json, default is auto-detect",
 			},
 			cli.BoolFlag{
 				Name:  "unset, u",
@@ -279,6 +334,18 @@ var Commands = []cli.Command{
 				Name:  "no-proxy",
 				Usage: "Add machine IP to NO_PROXY environment variable",
 			},
+			cli.BoolFlag{
+				Name:  "cache",
+				Usage: "Also write the environment to a file that the shell can source, in the machine directory by default: docker.env for the POSIX shells, docker.fish, docker.tcsh, docker.ps1, docker.cmd or docker.el",
+			},
+			cli.StringFlag{
+				Name:  "cache-file",
+				Usage: "Path of the file written by --cache, implies --cache",
+			},
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Display the environment of all the running machines, each applied when MACHINE_ENV_SELECT is set to its name, or keyed by machine name with --shell json",
+			},
 		},
 	},
 	{
@@ -289,9 +356,48 @@ var Commands = []cli.Command{
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "format, f",
-				Usage: "Format the output using the given go template.",
+				Usage: "Format the output using the given go template. The driver flag values are in .DriverConfig, e.g. {{index .DriverConfig \"amazonec2-region\"}}",
 				Value: "",
 			},
+			cli.StringFlag{
+				Name:  "cert",
+				Usage: "Print the PEM content of a cert of the machine: ca, cert, key or server-cert",
+			},
+			cli.BoolFlag{
+				Name:  "show-private-key",
+				Usage: "Allow --cert key to print the client private key",
+			},
+			cli.BoolFlag{
+				Name:  "verify-roundtrip",
+				Usage: "Check that the stored driver config is serialized back the same by the driver, listing the fields lost or changed",
+			},
+		},
+	},
+	{
+		Name:        "inventory",
+		Usage:       "Print the machines as an inventory for other tools",
+		Description: "The formats are json, ansible for an ansible inventory script and prometheus-sd for the Prometheus file-based service discovery.",
+		Action:      runCommand(cmdInventory),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Format of the inventory: json, ansible or prometheus-sd",
+				Value: inventoryFormatJSON,
+			},
+			cli.StringSliceFlag{
+				Name:  "filter",
+				Usage: "Filter the machines like ls",
+				Value: &cli.StringSlice{},
+			},
+			cli.IntFlag{
+				Name:  "timeout, t",
+				Usage: fmt.Sprintf("Timeout in seconds, default to %ds", lsDefaultTimeout),
+				Value: lsDefaultTimeout,
+			},
+			cli.StringFlag{
+				Name:  "metrics-port",
+				Usage: "Port of the prometheus-sd targets, instead of the port of --engine-metrics-addr",
+			},
 		},
 	},
 	{
@@ -308,6 +414,22 @@ var Commands = []cli.Command{
 		Flags:           []cli.Flag{updateConfigBoolFlag},
 		SkipFlagParsing: true,
 	},
+	{
+		Name:        "logs",
+		Usage:       "Fetch the logs of the Docker daemon of a machine",
+		Description: "Argument is a machine name.",
+		Action:      runCommand(cmdLogs),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "follow, f",
+				Usage: "Follow log output",
+			},
+			cli.StringFlag{
+				Name:  "since",
+				Usage: "Only show the logs of the last duration, e.g. 10m (requires journald)",
+			},
+		},
+	},
 	{
 		Name:   "ls",
 		Usage:  "List machines",
@@ -329,22 +451,104 @@ var Commands = []cli.Command{
 			},
 			cli.StringFlag{
 				Name:  "format, f",
-				Usage: "Pretty-print machines using a Go template",
+				Usage: "Pretty-print machines using a Go template, with the colorize and ago functions. The driver flag values are in .DriverConfig, e.g. {{index .DriverConfig \"amazonec2-region\"}}",
+			},
+			cli.StringFlag{
+				Name:  "template-file",
+				Usage: "Pretty-print machines using the Go template of a file, like --format",
+			},
+			cli.StringFlag{
+				Name:  "sort",
+				Usage: "Sort machines by name, state, driver or created",
+				Value: "name",
+			},
+			cli.BoolFlag{
+				Name:  "cached",
+				Usage: "List the states of the state cache written by refresh and --cache-refresh, with their age, without querying the machines",
+			},
+			cli.BoolFlag{
+				Name:  "cache-refresh",
+				Usage: "Query the states of all the machines and write them to the state cache",
+			},
+		},
+	},
+	{
+		Name:        "ops",
+		Usage:       "List the operations in progress on the machines, or cancel one",
+		Description: "The operations are the commands holding the lock of a machine, e.g. create or upgrade.",
+		Action:      runCommand(cmdOps),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "cancel",
+				Usage: "Cancel the operation in progress on a machine, the process running it exits and the machine is left as is",
+			},
+		},
+	},
+	{
+		Name:        "prune",
+		Usage:       "Remove the local entries of machines whose instance no longer exists",
+		Description: "Only machines reported as non-existent by their driver are removed.",
+		Action:      runCommand(cmdPrune),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "force, f",
+				Usage: "Remove local entries without prompting for confirmation",
+			},
+			cli.BoolFlag{
+				Name:  "ssh-keys",
+				Usage: "Also delete the SSH keys uploaded by machine whose machine no longer exists and that no instance uses",
+			},
+		},
+	},
+	{
+		Name:  "profile",
+		Usage: "Manage named sets of create flags",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the profiles",
+				Action: runCommand(cmdProfileList),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove profiles",
+				Description: "Argument(s) are one or more profile names.",
+				Action:      runCommand(cmdProfileRm),
+			},
+			{
+				Name:            "save",
+				Usage:           "Save create and driver flags as a profile, used with create --profile",
+				Description:     "Arguments are a profile name followed by create and driver flags.",
+				Action:          runCommand(cmdProfileSave),
+				SkipFlagParsing: true,
 			},
 		},
 	},
 	{
 		Name:            "provision",
 		Usage:           "Re-provision existing machines",
-		Action:          runCommand(withDriverFlags("provision", true, &updateConfigGenericFlag, cmdProvision)),
-		Flags:           []cli.Flag{updateConfigBoolFlag},
+		Action:          runCommand(withDriverFlags("provision", true, &updateConfigGenericFlag, withHostsLocked("provision", cmdProvision))),
+		Flags:           []cli.Flag{updateConfigBoolFlag, sshAgentForwardFlag},
 		SkipFlagParsing: true,
 	},
+	{
+		Name:        "refresh",
+		Usage:       "Update the state cache listed by ls --cached",
+		Description: "The states of all the machines are queried concurrently.",
+		Action:      runCommand(cmdRefresh),
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "timeout, t",
+				Usage: fmt.Sprintf("Timeout in seconds of the query of each machine, default to %ds", lsDefaultTimeout),
+				Value: lsDefaultTimeout,
+			},
+		},
+	},
 	{
 		Name:        "regenerate-certs",
 		Usage:       "Regenerate TLS Certificates for a machine",
 		Description: "Argument(s) are one or more machine names.",
-		Action:      runCommand(cmdRegenerateCerts),
+		Action:      runCommand(withHostsLocked("regenerate-certs", cmdRegenerateCerts)),
 		Flags: []cli.Flag{
 			cli.BoolFlag{
 				Name:  "force, f",
@@ -354,14 +558,76 @@ var Commands = []cli.Command{
 				Name:  "client-certs",
 				Usage: "Also regenerate client certificates and CA.",
 			},
+			cli.BoolFlag{
+				Name:  "client-only",
+				Usage: "Only regenerate the client certificate, with the existing CA, without restarting the daemons",
+			},
+			cli.BoolFlag{
+				Name:  "server-only",
+				Usage: "Only regenerate the server certificates and restart the daemons, keeping the client certificate and CA",
+			},
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Regenerate certificates for all machines",
+			},
+			cli.IntFlag{
+				Name:  "parallel",
+				Usage: fmt.Sprintf("Number of machines to regenerate concurrently with --all, default to %d", regenerateCertsDefaultParallel),
+				Value: regenerateCertsDefaultParallel,
+			},
+		},
+	},
+	{
+		Name:        "resize",
+		Usage:       "Change the size of the instance of a machine",
+		Description: "Argument is a machine name. The instance is stopped, resized and started again, then the command waits for the Docker daemon to answer.",
+		Action:      runCommand(withHostsLocked("resize", cmdResize)),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "size",
+				Usage: "New size of the instance, e.g. its instance type, machine type or droplet size",
+			},
+			cli.IntFlag{
+				Name:  "timeout",
+				Usage: fmt.Sprintf("Timeout in seconds of the wait for the Docker daemon, default to %ds", waitHealthyDefaultTimeout),
+				Value: waitHealthyDefaultTimeout,
+			},
 		},
 	},
 	{
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "wait-healthy",
+				Usage: "Wait for the Docker daemon to answer before returning",
+			},
+			cli.IntFlag{
+				Name:  "wait-healthy-timeout",
+				Usage: fmt.Sprintf("Timeout in seconds of --wait-healthy, default to %ds", waitHealthyDefaultTimeout),
+				Value: waitHealthyDefaultTimeout,
+			},
+		},
 		Name:        "restart",
 		Usage:       "Restart a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdRestart),
 	},
+	{
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "wait-healthy",
+				Usage: "Wait for the Docker daemon to answer before returning",
+			},
+			cli.IntFlag{
+				Name:  "wait-healthy-timeout",
+				Usage: fmt.Sprintf("Timeout in seconds of --wait-healthy, default to %ds", waitHealthyDefaultTimeout),
+				Value: waitHealthyDefaultTimeout,
+			},
+		},
+		Name:        "reboot",
+		Usage:       "Reboot a running machine, keeping its instance",
+		Description: "Argument(s) are one or more machine names. The reboot API of the provider is used when the driver has one, else the machine is rebooted from the guest.",
+		Action:      runCommand(withHostsLocked("reboot", cmdReboot)),
+	},
 	{
 		Flags: []cli.Flag{
 			cli.BoolFlag{
@@ -372,25 +638,33 @@ var Commands = []cli.Command{
 				Name:  "y",
 				Usage: "Assumes automatic yes to proceed with remove, without prompting further user confirmation",
 			},
+			cli.StringFlag{
+				Name:  "pre-remove-script",
+				Usage: "Local script run before each machine is removed, instead of the one given at create",
+			},
+			cli.StringFlag{
+				Name:  "post-remove-script",
+				Usage: "Local script run after each machine is removed, instead of the one given at create",
+			},
 			updateConfigBoolFlag,
 		},
 		Name:            "rm",
 		Usage:           "Remove a machine",
-		Description:     "Argument(s) are one or more machine names.",
-		Action:          runCommand(withDriverFlags("rm", true, &updateConfigGenericFlag, cmdRm)),
+		Description:     "Argument(s) are one or more machine names. The pre-remove and post-remove scripts are run with the name and the IP of the machine as arguments. A failing pre-remove script aborts the removal of the machine unless --force is given, a failing post-remove script is only logged.",
+		Action:          runCommand(withDriverFlags("rm", true, &updateConfigGenericFlag, withHostsLocked("rm", cmdRm))),
 		SkipFlagParsing: true,
 	},
 	{
 		Name:            "ssh",
 		Usage:           "Log into or run a command on a machine with SSH.",
-		Description:     "Arguments are [machine-name] [command]",
+		Description:     "Arguments are [--sudo] [--ssh-agent-forward] [--command-file path] [machine-name] [command]. With --sudo, the command is run, or a root shell is started, with passwordless sudo. With --ssh-agent-forward, the local SSH agent is forwarded to the session, letting anyone with root access to the machine authenticate as you while it is open. With --command-file, the local script is uploaded to a temporary file and run with the interpreter of its shebang, sh by default, the remaining arguments being its arguments. The proxy variables of the engine env are exported to it and its exit status is the exit code of the command.",
 		Action:          runCommand(cmdSSH),
 		SkipFlagParsing: true,
 	},
 	{
 		Name:        "scp",
 		Usage:       "Copy files between machines",
-		Description: "Arguments are [[user@]machine:][path] [[user@]machine:][path].",
+		Description: "Arguments are [[user@]machine:][path] [[user@]machine:][path]. A local path of - streams a single file from stdin to the machine, or from the machine to stdout, e.g. to pipe a log to grep. The file is streamed over the native SSH client and no progress is printed.",
 		Action:      runCommand(cmdScp),
 		Flags: []cli.Flag{
 			cli.BoolFlag{
@@ -405,6 +679,19 @@ var Commands = []cli.Command{
 				Name:  "quiet, q",
 				Usage: "Disables the progress meter as well as warning and diagnostic messages from ssh",
 			},
+			cli.BoolFlag{
+				Name:  "resume",
+				Usage: "Resume an interrupted copy of a single file from where it stopped, verifying the result (uses the native SSH client)",
+			},
+			cli.BoolFlag{
+				Name:  "sudo",
+				Usage: "Copy a single file with sudo on the machine, to or from a path the SSH user can't access. Requires passwordless sudo (uses the native SSH client)",
+			},
+			cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "Skip the files matching a glob in recursive copies, e.g. .git, *.log or build/cache. A pattern without a slash matches names anywhere, one with a slash the path from the copied directory. Can be repeated, a file matching any pattern is skipped (uses rsync with --delta, the native SSH client otherwise)",
+				Value: &cli.StringSlice{},
+			},
 		},
 	},
 	{
@@ -420,10 +707,34 @@ var Commands = []cli.Command{
 		},
 	},
 	{
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "wait-healthy",
+				Usage: "Wait for the Docker daemon to answer before returning",
+			},
+			cli.IntFlag{
+				Name:  "wait-healthy-timeout",
+				Usage: fmt.Sprintf("Timeout in seconds of --wait-healthy, default to %ds", waitHealthyDefaultTimeout),
+				Value: waitHealthyDefaultTimeout,
+			},
+			cli.IntFlag{
+				Name:  "start-retries",
+				Usage: "Number of times to retry starting a machine the provider lacks the capacity for or fails transiently",
+			},
+			cli.IntFlag{
+				Name:  "start-retry-interval",
+				Usage: fmt.Sprintf("Delay in seconds before the first retry of --start-retries, doubled after each retry, default to %ds", startRetryDefaultInterval),
+				Value: startRetryDefaultInterval,
+			},
+			cli.BoolFlag{
+				Name:  "start-allow-replace",
+				Usage: "Replace the spot instance of a machine that still can't be started for lack of capacity with a new one, losing its data",
+			},
+		},
 		Name:        "start",
 		Usage:       "Start a machine",
 		Description: "Argument(s) are one or more machine names.",
-		Action:      runCommand(cmdStart),
+		Action:      runCommand(withHostsLocked("start", cmdStart)),
 	},
 	{
 		Name:            "status",
@@ -437,13 +748,59 @@ var Commands = []cli.Command{
 		Name:        "stop",
 		Usage:       "Stop a machine",
 		Description: "Argument(s) are one or more machine names.",
-		Action:      runCommand(cmdStop),
+		Action:      runCommand(withHostsLocked("stop", cmdStop)),
 	},
 	{
 		Name:        "upgrade",
 		Usage:       "Upgrade a machine to the latest version of Docker",
 		Description: "Argument(s) are one or more machine names.",
-		Action:      runCommand(cmdUpgrade),
+		Action:      runCommand(withHostsLocked("upgrade", cmdUpgrade)),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Upgrade, or check with --check, all machines",
+			},
+			cli.BoolFlag{
+				Name:  "check",
+				Usage: "Only print which machines have a Docker upgrade available, without changing anything",
+			},
+			cli.StringFlag{
+				Name:  "target-version",
+				Usage: "Docker version compared against with --check, default to the latest Docker release",
+			},
+			cli.StringSliceFlag{
+				Name:  "filter",
+				Usage: "Only upgrade the machines of --all matching the filter, like ls",
+				Value: &cli.StringSlice{},
+			},
+			cli.StringSliceFlag{
+				Name:  "group",
+				Usage: "Only upgrade the machines of --all in the group, as named by inventory, e.g. label_env_prod",
+				Value: &cli.StringSlice{},
+			},
+			cli.BoolFlag{
+				Name:  "rolling",
+				Usage: "Upgrade the machines one after the other, waiting for the Docker daemon of each one to answer before the next, and print the result of each machine",
+			},
+			cli.IntFlag{
+				Name:  "max-unavailable",
+				Usage: "Number of machines upgraded at once by --rolling",
+				Value: 1,
+			},
+			cli.BoolFlag{
+				Name:  "drain",
+				Usage: "Drain the swarm node of each machine while --rolling upgrades it",
+			},
+			cli.BoolFlag{
+				Name:  "continue-on-error",
+				Usage: "Keep upgrading the other machines after a failure of --rolling",
+			},
+			cli.IntFlag{
+				Name:  "wait-healthy-timeout",
+				Usage: fmt.Sprintf("Timeout in seconds of the wait for the Docker daemon of each machine of --rolling, default to %ds", waitHealthyDefaultTimeout),
+				Value: waitHealthyDefaultTimeout,
+			},
+		},
 	},
 	{
 		Name:            "url",
@@ -453,6 +810,53 @@ var Commands = []cli.Command{
 		Flags:           []cli.Flag{updateConfigBoolFlag},
 		SkipFlagParsing: true,
 	},
+	{
+		Name:        "render",
+		Usage:       "Print the driver and the flag values create would use for a config file, without creating anything",
+		Description: "The options of the config file are set over the defaults of the create and driver flags, and the driver flags not set are read from their environment variables. The output is a JSON config file.",
+		Action:      runCommand(cmdRender),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "config",
+				Usage: "YAML or JSON config or profile file to render",
+			},
+			cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "Set an option of the config file as key=value, overriding its value in the file. Can be repeated",
+				Value: &cli.StringSlice{},
+			},
+		},
+	},
+	{
+		Name:        "validate-config",
+		Usage:       "Check a config or profile file against the flags of its driver, without creating anything",
+		Description: "Argument is a YAML or JSON file with a driver and the options, i.e. the create and driver flags, to use.",
+		Action:      runCommand(cmdValidateConfig),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Format of the problems: table or json",
+				Value: validateConfigFormatTable,
+			},
+		},
+	},
+	{
+		Name:        "verify-credentials",
+		Usage:       "Check that the credentials of a driver work, without creating anything",
+		Description: fmt.Sprintf("Run '%s verify-credentials --driver name --help' to include the flags for that driver in the help text.", os.Args[0]),
+		Action: runCommand(withDriverFlags("verify-credentials", false, &cli.GenericFlag{
+			Name:   "driver, d",
+			EnvVar: "MACHINE_DRIVER",
+		}, cmdVerifyCredentials)),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "driver, d",
+				Usage:  "Driver whose credentials are checked.",
+				EnvVar: "MACHINE_DRIVER",
+			},
+		},
+		SkipFlagParsing: true,
+	},
 	{
 		Name:   "version",
 		Usage:  "Show the Docker Machine version or a machine docker version",
@@ -463,14 +867,9 @@ var Commands = []cli.Command{
 func printIP(h *host.Host) func() error {
 	return func() error {
 		// preference: IPv4 address, then IPv6 address
-		ip, err := h.Driver.GetIP()
+		ip, err := drivers.MachineIP(h.Driver, h.PreferIPv6())
 		if err != nil {
-			log.Warnf("Error getting IPv4 address: %s", err)
-			log.Debug("Getting IPv6 address")
-			ip, err = h.Driver.GetIPv6()
-			if err != nil {
-				return fmt.Errorf("error getting IPv6 address: %s", err)
-			}
+			return err
 		}
 
 		fmt.Println(ip)
@@ -486,9 +885,10 @@ func machineCommand(actionName string, host *host.Host, errorChan chan<- error)
 	commands := map[string](func() error){
 		"configureAuth":    host.ConfigureAuth,
 		"configureAllAuth": host.ConfigureAllAuth,
-		"start":            host.Start,
+		"start":            func() error { return startHost(host, startRetry) },
 		"stop":             host.Stop,
 		"restart":          host.Restart,
+		"reboot":           host.Reboot,
 		"kill":             host.Kill,
 		"upgrade":          host.Upgrade,
 		"ip":               printIP(host),
@@ -527,6 +927,138 @@ func runActionForeachMachine(actionName string, machines []*host.Host) []error {
 	return errs
 }
 
+// runLockedActionForeachMachine works like runActionForeachMachine, holding
+// the lock of each machine while the action runs on it. It is used with
+// --all, whose machines withHostsLocked doesn't lock up front.
+func runLockedActionForeachMachine(api libmachine.API, actionName string, machines []*host.Host) []error {
+	var (
+		errorChan = make(chan error)
+		errs      = []error{}
+	)
+
+	for _, machine := range machines {
+		go func(h *host.Host) {
+			errorChan <- withHostLock(api, actionName, h, func() error {
+				actionErr := make(chan error, 1)
+				machineCommand(actionName, h, actionErr)
+				return <-actionErr
+			})
+		}(machine)
+	}
+
+	for range machines {
+		if err := <-errorChan; err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	close(errorChan)
+
+	return errs
+}
+
+// runForeachHostLimited calls fn for every host with at most `parallel` calls
+// in flight at once. A failure for one host does not stop the others; the
+// errors are returned keyed by host name.
+func runForeachHostLimited(hosts []*host.Host, parallel int, fn func(h *host.Host) error) map[string]error {
+	byName := map[string]*host.Host{}
+	names := []string{}
+	for _, h := range hosts {
+		byName[h.Name] = h
+		names = append(names, h.Name)
+	}
+
+	return runForeachNameLimited(names, parallel, func(name string) error {
+		return fn(byName[name])
+	})
+}
+
+// runForeachNameLimited is runForeachHostLimited for machines that are not
+// loaded yet, or don't exist yet.
+func runForeachNameLimited(names []string, parallel int, fn func(name string) error) map[string]error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sem  = make(chan struct{}, parallel)
+		errs = map[string]error{}
+	)
+
+	progress, stop := startProgress(names)
+	defer stop()
+
+	for _, name := range names {
+		progress.Update(name, "waiting")
+	}
+
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			progress.Update(name, "running")
+			if err := progress.Run(name, func() error { return fn(name) }); err != nil {
+				progress.Update(name, "error: "+err.Error())
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+				return
+			}
+			progress.Update(name, "done")
+		}(name)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// startProgress renders the progress of an operation on several machines to
+// stderr, leaving stdout to the output of the command. On a terminal the log
+// output shown on it goes through the progress table until the returned
+// function is called.
+func startProgress(names []string) (*log.Progress, func()) {
+	if len(names) < 2 {
+		progress := log.NewProgress(io.Discard, false, names)
+		return progress, func() {}
+	}
+
+	tty := term.IsTerminal(os.Stderr.Fd())
+	progress := log.NewProgress(os.Stderr, tty, names)
+	if !tty {
+		return progress, func() { progress.Close() }
+	}
+
+	log.SetErrWriter(progress)
+
+	// The log lines written to stdout only tear the table when stdout is the
+	// same terminal, they are left alone when it is read by a script.
+	outTTY := term.IsTerminal(os.Stdout.Fd())
+	if outTTY {
+		log.SetOutWriter(progress)
+	}
+
+	return progress, func() {
+		if outTTY {
+			log.SetOutWriter(os.Stdout)
+		}
+		log.SetErrWriter(os.Stderr)
+		progress.Close()
+	}
+}
+
+// isInstanceNotFound returns true if err tells the instance of a machine
+// doesn't exist. Drivers that don't classify their errors are matched on the
+// message.
+func isInstanceNotFound(err error) bool {
+	return errors.Is(err, mcnerror.ErrInstanceNotFound) || strings.Contains(strings.ToLower(err.Error()), "not found")
+}
+
 func consolidateErrs(errs []error) error {
 	finalErr := ""
 	for _, err := range errs {
@@ -583,33 +1115,39 @@ func withDriverFlags(
 			}
 		}
 
-		// If the driver is still not defined, we'll assume it's not available because it wasn't specified and just run
-		// the command.
-		if driverName == "" {
-			return updateAndRunCommand(c, cmdName, nil, handler)
-		}
+		return runWithDriverFlags(c, api, cmdName, driverName, handler)
+	}
+}
 
-		// Create a new empty host object with the driver. Unfortunately, this is the only way of getting driver args
-		// at the moment.
-		rawDriver, err := json.Marshal(&drivers.BaseDriver{MachineName: "temp-driver-loader"})
-		if err != nil {
-			return fmt.Errorf("error marshalling base driver: %w", err)
-		}
+// runWithDriverFlags adds the flags of the named driver to the command with the given name and reruns the CLI app to
+// execute the given handler function.
+func runWithDriverFlags(c CommandLine, api libmachine.API, cmdName, driverName string, handler cmdHandler) error {
+	// If the driver is still not defined, we'll assume it's not available because it wasn't specified and just run
+	// the command.
+	if driverName == "" {
+		return updateAndRunCommand(c, cmdName, nil, handler)
+	}
 
-		h, err := api.NewHost(driverName, rawDriver)
-		if err != nil {
-			return err
-		}
+	// Create a new empty host object with the driver. Unfortunately, this is the only way of getting driver args
+	// at the moment.
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{MachineName: "temp-driver-loader"})
+	if err != nil {
+		return fmt.Errorf("error marshalling base driver: %w", err)
+	}
 
-		// Convert driver flags into CLI flags.
-		driverFlags := h.Driver.GetCreateFlags()
-		driverCLIFlags, err := convertMcnFlagsToCliFlags(driverFlags)
-		if err != nil {
-			return fmt.Errorf("error converting driver flags to CLI flags: %w", err)
-		}
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		return err
+	}
 
-		return updateAndRunCommand(c, cmdName, driverCLIFlags, handler)
+	// Convert driver flags into CLI flags.
+	driverFlags := h.Driver.GetCreateFlags()
+	driverCLIFlags, err := convertMcnFlagsToCliFlags(driverFlags, c.GlobalString("env-prefix"))
+	if err != nil {
+		return fmt.Errorf("error converting driver flags to CLI flags: %w", err)
 	}
+
+	return updateAndRunCommand(c, cmdName, driverCLIFlags, handler)
 }
 
 // updateAndRunCommand add the given driver-specific flags to the command with the given name and reruns the CLI app
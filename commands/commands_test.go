@@ -4,7 +4,9 @@ import (
 	"errors"
 	"flag"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/rancher/machine/commands/commandstest"
 	"github.com/rancher/machine/drivers/fakedriver"
@@ -127,6 +129,40 @@ func TestRunActionForeachMachine(t *testing.T) {
 	}
 }
 
+func TestRunForeachHostLimited(t *testing.T) {
+	hosts := []*host.Host{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}, {Name: "spam"}}
+
+	var (
+		mu       sync.Mutex
+		inFlight int
+		maxSeen  int
+	)
+
+	errs := runForeachHostLimited(hosts, 2, func(h *host.Host) error {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		if h.Name == "bar" {
+			return errors.New("bar failed")
+		}
+		return nil
+	})
+
+	assert.True(t, maxSeen <= 2)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs["bar"], "bar failed")
+}
+
 func TestPrintIPEmptyGivenLocalEngine(t *testing.T) {
 	stdoutGetter := commandstest.NewStdoutGetter()
 	defer stdoutGetter.Stop()
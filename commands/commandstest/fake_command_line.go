@@ -70,6 +70,13 @@ func (fcli *FakeCommandLine) GlobalString(key string) string {
 	return fcli.GlobalFlags.String(key)
 }
 
+func (fcli *FakeCommandLine) GlobalBool(key string) bool {
+	if fcli.GlobalFlags == nil {
+		return false
+	}
+	return fcli.GlobalFlags.Bool(key)
+}
+
 func (fcli *FakeCommandLine) Generic(name string) interface{} {
 	return fcli.LocalFlags.Data[name]
 }
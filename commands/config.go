@@ -2,8 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"text/template"
 
 	"github.com/rancher/machine/commands/mcndirs"
 	"github.com/rancher/machine/libmachine"
@@ -33,14 +35,46 @@ func cmdConfig(c CommandLine, api libmachine.API) error {
 
 	log.Debug(dockerHost)
 
-	tlsCACert := filepath.Join(mcndirs.GetMachineDir(), host.Name, "ca.pem")
-	tlsCert := filepath.Join(mcndirs.GetMachineDir(), host.Name, "cert.pem")
-	tlsKey := filepath.Join(mcndirs.GetMachineDir(), host.Name, "key.pem")
+	cfg := connectionConfig{
+		Host:           dockerHost,
+		CaCertPath:     filepath.Join(mcndirs.GetMachineDir(), host.Name, "ca.pem"),
+		ClientCertPath: filepath.Join(mcndirs.GetMachineDir(), host.Name, "cert.pem"),
+		ClientKeyPath:  filepath.Join(mcndirs.GetMachineDir(), host.Name, "key.pem"),
+		TLSVerify:      true,
+	}
+
+	if tmplString := c.String("format"); tmplString != "" {
+		return printConnectionConfig(os.Stdout, tmplString, cfg)
+	}
 
 	// TODO(nathanleclaire): These magic strings for the certificate file
 	// names should be cross-package constants.
 	fmt.Printf("--tlsverify\n--tlscacert=%q\n--tlscert=%q\n--tlskey=%q\n-H=%s\n",
-		tlsCACert, tlsCert, tlsKey, dockerHost)
+		cfg.CaCertPath, cfg.ClientCertPath, cfg.ClientKeyPath, cfg.Host)
 
 	return nil
 }
+
+// connectionConfig is the connection config of a machine exposed to --format
+// templates.
+type connectionConfig struct {
+	Host           string
+	CaCertPath     string
+	ClientCertPath string
+	ClientKeyPath  string
+	TLSVerify      bool
+}
+
+func printConnectionConfig(w io.Writer, tmplString string, cfg connectionConfig) error {
+	tmpl, err := template.New("").Funcs(funcMap).Parse(tmplString)
+	if err != nil {
+		return fmt.Errorf("template parsing error: %v", err)
+	}
+
+	if err := tmpl.Execute(w, cfg); err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte{'\n'})
+	return err
+}
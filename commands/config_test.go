@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintConnectionConfig(t *testing.T) {
+	cfg := connectionConfig{
+		Host:           "tcp://192.0.2.10:2376",
+		CaCertPath:     "/machines/foo/ca.pem",
+		ClientCertPath: "/machines/foo/cert.pem",
+		ClientKeyPath:  "/machines/foo/key.pem",
+		TLSVerify:      true,
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printConnectionConfig(out, "{{.Host}} {{.CaCertPath}} {{.TLSVerify}}", cfg))
+	assert.Equal(t, "tcp://192.0.2.10:2376 /machines/foo/ca.pem true\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printConnectionConfig(out, "{{json .}}", cfg))
+	assert.Equal(t, `{"Host":"tcp://192.0.2.10:2376","CaCertPath":"/machines/foo/ca.pem","ClientCertPath":"/machines/foo/cert.pem","ClientKeyPath":"/machines/foo/key.pem","TLSVerify":true}`+"\n", out.String())
+
+	assert.Error(t, printConnectionConfig(out, "{{.Host", cfg))
+}
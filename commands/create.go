@@ -8,15 +8,20 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rancher/machine/commands/mcndirs"
 	"github.com/rancher/machine/libmachine"
 	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/cert"
 	"github.com/rancher/machine/libmachine/crashreport"
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
@@ -25,11 +30,17 @@ import (
 	"github.com/rancher/machine/libmachine/log"
 	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
+	"github.com/rancher/machine/libmachine/provision"
+	"github.com/rancher/machine/libmachine/ssh"
 	"github.com/rancher/machine/libmachine/swarm"
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
 )
 
+// waitCloudInitDefaultTimeout is the default timeout in seconds of
+// --wait-cloud-init.
+const waitCloudInitDefaultTimeout = 600
+
 var (
 	errNoMachineName = errors.New("error: No machine name specified")
 )
@@ -42,17 +53,40 @@ var (
 			Value:  "virtualbox",
 			EnvVar: "MACHINE_DRIVER",
 		},
+		cli.StringFlag{
+			Name:  "profile",
+			Usage: "Apply the flags of a profile saved with 'profile save', flags given on the command line override them",
+		},
+		cli.StringFlag{
+			Name:  "env-file",
+			Usage: "Read environment variables from a file of KEY=VALUE lines, variables already set are not overridden",
+			Value: "",
+		},
 		cli.StringFlag{
 			Name:   "engine-install-url",
 			Usage:  "Custom URL to use for engine installation",
 			Value:  drivers.DefaultEngineInstallURL,
 			EnvVar: "MACHINE_DOCKER_INSTALL_URL",
 		},
+		cli.StringFlag{
+			Name:  "engine-install-url-sha256",
+			Usage: "Expected SHA-256 of the engine install script, the script is not run if it doesn't match",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "engine-install-script-file",
+			Usage: "Local engine install script to use instead of downloading one from --engine-install-url",
+			Value: "",
+		},
 		cli.StringSliceFlag{
 			Name:  "engine-opt",
 			Usage: "Specify arbitrary flags to include with the created engine in the form flag=value",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringFlag{
+			Name:  "engine-flag-file",
+			Usage: "Specify a local file of engine flags, one --flag[=value] per line, passed before the --engine-opt flags. The host and TLS flags set by machine are ignored",
+		},
 		cli.StringSliceFlag{
 			Name:  "engine-insecure-registry",
 			Usage: "Specify insecure registries to allow with the created engine",
@@ -64,6 +98,11 @@ var (
 			Value:  &cli.StringSlice{},
 			EnvVar: "ENGINE_REGISTRY_MIRROR",
 		},
+		cli.StringSliceFlag{
+			Name:  "engine-registry-ca-file",
+			Usage: "Specify a PEM CA file trusted for pulls from a registry in the form registry=file",
+			Value: &cli.StringSlice{},
+		},
 		cli.StringSliceFlag{
 			Name:  "engine-label",
 			Usage: "Specify labels for the created engine",
@@ -73,9 +112,75 @@ var (
 			Name:  "engine-storage-driver",
 			Usage: "Specify a storage driver to use with the engine",
 		},
+		cli.StringFlag{
+			Name:  "engine-data-root",
+			Usage: "Specify an absolute path on the host to store the engine data in",
+		},
+		cli.StringFlag{
+			Name:  "engine-metrics-addr",
+			Usage: "Specify the host:port the engine serves its Prometheus metrics on",
+		},
+		cli.BoolFlag{
+			Name:  "engine-metrics-open-port",
+			Usage: "Open the port of --engine-metrics-addr with the driver's open port option, when it has one",
+		},
+		cli.StringSliceFlag{
+			Name:  "engine-systemd-dropin",
+			Usage: "Specify a KEY=VALUE directive of the [Service] section of the engine systemd unit",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "engine-systemd-dropin-file",
+			Usage: "Specify a local systemd drop-in file for the engine unit",
+		},
+		cli.BoolFlag{
+			Name:  "engine-rootless",
+			Usage: "Run Docker rootless as the SSH user, on systemd based OSes only. Privileged containers, AppArmor and container ports below 1024 are not available, and --engine-data-root and systemd drop-ins can't be used",
+		},
+		cli.StringFlag{
+			Name:  "engine-nvidia-runtime",
+			Usage: "Install the NVIDIA container toolkit and register the nvidia runtime, as the default runtime with 'default' or beside runc with 'available'. The image of the machine must include the NVIDIA driver",
+		},
+		cli.IntFlag{
+			Name:  "engine-mtu",
+			Usage: fmt.Sprintf("Specify the MTU of the engine networks, between %d and %d, written to daemon.json. It can't be combined with --engine-opt mtu=N", provision.MinEngineMTU, provision.MaxEngineMTU),
+		},
+		cli.StringSliceFlag{
+			Name:  "engine-default-ulimit",
+			Usage: "Specify a name=soft[:hard] default ulimit of the containers, e.g. nofile=65536:65536, written to daemon.json. It can't be combined with --engine-opt default-ulimit",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "engine-limit-nofile",
+			Usage: "Specify the LimitNOFILE of the engine process, a number of files or infinity, set with a systemd drop-in",
+		},
+		cli.StringFlag{
+			Name:  "engine-cgroup-driver",
+			Usage: "Specify the cgroup driver of the engine, systemd or cgroupfs, written to daemon.json. Default to systemd on the cgroup v2 machines running systemd, and to the default of the engine on the others",
+		},
+		cli.IntFlag{
+			Name:  "engine-port",
+			Usage: fmt.Sprintf("Specify the TCP port the engine listens on, opened in the firewall of the amazonec2, google and azure drivers. Default to the port of the driver, %d for most", engine.DefaultPort),
+		},
+		cli.StringFlag{
+			Name:  "engine-containerd-config-file",
+			Usage: "Specify a local containerd config file merged into /etc/containerd/config.toml, on systemd based OSes only. containerd is restarted with it and the previous config is restored if it fails to start",
+		},
+		cli.StringFlag{
+			Name:  "engine-network-plugin",
+			Usage: "Set up a network plugin once the engine is up: " + strings.Join(provision.NetworkPlugins(), ", ") + ", or plugin:<reference> to install a managed plugin",
+		},
+		cli.StringFlag{
+			Name:  "engine-network-plugin-script",
+			Usage: "Specify a local script run as root on the machine once the engine is up, to set up a custom network plugin",
+		},
+		cli.StringFlag{
+			Name:  "boot2docker-profile-file",
+			Usage: "Specify a local shell script installed as the bootlocal.sh of boot2docker machines, run at every boot to customize the OS, e.g. load kernel modules or mount filesystems",
+		},
 		cli.StringSliceFlag{
 			Name:  "engine-env",
-			Usage: "Specify environment variables to set in the engine",
+			Usage: "Specify environment variables to set in the engine as KEY=value, written to an environment file of the docker unit on systemd machines",
 			Value: &cli.StringSlice{},
 		},
 		cli.BoolFlag{
@@ -97,6 +202,10 @@ var (
 			Usage: "Discovery service to use with Swarm",
 			Value: "",
 		},
+		cli.BoolFlag{
+			Name:  "swarm-discovery-check",
+			Usage: "Check that the consul or etcd discovery service is reachable before creating the machine",
+		},
 		cli.StringFlag{
 			Name:  "swarm-strategy",
 			Usage: "Define a default scheduling strategy for Swarm",
@@ -131,42 +240,586 @@ var (
 			Usage: "Support extra SANs for TLS certs",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringFlag{
+			Name:  "tls-min-version",
+			Usage: "Minimum TLS version used to talk to the Docker daemon (1.2 or 1.3)",
+			Value: cert.DefaultTLSMinVersion,
+		},
+		cli.StringSliceFlag{
+			Name:  "tls-cipher-suite",
+			Usage: "TLS 1.2 cipher suite used to talk to the Docker daemon, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (default: ECDHE suites with AES-GCM or ChaCha20-Poly1305)",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "tls-cert-duration",
+			Usage: fmt.Sprintf("Validity of the generated client and server certs, e.g. 2160h (default: %s)", cert.DefaultCertDuration),
+		},
+		cli.StringFlag{
+			Name:  "tls-ca-duration",
+			Usage: fmt.Sprintf("Validity of the CA when it is generated, e.g. 8760h (default: %s)", cert.DefaultCertDuration),
+		},
+		cli.StringFlag{
+			Name:  "tls-ca-name",
+			Usage: "Use the named CA of the storage dir instead of the default one, creating it if absent",
+		},
+		cli.StringFlag{
+			Name:  "expect-ip",
+			Usage: "Fail and remove the machine if its IP is not this address or in this CIDR block",
+		},
+		sshAgentForwardFlag,
+		cli.BoolFlag{
+			Name:   "ssh-port-probe",
+			Usage:  "Probe the SSH port with a TCP connection while waiting for SSH, to fail fast when the machine is not routable",
+			EnvVar: "MACHINE_SSH_PORT_PROBE",
+		},
+		cli.StringFlag{
+			Name:  "ssh-import-key",
+			Usage: "Use this existing private key as the SSH key of the machine instead of generating one, anyone holding it can then access the machine",
+		},
+		cli.StringFlag{
+			Name:  "name-pattern",
+			Usage: "Create machines named after this template instead of a name argument, e.g. web-{{.Index}} ({{random 6}} and {{timestamp}} are also available)",
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Usage: "Number of machines to create with --name-pattern",
+			Value: 1,
+		},
+		cli.IntFlag{
+			Name:  "parallel",
+			Usage: fmt.Sprintf("Number of machines to create concurrently with --name-pattern, default to %d", createDefaultParallel),
+			Value: createDefaultParallel,
+		},
 		cli.StringFlag{
 			Name:  "custom-install-script",
 			Usage: "Use a custom provisioning script instead of installing docker",
 			Value: "",
 		},
+		cli.StringFlag{
+			Name:  "pre-remove-script",
+			Usage: "Specify a local script run by rm before the machine is removed, with its name and IP as arguments. The removal is aborted if it fails, unless rm is forced",
+		},
+		cli.StringFlag{
+			Name:  "post-remove-script",
+			Usage: "Specify a local script run by rm after the machine is removed, with its name and IP as arguments",
+		},
+		cli.BoolFlag{
+			Name:  "no-provision",
+			Usage: "Don't provision the machine, its image must run Docker with TLS on the engine port with a server cert signed by the CA of --tls-ca-cert, verifying the client certs signed by it",
+		},
+		cli.BoolFlag{
+			Name:  "skip-ssh-wait",
+			Usage: "With --no-provision, don't wait for SSH either, e.g. for images configured by cloud-init from the --user-data-file",
+		},
+		cli.BoolFlag{
+			Name:  "wait-cloud-init",
+			Usage: "Wait for cloud-init to be done before provisioning, on the images having it",
+		},
+		cli.IntFlag{
+			Name:  "wait-cloud-init-timeout",
+			Usage: fmt.Sprintf("Timeout in seconds of --wait-cloud-init, default to %ds", waitCloudInitDefaultTimeout),
+			Value: waitCloudInitDefaultTimeout,
+		},
+		cli.BoolFlag{
+			Name:  "estimate-cost",
+			Usage: "Print the estimated cost of the instance before creating it, for the drivers with pricing data",
+		},
+		cli.StringFlag{
+			Name:  "set-hostname",
+			Usage: "Specify the OS hostname set on the machine instead of the machine name",
+		},
+		cli.BoolFlag{
+			Name:  "no-set-hostname",
+			Usage: "Keep the hostname set by the provider instead of setting one",
+		},
+		cli.BoolFlag{
+			Name:  "strict-hostnames",
+			Usage: "Fail instead of warning when an existing machine has the hostname of the new machine",
+		},
+		cli.StringFlag{
+			Name:  "daemon-hostname",
+			Usage: "Specify a DNS name of the machine used in the Docker daemon URL instead of its IP",
+		},
+		cli.BoolFlag{
+			Name:  "daemon-hostname-no-verify",
+			Usage: "Don't check that the daemon hostname resolves and reaches the machine",
+		},
+		cli.StringFlag{
+			Name:  "daemon-external-url",
+			Usage: "Specify the URL of a load balancer in front of the Docker daemon, e.g. tcp://lb:2376, given to the clients by env, url and config",
+		},
+		cli.BoolFlag{
+			Name:  "daemon-use-private-ip",
+			Usage: "Reach the Docker daemon at the private IP of the machine, reported by drivers like amazonec2, while SSH still uses its public address",
+		},
+		cli.BoolFlag{
+			Name:  "daemon-ssh-transport",
+			Usage: "Reach the Docker daemon over SSH with an ssh:// URL instead of TLS, the daemon doesn't listen on a TCP port",
+		},
+		cli.BoolFlag{
+			Name:  "prefer-ipv6",
+			Usage: "Use the IPv6 address of the machine in the Docker daemon URL, the swarm and ip when it has both an IPv4 and an IPv6 address. SSH keeps using the address reported by the driver",
+		},
+		cli.StringSliceFlag{
+			Name:  "provision-dns-server",
+			Usage: "Specify a DNS server set in the resolver of the machine and of its containers",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "provision-dns-search",
+			Usage: "Specify a DNS search domain set in the resolver of the machine and of its containers",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "provision-sysctl",
+			Usage: "Specify a kernel parameter in the form key=value set on the machine, e.g. vm.max_map_count=262144",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "provision-timezone",
+			Usage: "Specify the time zone set on the machine, e.g. Europe/Paris",
+		},
+		cli.StringSliceFlag{
+			Name:  "provision-ntp-server",
+			Usage: "Specify an NTP server the clock of the machine is synchronized with before the provisioning completes",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "provision-package-mirror",
+			Usage: "Specify the URL of a mirror replacing the host of the default apt or dnf/yum repositories, configured before installing the packages and Docker",
+		},
+		cli.StringFlag{
+			Name:   "provision-package-mirror-auth",
+			Usage:  "Specify the user:password credentials of the package mirror, which are not stored with the machine",
+			EnvVar: "MACHINE_PROVISION_PACKAGE_MIRROR_AUTH",
+		},
+		cli.BoolFlag{
+			Name:  "provision-mirror-ephemeral",
+			Usage: "Remove the package mirror credentials from the machine once it is provisioned",
+		},
 		cli.StringFlag{
 			Name:  "hostname-override",
 			Usage: "Specify hostname to use during cloud-init instead of default generated hostname",
 			Value: "",
 		},
+		cli.StringFlag{
+			Name:  "user-data-file",
+			Usage: "Specify a user-data file passed to the instance by the drivers supporting it, in place of their own user-data flag",
+			Value: "",
+		},
+		cli.StringSliceFlag{
+			Name:  "instance-metadata",
+			Usage: "Specify instance metadata in the form key=value, for the google and azure drivers",
+			Value: &cli.StringSlice{},
+		},
+		cli.BoolFlag{
+			Name:  "encrypt-volumes",
+			Usage: "Encrypt the disks of the instance, with --amazonec2-kms-key, --google-kms-key or --azure-disk-encryption-set",
+		},
+	}
+)
+
+func cmdCreate(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		return invalidArguments(fmt.Errorf("invalid arguments: found extra arguments %v", c.Args()[1:]))
+	}
+
+	if c.Bool("swarm-discovery-check") {
+		if err := checkSwarmDiscovery(c.String("swarm-discovery")); err != nil {
+			return fmt.Errorf("error checking swarm discovery: [%s]", err)
+		}
+	}
+
+	if c.String("name-pattern") != "" {
+		return createFromNamePattern(c, api)
+	}
+
+	if c.Int("count") > 1 {
+		return invalidArguments(errors.New("--count requires --name-pattern"))
+	}
+
+	name := c.Args().First()
+	if name == "" {
+		c.ShowHelp()
+		return errNoMachineName
+	}
+
+	return createMachine(c, api, name)
+}
+
+// createFromNamePattern creates --count machines named after --name-pattern,
+// --parallel at a time. All the names are checked before any instance is
+// created.
+func createFromNamePattern(c CommandLine, api libmachine.API) error {
+	if c.Args().First() != "" {
+		return errors.New("a machine name can't be given with --name-pattern")
+	}
+
+	count := c.Int("count")
+	if count < 1 {
+		return errors.New("--count must be at least 1")
+	}
+
+	names, err := generateMachineNames(c.String("name-pattern"), count, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := checkMachineNamesAvailable(names, api); err != nil {
+		return err
+	}
+
+	parallel := c.Int("parallel")
+	if parallel <= 0 {
+		parallel = createDefaultParallel
+	}
+
+	log.Infof("Creating %s", strings.Join(names, ", "))
+
+	errs := runForeachNameLimited(names, parallel, func(name string) error {
+		return createMachine(c, api, name)
+	})
+
+	if len(errs) > 0 {
+		for _, name := range names {
+			if err, ok := errs[name]; ok {
+				log.Errorf("Error creating %s: %s", name, err)
+			}
+		}
+		return fleetError(len(errs), len(names), fmt.Errorf("Error: %d of %d machines could not be created", len(errs), len(names)))
+	}
+
+	return nil
+}
+
+func createMachine(c CommandLine, api libmachine.API, name string) error {
+	if !host.ValidateHostName(name) {
+		return invalidArguments(fmt.Errorf("error creating machine: [%s]", mcnerror.ErrInvalidHostname))
+	}
+
+	release, err := lockHosts(api, "create", []string{name})
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing swarm discovery: [%s]", err))
+	}
+
+	installScriptFile := c.String("engine-install-script-file")
+	if installScriptFile != "" {
+		// The path is stored with the machine and reused by provision and upgrade.
+		absPath, err := filepath.Abs(installScriptFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine install script: [%s]", err)
+		}
+		installScriptFile = absPath
+
+		if _, err := os.Stat(installScriptFile); err != nil {
+			return fmt.Errorf("error reading engine install script: [%s]", err)
+		}
+	}
+
+	for _, directive := range c.StringSlice("engine-systemd-dropin") {
+		if err := provision.ValidateSystemdDirective(directive); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing engine systemd drop-in: [%s]", err))
+		}
+	}
+
+	dropInFile := c.String("engine-systemd-dropin-file")
+	if dropInFile != "" {
+		absPath, err := filepath.Abs(dropInFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine systemd drop-in: [%s]", err)
+		}
+		dropInFile = absPath
+
+		content, err := os.ReadFile(dropInFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine systemd drop-in: [%s]", err)
+		}
+
+		if err := provision.ValidateSystemdDropIn(string(content)); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing engine systemd drop-in %s: [%s]", dropInFile, err))
+		}
+	}
+
+	// The daemon flags of the engine flag file come before the --engine-opt
+	// ones, as they are merged when provisioning.
+	engineOpts := c.StringSlice("engine-opt")
+
+	flagFile := c.String("engine-flag-file")
+	if flagFile != "" {
+		absPath, err := filepath.Abs(flagFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine flag file: [%s]", err)
+		}
+		flagFile = absPath
+
+		content, err := os.ReadFile(flagFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine flag file: [%s]", err)
+		}
+
+		fileFlags, err := provision.ParseEngineFlagFile(string(content))
+		if err != nil {
+			return invalidArguments(fmt.Errorf("error parsing engine flag file %s: [%s]", flagFile, err))
+		}
+		engineOpts = append(fileFlags, engineOpts...)
+	}
+
+	if expectIP := c.String("expect-ip"); expectIP != "" {
+		if _, err := drivers.ParseExpectedIP(expectIP); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing expected IP: [%s]", err))
+		}
+	}
+
+	if err := validateNoProvision(c); err != nil {
+		return invalidArguments(err)
+	}
+
+	hostname := c.String("set-hostname")
+	if hostname != "" {
+		if c.Bool("no-set-hostname") {
+			return invalidArguments(errors.New("--set-hostname and --no-set-hostname can't be used together"))
+		}
+
+		if err := provision.ValidateHostname(hostname); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing hostname: [%s]", err))
+		}
+	}
+
+	daemonHostname := c.String("daemon-hostname")
+	if daemonHostname != "" {
+		if err := validateDaemonHostname(daemonHostname, c.Bool("daemon-hostname-no-verify")); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing daemon hostname: [%s]", err))
+		}
+	}
+
+	if c.Bool("daemon-use-private-ip") && (daemonHostname != "" || c.Bool("prefer-ipv6")) {
+		return invalidArguments(errors.New("--daemon-use-private-ip can't be used with --daemon-hostname or --prefer-ipv6"))
+	}
+
+	if c.Bool("prefer-ipv6") && (daemonHostname != "" || c.Bool("daemon-ssh-transport")) {
+		return invalidArguments(errors.New("--prefer-ipv6 can't be used with --daemon-hostname or --daemon-ssh-transport, the daemon isn't reached at the IP of the machine"))
+	}
+
+	daemonExternalURL := c.String("daemon-external-url")
+	externalHost, err := daemonExternalHost(daemonExternalURL)
+	if err != nil {
+		return invalidArguments(fmt.Errorf("error parsing daemon external URL: [%s]", err))
+	}
+
+	if c.Bool("daemon-ssh-transport") {
+		if daemonHostname != "" || daemonExternalURL != "" || c.Bool("daemon-use-private-ip") {
+			return invalidArguments(errors.New("--daemon-ssh-transport can't be used with --daemon-hostname, --daemon-external-url or --daemon-use-private-ip"))
+		}
+		if c.Bool("engine-rootless") {
+			return invalidArguments(errors.New("--daemon-ssh-transport can't be used with --engine-rootless"))
+		}
+		if c.Bool("swarm") || c.Bool("swarm-master") {
+			return invalidArguments(errors.New("--daemon-ssh-transport can't be used with --swarm or --swarm-master"))
+		}
+		if c.IsSet("engine-port") {
+			return invalidArguments(errors.New("--daemon-ssh-transport can't be used with --engine-port"))
+		}
+	}
+
+	if port := c.Int("engine-port"); c.IsSet("engine-port") && (port < 1 || port > 65535) {
+		return invalidArguments(fmt.Errorf("invalid engine port %d, must be between 1 and 65535", port))
+	}
+
+	dnsServers := c.StringSlice("provision-dns-server")
+	if err := provision.ValidateDNSServers(dnsServers, engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing DNS servers: [%s]", err))
+	}
+	dnsSearch := c.StringSlice("provision-dns-search")
+	if err := provision.ValidateDNSSearch(dnsSearch, engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing DNS search domains: [%s]", err))
+	}
+
+	sysctls := c.StringSlice("provision-sysctl")
+	if err := provision.ValidateSysctls(sysctls); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing sysctls: [%s]", err))
+	}
+
+	if err := provision.ValidateTimeZone(c.String("provision-timezone")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing time zone: [%s]", err))
+	}
+
+	ntpServers := c.StringSlice("provision-ntp-server")
+	if err := provision.ValidateNTPServers(ntpServers); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing NTP servers: [%s]", err))
+	}
+
+	packageMirror := c.String("provision-package-mirror")
+	packageMirrorAuth := c.String("provision-package-mirror-auth")
+	if err := provision.ValidatePackageMirror(packageMirror, packageMirrorAuth); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing package mirror: [%s]", err))
+	}
+
+	registryCAFiles, err := provision.ParseRegistryCAFiles(c.StringSlice("engine-registry-ca-file"))
+	if err != nil {
+		return invalidArguments(fmt.Errorf("error parsing registry CA files: [%s]", err))
+	}
+
+	if err := validateEngineMetricsAddr(c.String("engine-metrics-addr")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine metrics address: [%s]", err))
+	}
+
+	if err := validateEngineDataRoot(c.String("engine-data-root"), engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine data root: [%s]", err))
+	}
+
+	if c.Bool("engine-rootless") {
+		if c.String("engine-data-root") != "" || len(c.StringSlice("engine-systemd-dropin")) > 0 || c.String("engine-systemd-dropin-file") != "" || c.String("engine-limit-nofile") != "" {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-data-root, --engine-limit-nofile or systemd drop-ins"))
+		}
+		if len(c.StringSlice("engine-default-ulimit")) > 0 {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-default-ulimit"))
+		}
+		if c.String("engine-nvidia-runtime") != "" {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-nvidia-runtime"))
+		}
+		if c.Int("engine-mtu") != 0 {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-mtu"))
+		}
+		if c.String("engine-network-plugin") != "" || c.String("engine-network-plugin-script") != "" {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-network-plugin or --engine-network-plugin-script"))
+		}
+		if c.String("engine-containerd-config-file") != "" {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-containerd-config-file"))
+		}
+		if c.String("engine-cgroup-driver") != "" {
+			return invalidArguments(errors.New("--engine-rootless can't be used with --engine-cgroup-driver"))
+		}
+	}
+
+	if err := provision.ValidateNvidiaRuntime(c.String("engine-nvidia-runtime")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine nvidia runtime: [%s]", err))
+	}
+
+	if err := provision.ValidateEngineEnv(c.StringSlice("engine-env")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine env: [%s]", err))
+	}
+
+	if err := provision.ValidateEngineMTU(c.Int("engine-mtu"), engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine MTU: [%s]", err))
+	}
+
+	if err := provision.ValidateCgroupDriver(c.String("engine-cgroup-driver"), engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine cgroup driver: [%s]", err))
 	}
-)
 
-func cmdCreate(c CommandLine, api libmachine.API) error {
-	if len(c.Args()) > 1 {
-		return fmt.Errorf("invalid arguments: found extra arguments %v", c.Args()[1:])
+	if err := provision.ValidateDefaultUlimits(c.StringSlice("engine-default-ulimit"), engineOpts); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine default ulimits: [%s]", err))
 	}
 
-	name := c.Args().First()
-	if name == "" {
-		c.ShowHelp()
-		return errNoMachineName
+	if err := provision.ValidateLimitNOFILE(c.String("engine-limit-nofile")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine LimitNOFILE: [%s]", err))
 	}
 
-	if !host.ValidateHostName(name) {
-		return fmt.Errorf("error creating machine: [%s]", mcnerror.ErrInvalidHostname)
+	if err := provision.ValidateNetworkPlugin(c.String("engine-network-plugin")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing engine network plugin: [%s]", err))
 	}
 
-	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
-		return fmt.Errorf("error parsing swarm discovery: [%s]", err)
+	networkPluginScript := c.String("engine-network-plugin-script")
+	if networkPluginScript != "" {
+		absPath, err := filepath.Abs(networkPluginScript)
+		if err != nil {
+			return fmt.Errorf("error reading engine network plugin script: [%s]", err)
+		}
+		networkPluginScript = absPath
+
+		if _, err := os.Stat(networkPluginScript); err != nil {
+			return fmt.Errorf("error reading engine network plugin script: [%s]", err)
+		}
+	}
+
+	preRemoveScript, err := removeScriptPath(c.String("pre-remove-script"))
+	if err != nil {
+		return fmt.Errorf("error reading pre-remove script: [%s]", err)
+	}
+	postRemoveScript, err := removeScriptPath(c.String("post-remove-script"))
+	if err != nil {
+		return fmt.Errorf("error reading post-remove script: [%s]", err)
+	}
+
+	containerdConfigFile := c.String("engine-containerd-config-file")
+	if containerdConfigFile != "" {
+		absPath, err := filepath.Abs(containerdConfigFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine containerd config file: [%s]", err)
+		}
+		containerdConfigFile = absPath
+
+		content, err := os.ReadFile(containerdConfigFile)
+		if err != nil {
+			return fmt.Errorf("error reading engine containerd config file: [%s]", err)
+		}
+
+		if err := provision.ValidateContainerdConfig(string(content)); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing engine containerd config file %s: [%s]", containerdConfigFile, err))
+		}
+	}
+
+	boot2dockerProfileFile := c.String("boot2docker-profile-file")
+	if boot2dockerProfileFile != "" {
+		absPath, err := filepath.Abs(boot2dockerProfileFile)
+		if err != nil {
+			return fmt.Errorf("error reading boot2docker profile file: [%s]", err)
+		}
+		boot2dockerProfileFile = absPath
+
+		if err := provision.ValidateBoot2DockerProfileFile(boot2dockerProfileFile); err != nil {
+			return invalidArguments(fmt.Errorf("error reading boot2docker profile file: [%s]", err))
+		}
+	}
+
+	if _, err := cert.TLSVersion(c.String("tls-min-version")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing TLS min version: [%s]", err))
+	}
+
+	if _, err := cert.TLSCipherSuites(c.StringSlice("tls-cipher-suite")); err != nil {
+		return invalidArguments(fmt.Errorf("error parsing TLS cipher suites: [%s]", err))
+	}
+
+	caName := c.String("tls-ca-name")
+	if caName != "" {
+		if err := validateCAName(caName); err != nil {
+			return invalidArguments(fmt.Errorf("error parsing TLS CA name: [%s]", err))
+		}
+		for _, flag := range []string{"tls-ca-cert", "tls-ca-key", "tls-client-cert", "tls-client-key"} {
+			if c.GlobalString(flag) != "" {
+				return invalidArguments(fmt.Errorf("--tls-ca-name can't be used with --%s", flag))
+			}
+		}
+	}
+
+	certDuration, err := parseCertDuration(c.String("tls-cert-duration"))
+	if err != nil {
+		return invalidArguments(fmt.Errorf("error parsing TLS cert duration: [%s]", err))
+	}
+
+	caDuration, err := parseCertDuration(c.String("tls-ca-duration"))
+	if err != nil {
+		return invalidArguments(fmt.Errorf("error parsing TLS CA duration: [%s]", err))
+	}
+
+	importKey := c.String("ssh-import-key")
+	if importKey != "" {
+		if err := ssh.ValidateSSHKey(importKey); err != nil {
+			return invalidArguments(fmt.Errorf("error importing SSH key: %s", err))
+		}
+		log.Warnf("The SSH key %s will grant access to %s, anyone holding it can log in to the machine", importKey, name)
 	}
 
 	// TODO: Fix hacky JSON solution
 	rawDriver, err := json.Marshal(&drivers.BaseDriver{
-		MachineName: name,
-		StorePath:   c.GlobalString("storage-path"),
+		MachineName:  name,
+		StorePath:    c.GlobalString("storage-path"),
+		SSHPortProbe: c.Bool("ssh-port-probe"),
 	})
 	if err != nil {
 		return fmt.Errorf("error attempting to marshal bare driver data: %s", err)
@@ -177,6 +830,9 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 	if err != nil {
 		return fmt.Errorf("error getting new host: %s", err)
 	}
+	if c.Bool("ssh-agent-forward") {
+		drivers.SetSSHAgentForward(h.Driver, true)
+	}
 
 	h.HostOptions = &host.Options{
 		AuthOptions: &auth.Options{
@@ -188,17 +844,43 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 			ServerCertPath:   filepath.Join(mcndirs.GetMachineDir(), name, "server.pem"),
 			ServerKeyPath:    filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem"),
 			StorePath:        filepath.Join(mcndirs.GetMachineDir(), name),
-			ServerCertSANs:   c.StringSlice("tls-san"),
+			ServerCertSANs:   serverCertSANs(serverCertSANs(serverCertSANs(c.StringSlice("tls-san"), hostname), daemonHostname), externalHost),
+			TLSMinVersion:    c.String("tls-min-version"),
+			TLSCipherSuites:  c.StringSlice("tls-cipher-suite"),
+			CertDuration:     certDuration,
+			CADuration:       caDuration,
 		},
 		EngineOptions: &engine.Options{
-			ArbitraryFlags:   c.StringSlice("engine-opt"),
-			Env:              c.StringSlice("engine-env"),
-			InsecureRegistry: c.StringSlice("engine-insecure-registry"),
-			Labels:           c.StringSlice("engine-label"),
-			RegistryMirror:   c.StringSlice("engine-registry-mirror"),
-			StorageDriver:    c.String("engine-storage-driver"),
-			TLSVerify:        true,
-			InstallURL:       c.String("engine-install-url"),
+			ArbitraryFlags:       c.StringSlice("engine-opt"),
+			DNS:                  dnsServers,
+			DNSSearch:            dnsSearch,
+			Sysctls:              sysctls,
+			TimeZone:             c.String("provision-timezone"),
+			NTPServers:           ntpServers,
+			Env:                  c.StringSlice("engine-env"),
+			InsecureRegistry:     c.StringSlice("engine-insecure-registry"),
+			Labels:               c.StringSlice("engine-label"),
+			RegistryMirror:       c.StringSlice("engine-registry-mirror"),
+			StorageDriver:        c.String("engine-storage-driver"),
+			GraphDir:             c.String("engine-data-root"),
+			MetricsAddr:          c.String("engine-metrics-addr"),
+			TLSVerify:            true,
+			InstallURL:           c.String("engine-install-url"),
+			InstallURLSHA256:     c.String("engine-install-url-sha256"),
+			InstallScriptFile:    installScriptFile,
+			SystemdDropIns:       c.StringSlice("engine-systemd-dropin"),
+			SystemdDropInFile:    dropInFile,
+			FlagFile:             flagFile,
+			Hostname:             hostname,
+			KeepHostname:         c.Bool("no-set-hostname"),
+			PreferIPv6:           c.Bool("prefer-ipv6"),
+			DaemonHostname:       daemonHostname,
+			Rootless:             c.Bool("engine-rootless"),
+			NvidiaRuntime:        c.String("engine-nvidia-runtime"),
+			NetworkPlugin:        c.String("engine-network-plugin"),
+			MTU:                  c.Int("engine-mtu"),
+			NetworkPluginScript:  networkPluginScript,
+			ContainerdConfigFile: containerdConfigFile,
 		},
 		SwarmOptions: &swarm.Options{
 			IsSwarm:            c.Bool("swarm") || c.Bool("swarm-master"),
@@ -214,6 +896,24 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 			IsExperimental:     c.Bool("swarm-experimental"),
 		},
 	}
+	h.HostOptions.EngineOptions.DaemonHostnameNoVerify = daemonHostname != "" && c.Bool("daemon-hostname-no-verify")
+	h.HostOptions.EngineOptions.DaemonUsePrivateIP = c.Bool("daemon-use-private-ip")
+	h.HostOptions.EngineOptions.RegistryCAFiles = registryCAFiles
+	h.HostOptions.EngineOptions.DaemonExternalURL = daemonExternalURL
+	h.HostOptions.EngineOptions.SSHTransport = c.Bool("daemon-ssh-transport")
+	h.HostOptions.EngineOptions.DefaultUlimits = c.StringSlice("engine-default-ulimit")
+	h.HostOptions.EngineOptions.LimitNOFILE = c.String("engine-limit-nofile")
+	h.HostOptions.EngineOptions.Port = c.Int("engine-port")
+	h.HostOptions.EngineOptions.CgroupDriver = c.String("engine-cgroup-driver")
+	h.HostOptions.PreRemoveScript = preRemoveScript
+	h.HostOptions.PostRemoveScript = postRemoveScript
+	h.HostOptions.EngineOptions.PackageMirror = packageMirror
+	h.HostOptions.EngineOptions.PackageMirrorAuth = packageMirrorAuth
+	h.HostOptions.EngineOptions.PackageMirrorEphemeral = c.Bool("provision-mirror-ephemeral")
+	h.HostOptions.EngineOptions.Boot2DockerProfileFile = boot2dockerProfileFile
+	if caName != "" {
+		useNamedCA(h.HostOptions.AuthOptions, caName)
+	}
 
 	exists, err := api.Exists(h.Name)
 	if err != nil {
@@ -233,8 +933,36 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 	userdataFlag := drivers.DriverUserdataFlag(h.Driver)
 	osFlag := drivers.DriverOSFlag(h.Driver)
 
+	if c.Bool("engine-metrics-open-port") && c.String("engine-metrics-addr") != "" {
+		addOpenPort(driverOpts, driverName, c.String("engine-metrics-addr"))
+	}
+
+	if err := setUserDataFile(driverOpts, driverName, userdataFlag, c.String("user-data-file")); err != nil {
+		return err
+	}
+
+	if err := setInstanceMetadata(driverOpts, driverName, c.StringSlice("instance-metadata")); err != nil {
+		return err
+	}
+
+	setEnginePort(driverOpts, driverName, c.Int("engine-port"))
+
+	if c.Bool("encrypt-volumes") {
+		if err := setEncryptVolumes(driverOpts, driverName); err != nil {
+			return err
+		}
+		h.HostOptions.EncryptVolumes = true
+	}
+
+	// Stored before the custom install script is merged into the userdata,
+	// which is done again by clone.
+	h.HostOptions.DriverOptions = copyDriverOptions(driverOpts.Values)
+
 	customInstallScript := c.String("custom-install-script")
 	h.HostOptions.HostnameOverride = c.String("hostname-override")
+	if h.HostOptions.HostnameOverride == "" {
+		h.HostOptions.HostnameOverride = hostname
+	}
 	if customInstallScript != "" {
 		h.HostOptions.CustomInstallScript = customInstallScript
 		h.HostOptions.AuthOptions = nil
@@ -249,10 +977,82 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 		}
 	}
 
+	h.HostOptions.DetectSSHUser = sshUserDetectionEnabled(c, mcnFlags, driverName, c.GlobalString("env-prefix"))
+	h.HostOptions.ExpectIP = c.String("expect-ip")
+	h.HostOptions.NoProvision = c.Bool("no-provision")
+	h.HostOptions.SkipSSHWait = c.Bool("skip-ssh-wait")
+	if c.Bool("wait-cloud-init") {
+		h.HostOptions.WaitCloudInitTimeout = c.Int("wait-cloud-init-timeout")
+	}
+
+	if err := checkHostnameCollisions(api, h, c.Bool("strict-hostnames")); err != nil {
+		return err
+	}
+
+	if err := createHost(api, h, driverOpts, c.Bool("estimate-cost"), importKey); err != nil {
+		return err
+	}
+
+	if customInstallScript == "" {
+		if c.GlobalBool("quiet") {
+			// The URL of the machine is the result scripts look for.
+			url, err := h.ClientURL()
+			if err != nil {
+				return fmt.Errorf("error getting the URL of the machine: %s", err)
+			}
+			fmt.Println(url)
+			return nil
+		}
+
+		log.Infof("to see how to connect your Docker Client to the Docker Engine running on this virtual machine, run: %s env %s", os.Args[0], name)
+	}
+
+	return nil
+}
+
+// validateNoProvision checks that --skip-ssh-wait is only used with
+// --no-provision, which can't be used with a custom install script or
+// --wait-cloud-init.
+func validateNoProvision(c CommandLine) error {
+	if c.Bool("skip-ssh-wait") && !c.Bool("no-provision") {
+		return errors.New("--skip-ssh-wait can only be used with --no-provision")
+	}
+	if c.Bool("no-provision") && c.String("custom-install-script") != "" {
+		return errors.New("--no-provision can't be used with --custom-install-script")
+	}
+	if c.Bool("no-provision") && c.Bool("wait-cloud-init") {
+		return errors.New("--no-provision can't be used with --wait-cloud-init")
+	}
+	if c.Bool("wait-cloud-init") && c.Int("wait-cloud-init-timeout") < 1 {
+		return errors.New("--wait-cloud-init-timeout must be at least 1s")
+	}
+
+	return nil
+}
+
+// createHost configures the driver of a new host, creates its instance and
+// saves it to the store. With estimateCost, the estimated cost of the instance
+// is printed first. With importKey, the private key is copied to the storage
+// dir of the machine, where the driver uses it instead of generating one.
+func createHost(api libmachine.API, h *host.Host, driverOpts *rpcdriver.RPCFlags, estimateCost bool, importKey string) error {
 	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
 		return fmt.Errorf("error setting machine configuration from flags provided: %s", err)
 	}
 
+	if importKey != "" {
+		keyPath := h.Driver.GetSSHKeyPath()
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+			return fmt.Errorf("error importing SSH key: %s", err)
+		}
+		if err := ssh.ImportSSHKey(importKey, keyPath); err != nil {
+			return fmt.Errorf("error importing SSH key: %s", err)
+		}
+	}
+
+	if estimateCost {
+		printCostEstimate(h.Driver)
+	}
+
 	if err := api.Create(h); err != nil {
 		// Wait for all the logs to reach the client
 		time.Sleep(2 * time.Second)
@@ -275,11 +1075,54 @@ func cmdCreate(c CommandLine, api libmachine.API) error {
 		return fmt.Errorf("error attempting to save store: %s", err)
 	}
 
-	if customInstallScript == "" {
-		log.Infof("to see how to connect your Docker Client to the Docker Engine running on this virtual machine, run: %s env %s", os.Args[0], name)
+	return nil
+}
+
+// printCostEstimate prints the estimated cost of the instance of d, or
+// "unknown" when it can't be estimated, which doesn't stop the create.
+func printCostEstimate(d drivers.Driver) {
+	estimate, err := drivers.EstimateCost(d)
+	if err != nil {
+		log.Debugf("Unable to estimate the cost of the instance: %s", err)
+		log.Info("Estimated cost: unknown")
+		return
 	}
 
-	return nil
+	log.Infof("Estimated cost of %s: %.4f %s/hour, %.2f %s/month", estimate.Description, estimate.Hourly, estimate.Currency, estimate.Monthly, estimate.Currency)
+}
+
+// sshUserDetectionEnabled returns true when the driver lets the user choose
+// the SSH user but none was given, either on the command line or through the
+// flag's environment variables.
+func sshUserDetectionEnabled(c CommandLine, mcnflags []mcnflag.Flag, driverName, envPrefix string) bool {
+	name := driverName + "-ssh-user"
+	for _, f := range mcnflags {
+		if f.String() != name {
+			continue
+		}
+
+		if c.IsSet(name) {
+			return false
+		}
+
+		envVar := ""
+		switch t := f.(type) {
+		case *mcnflag.StringFlag:
+			envVar = t.EnvVar
+		case mcnflag.StringFlag:
+			envVar = t.EnvVar
+		}
+
+		for _, name := range driverFlagEnvVars(envPrefix, envVar) {
+			if os.Getenv(name) != "" {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
 }
 
 func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) *rpcdriver.RPCFlags {
@@ -314,7 +1157,51 @@ func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) *rpcdriver.RPCFlags {
 	return &driverOpts
 }
 
-func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
+// driverSecretFlagRE matches the names of the driver flags holding
+// credentials by their suffix, e.g. amazonec2-secret-key, azure-client-secret
+// or digitalocean-access-token, and google-auth-encoded-json, the service
+// account key of google. Names like openstack-auth-url or
+// amazonec2-ssh-keypath don't match.
+var driverSecretFlagRE = regexp.MustCompile(`(?i)(^|-)(secret|password|passwd|token|access-key|secret-key|api-key|apikey)$|^google-auth-encoded-json$`)
+
+// copyDriverOptions returns a copy of the driver flag values to store, so
+// that later changes to the values sent to the driver aren't stored. The
+// flags holding credentials are left out, config.json being plain text.
+func copyDriverOptions(values map[string]interface{}) map[string]interface{} {
+	stored := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if driverSecretFlagRE.MatchString(name) {
+			continue
+		}
+		stored[name] = value
+	}
+
+	return stored
+}
+
+// driverFlagEnvVars returns the environment variables a driver flag is read
+// from, in order of precedence. With an env prefix, the prefixed variable
+// comes before the variable of the driver.
+func driverFlagEnvVars(envPrefix, envVar string) []string {
+	if envVar == "" {
+		return nil
+	}
+
+	if envPrefix == "" {
+		return []string{envVar}
+	}
+
+	return []string{envPrefix + envVar, envVar}
+}
+
+// convertMcnFlagsToCliFlags converts driver flags to CLI flags. The flags are
+// read from the environment variables given by driverFlagEnvVars when they
+// are not on the command line.
+func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag, envPrefix string) ([]cli.Flag, error) {
+	envVar := func(name string) string {
+		return strings.Join(driverFlagEnvVars(envPrefix, name), ",")
+	}
+
 	cliFlags := []cli.Flag{}
 	for _, f := range mcnFlags {
 		switch t := f.(type) {
@@ -325,14 +1212,14 @@ func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 			f := f.(*mcnflag.BoolFlag)
 			cliFlags = append(cliFlags, cli.BoolFlag{
 				Name:   f.Name,
-				EnvVar: f.EnvVar,
+				EnvVar: envVar(f.EnvVar),
 				Usage:  f.Usage,
 			})
 		case *mcnflag.IntFlag:
 			f := f.(*mcnflag.IntFlag)
 			cliFlags = append(cliFlags, cli.IntFlag{
 				Name:   f.Name,
-				EnvVar: f.EnvVar,
+				EnvVar: envVar(f.EnvVar),
 				Usage:  f.Usage,
 				Value:  f.Value,
 			})
@@ -340,7 +1227,7 @@ func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 			f := f.(*mcnflag.StringFlag)
 			cliFlags = append(cliFlags, cli.StringFlag{
 				Name:   f.Name,
-				EnvVar: f.EnvVar,
+				EnvVar: envVar(f.EnvVar),
 				Usage:  f.Usage,
 				Value:  f.Value,
 			})
@@ -348,7 +1235,7 @@ func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 			f := f.(*mcnflag.StringSliceFlag)
 			cliFlags = append(cliFlags, cli.StringSliceFlag{
 				Name:   f.Name,
-				EnvVar: f.EnvVar,
+				EnvVar: envVar(f.EnvVar),
 				Usage:  f.Usage,
 
 				// TODO: Is this used with defaults? Can we convert the literal []string to cli.StringSlice properly?
@@ -380,6 +1267,253 @@ func validateSwarmDiscovery(discovery string) error {
 	return fmt.Errorf("[validateSwarmDiscovery] swarm Discovery URL was in the wrong format: %s", discovery)
 }
 
+// validateEngineDataRoot checks the data root is an absolute path on the host,
+// and that it isn't also set in the daemon flags, which the daemon refuses
+// with daemon.json. It ends up in shell commands and in a systemd unit, so
+// whitespace and quotes are refused.
+func validateEngineDataRoot(dataRoot string, engineOpts []string) error {
+	if dataRoot == "" {
+		return nil
+	}
+
+	if !path.IsAbs(dataRoot) {
+		return fmt.Errorf("%s is not an absolute path", dataRoot)
+	}
+
+	if path.Clean(dataRoot) == "/" {
+		return errors.New("the data root can't be /")
+	}
+
+	if strings.ContainsAny(dataRoot, " \t\n'\"\\$`") {
+		return fmt.Errorf("%q contains unsupported characters", dataRoot)
+	}
+
+	for _, opt := range engineOpts {
+		if name, _, _ := strings.Cut(opt, "="); name == "data-root" || name == "graph" {
+			return fmt.Errorf("the data root can't be set with both --engine-data-root and the daemon flag --%s", opt)
+		}
+	}
+
+	return nil
+}
+
+// validateEngineMetricsAddr checks the metrics address is a host:port.
+func validateEngineMetricsAddr(addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// addOpenPort adds the port of addr to the ports opened by the driver's
+// <driver>-open-port flag, for the drivers managing a firewall.
+func addOpenPort(driverOpts *rpcdriver.RPCFlags, driverName, addr string) {
+	name := driverName + "-open-port"
+	value, ok := driverOpts.Values[name]
+	if !ok {
+		log.Warnf("The %s driver can't open ports, the port of %s has to be opened manually", driverName, addr)
+		return
+	}
+
+	_, port, _ := net.SplitHostPort(addr)
+	ports, _ := value.([]string)
+	driverOpts.Values[name] = append(ports, port+"/tcp")
+}
+
+// setUserDataFile sets the user-data flag of the driver to the file given
+// with --user-data-file. The hostname and custom install script setup is
+// merged into the file afterwards, like with the flag of the driver.
+func setUserDataFile(driverOpts *rpcdriver.RPCFlags, driverName, userdataFlag, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if userdataFlag == "" {
+		return fmt.Errorf("the %s driver does not support --user-data-file", driverName)
+	}
+
+	if current := driverOpts.String(userdataFlag); current != "" && current != path {
+		return fmt.Errorf("--user-data-file and --%s can't be used together", userdataFlag)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("unable to read user data file %s: %v", path, err)
+	}
+
+	driverOpts.Values[userdataFlag] = path
+
+	return nil
+}
+
+// setInstanceMetadata adds the key=value entries given with
+// --instance-metadata to the metadata flag of the driver, the instance
+// metadata of google and the tags of azure.
+func setInstanceMetadata(driverOpts *rpcdriver.RPCFlags, driverName string, entries []string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if key, _, ok := strings.Cut(entry, "="); !ok || key == "" {
+			return fmt.Errorf("invalid instance metadata %q, must be key=value", entry)
+		}
+	}
+
+	switch driverName {
+	case "google":
+		metadata, _ := driverOpts.Values["google-metadata"].([]string)
+		driverOpts.Values["google-metadata"] = append(metadata, entries...)
+	case "azure":
+		// The azure tags are a comma separated list of keys and values.
+		tags := []string{}
+		if current := driverOpts.String("azure-tags"); current != "" {
+			tags = append(tags, current)
+		}
+		for _, entry := range entries {
+			if strings.Contains(entry, ",") {
+				return fmt.Errorf("invalid instance metadata %q, the azure tags can't contain commas", entry)
+			}
+			key, value, _ := strings.Cut(entry, "=")
+			tags = append(tags, key, value)
+		}
+		driverOpts.Values["azure-tags"] = strings.Join(tags, ",")
+	default:
+		return fmt.Errorf("the %s driver does not support --instance-metadata", driverName)
+	}
+
+	return nil
+}
+
+// setEnginePort sets the driver flags opening the engine port of
+// --engine-port in the firewall of the instance, or setting the port of the
+// URL of the driver.
+func setEnginePort(driverOpts *rpcdriver.RPCFlags, driverName string, port int) {
+	if port == 0 {
+		return
+	}
+
+	switch driverName {
+	case "amazonec2", "google":
+		flag := driverName + "-open-port"
+		openPorts, _ := driverOpts.Values[flag].([]string)
+		driverOpts.Values[flag] = append(openPorts, fmt.Sprintf("%d/tcp", port))
+	case "azure":
+		driverOpts.Values["azure-docker-port"] = port
+	case "generic":
+		driverOpts.Values["generic-engine-port"] = port
+	case "vmwarevcloudair":
+		driverOpts.Values["vmwarevcloudair-docker-port"] = port
+	}
+}
+
+// setEncryptVolumes sets the driver flags encrypting the disks of the
+// instance for --encrypt-volumes. Amazon EC2 encrypts them with the AWS
+// managed key unless --amazonec2-kms-key is given, google and azure require
+// the key to be given.
+func setEncryptVolumes(driverOpts *rpcdriver.RPCFlags, driverName string) error {
+	switch driverName {
+	case "amazonec2":
+		driverOpts.Values["amazonec2-encrypt-ebs-volume"] = true
+	case "google":
+		if driverOpts.String("google-kms-key") == "" {
+			return errors.New("--encrypt-volumes requires --google-kms-key with the google driver")
+		}
+	case "azure":
+		if driverOpts.String("azure-disk-encryption-set") == "" {
+			return errors.New("--encrypt-volumes requires --azure-disk-encryption-set with the azure driver")
+		}
+		driverOpts.Values["azure-managed-disks"] = true
+	default:
+		return fmt.Errorf("the %s driver does not support --encrypt-volumes", driverName)
+	}
+
+	return nil
+}
+
+// lookupHost resolves the daemon hostname, replaced in tests.
+var lookupHost = net.LookupHost
+
+// validateDaemonHostname checks that the daemon hostname is a valid DNS name
+// which resolves, unless noVerify is set.
+func validateDaemonHostname(hostname string, noVerify bool) error {
+	if err := provision.ValidateHostname(hostname); err != nil {
+		return err
+	}
+
+	if noVerify {
+		return nil
+	}
+
+	if _, err := lookupHost(hostname); err != nil {
+		return fmt.Errorf("%s doesn't resolve, use --daemon-hostname-no-verify to skip this check: %s", hostname, err)
+	}
+
+	return nil
+}
+
+// serverCertSANs adds a custom hostname of the machine to the SANs of its
+// server cert.
+func serverCertSANs(sans []string, hostname string) []string {
+	if hostname == "" {
+		return sans
+	}
+
+	for _, san := range sans {
+		if san == hostname {
+			return sans
+		}
+	}
+
+	return append(sans, hostname)
+}
+
+// daemonExternalHost checks the external URL of the daemon, a tcp://host:port
+// URL, and returns its host added to the SANs of the server cert.
+func daemonExternalHost(externalURL string) (string, error) {
+	if externalURL == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(externalURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "tcp" || u.Hostname() == "" || u.Port() == "" || (u.Path != "" && u.Path != "/") {
+		return "", fmt.Errorf("invalid URL %q, must be tcp://host:port", externalURL)
+	}
+
+	return u.Hostname(), nil
+}
+
+// parseCertDuration parses the validity of a generated cert. An empty value
+// returns 0, which keeps the default validity.
+func parseCertDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%s is not a positive duration", value)
+	}
+
+	return duration, nil
+}
+
 func tlsPath(c CommandLine, flag string, defaultName string) string {
 	path := c.GlobalString(flag)
 	if path != "" {
@@ -389,6 +1523,28 @@ func tlsPath(c CommandLine, flag string, defaultName string) string {
 	return filepath.Join(mcndirs.GetMachineCertDir(), defaultName)
 }
 
+var validCANamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+func validateCAName(name string) error {
+	if !validCANamePattern.MatchString(name) {
+		return fmt.Errorf("invalid CA name %q, must only contain letters, digits, '_' and '-'", name)
+	}
+	return nil
+}
+
+// useNamedCA makes the machine use the CA and the client cert of the named
+// CA, kept in their own directory of the cert dir.
+func useNamedCA(authOptions *auth.Options, name string) {
+	dir := filepath.Join(mcndirs.GetMachineCertDir(), name)
+
+	authOptions.CAName = name
+	authOptions.CertDir = dir
+	authOptions.CaCertPath = filepath.Join(dir, "ca.pem")
+	authOptions.CaPrivateKeyPath = filepath.Join(dir, "ca-key.pem")
+	authOptions.ClientCertPath = filepath.Join(dir, "cert.pem")
+	authOptions.ClientKeyPath = filepath.Join(dir, "key.pem")
+}
+
 func gzipEncode(data []byte) (string, error) {
 	var b bytes.Buffer
 	gz := gzip.NewWriter(&b)
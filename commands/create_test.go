@@ -1,13 +1,21 @@
 package commands
 
 import (
+	"errors"
 	"testing"
 
 	"flag"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/commands/mcndirs"
+	"github.com/rancher/machine/libmachine/auth"
+	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
 )
 
 func TestValidateSwarmDiscoveryErrorsGivenInvalidURL(t *testing.T) {
@@ -25,6 +33,137 @@ func TestValidateSwarmDiscoveryAcceptsValidFormat(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidateEngineDataRoot(t *testing.T) {
+	assert.NoError(t, validateEngineDataRoot("", nil))
+	assert.NoError(t, validateEngineDataRoot("/mnt/docker", nil))
+	assert.Error(t, validateEngineDataRoot("mnt/docker", nil))
+	assert.Error(t, validateEngineDataRoot("/", nil))
+	assert.Error(t, validateEngineDataRoot("/mnt/my docker", nil))
+	assert.NoError(t, validateEngineDataRoot("/mnt/docker", []string{"mtu=1400"}))
+	assert.Error(t, validateEngineDataRoot("/mnt/docker", []string{"data-root=/srv/docker"}))
+}
+
+func TestValidateEngineMetricsAddr(t *testing.T) {
+	assert.NoError(t, validateEngineMetricsAddr(""))
+	assert.NoError(t, validateEngineMetricsAddr("0.0.0.0:9323"))
+	assert.Error(t, validateEngineMetricsAddr("9323"))
+	assert.Error(t, validateEngineMetricsAddr("0.0.0.0:metrics"))
+}
+
+func TestServerCertSANs(t *testing.T) {
+	assert.Equal(t, []string{"a.example.com"}, serverCertSANs([]string{"a.example.com"}, ""))
+	assert.Equal(t, []string{"a.example.com", "web-1"}, serverCertSANs([]string{"a.example.com"}, "web-1"))
+	assert.Equal(t, []string{"web-1"}, serverCertSANs([]string{"web-1"}, "web-1"))
+}
+
+func TestAddOpenPort(t *testing.T) {
+	driverOpts := &rpcdriver.RPCFlags{
+		Values: map[string]interface{}{
+			"amazonec2-open-port": []string{"8080"},
+		},
+	}
+
+	addOpenPort(driverOpts, "amazonec2", "0.0.0.0:9323")
+	assert.Equal(t, []string{"8080", "9323/tcp"}, driverOpts.Values["amazonec2-open-port"])
+
+	addOpenPort(driverOpts, "digitalocean", "0.0.0.0:9323")
+	assert.NotContains(t, driverOpts.Values, "digitalocean-open-port")
+}
+
+func TestDaemonExternalHost(t *testing.T) {
+	host, err := daemonExternalHost("tcp://lb.example.com:2376")
+	assert.NoError(t, err)
+	assert.Equal(t, "lb.example.com", host)
+
+	host, err = daemonExternalHost("tcp://[fd00::1]:2376")
+	assert.NoError(t, err)
+	assert.Equal(t, "fd00::1", host)
+
+	host, err = daemonExternalHost("")
+	assert.NoError(t, err)
+	assert.Empty(t, host)
+
+	for _, externalURL := range []string{"https://lb.example.com:2376", "tcp://lb.example.com", "tcp://lb.example.com:2376/path"} {
+		_, err = daemonExternalHost(externalURL)
+		assert.EqualError(t, err, `invalid URL "`+externalURL+`", must be tcp://host:port`)
+	}
+}
+
+func TestSetUserDataFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user-data")
+	assert.NoError(t, os.WriteFile(path, []byte("#cloud-config\n"), 0600))
+
+	driverOpts := &rpcdriver.RPCFlags{
+		Values: map[string]interface{}{
+			"amazonec2-userdata": "",
+		},
+	}
+
+	assert.NoError(t, setUserDataFile(driverOpts, "amazonec2", "amazonec2-userdata", path))
+	assert.Equal(t, path, driverOpts.Values["amazonec2-userdata"])
+
+	assert.EqualError(t, setUserDataFile(driverOpts, "amazonec2", "amazonec2-userdata", path+"-other"), "--user-data-file and --amazonec2-userdata can't be used together")
+	assert.EqualError(t, setUserDataFile(driverOpts, "virtualbox", "", path), "the virtualbox driver does not support --user-data-file")
+	assert.NoError(t, setUserDataFile(driverOpts, "virtualbox", "", ""))
+}
+
+func TestSetInstanceMetadata(t *testing.T) {
+	driverOpts := &rpcdriver.RPCFlags{
+		Values: map[string]interface{}{
+			"google-metadata": []string{"a=1"},
+			"azure-tags":      "env,prod",
+		},
+	}
+
+	assert.NoError(t, setInstanceMetadata(driverOpts, "google", []string{"b=2"}))
+	assert.Equal(t, []string{"a=1", "b=2"}, driverOpts.Values["google-metadata"])
+
+	assert.NoError(t, setInstanceMetadata(driverOpts, "azure", []string{"team=ops"}))
+	assert.Equal(t, "env,prod,team,ops", driverOpts.Values["azure-tags"])
+
+	assert.EqualError(t, setInstanceMetadata(driverOpts, "azure", []string{"a=b,c"}), `invalid instance metadata "a=b,c", the azure tags can't contain commas`)
+	assert.EqualError(t, setInstanceMetadata(driverOpts, "google", []string{"novalue"}), `invalid instance metadata "novalue", must be key=value`)
+	assert.EqualError(t, setInstanceMetadata(driverOpts, "amazonec2", []string{"a=b"}), "the amazonec2 driver does not support --instance-metadata")
+}
+
+func TestSetEnginePort(t *testing.T) {
+	driverOpts := &rpcdriver.RPCFlags{
+		Values: map[string]interface{}{
+			"google-open-port": []string{"80/tcp"},
+		},
+	}
+
+	setEnginePort(driverOpts, "google", 0)
+	assert.Equal(t, []string{"80/tcp"}, driverOpts.Values["google-open-port"])
+
+	setEnginePort(driverOpts, "google", 12376)
+	assert.Equal(t, []string{"80/tcp", "12376/tcp"}, driverOpts.Values["google-open-port"])
+
+	setEnginePort(driverOpts, "amazonec2", 12376)
+	assert.Equal(t, []string{"12376/tcp"}, driverOpts.Values["amazonec2-open-port"])
+
+	setEnginePort(driverOpts, "azure", 12376)
+	assert.Equal(t, 12376, driverOpts.Values["azure-docker-port"])
+}
+
+func TestSetEncryptVolumes(t *testing.T) {
+	driverOpts := &rpcdriver.RPCFlags{Values: map[string]interface{}{}}
+
+	assert.NoError(t, setEncryptVolumes(driverOpts, "amazonec2"))
+	assert.Equal(t, true, driverOpts.Values["amazonec2-encrypt-ebs-volume"])
+
+	assert.EqualError(t, setEncryptVolumes(driverOpts, "google"), "--encrypt-volumes requires --google-kms-key with the google driver")
+	driverOpts.Values["google-kms-key"] = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	assert.NoError(t, setEncryptVolumes(driverOpts, "google"))
+
+	assert.EqualError(t, setEncryptVolumes(driverOpts, "azure"), "--encrypt-volumes requires --azure-disk-encryption-set with the azure driver")
+	driverOpts.Values["azure-disk-encryption-set"] = "/subscriptions/s/resourceGroups/g/providers/Microsoft.Compute/diskEncryptionSets/des"
+	assert.NoError(t, setEncryptVolumes(driverOpts, "azure"))
+	assert.Equal(t, true, driverOpts.Values["azure-managed-disks"])
+
+	assert.EqualError(t, setEncryptVolumes(driverOpts, "virtualbox"), "the virtualbox driver does not support --encrypt-volumes")
+}
+
 type fakeFlagGetter struct {
 	flag.Value
 	value interface{}
@@ -118,3 +257,128 @@ func TestGetDriverOpts(t *testing.T) {
 		assert.Equal(t, tt.expected["stringslice_defaulted"], driverOpts.StringSlice("stringslice_defaulted"))
 	}
 }
+
+func TestSSHUserDetectionEnabled(t *testing.T) {
+	flags := []mcnflag.Flag{
+		&mcnflag.StringFlag{
+			Name:   "amazonec2-ssh-user",
+			EnvVar: "AWS_SSH_USER_TEST",
+			Value:  "ubuntu",
+		},
+	}
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+	assert.True(t, sshUserDetectionEnabled(commandLine, flags, "amazonec2", ""))
+	assert.False(t, sshUserDetectionEnabled(commandLine, flags, "google", ""))
+
+	os.Setenv("AWS_SSH_USER_TEST", "admin")
+	assert.False(t, sshUserDetectionEnabled(commandLine, flags, "amazonec2", ""))
+	os.Unsetenv("AWS_SSH_USER_TEST")
+
+	os.Setenv("MYAPP_AWS_SSH_USER_TEST", "admin")
+	assert.True(t, sshUserDetectionEnabled(commandLine, flags, "amazonec2", ""))
+	assert.False(t, sshUserDetectionEnabled(commandLine, flags, "amazonec2", "MYAPP_"))
+	os.Unsetenv("MYAPP_AWS_SSH_USER_TEST")
+
+	commandLine.LocalFlags.Data["amazonec2-ssh-user"] = "admin"
+	assert.False(t, sshUserDetectionEnabled(commandLine, flags, "amazonec2", ""))
+}
+
+func TestConvertMcnFlagsToCliFlagsEnvPrefix(t *testing.T) {
+	mcnFlags := []mcnflag.Flag{
+		&mcnflag.StringFlag{Name: "amazonec2-region", EnvVar: "AWS_DEFAULT_REGION"},
+		&mcnflag.BoolFlag{Name: "amazonec2-private-address-only"},
+	}
+
+	cliFlags, err := convertMcnFlagsToCliFlags(mcnFlags, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "AWS_DEFAULT_REGION", cliFlags[0].(cli.StringFlag).EnvVar)
+
+	cliFlags, err = convertMcnFlagsToCliFlags(mcnFlags, "MYAPP_")
+	assert.NoError(t, err)
+	assert.Equal(t, "MYAPP_AWS_DEFAULT_REGION,AWS_DEFAULT_REGION", cliFlags[0].(cli.StringFlag).EnvVar)
+	assert.Equal(t, "", cliFlags[1].(cli.BoolFlag).EnvVar)
+}
+
+func TestParseCertDuration(t *testing.T) {
+	duration, err := parseCertDuration("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), duration)
+
+	duration, err = parseCertDuration("2160h")
+	assert.NoError(t, err)
+	assert.Equal(t, 2160*time.Hour, duration)
+
+	_, err = parseCertDuration("90d")
+	assert.Error(t, err)
+
+	_, err = parseCertDuration("-1h")
+	assert.Error(t, err)
+}
+
+func TestValidateCAName(t *testing.T) {
+	assert.NoError(t, validateCAName("staging"))
+	assert.NoError(t, validateCAName("prod_eu-1"))
+	assert.Error(t, validateCAName("../prod"))
+	assert.Error(t, validateCAName("ca.pem"))
+	assert.Error(t, validateCAName("-prod"))
+}
+
+func TestUseNamedCA(t *testing.T) {
+	authOptions := &auth.Options{}
+
+	useNamedCA(authOptions, "staging")
+
+	dir := filepath.Join(mcndirs.GetMachineCertDir(), "staging")
+	assert.Equal(t, "staging", authOptions.CAName)
+	assert.Equal(t, dir, authOptions.CertDir)
+	assert.Equal(t, filepath.Join(dir, "ca.pem"), authOptions.CaCertPath)
+	assert.Equal(t, filepath.Join(dir, "ca-key.pem"), authOptions.CaPrivateKeyPath)
+	assert.Equal(t, filepath.Join(dir, "cert.pem"), authOptions.ClientCertPath)
+	assert.Equal(t, filepath.Join(dir, "key.pem"), authOptions.ClientKeyPath)
+}
+
+func TestValidateDaemonHostname(t *testing.T) {
+	defer func(f func(string) ([]string, error)) { lookupHost = f }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host == "foo.example.com" {
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	assert.NoError(t, validateDaemonHostname("foo.example.com", false))
+	assert.Error(t, validateDaemonHostname("bar.example.com", false))
+	assert.NoError(t, validateDaemonHostname("bar.example.com", true))
+	assert.Error(t, validateDaemonHostname("-foo.example.com", true))
+}
+
+func TestValidateNoProvision(t *testing.T) {
+	for _, tt := range []struct {
+		data        map[string]interface{}
+		expectedErr string
+	}{
+		{map[string]interface{}{"no-provision": true, "skip-ssh-wait": true}, ""},
+		{map[string]interface{}{"skip-ssh-wait": true}, "--skip-ssh-wait can only be used with --no-provision"},
+		{map[string]interface{}{"no-provision": true, "custom-install-script": "install.sh"}, "--no-provision can't be used with --custom-install-script"},
+		{map[string]interface{}{"wait-cloud-init": true, "wait-cloud-init-timeout": 600}, ""},
+		{map[string]interface{}{"no-provision": true, "wait-cloud-init": true, "wait-cloud-init-timeout": 600}, "--no-provision can't be used with --wait-cloud-init"},
+		{map[string]interface{}{"wait-cloud-init": true, "wait-cloud-init-timeout": 0}, "--wait-cloud-init-timeout must be at least 1s"},
+	} {
+		commandLine := &commandstest.FakeCommandLine{
+			LocalFlags: &commandstest.FakeFlagger{Data: tt.data},
+		}
+
+		err := validateNoProvision(commandLine)
+
+		if tt.expectedErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.expectedErr)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+func cmdDiff(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		return ErrExpectedOneMachine
+	}
+
+	target, err := targetHost(c, api)
+	if err != nil {
+		return err
+	}
+
+	h, err := api.Load(target)
+	if err != nil {
+		return err
+	}
+
+	stored, err := drivers.StoredAttributes(h.Driver)
+	if errors.Is(err, mcnerror.ErrNotSupported) {
+		return fmt.Errorf("the %s driver doesn't support comparing the machine with its instance", h.DriverName)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading the stored attributes of %s: %s", h.Name, err)
+	}
+
+	live, err := drivers.Fetch(h.Driver)
+	if err != nil {
+		return fmt.Errorf("error fetching the instance of %s: %s", h.Name, err)
+	}
+
+	return writeDiff(os.Stdout, h.Name, diffAttributes(stored, live))
+}
+
+func writeDiff(w io.Writer, name string, differences []string) error {
+	if len(differences) == 0 {
+		_, err := fmt.Fprintf(w, "The instance of %s matches its stored config\n", name)
+		return err
+	}
+
+	for _, difference := range differences {
+		if _, err := fmt.Fprintln(w, difference); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffAttributes returns the differences between the attributes stored in the
+// config of a machine and the ones of its instance, one per line.
+func diffAttributes(stored, live *drivers.InstanceAttributes) []string {
+	differences := []string{}
+
+	if stored.InstanceType != "" && stored.InstanceType != live.InstanceType {
+		differences = append(differences, fmt.Sprintf("instance type: stored %s, instance %s", orNone(stored.InstanceType), orNone(live.InstanceType)))
+	}
+
+	if stored.IP != "" && stored.IP != live.IP {
+		differences = append(differences, fmt.Sprintf("IP: stored %s, instance %s", orNone(stored.IP), orNone(live.IP)))
+	}
+
+	keys := map[string]bool{}
+	for key := range stored.Tags {
+		keys[key] = true
+	}
+	for key := range live.Tags {
+		keys[key] = true
+	}
+	sortedKeys := []string{}
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		storedValue, inStored := stored.Tags[key]
+		liveValue, inLive := live.Tags[key]
+		switch {
+		case !inLive:
+			differences = append(differences, fmt.Sprintf("tag %s: removed from the instance", key))
+		case !inStored:
+			differences = append(differences, fmt.Sprintf("tag %s: added to the instance with value %q", key, liveValue))
+		case storedValue != liveValue:
+			differences = append(differences, fmt.Sprintf("tag %s: stored %q, instance %q", key, storedValue, liveValue))
+		}
+	}
+
+	open := map[string]bool{}
+	for _, port := range live.OpenPorts {
+		open[port] = true
+	}
+	closed := []string{}
+	for _, port := range stored.OpenPorts {
+		if !open[port] {
+			closed = append(closed, port)
+		}
+	}
+	if len(closed) > 0 {
+		differences = append(differences, fmt.Sprintf("open ports: %s no longer open", strings.Join(closed, ", ")))
+	}
+
+	return differences
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffAttributes(t *testing.T) {
+	stored := &drivers.InstanceAttributes{
+		InstanceType: "t3.small",
+		IP:           "1.2.3.4",
+		Tags:         map[string]string{"Name": "foo", "env": "prod", "team": "web"},
+		OpenPorts:    []string{"8080", "9000/udp"},
+	}
+	live := &drivers.InstanceAttributes{
+		InstanceType: "t3.large",
+		IP:           "1.2.3.4",
+		Tags:         map[string]string{"Name": "foo", "env": "staging", "owner": "ops"},
+		OpenPorts:    []string{"9000/udp"},
+	}
+
+	assert.Equal(t, []string{
+		"instance type: stored t3.small, instance t3.large",
+		`tag env: stored "prod", instance "staging"`,
+		`tag owner: added to the instance with value "ops"`,
+		"tag team: removed from the instance",
+		"open ports: 8080 no longer open",
+	}, diffAttributes(stored, live))
+
+	assert.Empty(t, diffAttributes(stored, stored))
+}
+
+func TestWriteDiff(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, writeDiff(&out, "foo", nil))
+	assert.Equal(t, "The instance of foo matches its stored config\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, writeDiff(&out, "foo", []string{"IP: stored 1.2.3.4, instance <none>"}))
+	assert.Equal(t, "IP: stored 1.2.3.4, instance <none>\n", out.String())
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const swarmDiscoveryCheckAttempts = 3
+
+var (
+	swarmDiscoveryCheckTimeout = 5 * time.Second
+	swarmDiscoveryCheckDelay   = 2 * time.Second
+)
+
+// swarmDiscoveryProbes are the HTTP endpoints probed for the discovery
+// backends that can be, keyed by URL scheme.
+var swarmDiscoveryProbes = map[string]string{
+	"consul": "/v1/status/leader",
+	"etcd":   "/version",
+}
+
+// checkSwarmDiscovery probes the swarm discovery backend, so that a wrong or
+// unavailable backend fails the creation before any machine is created. The
+// backends that can't be probed are not checked. Each host of the backend is
+// tried a few times, the check passes when one of them answers.
+func checkSwarmDiscovery(discovery string) error {
+	if discovery == "" {
+		return nil
+	}
+
+	u, err := url.Parse(discovery)
+	if err != nil {
+		return err
+	}
+
+	probe, ok := swarmDiscoveryProbes[u.Scheme]
+	if !ok {
+		log.Infof("Skipping the check of the swarm discovery, %s backends can't be probed", u.Scheme)
+		return nil
+	}
+
+	client := &http.Client{Timeout: swarmDiscoveryCheckTimeout}
+
+	errs := []string{}
+	for _, host := range strings.Split(u.Host, ",") {
+		endpoint := fmt.Sprintf("http://%s%s", host, probe)
+
+		var lastErr error
+		for attempt := 1; attempt <= swarmDiscoveryCheckAttempts; attempt++ {
+			if lastErr = probeSwarmDiscovery(client, endpoint); lastErr == nil {
+				log.Debugf("Swarm discovery %s is reachable", endpoint)
+				return nil
+			}
+
+			log.Debugf("Swarm discovery %s is not reachable (attempt %d/%d): %s", endpoint, attempt, swarmDiscoveryCheckAttempts, lastErr)
+			if attempt < swarmDiscoveryCheckAttempts {
+				time.Sleep(swarmDiscoveryCheckDelay)
+			}
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %s", host, lastErr))
+	}
+
+	return fmt.Errorf("the %s discovery backend is not reachable after %d attempts per host: %s", u.Scheme, swarmDiscoveryCheckAttempts, strings.Join(errs, "; "))
+}
+
+func probeSwarmDiscovery(client *http.Client, endpoint string) error {
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+
+	return nil
+}
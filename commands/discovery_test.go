@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSwarmDiscovery(t *testing.T) {
+	swarmDiscoveryCheckDelay = time.Millisecond
+	defer func() { swarmDiscoveryCheckDelay = 2 * time.Second }()
+
+	probed := []string{}
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed = append(probed, r.URL.Path)
+	}))
+	defer up.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	upHost := strings.TrimPrefix(up.URL, "http://")
+	downHost := strings.TrimPrefix(down.URL, "http://")
+
+	assert.NoError(t, checkSwarmDiscovery(""))
+	assert.NoError(t, checkSwarmDiscovery("token://abc"))
+	assert.NoError(t, checkSwarmDiscovery("consul://"+upHost+"/swarm"))
+	assert.NoError(t, checkSwarmDiscovery("etcd://"+downHost+","+upHost+"/swarm"), "one reachable host is enough")
+	assert.Equal(t, []string{"/v1/status/leader", "/version"}, probed)
+
+	err := checkSwarmDiscovery("consul://" + downHost + "/swarm")
+	assert.EqualError(t, err, "the consul discovery backend is not reachable after 3 attempts per host: "+downHost+": http://"+downHost+"/v1/status/leader returned 503 Service Unavailable")
+}
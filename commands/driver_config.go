@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"github.com/rancher/machine/libmachine/host"
+)
+
+const redactedDriverOption = "<REDACTED>"
+
+// driverConfig returns the driver flag values the host was created with, as
+// exposed to the ls and inspect templates under DriverConfig. The keys are
+// the flag names of the driver, e.g. amazonec2-region or google-zone, as
+// listed by create --driver <driver> --help. The flags holding credentials
+// aren't stored, and are redacted if a config.json still has them. The map is
+// empty for the hosts created before the driver options were stored.
+func driverConfig(h *host.Host) map[string]interface{} {
+	config := map[string]interface{}{}
+	if h.HostOptions == nil {
+		return config
+	}
+
+	for name, value := range h.HostOptions.DriverOptions {
+		if s, ok := value.(string); ok && s != "" && driverSecretFlagRE.MatchString(name) {
+			value = redactedDriverOption
+		}
+		config[name] = value
+	}
+
+	return config
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriverConfig(t *testing.T) {
+	h := &host.Host{
+		Name: "foo",
+		HostOptions: &host.Options{
+			DriverOptions: map[string]interface{}{
+				"amazonec2-region":        "eu-west-1",
+				"amazonec2-root-size":     float64(16),
+				"amazonec2-secret-key":    "s3cr3t",
+				"amazonec2-access-key":    "AKIA",
+				"amazonec2-session-token": "",
+				"amazonec2-ssh-keypath":   "/keys/id_rsa",
+				"vsphere-password":        "hunter2",
+				"exoscale-api-key":        "EXO",
+			},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"amazonec2-region":        "eu-west-1",
+		"amazonec2-root-size":     float64(16),
+		"amazonec2-secret-key":    redactedDriverOption,
+		"amazonec2-access-key":    redactedDriverOption,
+		"amazonec2-session-token": "",
+		"amazonec2-ssh-keypath":   "/keys/id_rsa",
+		"vsphere-password":        redactedDriverOption,
+		"exoscale-api-key":        redactedDriverOption,
+	}, driverConfig(h))
+	assert.Equal(t, "s3cr3t", h.HostOptions.DriverOptions["amazonec2-secret-key"])
+}
+
+func TestDriverConfigNotStored(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{}, driverConfig(&host.Host{Name: "foo"}))
+	assert.Equal(t, map[string]interface{}{}, driverConfig(&host.Host{Name: "foo", HostOptions: &host.Options{}}))
+}
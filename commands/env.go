@@ -1,27 +1,42 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/rancher/machine/commands/mcndirs"
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/cert"
 	"github.com/rancher/machine/libmachine/check"
+	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
 	"github.com/rancher/machine/libmachine/shell"
 )
 
 const (
-	envTmpl = `{{ .Prefix }}DOCKER_TLS_VERIFY{{ .Delimiter }}{{ .DockerTLSVerify }}{{ .Suffix }}{{ .Prefix }}DOCKER_HOST{{ .Delimiter }}{{ .DockerHost }}{{ .Suffix }}{{ .Prefix }}DOCKER_CERT_PATH{{ .Delimiter }}{{ .DockerCertPath }}{{ .Suffix }}{{ .Prefix }}DOCKER_MACHINE_NAME{{ .Delimiter }}{{ .MachineName }}{{ .Suffix }}{{ if .ComposePathsVar }}{{ .Prefix }}COMPOSE_CONVERT_WINDOWS_PATHS{{ .Delimiter }}true{{ .Suffix }}{{end}}{{ if .NoProxyVar }}{{ .Prefix }}{{ .NoProxyVar }}{{ .Delimiter }}{{ .NoProxyValue }}{{ .Suffix }}{{end}}{{ .UsageHint }}`
+	envCacheFingerprintKey = "DOCKER_MACHINE_CERT_FINGERPRINT"
+	envCacheURLKey         = "DOCKER_MACHINE_URL"
+
+	envAllParallel = 10
+	envSelectVar   = "MACHINE_ENV_SELECT"
+
+	envTmpl = `{{ if not .SSHTransport }}{{ .Prefix }}DOCKER_TLS_VERIFY{{ .Delimiter }}{{ .DockerTLSVerify }}{{ .Suffix }}{{end}}{{ .Prefix }}DOCKER_HOST{{ .Delimiter }}{{ .DockerHost }}{{ .Suffix }}{{ if not .SSHTransport }}{{ .Prefix }}DOCKER_CERT_PATH{{ .Delimiter }}{{ .DockerCertPath }}{{ .Suffix }}{{end}}{{ .Prefix }}DOCKER_MACHINE_NAME{{ .Delimiter }}{{ .MachineName }}{{ .Suffix }}{{ if .ComposePathsVar }}{{ .Prefix }}COMPOSE_CONVERT_WINDOWS_PATHS{{ .Delimiter }}true{{ .Suffix }}{{end}}{{ if .NoProxyVar }}{{ .Prefix }}{{ .NoProxyVar }}{{ .Delimiter }}{{ .NoProxyValue }}{{ .Suffix }}{{end}}{{ .UsageHint }}`
 )
 
 var (
 	errImproperUnsetEnvArgs = errors.New("Error: Expected no machine name when the -u flag is present")
+	errImproperAllEnvArgs   = errors.New("Error: --all cannot be combined with machine names, --unset or --cache")
 	defaultUsageHinter      UsageHintGenerator
 	runtimeOS               = func() string { return runtime.GOOS }
 )
@@ -42,6 +57,9 @@ type ShellConfig struct {
 	NoProxyVar      string
 	NoProxyValue    string
 	ComposePathsVar bool
+	// SSHTransport leaves out the TLS variables, the daemon is reached over
+	// SSH.
+	SSHTransport bool
 }
 
 func cmdEnv(c CommandLine, api libmachine.API) error {
@@ -54,6 +72,10 @@ func cmdEnv(c CommandLine, api libmachine.API) error {
 	// being run (it is intended to be run in a subshell)
 	log.SetOutWriter(os.Stderr)
 
+	if c.Bool("all") {
+		return envAll(c, api)
+	}
+
 	if c.Bool("unset") {
 		shellCfg, err = shellCfgUnset(c, api)
 		if err != nil {
@@ -64,11 +86,251 @@ func cmdEnv(c CommandLine, api libmachine.API) error {
 		if err != nil {
 			return err
 		}
+
+		if c.Bool("cache") || c.String("cache-file") != "" {
+			if err := cacheEnv(c, api, shellCfg); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.String("shell") == "json" {
+		return executeJSON(os.Stdout, shellCfg, c.Bool("unset"))
 	}
 
 	return executeTemplateStdout(shellCfg)
 }
 
+// envAll prints the environment of every running machine, checked
+// envAllParallel machines at a time. The machines that can't be reached are
+// skipped with a warning. For the shells, the environment of each machine is
+// guarded by a test of envSelectVar, so that the output can be sourced for one
+// machine; with json, it is an object keyed by machine name.
+func envAll(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 0 || c.Bool("unset") || c.Bool("cache") || c.String("cache-file") != "" {
+		return invalidArguments(errImproperAllEnvArgs)
+	}
+
+	userShell, err := getShell(c.String("shell"))
+	if err != nil {
+		return err
+	}
+
+	hosts, hostsInError, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return err
+	}
+
+	for name, err := range hostsInError {
+		log.Warnf("Skipping %s, its configuration could not be loaded: %s", name, err)
+	}
+
+	var (
+		lock      sync.Mutex
+		shellCfgs = map[string]*ShellConfig{}
+	)
+
+	errs := runForeachHostLimited(hosts, envAllParallel, func(h *host.Host) error {
+		shellCfg, err := hostShellCfg(c, h, userShell)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		shellCfgs[h.Name] = shellCfg
+		lock.Unlock()
+		return nil
+	})
+
+	names := []string{}
+	for _, h := range hosts {
+		if err, ok := errs[h.Name]; ok {
+			log.Warnf("Skipping %s: %s", h.Name, err)
+			continue
+		}
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+
+	if userShell == "json" {
+		env := map[string]interface{}{}
+		for _, name := range names {
+			env[name] = envJSON(shellCfgs[name], false)
+		}
+
+		data, err := json.MarshalIndent(env, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Println(string(data))
+		return err
+	}
+
+	return executeGuardedTemplates(os.Stdout, userShell, names, shellCfgs)
+}
+
+// executeGuardedTemplates writes the environment of the machines, each
+// applied only when envSelectVar is set to the machine name.
+func executeGuardedTemplates(w io.Writer, userShell string, names []string, shellCfgs map[string]*ShellConfig) error {
+	fmt.Fprintf(w, "%s Set %s to the name of a machine and source this output to configure your shell for it\n", shellComment(userShell), envSelectVar)
+
+	for _, name := range names {
+		start, end := envGuard(userShell, name)
+
+		shellCfg := *shellCfgs[name]
+		shellCfg.UsageHint = ""
+
+		fmt.Fprint(w, start)
+		if err := executeTemplate(w, &shellCfg); err != nil {
+			return err
+		}
+		fmt.Fprint(w, end)
+	}
+
+	return nil
+}
+
+// envGuard returns the lines opening and closing the block of a shell applied
+// only when envSelectVar is name.
+func envGuard(userShell, name string) (string, string) {
+	switch userShell {
+	case "fish":
+		return fmt.Sprintf("if test \"$%s\" = %q\n", envSelectVar, name), "end\n"
+	case "powershell":
+		return fmt.Sprintf("if ($Env:%s -eq %q) {\n", envSelectVar, name), "}\n"
+	case "cmd":
+		return fmt.Sprintf("IF \"%%%s%%\"==%q (\n", envSelectVar, name), ")\n"
+	case "tcsh":
+		return fmt.Sprintf("if ( \"`printenv %s`\" == %q ) then\n", envSelectVar, name), "endif\n"
+	case "emacs":
+		return fmt.Sprintf("(when (equal (getenv \"%s\") %q)\n", envSelectVar, name), ")\n"
+	default:
+		return fmt.Sprintf("if [ \"${%s:-}\" = %q ]; then\n", envSelectVar, name), "fi\n"
+	}
+}
+
+// shellComment returns the start of the comment lines of the shell.
+func shellComment(userShell string) string {
+	switch userShell {
+	case "cmd":
+		return "REM"
+	case "emacs":
+		return ";;"
+	default:
+		return "#"
+	}
+}
+
+// envCacheFileName returns the name of the cache file of the shell in the
+// machine directory, docker.env for the POSIX shells and json.
+func envCacheFileName(userShell string) string {
+	switch userShell {
+	case "fish":
+		return "docker.fish"
+	case "tcsh":
+		return "docker.tcsh"
+	case "powershell":
+		return "docker.ps1"
+	case "cmd":
+		return "docker.cmd"
+	case "emacs":
+		return "docker.el"
+	default:
+		return "docker.env"
+	}
+}
+
+// cacheEnv writes the environment of the machine to a file that can be
+// sourced directly by the shell, without running env again. The file records
+// the fingerprint of the server certificate and the daemon URL so that env
+// can warn when a previously cached file was stale.
+func cacheEnv(c CommandLine, api libmachine.API, shellCfg *ShellConfig) error {
+	h, err := api.Load(shellCfg.MachineName)
+	if err != nil {
+		return err
+	}
+
+	userShell, err := getShell(c.String("shell"))
+	if err != nil {
+		return err
+	}
+
+	path := c.String("cache-file")
+	if path == "" {
+		path = filepath.Join(mcndirs.GetMachineDir(), h.Name, envCacheFileName(userShell))
+	}
+
+	fingerprint := ""
+	if authOptions := h.AuthOptions(); authOptions != nil {
+		if fingerprint, err = cert.Fingerprint(authOptions.ServerCertPath); err != nil {
+			log.Debugf("Unable to compute the fingerprint of %s: %s", authOptions.ServerCertPath, err)
+		}
+	}
+
+	changed, err := writeEnvCache(path, userShell, shellCfg, fingerprint)
+	if err != nil {
+		return fmt.Errorf("Error writing environment cache: %s", err)
+	}
+
+	for _, what := range changed {
+		log.Warnf("The %s of %s changed since %s was cached, the file has been updated", what, h.Name, path)
+	}
+
+	return nil
+}
+
+// writeEnvCache writes the cache file at path, in the syntax of the shell the
+// shell config is for, and returns what changed compared to the previous
+// version of the file, if any.
+func writeEnvCache(path string, userShell string, shellCfg *ShellConfig, fingerprint string) ([]string, error) {
+	comment := shellComment(userShell)
+
+	changed := []string{}
+	if previous, err := os.ReadFile(path); err == nil {
+		metadata := envCacheMetadata(string(previous), comment)
+		if v, ok := metadata[envCacheFingerprintKey]; ok && v != fingerprint {
+			changed = append(changed, "certificate")
+		}
+		if v, ok := metadata[envCacheURLKey]; ok && v != shellCfg.DockerHost {
+			changed = append(changed, "URL")
+		}
+	}
+
+	cacheCfg := *shellCfg
+	cacheCfg.UsageHint = ""
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s=%s\n", comment, envCacheFingerprintKey, fingerprint)
+	fmt.Fprintf(&buf, "%s %s=%s\n", comment, envCacheURLKey, shellCfg.DockerHost)
+	if err := executeTemplate(&buf, &cacheCfg); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	return changed, os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// envCacheMetadata reads the "<comment> KEY=VALUE" header of a cache file.
+func envCacheMetadata(content string, comment string) map[string]string {
+	metadata := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, comment+" ") {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(line, comment+" "), "=", 2)
+		if len(parts) == 2 {
+			metadata[parts[0]] = parts[1]
+		}
+	}
+
+	return metadata
+}
+
 func shellCfgSet(c CommandLine, api libmachine.API) (*ShellConfig, error) {
 	if len(c.Args()) > 1 {
 		return nil, ErrExpectedOneMachine
@@ -84,16 +346,26 @@ func shellCfgSet(c CommandLine, api libmachine.API) (*ShellConfig, error) {
 		return nil, err
 	}
 
-	dockerHost, _, err := check.DefaultConnChecker.Check(host, c.Bool("swarm"))
+	userShell, err := getShell(c.String("shell"))
 	if err != nil {
-		return nil, fmt.Errorf("Error checking TLS connection: %s", err)
+		return nil, err
 	}
 
-	userShell, err := getShell(c.String("shell"))
-	if err != nil {
+	return hostShellCfg(c, host, userShell)
+}
+
+// hostShellCfg returns the environment of the daemon of h, checking that it
+// can be reached.
+func hostShellCfg(c CommandLine, host *host.Host, userShell string) (*ShellConfig, error) {
+	if err := checkProvisioned(host); err != nil {
 		return nil, err
 	}
 
+	dockerHost, _, err := check.DefaultConnChecker.Check(host, c.Bool("swarm"))
+	if err != nil {
+		return nil, fmt.Errorf("Error checking TLS connection: %s", err)
+	}
+
 	shellCfg := &ShellConfig{
 		DockerCertPath:  filepath.Join(mcndirs.GetMachineDir(), host.Name),
 		DockerHost:      dockerHost,
@@ -101,6 +373,11 @@ func shellCfgSet(c CommandLine, api libmachine.API) (*ShellConfig, error) {
 		UsageHint:       defaultUsageHinter.GenerateUsageHint(userShell, os.Args),
 		MachineName:     host.Name,
 	}
+	if host.DaemonSSHTransport() {
+		shellCfg.DockerCertPath = ""
+		shellCfg.DockerTLSVerify = ""
+		shellCfg.SSHTransport = true
+	}
 
 	if c.Bool("no-proxy") {
 		ip, err := host.Driver.GetIP()
@@ -207,13 +484,56 @@ func shellCfgUnset(c CommandLine, api libmachine.API) (*ShellConfig, error) {
 }
 
 func executeTemplateStdout(shellCfg *ShellConfig) error {
+	return executeTemplate(os.Stdout, shellCfg)
+}
+
+func executeTemplate(w io.Writer, shellCfg *ShellConfig) error {
 	t := template.New("envConfig")
 	tmpl, err := t.Parse(envTmpl)
 	if err != nil {
 		return err
 	}
 
-	return tmpl.Execute(os.Stdout, shellCfg)
+	return tmpl.Execute(w, shellCfg)
+}
+
+// executeJSON writes the environment as a JSON object, for the tools that
+// can't parse shell syntax. The variables are null when they are unset.
+func executeJSON(w io.Writer, shellCfg *ShellConfig, unset bool) error {
+	data, err := json.MarshalIndent(envJSON(shellCfg, unset), "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func envJSON(shellCfg *ShellConfig, unset bool) map[string]interface{} {
+	env := map[string]interface{}{
+		"DOCKER_TLS_VERIFY":   shellCfg.DockerTLSVerify,
+		"DOCKER_HOST":         shellCfg.DockerHost,
+		"DOCKER_CERT_PATH":    shellCfg.DockerCertPath,
+		"DOCKER_MACHINE_NAME": shellCfg.MachineName,
+	}
+	if shellCfg.SSHTransport {
+		delete(env, "DOCKER_TLS_VERIFY")
+		delete(env, "DOCKER_CERT_PATH")
+	}
+	if shellCfg.ComposePathsVar {
+		env["COMPOSE_CONVERT_WINDOWS_PATHS"] = "true"
+	}
+	if shellCfg.NoProxyVar != "" {
+		env[shellCfg.NoProxyVar] = shellCfg.NoProxyValue
+	}
+
+	if unset {
+		for key := range env {
+			env[key] = nil
+		}
+	}
+
+	return env
 }
 
 func getShell(userShell string) (string, error) {
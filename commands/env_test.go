@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -686,3 +687,191 @@ func TestShellCfgUnset(t *testing.T) {
 		os.Setenv(test.noProxyVar, "")
 	}
 }
+
+func TestWriteEnvCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "quux", "docker.env")
+	shellCfg := &ShellConfig{
+		DockerCertPath:  "/certs/quux",
+		DockerHost:      "tcp://1.2.3.4:2376",
+		DockerTLSVerify: "1",
+		MachineName:     "quux",
+		Prefix:          "export ",
+		Delimiter:       "=\"",
+		Suffix:          "\"\n",
+		UsageHint:       "# hint\n",
+	}
+
+	changed, err := writeEnvCache(path, "bash", shellCfg, "AA:BB")
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `# DOCKER_MACHINE_CERT_FINGERPRINT=AA:BB
+# DOCKER_MACHINE_URL=tcp://1.2.3.4:2376
+export DOCKER_TLS_VERIFY="1"
+export DOCKER_HOST="tcp://1.2.3.4:2376"
+export DOCKER_CERT_PATH="/certs/quux"
+export DOCKER_MACHINE_NAME="quux"
+`, string(content))
+
+	shellCfg.DockerHost = "tcp://5.6.7.8:2376"
+	changed, err = writeEnvCache(path, "bash", shellCfg, "CC:DD")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"certificate", "URL"}, changed)
+}
+
+func TestWriteEnvCacheShell(t *testing.T) {
+	path := filepath.Join(t.TempDir(), envCacheFileName("cmd"))
+	shellCfg := &ShellConfig{
+		DockerCertPath:  "/certs/quux",
+		DockerHost:      "tcp://1.2.3.4:2376",
+		DockerTLSVerify: "1",
+		MachineName:     "quux",
+		Prefix:          "SET ",
+		Delimiter:       "=",
+		Suffix:          "\n",
+	}
+
+	changed, err := writeEnvCache(path, "cmd", shellCfg, "AA:BB")
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `REM DOCKER_MACHINE_CERT_FINGERPRINT=AA:BB
+REM DOCKER_MACHINE_URL=tcp://1.2.3.4:2376
+SET DOCKER_TLS_VERIFY=1
+SET DOCKER_HOST=tcp://1.2.3.4:2376
+SET DOCKER_CERT_PATH=/certs/quux
+SET DOCKER_MACHINE_NAME=quux
+`, string(content))
+
+	changed, err = writeEnvCache(path, "cmd", shellCfg, "CC:DD")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"certificate"}, changed)
+
+	assert.Equal(t, "docker.env", envCacheFileName("zsh"))
+	assert.Equal(t, "docker.fish", envCacheFileName("fish"))
+}
+
+func TestExecuteJSON(t *testing.T) {
+	shellCfg := &ShellConfig{
+		DockerCertPath:  "/certs/quux",
+		DockerHost:      "tcp://1.2.3.4:2376",
+		DockerTLSVerify: "1",
+		MachineName:     "quux",
+		NoProxyVar:      "NO_PROXY",
+		NoProxyValue:    "1.2.3.4",
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, executeJSON(&buf, shellCfg, false))
+	assert.Equal(t, `{
+    "DOCKER_CERT_PATH": "/certs/quux",
+    "DOCKER_HOST": "tcp://1.2.3.4:2376",
+    "DOCKER_MACHINE_NAME": "quux",
+    "DOCKER_TLS_VERIFY": "1",
+    "NO_PROXY": "1.2.3.4"
+}
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, executeJSON(&buf, &ShellConfig{}, true))
+	assert.Equal(t, `{
+    "DOCKER_CERT_PATH": null,
+    "DOCKER_HOST": null,
+    "DOCKER_MACHINE_NAME": null,
+    "DOCKER_TLS_VERIFY": null
+}
+`, buf.String())
+}
+
+func TestExecuteTemplateSSHTransport(t *testing.T) {
+	shellCfg := &ShellConfig{
+		Prefix:       "export ",
+		Delimiter:    "=\"",
+		Suffix:       "\"\n",
+		DockerHost:   "ssh://ubuntu@1.2.3.4:22",
+		MachineName:  "quux",
+		SSHTransport: true,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, executeTemplate(&buf, shellCfg))
+	assert.Equal(t, `export DOCKER_HOST="ssh://ubuntu@1.2.3.4:22"
+export DOCKER_MACHINE_NAME="quux"
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, executeJSON(&buf, shellCfg, false))
+	assert.Equal(t, `{
+    "DOCKER_HOST": "ssh://ubuntu@1.2.3.4:22",
+    "DOCKER_MACHINE_NAME": "quux"
+}
+`, buf.String())
+}
+
+func TestExecuteGuardedTemplates(t *testing.T) {
+	shellCfgs := map[string]*ShellConfig{
+		"bar": {
+			Prefix:          "export ",
+			Delimiter:       "=\"",
+			Suffix:          "\"\n",
+			DockerCertPath:  "/certs/bar",
+			DockerHost:      "tcp://1.2.3.4:2376",
+			DockerTLSVerify: "1",
+			MachineName:     "bar",
+			UsageHint:       "# hint\n",
+		},
+		"foo": {
+			Prefix:          "export ",
+			Delimiter:       "=\"",
+			Suffix:          "\"\n",
+			DockerCertPath:  "/certs/foo",
+			DockerHost:      "tcp://5.6.7.8:2376",
+			DockerTLSVerify: "1",
+			MachineName:     "foo",
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, executeGuardedTemplates(&buf, "bash", []string{"bar", "foo"}, shellCfgs))
+	assert.Equal(t, `# Set MACHINE_ENV_SELECT to the name of a machine and source this output to configure your shell for it
+if [ "${MACHINE_ENV_SELECT:-}" = "bar" ]; then
+export DOCKER_TLS_VERIFY="1"
+export DOCKER_HOST="tcp://1.2.3.4:2376"
+export DOCKER_CERT_PATH="/certs/bar"
+export DOCKER_MACHINE_NAME="bar"
+fi
+if [ "${MACHINE_ENV_SELECT:-}" = "foo" ]; then
+export DOCKER_TLS_VERIFY="1"
+export DOCKER_HOST="tcp://5.6.7.8:2376"
+export DOCKER_CERT_PATH="/certs/foo"
+export DOCKER_MACHINE_NAME="foo"
+fi
+`, buf.String())
+}
+
+func TestEnvGuard(t *testing.T) {
+	var tests = []struct {
+		userShell, start, end string
+	}{
+		{"fish", "if test \"$MACHINE_ENV_SELECT\" = \"foo\"\n", "end\n"},
+		{"powershell", "if ($Env:MACHINE_ENV_SELECT -eq \"foo\") {\n", "}\n"},
+		{"cmd", "IF \"%MACHINE_ENV_SELECT%\"==\"foo\" (\n", ")\n"},
+		{"tcsh", "if ( \"`printenv MACHINE_ENV_SELECT`\" == \"foo\" ) then\n", "endif\n"},
+		{"emacs", "(when (equal (getenv \"MACHINE_ENV_SELECT\") \"foo\")\n", ")\n"},
+		{"zsh", "if [ \"${MACHINE_ENV_SELECT:-}\" = \"foo\" ]; then\n", "fi\n"},
+	}
+
+	for _, test := range tests {
+		start, end := envGuard(test.userShell, "foo")
+		assert.Equal(t, test.start, start, test.userShell)
+		assert.Equal(t, test.end, end, test.userShell)
+	}
+}
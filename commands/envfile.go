@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// withEnvFile loads the file given with --env-file into the process
+// environment before the handler runs. It has to wrap withDriverFlags so that
+// the values are visible when the driver flags are resolved from their
+// EnvVar bindings.
+func withEnvFile(handler cmdHandler) cmdHandler {
+	return func(c CommandLine, api libmachine.API) error {
+		if path, ok := getFlagValue(c.Args(), "--env-file", "", ""); ok && path != "" {
+			if err := loadEnvFile(path); err != nil {
+				return err
+			}
+		}
+
+		return handler(c, api)
+	}
+}
+
+// loadEnvFile sets the variables of a KEY=VALUE file in the process
+// environment. Variables that are already set are left untouched.
+func loadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading env file: %s", err)
+	}
+	defer file.Close()
+
+	vars, err := parseEnvFile(file)
+	if err != nil {
+		return fmt.Errorf("error parsing env file %s: %s", path, err)
+	}
+
+	for _, v := range vars {
+		if _, ok := os.LookupEnv(v[0]); ok {
+			log.Debugf("%s is already set, ignoring the value from %s", v[0], path)
+			continue
+		}
+
+		if err := os.Setenv(v[0], v[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseEnvFile parses KEY=VALUE lines. Blank lines and lines starting with
+// '#' are ignored, an optional "export " prefix is accepted and values may be
+// single or double quoted. Unquoted values end at a " #" comment.
+func parseEnvFile(r io.Reader) ([][2]string, error) {
+	vars := [][2]string{}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+
+		vars = append(vars, [2]string{key, value})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+func parseEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := strings.LastIndex(value, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", value)
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", value)
+		}
+		return value[1:end], nil
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+
+	return strings.TrimSpace(value), nil
+}
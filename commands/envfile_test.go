@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	content := `
+# credentials
+AWS_ACCESS_KEY_ID=foo
+export AWS_SECRET_ACCESS_KEY = "b\"ar"
+MACHINE_DRIVER='amazonec2' # quoted
+AWS_DEFAULT_REGION=us-east-1 # trailing comment
+EMPTY=
+`
+
+	vars, err := parseEnvFile(strings.NewReader(content))
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"AWS_ACCESS_KEY_ID", "foo"},
+		{"AWS_SECRET_ACCESS_KEY", `b"ar`},
+		{"MACHINE_DRIVER", "amazonec2"},
+		{"AWS_DEFAULT_REGION", "us-east-1"},
+		{"EMPTY", ""},
+	}, vars)
+}
+
+func TestParseEnvFileInvalidLine(t *testing.T) {
+	_, err := parseEnvFile(strings.NewReader("FOO=bar\nnot a variable\n"))
+
+	assert.EqualError(t, err, "line 2: expected KEY=VALUE")
+}
+
+func TestParseEnvFileUnterminatedQuote(t *testing.T) {
+	_, err := parseEnvFile(strings.NewReader(`FOO="bar`))
+
+	assert.Error(t, err)
+}
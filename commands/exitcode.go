@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/rancher/machine/libmachine/crashreport"
+	"github.com/rancher/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// The exit codes of the commands, which scripts can rely on to tell the
+// failure classes apart. The existing codes must never change.
+const (
+	exitError            = 1
+	exitInvalidArguments = 2
+	exitPreCreateCheck   = 3
+	exitInstanceNotFound = 4
+	exitMachineNotFound  = 5
+	exitDriverNotFound   = 6
+	exitUnauthorized     = 7
+	exitTimeout          = 8
+	exitTransient        = 9
+	exitPartialFailure   = 10
+)
+
+// ExitCode is an exit code of the commands and the failures it is used for.
+type ExitCode struct {
+	Code        int
+	Name        string
+	Description string
+}
+
+// ExitCodes is the exit code contract printed by --exit-code-map.
+var ExitCodes = []ExitCode{
+	{0, "success", "The command succeeded"},
+	{exitError, "error", "Any failure not in another class"},
+	{exitInvalidArguments, "invalid-arguments", "The arguments or flags are invalid, nothing was changed"},
+	{exitPreCreateCheck, "pre-create-check", "The driver refused the configuration of the machine before creating anything"},
+	{exitInstanceNotFound, "instance-not-found", "The instance of the machine doesn't exist at the provider"},
+	{exitMachineNotFound, "machine-not-found", "The machine doesn't exist in the store"},
+	{exitDriverNotFound, "driver-not-found", "The driver plugin binary can't be found"},
+	{exitUnauthorized, "unauthorized", "The provider rejected the credentials"},
+	{exitTimeout, "timeout", "An operation didn't complete in time"},
+	{exitTransient, "transient", "The provider is rate limiting, unavailable or out of capacity, retrying may succeed"},
+	{exitPartialFailure, "partial-failure", "The command failed for some of the machines and succeeded for the others"},
+}
+
+// invalidArgumentsError is the error of a command called with invalid
+// arguments or flags.
+type invalidArgumentsError struct {
+	error
+}
+
+func (e invalidArgumentsError) Unwrap() error {
+	return e.error
+}
+
+func invalidArguments(err error) error {
+	return invalidArgumentsError{err}
+}
+
+// partialFailureError is the error of a command run for several machines that
+// failed for some of them only.
+type partialFailureError struct {
+	error
+}
+
+func (e partialFailureError) Unwrap() error {
+	return e.error
+}
+
+// fleetError returns err, the error of a command that failed for `failed` of
+// its `total` machines, as a partial failure when it succeeded for others.
+func fleetError(failed, total int, err error) error {
+	if failed > 0 && failed < total {
+		return partialFailureError{err}
+	}
+
+	return err
+}
+
+// exitCode returns the exit code of the class of err.
+func exitCode(err error) int {
+	if crashErr, ok := err.(crashreport.CrashError); ok {
+		if _, ok := crashErr.Cause.(mcnerror.ErrDuringPreCreate); ok {
+			return exitPreCreateCheck
+		}
+		err = crashErr.Cause
+	}
+
+	err = mcnerror.FromMessage(err)
+
+	var (
+		hostNotFound   mcnerror.ErrHostDoesNotExist
+		driverNotFound localbinary.ErrPluginBinaryNotFound
+	)
+
+	var remoteExit remoteExitError
+
+	switch {
+	case errors.As(err, &remoteExit):
+		// The exit status of a script run on the machine is passed through.
+		return remoteExit.status
+	case errors.As(err, &partialFailureError{}):
+		return exitPartialFailure
+	case errors.As(err, &invalidArgumentsError{}), errors.Is(err, mcnerror.ErrInvalidHostname),
+		errors.Is(err, ErrNoMachineSpecified), errors.Is(err, ErrExpectedOneMachine),
+		errors.Is(err, ErrTooManyArguments), errors.Is(err, errNoMachineName):
+		return exitInvalidArguments
+	case errors.As(err, new(notFoundError)), errors.Is(err, mcnerror.ErrInstanceNotFound):
+		return exitInstanceNotFound
+	case errors.As(err, &hostNotFound):
+		return exitMachineNotFound
+	case errors.As(err, &driverNotFound):
+		return exitDriverNotFound
+	case errors.Is(err, mcnerror.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, mcnerror.ErrTimeout):
+		return exitTimeout
+	case errors.Is(err, mcnerror.ErrTransient), errors.Is(err, mcnerror.ErrCapacity):
+		return exitTransient
+	}
+
+	return exitError
+}
+
+// PrintExitCodeMap writes the exit code contract to w as JSON.
+func PrintExitCodeMap(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ExitCodes)
+}
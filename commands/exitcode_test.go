@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/crashreport"
+	"github.com/rancher/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	var tests = []struct {
+		description string
+		err         error
+		expected    int
+	}{
+		{"plain", errors.New("boom"), exitError},
+		{"invalid arguments", invalidArguments(errors.New("--count requires --name-pattern")), exitInvalidArguments},
+		{"expected one machine", ErrExpectedOneMachine, exitInvalidArguments},
+		{"pre-create check", crashreport.CrashError{Cause: mcnerror.ErrDuringPreCreate{Cause: errors.New("no quota")}}, exitPreCreateCheck},
+		{"status not found", notFoundError("foo not found"), exitInstanceNotFound},
+		{"instance not found", fmt.Errorf("error stopping: %w", mcnerror.NotFound(errors.New("404"))), exitInstanceNotFound},
+		{"instance not found from a plugin", errors.New(mcnerror.ToMessage(mcnerror.NotFound(errors.New("404"))).Error()), exitInstanceNotFound},
+		{"machine not found", mcnerror.ErrHostDoesNotExist{Name: "foo"}, exitMachineNotFound},
+		{"driver not found", localbinary.ErrPluginBinaryNotFound{}, exitDriverNotFound},
+		{"unauthorized", crashreport.CrashError{Cause: mcnerror.Unauthorized(errors.New("401"))}, exitUnauthorized},
+		{"timeout", mcnerror.Timeout(errors.New("too slow")), exitTimeout},
+		{"transient", mcnerror.MarkTransient(errors.New("503")), exitTransient},
+		{"capacity", mcnerror.Capacity(errors.New("InsufficientInstanceCapacity")), exitTransient},
+		{"partial failure", fleetError(1, 2, errors.New("foo failed")), exitPartialFailure},
+		{"fleet failure", fleetError(2, 2, errors.New("all failed")), exitError},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, exitCode(test.err), test.description)
+	}
+}
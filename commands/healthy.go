@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcndockerclient"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+const waitHealthyDefaultTimeout = 120
+
+// waitHealthyInterval is the delay between two queries of a daemon that is
+// not ready yet.
+var waitHealthyInterval = 3 * time.Second
+
+// waitHealthyClientOptions are the options of the queries of the daemons,
+// which are not retried as the daemons are polled.
+var waitHealthyClientOptions = mcndockerclient.ClientOptions{
+	DialTimeout:    10 * time.Second,
+	RequestTimeout: 10 * time.Second,
+	Retries:        -1,
+}
+
+// daemonInfo succeeds once the daemon of a machine answers `docker info`.
+var daemonInfo = func(h *host.Host) error {
+	if h.DaemonSSHTransport() {
+		if _, err := h.RunSSHCommand("docker info > /dev/null"); err != nil {
+			return fmt.Errorf("unable to query docker info: %s", err)
+		}
+		return nil
+	}
+
+	url, err := h.URL()
+	if err != nil {
+		return err
+	}
+
+	return mcndockerclient.DaemonInfo(&mcndockerclient.RemoteDocker{
+		HostURL:    url,
+		AuthOption: h.AuthOptions(),
+	}, waitHealthyClientOptions)
+}
+
+// runActionWaitHealthy runs start, restart or reboot. With --wait-healthy it then
+// waits for the daemons of the machines to answer, so that the command only
+// returns once they can be used.
+func runActionWaitHealthy(actionName string, c CommandLine, api libmachine.API) error {
+	if !c.Bool("wait-healthy") {
+		return runAction(actionName, c, api)
+	}
+
+	if err := runAction(actionName, c, api); err != nil {
+		return fmt.Errorf("Error waiting for the machines to boot: %s", err)
+	}
+
+	names := c.Args()
+	if len(names) == 0 {
+		target, err := targetHost(c, api)
+		if err != nil {
+			return err
+		}
+		names = []string{target}
+	}
+
+	hosts, hostsInError := persist.LoadHosts(api, names)
+	if len(hostsInError) > 0 {
+		errs := []error{}
+		for _, err := range hostsInError {
+			errs = append(errs, err)
+		}
+		return consolidateErrs(errs)
+	}
+
+	timeout := time.Duration(c.Int("wait-healthy-timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = waitHealthyDefaultTimeout * time.Second
+	}
+
+	errs := runForeachHostLimited(hosts, len(hosts), func(h *host.Host) error {
+		return waitDaemonHealthy(h, timeout)
+	})
+	if len(errs) > 0 {
+		list := []error{}
+		for _, h := range hosts {
+			if err, ok := errs[h.Name]; ok {
+				list = append(list, err)
+			}
+		}
+		return consolidateErrs(list)
+	}
+
+	return nil
+}
+
+// waitDaemonHealthy polls the daemon of a running machine until it answers
+// or the timeout expires.
+func waitDaemonHealthy(h *host.Host, timeout time.Duration) error {
+	log.Infof("Waiting for the Docker daemon of %q to be ready...", h.Name)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := daemonInfo(h)
+		if err == nil {
+			log.Infof("Docker daemon of %q is ready.", h.Name)
+			return nil
+		}
+
+		if time.Now().Add(waitHealthyInterval).After(deadline) {
+			return fmt.Errorf("machine %q is running but its Docker daemon was not ready after %s: %s", h.Name, timeout, err)
+		}
+
+		log.Debugf("Docker daemon of %q is not ready yet: %s", h.Name, err)
+		time.Sleep(waitHealthyInterval)
+	}
+}
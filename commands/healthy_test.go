@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubDaemonInfo(t *testing.T, info func(h *host.Host) error) {
+	origInfo, origInterval := daemonInfo, waitHealthyInterval
+	daemonInfo, waitHealthyInterval = info, time.Millisecond
+	t.Cleanup(func() {
+		daemonInfo, waitHealthyInterval = origInfo, origInterval
+	})
+}
+
+func TestWaitDaemonHealthy(t *testing.T) {
+	calls := 0
+	stubDaemonInfo(t, func(h *host.Host) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	err := waitDaemonHealthy(&host.Host{Name: "foo"}, time.Second)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitDaemonHealthyTimeout(t *testing.T) {
+	stubDaemonInfo(t, func(h *host.Host) error {
+		return errors.New("connection refused")
+	})
+
+	err := waitDaemonHealthy(&host.Host{Name: "foo"}, 10*time.Millisecond)
+
+	assert.EqualError(t, err, `machine "foo" is running but its Docker daemon was not ready after 10ms: connection refused`)
+}
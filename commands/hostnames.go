@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+// osHostname returns the OS hostname set on the machine by the provisioning,
+// or an empty string when the hostname of the provider is kept.
+func osHostname(h *host.Host) string {
+	if h.HostOptions == nil {
+		return h.Name
+	}
+
+	if h.HostOptions.CustomInstallScript != "" {
+		if h.HostOptions.HostnameOverride != "" {
+			return h.HostOptions.HostnameOverride
+		}
+		return h.Name
+	}
+
+	engineOptions := h.HostOptions.EngineOptions
+	switch {
+	case engineOptions == nil:
+		return h.Name
+	case engineOptions.KeepHostname:
+		return ""
+	case engineOptions.Hostname != "":
+		return engineOptions.Hostname
+	}
+
+	return h.Name
+}
+
+// hostnameCollisions returns the names of the machines of hosts whose OS
+// hostname is the one of h.
+func hostnameCollisions(h *host.Host, hosts []*host.Host) []string {
+	hostname := osHostname(h)
+	if hostname == "" {
+		return nil
+	}
+
+	collisions := []string{}
+	for _, other := range hosts {
+		if other.Name != h.Name && strings.EqualFold(osHostname(other), hostname) {
+			collisions = append(collisions, other.Name)
+		}
+	}
+	sort.Strings(collisions)
+
+	return collisions
+}
+
+// checkHostnameCollisions warns when existing machines have the OS hostname
+// the new machine would get, which confuses swarm and DNS, or fails before
+// anything is created when strict. Only the stored machines are read.
+func checkHostnameCollisions(api libmachine.API, h *host.Host, strict bool) error {
+	hosts, _, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return fmt.Errorf("error checking the hostnames of the machines: %s", err)
+	}
+
+	collisions := hostnameCollisions(h, hosts)
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("The hostname %s of %s is already the hostname of %s", osHostname(h), h.Name, strings.Join(collisions, ", "))
+	if strict {
+		return invalidArguments(fmt.Errorf("%s, use another --set-hostname", msg))
+	}
+
+	log.Warnf("%s, use --set-hostname to avoid swarm and DNS conflicts", msg)
+	return nil
+}
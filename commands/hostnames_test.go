@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func hostWithHostname(name, hostname string) *host.Host {
+	return &host.Host{
+		Name: name,
+		HostOptions: &host.Options{
+			EngineOptions: &engine.Options{Hostname: hostname},
+		},
+	}
+}
+
+func TestOsHostname(t *testing.T) {
+	assert.Equal(t, "foo", osHostname(&host.Host{Name: "foo"}))
+	assert.Equal(t, "foo", osHostname(hostWithHostname("foo", "")))
+	assert.Equal(t, "node1", osHostname(hostWithHostname("foo", "node1")))
+	assert.Equal(t, "", osHostname(&host.Host{
+		Name:        "foo",
+		HostOptions: &host.Options{EngineOptions: &engine.Options{KeepHostname: true}},
+	}))
+	assert.Equal(t, "custom", osHostname(&host.Host{
+		Name:        "foo",
+		HostOptions: &host.Options{CustomInstallScript: "script.sh", HostnameOverride: "custom"},
+	}))
+}
+
+func TestHostnameCollisions(t *testing.T) {
+	hosts := []*host.Host{
+		hostWithHostname("a", "node1"),
+		hostWithHostname("b", ""),
+		hostWithHostname("c", "NODE1"),
+	}
+
+	assert.Equal(t, []string{"a", "c"}, hostnameCollisions(hostWithHostname("new", "node1"), hosts))
+	assert.Equal(t, []string{"c"}, hostnameCollisions(hostWithHostname("a", "node1"), hosts))
+	assert.Empty(t, hostnameCollisions(hostWithHostname("new", "node2"), hosts))
+	assert.Equal(t, []string{"a", "c"}, hostnameCollisions(hostWithHostname("node1", ""), hosts))
+}
+
+func TestCheckHostnameCollisions(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{hostWithHostname("a", "node1")},
+	}
+
+	err := checkHostnameCollisions(api, hostWithHostname("new", "node1"), true)
+	assert.EqualError(t, err, "The hostname node1 of new is already the hostname of a, use another --set-hostname")
+	assert.Equal(t, exitInvalidArguments, exitCode(err))
+
+	assert.NoError(t, checkHostnameCollisions(api, hostWithHostname("new", "node1"), false))
+	assert.NoError(t, checkHostnameCollisions(api, hostWithHostname("new", "node2"), true))
+}
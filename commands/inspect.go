@@ -2,13 +2,19 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"text/template"
 
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/host"
 )
 
+var errPrivateKeyOutput = errors.New("Error: printing a private key requires --show-private-key")
+
 var funcMap = template.FuncMap{
 	"json": func(v interface{}) string {
 		a, _ := json.Marshal(v)
@@ -36,37 +42,117 @@ func cmdInspect(c CommandLine, api libmachine.API) error {
 		return err
 	}
 
-	tmplString := c.String("format")
+	if c.Bool("verify-roundtrip") {
+		return verifyDriverRoundTrip(os.Stdout, host)
+	}
+
+	if c.String("cert") != "" {
+		return printCert(os.Stdout, host, c.String("cert"), c.Bool("show-private-key"))
+	}
+
+	tmplString, tmplFile, err := templateFlag(c)
+	if err != nil {
+		return err
+	}
+
 	if tmplString != "" {
-		var tmpl *template.Template
-		var err error
-		if tmpl, err = template.New("").Funcs(funcMap).Parse(tmplString); err != nil {
-			return fmt.Errorf("template parsing error: %v", err)
-		}
+		return printHostTemplate(os.Stdout, host, tmplString, tmplFile)
+	}
 
-		jsonHost, err := json.Marshal(host)
-		if err != nil {
-			return err
-		}
+	prettyJSON, err := json.MarshalIndent(host, "", "    ")
+	if err != nil {
+		return err
+	}
 
-		obj := make(map[string]interface{})
-		if err := json.Unmarshal(jsonHost, &obj); err != nil {
-			return err
-		}
+	fmt.Println(string(prettyJSON))
 
-		if err := tmpl.Execute(os.Stdout, obj); err != nil {
-			return err
-		}
+	return nil
+}
+
+// printHostTemplate writes a machine formatted with a Go template, given with
+// --format or read from tmplFile. The fields a template file refers to must
+// exist, so that a typo is reported instead of printed as <no value>.
+func printHostTemplate(w io.Writer, h *host.Host, tmplString, tmplFile string) error {
+	tmpl := template.New("").Funcs(funcMap)
+	if tmplFile != "" {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err := tmpl.Parse(tmplString)
+	if err != nil {
+		return templateError(fmt.Errorf("template parsing error: %v", err), tmplFile)
+	}
 
-		os.Stdout.Write([]byte{'\n'})
-	} else {
-		prettyJSON, err := json.MarshalIndent(host, "", "    ")
-		if err != nil {
-			return err
+	jsonHost, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(jsonHost, &obj); err != nil {
+		return err
+	}
+
+	for name, path := range certPaths(h.AuthOptions()) {
+		obj[name] = path
+	}
+	obj["DriverConfig"] = driverConfig(h)
+
+	if err := tmpl.Execute(w, obj); err != nil {
+		return templateError(err, tmplFile)
+	}
+
+	_, err = w.Write([]byte{'\n'})
+	return err
+}
+
+// certPaths returns the paths of the certs and keys of a machine, keyed by the
+// name they are exposed with to --format templates.
+func certPaths(authOptions *auth.Options) map[string]string {
+	if authOptions == nil {
+		return nil
+	}
+
+	return map[string]string{
+		"CaCertPath":       authOptions.CaCertPath,
+		"CaPrivateKeyPath": authOptions.CaPrivateKeyPath,
+		"ClientCertPath":   authOptions.ClientCertPath,
+		"ClientKeyPath":    authOptions.ClientKeyPath,
+		"ServerCertPath":   authOptions.ServerCertPath,
+		"ServerKeyPath":    authOptions.ServerKeyPath,
+	}
+}
+
+// printCert writes the PEM content of one of the certs of a machine. The
+// client key is only printed when showPrivateKey is set.
+func printCert(w io.Writer, h *host.Host, name string, showPrivateKey bool) error {
+	authOptions := h.AuthOptions()
+	if authOptions == nil {
+		return fmt.Errorf("Error: %s has no TLS configuration", h.Name)
+	}
+
+	var path string
+	switch name {
+	case "ca":
+		path = authOptions.CaCertPath
+	case "cert":
+		path = authOptions.ClientCertPath
+	case "server-cert":
+		path = authOptions.ServerCertPath
+	case "key":
+		if !showPrivateKey {
+			return errPrivateKeyOutput
 		}
+		path = authOptions.ClientKeyPath
+	default:
+		return fmt.Errorf("Error: unknown cert %q, expected one of ca, cert, key or server-cert", name)
+	}
 
-		fmt.Println(string(prettyJSON))
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = w.Write(content)
+	return err
 }
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/rancher/machine/libmachine/host"
+)
+
+// verifyDriverRoundTrip checks that the driver config stored for a machine
+// is serialized back the same by its driver once loaded. A field lost or
+// changed on the way is usually a field of the driver missing a JSON tag, or
+// unexported, and is lost on the next save.
+func verifyDriverRoundTrip(out io.Writer, h *host.Host) error {
+	if h.Driver.DriverName() == "not-found" {
+		return fmt.Errorf("the %s driver of %s is not installed, its config can't be loaded", h.DriverName, h.Name)
+	}
+
+	data, err := json.Marshal(h.Driver)
+	if err != nil {
+		return fmt.Errorf("error serializing the driver of %s: %s", h.Name, err)
+	}
+
+	diffs, err := roundTripDiffs(h.RawDriver, data)
+	if err != nil {
+		return err
+	}
+
+	if len(diffs) == 0 {
+		fmt.Fprintf(out, "The driver config of %s round-trips\n", h.Name)
+		return nil
+	}
+
+	for _, diff := range diffs {
+		fmt.Fprintln(out, diff)
+	}
+
+	return fmt.Errorf("%d field(s) of the %s driver config of %s don't round-trip", len(diffs), h.DriverName, h.Name)
+}
+
+// roundTripDiffs returns the fields of the stored config lost or changed in
+// the serialized one. The fields only in the serialized one are the fields
+// added to the driver since the machine was saved, and are ignored.
+func roundTripDiffs(stored, serialized []byte) ([]string, error) {
+	before := map[string]interface{}{}
+	if err := json.Unmarshal(stored, &before); err != nil {
+		return nil, fmt.Errorf("error reading the stored driver config: %s", err)
+	}
+
+	after := map[string]interface{}{}
+	if err := json.Unmarshal(serialized, &after); err != nil {
+		return nil, fmt.Errorf("error reading the serialized driver config: %s", err)
+	}
+
+	diffs := []string{}
+	compareRoundTrip("", before, after, &diffs)
+	sort.Strings(diffs)
+
+	return diffs, nil
+}
+
+func compareRoundTrip(prefix string, before, after map[string]interface{}, diffs *[]string) {
+	for key, value := range before {
+		field := prefix + key
+
+		got, ok := after[key]
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: lost, stored as %s", field, roundTripValue(value)))
+			continue
+		}
+
+		valueMap, isMap := value.(map[string]interface{})
+		gotMap, gotIsMap := got.(map[string]interface{})
+		if isMap && gotIsMap {
+			compareRoundTrip(field+".", valueMap, gotMap, diffs)
+			continue
+		}
+
+		if !reflect.DeepEqual(value, got) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: stored as %s, serialized as %s", field, roundTripValue(value), roundTripValue(got)))
+		}
+	}
+}
+
+func roundTripValue(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rancher/machine/drivers/errdriver"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripDiffs(t *testing.T) {
+	stored := []byte(`{"IPAddress":"10.0.0.1","KmsKey":"alias/k","Zone":"a","Tags":{"env":"prod","team":"ops"}}`)
+	serialized := []byte(`{"IPAddress":"10.0.0.1","Zone":"b","Tags":{"env":"prod"},"NewField":true}`)
+
+	diffs, err := roundTripDiffs(stored, serialized)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`KmsKey: lost, stored as "alias/k"`,
+		`Tags.team: lost, stored as "ops"`,
+		`Zone: stored as "a", serialized as "b"`,
+	}, diffs)
+}
+
+func TestVerifyDriverRoundTrip(t *testing.T) {
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "fake",
+		Driver:     &fakedriver.Driver{MockIP: "10.0.0.1"},
+		RawDriver:  []byte(`{"MockIP":"10.0.0.1"}`),
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, verifyDriverRoundTrip(out, h))
+	assert.Equal(t, "The driver config of foo round-trips\n", out.String())
+
+	h.RawDriver = []byte(`{"MockIP":"10.0.0.1","Secret":"s"}`)
+	out.Reset()
+	assert.EqualError(t, verifyDriverRoundTrip(out, h), "1 field(s) of the fake driver config of foo don't round-trip")
+	assert.Equal(t, "Secret: lost, stored as \"s\"\n", out.String())
+}
+
+func TestVerifyDriverRoundTripDriverNotFound(t *testing.T) {
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "missing",
+		Driver:     errdriver.NewDriver("missing"),
+	}
+
+	assert.EqualError(t, verifyDriverRoundTrip(&bytes.Buffer{}, h), "the missing driver of foo is not installed, its config can't be loaded")
+}
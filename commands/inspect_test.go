@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rancher/machine/commands/commandstest"
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/libmachinetest"
 	"github.com/stretchr/testify/assert"
 )
@@ -29,3 +34,76 @@ func TestCmdInspect(t *testing.T) {
 		assert.Equal(t, tc.expectedErr, err)
 	}
 }
+
+func TestPrintCert(t *testing.T) {
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(caPath, []byte("CA PEM"), 0600))
+	assert.NoError(t, os.WriteFile(keyPath, []byte("KEY PEM"), 0600))
+
+	h := &host.Host{
+		Name: "foo",
+		HostOptions: &host.Options{
+			AuthOptions: &auth.Options{
+				CaCertPath:    caPath,
+				ClientKeyPath: keyPath,
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printCert(out, h, "ca", false))
+	assert.Equal(t, "CA PEM", out.String())
+
+	assert.Equal(t, errPrivateKeyOutput, printCert(out, h, "key", false))
+
+	out.Reset()
+	assert.NoError(t, printCert(out, h, "key", true))
+	assert.Equal(t, "KEY PEM", out.String())
+
+	assert.Error(t, printCert(out, h, "unknown", true))
+}
+
+func TestPrintHostTemplate(t *testing.T) {
+	h := &host.Host{
+		Name:        "foo",
+		DriverName:  "none",
+		HostOptions: &host.Options{AuthOptions: &auth.Options{CaCertPath: "/certs/ca.pem"}},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printHostTemplate(out, h, "{{.Name}}\n{{.DriverName}} {{.CaCertPath}}", "report.tmpl"))
+	assert.Equal(t, "foo\nnone /certs/ca.pem\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printHostTemplate(out, h, "{{.Nmae}}", ""))
+	assert.Equal(t, "<no value>\n", out.String())
+
+	err := printHostTemplate(out, h, "{{.Nmae}}", "report.tmpl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error in the template file report.tmpl")
+	assert.Contains(t, err.Error(), "<.Nmae>")
+
+	err = printHostTemplate(out, h, "{{.Name", "report.tmpl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error in the template file report.tmpl: template parsing error")
+}
+
+func TestPrintHostTemplateDriverConfig(t *testing.T) {
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "amazonec2",
+		HostOptions: &host.Options{
+			AuthOptions: &auth.Options{},
+			DriverOptions: map[string]interface{}{
+				"amazonec2-region":     "eu-west-1",
+				"amazonec2-secret-key": "s3cr3t",
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printHostTemplate(out, h, `{{index .DriverConfig "amazonec2-region"}} {{index .DriverConfig "amazonec2-secret-key"}}`, ""))
+	assert.Equal(t, "eu-west-1 <REDACTED>\n", out.String())
+}
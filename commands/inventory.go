@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+const (
+	inventoryFormatJSON         = "json"
+	inventoryFormatAnsible      = "ansible"
+	inventoryFormatPrometheusSD = "prometheus-sd"
+)
+
+// InventoryItem is the entry of a machine in the inventory.
+type InventoryItem struct {
+	Name       string
+	DriverName string
+	State      string
+	IP         string `json:",omitempty"`
+	PrivateIP  string `json:",omitempty"`
+	URL        string `json:",omitempty"`
+	Labels     map[string]string
+	Groups     []string
+	SSHUser    string `json:",omitempty"`
+	SSHPort    int    `json:",omitempty"`
+	SSHKeyPath string `json:",omitempty"`
+	// MetricsAddr is the host:port the daemon serves its metrics on.
+	MetricsAddr string `json:",omitempty"`
+	Error       string `json:",omitempty"`
+}
+
+// invalidGroupChars are the characters replaced in the group names, which
+// ansible limits to letters, digits and underscores.
+var invalidGroupChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func cmdInventory(c CommandLine, api libmachine.API) error {
+	format := c.String("format")
+	if format == "" {
+		format = inventoryFormatJSON
+	}
+	if format != inventoryFormatJSON && format != inventoryFormatAnsible && format != inventoryFormatPrometheusSD {
+		return fmt.Errorf("unsupported format %q, must be one of %s, %s or %s", format, inventoryFormatJSON, inventoryFormatAnsible, inventoryFormatPrometheusSD)
+	}
+
+	filters, err := parseFilters(c.StringSlice("filter"))
+	if err != nil {
+		return err
+	}
+
+	hostList, hostInError, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return err
+	}
+
+	hostList = filterHosts(hostList, filters)
+
+	timeout := time.Duration(c.Int("timeout")) * time.Second
+	items := getInventoryItems(hostList, hostInError, timeout)
+
+	var document interface{}
+	switch format {
+	case inventoryFormatAnsible:
+		document = ansibleInventory(items)
+	case inventoryFormatPrometheusSD:
+		document = prometheusSDTargets(items, c.String("metrics-port"))
+	default:
+		document = items
+	}
+
+	return writeInventory(os.Stdout, document)
+}
+
+func writeInventory(w io.Writer, document interface{}) error {
+	data, err := json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// getInventoryItems gets the state of the machines like ls, then the
+// addresses of the running ones.
+func getInventoryItems(hostList []*host.Host, hostInError map[string]error, timeout time.Duration) []InventoryItem {
+	hosts := map[string]*host.Host{}
+	for _, h := range hostList {
+		hosts[h.Name] = h
+	}
+
+	items := []InventoryItem{}
+	for _, listItem := range getHostListItems(hostList, hostInError, timeout) {
+		item := InventoryItem{
+			Name:       listItem.Name,
+			DriverName: listItem.DriverName,
+			State:      listItem.State.String(),
+			Labels:     map[string]string{},
+			Error:      listItem.Error,
+		}
+		if listItem.EngineOptions != nil {
+			item.Labels = parseEngineLabels(listItem.EngineOptions.Labels)
+			item.MetricsAddr = listItem.EngineOptions.MetricsAddr
+		}
+		item.Groups = inventoryGroups(item.DriverName, item.Labels)
+
+		if h, ok := hosts[item.Name]; ok && listItem.State == state.Running {
+			if item.URL = listItem.URL; item.URL != "" && h.DaemonExternalURL() != "" {
+				item.URL = h.DaemonExternalURL()
+			}
+			item.PrivateIP = hostPrivateIP(h)
+			fillInventoryAddresses(h, &item)
+		}
+
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	return items
+}
+
+func fillInventoryAddresses(h *host.Host, item *InventoryItem) {
+	var err error
+	if item.IP, err = h.Driver.GetIP(); err != nil {
+		log.Debugf("Unable to get the IP of %s: %s", h.Name, err)
+	}
+
+	item.SSHUser = h.Driver.GetSSHUsername()
+	item.SSHKeyPath = h.Driver.GetSSHKeyPath()
+	if item.SSHPort, err = h.Driver.GetSSHPort(); err != nil {
+		log.Debugf("Unable to get the SSH port of %s: %s", h.Name, err)
+	}
+}
+
+// hostPrivateIP returns the private IP recorded by the drivers which have
+// one, read from the raw config as the drivers run as plugins.
+func hostPrivateIP(h *host.Host) string {
+	return drivers.PrivateIPFromConfig(h.RawDriver)
+}
+
+// parseEngineLabels returns the key=value engine labels as a map, the labels
+// without a value being mapped to an empty string.
+func parseEngineLabels(labels []string) map[string]string {
+	parsed := map[string]string{}
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		parsed[key] = value
+	}
+
+	return parsed
+}
+
+// inventoryGroups returns the groups of a machine, one for its driver and one
+// for each of its engine labels, the ones matched by the label filter of ls.
+func inventoryGroups(driverName string, labels map[string]string) []string {
+	groups := []string{groupName("driver", driverName)}
+
+	keys := []string{}
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		groups = append(groups, groupName("label", key, labels[key]))
+	}
+
+	return groups
+}
+
+func groupName(parts ...string) string {
+	name := strings.Join(parts, "_")
+	return strings.Trim(invalidGroupChars.ReplaceAllString(name, "_"), "_")
+}
+
+// ansibleInventory returns the inventory in the JSON format of the ansible
+// inventory scripts.
+func ansibleInventory(items []InventoryItem) map[string]interface{} {
+	all := []string{}
+	groups := map[string][]string{}
+	hostvars := map[string]map[string]interface{}{}
+
+	for _, item := range items {
+		all = append(all, item.Name)
+		for _, group := range item.Groups {
+			groups[group] = append(groups[group], item.Name)
+		}
+
+		vars := map[string]interface{}{
+			"docker_machine_driver": item.DriverName,
+			"docker_machine_state":  item.State,
+			"docker_machine_labels": item.Labels,
+		}
+		if item.IP != "" {
+			vars["ansible_host"] = item.IP
+		}
+		if item.SSHUser != "" {
+			vars["ansible_user"] = item.SSHUser
+		}
+		if item.SSHPort != 0 {
+			vars["ansible_port"] = item.SSHPort
+		}
+		if item.SSHKeyPath != "" {
+			vars["ansible_ssh_private_key_file"] = item.SSHKeyPath
+		}
+		if item.PrivateIP != "" {
+			vars["private_ip"] = item.PrivateIP
+		}
+		if item.URL != "" {
+			vars["docker_host"] = item.URL
+		}
+		hostvars[item.Name] = vars
+	}
+
+	inventory := map[string]interface{}{
+		"all":   map[string]interface{}{"hosts": all},
+		"_meta": map[string]interface{}{"hostvars": hostvars},
+	}
+	for group, hosts := range groups {
+		inventory[group] = map[string]interface{}{"hosts": hosts}
+	}
+
+	return inventory
+}
+
+// prometheusSDTarget is a target group of the Prometheus file-based service
+// discovery.
+type prometheusSDTarget struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// prometheusSDTargets returns the daemon metrics endpoints of the machines,
+// at the given port or at the port of their engine metrics address. The
+// machines without an IP or a port are skipped.
+func prometheusSDTargets(items []InventoryItem, port string) []prometheusSDTarget {
+	targets := []prometheusSDTarget{}
+	for _, item := range items {
+		targetPort := port
+		if targetPort == "" && item.MetricsAddr != "" {
+			_, targetPort, _ = net.SplitHostPort(item.MetricsAddr)
+		}
+		if item.IP == "" || targetPort == "" {
+			log.Debugf("Skipping %s, it has no IP or metrics port", item.Name)
+			continue
+		}
+
+		labels := map[string]string{
+			"machine":        item.Name,
+			"machine_driver": item.DriverName,
+		}
+		for key, value := range item.Labels {
+			labels["label_"+invalidGroupChars.ReplaceAllString(key, "_")] = value
+		}
+
+		targets = append(targets, prometheusSDTarget{
+			Targets: []string{net.JoinHostPort(item.IP, targetPort)},
+			Labels:  labels,
+		})
+	}
+
+	return targets
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventoryGroups(t *testing.T) {
+	groups := inventoryGroups("amazonec2", map[string]string{"tier": "web", "env": "prod-1"})
+
+	assert.Equal(t, []string{"driver_amazonec2", "label_env_prod_1", "label_tier_web"}, groups)
+}
+
+func TestHostPrivateIP(t *testing.T) {
+	assert.Equal(t, "10.0.0.5", hostPrivateIP(&host.Host{RawDriver: []byte(`{"PrivateIPAddress":"10.0.0.5"}`)}))
+	assert.Equal(t, "10.0.0.6", hostPrivateIP(&host.Host{RawDriver: []byte(`{"PrivateIPAddr":"10.0.0.6"}`)}))
+	assert.Empty(t, hostPrivateIP(&host.Host{RawDriver: []byte(`{}`)}))
+}
+
+func TestGetInventoryItems(t *testing.T) {
+	hosts := []*host.Host{
+		{
+			Name:   "foo",
+			Driver: &fakedriver.Driver{MockState: state.Running, MockIP: "1.2.3.4"},
+			HostOptions: &host.Options{
+				EngineOptions: &engine.Options{
+					Labels:      []string{"env=prod"},
+					MetricsAddr: "0.0.0.0:9323",
+				},
+			},
+			RawDriver: []byte(`{"PrivateIPAddress":"10.0.0.5"}`),
+		},
+		{
+			Name:   "bar",
+			Driver: &fakedriver.Driver{MockState: state.Stopped},
+		},
+	}
+
+	items := getInventoryItems(hosts, nil, 5*time.Second)
+
+	// The fake machines have no daemon to query.
+	assert.Len(t, items, 2)
+	assert.Contains(t, items[1].Error, "Unable to query docker version")
+	items[1].Error = ""
+
+	assert.Equal(t, []InventoryItem{
+		{
+			Name:       "bar",
+			DriverName: "Driver",
+			State:      "Stopped",
+			Labels:     map[string]string{},
+			Groups:     []string{"driver_Driver"},
+		},
+		{
+			Name:        "foo",
+			DriverName:  "Driver",
+			State:       "Running",
+			IP:          "1.2.3.4",
+			PrivateIP:   "10.0.0.5",
+			URL:         "tcp://1.2.3.4:2376",
+			Labels:      map[string]string{"env": "prod"},
+			Groups:      []string{"driver_Driver", "label_env_prod"},
+			MetricsAddr: "0.0.0.0:9323",
+		},
+	}, items)
+}
+
+func TestAnsibleInventory(t *testing.T) {
+	items := []InventoryItem{
+		{Name: "bar", DriverName: "google", State: "Stopped", Labels: map[string]string{}, Groups: []string{"driver_google"}},
+		{Name: "foo", DriverName: "amazonec2", State: "Running", IP: "1.2.3.4", SSHUser: "ubuntu", SSHPort: 22, URL: "tcp://1.2.3.4:2376", Labels: map[string]string{"env": "prod"}, Groups: []string{"driver_amazonec2", "label_env_prod"}},
+	}
+
+	inventory := ansibleInventory(items)
+
+	assert.Equal(t, map[string]interface{}{"hosts": []string{"bar", "foo"}}, inventory["all"])
+	assert.Equal(t, map[string]interface{}{"hosts": []string{"foo"}}, inventory["label_env_prod"])
+	assert.Equal(t, map[string]interface{}{"hosts": []string{"bar"}}, inventory["driver_google"])
+
+	hostvars := inventory["_meta"].(map[string]interface{})["hostvars"].(map[string]map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"ansible_host":          "1.2.3.4",
+		"ansible_user":          "ubuntu",
+		"ansible_port":          22,
+		"docker_host":           "tcp://1.2.3.4:2376",
+		"docker_machine_driver": "amazonec2",
+		"docker_machine_state":  "Running",
+		"docker_machine_labels": map[string]string{"env": "prod"},
+	}, hostvars["foo"])
+	assert.NotContains(t, hostvars["bar"], "ansible_host")
+}
+
+func TestPrometheusSDTargets(t *testing.T) {
+	items := []InventoryItem{
+		{Name: "foo", DriverName: "amazonec2", IP: "1.2.3.4", MetricsAddr: "0.0.0.0:9323", Labels: map[string]string{"env": "prod"}},
+		{Name: "bar", DriverName: "google", IP: "5.6.7.8", Labels: map[string]string{}},
+		{Name: "baz", DriverName: "google", Labels: map[string]string{}},
+	}
+
+	assert.Equal(t, []prometheusSDTarget{
+		{
+			Targets: []string{"1.2.3.4:9323"},
+			Labels:  map[string]string{"machine": "foo", "machine_driver": "amazonec2", "label_env": "prod"},
+		},
+	}, prometheusSDTargets(items, ""))
+
+	assert.Len(t, prometheusSDTargets(items, "9100"), 2)
+}
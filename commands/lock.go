@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+const defaultLockTimeout = time.Minute
+
+// cancelCheckInterval is how often a process holding machine locks checks
+// whether the cancellation of its operation was requested with ops --cancel.
+var cancelCheckInterval = time.Second
+
+// hostLockTimeout is how long mutating commands wait for a machine locked by
+// another process. It is set from the global --lock-timeout flag.
+var hostLockTimeout = defaultLockTimeout
+
+// lockHosts takes the lock of every named machine for operation, in a stable
+// order so that two processes locking overlapping sets can't deadlock. The
+// returned function releases them. The process exits when the cancellation of
+// the operation is requested while it holds the locks, leaving them to be
+// broken as stale.
+func lockHosts(api libmachine.API, operation string, names []string) (func(), error) {
+	sorted := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	locks := map[string]*persist.Lock{}
+	done := make(chan struct{})
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			for _, lock := range locks {
+				if err := lock.Release(); err != nil {
+					log.Warnf("Error releasing machine lock: %s", err)
+				}
+			}
+		})
+	}
+
+	for _, name := range sorted {
+		lock, err := persist.AcquireOperationLock(api.GetMachinesDir(), name, operation, hostLockTimeout)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		locks[name] = lock
+	}
+
+	if len(locks) > 0 {
+		go watchCancel(operation, locks, done)
+	}
+
+	return release, nil
+}
+
+// watchCancel exits the process when the cancellation of the operation of one
+// of the locked machines is requested, until done is closed. The locks are not
+// released first: the operation may still be writing the machine config, and
+// another process must not take the machine before the exit.
+func watchCancel(operation string, locks map[string]*persist.Lock, done chan struct{}) {
+	ticker := time.NewTicker(cancelCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for name, lock := range locks {
+				if lock.Cancelled() {
+					log.Errorf("The %s of %s was cancelled with ops --cancel", operation, name)
+					osExit(exitError)
+					return
+				}
+			}
+		}
+	}
+}
+
+// withHostsLocked runs the handler of a mutating command holding the lock of
+// the machines given as arguments, or of the default machine, for operation.
+// With --all, the handler locks each machine itself while it works on it,
+// see runLockedActionForeachMachine.
+func withHostsLocked(operation string, handler cmdHandler) cmdHandler {
+	return func(c CommandLine, api libmachine.API) error {
+		names := c.Args()
+		if len(names) == 0 && !c.Bool("all") {
+			if target, err := targetHost(c, api); err == nil {
+				names = []string{target}
+			}
+		}
+
+		release, err := lockHosts(api, operation, names)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(c, api)
+	}
+}
+
+// withHostLock runs fn holding the lock of the machine for operation.
+func withHostLock(api libmachine.API, operation string, h *host.Host, fn func() error) error {
+	release, err := lockHosts(api, operation, []string{h.Name})
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn()
+}
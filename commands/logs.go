@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/provision"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+func cmdLogs(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		return ErrExpectedOneMachine
+	}
+
+	var since time.Duration
+	if c.String("since") != "" {
+		var err error
+		if since, err = time.ParseDuration(c.String("since")); err != nil || since <= 0 {
+			return fmt.Errorf("invalid value for --since %q, expected a positive duration like 10m or 2h", c.String("since"))
+		}
+	}
+
+	target, err := targetHost(c, api)
+	if err != nil {
+		return err
+	}
+
+	host, err := api.Load(target)
+	if err != nil {
+		return err
+	}
+
+	currentState, err := host.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if currentState != state.Running {
+		return errStateInvalidForSSH{host.Name}
+	}
+
+	provisioner, err := provision.DetectProvisioner(host.Driver)
+	if err != nil {
+		return err
+	}
+
+	cmd, err := provision.DockerLogsCommand(provisioner, c.Bool("follow"), since)
+	if err != nil {
+		return err
+	}
+
+	client, err := host.CreateSSHClient()
+	if err != nil {
+		return err
+	}
+
+	return client.Shell(cmd)
+}
@@ -46,6 +46,30 @@ var (
 		"Error":         "ERRORS",
 		"DockerVersion": "DOCKER",
 		"ResponseTime":  "RESPONSE",
+		"Created":       "CREATED",
+		"Provisioning":  "PROVISIONING",
+		"CacheAge":      "CACHED",
+	}
+
+	// lsTemplateFuncs are the functions available in the templates of
+	// --format.
+	lsTemplateFuncs = template.FuncMap{
+		"colorize": colorizeState,
+		"ago":      ago,
+	}
+
+	// lsSortFields are the fields the items can be sorted by with --sort.
+	lsSortFields = map[string]func(a, b HostListItem) bool{
+		"name": func(a, b HostListItem) bool { return false },
+		"state": func(a, b HostListItem) bool {
+			return a.State.String() < b.State.String()
+		},
+		"driver": func(a, b HostListItem) bool {
+			return a.DriverName < b.DriverName
+		},
+		"created": func(a, b HostListItem) bool {
+			return a.Created.Before(b.Created)
+		},
 	}
 )
 
@@ -63,6 +87,16 @@ type HostListItem struct {
 	Error         string
 	DockerVersion string
 	ResponseTime  time.Duration
+	Created       time.Time
+	Provisioning  host.ProvisioningStatus
+	// DriverConfig are the driver flag values the machine was created
+	// with, see driverConfig.
+	DriverConfig map[string]interface{} `json:",omitempty"`
+	// Cached is when the item was written to the state cache.
+	Cached time.Time
+	// CacheAge is how long ago the item was cached, set when listing the
+	// state cache.
+	CacheAge string `json:"-"`
 }
 
 // FilterOptions -
@@ -80,25 +114,83 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 		return err
 	}
 
+	cached := c.Bool("cached")
+	if cached && c.Bool("cache-refresh") {
+		return invalidArguments(errCachedAndRefresh)
+	}
+
+	timeout := time.Duration(c.Int("timeout")) * time.Second
+
+	var refreshed []HostListItem
+	if c.Bool("cache-refresh") {
+		if refreshed, err = refreshLsCache(api, timeout); err != nil {
+			return err
+		}
+	}
+
 	hostList, hostInError, err := persist.LoadAllHosts(api)
 	if err != nil {
 		return err
 	}
 
+	// The states are known without querying the drivers when listing a
+	// snapshot, so they are filtered on afterwards.
+	snapshot := cached || refreshed != nil
+	stateFilters := filters.State
+	if snapshot {
+		filters.State = nil
+	}
+
 	hostList = filterHosts(hostList, filters)
 
 	// Just print out the names if we're being quiet
-	if c.Bool("quiet") {
+	if c.Bool("quiet") && !snapshot {
 		for _, host := range hostList {
 			fmt.Println(host.Name)
 		}
 		return nil
 	}
 
-	template, table, err := parseFormat(c.String("format"))
+	var items []HostListItem
+	switch {
+	case cached:
+		cache, err := readLsCache(lsCachePath())
+		if err != nil {
+			return err
+		}
+		items = filterCachedItems(cachedHostListItems(cache, hostList), stateFilters)
+	case refreshed != nil:
+		items = filterCachedItems(selectHostListItems(refreshed, hostList, hostInError), stateFilters)
+	}
+
+	if c.Bool("quiet") {
+		for _, item := range items {
+			fmt.Println(item.Name)
+		}
+		return nil
+	}
+
+	format, templateFile, err := templateFlag(c)
 	if err != nil {
 		return err
 	}
+	if format == "" && cached {
+		format = lsCachedFormat
+	}
+
+	template, table, err := parseFormat(format)
+	if err != nil {
+		return templateError(err, templateFile)
+	}
+
+	sortField := strings.ToLower(c.String("sort"))
+	if sortField == "" {
+		sortField = "name"
+	}
+	less, ok := lsSortFields[sortField]
+	if !ok {
+		return fmt.Errorf("unsupported sort field %q, must be one of name, state, driver or created", c.String("sort"))
+	}
 
 	var w io.Writer
 	if table {
@@ -108,17 +200,31 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 		w = tabWriter
 
 		if err := template.Execute(w, headers); err != nil {
-			return err
+			return templateError(err, templateFile)
 		}
 	} else {
 		w = os.Stdout
 	}
 
-	timeout := time.Duration(c.Int("timeout")) * time.Second
-	items := getHostListItems(hostList, hostInError, timeout)
+	if !snapshot {
+		items = getHostListItems(hostList, hostInError, timeout)
+		setSwarmColumns(items, hostList)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return less(items[i], items[j]) })
 
+	for _, item := range items {
+		if err := template.Execute(w, item); err != nil {
+			return templateError(err, templateFile)
+		}
+	}
+
+	return nil
+}
+
+// setSwarmColumns sets the swarm column of the items, the name of the master
+// of their swarm.
+func setSwarmColumns(items []HostListItem, hostList []*host.Host) {
 	swarmMasters := make(map[string]string)
-	swarmInfo := make(map[string]string)
 
 	for _, host := range hostList {
 		if host.HostOptions != nil {
@@ -126,14 +232,10 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 			if swarmOptions.Master {
 				swarmMasters[swarmOptions.Discovery] = host.Name
 			}
-
-			if swarmOptions.Discovery != "" {
-				swarmInfo[host.Name] = swarmOptions.Discovery
-			}
 		}
 	}
 
-	for _, item := range items {
+	for i, item := range items {
 		swarmColumn := ""
 		if item.SwarmOptions != nil && item.SwarmOptions.Discovery != "" {
 			swarmColumn = swarmMasters[item.SwarmOptions.Discovery]
@@ -141,14 +243,26 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 				swarmColumn = fmt.Sprintf("%s (master)", swarmColumn)
 			}
 		}
-		item.Swarm = swarmColumn
+		items[i].Swarm = swarmColumn
+	}
+}
 
-		if err := template.Execute(w, item); err != nil {
-			return err
+// selectHostListItems returns the items of the hosts of hostList and of the
+// hosts in error.
+func selectHostListItems(items []HostListItem, hostList []*host.Host, hostsInError map[string]error) []HostListItem {
+	names := map[string]bool{}
+	for _, h := range hostList {
+		names[h.Name] = true
+	}
+
+	selected := []HostListItem{}
+	for _, item := range items {
+		if _, inError := hostsInError[item.Name]; names[item.Name] || inError {
+			selected = append(selected, item)
 		}
 	}
 
-	return nil
+	return selected
 }
 
 func parseFormat(format string) (*template.Template, bool, error) {
@@ -168,7 +282,7 @@ func parseFormat(format string) (*template.Template, bool, error) {
 	r := strings.NewReplacer(`\t`, "\t", `\n`, "\n")
 	finalFormat = r.Replace(finalFormat)
 
-	template, err := template.New("").Parse(finalFormat + "\n")
+	template, err := template.New("").Funcs(lsTemplateFuncs).Parse(finalFormat + "\n")
 	if err != nil {
 		return nil, false, err
 	}
@@ -353,13 +467,17 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 	}
 
 	if err == nil && url != "" {
-		// PERFORMANCE: Reuse the url instead of asking the host again.
-		// This reduces the number of calls to the drivers
-		dockerHost := &mcndockerclient.RemoteDocker{
-			HostURL:    url,
-			AuthOption: h.AuthOptions(),
+		if h.DaemonSSHTransport() {
+			dockerVersion, err = h.DockerVersion()
+		} else {
+			// PERFORMANCE: Reuse the url instead of asking the host again.
+			// This reduces the number of calls to the drivers
+			dockerHost := &mcndockerclient.RemoteDocker{
+				HostURL:    url,
+				AuthOption: h.AuthOptions(),
+			}
+			dockerVersion, err = mcndockerclient.DockerVersion(dockerHost)
 		}
-		dockerVersion, err = mcndockerclient.DockerVersion(dockerHost)
 
 		if err != nil {
 			dockerVersion = "Unknown"
@@ -374,6 +492,9 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 	if hostError == drivers.ErrHostIsNotRunning.Error() {
 		hostError = ""
 	}
+	if hostError == "" && !h.IsProvisioned() {
+		hostError = fmt.Sprintf("Not provisioned (%s)", h.ProvisioningStatus)
+	}
 
 	var swarmOptions *swarm.Options
 	var engineOptions *engine.Options
@@ -412,7 +533,19 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		DockerVersion: dockerVersion,
 		Error:         hostError,
 		ResponseTime:  time.Now().Round(time.Millisecond).Sub(requestBeginning.Round(time.Millisecond)),
+		Created:       hostCreated(h),
+		Provisioning:  h.ProvisioningStatus,
+		DriverConfig:  driverConfig(h),
+	}
+}
+
+// hostCreated returns when the host was created, or the zero time for hosts
+// created before it was recorded.
+func hostCreated(h *host.Host) time.Time {
+	if h.HostOptions == nil {
+		return time.Time{}
 	}
+	return h.HostOptions.Created
 }
 
 func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem, timeout time.Duration) {
@@ -435,6 +568,8 @@ func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem, timeout t
 			DriverName:   h.Driver.DriverName(),
 			State:        state.Timeout,
 			ResponseTime: timeout,
+			Created:      hostCreated(h),
+			DriverConfig: driverConfig(h),
 		}
 	}
 }
@@ -504,3 +639,46 @@ func toSwarmURL(hostURL string, swarmHost string) string {
 	swarmPort := urlPort(swarmHost)
 	return strings.Replace(hostURL, ":"+hostPort, ":"+swarmPort, 1)
 }
+
+// colorizeState returns the state wrapped in the ANSI color matching it.
+func colorizeState(s state.State) string {
+	color := ""
+	switch s {
+	case state.Running:
+		color = "32"
+	case state.Starting, state.Stopping, state.Stopped, state.Paused, state.Saved, state.Preempted:
+		color = "33"
+	case state.Error, state.Timeout, state.NotFound:
+		color = "31"
+	default:
+		return s.String()
+	}
+
+	return fmt.Sprintf("\x1b[%sm%s\x1b[0m", color, s)
+}
+
+// ago returns how long ago t was in a readable form.
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "Unknown"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "Less than a minute ago"
+	case d < time.Hour:
+		return pluralize(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return pluralize(int(d/time.Hour), "hour") + " ago"
+	default:
+		return pluralize(int(d/(24*time.Hour)), "day") + " ago"
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"bytes"
 	"errors"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
@@ -472,6 +475,32 @@ func TestGetHostStateTimeout(t *testing.T) {
 	assert.Equal(t, time.Millisecond, hostItem.ResponseTime)
 }
 
+func TestGetHostStateDriverConfig(t *testing.T) {
+	hosts := []*host.Host{
+		{
+			Name: "foo",
+			Driver: &fakedriver.Driver{
+				MockState: state.Timeout,
+			},
+			HostOptions: &host.Options{
+				DriverOptions: map[string]interface{}{
+					"amazonec2-region":        "eu-west-1",
+					"amazonec2-instance-type": "t3.large",
+				},
+			},
+		},
+	}
+
+	hostItem := getHostListItems(hosts, nil, time.Millisecond)[0]
+
+	tmpl, _, err := parseFormat(`{{.Name}} {{index .DriverConfig "amazonec2-region"}} {{index .DriverConfig "amazonec2-instance-type"}}`)
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, tmpl.Execute(out, hostItem))
+	assert.Equal(t, "foo eu-west-1 t3.large\n", out.String())
+}
+
 func TestGetHostStateError(t *testing.T) {
 	hosts := []*host.Host{
 		{
@@ -531,3 +560,95 @@ func TestOnErrorWithMultilineComment(t *testing.T) {
 
 	assert.Equal(t, itemInError.Error, "missing parameter: the request must contain the parameter InstanceId	status code: 400")
 }
+
+func TestParseFormatFuncs(t *testing.T) {
+	template, table, err := parseFormat("{{ .Name }} {{ .State | colorize }} {{ ago .Created }}")
+	assert.NoError(t, err)
+	assert.False(t, table)
+
+	var buf bytes.Buffer
+	assert.NoError(t, template.Execute(&buf, HostListItem{
+		Name:    "foo",
+		State:   state.Running,
+		Created: time.Now().Add(-2 * time.Hour),
+	}))
+	assert.Equal(t, "foo \x1b[32mRunning\x1b[0m 2 hours ago\n", buf.String())
+}
+
+func TestAgo(t *testing.T) {
+	assert.Equal(t, "Unknown", ago(time.Time{}))
+	assert.Equal(t, "Less than a minute ago", ago(time.Now()))
+	assert.Equal(t, "1 minute ago", ago(time.Now().Add(-90*time.Second)))
+	assert.Equal(t, "3 days ago", ago(time.Now().Add(-73*time.Hour)))
+}
+
+func TestLsSortFields(t *testing.T) {
+	now := time.Now()
+	items := []HostListItem{
+		{Name: "a", DriverName: "virtualbox", State: state.Stopped, Created: now},
+		{Name: "b", DriverName: "amazonec2", State: state.Running, Created: now.Add(-time.Hour)},
+		{Name: "c", DriverName: "amazonec2", State: state.Stopped},
+	}
+
+	names := func(field string) []string {
+		sorted := append([]HostListItem{}, items...)
+		less := lsSortFields[field]
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		result := []string{}
+		for _, item := range sorted {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, names("name"))
+	assert.Equal(t, []string{"b", "a", "c"}, names("state"))
+	assert.Equal(t, []string{"b", "c", "a"}, names("driver"))
+	assert.Equal(t, []string{"c", "b", "a"}, names("created"))
+}
+
+func TestLsCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lsCacheFileName)
+
+	assert.NoError(t, writeLsCache(path, []HostListItem{
+		{Name: "foo", State: state.Running},
+		{Name: "bar", State: state.Stopped},
+		{Name: "gone", State: state.Running},
+	}, []string{"foo", "bar", "gone"}))
+
+	// Refreshing some machines keeps the others, except the removed ones.
+	assert.NoError(t, writeLsCache(path, []HostListItem{
+		{Name: "foo", State: state.Stopped},
+	}, []string{"foo", "bar"}))
+
+	cache, err := readLsCache(path)
+	assert.NoError(t, err)
+	assert.Len(t, cache.Items, 2)
+
+	hosts := []*host.Host{
+		{Name: "foo", DriverName: "fakedriver"},
+		{Name: "bar", DriverName: "fakedriver"},
+		{Name: "new", DriverName: "fakedriver"},
+	}
+
+	items := cachedHostListItems(cache, hosts)
+
+	assert.Equal(t, "bar", items[0].Name)
+	assert.Equal(t, state.Stopped, items[0].State)
+	assert.Equal(t, "Less than a minute ago", items[0].CacheAge)
+	assert.Equal(t, "foo", items[1].Name)
+	assert.Equal(t, state.Stopped, items[1].State)
+	assert.Equal(t, "new", items[2].Name)
+	assert.Equal(t, "Never", items[2].CacheAge)
+	assert.Equal(t, "Not cached, run refresh", items[2].Error)
+
+	filtered := filterCachedItems(items, []string{"stopped"})
+	assert.Len(t, filtered, 2)
+}
+
+func TestReadLsCacheMissing(t *testing.T) {
+	cache, err := readLsCache(filepath.Join(t.TempDir(), lsCacheFileName))
+
+	assert.NoError(t, err)
+	assert.Empty(t, cache.Items)
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rancher/machine/commands/mcndirs"
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+const (
+	lsCacheFileName = "ls-cache.json"
+	// lsCachedFormat is the default format of ls --cached, which shows the
+	// age of the entries.
+	lsCachedFormat = lsDefaultFormat + "\t{{ .CacheAge }}"
+)
+
+var errCachedAndRefresh = fmt.Errorf("--cached and --cache-refresh can't be used together")
+
+// lsCache is the snapshot of the states of the machines written by
+// ls --cache-refresh and refresh, and read by ls --cached.
+type lsCache struct {
+	Items []HostListItem
+}
+
+func lsCachePath() string {
+	return filepath.Join(mcndirs.GetBaseDir(), lsCacheFileName)
+}
+
+// readLsCache reads the snapshot, or returns an empty one when none was
+// written yet.
+func readLsCache(path string) (*lsCache, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &lsCache{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &lsCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("invalid state cache %s: %s", path, err)
+	}
+
+	return cache, nil
+}
+
+// writeLsCache updates the snapshot with items, keeping the entries of the
+// other machines still in the store. The file is replaced atomically as
+// dashboards may read it at any time.
+func writeLsCache(path string, items []HostListItem, names []string) error {
+	cache, err := readLsCache(path)
+	if err != nil {
+		log.Warnf("Replacing the state cache: %s", err)
+		cache = &lsCache{}
+	}
+
+	stored := map[string]bool{}
+	for _, name := range names {
+		stored[name] = true
+	}
+
+	refreshed := map[string]bool{}
+	now := time.Now().UTC()
+	merged := []HostListItem{}
+	for _, item := range items {
+		item.Cached = now
+		refreshed[item.Name] = true
+		merged = append(merged, item)
+	}
+	for _, item := range cache.Items {
+		if !refreshed[item.Name] && stored[item.Name] {
+			merged = append(merged, item)
+		}
+	}
+	sortHostListItemsByName(merged)
+
+	data, err := json.MarshalIndent(&lsCache{Items: merged}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), lsCacheFileName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// cachedHostListItems returns the cached items of hosts, with their age. The
+// hosts missing from the snapshot are listed without a state.
+func cachedHostListItems(cache *lsCache, hostList []*host.Host) []HostListItem {
+	cached := map[string]HostListItem{}
+	for _, item := range cache.Items {
+		cached[item.Name] = item
+	}
+
+	items := []HostListItem{}
+	for _, h := range hostList {
+		item, ok := cached[h.Name]
+		if !ok {
+			items = append(items, HostListItem{
+				Name:       h.Name,
+				Active:     "-",
+				DriverName: h.DriverName,
+				Error:      "Not cached, run refresh",
+				Created:    hostCreated(h),
+				CacheAge:   "Never",
+			})
+			continue
+		}
+
+		item.CacheAge = ago(item.Cached)
+		items = append(items, item)
+	}
+
+	sortHostListItemsByName(items)
+	return items
+}
+
+// filterCachedItems keeps the items matching the state filters, which are
+// checked against the cached states instead of querying the drivers.
+func filterCachedItems(items []HostListItem, states []string) []HostListItem {
+	if len(states) == 0 {
+		return items
+	}
+
+	filtered := []HostListItem{}
+	for _, item := range items {
+		for _, s := range states {
+			if strings.EqualFold(s, item.State.String()) {
+				filtered = append(filtered, item)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// refreshLsCache queries the states of all the machines concurrently and
+// writes them to the snapshot.
+func refreshLsCache(api libmachine.API, timeout time.Duration) ([]HostListItem, error) {
+	hostList, hostInError, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return nil, err
+	}
+
+	items := getHostListItems(hostList, hostInError, timeout)
+	setSwarmColumns(items, hostList)
+
+	names := []string{}
+	for _, h := range hostList {
+		names = append(names, h.Name)
+	}
+
+	if err := writeLsCache(lsCachePath(), items, names); err != nil {
+		return nil, fmt.Errorf("error writing the state cache: %s", err)
+	}
+
+	return items, nil
+}
+
+func cmdRefresh(c CommandLine, api libmachine.API) error {
+	items, err := refreshLsCache(api, time.Duration(c.Int("timeout"))*time.Second)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Refreshed the state of %d machine(s) in %s", len(items), lsCachePath())
+	return nil
+}
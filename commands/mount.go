@@ -9,6 +9,7 @@ import (
 
 	"github.com/rancher/machine/libmachine"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/ssh"
 )
 
 var (
@@ -76,6 +77,8 @@ func getMountCmd(src, dest string, unmount bool, hostInfoLoader HostInfoLoader)
 		sshArgs = append(sshArgs, "-o", "IdentitiesOnly=yes")
 	}
 
+	sshArgs = append(sshArgs, ssh.AlgorithmArgs()...)
+
 	// Append needed -i / private key flags to command.
 	sshArgs = append(sshArgs, srcOpts...)
 
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"text/template"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+const (
+	createDefaultParallel = 5
+
+	namePatternRandomChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// namePatternData is given to the --name-pattern template for every machine.
+type namePatternData struct {
+	// Index is the 1-based position of the machine in the batch.
+	Index int
+}
+
+func namePatternFuncs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		// random returns n random lowercase letters and digits.
+		"random": func(n int) (string, error) {
+			suffix := make([]byte, n)
+			for i := range suffix {
+				j, err := rand.Int(rand.Reader, big.NewInt(int64(len(namePatternRandomChars))))
+				if err != nil {
+					return "", err
+				}
+				suffix[i] = namePatternRandomChars[j.Int64()]
+			}
+			return string(suffix), nil
+		},
+		// timestamp is the same for the whole batch.
+		"timestamp": func() string {
+			return now.UTC().Format("20060102150405")
+		},
+	}
+}
+
+// generateMachineNames renders the name pattern count times. Every name is
+// validated and must be unique in the batch.
+func generateMachineNames(pattern string, count int, now time.Time) ([]string, error) {
+	tmpl, err := template.New("name-pattern").Funcs(namePatternFuncs(now)).Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing name pattern: %s", err)
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for i := 1; i <= count; i++ {
+		var name bytes.Buffer
+		if err := tmpl.Execute(&name, namePatternData{Index: i}); err != nil {
+			return nil, fmt.Errorf("error executing name pattern: %s", err)
+		}
+
+		if !host.ValidateHostName(name.String()) {
+			return nil, fmt.Errorf("name pattern generated %q: %s", name.String(), mcnerror.ErrInvalidHostname)
+		}
+
+		if seen[name.String()] {
+			return nil, fmt.Errorf("name pattern generated %q more than once, use {{.Index}} or {{random 6}} to make names unique", name.String())
+		}
+		seen[name.String()] = true
+
+		names = append(names, name.String())
+	}
+
+	return names, nil
+}
+
+// checkMachineNamesAvailable fails if any of the names is already used by a
+// machine in the store.
+func checkMachineNamesAvailable(names []string, api libmachine.API) error {
+	for _, name := range names {
+		exists, err := api.Exists(name)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return mcnerror.ErrHostAlreadyExists{
+				Name: name,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateMachineNames(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	names, err := generateMachineNames("web-{{.Index}}", 3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "web-2", "web-3"}, names)
+
+	names, err = generateMachineNames("web-{{timestamp}}-{{.Index}}", 1, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-20200102030405-1"}, names)
+
+	names, err = generateMachineNames("web-{{random 6}}", 2, now)
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+	assert.Regexp(t, regexp.MustCompile(`^web-[a-z0-9]{6}$`), names[0])
+}
+
+func TestGenerateMachineNamesErrors(t *testing.T) {
+	now := time.Now()
+
+	_, err := generateMachineNames("web", 2, now)
+	assert.Error(t, err)
+
+	_, err = generateMachineNames("web_{{.Index}}", 1, now)
+	assert.Error(t, err)
+
+	_, err = generateMachineNames("web-{{.Unknown}}", 1, now)
+	assert.Error(t, err)
+}
+
+func TestCheckMachineNamesAvailable(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{Name: "web-2"},
+		},
+	}
+
+	assert.NoError(t, checkMachineNamesAvailable([]string{"web-1", "web-3"}, api))
+	assert.EqualError(t, checkMachineNamesAvailable([]string{"web-1", "web-2"}, api), `Docker machine "web-2" already exists`)
+}
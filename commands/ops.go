@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+func cmdOps(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 0 {
+		return invalidArguments(errors.New("Error: ops does not take any argument, use --cancel to cancel an operation"))
+	}
+
+	if name := c.String("cancel"); name != "" {
+		return cancelOperation(os.Stdout, api.GetMachinesDir(), name)
+	}
+
+	locked, err := persist.ListLocks(api.GetMachinesDir())
+	if err != nil {
+		return err
+	}
+
+	printOperations(os.Stdout, locked)
+	return nil
+}
+
+func printOperations(out io.Writer, locked []persist.LockedMachine) {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tOPERATION\tPID\tHOST\tSTARTED")
+	for _, l := range locked {
+		operation := l.Owner.Operation
+		if operation == "" {
+			operation = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", l.Name, operation, l.Owner.PID, l.Owner.Hostname, l.Owner.Created.Format(time.RFC3339))
+	}
+}
+
+// cancelOperation requests the process running an operation on a machine to
+// cancel it. The process exits within a second or so, leaving the machine as
+// the operation left it.
+func cancelOperation(out io.Writer, machinesDir, name string) error {
+	owner, err := persist.RequestCancel(machinesDir, name)
+	if err != nil {
+		return err
+	}
+
+	operation := owner.Operation
+	if operation == "" {
+		operation = "operation"
+	}
+	fmt.Fprintf(out, "Requested the cancellation of the %s of %s by process %d on %s\n", operation, name, owner.PID, owner.Hostname)
+
+	return nil
+}
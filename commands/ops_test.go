@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+// machinesDirAPI is a FakeAPI storing its locks in dir.
+type machinesDirAPI struct {
+	*libmachinetest.FakeAPI
+	dir string
+}
+
+func (api *machinesDirAPI) GetMachinesDir() string {
+	return api.dir
+}
+
+func TestPrintOperations(t *testing.T) {
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	out := &bytes.Buffer{}
+
+	printOperations(out, []persist.LockedMachine{
+		{Name: "foo", Owner: persist.LockOwner{PID: 42, Hostname: "ci-1", Created: started, Operation: "create"}},
+		{Name: "bar", Owner: persist.LockOwner{PID: 7, Hostname: "ci-2", Created: started}},
+	})
+
+	assert.Equal(t, "NAME   OPERATION   PID   HOST   STARTED\n"+
+		"foo    create      42    ci-1   2024-05-01T10:00:00Z\n"+
+		"bar    -           7     ci-2   2024-05-01T10:00:00Z\n", out.String())
+}
+
+func TestCancelOperation(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.EqualError(t, cancelOperation(&bytes.Buffer{}, dir, "foo"), `no operation is in progress on "foo"`)
+
+	lock, err := persist.AcquireOperationLock(dir, "foo", "create", 0)
+	assert.NoError(t, err)
+	defer lock.Release()
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, cancelOperation(out, dir, "foo"))
+	assert.Contains(t, out.String(), "Requested the cancellation of the create of foo by process")
+	assert.True(t, lock.Cancelled())
+}
+
+func TestLockHostsExitsWhenCancelled(t *testing.T) {
+	defer func(interval time.Duration) { cancelCheckInterval = interval }(cancelCheckInterval)
+	cancelCheckInterval = 10 * time.Millisecond
+
+	exited := make(chan int, 1)
+	defer func(fnOsExit func(code int)) { osExit = fnOsExit }(osExit)
+	osExit = func(code int) { exited <- code }
+
+	api := &machinesDirAPI{FakeAPI: &libmachinetest.FakeAPI{}, dir: t.TempDir()}
+
+	release, err := lockHosts(api, "upgrade", []string{"foo"})
+	assert.NoError(t, err)
+	defer release()
+
+	_, err = persist.RequestCancel(api.GetMachinesDir(), "foo")
+	assert.NoError(t, err)
+
+	select {
+	case code := <-exited:
+		assert.Equal(t, exitError, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the process to exit once the operation was cancelled")
+	}
+
+	// The lock is kept until the process is gone.
+	locked, err := persist.ListLocks(api.GetMachinesDir())
+	assert.NoError(t, err)
+	assert.Len(t, locked, 1)
+}
@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancher/machine/commands/mcndirs"
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+)
+
+var (
+	errNoProfileName = errors.New("Error: Expected a profile name as the first argument")
+
+	// profileFlagAliases maps the short create flags to their long name.
+	profileFlagAliases = map[string]string{
+		"d": "driver",
+	}
+)
+
+// profile is a named set of create flags. Options maps the long flag names to
+// their values, boolean flags given without a value have none.
+type profile struct {
+	Name    string `json:"-"`
+	Driver  string
+	Options map[string][]string
+}
+
+func profilePath(name string) string {
+	return filepath.Join(mcndirs.GetProfileDir(), name+".json")
+}
+
+func loadProfile(name string) (*profile, error) {
+	data, err := os.ReadFile(profilePath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %q does not exist", name)
+		}
+		return nil, fmt.Errorf("error reading profile %q: %s", name, err)
+	}
+
+	p := &profile{Name: name}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("error parsing profile %q: %s", name, err)
+	}
+
+	return p, nil
+}
+
+func saveProfile(p *profile) error {
+	data, err := json.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(mcndirs.GetProfileDir(), 0700); err != nil {
+		return err
+	}
+
+	// Profiles may hold provider credentials.
+	return os.WriteFile(profilePath(p.Name), data, 0600)
+}
+
+func listProfiles() ([]*profile, error) {
+	files, err := filepath.Glob(filepath.Join(mcndirs.GetProfileDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []*profile{}
+	for _, file := range files {
+		p, err := loadProfile(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+
+	return profiles, nil
+}
+
+// parseProfileArgs reads the create and driver flags of `profile save`. As no
+// argument follows them, a flag followed by a word takes it as its value.
+func parseProfileArgs(args []string) (map[string][]string, error) {
+	options := map[string][]string{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || strings.Trim(arg, "-") == "" {
+			return nil, fmt.Errorf("unexpected argument %q, expected a flag", arg)
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value, hasValue = parts[0], parts[1], true
+		} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			value, hasValue = args[i+1], true
+			i++
+		}
+
+		if alias, ok := profileFlagAliases[name]; ok {
+			name = alias
+		}
+
+		if name == "profile" {
+			return nil, errors.New("a profile can't use another profile")
+		}
+
+		if hasValue {
+			options[name] = append(options[name], value)
+		} else if _, ok := options[name]; !ok {
+			options[name] = []string{}
+		}
+	}
+
+	return options, nil
+}
+
+// applyProfile returns the create arguments with the --profile flag replaced
+// by the flags of the profile. The flags given on the command line override
+// the ones of the profile.
+func applyProfile(p *profile, args []string) []string {
+	given := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--profile" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(args[i], "--profile=") {
+			continue
+		}
+		given = append(given, args[i])
+	}
+
+	names := []string{}
+	for name := range p.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	applied := []string{}
+	for _, name := range names {
+		short := ""
+		for alias, long := range profileFlagAliases {
+			if long == name {
+				short = "-" + alias
+			}
+		}
+
+		if _, ok := getFlagValue(given, "--"+name, short, ""); ok {
+			continue
+		}
+
+		values := p.Options[name]
+		if len(values) == 0 {
+			applied = append(applied, "--"+name)
+		}
+		for _, value := range values {
+			applied = append(applied, "--"+name+"="+value)
+		}
+	}
+
+	return append(applied, given...)
+}
+
+// withProfile replaces the --profile flag of create by the flags of the
+// profile and reruns the command, before the driver flags are resolved.
+func withProfile(handler cmdHandler) cmdHandler {
+	return func(c CommandLine, api libmachine.API) error {
+		name, ok := getFlagValue(c.Args(), "--profile", "", "")
+		if !ok {
+			return handler(c, api)
+		}
+
+		if name == "" {
+			return errors.New("--profile requires a profile name")
+		}
+
+		p, err := loadProfile(name)
+		if err != nil {
+			return err
+		}
+
+		args := append([]string{}, os.Args[:len(os.Args)-len(c.Args())]...)
+		os.Args = append(args, applyProfile(p, c.Args())...)
+
+		return c.Application().Run(os.Args)
+	}
+}
+
+func cmdProfileSave(c CommandLine, api libmachine.API) error {
+	name := c.Args().First()
+	if name == "" || strings.HasPrefix(name, "-") {
+		return errNoProfileName
+	}
+
+	if !host.ValidateHostName(name) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+
+	options, err := parseProfileArgs(c.Args().Tail())
+	if err != nil {
+		return err
+	}
+
+	p := &profile{
+		Name:    name,
+		Options: options,
+	}
+	if driver := options["driver"]; len(driver) > 0 {
+		p.Driver = driver[len(driver)-1]
+	}
+
+	if err := saveProfile(p); err != nil {
+		return fmt.Errorf("error saving profile %q: %s", name, err)
+	}
+
+	fmt.Printf("Saved profile %s\n", name)
+	return nil
+}
+
+func cmdProfileList(c CommandLine, api libmachine.API) error {
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tDRIVER\tFLAGS")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", p.Name, p.Driver, len(p.Options))
+	}
+
+	return nil
+}
+
+func cmdProfileRm(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) == 0 {
+		return errors.New("Error: Expected one or more profile names as arguments")
+	}
+
+	errs := []error{}
+	for _, name := range c.Args() {
+		if err := os.Remove(profilePath(name)); err != nil {
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("profile %q does not exist", name)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		fmt.Printf("Removed profile %s\n", name)
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
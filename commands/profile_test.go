@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/commands/mcndirs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfileArgs(t *testing.T) {
+	options, err := parseProfileArgs([]string{
+		"-d", "amazonec2",
+		"--amazonec2-region=eu-west-1",
+		"--engine-label", "env=prod",
+		"--engine-label", "team=web",
+		"--amazonec2-use-private-address",
+		"--amazonec2-instance-type", "t3.large",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"driver":                        {"amazonec2"},
+		"amazonec2-region":              {"eu-west-1"},
+		"engine-label":                  {"env=prod", "team=web"},
+		"amazonec2-use-private-address": {},
+		"amazonec2-instance-type":       {"t3.large"},
+	}, options)
+
+	_, err = parseProfileArgs([]string{"--driver", "amazonec2", "name", "other"})
+	assert.Error(t, err)
+
+	_, err = parseProfileArgs([]string{"--profile", "other"})
+	assert.Error(t, err)
+}
+
+func TestApplyProfile(t *testing.T) {
+	p := &profile{
+		Driver: "amazonec2",
+		Options: map[string][]string{
+			"driver":                        {"amazonec2"},
+			"amazonec2-region":              {"eu-west-1"},
+			"amazonec2-use-private-address": {},
+			"engine-label":                  {"env=prod", "team=web"},
+		},
+	}
+
+	args := applyProfile(p, []string{"--profile", "prod", "--amazonec2-region", "us-east-1", "web-1"})
+	assert.Equal(t, []string{
+		"--amazonec2-use-private-address",
+		"--driver=amazonec2",
+		"--engine-label=env=prod",
+		"--engine-label=team=web",
+		"--amazonec2-region", "us-east-1", "web-1",
+	}, args)
+
+	args = applyProfile(p, []string{"--profile=prod", "-d", "google", "web-1"})
+	assert.NotContains(t, args, "--driver=amazonec2")
+	assert.Equal(t, []string{"-d", "google", "web-1"}, args[len(args)-3:])
+}
+
+func TestProfileSaveListRm(t *testing.T) {
+	defer func(dir string) { mcndirs.BaseDir = dir }(mcndirs.BaseDir)
+	mcndirs.BaseDir = t.TempDir()
+
+	c := &commandstest.FakeCommandLine{
+		CliArgs: []string{"prod", "-d", "amazonec2", "--amazonec2-region", "eu-west-1"},
+	}
+	assert.NoError(t, cmdProfileSave(c, nil))
+
+	p, err := loadProfile("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "amazonec2", p.Driver)
+	assert.Equal(t, []string{"eu-west-1"}, p.Options["amazonec2-region"])
+
+	profiles, err := listProfiles()
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 1)
+
+	assert.NoError(t, cmdProfileRm(&commandstest.FakeCommandLine{CliArgs: []string{"prod"}}, nil))
+	assert.Error(t, cmdProfileRm(&commandstest.FakeCommandLine{CliArgs: []string{"prod"}}, nil))
+
+	_, err = loadProfile("prod")
+	assert.Error(t, err)
+}
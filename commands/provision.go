@@ -1,7 +1,35 @@
 package commands
 
-import "github.com/rancher/machine/libmachine"
+import (
+	"fmt"
+	"os"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/host"
+)
+
+type errNotProvisioned struct {
+	HostName string
+	Status   host.ProvisioningStatus
+}
+
+func (e errNotProvisioned) Error() string {
+	return fmt.Sprintf("Machine %q is not provisioned (status: %s), run '%s provision %s' to provision it", e.HostName, e.Status, os.Args[0], e.HostName)
+}
 
 func cmdProvision(c CommandLine, api libmachine.API) error {
-	return runAction("provision", c, api)
+	return runActionPreparing("provision", c, api, func(h *host.Host) {
+		if c.Bool("ssh-agent-forward") {
+			drivers.SetSSHAgentForward(h.Driver, true)
+		}
+	})
+}
+
+// checkProvisioned returns an error if the host is not ready to be used.
+func checkProvisioned(h *host.Host) error {
+	if !h.IsProvisioned() {
+		return errNotProvisioned{HostName: h.Name, Status: h.ProvisioningStatus}
+	}
+	return nil
 }
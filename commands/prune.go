@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/persist"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+func cmdPrune(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 0 {
+		c.ShowHelp()
+		return errors.New("Error: prune does not take any argument")
+	}
+
+	hosts, hostsInError, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return err
+	}
+
+	for name, err := range hostsInError {
+		log.Warnf("Skipping %s, its configuration could not be loaded: %s", name, err)
+	}
+
+	if err := pruneHosts(hosts, api, c.Bool("force")); err != nil {
+		return err
+	}
+
+	if c.Bool("ssh-keys") {
+		return pruneSSHKeys(hosts, api, c.Bool("force"))
+	}
+
+	return nil
+}
+
+// pruneHosts removes the local entry of every host whose driver reports that
+// the instance does not exist anymore. Hosts whose state can't be determined
+// are kept: an unreachable API must never lead to a removal.
+func pruneHosts(hosts []*host.Host, api libmachine.API, force bool) error {
+	var errorOccurred []string
+	pruned := 0
+
+	for _, h := range hosts {
+		currentState, err := h.Driver.GetState()
+		if errors.Is(err, mcnerror.ErrInstanceNotFound) {
+			currentState, err = state.NotFound, nil
+		}
+		if err != nil {
+			log.Warnf("Skipping %s, its state could not be determined: %s", h.Name, err)
+			continue
+		}
+
+		if currentState != state.NotFound {
+			continue
+		}
+
+		log.Infof("The instance of %s does not exist anymore", h.Name)
+		if !force {
+			ok, err := confirmInput(fmt.Sprintf("Remove the local entry of %s?", h.Name))
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		if err := api.Remove(h.Name); err != nil {
+			errorOccurred = append(errorOccurred, fmt.Sprintf("Can't remove \"%s\": %s", h.Name, err))
+			continue
+		}
+
+		log.Infof("Successfully removed %s", h.Name)
+		pruned++
+	}
+
+	if pruned == 0 && len(errorOccurred) == 0 {
+		log.Info("Nothing to prune")
+	}
+
+	if len(errorOccurred) > 0 {
+		return errors.New(strings.Join(errorOccurred, "\n"))
+	}
+
+	return nil
+}
+
+// pruneSSHKeys deletes the SSH keys uploaded by machine, found through the
+// drivers of the hosts, whose machine doesn't exist anymore and that no
+// instance uses.
+func pruneSSHKeys(hosts []*host.Host, api libmachine.API, force bool) error {
+	names, err := api.List()
+	if err != nil {
+		return err
+	}
+
+	machines := map[string]bool{}
+	for _, name := range names {
+		machines[name] = true
+	}
+
+	var errorOccurred []string
+	seen := map[string]bool{}
+	pruned := 0
+
+	for _, h := range hosts {
+		keys, err := drivers.MachineSSHKeys(h.Driver)
+		if errors.Is(err, mcnerror.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			log.Warnf("Skipping the SSH keys of %s, they could not be listed: %s", h.Name, err)
+			continue
+		}
+
+		for _, key := range keys {
+			// Hosts sharing an account list the same keys.
+			id := h.DriverName + "/" + key.ID
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			if key.InUse || machines[key.MachineName] {
+				continue
+			}
+
+			log.Infof("The %s SSH key %s of machine %s is dangling", h.DriverName, key.Name, key.MachineName)
+			if !force {
+				ok, err := confirmInput(fmt.Sprintf("Delete the SSH key %s?", key.Name))
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					continue
+				}
+			}
+
+			if err := drivers.DeleteSSHKey(h.Driver, key.ID); err != nil {
+				errorOccurred = append(errorOccurred, fmt.Sprintf("Can't delete the SSH key \"%s\": %s", key.Name, err))
+				continue
+			}
+
+			log.Infof("Successfully deleted the SSH key %s", key.Name)
+			pruned++
+		}
+	}
+
+	if pruned == 0 && len(errorOccurred) == 0 {
+		log.Info("No dangling SSH key")
+	}
+
+	if len(errorOccurred) > 0 {
+		return errors.New(strings.Join(errorOccurred, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type notFoundDriver struct {
+	fakedriver.Driver
+}
+
+func (d *notFoundDriver) GetState() (state.State, error) {
+	return state.NotFound, mcnerror.NotFound(errors.New("instance i-123 not found"))
+}
+
+func TestPruneHosts(t *testing.T) {
+	hosts := []*host.Host{
+		{
+			Name:   "gone",
+			Driver: &fakedriver.Driver{MockState: state.NotFound},
+		},
+		{
+			Name:   "terminated",
+			Driver: &notFoundDriver{},
+		},
+		{
+			Name:   "running",
+			Driver: &fakedriver.Driver{MockState: state.Running},
+		},
+		{
+			Name:   "stopped",
+			Driver: &fakedriver.Driver{MockState: state.Stopped},
+		},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: hosts,
+	}
+
+	err := pruneHosts(hosts, api, true)
+	assert.NoError(t, err)
+
+	assert.False(t, libmachinetest.Exists(api, "gone"))
+	assert.False(t, libmachinetest.Exists(api, "terminated"))
+	assert.True(t, libmachinetest.Exists(api, "running"))
+	assert.True(t, libmachinetest.Exists(api, "stopped"))
+}
+
+type fakeSSHKeyDriver struct {
+	fakedriver.Driver
+	keys    []drivers.MachineSSHKey
+	deleted []string
+}
+
+func (d *fakeSSHKeyDriver) MachineSSHKeys() ([]drivers.MachineSSHKey, error) {
+	return d.keys, nil
+}
+
+func (d *fakeSSHKeyDriver) DeleteSSHKey(id string) error {
+	d.deleted = append(d.deleted, id)
+	return nil
+}
+
+func TestPruneSSHKeys(t *testing.T) {
+	driver := &fakeSSHKeyDriver{
+		keys: []drivers.MachineSSHKey{
+			{ID: "key-1", Name: "dev-1", MachineName: "dev"},
+			{ID: "key-2", Name: "gone-2", MachineName: "gone"},
+			{ID: "key-3", Name: "other-3", MachineName: "other", InUse: true},
+		},
+	}
+	hosts := []*host.Host{
+		{Name: "dev", DriverName: "fake", Driver: driver},
+		{Name: "test", DriverName: "fake", Driver: driver},
+		{Name: "plain", DriverName: "none", Driver: &fakedriver.Driver{}},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: hosts,
+	}
+
+	err := pruneSSHKeys(hosts, api, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key-2"}, driver.deleted)
+}
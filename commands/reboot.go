@@ -0,0 +1,13 @@
+package commands
+
+import (
+	"github.com/rancher/machine/libmachine"
+)
+
+// cmdReboot reboots running machines, with the reboot API of their provider
+// when the driver has one and else from the guest. Unlike restart, which may
+// stop and start them, their instance is kept, with the data of its instance
+// store or its spot capacity.
+func cmdReboot(c CommandLine, api libmachine.API) error {
+	return runActionWaitHealthy("reboot", c, api)
+}
@@ -1,11 +1,44 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/cert"
+	"github.com/rancher/machine/libmachine/check"
+	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/persist"
+)
+
+const (
+	regenerateCertsDefaultParallel = 5
+)
+
+var (
+	errRegenerateCertsAllWithArgs = errors.New("Error: --all cannot be combined with machine names")
+	errRegenerateCertsOnlyFlags   = errors.New("Error: --client-only, --server-only and --client-certs cannot be combined")
 )
 
 func cmdRegenerateCerts(c CommandLine, api libmachine.API) error {
+	if c.Bool("all") && len(c.Args()) > 0 {
+		return errRegenerateCertsAllWithArgs
+	}
+
+	only := 0
+	for _, flag := range []string{"client-only", "server-only", "client-certs"} {
+		if c.Bool(flag) {
+			only++
+		}
+	}
+	if only > 1 {
+		return invalidArguments(errRegenerateCertsOnlyFlags)
+	}
+
 	if !c.Bool("force") {
 		ok, err := confirmInput("Regenerate TLS machine certs?  Warning: this is irreversible.")
 		if err != nil {
@@ -19,8 +52,209 @@ func cmdRegenerateCerts(c CommandLine, api libmachine.API) error {
 
 	log.Infof("Regenerating TLS certificates")
 
+	if c.Bool("client-only") {
+		return regenerateClientCerts(c, api)
+	}
+
+	// The server certs are the only ones regenerated without --client-certs,
+	// so --server-only just rules out the latter.
+	if c.Bool("all") {
+		return regenerateCertsForAll(c, api)
+	}
+
 	if c.Bool("client-certs") {
 		return runAction("configureAllAuth", c, api)
 	}
 	return runAction("configureAuth", c, api)
 }
+
+// regenerateCertsForAll reissues the certs of every machine in the store,
+// `--parallel` machines at a time. Each machine's daemon is checked with the
+// new certs before it is reported as successful, and a failure on one machine
+// does not abort the others.
+func regenerateCertsForAll(c CommandLine, api libmachine.API) error {
+	hosts, hostsInError, err := persist.LoadAllHosts(api)
+	if err != nil {
+		return err
+	}
+
+	for name, err := range hostsInError {
+		log.Warnf("Skipping %s, its configuration could not be loaded: %s", name, err)
+	}
+
+	if len(hosts) == 0 {
+		return ErrHostLoad
+	}
+
+	// The client certs and the CA are shared by every machine using the same
+	// CA, so they are regenerated once up front rather than racing from each
+	// goroutine.
+	if c.Bool("client-certs") {
+		log.Info("Regenerating local certificates")
+		bootstrapped := map[string]bool{}
+		for _, h := range hosts {
+			authOptions := h.AuthOptions()
+			if authOptions == nil || bootstrapped[authOptions.CaCertPath] {
+				continue
+			}
+			if err := cert.BootstrapCertificates(authOptions); err != nil {
+				return err
+			}
+			bootstrapped[authOptions.CaCertPath] = true
+		}
+	}
+
+	parallel := c.Int("parallel")
+	if parallel <= 0 {
+		parallel = regenerateCertsDefaultParallel
+	}
+
+	errs := runForeachHostLimited(hosts, parallel, func(h *host.Host) error {
+		release, err := lockHosts(api, "regenerate-certs", []string{h.Name})
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return regenerateAndVerifyCerts(h, api)
+	})
+
+	printRegenerateCertsSummary(hosts, errs)
+
+	if len(errs) > 0 {
+		return fleetError(len(errs), len(hosts), fmt.Errorf("Error: certificates could not be regenerated for %d of %d machines", len(errs), len(hosts)))
+	}
+
+	return nil
+}
+
+// regenerateClientCerts reissues the client cert of the machines with their
+// existing CA, once per CA, copies it to the machine directories and checks
+// that the daemons accept it. The daemons are not restarted.
+func regenerateClientCerts(c CommandLine, api libmachine.API) error {
+	var hosts []*host.Host
+	if c.Bool("all") {
+		loaded, hostsInError, err := persist.LoadAllHosts(api)
+		if err != nil {
+			return err
+		}
+		for name, err := range hostsInError {
+			log.Warnf("Skipping %s, its configuration could not be loaded: %s", name, err)
+		}
+		hosts = loaded
+	} else {
+		names := c.Args()
+		if len(names) == 0 {
+			target, err := targetHost(c, api)
+			if err != nil {
+				return err
+			}
+			names = []string{target}
+		}
+
+		loaded, hostsInError := persist.LoadHosts(api, names)
+		if len(hostsInError) > 0 {
+			errs := []error{}
+			for _, err := range hostsInError {
+				errs = append(errs, err)
+			}
+			return consolidateErrs(errs)
+		}
+		hosts = loaded
+	}
+
+	if len(hosts) == 0 {
+		return ErrHostLoad
+	}
+
+	log.Info("Regenerating the client certificate")
+	regenerated := map[string]bool{}
+	for _, h := range hosts {
+		authOptions := h.AuthOptions()
+		if authOptions == nil || regenerated[authOptions.ClientCertPath] {
+			continue
+		}
+		if err := cert.RegenerateClientCert(authOptions); err != nil {
+			return fmt.Errorf("Error regenerating the client certificate %s: %s", authOptions.ClientCertPath, err)
+		}
+		regenerated[authOptions.ClientCertPath] = true
+	}
+
+	parallel := c.Int("parallel")
+	if parallel <= 0 {
+		parallel = regenerateCertsDefaultParallel
+	}
+
+	errs := runForeachHostLimited(hosts, parallel, func(h *host.Host) error {
+		// The machines named on the command line are already locked.
+		if c.Bool("all") {
+			release, err := lockHosts(api, "regenerate-certs", []string{h.Name})
+			if err != nil {
+				return err
+			}
+			defer release()
+		}
+
+		if err := h.ConfigureClientAuth(); err != nil {
+			return err
+		}
+
+		if h.AuthOptions() == nil {
+			return nil
+		}
+
+		if _, _, err := check.DefaultConnChecker.Check(h, false); err != nil {
+			return fmt.Errorf("daemon is not reachable with the new client certificate: %s", err)
+		}
+
+		return nil
+	})
+
+	printRegenerateCertsSummary(hosts, errs)
+
+	if len(errs) > 0 {
+		return fleetError(len(errs), len(hosts), fmt.Errorf("Error: the client certificate could not be used for %d of %d machines", len(errs), len(hosts)))
+	}
+
+	return nil
+}
+
+func regenerateAndVerifyCerts(h *host.Host, api libmachine.API) error {
+	if err := h.ConfigureAuth(); err != nil {
+		return err
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error saving host to store: %s", err)
+	}
+
+	if h.AuthOptions() == nil {
+		return nil
+	}
+
+	if _, _, err := check.DefaultConnChecker.Check(h, false); err != nil {
+		return fmt.Errorf("daemon is not reachable with the new certificates: %s", err)
+	}
+
+	return nil
+}
+
+func printRegenerateCertsSummary(hosts []*host.Host, errs map[string]error) {
+	names := []string{}
+	for _, h := range hosts {
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tRESULT\tERROR")
+	for _, name := range names {
+		if err, ok := errs[name]; ok {
+			fmt.Fprintf(w, "%s\tFAILED\t%s\n", name, err)
+		} else {
+			fmt.Fprintf(w, "%s\tOK\t\n", name)
+		}
+	}
+}
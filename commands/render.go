@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rancher/machine/libmachine"
+)
+
+var errRenderNoConfig = errors.New("Error: Expected a config file given with --config")
+
+// RenderedConfig is the driver and the flag values create would use for a
+// config file. It is itself a valid config file.
+type RenderedConfig struct {
+	Driver  string
+	Options map[string]interface{}
+}
+
+func cmdRender(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 0 {
+		return invalidArguments(errors.New("Error: render does not take any argument"))
+	}
+
+	file := c.String("config")
+	if file == "" {
+		return invalidArguments(errRenderNoConfig)
+	}
+
+	overrides, err := parseRenderOverrides(c.StringSlice("set"))
+	if err != nil {
+		return invalidArguments(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", file, err)
+	}
+
+	rendered, problems := renderConfig(api, data, overrides, c.GlobalString("env-prefix"))
+	if len(problems) > 0 {
+		printConfigProblems(os.Stderr, &ConfigValidation{File: file, Problems: problems})
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), file)
+	}
+
+	return printRenderedConfig(os.Stdout, rendered)
+}
+
+// parseRenderOverrides parses the key=value options of --set.
+func parseRenderOverrides(values []string) (map[string]interface{}, error) {
+	overrides := map[string]interface{}{}
+	for _, value := range values {
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", value)
+		}
+		overrides[key] = v
+	}
+
+	return overrides, nil
+}
+
+// renderConfig resolves the flag values of a config file with the options of
+// overrides set over its own, like validate-config without configuring the
+// driver.
+func renderConfig(api libmachine.API, data []byte, overrides map[string]interface{}, envPrefix string) (*RenderedConfig, []ConfigProblem) {
+	config, problems := parseMachineConfig(data)
+	if config == nil || len(problems) > 0 {
+		return nil, problems
+	}
+
+	for key, value := range overrides {
+		config.Options[key] = value
+	}
+	for _, name := range []string{"driver", "d"} {
+		if driver, ok := overrides[name]; ok {
+			config.Driver = driver.(string)
+		}
+	}
+
+	if config.Driver == "" {
+		return nil, []ConfigProblem{{Key: "driver", Problem: "no driver is set"}}
+	}
+
+	d, problem := loadConfigDriver(api, config.Driver, "render")
+	if problem != nil {
+		return nil, []ConfigProblem{*problem}
+	}
+
+	driverOpts, problems := resolveConfigOptions(d, config, envPrefix)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	// The create flag defaults to another driver when the file sets the
+	// top-level key only.
+	driverOpts.Values["driver"] = config.Driver
+
+	return &RenderedConfig{Driver: config.Driver, Options: driverOpts.Values}, nil
+}
+
+func printRenderedConfig(out io.Writer, rendered *RenderedConfig) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(rendered)
+}
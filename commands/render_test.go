@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConfigDriverAPI loads the fake config driver for any driver name.
+type fakeConfigDriverAPI struct {
+	libmachinetest.FakeAPI
+}
+
+func (api *fakeConfigDriverAPI) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	return &host.Host{DriverName: driverName, Driver: &fakeConfigDriver{}}, nil
+}
+
+func TestCmdRenderRequiresConfig(t *testing.T) {
+	err := cmdRender(&commandstest.FakeCommandLine{LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}}}, &fakeConfigDriverAPI{})
+	assert.Equal(t, invalidArguments(errRenderNoConfig), err)
+}
+
+func TestParseRenderOverrides(t *testing.T) {
+	overrides, err := parseRenderOverrides([]string{"fake-region=eu-west-1", "engine-label=a=b"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"fake-region": "eu-west-1", "engine-label": "a=b"}, overrides)
+
+	_, err = parseRenderOverrides([]string{"fake-region"})
+	assert.EqualError(t, err, `invalid --set "fake-region", expected key=value`)
+}
+
+func TestRenderConfig(t *testing.T) {
+	t.Setenv("FAKE_REGION", "us-east-1")
+
+	rendered, problems := renderConfig(&fakeConfigDriverAPI{}, []byte(`
+driver: fake
+options:
+  fake-count: 2
+  fake-tag: [a, b]
+`), map[string]interface{}{"fake-count": "3"}, "")
+
+	assert.Empty(t, problems)
+	assert.Equal(t, "fake", rendered.Driver)
+	assert.Equal(t, "us-east-1", rendered.Options["fake-region"])
+	assert.Equal(t, 3, rendered.Options["fake-count"])
+	assert.Equal(t, []string{"a", "b"}, rendered.Options["fake-tag"])
+	assert.Equal(t, false, rendered.Options["fake-spot"])
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printRenderedConfig(out, rendered))
+	config, problems := parseMachineConfig(out.Bytes())
+	assert.Empty(t, problems)
+	assert.Equal(t, "fake", config.Driver)
+}
+
+func TestRenderConfigProblems(t *testing.T) {
+	_, problems := renderConfig(&fakeConfigDriverAPI{}, []byte("driver: fake\n"), map[string]interface{}{"fake-unknown": "1"}, "")
+	assert.Equal(t, []ConfigProblem{{Key: "fake-unknown", Problem: "unknown flag, neither a create flag nor a flag of the fake driver"}}, problems)
+
+	_, problems = renderConfig(&fakeConfigDriverAPI{}, []byte("options: {}\n"), map[string]interface{}{}, "")
+	assert.Equal(t, []ConfigProblem{{Key: "driver", Problem: "no driver is set"}}, problems)
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+var errResizeNoSize = errors.New("Error: the new size of the machine must be given with --size")
+
+// cmdResize changes the size of the instance of a machine, e.g. its instance
+// type, with the driver stopping and starting it as needed. The new size is
+// saved in the machine config and the command waits for the daemon to answer
+// again before returning.
+func cmdResize(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) > 1 {
+		return ErrExpectedOneMachine
+	}
+
+	size := c.String("size")
+	if size == "" {
+		return invalidArguments(errResizeNoSize)
+	}
+
+	target, err := targetHost(c, api)
+	if err != nil {
+		return err
+	}
+
+	h, err := api.Load(target)
+	if err != nil {
+		return err
+	}
+
+	// The IP of the machine may change when its instance is stopped and
+	// started again, its server certificate then has to be regenerated.
+	ipBefore, _ := h.Driver.GetIP()
+
+	log.Infof("Resizing %q to %s...", h.Name, size)
+	err = drivers.Resize(h.Driver, size)
+	if errors.Is(err, mcnerror.ErrNotSupported) {
+		return fmt.Errorf("the %s driver doesn't support resizing machines", h.DriverName)
+	}
+	if err != nil {
+		// The instance may have been started with a new IP even if it
+		// couldn't be resized.
+		if saveErr := api.Save(h); saveErr != nil {
+			log.Warnf("Error saving host to store: %s", saveErr)
+		}
+		return fmt.Errorf("Error resizing %s: %s", h.Name, err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error saving host to store: %s", err)
+	}
+
+	if ipAfter, err := h.Driver.GetIP(); err == nil && ipBefore != "" && ipAfter != ipBefore {
+		log.Infof("The IP of %q changed from %s to %s, regenerating its certificates...", h.Name, ipBefore, ipAfter)
+		if err := h.ConfigureAuth(); err != nil {
+			return fmt.Errorf("Error regenerating the certificates of %s: %s", h.Name, err)
+		}
+		if err := api.Save(h); err != nil {
+			return fmt.Errorf("Error saving host to store: %s", err)
+		}
+	}
+
+	timeout := time.Duration(c.Int("timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = waitHealthyDefaultTimeout * time.Second
+	}
+	if err := waitDaemonHealthy(h, timeout); err != nil {
+		return err
+	}
+
+	log.Infof("Machine %q was resized to %s.", h.Name, size)
+	return nil
+}
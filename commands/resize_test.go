@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResizeDriver struct {
+	*fakedriver.Driver
+	Size      string
+	resizeErr error
+}
+
+func (d *fakeResizeDriver) Resize(size string) error {
+	if d.resizeErr != nil {
+		return d.resizeErr
+	}
+	d.Size = size
+	return nil
+}
+
+func TestCmdResize(t *testing.T) {
+	stubDaemonInfo(t, func(h *host.Host) error {
+		return nil
+	})
+
+	driver := &fakeResizeDriver{Driver: &fakedriver.Driver{MockState: state.Running, MockIP: "1.2.3.4"}, Size: "small"}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{{Name: "foo", DriverName: "fake", Driver: driver}},
+	}
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs:    []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"size": "large"}},
+	}
+
+	assert.NoError(t, cmdResize(commandLine, api))
+	assert.Equal(t, "large", driver.Size)
+}
+
+func TestCmdResizeError(t *testing.T) {
+	driver := &fakeResizeDriver{Driver: &fakedriver.Driver{MockState: state.Running}, resizeErr: errors.New("unknown size")}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{{Name: "foo", DriverName: "fake", Driver: driver}},
+	}
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs:    []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"size": "huge"}},
+	}
+
+	assert.EqualError(t, cmdResize(commandLine, api), "Error resizing foo: unknown size")
+}
+
+func TestCmdResizeNotSupported(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{{Name: "foo", DriverName: "fake", Driver: &fakedriver.Driver{MockState: state.Running}}},
+	}
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs:    []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"size": "large"}},
+	}
+
+	assert.EqualError(t, cmdResize(commandLine, api), "the fake driver doesn't support resizing machines")
+}
+
+func TestCmdResizeNoSize(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs:    []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}
+
+	err := cmdResize(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errResizeNoSize, errors.Unwrap(err))
+	assert.Equal(t, exitInvalidArguments, exitCode(err))
+}
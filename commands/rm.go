@@ -27,8 +27,13 @@ func cmdRm(c CommandLine, api libmachine.API) error {
 		return nil
 	}
 
+	hooks := removeHooks{
+		pre:  c.String("pre-remove-script"),
+		post: c.String("post-remove-script"),
+	}
+
 	for _, hostName := range c.Args() {
-		err := removeRemoteMachine(hostName, api)
+		err := removeRemoteMachine(hostName, api, hooks, force)
 		if err != nil {
 			if _, ok := err.(mcnerror.ErrHostDoesNotExist); !ok {
 				errorOccurred = collectError(fmt.Sprintf("Error removing host %q: %s", hostName, err), force, errorOccurred)
@@ -67,17 +72,31 @@ func userConfirm(confirm bool, force bool) bool {
 	return sure
 }
 
-func removeRemoteMachine(hostName string, api libmachine.API) error {
+func removeRemoteMachine(hostName string, api libmachine.API, hooks removeHooks, force bool) error {
 	currentHost, loaderr := api.Load(hostName)
 	if loaderr != nil {
 		return loaderr
 	}
 
+	hooks = hooks.forHost(currentHost)
+	// The IP is gone once the instance is removed.
+	ip := removeHookIP(currentHost, hooks)
+	if err := runRemoveHook("pre-remove", hooks.pre, currentHost.Name, ip); err != nil {
+		if !force {
+			return err
+		}
+		log.Warnf("%s, removing it anyway", err)
+	}
+
 	err := currentHost.Driver.Remove()
-	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "not found") {
+	if err != nil && !isInstanceNotFound(err) {
 		return err
 	}
 
+	if err := runRemoveHook("post-remove", hooks.post, currentHost.Name, ip); err != nil {
+		log.Warn(err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// removeHooks are the local scripts run by rm before and after the instance
+// of a machine is removed.
+type removeHooks struct {
+	pre  string
+	post string
+}
+
+// forHost returns the hooks of the rm flags, the ones stored at create when
+// a flag isn't given.
+func (hooks removeHooks) forHost(h *host.Host) removeHooks {
+	if h.HostOptions == nil {
+		return hooks
+	}
+	if hooks.pre == "" {
+		hooks.pre = h.HostOptions.PreRemoveScript
+	}
+	if hooks.post == "" {
+		hooks.post = h.HostOptions.PostRemoveScript
+	}
+	return hooks
+}
+
+// removeScriptPath returns the absolute path of a remove script given at
+// create, which rm may run from another directory.
+func removeScriptPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(absPath); err != nil {
+		return "", err
+	}
+
+	return absPath, nil
+}
+
+// removeHookIP returns the IP given to the hooks, empty when the machine has
+// none, e.g. when it is stopped.
+func removeHookIP(h *host.Host, hooks removeHooks) string {
+	if hooks.pre == "" && hooks.post == "" {
+		return ""
+	}
+
+	ip, err := h.Driver.GetIP()
+	if err != nil {
+		log.Debugf("Unable to get the IP of %s for its remove scripts: %s", h.Name, err)
+		return ""
+	}
+	return ip
+}
+
+// runRemoveHook runs script with the name and the IP of the machine as
+// arguments, its output going to the output of rm.
+var runRemoveHook = func(kind, script, name, ip string) error {
+	if script == "" {
+		return nil
+	}
+
+	log.Infof("Running the %s script of %s...", kind, name)
+
+	cmd := exec.Command(script, name, ip)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("the %s script %s of %s failed: %s", kind, script, name, err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/rancher/machine/drivers/fakedriver"
 	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -230,3 +231,85 @@ func TestDontRemoveMachineIsRemovalFailsAndNotForced(t *testing.T) {
 
 	assert.True(t, libmachinetest.Exists(api, "machineToRemove1"))
 }
+
+func fakeRemoveHooks(t *testing.T, failing string) *[]string {
+	calls := []string{}
+	original := runRemoveHook
+	t.Cleanup(func() { runRemoveHook = original })
+	runRemoveHook = func(kind, script, name, ip string) error {
+		if script == "" {
+			return nil
+		}
+		calls = append(calls, kind+" "+script+" "+name+" "+ip)
+		if kind == failing {
+			return errors.New(kind + " failed")
+		}
+		return nil
+	}
+	return &calls
+}
+
+func TestCmdRmRunsRemoveHooks(t *testing.T) {
+	calls := fakeRemoveHooks(t, "post-remove")
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machineToRemove1"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"y":                 true,
+				"pre-remove-script": "/hooks/deregister",
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machineToRemove1",
+				Driver: &fakedriver.Driver{MockState: state.Running, MockIP: "10.0.0.1"},
+				HostOptions: &host.Options{
+					PreRemoveScript:  "/hooks/stored",
+					PostRemoveScript: "/hooks/release",
+				},
+			},
+		},
+	}
+
+	err := cmdRm(commandLine, api)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"pre-remove /hooks/deregister machineToRemove1 10.0.0.1",
+		"post-remove /hooks/release machineToRemove1 10.0.0.1",
+	}, *calls)
+	assert.False(t, libmachinetest.Exists(api, "machineToRemove1"))
+}
+
+func TestCmdRmFailingPreRemoveHook(t *testing.T) {
+	fakeRemoveHooks(t, "pre-remove")
+	newAPI := func() *libmachinetest.FakeAPI {
+		return &libmachinetest.FakeAPI{
+			Hosts: []*host.Host{
+				{
+					Name:        "machineToRemove1",
+					Driver:      &fakedriver.Driver{},
+					HostOptions: &host.Options{PreRemoveScript: "/hooks/deregister"},
+				},
+			},
+		}
+	}
+
+	api := newAPI()
+	err := cmdRm(&commandstest.FakeCommandLine{
+		CliArgs:    []string{"machineToRemove1"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"y": true}},
+	}, api)
+	assert.EqualError(t, err, "Error removing host \"machineToRemove1\": pre-remove failed")
+	assert.True(t, libmachinetest.Exists(api, "machineToRemove1"))
+
+	api = newAPI()
+	err = cmdRm(&commandstest.FakeCommandLine{
+		CliArgs:    []string{"machineToRemove1"},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"force": true}},
+	}, api)
+	assert.NoError(t, err)
+	assert.False(t, libmachinetest.Exists(api, "machineToRemove1"))
+}
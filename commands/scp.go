@@ -10,6 +10,7 @@ import (
 
 	"github.com/rancher/machine/libmachine/log"
 	"github.com/rancher/machine/libmachine/persist"
+	"github.com/rancher/machine/libmachine/ssh"
 )
 
 var (
@@ -54,7 +55,7 @@ func (s *storeHostInfoLoader) load(name string) (HostInfo, error) {
 	return host.Driver, nil
 }
 
-func getScpCmd(src, dest string, recursive bool, delta bool, quiet bool, hostInfoLoader HostInfoLoader) (*exec.Cmd, error) {
+func getScpCmd(src, dest string, recursive bool, delta bool, quiet bool, excludes []string, hostInfoLoader HostInfoLoader) (*exec.Cmd, error) {
 	var cmdPath string
 	var err error
 	if !delta {
@@ -97,6 +98,8 @@ func getScpCmd(src, dest string, recursive bool, delta bool, quiet bool, hostInf
 		sshArgs = append(sshArgs, "-o", "IdentitiesOnly=yes")
 	}
 
+	sshArgs = append(sshArgs, ssh.AlgorithmArgs()...)
+
 	// Append needed -i / private key flags to command.
 	sshArgs = append(sshArgs, srcOpts...)
 	sshArgs = append(sshArgs, destOpts...)
@@ -117,6 +120,7 @@ func getScpCmd(src, dest string, recursive bool, delta bool, quiet bool, hostInf
 		if recursive {
 			sshArgs = append(sshArgs, "-r")
 		}
+		sshArgs = append(sshArgs, rsyncExcludeArgs(srcPath, excludes)...)
 	}
 
 	sshArgs = append(sshArgs, locationArg)
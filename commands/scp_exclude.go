@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errExcludeNotRecursive = errors.New("--exclude filters the files of recursive copies, it needs --recursive")
+	errExcludeArgs         = errors.New("--exclude copies a directory between a machine and the local host, use --delta to copy between machines")
+)
+
+// validateExcludes checks the --exclude patterns, which are globs as
+// understood by path.Match.
+func validateExcludes(patterns []string, recursive bool) error {
+	if !recursive {
+		return errExcludeNotRecursive
+	}
+
+	for _, pattern := range patterns {
+		if strings.Trim(pattern, "/") == "" {
+			return fmt.Errorf("invalid --exclude pattern %q", pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %s", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// excluded tells whether a path, relative to the copied directory and slash
+// separated, matches one of the --exclude patterns. A pattern without a slash
+// other than a trailing one is matched against the name of every file and
+// directory, e.g. .git or *.log. Any other pattern is matched against the
+// path from the copied directory, e.g. build/cache or /vendor. A file is
+// excluded when any pattern matches it or one of its parent directories: the
+// patterns only ever exclude, so their order doesn't matter.
+func excluded(rel string, patterns []string) bool {
+	parts := strings.Split(rel, "/")
+
+	for _, pattern := range patterns {
+		anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+		pattern = strings.Trim(pattern, "/")
+
+		for i, part := range parts {
+			name := part
+			if anchored {
+				name = strings.Join(parts[:i+1], "/")
+			}
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rsyncExcludeArgs translates the --exclude patterns to the rsync ones, the
+// patterns with a slash being anchored at the root of the transfer, which is
+// the parent of the copied directory unless its path ends with a slash.
+func rsyncExcludeArgs(srcPath string, patterns []string) []string {
+	root := "/"
+	if !strings.HasSuffix(srcPath, "/") {
+		root = "/" + path.Base(srcPath) + "/"
+	}
+
+	args := []string{}
+	for _, pattern := range patterns {
+		if strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+			pattern = root + strings.TrimPrefix(pattern, "/")
+		}
+		args = append(args, "--exclude="+pattern)
+	}
+
+	return args
+}
+
+// scpExclude recursively copies a directory between a machine and the local
+// host, skipping the excluded files. scp can't filter the files it copies, so
+// the directory is walked and the kept files are streamed as a tar archive
+// over the native SSH client. Like with scp, the directory is copied into the
+// destination if it is an existing directory, or else as the destination.
+func scpExclude(src, dest string, patterns []string, hostInfoLoader HostInfoLoader) error {
+	srcHost, srcUser, srcPath, _, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	destHost, destUser, destPath, _, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case srcHost != nil && destHost == nil:
+		client, err := newResumeClient(srcHost, srcUser)
+		if err != nil {
+			return err
+		}
+		return excludeDownload(client, srcPath, destPath, patterns)
+	case srcHost == nil && destHost != nil:
+		client, err := newResumeClient(destHost, destUser)
+		if err != nil {
+			return err
+		}
+		return excludeUpload(client, srcPath, destPath, patterns)
+	default:
+		return errExcludeArgs
+	}
+}
+
+func excludeUpload(client resumeClient, localPath, remotePath string, patterns []string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", localPath)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeExcludeTar(writer, localPath, patterns))
+	}()
+
+	command := fmt.Sprintf(`dest=%s; if [ -d "$dest" ]; then dest="$dest"/%s; fi; mkdir -p "$dest" && tar -xf - -C "$dest"`,
+		shellQuote(remotePath), shellQuote(filepath.Base(filepath.Clean(localPath))))
+	err = client.Stream(command, reader, nil)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %s", localPath, err)
+	}
+
+	return nil
+}
+
+// writeExcludeTar writes the files of a local directory not excluded to w as
+// a tar archive, with paths relative to the directory.
+func writeExcludeTar(w io.Writer, root string, patterns []string) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if excluded(rel, patterns) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+func excludeDownload(client resumeClient, remotePath, localPath string, patterns []string) error {
+	remotePath = path.Clean(remotePath)
+	parent, base := path.Dir(remotePath), path.Base(remotePath)
+
+	dirs, err := remoteFind(client, parent, base, "-type d")
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("%s is not a directory", remotePath)
+	}
+	files, err := remoteFind(client, parent, base, "! -type d")
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, base)
+	}
+
+	for _, dir := range keptPaths(dirs, base, patterns) {
+		if err := os.MkdirAll(filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(dir, base))), 0755); err != nil {
+			return err
+		}
+	}
+
+	kept := keptPaths(files, base, patterns)
+	if len(kept) == 0 {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := extractExcludeTar(reader, localPath, base)
+		// Drain the archive so that the stream isn't blocked on an error.
+		io.Copy(io.Discard, reader)
+		extracted <- err
+	}()
+
+	err = client.Stream(fmt.Sprintf("tar -cf - -C %s -T -", shellQuote(parent)), strings.NewReader(strings.Join(kept, "\n")+"\n"), writer)
+	writer.CloseWithError(err)
+	if extractErr := <-extracted; err == nil {
+		err = extractErr
+	}
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %s", remotePath, err)
+	}
+
+	return nil
+}
+
+// remoteFind lists the paths under a directory of the machine matching the
+// find expression, relative to its parent.
+func remoteFind(client resumeClient, parent, base, expression string) ([]string, error) {
+	output, err := client.Output(fmt.Sprintf("cd %s && find %s %s", shellQuote(parent), shellQuote(base), expression))
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %s: %s", path.Join(parent, base), err, strings.TrimSpace(output))
+	}
+
+	paths := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
+
+// keptPaths returns the paths, relative to the parent of the copied
+// directory named base, that aren't excluded.
+func keptPaths(paths []string, base string, patterns []string) []string {
+	kept := []string{}
+	for _, p := range paths {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, base), "/")
+		if rel == "" || !excluded(rel, patterns) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// extractExcludeTar extracts an archive of the paths under the directory
+// named base to the local directory, refusing the paths outside of it.
+func extractExcludeTar(r io.Reader, localPath, base string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(path.Clean(header.Name), base), "/")
+		if rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+			return fmt.Errorf("refusing to extract %s outside of %s", header.Name, localPath)
+		}
+		target := filepath.Join(localPath, filepath.FromSlash(rel))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExcludes(t *testing.T) {
+	assert.NoError(t, validateExcludes([]string{".git", "*.log", "build/cache"}, true))
+	assert.Equal(t, errExcludeNotRecursive, validateExcludes([]string{".git"}, false))
+	assert.EqualError(t, validateExcludes([]string{"[a-"}, true), `invalid --exclude pattern "[a-": syntax error in pattern`)
+	assert.EqualError(t, validateExcludes([]string{"/"}, true), `invalid --exclude pattern "/"`)
+}
+
+func TestExcluded(t *testing.T) {
+	patterns := []string{".git", "*.log", "build/cache", "/vendor"}
+
+	for rel, expected := range map[string]bool{
+		".git":                true,
+		".git/config":         true,
+		"src/.git/HEAD":       true,
+		"app.log":             true,
+		"logs/app.log":        true,
+		"build/cache":         true,
+		"build/cache/obj":     true,
+		"src/build/cache/obj": false,
+		"build/out":           false,
+		"vendor/lib.go":       true,
+		"src/vendor/lib.go":   false,
+		"main.go":             false,
+	} {
+		assert.Equal(t, expected, excluded(rel, patterns), rel)
+	}
+}
+
+func TestRsyncExcludeArgs(t *testing.T) {
+	patterns := []string{".git", "build/cache", "/vendor"}
+
+	assert.Equal(t, []string{"--exclude=.git", "--exclude=/foo/build/cache", "--exclude=/foo/vendor"}, rsyncExcludeArgs("/tmp/foo", patterns))
+	assert.Equal(t, []string{"--exclude=.git", "--exclude=/build/cache", "--exclude=/vendor"}, rsyncExcludeArgs("/tmp/foo/", patterns))
+}
+
+// fakeTarClient serves the find commands of a download from a list of paths
+// and answers the tar commands with an archive of the requested files, or
+// records the archive of an upload.
+type fakeTarClient struct {
+	dirs, files []string
+	commands    []string
+	uploaded    []string
+}
+
+func (f *fakeTarClient) Output(command string) (string, error) {
+	f.commands = append(f.commands, command)
+	if strings.HasSuffix(command, "! -type d") {
+		return strings.Join(f.files, "\n") + "\n", nil
+	}
+	return strings.Join(f.dirs, "\n") + "\n", nil
+}
+
+func (f *fakeTarClient) Stream(command string, stdin io.Reader, stdout io.Writer) error {
+	f.commands = append(f.commands, command)
+	if stdout == nil {
+		tr := tar.NewReader(stdin)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			f.uploaded = append(f.uploaded, header.Name)
+		}
+	}
+
+	list, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(stdout)
+	for _, name := range strings.Fields(string(list)) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func TestExcludeUpload(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "src")
+	for _, name := range []string{"main.go", ".git/config", "pkg/app.log", "pkg/lib.go"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(name), 0644))
+	}
+
+	client := &fakeTarClient{}
+	assert.NoError(t, excludeUpload(client, root, "/home/docker/src", []string{".git", "*.log"}))
+
+	sort.Strings(client.uploaded)
+	assert.Equal(t, []string{"main.go", "pkg", "pkg/lib.go"}, client.uploaded)
+	assert.Equal(t, []string{`dest='/home/docker/src'; if [ -d "$dest" ]; then dest="$dest"/'src'; fi; mkdir -p "$dest" && tar -xf - -C "$dest"`}, client.commands)
+}
+
+func TestExcludeDownload(t *testing.T) {
+	client := &fakeTarClient{
+		dirs:  []string{"src", "src/.git", "src/pkg", "src/empty"},
+		files: []string{"src/main.go", "src/.git/config", "src/pkg/app.log", "src/pkg/lib.go"},
+	}
+	dest := t.TempDir()
+
+	assert.NoError(t, excludeDownload(client, "/home/docker/src", dest, []string{".git", "*.log"}))
+
+	found := []string{}
+	filepath.Walk(filepath.Join(dest, "src"), func(p string, info os.FileInfo, err error) error {
+		rel, _ := filepath.Rel(filepath.Join(dest, "src"), p)
+		found = append(found, filepath.ToSlash(rel))
+		return nil
+	})
+	assert.Equal(t, []string{".", "empty", "main.go", "pkg", "pkg/lib.go"}, found)
+
+	content, err := os.ReadFile(filepath.Join(dest, "src", "pkg", "lib.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "src/pkg/lib.go", string(content))
+	assert.Equal(t, "tar -cf - -C '/home/docker' -T -", client.commands[len(client.commands)-1])
+}
+
+func TestExtractExcludeTarOutside(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/../../etc/passwd", Mode: 0644, Typeflag: tar.TypeReg}))
+	assert.NoError(t, tw.Close())
+
+	dest := t.TempDir()
+	err := extractExcludeTar(&archive, dest, "src")
+
+	assert.EqualError(t, err, "refusing to extract src/../../etc/passwd outside of "+dest)
+}
@@ -0,0 +1,265 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/ssh"
+)
+
+var errResumeArgs = errors.New("--resume copies a single file between a machine and the local host, it can't be used with --recursive or --delta")
+
+// resumeClient runs the commands of a resumed transfer on a machine.
+type resumeClient interface {
+	Output(command string) (string, error)
+	Stream(command string, stdin io.Reader, stdout io.Writer) error
+}
+
+// fileTransferrer copies whole files over concurrent channels.
+type fileTransferrer interface {
+	Upload(src io.ReaderAt, size int64, remotePath string) error
+	Download(remotePath string, dst io.WriterAt, size int64) error
+}
+
+// newResumeClient connects to a machine with the native SSH client, which
+// streams the transferred data itself.
+var newResumeClient = func(h HostInfo, user string) (resumeClient, error) {
+	hostname, err := h.GetSSHHostname()
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := h.GetSSHPort()
+	if err != nil {
+		return nil, err
+	}
+
+	if user == "" {
+		user = h.GetSSHUsername()
+	}
+
+	auth := &ssh.Auth{}
+	if h.GetSSHKeyPath() != "" {
+		auth.Keys = []string{h.GetSSHKeyPath()}
+	}
+
+	client, err := ssh.NewNativeClient(user, hostname, port, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*ssh.NativeClient), nil
+}
+
+// scpResume copies a file between a machine and the local host, continuing
+// from the data already present at the destination. The transfer starts over
+// when the destination can't be a partial copy of the source, or when the
+// machine lacks the tools needed to resume.
+func scpResume(src, dest string, recursive, delta bool, hostInfoLoader HostInfoLoader) error {
+	if recursive || delta {
+		return errResumeArgs
+	}
+
+	srcHost, srcUser, srcPath, _, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	destHost, destUser, destPath, _, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case srcHost != nil && destHost == nil:
+		client, err := newResumeClient(srcHost, srcUser)
+		if err != nil {
+			return err
+		}
+		return resumeDownload(client, srcPath, destPath)
+	case srcHost == nil && destHost != nil:
+		client, err := newResumeClient(destHost, destUser)
+		if err != nil {
+			return err
+		}
+		return resumeUpload(client, srcPath, destPath)
+	default:
+		return errResumeArgs
+	}
+}
+
+func resumeDownload(client resumeClient, remotePath, localPath string) error {
+	remoteSize, err := remoteFileSize(client, remotePath)
+	if err != nil {
+		return err
+	}
+	if remoteSize < 0 {
+		return fmt.Errorf("%s does not exist on the machine", remotePath)
+	}
+
+	offset := int64(0)
+	if info, err := os.Stat(localPath); err == nil {
+		offset = info.Size()
+	}
+
+	offset = resumeOffset(client, offset, remoteSize)
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if offset == 0 {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(localPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if t, ok := client.(fileTransferrer); ok && offset == 0 {
+		if err := t.Download(remotePath, file, remoteSize); err != nil {
+			return fmt.Errorf("error downloading %s: %s", remotePath, err)
+		}
+	} else if offset < remoteSize {
+		command := fmt.Sprintf("tail -c +%d -- %s", offset+1, shellQuote(remotePath))
+		if err := client.Stream(command, nil, file); err != nil {
+			return fmt.Errorf("error downloading %s: %s", remotePath, err)
+		}
+	}
+
+	return verifyResumedCopy(client, remotePath, localPath)
+}
+
+func resumeUpload(client resumeClient, localPath, remotePath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	remoteSize, err := remoteFileSize(client, remotePath)
+	if err != nil {
+		return err
+	}
+
+	offset := int64(0)
+	if remoteSize > 0 {
+		offset = remoteSize
+	}
+
+	offset = resumeOffset(client, offset, info.Size())
+
+	if t, ok := client.(fileTransferrer); ok && offset == 0 {
+		if err := t.Upload(file, info.Size(), remotePath); err != nil {
+			return fmt.Errorf("error uploading %s: %s", localPath, err)
+		}
+	} else if offset < info.Size() || info.Size() == 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		redirect := ">>"
+		if offset == 0 {
+			redirect = ">"
+		}
+
+		command := fmt.Sprintf("cat %s %s", redirect, shellQuote(remotePath))
+		if err := client.Stream(command, file, nil); err != nil {
+			return fmt.Errorf("error uploading %s: %s", localPath, err)
+		}
+	}
+
+	return verifyResumedCopy(client, remotePath, localPath)
+}
+
+// resumeOffset returns where a transfer continues given the size of the
+// partial copy and the size of the source, falling back to 0 with the reason
+// logged when resuming is not possible.
+func resumeOffset(client resumeClient, partial, total int64) int64 {
+	if partial == 0 {
+		return 0
+	}
+
+	if partial > total {
+		log.Infof("The destination is larger than the source (%d > %d bytes), transferring the whole file", partial, total)
+		return 0
+	}
+
+	if _, err := client.Output("tail -c +1 /dev/null"); err != nil {
+		log.Infof("The machine can't resume transfers (tail -c is not available: %s), transferring the whole file", err)
+		return 0
+	}
+
+	log.Infof("Resuming the transfer at %d of %d bytes", partial, total)
+	return partial
+}
+
+// remoteFileSize returns the size of a file on a machine, or -1 if it does not
+// exist.
+func remoteFileSize(client resumeClient, path string) (int64, error) {
+	quoted := shellQuote(path)
+	output, err := client.Output(fmt.Sprintf("if [ -e %s ]; then wc -c < %s; else echo -1; fi", quoted, quoted))
+	if err != nil {
+		return 0, fmt.Errorf("error getting the size of %s on the machine: %s", path, err)
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error getting the size of %s on the machine: unexpected output %q", path, output)
+	}
+
+	return size, nil
+}
+
+// verifyResumedCopy checks that both copies have the same size and, when the
+// machine has sha256sum, the same checksum.
+func verifyResumedCopy(client resumeClient, remotePath, localPath string) error {
+	remoteSize, err := remoteFileSize(client, remotePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	localSize, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+
+	if localSize != remoteSize {
+		return fmt.Errorf("the copy is incomplete, %s has %d bytes and %s has %d bytes, run the command again to resume it", localPath, localSize, remotePath, remoteSize)
+	}
+
+	output, err := client.Output(fmt.Sprintf("sha256sum -- %s", shellQuote(remotePath)))
+	if err != nil {
+		log.Warnf("Unable to checksum %s on the machine, only its size was verified: %s", remotePath, err)
+		return nil
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 || fields[0] != hex.EncodeToString(hash.Sum(nil)) {
+		return fmt.Errorf("the checksums of %s and %s differ, remove the destination and copy the file again", localPath, remotePath)
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
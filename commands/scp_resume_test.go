@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeResumeClient keeps the files of a machine in memory and understands the
+// commands run by the resumed transfers.
+type fakeResumeClient struct {
+	files  map[string][]byte
+	noTail bool
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(strings.Trim(s, "'"), `'\''`, "'")
+}
+
+func (f *fakeResumeClient) Output(command string) (string, error) {
+	switch {
+	case command == "tail -c +1 /dev/null":
+		if f.noTail {
+			return "", errors.New("tail: invalid option -- 'c'")
+		}
+		return "", nil
+	case strings.HasPrefix(command, "if [ -e "):
+		path := unquote(strings.Fields(command)[3])
+		content, ok := f.files[path]
+		if !ok {
+			return "-1\n", nil
+		}
+		return fmt.Sprintf("%d\n", len(content)), nil
+	case strings.HasPrefix(command, "sha256sum -- "):
+		sum := sha256.Sum256(f.files[unquote(strings.TrimPrefix(command, "sha256sum -- "))])
+		return hex.EncodeToString(sum[:]) + "  file\n", nil
+	}
+
+	return "", fmt.Errorf("unexpected command %q", command)
+}
+
+func (f *fakeResumeClient) Stream(command string, stdin io.Reader, stdout io.Writer) error {
+	fields := strings.Fields(command)
+	switch {
+	case fields[0] == "tail":
+		if f.noTail {
+			return errors.New("tail: invalid option -- 'c'")
+		}
+		start, _ := strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+		_, err := stdout.Write(f.files[unquote(fields[4])][start-1:])
+		return err
+	case fields[0] == "cat":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		path := unquote(fields[2])
+		if fields[1] == ">>" {
+			f.files[path] = append(f.files[path], data...)
+		} else {
+			f.files[path] = data
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unexpected command %q", command)
+}
+
+func TestResumeDownload(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("0123"), 0644))
+
+	client := &fakeResumeClient{files: map[string][]byte{"/tmp/image.tar": []byte("0123456789")}}
+
+	err := resumeDownload(client, "/tmp/image.tar", local)
+
+	assert.NoError(t, err)
+	content, _ := os.ReadFile(local)
+	assert.Equal(t, "0123456789", string(content))
+}
+
+func TestResumeDownloadRestartsWhenLocalIsLarger(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("a much longer old file"), 0644))
+
+	client := &fakeResumeClient{files: map[string][]byte{"/tmp/image.tar": []byte("0123456789")}}
+
+	err := resumeDownload(client, "/tmp/image.tar", local)
+
+	assert.NoError(t, err)
+	content, _ := os.ReadFile(local)
+	assert.Equal(t, "0123456789", string(content))
+}
+
+func TestResumeUpload(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("0123456789"), 0644))
+
+	client := &fakeResumeClient{files: map[string][]byte{"/tmp/it's.tar": []byte("012345")}}
+
+	err := resumeUpload(client, local, "/tmp/it's.tar")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(client.files["/tmp/it's.tar"]))
+}
+
+func TestResumeUploadWithoutTail(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("0123456789"), 0644))
+
+	client := &fakeResumeClient{files: map[string][]byte{"/tmp/image.tar": []byte("xxxx")}, noTail: true}
+
+	err := resumeUpload(client, local, "/tmp/image.tar")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(client.files["/tmp/image.tar"]))
+}
+
+func TestVerifyResumedCopyDetectsCorruption(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(local, []byte("0123456789"), 0644))
+
+	client := &fakeResumeClient{files: map[string][]byte{"/tmp/image.tar": bytes.Repeat([]byte("x"), 10)}}
+
+	err := verifyResumedCopy(client, "/tmp/image.tar", local)
+
+	assert.EqualError(t, err, fmt.Sprintf("the checksums of %s and /tmp/image.tar differ, remove the destination and copy the file again", local))
+}
+
+func TestScpResumeNeedsOneRemoteFile(t *testing.T) {
+	loader := &MockHostInfoLoader{MockHostInfo{ip: "192.0.2.10", sshPort: 22}}
+
+	assert.Equal(t, errResumeArgs, scpResume("/tmp/a", "/tmp/b", false, false, loader))
+	assert.Equal(t, errResumeArgs, scpResume("m1:/tmp/a", "m2:/tmp/b", false, false, loader))
+	assert.Equal(t, errResumeArgs, scpResume("m1:/tmp/a", "/tmp/b", true, false, loader))
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// scpStdio is the path standing for the local stdin as the source of a copy,
+// and for the local stdout as its destination.
+const scpStdio = "-"
+
+var errStreamArgs = errors.New("- streams a single file between stdin or stdout and a machine, it can't be used with --recursive, --delta, --resume or --exclude")
+
+// scpStream copies a file of a machine to the local stdout, or the local
+// stdin to a file of a machine, over the native SSH client. The data is
+// streamed as is, without a temp file on either side.
+func scpStream(src, dest string, recursive, delta, resume, sudo bool, excludes []string, hostInfoLoader HostInfoLoader) error {
+	if recursive || delta || resume || len(excludes) > 0 {
+		return errStreamArgs
+	}
+
+	srcHost, srcUser, srcPath, _, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	destHost, destUser, destPath, _, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case srcHost != nil && destHost == nil && destPath == scpStdio:
+		client, err := newStreamClient(srcHost, srcUser, sudo)
+		if err != nil {
+			return err
+		}
+		return streamDownload(client, srcPath, os.Stdout, sudo)
+	case srcHost == nil && srcPath == scpStdio && destHost != nil:
+		client, err := newStreamClient(destHost, destUser, sudo)
+		if err != nil {
+			return err
+		}
+		return streamUpload(client, os.Stdin, destPath, sudo)
+	default:
+		return errors.New("- must be the source or the destination of a copy to or from a machine")
+	}
+}
+
+func newStreamClient(h HostInfo, user string, sudo bool) (resumeClient, error) {
+	if sudo {
+		return newSudoClient(h, user)
+	}
+	return newResumeClient(h, user)
+}
+
+func streamDownload(client resumeClient, remotePath string, stdout io.Writer, sudo bool) error {
+	cmd := fmt.Sprintf("cat -- %s", shellQuote(remotePath))
+	if sudo {
+		cmd = "sudo -n " + cmd
+	}
+
+	if err := client.Stream(cmd, nil, stdout); err != nil {
+		return fmt.Errorf("error streaming %s: %s", remotePath, err)
+	}
+
+	return nil
+}
+
+func streamUpload(client resumeClient, stdin io.Reader, remotePath string, sudo bool) error {
+	cmd := fmt.Sprintf("cat > %s", shellQuote(remotePath))
+	if sudo {
+		cmd = fmt.Sprintf("sudo -n sh -c %s", shellQuote(cmd))
+	}
+
+	if err := client.Stream(cmd, stdin, nil); err != nil {
+		return fmt.Errorf("error streaming to %s: %s", remotePath, err)
+	}
+
+	return nil
+}
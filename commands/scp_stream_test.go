@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamDownload(t *testing.T) {
+	client := &fakeSudoClient{files: map[string]string{"/var/log/syslog": "kernel: ok\n"}}
+	var stdout bytes.Buffer
+
+	assert.NoError(t, streamDownload(client, "/var/log/syslog", &stdout, true))
+
+	assert.Equal(t, "kernel: ok\n", stdout.String())
+	assert.Equal(t, []string{"sudo -n cat -- '/var/log/syslog'"}, client.commands)
+}
+
+func TestStreamUpload(t *testing.T) {
+	client := &fakeSudoClient{}
+	assert.NoError(t, streamUpload(client, strings.NewReader("data"), "/tmp/my file", false))
+	assert.Equal(t, "data", client.uploaded.String())
+	assert.Equal(t, []string{"cat > '/tmp/my file'"}, client.commands)
+
+	client = &fakeSudoClient{}
+	assert.NoError(t, streamUpload(client, strings.NewReader("data"), "/etc/x", true))
+	assert.Equal(t, []string{`sudo -n sh -c 'cat > '\''/etc/x'\'''`}, client.commands)
+}
+
+func TestScpStreamArgs(t *testing.T) {
+	loader := &MockHostInfoLoader{}
+
+	assert.Equal(t, errStreamArgs, scpStream("-", "machine:/tmp/a", true, false, false, false, nil, loader))
+	assert.Equal(t, errStreamArgs, scpStream("machine:/tmp/a", "-", false, false, false, false, []string{"*.log"}, loader))
+	assert.EqualError(t, scpStream("-", "-", false, false, false, false, nil, loader), "- must be the source or the destination of a copy to or from a machine")
+	assert.EqualError(t, scpStream("-", "/tmp/a", false, false, false, false, nil, loader), "- must be the source or the destination of a copy to or from a machine")
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var errSudoArgs = errors.New("--sudo copies a single file between a machine and the local host, it can't be used with --recursive, --delta or --resume")
+
+// scpSudo copies a file between a machine and the local host with sudo on the
+// machine, for the paths the SSH user can't read or write. Uploads are staged
+// in a temp file moved into place, downloads are read with sudo cat.
+func scpSudo(src, dest string, recursive, delta, resume bool, hostInfoLoader HostInfoLoader) error {
+	if recursive || delta || resume {
+		return errSudoArgs
+	}
+
+	srcHost, srcUser, srcPath, _, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	destHost, destUser, destPath, _, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case srcHost != nil && destHost == nil:
+		client, err := newSudoClient(srcHost, srcUser)
+		if err != nil {
+			return err
+		}
+		return sudoDownload(client, srcPath, destPath)
+	case srcHost == nil && destHost != nil:
+		client, err := newSudoClient(destHost, destUser)
+		if err != nil {
+			return err
+		}
+		return sudoUpload(client, srcPath, destPath)
+	default:
+		return errSudoArgs
+	}
+}
+
+// newSudoClient connects to a machine and checks that the user has
+// passwordless sudo, as there is no terminal to type a password.
+func newSudoClient(h HostInfo, user string) (resumeClient, error) {
+	client, err := newResumeClient(h, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if output, err := client.Output("sudo -n true"); err != nil {
+		return nil, fmt.Errorf("--sudo needs passwordless sudo on %s: %s", h.GetMachineName(), strings.TrimSpace(output))
+	}
+
+	return client, nil
+}
+
+func sudoDownload(client resumeClient, remotePath, localPath string) error {
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, path.Base(remotePath))
+	}
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := client.Stream(fmt.Sprintf("sudo -n cat -- %s", shellQuote(remotePath)), nil, file); err != nil {
+		return fmt.Errorf("error downloading %s: %s", remotePath, err)
+	}
+
+	return nil
+}
+
+func sudoUpload(client resumeClient, localPath, remotePath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errSudoArgs
+	}
+
+	output, err := client.Output("mktemp /tmp/machine-scp.XXXXXX")
+	if err != nil {
+		return fmt.Errorf("error creating a temp file on the machine: %s", err)
+	}
+	tmpPath := strings.TrimSpace(output)
+
+	if err := client.Stream(fmt.Sprintf("cat > %s", shellQuote(tmpPath)), file, nil); err != nil {
+		client.Output(fmt.Sprintf("rm -f %s", shellQuote(tmpPath)))
+		return fmt.Errorf("error uploading %s: %s", localPath, err)
+	}
+
+	if output, err := client.Output(sudoMoveCommand(tmpPath, remotePath, filepath.Base(localPath), info.Mode().Perm())); err != nil {
+		client.Output(fmt.Sprintf("rm -f %s", shellQuote(tmpPath)))
+		return fmt.Errorf("error moving %s into place with sudo: %s: %s", remotePath, err, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// sudoMoveCommand moves the staged file to its destination, or into it when
+// it is a directory. A replaced file keeps its owner and mode, a new file is
+// owned by root with the mode of the local file.
+func sudoMoveCommand(tmpPath, remotePath, name string, mode os.FileMode) string {
+	return fmt.Sprintf(`tmp=%s; dest=%s; if sudo -n test -d "$dest"; then dest="$dest"/%s; fi; `+
+		`if sudo -n test -e "$dest"; then sudo -n chown "$(sudo -n stat -c %%u:%%g "$dest")" "$tmp" && sudo -n chmod "$(sudo -n stat -c %%a "$dest")" "$tmp"; `+
+		`else sudo -n chown 0:0 "$tmp" && sudo -n chmod %o "$tmp"; fi && sudo -n mv -f "$tmp" "$dest"`,
+		shellQuote(tmpPath), shellQuote(remotePath), shellQuote(name), mode)
+}
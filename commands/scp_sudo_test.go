@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSudoClient records the commands of a sudo transfer and the data
+// streamed to the machine.
+type fakeSudoClient struct {
+	commands []string
+	uploaded bytes.Buffer
+	files    map[string]string
+	noSudo   bool
+}
+
+func (f *fakeSudoClient) Output(command string) (string, error) {
+	f.commands = append(f.commands, command)
+	switch {
+	case command == "sudo -n true" && f.noSudo:
+		return "sudo: a password is required\n", errors.New("exit status 1")
+	case command == "mktemp /tmp/machine-scp.XXXXXX":
+		return "/tmp/machine-scp.abc123\n", nil
+	}
+
+	return "", nil
+}
+
+func (f *fakeSudoClient) Stream(command string, stdin io.Reader, stdout io.Writer) error {
+	f.commands = append(f.commands, command)
+	if stdin != nil {
+		_, err := io.Copy(&f.uploaded, stdin)
+		return err
+	}
+
+	_, err := io.WriteString(stdout, f.files[unquote(strings.TrimPrefix(command, "sudo -n cat -- "))])
+	return err
+}
+
+func TestSudoUpload(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "daemon.json")
+	assert.NoError(t, os.WriteFile(local, []byte("{}"), 0640))
+
+	client := &fakeSudoClient{}
+	assert.NoError(t, sudoUpload(client, local, "/etc/docker"))
+
+	assert.Equal(t, "{}", client.uploaded.String())
+	assert.Equal(t, []string{
+		"mktemp /tmp/machine-scp.XXXXXX",
+		"cat > '/tmp/machine-scp.abc123'",
+		sudoMoveCommand("/tmp/machine-scp.abc123", "/etc/docker", "daemon.json", 0640),
+	}, client.commands)
+}
+
+func TestSudoMoveCommand(t *testing.T) {
+	command := sudoMoveCommand("/tmp/machine-scp.abc123", "/etc/my app", "conf", 0600)
+
+	assert.Contains(t, command, "tmp='/tmp/machine-scp.abc123'; dest='/etc/my app';")
+	assert.Contains(t, command, `dest="$dest"/'conf'`)
+	assert.Contains(t, command, `sudo -n chown 0:0 "$tmp" && sudo -n chmod 600 "$tmp"`)
+	assert.True(t, strings.HasSuffix(command, `sudo -n mv -f "$tmp" "$dest"`))
+}
+
+func TestSudoDownload(t *testing.T) {
+	dir := t.TempDir()
+	client := &fakeSudoClient{files: map[string]string{"/etc/shadow": "root:*:"}}
+
+	assert.NoError(t, sudoDownload(client, "/etc/shadow", dir))
+
+	content, err := os.ReadFile(filepath.Join(dir, "shadow"))
+	assert.NoError(t, err)
+	assert.Equal(t, "root:*:", string(content))
+}
+
+func TestNewSudoClientWithoutPasswordlessSudo(t *testing.T) {
+	defer func(f func(h HostInfo, user string) (resumeClient, error)) { newResumeClient = f }(newResumeClient)
+	newResumeClient = func(h HostInfo, user string) (resumeClient, error) {
+		return &fakeSudoClient{noSudo: true}, nil
+	}
+
+	_, err := newSudoClient(&MockHostInfo{name: "machine"}, "")
+
+	assert.EqualError(t, err, "--sudo needs passwordless sudo on machine: sudo: a password is required")
+}
+
+func TestScpSudoArgs(t *testing.T) {
+	err := scpSudo("machine:/etc/a", "/tmp/a", true, false, false, nil)
+
+	assert.Equal(t, errSudoArgs, err)
+}
@@ -119,7 +119,7 @@ func TestGetScpCmd(t *testing.T) {
 		sshKeyPath:  "/fake/keypath/id_rsa",
 	}}
 
-	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, false, false, &hostInfoLoader)
+	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, false, false, nil, &hostInfoLoader)
 
 	expectedArgs := append(
 		baseSSHArgs,
@@ -146,7 +146,7 @@ func TestGetScpCmdWithoutSshKey(t *testing.T) {
 		sshUsername: "user",
 	}}
 
-	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, false, false, &hostInfoLoader)
+	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, false, false, nil, &hostInfoLoader)
 
 	expectedArgs := append(
 		baseSSHArgs,
@@ -167,7 +167,7 @@ func TestGetScpCmdWithDelta(t *testing.T) {
 		sshUsername: "user",
 	}}
 
-	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, true, false, &hostInfoLoader)
+	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, true, false, nil, &hostInfoLoader)
 
 	expectedArgs := append(
 		[]string{"--progress"},
@@ -18,7 +18,29 @@ func cmdScp(c CommandLine, api libmachine.API) error {
 
 	hostInfoLoader := &storeHostInfoLoader{api}
 
-	cmd, err := getScpCmd(src, dest, c.Bool("recursive"), c.Bool("delta"), c.Bool("quiet"), hostInfoLoader)
+	if src == scpStdio || dest == scpStdio {
+		return scpStream(src, dest, c.Bool("recursive"), c.Bool("delta"), c.Bool("resume"), c.Bool("sudo"), c.StringSlice("exclude"), hostInfoLoader)
+	}
+
+	if c.Bool("sudo") {
+		return scpSudo(src, dest, c.Bool("recursive"), c.Bool("delta"), c.Bool("resume"), hostInfoLoader)
+	}
+
+	if c.Bool("resume") {
+		return scpResume(src, dest, c.Bool("recursive"), c.Bool("delta"), hostInfoLoader)
+	}
+
+	excludes := c.StringSlice("exclude")
+	if len(excludes) > 0 {
+		if err := validateExcludes(excludes, c.Bool("recursive")); err != nil {
+			return invalidArguments(err)
+		}
+		if !c.Bool("delta") {
+			return scpExclude(src, dest, excludes, hostInfoLoader)
+		}
+	}
+
+	cmd, err := getScpCmd(src, dest, c.Bool("recursive"), c.Bool("delta"), c.Bool("quiet"), excludes, hostInfoLoader)
 	if err != nil {
 		return err
 	}
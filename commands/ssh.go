@@ -1,9 +1,13 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/ssh"
 	"github.com/rancher/machine/libmachine/state"
 )
 
@@ -15,6 +19,14 @@ func (e errStateInvalidForSSH) Error() string {
 	return fmt.Sprintf("Error: Cannot run SSH command: Host %q is not running", e.HostName)
 }
 
+type errSudoNotConfigured struct {
+	HostName string
+}
+
+func (e errSudoNotConfigured) Error() string {
+	return fmt.Sprintf("Error: Cannot run SSH command with --sudo: passwordless sudo is not configured for the SSH user of host %q", e.HostName)
+}
+
 func cmdSSH(c CommandLine, api libmachine.API) error {
 	// Check for help flag -- Needed due to SkipFlagParsing
 	firstArg := c.Args().First()
@@ -23,7 +35,17 @@ func cmdSSH(c CommandLine, api libmachine.API) error {
 		return nil
 	}
 
-	target, err := targetHost(c, api)
+	args, flags, err := parseSSHFlags(c.Args())
+	if err != nil {
+		return invalidArguments(err)
+	}
+
+	var target string
+	if len(args) == 0 {
+		target, err = defaultHost(api)
+	} else {
+		target, args = args[0], args[1:]
+	}
 	if err != nil {
 		return err
 	}
@@ -32,6 +54,9 @@ func cmdSSH(c CommandLine, api libmachine.API) error {
 	if err != nil {
 		return err
 	}
+	if flags.agentForward {
+		drivers.SetSSHAgentForward(host.Driver, true)
+	}
 
 	currentState, err := host.Driver.GetState()
 	if err != nil {
@@ -47,5 +72,79 @@ func cmdSSH(c CommandLine, api libmachine.API) error {
 		return err
 	}
 
-	return client.Shell(c.Args().Tail()...)
+	if flags.sudo {
+		// sudo -n fails instead of prompting when a password is needed.
+		if _, err := client.Output("sudo -n true"); err != nil {
+			return errSudoNotConfigured{host.Name}
+		}
+	}
+
+	if flags.commandFile != "" {
+		return runCommandFile(host, client, flags.commandFile, args, flags.sudo)
+	}
+
+	if flags.sudo {
+		if len(args) == 0 {
+			forceTTY(client)
+		}
+		args = sudoArgs(args)
+	}
+
+	return client.Shell(args...)
+}
+
+// forceTTY makes the external ssh client allocate a terminal for the remote
+// command, which it only does for a login shell. The interactive root shell
+// of sudo -i is a remote command. The native client always requests one.
+func forceTTY(client ssh.Client) {
+	if ec, ok := client.(*ssh.ExternalClient); ok {
+		ec.BaseArgs = append(ec.BaseArgs, "-t")
+	}
+}
+
+// sshFlags are the flags of the ssh command.
+type sshFlags struct {
+	sudo         bool
+	agentForward bool
+	commandFile  string
+}
+
+var errCommandFileNoPath = errors.New("--command-file requires the path of a script")
+
+// parseSSHFlags strips the leading --sudo, --ssh-agent-forward and
+// --command-file flags from the arguments of the ssh command, which skips
+// flag parsing to pass the remote command as is.
+func parseSSHFlags(args []string) ([]string, sshFlags, error) {
+	flags := sshFlags{}
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--sudo":
+			flags.sudo = true
+		case args[0] == "--ssh-agent-forward":
+			flags.agentForward = true
+		case args[0] == "--command-file":
+			if len(args) < 2 || args[1] == "" {
+				return nil, flags, errCommandFileNoPath
+			}
+			flags.commandFile, args = args[1], args[1:]
+		case strings.HasPrefix(args[0], "--command-file="):
+			flags.commandFile = strings.TrimPrefix(args[0], "--command-file=")
+			if flags.commandFile == "" {
+				return nil, flags, errCommandFileNoPath
+			}
+		default:
+			return args, flags, nil
+		}
+		args = args[1:]
+	}
+	return args, flags, nil
+}
+
+// sudoArgs wraps the remote command in non-interactive sudo, or starts a
+// root login shell when there is no command.
+func sudoArgs(args []string) []string {
+	if len(args) == 0 {
+		return []string{"sudo", "-n", "-i"}
+	}
+	return []string{"sudo", "-n", "sh", "-c", shellQuote(strings.Join(args, " "))}
 }
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/ssh"
+)
+
+// proxyEnvVars are the engine env variables exported to the scripts run with
+// --command-file, in lower case.
+var proxyEnvVars = map[string]bool{
+	"http_proxy":  true,
+	"https_proxy": true,
+	"no_proxy":    true,
+}
+
+// remoteExitError is the error of a script run with --command-file that
+// exited with a non-zero status, which becomes the exit code of the command.
+type remoteExitError struct {
+	status int
+}
+
+func (e remoteExitError) Error() string {
+	return fmt.Sprintf("the script exited with status %d", e.status)
+}
+
+// runCommandFile uploads the local script path to a temporary file on the
+// host, runs it with the interpreter of its shebang and the arguments args,
+// then removes it.
+func runCommandFile(h *host.Host, client ssh.Client, path string, args []string, sudo bool) error {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	upload := fmt.Sprintf(`f=$(mktemp) && printf %%s %s | base64 -d > "$f" && echo "$f"`, base64.StdEncoding.EncodeToString(script))
+	output, err := client.Output(upload)
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %s", path, err)
+	}
+
+	remotePath := strings.TrimSpace(output)
+	if remotePath == "" {
+		return fmt.Errorf("error uploading %s: no temporary file was created", path)
+	}
+
+	command := scriptCommand(scriptInterpreter(script), remotePath, args, proxyEnv(h), sudo)
+	err = client.Shell("sh", "-c", shellQuote(command))
+
+	var exitErr interface{ ExitStatus() int }
+	var cmdErr interface{ ExitCode() int }
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr):
+		return remoteExitError{exitErr.ExitStatus()}
+	case errors.As(err, &cmdErr):
+		return remoteExitError{cmdErr.ExitCode()}
+	}
+
+	// The session failed before the script could remove its file.
+	if _, rmErr := client.Output("rm -f " + shellQuote(remotePath)); rmErr != nil {
+		log.Warnf("Couldn't remove %s from %s: %s", remotePath, h.Name, rmErr)
+	}
+	return err
+}
+
+// scriptInterpreter returns the interpreter of the shebang of a script, sh
+// when it has none.
+func scriptInterpreter(script []byte) []string {
+	line, _, _ := bufio.NewReader(bytes.NewReader(script)).ReadLine()
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return []string{"sh"}
+	}
+
+	interpreter := strings.Fields(string(line[2:]))
+	if len(interpreter) == 0 {
+		return []string{"sh"}
+	}
+	return interpreter
+}
+
+// scriptCommand returns the remote command running the uploaded script,
+// which removes it and exits with the status of the script.
+func scriptCommand(interpreter []string, remotePath string, args, env []string, sudo bool) string {
+	words := []string{}
+	if sudo {
+		words = append(words, "sudo", "-n")
+	}
+	if len(env) > 0 {
+		// sudo resets the environment, env sets it for the interpreter.
+		words = append(words, "env")
+		for _, v := range env {
+			words = append(words, shellQuote(v))
+		}
+	}
+	for _, word := range interpreter {
+		words = append(words, shellQuote(word))
+	}
+	words = append(words, shellQuote(remotePath))
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+
+	return fmt.Sprintf("%s; rc=$?; rm -f %s; exit $rc", strings.Join(words, " "), shellQuote(remotePath))
+}
+
+// proxyEnv returns the proxy variables of the engine env of a host, which the
+// scripts need to reach the same networks as the Docker daemon.
+func proxyEnv(h *host.Host) []string {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return nil
+	}
+
+	env := []string{}
+	for _, v := range h.HostOptions.EngineOptions.Env {
+		name := strings.SplitN(v, "=", 2)[0]
+		if proxyEnvVars[strings.ToLower(name)] {
+			env = append(env, v)
+		}
+	}
+	return env
+}
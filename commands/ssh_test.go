@@ -1,12 +1,17 @@
 package commands
 
 import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rancher/machine/commands/commandstest"
 	"github.com/rancher/machine/drivers/fakedriver"
 	"github.com/rancher/machine/libmachine"
 	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/engine"
 	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/libmachinetest"
 	"github.com/rancher/machine/libmachine/ssh"
@@ -92,6 +97,65 @@ func TestCmdSSH(t *testing.T) {
 			},
 			expectedErr: errStateInvalidForSSH{"default"},
 		},
+		{
+			commandLine: &commandstest.FakeCommandLine{
+				CliArgs: []string{"--sudo", "default", "cat", "/etc/shadow"},
+			},
+			api: &libmachinetest.FakeAPI{
+				Hosts: []*host.Host{
+					{
+						Name: "default",
+						Driver: &fakedriver.Driver{
+							MockState: state.Running,
+						},
+					},
+				},
+			},
+			expectedErr:   nil,
+			clientCreator: &FakeSSHClientCreator{},
+			expectedShell: []string{"sudo", "-n", "sh", "-c", "'cat /etc/shadow'"},
+		},
+		{
+			commandLine: &commandstest.FakeCommandLine{
+				CliArgs: []string{"--sudo"},
+			},
+			api: &libmachinetest.FakeAPI{
+				Hosts: []*host.Host{
+					{
+						Name: "default",
+						Driver: &fakedriver.Driver{
+							MockState: state.Running,
+						},
+					},
+				},
+			},
+			expectedErr:   nil,
+			clientCreator: &FakeSSHClientCreator{},
+			expectedShell: []string{"sudo", "-n", "-i"},
+		},
+		{
+			commandLine: &commandstest.FakeCommandLine{
+				CliArgs: []string{"--sudo", "default", "df"},
+			},
+			api: &libmachinetest.FakeAPI{
+				Hosts: []*host.Host{
+					{
+						Name: "default",
+						Driver: &fakedriver.Driver{
+							MockState: state.Running,
+						},
+					},
+				},
+			},
+			expectedErr: errSudoNotConfigured{"default"},
+			clientCreator: &FakeSSHClientCreator{
+				client: &sshtest.FakeClient{
+					Outputs: map[string]sshtest.CmdResult{
+						"sudo -n true": {Err: errors.New("sudo: a password is required")},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -109,3 +173,73 @@ func TestCmdSSH(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSSHFlags(t *testing.T) {
+	args, flags, err := parseSSHFlags([]string{"--ssh-agent-forward", "--sudo", "default", "--sudo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "--sudo"}, args)
+	assert.Equal(t, sshFlags{sudo: true, agentForward: true}, flags)
+
+	args, flags, err = parseSSHFlags([]string{"default", "ls"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "ls"}, args)
+	assert.Equal(t, sshFlags{}, flags)
+
+	args, flags, err = parseSSHFlags([]string{"--command-file", "setup.sh", "--command-file=deploy.sh", "default", "-v"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "-v"}, args)
+	assert.Equal(t, sshFlags{commandFile: "deploy.sh"}, flags)
+
+	_, _, err = parseSSHFlags([]string{"--sudo", "--command-file"})
+	assert.Equal(t, errCommandFileNoPath, err)
+}
+
+func TestCmdSSHCommandFile(t *testing.T) {
+	script := []byte("#!/usr/bin/env bash\necho it's $1\n")
+	path := filepath.Join(t.TempDir(), "setup.sh")
+	assert.NoError(t, os.WriteFile(path, script, 0644))
+
+	upload := `f=$(mktemp) && printf %s ` + base64.StdEncoding.EncodeToString(script) + ` | base64 -d > "$f" && echo "$f"`
+	client := &sshtest.FakeClient{
+		Outputs: map[string]sshtest.CmdResult{
+			upload: {Out: "/tmp/tmp.x1\n"},
+		},
+	}
+	host.SetSSHClientCreator(&FakeSSHClientCreator{client: client})
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "default",
+				Driver: &fakedriver.Driver{
+					MockState: state.Running,
+				},
+				HostOptions: &host.Options{
+					EngineOptions: &engine.Options{
+						Env: []string{"HTTPS_PROXY=http://proxy:3128", "FOO=bar"},
+					},
+				},
+			},
+		},
+	}
+
+	err := cmdSSH(&commandstest.FakeCommandLine{CliArgs: []string{"--sudo", "--command-file", path, "default", "ready"}}, api)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", shellQuote(`sudo -n env 'HTTPS_PROXY=http://proxy:3128' '/usr/bin/env' 'bash' '/tmp/tmp.x1' 'ready'; rc=$?; rm -f '/tmp/tmp.x1'; exit $rc`)}, client.ActivatedShell)
+}
+
+func TestScriptInterpreter(t *testing.T) {
+	assert.Equal(t, []string{"/bin/bash", "-e"}, scriptInterpreter([]byte("#!/bin/bash -e\nset -x\n")))
+	assert.Equal(t, []string{"sh"}, scriptInterpreter([]byte("echo hello\n")))
+	assert.Equal(t, []string{"sh"}, scriptInterpreter([]byte("#!\n")))
+}
+
+func TestExitCodeRemoteExit(t *testing.T) {
+	assert.Equal(t, 42, exitCode(remoteExitError{42}))
+}
+
+func TestForceTTY(t *testing.T) {
+	client := &ssh.ExternalClient{BaseArgs: []string{"docker@192.168.99.100", "-p", "22"}}
+	forceTTY(client)
+	assert.Equal(t, []string{"docker@192.168.99.100", "-p", "22", "-t"}, client.BaseArgs)
+}
@@ -1,12 +1,56 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+const (
+	startRetryDefaultInterval = 10
+	startRetryMaxInterval     = 5 * time.Minute
+)
+
+// startRetryPolicy is how start retries the machines the provider fails to
+// start for lack of capacity or transiently.
+type startRetryPolicy struct {
+	retries      int
+	interval     time.Duration
+	allowReplace bool
+}
+
+// startRetry is the retry policy of start, set from its flags.
+var startRetry startRetryPolicy
+
+var (
+	startMachine     = (*host.Host).Start
+	provisionMachine = (*host.Host).Provision
+	startRetrySleep  = time.Sleep
 )
 
 func cmdStart(c CommandLine, api libmachine.API) error {
-	if err := runAction("start", c, api); err != nil {
+	if c.Int("start-retries") < 0 {
+		return invalidArguments(errors.New("--start-retries can't be negative"))
+	}
+	interval := time.Duration(c.Int("start-retry-interval")) * time.Second
+	if interval <= 0 {
+		interval = startRetryDefaultInterval * time.Second
+	}
+
+	startRetry = startRetryPolicy{
+		retries:      c.Int("start-retries"),
+		interval:     interval,
+		allowReplace: c.Bool("start-allow-replace"),
+	}
+
+	if err := runActionWaitHealthy("start", c, api); err != nil {
 		return err
 	}
 
@@ -14,3 +58,59 @@ func cmdStart(c CommandLine, api libmachine.API) error {
 
 	return nil
 }
+
+// startHost starts a machine, retrying with an exponential backoff while the
+// provider lacks the capacity to run it or fails transiently. The other
+// errors, e.g. of a terminated instance, are returned at once. With
+// allowReplace, the instance that still can't be started for lack of
+// capacity is replaced by a new one, which is then provisioned.
+func startHost(h *host.Host, policy startRetryPolicy) error {
+	interval := policy.interval
+	for attempt := 0; ; attempt++ {
+		err := startMachine(h)
+		if err == nil {
+			return nil
+		}
+
+		// A start that failed after the instance was started, e.g. while
+		// waiting for it, only has to wait for Docker when retried.
+		var inState mcnerror.ErrHostAlreadyInState
+		if attempt > 0 && errors.As(err, &inState) && inState.State == state.Running {
+			return h.WaitForDocker()
+		}
+
+		capacity := errors.Is(err, mcnerror.ErrCapacity)
+		if !capacity && !errors.Is(err, mcnerror.ErrTransient) {
+			return err
+		}
+
+		if attempt == policy.retries {
+			if capacity && policy.allowReplace {
+				return replaceHost(h)
+			}
+			return err
+		}
+
+		log.Warnf("Starting %q failed, retrying in %s (%d/%d): %s", h.Name, interval, attempt+1, policy.retries, err)
+		startRetrySleep(interval)
+		interval *= 2
+		if interval > startRetryMaxInterval {
+			interval = startRetryMaxInterval
+		}
+	}
+}
+
+// replaceHost replaces the instance of a machine that can't be started by a
+// new one, which is provisioned as by create.
+func replaceHost(h *host.Host) error {
+	log.Warnf("%q can't be started for lack of capacity, replacing its instance, the data of the old one is lost", h.Name)
+
+	if err := drivers.Replace(h.Driver); err != nil {
+		if errors.Is(err, mcnerror.ErrNotSupported) {
+			return fmt.Errorf("the %s driver doesn't support replacing the instance of %q", h.DriverName, h.Name)
+		}
+		return fmt.Errorf("error replacing the instance of %q: %s", h.Name, err)
+	}
+
+	return provisionMachine(h)
+}
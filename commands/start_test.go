@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubStart replaces the start of the machines by start, which is given the
+// number of the attempt, and records the retry delays.
+func stubStart(t *testing.T, start func(attempt int) error) *[]time.Duration {
+	origStart, origProvision, origSleep := startMachine, provisionMachine, startRetrySleep
+	t.Cleanup(func() {
+		startMachine, provisionMachine, startRetrySleep = origStart, origProvision, origSleep
+	})
+
+	attempt := 0
+	startMachine = func(h *host.Host) error {
+		attempt++
+		return start(attempt)
+	}
+	delays := []time.Duration{}
+	startRetrySleep = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+	return &delays
+}
+
+type replaceDriver struct {
+	*fakedriver.Driver
+	replaced bool
+}
+
+func (d *replaceDriver) Replace() error {
+	d.replaced = true
+	return nil
+}
+
+func TestStartHostRetriesCapacityErrors(t *testing.T) {
+	delays := stubStart(t, func(attempt int) error {
+		if attempt < 3 {
+			return mcnerror.Capacity(errors.New("InsufficientInstanceCapacity"))
+		}
+		return nil
+	})
+
+	err := startHost(&host.Host{Name: "foo"}, startRetryPolicy{retries: 5, interval: time.Second})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *delays)
+}
+
+func TestStartHostBackoffIsCapped(t *testing.T) {
+	delays := stubStart(t, func(attempt int) error {
+		return mcnerror.MarkTransient(errors.New("RequestLimitExceeded"))
+	})
+
+	err := startHost(&host.Host{Name: "foo"}, startRetryPolicy{retries: 3, interval: 3 * time.Minute})
+
+	assert.EqualError(t, err, "RequestLimitExceeded")
+	assert.Equal(t, []time.Duration{3 * time.Minute, 5 * time.Minute, 5 * time.Minute}, *delays)
+}
+
+func TestStartHostDoesNotRetryOtherErrors(t *testing.T) {
+	delays := stubStart(t, func(attempt int) error {
+		return errors.New("IncorrectInstanceState: the instance is terminated")
+	})
+
+	err := startHost(&host.Host{Name: "foo"}, startRetryPolicy{retries: 3, interval: time.Second, allowReplace: true})
+
+	assert.EqualError(t, err, "IncorrectInstanceState: the instance is terminated")
+	assert.Empty(t, *delays)
+}
+
+func TestStartHostReplace(t *testing.T) {
+	stubStart(t, func(attempt int) error {
+		return mcnerror.Capacity(errors.New("InsufficientInstanceCapacity"))
+	})
+	provisioned := false
+	provisionMachine = func(h *host.Host) error {
+		provisioned = true
+		return nil
+	}
+
+	driver := &replaceDriver{Driver: &fakedriver.Driver{}}
+	err := startHost(&host.Host{Name: "foo", Driver: driver}, startRetryPolicy{retries: 1, interval: time.Second, allowReplace: true})
+
+	assert.NoError(t, err)
+	assert.True(t, driver.replaced)
+	assert.True(t, provisioned)
+}
+
+func TestStartHostReplaceNotSupported(t *testing.T) {
+	stubStart(t, func(attempt int) error {
+		return mcnerror.Capacity(errors.New("ZONE_RESOURCE_POOL_EXHAUSTED"))
+	})
+
+	h := &host.Host{Name: "foo", DriverName: "fakedriver", Driver: &fakedriver.Driver{}}
+	err := startHost(h, startRetryPolicy{allowReplace: true})
+
+	assert.EqualError(t, err, `the fakedriver driver doesn't support replacing the instance of "foo"`)
+}
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/rancher/machine/libmachine"
 	"github.com/rancher/machine/libmachine/log"
@@ -39,7 +38,7 @@ func cmdStatus(c CommandLine, api libmachine.API) error {
 
 	currentState, err := host.Driver.GetState()
 	if err != nil {
-		if !strings.Contains(strings.ToLower(err.Error()), "not found") {
+		if !isInstanceNotFound(err) {
 			return fmt.Errorf("error getting state for host %s: %s", host.Name, err)
 		}
 
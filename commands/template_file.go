@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var errFormatAndTemplateFile = errors.New("Error: --format and --template-file can't be used together")
+
+// templateFlag returns the Go template of --format, or the content of the file
+// of --template-file and its path. The final newline of the file is dropped,
+// the commands ending each output with one.
+func templateFlag(c CommandLine) (string, string, error) {
+	format, path := c.String("format"), c.String("template-file")
+	if path == "" {
+		return format, "", nil
+	}
+	if format != "" {
+		return "", "", invalidArguments(errFormatAndTemplateFile)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading the template file: %s", err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return "", "", fmt.Errorf("the template file %s is empty", path)
+	}
+
+	return strings.TrimSuffix(string(content), "\n"), path, nil
+}
+
+// templateError names the template file of an error parsing or executing it,
+// the error itself naming the offending field.
+func templateError(err error, path string) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("error in the template file %s: %s", path, err)
+}
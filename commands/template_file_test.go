@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	assert.NoError(t, os.WriteFile(path, []byte("{{.Name}}\n{{.URL}}\n"), 0600))
+
+	format, file, err := templateFlag(&commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"format": "{{.Name}}"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "{{.Name}}", format)
+	assert.Empty(t, file)
+
+	format, file, err = templateFlag(&commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"template-file": path}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "{{.Name}}\n{{.URL}}", format)
+	assert.Equal(t, path, file)
+
+	_, _, err = templateFlag(&commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"format": "{{.Name}}", "template-file": path}},
+	})
+	assert.Equal(t, invalidArguments(errFormatAndTemplateFile), err)
+
+	_, _, err = templateFlag(&commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{"template-file": filepath.Join(t.TempDir(), "missing")}},
+	})
+	assert.Error(t, err)
+}
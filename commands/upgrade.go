@@ -1,7 +1,273 @@
 package commands
 
-import "github.com/rancher/machine/libmachine"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcndockerclient"
+	"github.com/rancher/machine/libmachine/mcnutils"
+	"github.com/rancher/machine/libmachine/persist"
+	"github.com/rancher/machine/libmachine/provision"
+	"github.com/rancher/machine/libmachine/state"
+	"github.com/rancher/machine/libmachine/versioncmp"
+)
+
+const upgradeCheckParallel = 10
+
+var errUpgradeAllArgs = errors.New("Error: --all doesn't take machine names as arguments")
+
+// dockerReleaseURL is the GitHub API endpoint of the latest Docker Engine
+// release, the default target of upgrade --check.
+var dockerReleaseURL = "https://api.github.com/repos/moby/moby/releases/latest"
+
+// upgradeCheckResult is the outcome of checking one machine.
+type upgradeCheckResult struct {
+	state   string
+	current string
+	err     error
+}
 
 func cmdUpgrade(c CommandLine, api libmachine.API) error {
-	return runAction("upgrade", c, api)
+	if c.Bool("all") && len(c.Args()) > 0 {
+		return errUpgradeAllArgs
+	}
+
+	rolling, err := validateRollingUpgrade(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("check") {
+		return upgradeCheck(c, api, os.Stdout)
+	}
+
+	if !c.Bool("all") && !c.Bool("rolling") {
+		return runAction("upgrade", c, api)
+	}
+
+	hosts, err := loadUpgradeHosts(c, api)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("rolling") {
+		return upgradeRolling(api, hosts, rolling)
+	}
+
+	if errs := runLockedActionForeachMachine(api, "upgrade", hosts); len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	for _, h := range hosts {
+		if err := api.Save(h); err != nil {
+			return fmt.Errorf("Error saving host to store: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// loadUpgradeHosts loads the machines named on the command line, every
+// machine selected by --filter and --group with --all, or the default
+// machine.
+func loadUpgradeHosts(c CommandLine, api libmachine.API) ([]*host.Host, error) {
+	if c.Bool("all") {
+		hosts, hostsInError, err := persist.LoadAllHosts(api)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, err := range hostsInError {
+			log.Warnf("Skipping %s, its configuration could not be loaded: %s", name, err)
+		}
+
+		if hosts, err = selectUpgradeHosts(hosts, c.StringSlice("filter"), c.StringSlice("group")); err != nil {
+			return nil, err
+		}
+
+		if len(hosts) == 0 {
+			return nil, ErrHostLoad
+		}
+
+		return hosts, nil
+	}
+
+	names := c.Args()
+	if len(names) == 0 {
+		target, err := targetHost(c, api)
+		if err != nil {
+			return nil, err
+		}
+		names = []string{target}
+	}
+
+	hosts, hostsInError := persist.LoadHosts(api, names)
+	if len(hostsInError) > 0 {
+		errs := []error{}
+		for _, err := range hostsInError {
+			errs = append(errs, err)
+		}
+		return nil, consolidateErrs(errs)
+	}
+
+	if len(hosts) == 0 {
+		return nil, ErrHostLoad
+	}
+
+	return hosts, nil
+}
+
+// upgradeCheck prints which machines run a Docker version older than the
+// target version, without changing anything.
+func upgradeCheck(c CommandLine, api libmachine.API, out io.Writer) error {
+	hosts, err := loadUpgradeHosts(c, api)
+	if err != nil {
+		return err
+	}
+
+	target := c.String("target-version")
+	if target == "" {
+		if target, err = latestDockerVersion(); err != nil {
+			return fmt.Errorf("Error getting the latest Docker version, use --target-version to set it: %s", err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		results = map[string]upgradeCheckResult{}
+	)
+
+	runForeachHostLimited(hosts, upgradeCheckParallel, func(h *host.Host) error {
+		result := checkDockerVersion(h)
+
+		mu.Lock()
+		results[h.Name] = result
+		mu.Unlock()
+
+		return result.err
+	})
+
+	failed := printUpgradeCheck(out, target, results)
+	if failed > 0 {
+		return fmt.Errorf("Error: the Docker version of %d of %d machines could not be checked", failed, len(hosts))
+	}
+
+	return nil
+}
+
+// checkDockerVersion returns the Docker version of a machine, asking the
+// daemon first and the docker client over SSH if the daemon doesn't answer.
+// Machines that are not running are not started.
+func checkDockerVersion(h *host.Host) upgradeCheckResult {
+	if h.HostOptions == nil || h.HostOptions.AuthOptions == nil {
+		return upgradeCheckResult{err: errors.New("Docker was not installed on machine")}
+	}
+
+	s, err := h.Driver.GetState()
+	if err != nil {
+		return upgradeCheckResult{err: err}
+	}
+
+	result := upgradeCheckResult{state: s.String()}
+	if s != state.Running {
+		result.err = fmt.Errorf("machine is %s", strings.ToLower(s.String()))
+		return result
+	}
+
+	if h.DaemonSSHTransport() {
+		result.current, result.err = h.DockerVersion()
+		return result
+	}
+
+	if result.current, err = mcndockerclient.DockerVersion(h); err == nil {
+		return result
+	}
+	log.Debugf("Docker daemon of %s did not answer, asking over SSH: %s", h.Name, err)
+
+	result.current, result.err = provision.DockerClientVersion(provision.GenericSSHCommander{Driver: h.Driver})
+	return result
+}
+
+// printUpgradeCheck prints the table of the checked machines and returns
+// the number of machines that couldn't be checked.
+func printUpgradeCheck(out io.Writer, target string, results map[string]upgradeCheckResult) int {
+	names := []string{}
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	failed := 0
+	fmt.Fprintln(w, "NAME\tSTATE\tCURRENT\tTARGET\tUPGRADE\tERROR")
+	for _, name := range names {
+		result := results[name]
+
+		upgrade := "Unknown"
+		errMsg := ""
+		switch {
+		case result.err != nil:
+			failed++
+			errMsg = result.err.Error()
+		case versioncmp.LessThan(result.current, target):
+			upgrade = "Available"
+		default:
+			upgrade = "Up to date"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, result.state, result.current, target, upgrade, errMsg)
+	}
+
+	return failed
+}
+
+// latestDockerVersion returns the version of the latest Docker Engine
+// release, e.g. "28.5.1".
+func latestDockerVersion() (string, error) {
+	req, err := http.NewRequest("GET", dockerReleaseURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if mcnutils.GithubAPIToken != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("token %s", mcnutils.GithubAPIToken))
+	}
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s from %s", rsp.Status, dockerReleaseURL)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+
+	// Releases are tagged v28.5.1, or docker-v29.0.0 since Docker 29.
+	version := strings.TrimPrefix(strings.TrimPrefix(release.TagName, "docker-"), "v")
+	if version == "" {
+		return "", fmt.Errorf("no release tag in the response of %s", dockerReleaseURL)
+	}
+
+	return version, nil
 }
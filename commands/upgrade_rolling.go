@@ -0,0 +1,327 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/provision"
+)
+
+var (
+	errUpgradeRollingCheck  = errors.New("--rolling can't be used with --check")
+	errUpgradeRollingOnly   = errors.New("--max-unavailable, --drain and --continue-on-error can only be used with --rolling")
+	errUpgradeSelectorNoAll = errors.New("--filter and --group select among all the machines, they can only be used with --all")
+)
+
+const (
+	upgradeResultUpgraded = "Upgraded"
+	upgradeResultFailed   = "Failed"
+	upgradeResultSkipped  = "Skipped"
+)
+
+// rollingUpgradeOptions are the options of upgrade --rolling.
+type rollingUpgradeOptions struct {
+	maxUnavailable  int
+	drain           bool
+	continueOnError bool
+	timeout         time.Duration
+	// lockHosts takes the lock of each machine while it is upgraded, the
+	// machines of --all are not locked up front by withHostsLocked.
+	lockHosts bool
+}
+
+// upgradeResult is the outcome of upgrading one machine.
+type upgradeResult struct {
+	result string
+	err    error
+}
+
+var (
+	upgradeMachine = (*host.Host).Upgrade
+
+	// swarmCommand runs a docker command on a machine over SSH.
+	swarmCommand = func(h *host.Host, command string) (string, error) {
+		return provision.GenericSSHCommander{Driver: h.Driver}.SSHCommand(command)
+	}
+)
+
+// validateRollingUpgrade checks the flags of upgrade --rolling and returns
+// its options.
+func validateRollingUpgrade(c CommandLine) (rollingUpgradeOptions, error) {
+	opts := rollingUpgradeOptions{
+		maxUnavailable:  c.Int("max-unavailable"),
+		drain:           c.Bool("drain"),
+		continueOnError: c.Bool("continue-on-error"),
+		timeout:         time.Duration(c.Int("wait-healthy-timeout")) * time.Second,
+		lockHosts:       c.Bool("all"),
+	}
+
+	if !c.Bool("all") && (len(c.StringSlice("filter")) > 0 || len(c.StringSlice("group")) > 0) {
+		return opts, invalidArguments(errUpgradeSelectorNoAll)
+	}
+
+	if !c.Bool("rolling") {
+		if c.IsSet("max-unavailable") || opts.drain || opts.continueOnError {
+			return opts, invalidArguments(errUpgradeRollingOnly)
+		}
+		return opts, nil
+	}
+
+	if c.Bool("check") {
+		return opts, invalidArguments(errUpgradeRollingCheck)
+	}
+	if c.IsSet("max-unavailable") && opts.maxUnavailable < 1 {
+		return opts, invalidArguments(fmt.Errorf("--max-unavailable must be at least 1, not %d", opts.maxUnavailable))
+	}
+	if opts.maxUnavailable < 1 {
+		opts.maxUnavailable = 1
+	}
+	if opts.timeout <= 0 {
+		opts.timeout = waitHealthyDefaultTimeout * time.Second
+	}
+
+	return opts, nil
+}
+
+// selectUpgradeHosts keeps the machines matching the --filter conditions,
+// like ls, and belonging to one of the --group groups, like inventory.
+func selectUpgradeHosts(hosts []*host.Host, filter, groups []string) ([]*host.Host, error) {
+	filters, err := parseFilters(filter)
+	if err != nil {
+		return nil, invalidArguments(err)
+	}
+	hosts = filterHosts(hosts, filters)
+
+	if len(groups) == 0 {
+		return hosts, nil
+	}
+
+	selected := []*host.Host{}
+	for _, h := range hosts {
+		labels := map[string]string{}
+		if h.HostOptions != nil && h.HostOptions.EngineOptions != nil {
+			labels = parseEngineLabels(h.HostOptions.EngineOptions.Labels)
+		}
+
+		if hasAnyGroup(inventoryGroups(h.DriverName, labels), groups) {
+			selected = append(selected, h)
+		}
+	}
+
+	return selected, nil
+}
+
+func hasAnyGroup(groups, wanted []string) bool {
+	for _, group := range groups {
+		for _, w := range wanted {
+			if group == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rollingUpgrade upgrades the machines in name order, at most
+// maxUnavailable at once, waiting for the daemon of each one to answer before
+// it counts as upgraded. The machines that are not upgraded yet are skipped
+// after the first failure, unless continueOnError is set.
+func rollingUpgrade(api libmachine.API, hosts []*host.Host, opts rollingUpgradeOptions) map[string]upgradeResult {
+	sorted := append([]*host.Host{}, hosts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		failed  bool
+		results = map[string]upgradeResult{}
+		slots   = make(chan struct{}, opts.maxUnavailable)
+	)
+
+	for i, h := range sorted {
+		slots <- struct{}{}
+
+		mu.Lock()
+		stop := failed && !opts.continueOnError
+		mu.Unlock()
+		if stop {
+			<-slots
+			mu.Lock()
+			for _, skipped := range sorted[i:] {
+				results[skipped.Name] = upgradeResult{result: upgradeResultSkipped}
+			}
+			mu.Unlock()
+			break
+		}
+
+		log.Infof("Upgrading %q (%d/%d)...", h.Name, i+1, len(sorted))
+
+		wg.Add(1)
+		go func(h *host.Host) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			upgrade := func() error { return upgradeOne(api, h, sorted, opts) }
+			var err error
+			if opts.lockHosts {
+				err = withHostLock(api, "upgrade", h, upgrade)
+			} else {
+				err = upgrade()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Errorf("Upgrading %q failed: %s", h.Name, err)
+				failed = true
+				results[h.Name] = upgradeResult{result: upgradeResultFailed, err: err}
+				return
+			}
+			results[h.Name] = upgradeResult{result: upgradeResultUpgraded}
+		}(h)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// upgradeOne upgrades a machine of a rolling upgrade, drained from its swarm
+// cluster with opts.drain.
+func upgradeOne(api libmachine.API, h *host.Host, fleet []*host.Host, opts rollingUpgradeOptions) error {
+	var (
+		manager *host.Host
+		nodeID  string
+	)
+	if opts.drain {
+		var err error
+		if manager, nodeID, err = swarmNodeManager(h, fleet); err != nil {
+			return err
+		}
+		if manager != nil {
+			if err := updateNodeAvailability(manager, nodeID, "drain"); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := upgradeMachine(h)
+	if saveErr := api.Save(h); err == nil && saveErr != nil {
+		err = fmt.Errorf("Error saving host to store: %s", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := waitDaemonHealthy(h, opts.timeout); err != nil {
+		return err
+	}
+
+	// A node that failed to upgrade is left drained, not to schedule tasks
+	// on it.
+	if manager != nil {
+		return updateNodeAvailability(manager, nodeID, "active")
+	}
+
+	return nil
+}
+
+// swarmNodeManager returns the swarm node ID of a machine and a manager of
+// its cluster among the fleet, the machine itself if it is a manager. No
+// manager is returned for a machine that is not part of a swarm cluster.
+func swarmNodeManager(h *host.Host, fleet []*host.Host) (*host.Host, string, error) {
+	nodeID, isManager, err := swarmNodeInfo(h)
+	if err != nil {
+		return nil, "", err
+	}
+	if nodeID == "" {
+		log.Infof("%q is not part of a swarm cluster, it is not drained", h.Name)
+		return nil, "", nil
+	}
+	if isManager {
+		return h, nodeID, nil
+	}
+
+	for _, other := range fleet {
+		if other == h {
+			continue
+		}
+		if _, isManager, err := swarmNodeInfo(other); err == nil && isManager {
+			return other, nodeID, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("no swarm manager among the machines can drain %q", h.Name)
+}
+
+func swarmNodeInfo(h *host.Host) (string, bool, error) {
+	output, err := swarmCommand(h, "sudo docker info --format '{{.Swarm.NodeID}} {{.Swarm.ControlAvailable}}'")
+	if err != nil {
+		return "", false, fmt.Errorf("error getting the swarm node of %q: %s: %s", h.Name, err, strings.TrimSpace(output))
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return "", false, nil
+	}
+	return fields[0], fields[1] == "true", nil
+}
+
+func updateNodeAvailability(manager *host.Host, nodeID, availability string) error {
+	output, err := swarmCommand(manager, fmt.Sprintf("sudo docker node update --availability %s %s", availability, nodeID))
+	if err != nil {
+		return fmt.Errorf("error setting the availability of swarm node %s to %s: %s: %s", nodeID, availability, err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// printUpgradeResults prints the table of the results of a rolling upgrade
+// and returns the number of machines that failed or were skipped.
+func printUpgradeResults(out io.Writer, results map[string]upgradeResult) int {
+	names := []string{}
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	notUpgraded := 0
+	fmt.Fprintln(w, "NAME\tRESULT\tERROR")
+	for _, name := range names {
+		result := results[name]
+		errMsg := ""
+		if result.err != nil {
+			errMsg = result.err.Error()
+		}
+		if result.result != upgradeResultUpgraded {
+			notUpgraded++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, result.result, errMsg)
+	}
+
+	return notUpgraded
+}
+
+// upgradeRolling runs upgrade --rolling for the loaded machines.
+func upgradeRolling(api libmachine.API, hosts []*host.Host, opts rollingUpgradeOptions) error {
+	results := rollingUpgrade(api, hosts, opts)
+
+	notUpgraded := printUpgradeResults(os.Stdout, results)
+	if notUpgraded > 0 {
+		return fleetError(notUpgraded, len(hosts), fmt.Errorf("Error: %d of %d machines were not upgraded", notUpgraded, len(hosts)))
+	}
+
+	return nil
+}
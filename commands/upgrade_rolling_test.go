@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubUpgradeMachine(t *testing.T, upgrade func(h *host.Host) error) {
+	origUpgrade, origSwarm := upgradeMachine, swarmCommand
+	t.Cleanup(func() {
+		upgradeMachine, swarmCommand = origUpgrade, origSwarm
+	})
+	upgradeMachine = upgrade
+	stubDaemonInfo(t, func(h *host.Host) error { return nil })
+}
+
+func upgradeHosts(names ...string) []*host.Host {
+	hosts := []*host.Host{}
+	for _, name := range names {
+		hosts = append(hosts, &host.Host{Name: name, DriverName: "fakedriver", Driver: &fakedriver.Driver{MockName: name}})
+	}
+	return hosts
+}
+
+func TestRollingUpgradeStopsOnFailure(t *testing.T) {
+	upgraded := []string{}
+	stubUpgradeMachine(t, func(h *host.Host) error {
+		upgraded = append(upgraded, h.Name)
+		if h.Name == "b" {
+			return errors.New("apt-get failed")
+		}
+		return nil
+	})
+
+	hosts := upgradeHosts("c", "a", "b")
+	results := rollingUpgrade(&libmachinetest.FakeAPI{Hosts: hosts}, hosts, rollingUpgradeOptions{maxUnavailable: 1, timeout: time.Second})
+
+	assert.Equal(t, []string{"a", "b"}, upgraded)
+	assert.Equal(t, map[string]upgradeResult{
+		"a": {result: upgradeResultUpgraded},
+		"b": {result: upgradeResultFailed, err: errors.New("apt-get failed")},
+		"c": {result: upgradeResultSkipped},
+	}, results)
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, 2, printUpgradeResults(out, results))
+	assert.Equal(t, `NAME   RESULT     ERROR
+a      Upgraded   
+b      Failed     apt-get failed
+c      Skipped    
+`, out.String())
+}
+
+func TestRollingUpgradeContinueOnError(t *testing.T) {
+	stubUpgradeMachine(t, func(h *host.Host) error {
+		if h.Name == "a" {
+			return errors.New("apt-get failed")
+		}
+		return nil
+	})
+
+	hosts := upgradeHosts("a", "b", "c")
+	results := rollingUpgrade(&libmachinetest.FakeAPI{Hosts: hosts}, hosts, rollingUpgradeOptions{maxUnavailable: 2, continueOnError: true, timeout: time.Second})
+
+	assert.Equal(t, upgradeResultFailed, results["a"].result)
+	assert.Equal(t, upgradeResultUpgraded, results["b"].result)
+	assert.Equal(t, upgradeResultUpgraded, results["c"].result)
+}
+
+func TestRollingUpgradeDrain(t *testing.T) {
+	stubUpgradeMachine(t, func(h *host.Host) error { return nil })
+	commands := []string{}
+	swarmCommand = func(h *host.Host, command string) (string, error) {
+		commands = append(commands, h.Name+": "+command)
+		switch h.Name {
+		case "manager":
+			return "mgr1 true\n", nil
+		case "worker":
+			return "wrk1 false\n", nil
+		}
+		return " false\n", nil
+	}
+
+	hosts := upgradeHosts("worker", "manager", "standalone")
+	err := upgradeOne(&libmachinetest.FakeAPI{Hosts: hosts}, hosts[0], hosts, rollingUpgradeOptions{drain: true, timeout: time.Second})
+
+	assert.NoError(t, err)
+	info := "sudo docker info --format '{{.Swarm.NodeID}} {{.Swarm.ControlAvailable}}'"
+	assert.Equal(t, []string{
+		"worker: " + info,
+		"manager: " + info,
+		"manager: sudo docker node update --availability drain wrk1",
+		"manager: sudo docker node update --availability active wrk1",
+	}, commands)
+}
+
+func TestRollingUpgradeDrainWithoutManager(t *testing.T) {
+	stubUpgradeMachine(t, func(h *host.Host) error { return nil })
+	swarmCommand = func(h *host.Host, command string) (string, error) {
+		return "wrk1 false\n", nil
+	}
+
+	hosts := upgradeHosts("worker")
+	err := upgradeOne(&libmachinetest.FakeAPI{Hosts: hosts}, hosts[0], hosts, rollingUpgradeOptions{drain: true, timeout: time.Second})
+
+	assert.EqualError(t, err, `no swarm manager among the machines can drain "worker"`)
+}
+
+func TestSelectUpgradeHosts(t *testing.T) {
+	hosts := upgradeHosts("prod", "dev")
+	hosts[0].HostOptions = &host.Options{EngineOptions: &engine.Options{Labels: []string{"env=prod"}}}
+
+	selected, err := selectUpgradeHosts(hosts, nil, []string{"label_env_prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, hosts[:1], selected)
+
+	selected, err = selectUpgradeHosts(hosts, []string{"name=dev"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hosts[1:], selected)
+
+	_, err = selectUpgradeHosts(hosts, []string{"size=large"}, nil)
+	assert.Equal(t, exitInvalidArguments, exitCode(err))
+}
+
+func TestValidateRollingUpgrade(t *testing.T) {
+	for _, tc := range []struct {
+		flags map[string]interface{}
+		err   error
+	}{
+		{map[string]interface{}{"rolling": true, "check": true}, errUpgradeRollingCheck},
+		{map[string]interface{}{"drain": true}, errUpgradeRollingOnly},
+		{map[string]interface{}{"max-unavailable": 2}, errUpgradeRollingOnly},
+		{map[string]interface{}{"group": []string{"driver_amazonec2"}}, errUpgradeSelectorNoAll},
+	} {
+		_, err := validateRollingUpgrade(&commandstest.FakeCommandLine{LocalFlags: &commandstest.FakeFlagger{Data: tc.flags}})
+		assert.Equal(t, invalidArguments(tc.err), err)
+	}
+
+	opts, err := validateRollingUpgrade(&commandstest.FakeCommandLine{LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{
+		"all":     true,
+		"rolling": true,
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, rollingUpgradeOptions{maxUnavailable: 1, timeout: waitHealthyDefaultTimeout * time.Second, lockHosts: true}, opts)
+
+	_, err = validateRollingUpgrade(&commandstest.FakeCommandLine{LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{
+		"rolling":         true,
+		"max-unavailable": 0,
+	}}})
+	assert.EqualError(t, err, "--max-unavailable must be at least 1, not 0")
+}
+
+func TestRollingUpgradeLocksHosts(t *testing.T) {
+	defer func(timeout time.Duration) { hostLockTimeout = timeout }(hostLockTimeout)
+	hostLockTimeout = 0
+
+	hosts := upgradeHosts("a", "b")
+	api := &machinesDirAPI{FakeAPI: &libmachinetest.FakeAPI{Hosts: hosts}, dir: t.TempDir()}
+
+	locked := map[string][]persist.LockedMachine{}
+	stubUpgradeMachine(t, func(h *host.Host) error {
+		locks, err := persist.ListLocks(api.GetMachinesDir())
+		locked[h.Name] = locks
+		return err
+	})
+
+	busy, err := persist.AcquireOperationLock(api.GetMachinesDir(), "b", "stop", 0)
+	assert.NoError(t, err)
+	defer busy.Release()
+
+	results := rollingUpgrade(api, hosts, rollingUpgradeOptions{maxUnavailable: 1, continueOnError: true, timeout: time.Second, lockHosts: true})
+
+	assert.Equal(t, upgradeResultUpgraded, results["a"].result)
+	assert.Len(t, locked["a"], 2)
+	assert.Equal(t, upgradeResultFailed, results["b"].result)
+	assert.IsType(t, persist.ErrLockTimeout{}, results["b"].err)
+}
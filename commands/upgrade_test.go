@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/mcndockerclient"
+	"github.com/rancher/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdUpgradeAllWithArgs(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"all": true,
+			},
+		},
+	}
+
+	err := cmdUpgrade(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errUpgradeAllArgs, err)
+}
+
+func TestUpgradeCheck(t *testing.T) {
+	defer func(versioner mcndockerclient.DockerVersioner) { mcndockerclient.CurrentDockerVersioner = versioner }(mcndockerclient.CurrentDockerVersioner)
+	mcndockerclient.CurrentDockerVersioner = &mcndockerclient.FakeDockerVersioner{Version: "27.1.0"}
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"all":            true,
+				"check":          true,
+				"target-version": "28.0.0",
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:        "running",
+				Driver:      &fakedriver.Driver{MockState: state.Running},
+				HostOptions: &host.Options{AuthOptions: &auth.Options{}},
+			},
+			{
+				Name:        "stopped",
+				Driver:      &fakedriver.Driver{MockState: state.Stopped},
+				HostOptions: &host.Options{AuthOptions: &auth.Options{}},
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	err := upgradeCheck(commandLine, api, out)
+
+	assert.EqualError(t, err, "Error: the Docker version of 1 of 2 machines could not be checked")
+	assert.Equal(t, "NAME      STATE     CURRENT   TARGET   UPGRADE     ERROR\n"+
+		"running   Running   27.1.0    28.0.0   Available   \n"+
+		"stopped   Stopped             28.0.0   Unknown     machine is stopped\n", out.String())
+}
+
+func TestPrintUpgradeCheckUpToDate(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	failed := printUpgradeCheck(out, "28.0.0", map[string]upgradeCheckResult{
+		"machine": {state: "Running", current: "28.0.0"},
+	})
+
+	assert.Equal(t, 0, failed)
+	assert.Contains(t, out.String(), "Up to date")
+}
+
+func TestLatestDockerVersion(t *testing.T) {
+	defer func(url string) { dockerReleaseURL = url }(dockerReleaseURL)
+
+	for tag, expected := range map[string]string{
+		"v28.5.1":        "28.5.1",
+		"docker-v29.0.0": "29.0.0",
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"tag_name": "` + tag + `"}`))
+		}))
+		dockerReleaseURL = server.URL
+
+		version, err := latestDockerVersion()
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, version)
+		server.Close()
+	}
+}
@@ -21,7 +21,11 @@ func cmdURL(c CommandLine, api libmachine.API) error {
 		return err
 	}
 
-	url, err := host.URL()
+	if err := checkProvisioned(host); err != nil {
+		return err
+	}
+
+	url, err := host.ClientURL()
 	if err != nil {
 		return err
 	}
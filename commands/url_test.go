@@ -55,3 +55,25 @@ func TestCmdURL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "tcp://120.0.0.1:2376\n", stdoutGetter.Output())
 }
+
+func TestCmdURLNotProvisioned(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "machine",
+				Driver: &fakedriver.Driver{
+					MockState: state.Running,
+					MockIP:    "120.0.0.1",
+				},
+				ProvisioningStatus: host.ProvisioningFailed,
+			},
+		},
+	}
+
+	err := cmdURL(commandLine, api)
+
+	assert.Equal(t, errNotProvisioned{HostName: "machine", Status: host.ProvisioningFailed}, err)
+}
@@ -0,0 +1,408 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
+	"github.com/rancher/machine/libmachine/mcnflag"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	validateConfigFormatTable = "table"
+	validateConfigFormatJSON  = "json"
+
+	configFlagBool        = "boolean"
+	configFlagInt         = "integer"
+	configFlagString      = "string"
+	configFlagStringSlice = "list of strings"
+)
+
+var errValidateConfigNoFile = errors.New("Error: Expected a config file as the only argument")
+
+// ConfigProblem is a problem found in a config file. Key is the option or
+// top-level key the problem is about, if any.
+type ConfigProblem struct {
+	Key     string `json:",omitempty"`
+	Problem string
+}
+
+// ConfigValidation is the result of the validation of a config file.
+type ConfigValidation struct {
+	File     string
+	Driver   string `json:",omitempty"`
+	Problems []ConfigProblem
+}
+
+// machineConfig is a config file: the driver and the create and driver flags
+// to use, like a profile. The option values are scalars or lists.
+type machineConfig struct {
+	Driver  string
+	Options map[string]interface{}
+}
+
+func cmdValidateConfig(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		return invalidArguments(errValidateConfigNoFile)
+	}
+
+	format := c.String("format")
+	if format == "" {
+		format = validateConfigFormatTable
+	}
+	if format != validateConfigFormatTable && format != validateConfigFormatJSON {
+		return invalidArguments(fmt.Errorf("unsupported format %q, must be %s or %s", format, validateConfigFormatTable, validateConfigFormatJSON))
+	}
+
+	file := c.Args().First()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", file, err)
+	}
+
+	validation := validateConfig(api, file, data, c.GlobalString("env-prefix"))
+
+	if format == validateConfigFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(validation); err != nil {
+			return err
+		}
+	} else {
+		printConfigProblems(os.Stdout, validation)
+	}
+
+	if len(validation.Problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(validation.Problems), file)
+	}
+
+	return nil
+}
+
+// validateConfig parses a config file, resolves its driver and checks its
+// options against the create flags and the flags of the driver. Nothing is
+// created: the driver is only configured from the options, which reports the
+// required flags missing.
+func validateConfig(api libmachine.API, file string, data []byte, envPrefix string) *ConfigValidation {
+	validation := &ConfigValidation{File: file, Problems: []ConfigProblem{}}
+
+	config, problems := parseMachineConfig(data)
+	validation.Problems = append(validation.Problems, problems...)
+	if config == nil {
+		return validation
+	}
+	validation.Driver = config.Driver
+
+	if config.Driver == "" {
+		validation.Problems = append(validation.Problems, ConfigProblem{Key: "driver", Problem: "no driver is set"})
+		return validation
+	}
+
+	d, problem := loadConfigDriver(api, config.Driver, "validate-config")
+	if problem != nil {
+		validation.Problems = append(validation.Problems, *problem)
+		return validation
+	}
+
+	validation.Problems = append(validation.Problems, validateDriverConfig(d, config, envPrefix)...)
+	return validation
+}
+
+// loadConfigDriver loads the driver of a config file for a placeholder
+// machine, to read its flags.
+func loadConfigDriver(api libmachine.API, driverName, machineName string) (drivers.Driver, *ConfigProblem) {
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{MachineName: machineName})
+	if err != nil {
+		return nil, &ConfigProblem{Problem: fmt.Sprintf("error marshalling base driver: %s", err)}
+	}
+
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		return nil, &ConfigProblem{Key: "driver", Problem: fmt.Sprintf("driver %q can't be loaded: %s", driverName, err)}
+	}
+
+	return h.Driver, nil
+}
+
+// parseMachineConfig parses a YAML or JSON config file. The keys are matched
+// case-insensitively so that profiles are valid config files.
+func parseMachineConfig(data []byte) (*machineConfig, []ConfigProblem) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, []ConfigProblem{{Problem: fmt.Sprintf("error parsing the file: %s", err)}}
+	}
+
+	config := &machineConfig{Options: map[string]interface{}{}}
+	problems := []ConfigProblem{}
+
+	for _, key := range sortedConfigKeys(raw) {
+		value := raw[key]
+		switch strings.ToLower(key) {
+		case "driver":
+			driver, ok := value.(string)
+			if !ok {
+				problems = append(problems, ConfigProblem{Key: key, Problem: "expected a driver name"})
+				continue
+			}
+			config.Driver = driver
+		case "options":
+			if value == nil {
+				continue
+			}
+			options, ok := value.(map[interface{}]interface{})
+			if !ok {
+				problems = append(problems, ConfigProblem{Key: key, Problem: "expected a map of flag names to values"})
+				continue
+			}
+			for name, option := range options {
+				config.Options[fmt.Sprint(name)] = option
+			}
+		default:
+			problems = append(problems, ConfigProblem{Key: key, Problem: "unknown key, expected driver or options"})
+		}
+	}
+
+	// Like on the command line, the driver may be given as a create flag.
+	for _, name := range []string{"driver", "d"} {
+		option, ok := config.Options[name]
+		if !ok {
+			continue
+		}
+		driver, err := configFlagValue(configFlagString, option)
+		if err != nil {
+			continue
+		}
+		if config.Driver == "" {
+			config.Driver = driver.(string)
+		} else if config.Driver != driver {
+			problems = append(problems, ConfigProblem{Key: name, Problem: fmt.Sprintf("driver %q conflicts with driver %q", driver, config.Driver)})
+		}
+	}
+
+	return config, problems
+}
+
+// validateDriverConfig checks the options of a config file against the
+// create flags and the flags of its driver, then configures the driver with
+// them.
+func validateDriverConfig(d drivers.Driver, config *machineConfig, envPrefix string) []ConfigProblem {
+	driverOpts, problems := resolveConfigOptions(d, config, envPrefix)
+
+	if err := d.SetConfigFromFlags(driverOpts); err != nil {
+		problems = append(problems, ConfigProblem{Problem: fmt.Sprintf("the %s driver rejects the config: %s", config.Driver, err)})
+	}
+
+	return problems
+}
+
+// resolveConfigOptions returns the flag values create would send to the
+// driver for the options of a config file: the defaults of the create and
+// driver flags, overridden by the options, the driver flags not set being read
+// from their environment variables like with create.
+func resolveConfigOptions(d drivers.Driver, config *machineConfig, envPrefix string) (*rpcdriver.RPCFlags, []ConfigProblem) {
+	// Like with create, the driver is sent the create flags too.
+	flagKinds := map[string]string{}
+	driverOpts := &rpcdriver.RPCFlags{Values: map[string]interface{}{}}
+	for _, f := range SharedCreateFlags {
+		names, kind, value := cliFlagKind(f)
+		for _, name := range strings.Split(names, ",") {
+			flagKinds[strings.TrimSpace(name)] = kind
+		}
+		driverOpts.Values[strings.TrimSpace(strings.Split(names, ",")[0])] = value
+	}
+
+	driverFlags := d.GetCreateFlags()
+	for _, f := range driverFlags {
+		flagKinds[f.String()] = mcnFlagKind(f)
+		driverOpts.Values[f.String()] = f.Default()
+	}
+
+	problems := []ConfigProblem{}
+	for _, name := range sortedConfigKeys(config.Options) {
+		kind, ok := flagKinds[name]
+		if !ok {
+			problems = append(problems, ConfigProblem{Key: name, Problem: fmt.Sprintf("unknown flag, neither a create flag nor a flag of the %s driver", config.Driver)})
+			continue
+		}
+
+		value, err := configFlagValue(kind, config.Options[name])
+		if err != nil {
+			problems = append(problems, ConfigProblem{Key: name, Problem: err.Error()})
+			continue
+		}
+		if alias, ok := profileFlagAliases[name]; ok {
+			name = alias
+		}
+		driverOpts.Values[name] = value
+	}
+
+	for _, f := range driverFlags {
+		if _, ok := config.Options[f.String()]; ok {
+			continue
+		}
+		for _, envVar := range driverFlagEnvVars(envPrefix, mcnFlagEnvVar(f)) {
+			env, ok := os.LookupEnv(envVar)
+			if !ok {
+				continue
+			}
+			value, err := configFlagValue(mcnFlagKind(f), env)
+			if err != nil {
+				problems = append(problems, ConfigProblem{Key: f.String(), Problem: fmt.Sprintf("invalid value of %s: %s", envVar, err)})
+			} else {
+				driverOpts.Values[f.String()] = value
+			}
+			break
+		}
+	}
+
+	return driverOpts, problems
+}
+
+// configFlagValue converts an option value to the type of a flag of the given
+// kind. A boolean flag given without a value, as saved in the profiles, is
+// set. Strings are converted like on the command line.
+func configFlagValue(kind string, value interface{}) (interface{}, error) {
+	values := []interface{}{value}
+	isList := false
+	switch v := value.(type) {
+	case []interface{}:
+		values, isList = v, true
+	case []string:
+		values, isList = []interface{}{}, true
+		for _, s := range v {
+			values = append(values, s)
+		}
+	}
+
+	for _, v := range values {
+		switch v.(type) {
+		case string, bool, int, int64, uint64, float64:
+		default:
+			return nil, fmt.Errorf("expected a %s, got %T", kind, v)
+		}
+	}
+
+	if kind == configFlagStringSlice {
+		strs := []string{}
+		for _, v := range values {
+			if s, ok := v.(string); ok && !isList {
+				strs = append(strs, strings.Split(s, ",")...)
+			} else {
+				strs = append(strs, fmt.Sprint(v))
+			}
+		}
+		return strs, nil
+	}
+
+	if kind == configFlagBool && len(values) == 0 {
+		return true, nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected a single %s, got %d values", kind, len(values))
+	}
+
+	switch v := values[0]; kind {
+	case configFlagBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		if b, err := strconv.ParseBool(fmt.Sprint(v)); err == nil {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected a %s, got %q", kind, fmt.Sprint(v))
+	case configFlagInt:
+		if i, ok := v.(int); ok {
+			return i, nil
+		}
+		if i, err := strconv.Atoi(fmt.Sprint(v)); err == nil {
+			return i, nil
+		}
+		return nil, fmt.Errorf("expected an %s, got %q", kind, fmt.Sprint(v))
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// cliFlagKind returns the names, comma separated, the kind and the default
+// value of a create flag.
+func cliFlagKind(f cli.Flag) (string, string, interface{}) {
+	switch f := f.(type) {
+	case cli.BoolFlag:
+		return f.Name, configFlagBool, false
+	case cli.IntFlag:
+		return f.Name, configFlagInt, f.Value
+	case cli.StringSliceFlag:
+		value := []string{}
+		if f.Value != nil {
+			value = f.Value.Value()
+		}
+		return f.Name, configFlagStringSlice, value
+	case cli.StringFlag:
+		return f.Name, configFlagString, f.Value
+	default:
+		return "", "", nil
+	}
+}
+
+func mcnFlagKind(f mcnflag.Flag) string {
+	switch f.(type) {
+	case *mcnflag.BoolFlag:
+		return configFlagBool
+	case *mcnflag.IntFlag:
+		return configFlagInt
+	case *mcnflag.StringSliceFlag:
+		return configFlagStringSlice
+	default:
+		return configFlagString
+	}
+}
+
+func mcnFlagEnvVar(f mcnflag.Flag) string {
+	switch f := f.(type) {
+	case *mcnflag.BoolFlag:
+		return f.EnvVar
+	case *mcnflag.IntFlag:
+		return f.EnvVar
+	case *mcnflag.StringFlag:
+		return f.EnvVar
+	case *mcnflag.StringSliceFlag:
+		return f.EnvVar
+	default:
+		return ""
+	}
+}
+
+func sortedConfigKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printConfigProblems(out io.Writer, validation *ConfigValidation) {
+	if len(validation.Problems) == 0 {
+		fmt.Fprintf(out, "%s is valid for the %s driver\n", validation.File, validation.Driver)
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "FILE\tKEY\tPROBLEM")
+	for _, problem := range validation.Problems {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", validation.File, problem.Key, problem.Problem)
+	}
+}
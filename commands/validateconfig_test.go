@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/rancher/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConfigDriver struct {
+	fakedriver.Driver
+	region string
+	count  int
+}
+
+func (d *fakeConfigDriver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		&mcnflag.StringFlag{Name: "fake-region", EnvVar: "FAKE_REGION"},
+		&mcnflag.IntFlag{Name: "fake-count", Value: 1},
+		&mcnflag.BoolFlag{Name: "fake-spot"},
+		&mcnflag.StringSliceFlag{Name: "fake-tag"},
+	}
+}
+
+func (d *fakeConfigDriver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.region = flags.String("fake-region")
+	d.count = flags.Int("fake-count")
+	if d.region == "" {
+		return errors.New("fake-region is required")
+	}
+	return nil
+}
+
+func TestCmdValidateConfigRequiresFile(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{}
+
+	err := cmdValidateConfig(commandLine, &libmachinetest.FakeAPI{})
+	assert.Equal(t, invalidArguments(errValidateConfigNoFile), err)
+}
+
+func TestParseMachineConfig(t *testing.T) {
+	config, problems := parseMachineConfig([]byte(`
+driver: fake
+name: dev
+options:
+  fake-region: eu-west-1
+  engine-label: [env=dev, team=ops]
+`))
+
+	assert.Equal(t, "fake", config.Driver)
+	assert.Equal(t, "eu-west-1", config.Options["fake-region"])
+	assert.Equal(t, []interface{}{"env=dev", "team=ops"}, config.Options["engine-label"])
+	assert.Equal(t, []ConfigProblem{{Key: "name", Problem: "unknown key, expected driver or options"}}, problems)
+}
+
+func TestParseMachineConfigProfile(t *testing.T) {
+	config, problems := parseMachineConfig([]byte(`{"Driver": "fake", "Options": {"fake-spot": [], "fake-count": ["2"]}}`))
+
+	assert.Empty(t, problems)
+	assert.Equal(t, "fake", config.Driver)
+	assert.Equal(t, []interface{}{}, config.Options["fake-spot"])
+	assert.Equal(t, []interface{}{"2"}, config.Options["fake-count"])
+}
+
+func TestParseMachineConfigDriverOption(t *testing.T) {
+	config, problems := parseMachineConfig([]byte("options:\n  driver: fake\n"))
+	assert.Empty(t, problems)
+	assert.Equal(t, "fake", config.Driver)
+
+	_, problems = parseMachineConfig([]byte("driver: fake\noptions:\n  d: other\n"))
+	assert.Equal(t, []ConfigProblem{{Key: "d", Problem: `driver "other" conflicts with driver "fake"`}}, problems)
+}
+
+func TestParseMachineConfigInvalid(t *testing.T) {
+	config, problems := parseMachineConfig([]byte("driver: [fake"))
+
+	assert.Nil(t, config)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Problem, "error parsing the file")
+}
+
+func TestValidateDriverConfig(t *testing.T) {
+	d := &fakeConfigDriver{}
+	config := &machineConfig{
+		Driver: "fake",
+		Options: map[string]interface{}{
+			"fake-region":  "eu-west-1",
+			"fake-count":   "3",
+			"fake-spot":    "yes",
+			"fake-tag":     []interface{}{"a", map[interface{}]interface{}{"b": "c"}},
+			"fake-unknown": true,
+			"engine-label": "env=dev",
+			"swarm":        true,
+		},
+	}
+
+	problems := validateDriverConfig(d, config, "")
+
+	assert.Equal(t, []ConfigProblem{
+		{Key: "fake-spot", Problem: `expected a boolean, got "yes"`},
+		{Key: "fake-tag", Problem: "expected a list of strings, got map[interface {}]interface {}"},
+		{Key: "fake-unknown", Problem: "unknown flag, neither a create flag nor a flag of the fake driver"},
+	}, problems)
+	assert.Equal(t, "eu-west-1", d.region)
+	assert.Equal(t, 3, d.count)
+}
+
+func TestValidateDriverConfigMissingRequired(t *testing.T) {
+	problems := validateDriverConfig(&fakeConfigDriver{}, &machineConfig{Driver: "fake", Options: map[string]interface{}{}}, "")
+
+	assert.Equal(t, []ConfigProblem{{Problem: "the fake driver rejects the config: fake-region is required"}}, problems)
+}
+
+func TestValidateDriverConfigEnvVar(t *testing.T) {
+	t.Setenv("CI_FAKE_REGION", "us-east-1")
+	d := &fakeConfigDriver{}
+
+	problems := validateDriverConfig(d, &machineConfig{Driver: "fake", Options: map[string]interface{}{}}, "CI_")
+
+	assert.Empty(t, problems)
+	assert.Equal(t, "us-east-1", d.region)
+}
+
+func TestConfigFlagValue(t *testing.T) {
+	tests := []struct {
+		kind     string
+		value    interface{}
+		expected interface{}
+		err      string
+	}{
+		{kind: configFlagBool, value: true, expected: true},
+		{kind: configFlagBool, value: "false", expected: false},
+		{kind: configFlagBool, value: []interface{}{}, expected: true},
+		{kind: configFlagInt, value: 2, expected: 2},
+		{kind: configFlagInt, value: "2", expected: 2},
+		{kind: configFlagInt, value: 2.5, err: `expected an integer, got "2.5"`},
+		{kind: configFlagString, value: 8, expected: "8"},
+		{kind: configFlagString, value: []interface{}{"a", "b"}, err: "expected a single string, got 2 values"},
+		{kind: configFlagStringSlice, value: "a,b", expected: []string{"a", "b"}},
+		{kind: configFlagStringSlice, value: []interface{}{"a,b", 1}, expected: []string{"a,b", "1"}},
+	}
+
+	for _, test := range tests {
+		value, err := configFlagValue(test.kind, test.value)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, value)
+	}
+}
+
+func TestPrintConfigProblems(t *testing.T) {
+	out := &bytes.Buffer{}
+	printConfigProblems(out, &ConfigValidation{File: "machine.yaml", Driver: "fake", Problems: []ConfigProblem{}})
+	assert.Equal(t, "machine.yaml is valid for the fake driver\n", out.String())
+
+	out.Reset()
+	printConfigProblems(out, &ConfigValidation{File: "machine.yaml", Problems: []ConfigProblem{{Key: "driver", Problem: "no driver is set"}}})
+	assert.Equal(t, "FILE           KEY      PROBLEM\nmachine.yaml   driver   no driver is set\n", out.String())
+}
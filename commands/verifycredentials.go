@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+var errVerifyCredentialsNoDriver = errors.New("Error: a driver is required, use --driver or MACHINE_DRIVER")
+
+func cmdVerifyCredentials(c CommandLine, api libmachine.API) error {
+	driverName := c.String("driver")
+	if driverName == "" {
+		return errVerifyCredentialsNoDriver
+	}
+
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: "verify-credentials",
+		StorePath:   c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return fmt.Errorf("error attempting to marshal bare driver data: %s", err)
+	}
+
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		return fmt.Errorf("error getting new host: %s", err)
+	}
+
+	driverOpts := getDriverOpts(c, h.Driver.GetCreateFlags())
+	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
+		return fmt.Errorf("error setting driver configuration from flags provided: %s", err)
+	}
+
+	return verifyCredentials(os.Stdout, driverName, h.Driver)
+}
+
+// verifyCredentials checks the credentials of a configured driver and prints
+// the identity they map to.
+func verifyCredentials(out io.Writer, driverName string, d drivers.Driver) error {
+	identity, err := drivers.CheckCredentials(d)
+	if errors.Is(err, mcnerror.ErrNotSupported) {
+		return fmt.Errorf("Error: the %s driver doesn't support checking its credentials", driverName)
+	}
+	if err != nil {
+		return fmt.Errorf("Error: the credentials of the %s driver don't work: %s", driverName, err)
+	}
+
+	if identity == "" {
+		fmt.Fprintf(out, "The credentials of the %s driver are valid\n", driverName)
+		return nil
+	}
+
+	fmt.Fprintf(out, "The credentials of the %s driver are valid, identity: %s\n", driverName, identity)
+	return nil
+}
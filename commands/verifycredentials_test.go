@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCredentialsDriver struct {
+	fakedriver.Driver
+	identity string
+	err      error
+}
+
+func (d *fakeCredentialsDriver) CheckCredentials() (string, error) {
+	return d.identity, d.err
+}
+
+func TestCmdVerifyCredentialsRequiresDriver(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+
+	err := cmdVerifyCredentials(commandLine, &libmachinetest.FakeAPI{})
+	assert.Equal(t, errVerifyCredentialsNoDriver, err)
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := verifyCredentials(out, "amazonec2", &fakeCredentialsDriver{identity: "arn:aws:iam::123456789012:user/ci"})
+	assert.NoError(t, err)
+	assert.Equal(t, "The credentials of the amazonec2 driver are valid, identity: arn:aws:iam::123456789012:user/ci\n", out.String())
+
+	err = verifyCredentials(out, "amazonec2", &fakeCredentialsDriver{err: errors.New("AuthFailure")})
+	assert.EqualError(t, err, "Error: the credentials of the amazonec2 driver don't work: AuthFailure")
+
+	err = verifyCredentials(out, "fakedriver", &fakedriver.Driver{})
+	assert.EqualError(t, err, "Error: the fakedriver driver doesn't support checking its credentials")
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/rancher/machine/libmachine"
+	"github.com/rancher/machine/libmachine/host"
 	"github.com/rancher/machine/libmachine/mcndockerclient"
 )
 
@@ -29,7 +30,7 @@ func printVersion(c CommandLine, api libmachine.API, out io.Writer) error {
 	}
 
 	if host != nil && host.HostOptions != nil && host.HostOptions.AuthOptions != nil {
-		version, err := mcndockerclient.DockerVersion(host)
+		version, err := dockerVersion(host)
 		if err != nil {
 			return err
 		}
@@ -40,3 +41,12 @@ func printVersion(c CommandLine, api libmachine.API, out io.Writer) error {
 
 	return nil
 }
+
+// dockerVersion returns the version of the daemon of a machine, asked over
+// SSH when the daemon is only reached that way.
+func dockerVersion(h *host.Host) (string, error) {
+	if h.DaemonSSHTransport() {
+		return h.DockerVersion()
+	}
+	return mcndockerclient.DockerVersion(h)
+}
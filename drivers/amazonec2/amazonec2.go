@@ -3,13 +3,14 @@ package amazonec2
 import (
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	mrand "math/rand"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -21,10 +22,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/rancher/machine/drivers/driverutil"
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/mcnutils"
 	"github.com/rancher/machine/libmachine/ssh"
@@ -34,6 +38,7 @@ import (
 
 const (
 	driverName                  = "amazonec2"
+	maxUserDataSize             = 16 << 10 // before the base64 encoding
 	ipRange                     = "0.0.0.0/0"
 	ipv6Range                   = "::/0"
 	machineSecurityGroupName    = "rancher-nodes"
@@ -48,15 +53,17 @@ const (
 	defaultSSHUser              = "ubuntu"
 	defaultSpotPrice            = "0.50"
 	defaultBlockDurationMinutes = 0
-	charset                     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	defaultPlacementStrategy    = ec2.PlacementStrategyCluster
 	ec2VolumeResource           = "volume"
 	ec2NetworkInterfaceResource = "network-interface"
 	ec2InstanceResource         = "instance"
+	ec2KeyPairResource          = "key-pair"
 	description                 = "managed by rancher-machine"
 )
 
 const (
 	keypairNotFoundCode             = "InvalidKeyPair.NotFound"
+	keyPairDuplicateCode            = "InvalidKeyPair.Duplicate"
 	spotInstanceRequestNotFoundCode = "InvalidSpotInstanceRequestID.NotFound"
 )
 
@@ -89,12 +96,15 @@ var (
 	errorInvalidValueForHTTPEndpoint           = errors.New("httpEndpoint must be either enabled or disabled")
 	errorInvalidValueForHTTPProtocolIpv6       = errors.New("httpProtocolIpv6 must be either enabled or disabled")
 	errorInvalidValueForIpv6AddressCount       = errors.New("ipv6AddressCount must be greater than zero when Ipv6AddressOnly is true")
+	errorInvalidPlacementStrategy              = errors.New("placement group strategy must be one of cluster, spread or partition")
 )
 
 type Driver struct {
 	*drivers.BaseDriver
 	clientFactory         func() Ec2Client
 	awsCredentialsFactory func() awsCredentials
+	stsClientFactory      func() stsClient
+	kmsClientFactory      func() kmsClient
 	Id                    string
 	AccessKey             string
 	SecretKey             string
@@ -118,6 +128,15 @@ type Driver struct {
 	SecurityGroupName  string
 	SecurityGroupNames []string
 
+	// ExistingSecurityGroupNames are security groups of the VPC attached by
+	// name, they are never created.
+	ExistingSecurityGroupNames []string
+
+	// PlacementGroup is the placement group of the instance. It is created
+	// with PlacementGroupStrategy when it doesn't exist.
+	PlacementGroup         string
+	PlacementGroupStrategy string
+
 	SecurityGroupReadOnly   bool
 	OpenPorts               []string
 	Tags                    string
@@ -143,8 +162,8 @@ type Driver struct {
 	DisableSSL              bool
 	UserDataFile            string
 	EncryptEbsVolume        bool
+	KmsKeyId                string
 	spotInstanceRequestId   string
-	kmsKeyId                *string
 	bdmList                 []*ec2.BlockDeviceMapping
 	// Metadata Options
 	HttpEndpoint string
@@ -168,6 +187,24 @@ type Driver struct {
 	// Indicates whether the instance has only IPv6 address.
 	// Useful when the VPC or subnet is configured as IPv6-only.
 	Ipv6AddressOnly bool
+
+	// ClientToken is the idempotency token of the RunInstances calls. It is
+	// generated before the machine is first saved, so that a retried create,
+	// even by another process, references the instance already launched.
+	ClientToken string
+
+	// AZPreference are the availability zones the subnet is picked from
+	// when no subnet is given, in order of preference.
+	AZPreference []string
+
+	// AZFallback retries the launch in the other zones of the VPC with a
+	// subnet when the chosen zone is out of capacity.
+	AZFallback bool
+
+	// AssignPublicIP is true or false to assign a public IPv4 address or
+	// not, subnet to follow the setting of the subnet, and empty to assign
+	// one unless PrivateIPOnly.
+	AssignPublicIP string
 }
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -209,6 +246,21 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultZone,
 			EnvVar: "AWS_ZONE",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "amazonec2-az-preference",
+			Usage:  "AWS zones to pick the subnet from when no subnet id is given, in order of preference (i.e. b or us-east-1b), instead of --amazonec2-zone",
+			EnvVar: "AWS_AZ_PREFERENCE",
+		},
+		mcnflag.BoolFlag{
+			Name:   "amazonec2-az-fallback",
+			Usage:  "Retry in the other zones of the VPC with a subnet when the zone is out of capacity or the spot request fails",
+			EnvVar: "AWS_AZ_FALLBACK",
+		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-assign-public-ip",
+			Usage:  "Assign a public IPv4 address: true, false or subnet to follow the setting of the subnet, default to true unless --amazonec2-private-address-only",
+			EnvVar: "AWS_ASSIGN_PUBLIC_IP",
+		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-subnet-id",
 			Usage:  "AWS VPC subnet id",
@@ -216,7 +268,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.BoolFlag{
 			Name:   "amazonec2-security-group-readonly",
-			Usage:  "Skip adding default rules to security groups",
+			Usage:  "Skip adding default rules to security groups, the groups must already allow SSH and Docker",
 			EnvVar: "AWS_SECURITY_GROUP_READONLY",
 		},
 		mcnflag.StringSliceFlag{
@@ -225,10 +277,26 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  []string{defaultSecurityGroup},
 			EnvVar: "AWS_SECURITY_GROUP",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "amazonec2-security-group-name",
+			Usage:  "Name of an existing AWS VPC security group to attach, the default security group is not created when only these are given",
+			EnvVar: "AWS_SECURITY_GROUP_NAME",
+		},
 		mcnflag.StringSliceFlag{
 			Name:  "amazonec2-open-port",
 			Usage: "Make the specified port number accessible from the Internet",
 		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-placement-group",
+			Usage:  "AWS placement group of the instance, created if it doesn't exist",
+			EnvVar: "AWS_PLACEMENT_GROUP",
+		},
+		mcnflag.StringFlag{
+			Name:   "amazonec2-placement-group-strategy",
+			Usage:  "Strategy of a created placement group: cluster, spread or partition",
+			Value:  defaultPlacementStrategy,
+			EnvVar: "AWS_PLACEMENT_GROUP_STRATEGY",
+		},
 		mcnflag.StringFlag{
 			Name:   "amazonec2-tags",
 			Usage:  "AWS Tags (e.g. key1,value1,key2,value2)",
@@ -288,7 +356,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.BoolFlag{
 			Name:  "amazonec2-use-private-address",
-			Usage: "Force the usage of private IP address",
+			Usage: "Force the usage of private IP address, for SSH as well as the Docker daemon, see --daemon-use-private-ip to only reach the daemon at it",
 		},
 		mcnflag.BoolFlag{
 			Name:  "amazonec2-monitoring",
@@ -321,7 +389,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		},
 		mcnflag.BoolFlag{
 			Name:   "amazonec2-insecure-transport",
-			Usage:  "Disable SSL when sending requests",
+			Usage:  "Disable SSL when sending requests to a custom endpoint, or skip the verification of its certs for an https URL",
 			EnvVar: "AWS_INSECURE_TRANSPORT",
 		},
 		mcnflag.StringFlag{
@@ -401,6 +469,8 @@ func NewDriver(hostName, storePath string) *Driver {
 
 	driver.clientFactory = driver.buildClient
 	driver.awsCredentialsFactory = driver.buildCredentials
+	driver.stsClientFactory = driver.buildSTSClient
+	driver.kmsClientFactory = driver.buildKMSClient
 
 	return driver
 }
@@ -416,12 +486,66 @@ func (d *Driver) buildClient() Ec2Client {
 	if d.Endpoint != "" {
 		config = config.WithEndpoint(d.Endpoint)
 		config = config.WithDisableSSL(d.DisableSSL)
+		if d.DisableSSL {
+			config = config.WithHTTPClient(insecureHTTPClient())
+		}
+	} else {
+		// use AWS dual stack endpoint to support both IPv6 and IPv4
+		config.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
 	}
-	// use AWS dual stack endpoint to support both IPv6 and IPv4
-	config.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
 	return ec2.New(session.New(config))
 }
 
+func (d *Driver) buildSTSClient() stsClient {
+	config := aws.NewConfig()
+	config = config.WithRegion(d.Region)
+	config = config.WithCredentials(d.awsCredentialsFactory().Credentials())
+	config = config.WithLogger(AwsLogger())
+	config = config.WithLogLevel(aws.LogDebugWithHTTPBody)
+	config = config.WithMaxRetries(d.RetryCount)
+	return sts.New(session.New(config))
+}
+
+func (d *Driver) buildKMSClient() kmsClient {
+	config := aws.NewConfig()
+	config = config.WithRegion(d.Region)
+	config = config.WithCredentials(d.awsCredentialsFactory().Credentials())
+	config = config.WithLogger(AwsLogger())
+	config = config.WithLogLevel(aws.LogDebugWithHTTPBody)
+	config = config.WithMaxRetries(d.RetryCount)
+	return kms.New(session.New(config))
+}
+
+// insecureHTTPClient returns an HTTP client skipping the verification of the
+// certs of the endpoint, for test endpoints with self-signed certs.
+func insecureHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Transport: transport}
+}
+
+// validateEndpoint checks that a custom endpoint is either a host[:port] or
+// an http or https URL.
+func validateEndpoint(endpoint string) error {
+	raw := endpoint
+	if !strings.Contains(endpoint, "://") {
+		raw = "https://" + endpoint
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q (--amazonec2-endpoint): %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid endpoint %q (--amazonec2-endpoint), the scheme must be http or https", endpoint)
+	}
+	if u.Hostname() == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("invalid endpoint %q (--amazonec2-endpoint), must be a host[:port] or an http(s)://host[:port][/path] URL", endpoint)
+	}
+
+	return nil
+}
+
 func (d *Driver) buildCredentials() awsCredentials {
 	return NewAWSCredentials(d.AccessKey, d.SecretKey, d.SessionToken)
 }
@@ -465,7 +589,16 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Endpoint = flags.String("amazonec2-endpoint")
 
 	region, err := validateAwsRegion(flags.String("amazonec2-region"))
-	if err != nil && d.Endpoint == "" {
+	if d.Endpoint != "" {
+		if err := validateEndpoint(d.Endpoint); err != nil {
+			return err
+		}
+		// A custom endpoint may serve regions unknown to the driver, the
+		// region is still needed to sign the requests.
+		if region = flags.String("amazonec2-region"); region == "" {
+			region = defaultRegion
+		}
+	} else if err != nil {
 		return err
 	}
 
@@ -486,7 +619,16 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.VpcId = flags.String("amazonec2-vpc-id")
 	d.SubnetId = flags.String("amazonec2-subnet-id")
 	d.SecurityGroupNames = flags.StringSlice("amazonec2-security-group")
+	d.ExistingSecurityGroupNames = flags.StringSlice("amazonec2-security-group-name")
+	if len(d.ExistingSecurityGroupNames) > 0 && len(d.SecurityGroupNames) == 1 && d.SecurityGroupNames[0] == defaultSecurityGroup {
+		d.SecurityGroupNames = nil
+	}
 	d.SecurityGroupReadOnly = flags.Bool("amazonec2-security-group-readonly")
+	d.PlacementGroup = flags.String("amazonec2-placement-group")
+	d.PlacementGroupStrategy = flags.String("amazonec2-placement-group-strategy")
+	if d.PlacementGroup != "" && !validPlacementStrategy(d.PlacementGroupStrategy) {
+		return errorInvalidPlacementStrategy
+	}
 	d.Tags = flags.String("amazonec2-tags")
 	zone := flags.String("amazonec2-zone")
 	d.Zone = zone[:]
@@ -509,7 +651,15 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SetSwarmConfigFromFlags(flags)
 	d.RetryCount = flags.Int("amazonec2-retries")
 	d.OpenPorts = flags.StringSlice("amazonec2-open-port")
+	d.AZPreference = flags.StringSlice("amazonec2-az-preference")
+	d.AZFallback = flags.Bool("amazonec2-az-fallback")
+	d.AssignPublicIP = flags.String("amazonec2-assign-public-ip")
 	d.UserDataFile = flags.String("amazonec2-userdata")
+	if d.UserDataFile != "" {
+		if err := driverutil.CheckUserDataSize(d.UserDataFile, maxUserDataSize, false); err != nil {
+			return err
+		}
+	}
 	d.EncryptEbsVolume = flags.Bool("amazonec2-encrypt-ebs-volume")
 
 	httpEndpoint := flags.String("amazonec2-http-endpoint")
@@ -540,10 +690,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errorInvalidValueForIpv6AddressCount
 	}
 
-	kmskeyid := flags.String("amazonec2-kms-key")
-	if kmskeyid != "" {
-		d.kmsKeyId = aws.String(kmskeyid)
-	}
+	d.KmsKeyId = flags.String("amazonec2-kms-key")
 
 	d.DisableSSL = flags.Bool("amazonec2-insecure-transport")
 
@@ -555,12 +702,17 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errorNoPrivateSSHKey
 	}
 
+	if err := d.validateAssignPublicIP(); err != nil {
+		return err
+	}
+
 	_, err = d.awsCredentialsFactory().Credentials().Get()
 	if err != nil {
 		return errorMissingCredentials
 	}
 
-	if d.VpcId == "" {
+	// The VPC of a given subnet is used rather than the default one.
+	if d.VpcId == "" && d.SubnetId == "" {
 		d.VpcId, err = d.getDefaultVPCId()
 		if err != nil {
 			log.Warnf("Couldn't determine your account Default VPC ID : %q", err)
@@ -571,7 +723,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errorNoVPCIdFound
 	}
 
-	if d.SubnetId != "" && d.VpcId != "" {
+	if d.SubnetId != "" {
 		subnetFilter := []*ec2.Filter{
 			{
 				Name:   aws.String("subnet-id"),
@@ -590,9 +742,16 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 			return errorNoSubnetsFound
 		}
 
-		if *subnets.Subnets[0].VpcId != d.VpcId {
+		subnet := subnets.Subnets[0]
+		if d.VpcId != "" && *subnet.VpcId != d.VpcId {
 			return fmt.Errorf("SubnetId: %s does not belong to VpcId: %s", d.SubnetId, d.VpcId)
 		}
+		d.VpcId = *subnet.VpcId
+
+		// The instance must be placed in the zone of its subnet.
+		if subnet.AvailabilityZone != nil {
+			d.setZone(*subnet.AvailabilityZone)
+		}
 	}
 
 	if d.isSwarmMaster() {
@@ -619,6 +778,10 @@ func (d *Driver) DriverName() string {
 }
 
 func (d *Driver) checkSubnet() error {
+	if d.SubnetId == "" && len(d.AZPreference) > 0 {
+		return d.checkPreferredSubnet()
+	}
+
 	regionZone := d.getRegionZone()
 	if d.SubnetId == "" {
 		filters := []*ec2.Filter{
@@ -691,9 +854,37 @@ func (d *Driver) PreCreateCheck() error {
 		return err
 	}
 
+	if err := d.checkEncryption(); err != nil {
+		return err
+	}
+
+	if d.ClientToken == "" {
+		d.ClientToken = newClientToken()
+	}
+
 	return nil
 }
 
+// clientToken returns the idempotency token of the RunInstances calls, nil
+// letting the SDK generate one when the machine was created before tokens were
+// stored.
+func (d *Driver) clientToken() *string {
+	if d.ClientToken == "" {
+		return nil
+	}
+	return aws.String(d.ClientToken)
+}
+
+// newClientToken returns a random idempotency token for the RunInstances
+// calls, these are limited to 64 ASCII characters.
+func newClientToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%x", b)
+}
+
 func (d *Driver) instanceIpAvailable() bool {
 	switch {
 	case d.Ipv6AddressOnly:
@@ -785,6 +976,8 @@ func (d *Driver) Create() error {
 		if removalErr := d.Remove(); removalErr != nil {
 			return removalErr
 		}
+		// The instance was terminated, a new create must launch another one.
+		d.ClientToken = ""
 		return err
 	}
 
@@ -802,6 +995,20 @@ func (d *Driver) innerCreate() error {
 		return err
 	}
 
+	if err := d.attachExistingSecurityGroups(d.ExistingSecurityGroupNames); err != nil {
+		return err
+	}
+
+	if err := d.ensurePlacementGroup(); err != nil {
+		return err
+	}
+
+	return d.launchInstanceWithFallback()
+}
+
+// launchInstance runs the instance of the machine, once its key pair, security
+// groups and placement group exist, and waits for it to be running.
+func (d *Driver) launchInstance() error {
 	var userdata string
 	if b64, err := d.Base64UserData(); err != nil {
 		return err
@@ -811,17 +1018,11 @@ func (d *Driver) innerCreate() error {
 
 	bdmList := d.updateBDMList()
 
-	associatePublicIpAddress := !d.PrivateIPOnly
-	if d.Ipv6AddressOnly {
-		// We cannot assign public IPv4 address in IPv6-only subnet
-		associatePublicIpAddress = false
-	}
-
 	netSpecs := []*ec2.InstanceNetworkInterfaceSpecification{{
 		DeviceIndex:              aws.Int64(0), // eth0
 		Groups:                   makePointerSlice(d.securityGroupIds()),
 		SubnetId:                 &d.SubnetId,
-		AssociatePublicIpAddress: aws.Bool(associatePublicIpAddress),
+		AssociatePublicIpAddress: d.associatePublicIPAddress(),
 		PrimaryIpv6:              aws.Bool(d.EnablePrimaryIpv6),
 		Ipv6AddressCount:         aws.Int64(d.Ipv6AddressCount),
 	}}
@@ -832,12 +1033,10 @@ func (d *Driver) innerCreate() error {
 	var instance *ec2.Instance
 	if d.RequestSpotInstance {
 		req := ec2.RunInstancesInput{
-			ImageId:  &d.AMI,
-			MinCount: aws.Int64(1),
-			MaxCount: aws.Int64(1),
-			Placement: &ec2.Placement{
-				AvailabilityZone: &regionZone,
-			},
+			ImageId:           &d.AMI,
+			MinCount:          aws.Int64(1),
+			MaxCount:          aws.Int64(1),
+			Placement:         d.placement(regionZone),
 			KeyName:           &d.KeyName,
 			InstanceType:      &d.InstanceType,
 			NetworkInterfaces: netSpecs,
@@ -849,6 +1048,7 @@ func (d *Driver) innerCreate() error {
 			BlockDeviceMappings: bdmList,
 			UserData:            &userdata,
 			MetadataOptions:     &ec2.InstanceMetadataOptionsRequest{},
+			ClientToken:         d.clientToken(),
 			InstanceMarketOptions: &ec2.InstanceMarketOptionsRequest{
 				MarketType: aws.String(ec2.MarketTypeSpot),
 				SpotOptions: &ec2.SpotMarketOptions{
@@ -875,7 +1075,7 @@ func (d *Driver) innerCreate() error {
 		}
 		res, err := d.getClient().RunInstances(&req)
 		if err != nil {
-			return fmt.Errorf("error request spot instance: %s", err)
+			return fmt.Errorf("error request spot instance: %w", classifyError(err))
 		}
 		d.spotInstanceRequestId = *res.Instances[0].SpotInstanceRequestId
 
@@ -892,7 +1092,7 @@ func (d *Driver) innerCreate() error {
 						continue
 					}
 				}
-				return fmt.Errorf("error fulfilling spot request: %v", err)
+				return fmt.Errorf("error fulfilling spot request: %w", d.spotRequestError(err))
 			}
 			break
 		}
@@ -938,12 +1138,10 @@ func (d *Driver) innerCreate() error {
 			ec2NetworkInterfaceResource,
 		})
 		req := ec2.RunInstancesInput{
-			ImageId:  &d.AMI,
-			MinCount: aws.Int64(1),
-			MaxCount: aws.Int64(1),
-			Placement: &ec2.Placement{
-				AvailabilityZone: &regionZone,
-			},
+			ImageId:           &d.AMI,
+			MinCount:          aws.Int64(1),
+			MaxCount:          aws.Int64(1),
+			Placement:         d.placement(regionZone),
 			KeyName:           &d.KeyName,
 			InstanceType:      &d.InstanceType,
 			NetworkInterfaces: netSpecs,
@@ -955,6 +1153,7 @@ func (d *Driver) innerCreate() error {
 			BlockDeviceMappings: bdmList,
 			UserData:            &userdata,
 			MetadataOptions:     &ec2.InstanceMetadataOptionsRequest{},
+			ClientToken:         d.clientToken(),
 			TagSpecifications:   resourceTags,
 		}
 
@@ -973,7 +1172,7 @@ func (d *Driver) innerCreate() error {
 		res, err := d.getClient().RunInstances(&req)
 
 		if err != nil {
-			return fmt.Errorf("error launching instance: %s", err)
+			return fmt.Errorf("error launching instance: %w", classifyError(err))
 		}
 		instance = res.Instances[0]
 	}
@@ -1088,6 +1287,11 @@ func (d *Driver) GetIP() (string, error) {
 		return "", err
 	}
 
+	return d.instanceIP(inst)
+}
+
+// instanceIP returns the IPv4 address the machine is reached at.
+func (d *Driver) instanceIP(inst *ec2.Instance) (string, error) {
 	if d.PrivateIPOnly {
 		if inst.PrivateIpAddress == nil {
 			return "", fmt.Errorf("no private IPv4 address for instance %v", *inst.InstanceId)
@@ -1126,6 +1330,9 @@ func (d *Driver) GetIPv6() (string, error) {
 func (d *Driver) GetState() (state.State, error) {
 	inst, err := d.getInstance()
 	if err != nil {
+		if errors.Is(err, mcnerror.ErrInstanceNotFound) {
+			return state.NotFound, err
+		}
 		return state.Error, err
 	}
 	switch *inst.State.Name {
@@ -1140,7 +1347,7 @@ func (d *Driver) GetState() (state.State, error) {
 	case ec2.InstanceStateNameStopped:
 		return state.Stopped, nil
 	case ec2.InstanceStateNameTerminated:
-		return state.Error, fmt.Errorf("valid machine %v not found", d.MachineName)
+		return state.NotFound, mcnerror.NotFound(fmt.Errorf("valid machine %v not found", d.MachineName))
 	default:
 		log.Warnf("Unrecognized instance state: %v", *inst.State.Name)
 		return state.Error, nil
@@ -1183,7 +1390,7 @@ func (d *Driver) Start() error {
 		InstanceIds: []*string{&d.InstanceId},
 	})
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	return d.waitForInstance()
@@ -1204,6 +1411,12 @@ func (d *Driver) Restart() error {
 	return err
 }
 
+// Reboot reboots the instance with RebootInstances, Restart doesn't stop it
+// either.
+func (d *Driver) Reboot() error {
+	return classifyError(d.Restart())
+}
+
 func (d *Driver) Kill() error {
 	_, err := d.getClient().StopInstances(&ec2.StopInstancesInput{
 		InstanceIds: []*string{&d.InstanceId},
@@ -1229,6 +1442,10 @@ func (d *Driver) Remove() error {
 		}
 	}
 
+	if err := d.removePlacementGroup(); err != nil {
+		multierr.Errs = append(multierr.Errs, err)
+	}
+
 	if !d.ExistingKey {
 		if err := d.deleteKeyPair(); err != nil && !strings.Contains(err.Error(), "not found") {
 			multierr.Errs = append(multierr.Errs, err)
@@ -1256,14 +1473,35 @@ func (d *Driver) getInstance() (*ec2.Instance, error) {
 		InstanceIds: []*string{&d.InstanceId},
 	})
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	if len(instances.Reservations) == 0 || len(instances.Reservations[0].Instances) == 0 {
-		return nil, fmt.Errorf("instance %v not found", d.InstanceId)
+		return nil, mcnerror.NotFound(fmt.Errorf("instance %v not found", d.InstanceId))
 	}
 	return instances.Reservations[0].Instances[0], nil
 }
 
+// classifyError wraps the EC2 errors with the matching libmachine error kind.
+func classifyError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+
+	switch awsErr.Code() {
+	case "InvalidInstanceID.NotFound":
+		return mcnerror.NotFound(err)
+	case "RequestLimitExceeded", "Throttling", "ServiceUnavailable", "Unavailable", "InternalError":
+		return mcnerror.MarkTransient(err)
+	case "InsufficientInstanceCapacity", "InsufficientCapacity", "InsufficientHostCapacity", "InsufficientReservedInstanceCapacity":
+		return mcnerror.Capacity(err)
+	case "AuthFailure", "UnauthorizedOperation":
+		return mcnerror.Unauthorized(err)
+	}
+
+	return err
+}
+
 func (d *Driver) instanceIsRunning() bool {
 	st, err := d.GetState()
 	if err != nil {
@@ -1312,18 +1550,20 @@ func (d *Driver) createKeyPair() error {
 		return err
 	}
 
-	r := mrand.New(mrand.NewSource(time.Now().UnixNano()))
-	b := make([]byte, 5)
-	for i := range b {
-		b[i] = charset[r.Intn(len(charset))]
-	}
-	keyName := d.MachineName + "-" + string(b)
+	keyName := keyPairName(d.MachineName, publicKey)
 
 	log.Debugf("Creating key pair: %s", keyName)
 	_, err = d.getClient().ImportKeyPair(&ec2.ImportKeyPairInput{
 		KeyName:           &keyName,
 		PublicKeyMaterial: publicKey,
+		TagSpecifications: d.keyPairTagSpecifications(),
 	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == keyPairDuplicateCode {
+		// The name is derived from the key, which a previous attempt to
+		// create the machine already imported.
+		log.Debugf("Key pair %s was already imported", keyName)
+		err = nil
+	}
 	if err != nil {
 		return err
 	}
@@ -1520,42 +1760,100 @@ func (d *Driver) configureSecurityGroups(groupNames []string) error {
 		}
 		d.SecurityGroupIds = append(d.SecurityGroupIds, *group.GroupId)
 
-		ingressPerms, err := d.ingressPermissions(group)
-		if err != nil {
+		if err := d.authorizeSecurityGroup(groupName, group); err != nil {
 			return err
 		}
+	}
 
-		if len(ingressPerms) > 0 {
-			log.Debugf("Adding the following ingress rules to the security group %s: %v", groupName, ingressPerms)
-			_, err := d.getClient().AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
-				GroupId:       group.GroupId,
-				IpPermissions: ingressPerms,
-			})
-			if err != nil {
-				if strings.Contains(err.Error(), "already exists") {
-					log.Debugf("Skip updating the security group due to: %s", err.Error())
-				} else {
-					return err
-				}
-			}
+	return nil
+}
+
+// attachExistingSecurityGroups resolves the names of existing security groups
+// of the VPC to their ids. Unless the groups are read-only, the SSH and Docker
+// ports are opened like in the groups of configureSecurityGroups.
+func (d *Driver) attachExistingSecurityGroups(groupNames []string) error {
+	if len(groupNames) == 0 {
+		return nil
+	}
+
+	log.Debugf("Looking up existing security groups %v in %s", groupNames, d.VpcId)
+
+	groups, err := d.getClient().DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("group-name"),
+				Values: makePointerSlice(groupNames),
+			},
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{&d.VpcId},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	groupsByName := make(map[string]*ec2.SecurityGroup)
+	for _, securityGroup := range groups.SecurityGroups {
+		groupsByName[*securityGroup.GroupName] = securityGroup
+	}
+
+	for _, groupName := range groupNames {
+		group, ok := groupsByName[groupName]
+		if !ok {
+			return fmt.Errorf("security group %q not found in VPC %s", groupName, d.VpcId)
 		}
 
-		egressPerms, err := d.egressPermissions(group)
-		if err != nil {
+		log.Debugf("Attaching existing security group %s (%s)", groupName, *group.GroupId)
+		d.SecurityGroupIds = append(d.SecurityGroupIds, *group.GroupId)
+
+		if err := d.authorizeSecurityGroup(groupName, group); err != nil {
 			return err
 		}
-		if len(egressPerms) > 0 {
-			log.Debugf("Adding the following engress rules to the security group %s: %v", groupName, egressPerms)
-			_, err = d.getClient().AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
-				GroupId:       group.GroupId,
-				IpPermissions: egressPerms,
-			})
-			if err != nil {
-				if strings.Contains(err.Error(), "already exists") {
-					log.Debugf("Skip updating the security group due to: %s", err.Error())
-				} else {
-					return err
-				}
+	}
+
+	return nil
+}
+
+// authorizeSecurityGroup adds the missing ingress and egress rules to a
+// security group.
+func (d *Driver) authorizeSecurityGroup(groupName string, group *ec2.SecurityGroup) error {
+	ingressPerms, err := d.ingressPermissions(group)
+	if err != nil {
+		return err
+	}
+
+	if len(ingressPerms) > 0 {
+		log.Debugf("Adding the following ingress rules to the security group %s: %v", groupName, ingressPerms)
+		_, err := d.getClient().AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       group.GroupId,
+			IpPermissions: ingressPerms,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "already exists") {
+				log.Debugf("Skip updating the security group due to: %s", err.Error())
+			} else {
+				return err
+			}
+		}
+	}
+
+	egressPerms, err := d.egressPermissions(group)
+	if err != nil {
+		return err
+	}
+	if len(egressPerms) > 0 {
+		log.Debugf("Adding the following engress rules to the security group %s: %v", groupName, egressPerms)
+		_, err = d.getClient().AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
+			GroupId:       group.GroupId,
+			IpPermissions: egressPerms,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "already exists") {
+				log.Debugf("Skip updating the security group due to: %s", err.Error())
+			} else {
+				return err
 			}
 		}
 	}
@@ -1833,7 +2131,9 @@ func (d *Driver) updateBDMList() []*ec2.BlockDeviceMapping {
 				bdm.Ebs.VolumeType = aws.String(d.VolumeType)
 			}
 			bdm.Ebs.DeleteOnTermination = aws.Bool(true)
-			bdm.Ebs.KmsKeyId = d.kmsKeyId
+			if d.KmsKeyId != "" {
+				bdm.Ebs.KmsKeyId = aws.String(d.KmsKeyId)
+			}
 			bdm.Ebs.Encrypted = aws.Bool(d.EncryptEbsVolume)
 			bdmList = append(bdmList, bdm)
 		}
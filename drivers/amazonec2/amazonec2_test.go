@@ -3,12 +3,14 @@ package amazonec2
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/rancher/machine/commands/commandstest"
 	"github.com/rancher/machine/version"
@@ -365,6 +367,65 @@ func TestEndpointIsMandatoryWhenSSLDisabled(t *testing.T) {
 	assert.Equal(t, err, errorDisableSSLWithoutCustomEndpoint)
 }
 
+func TestValidateEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"ec2.example.com", "localhost:4566", "http://localhost:4566", "https://ec2.example.com/api/"} {
+		assert.NoError(t, validateEndpoint(endpoint), endpoint)
+	}
+
+	assert.EqualError(t, validateEndpoint("ftp://ec2.example.com"), `invalid endpoint "ftp://ec2.example.com" (--amazonec2-endpoint), the scheme must be http or https`)
+	assert.EqualError(t, validateEndpoint("https://"), `invalid endpoint "https://" (--amazonec2-endpoint), must be a host[:port] or an http(s)://host[:port][/path] URL`)
+	assert.EqualError(t, validateEndpoint("https://ec2.example.com?a=b"), `invalid endpoint "https://ec2.example.com?a=b" (--amazonec2-endpoint), must be a host[:port] or an http(s)://host[:port][/path] URL`)
+}
+
+func TestCustomEndpointKeepsRegion(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithLogin{})
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	options := &commandstest.FakeFlagger{
+		Data: map[string]interface{}{
+			"name":                         "test",
+			"amazonec2-endpoint":           "https://localhost:4566",
+			"amazonec2-region":             "local-1",
+			"amazonec2-ami":                "ami-12345",
+			"amazonec2-insecure-transport": true,
+		},
+	}
+
+	err := driver.SetConfigFromFlags(options)
+	assert.NoError(t, err)
+	assert.Equal(t, "local-1", driver.Region)
+
+	client := driver.buildClient().(*ec2.EC2)
+	assert.Equal(t, "https://localhost:4566", client.Endpoint)
+	assert.Equal(t, "local-1", client.SigningRegion)
+	assert.Equal(t, endpoints.DualStackEndpointStateUnset, client.Config.UseDualStackEndpoint)
+	assert.True(t, client.Config.HTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestInvalidCustomEndpoint(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithLogin{})
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	options := &commandstest.FakeFlagger{
+		Data: map[string]interface{}{
+			"name":               "test",
+			"amazonec2-endpoint": "ftp://localhost",
+		},
+	}
+
+	err := driver.SetConfigFromFlags(options)
+
+	assert.EqualError(t, err, `invalid endpoint "ftp://localhost" (--amazonec2-endpoint), the scheme must be http or https`)
+}
+
+func TestClientToken(t *testing.T) {
+	driver := NewTestDriver()
+	assert.Nil(t, driver.clientToken())
+
+	driver.ClientToken = newClientToken()
+	assert.Len(t, driver.ClientToken, 32)
+	assert.NotEqual(t, driver.ClientToken, newClientToken())
+	assert.Equal(t, driver.ClientToken, *driver.clientToken())
+}
+
 var values = []string{
 	"bob",
 	"jake",
@@ -520,6 +581,43 @@ func TestConfigureSecurityGroupsErrLookupExist(t *testing.T) {
 	recorder.AssertExpectations(t)
 }
 
+func TestAttachExistingSecurityGroupsReadOnly(t *testing.T) {
+	groups := []string{"team-a", "team-b"}
+	recorder := fakeEC2SecurityGroupTestRecorder{}
+
+	recorder.On("DescribeSecurityGroups", mock.MatchedBy(matchGroupLookup(groups))).Return(
+		&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+			{GroupName: aws.String("team-b"), GroupId: aws.String("sg-b")},
+			{GroupName: aws.String("team-a"), GroupId: aws.String("sg-a")},
+		}}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.SecurityGroupReadOnly = true
+	err := driver.attachExistingSecurityGroups(groups)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sg-a", "sg-b"}, driver.SecurityGroupIds)
+	recorder.AssertExpectations(t)
+}
+
+func TestAttachExistingSecurityGroupsNotFound(t *testing.T) {
+	groups := []string{"team-a", "missing"}
+	recorder := fakeEC2SecurityGroupTestRecorder{}
+
+	recorder.On("DescribeSecurityGroups", mock.MatchedBy(matchGroupLookup(groups))).Return(
+		&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{
+			{GroupName: aws.String("team-a"), GroupId: aws.String("sg-a")},
+		}}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.SecurityGroupReadOnly = true
+	driver.VpcId = "vpc-123"
+	err := driver.attachExistingSecurityGroups(groups)
+
+	assert.EqualError(t, err, `security group "missing" not found in VPC vpc-123`)
+	recorder.AssertExpectations(t)
+}
+
 func TestBase64UserDataIsEmptyIfNoFileProvided(t *testing.T) {
 	driver := NewTestDriver()
 
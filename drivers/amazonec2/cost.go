@@ -0,0 +1,72 @@
+package amazonec2
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// onDemandPrices are the hourly on-demand prices in USD of Linux instances of
+// the common types, which are the same in all the pricingRegions.
+var onDemandPrices = map[string]float64{
+	"t2.nano":     0.0058,
+	"t2.micro":    0.0116,
+	"t2.small":    0.023,
+	"t2.medium":   0.0464,
+	"t2.large":    0.0928,
+	"t2.xlarge":   0.1856,
+	"t2.2xlarge":  0.3712,
+	"t3.nano":     0.0052,
+	"t3.micro":    0.0104,
+	"t3.small":    0.0208,
+	"t3.medium":   0.0416,
+	"t3.large":    0.0832,
+	"t3.xlarge":   0.1664,
+	"t3.2xlarge":  0.3328,
+	"m5.large":    0.096,
+	"m5.xlarge":   0.192,
+	"m5.2xlarge":  0.384,
+	"m5.4xlarge":  0.768,
+	"c5.large":    0.085,
+	"c5.xlarge":   0.17,
+	"c5.2xlarge":  0.34,
+	"c5.4xlarge":  0.68,
+	"r5.large":    0.126,
+	"r5.xlarge":   0.252,
+	"r5.2xlarge":  0.504,
+	"g4dn.xlarge": 0.526,
+	"p3.2xlarge":  3.06,
+}
+
+var pricingRegions = map[string]bool{
+	"us-east-1": true,
+	"us-east-2": true,
+	"us-west-2": true,
+}
+
+// EstimateCost estimates the on-demand cost of the instance from a table of
+// the prices of the common instance types in the cheapest US regions. Spot
+// instances usually cost much less.
+func (d *Driver) EstimateCost() (*drivers.CostEstimate, error) {
+	if !pricingRegions[d.Region] {
+		return nil, mcnerror.NotSupported(fmt.Errorf("no pricing data for region %s", d.Region))
+	}
+
+	hourly, ok := onDemandPrices[d.InstanceType]
+	if !ok {
+		return nil, mcnerror.NotSupported(fmt.Errorf("no pricing data for instance type %s", d.InstanceType))
+	}
+
+	description := fmt.Sprintf("%s on-demand in %s", d.InstanceType, d.Region)
+	if d.RequestSpotInstance {
+		description += ", spot instances usually cost less"
+	}
+
+	return &drivers.CostEstimate{
+		Hourly:      hourly,
+		Monthly:     hourly * drivers.HoursPerMonth,
+		Currency:    "USD",
+		Description: description,
+	}, nil
+}
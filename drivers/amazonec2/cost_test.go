@@ -0,0 +1,37 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCost(t *testing.T) {
+	driver := NewDriver("machineFoo", "path")
+	driver.Region = "us-east-2"
+	driver.InstanceType = "t3.micro"
+
+	estimate, err := driver.EstimateCost()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0104, estimate.Hourly)
+	assert.InDelta(t, 7.592, estimate.Monthly, 0.0001)
+	assert.Equal(t, "USD", estimate.Currency)
+	assert.Equal(t, "t3.micro on-demand in us-east-2", estimate.Description)
+}
+
+func TestEstimateCostUnknown(t *testing.T) {
+	driver := NewDriver("machineFoo", "path")
+	driver.Region = "eu-west-1"
+
+	_, err := driver.EstimateCost()
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+
+	driver.Region = "us-east-1"
+	driver.InstanceType = "x1e.32xlarge"
+
+	_, err = driver.EstimateCost()
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+}
@@ -0,0 +1,40 @@
+package amazonec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type stsClient interface {
+	GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+// CheckCredentials checks that the credentials can call the EC2 API of the
+// region, with a read-only DescribeRegions call, and returns the ARN they map
+// to. The ARN isn't looked up with a custom endpoint, which usually has no STS
+// API alongside.
+func (d *Driver) CheckCredentials() (string, error) {
+	if _, err := d.awsCredentialsFactory().Credentials().Get(); err != nil {
+		return "", errorMissingCredentials
+	}
+
+	if _, err := d.getClient().DescribeRegions(&ec2.DescribeRegionsInput{
+		RegionNames: []*string{aws.String(d.Region)},
+	}); err != nil {
+		return "", fmt.Errorf("unable to describe region %s: %s", d.Region, err)
+	}
+
+	if d.Endpoint != "" {
+		return "", nil
+	}
+
+	identity, err := d.stsClientFactory().GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("unable to get the caller identity: %s", err)
+	}
+
+	return aws.StringValue(identity.Arn), nil
+}
@@ -0,0 +1,51 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithRegions{})
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	driver.stsClientFactory = func() stsClient {
+		return &fakeSTS{arn: "arn:aws:iam::123456789012:user/ci"}
+	}
+
+	identity, err := driver.CheckCredentials()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/ci", identity)
+}
+
+func TestCheckCredentialsCustomEndpoint(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithRegions{})
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	driver.Endpoint = "https://ec2.example.com"
+
+	identity, err := driver.CheckCredentials()
+
+	assert.NoError(t, err)
+	assert.Empty(t, identity)
+}
+
+func TestCheckCredentialsRejected(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithRegions{err: errors.New("AuthFailure")})
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	driver.Region = "us-east-1"
+
+	_, err := driver.CheckCredentials()
+
+	assert.EqualError(t, err, "unable to describe region us-east-1: AuthFailure")
+}
+
+func TestCheckCredentialsMissing(t *testing.T) {
+	driver := NewCustomTestDriver(&fakeEC2WithRegions{})
+	driver.awsCredentialsFactory = NewErrorAwsCredentials
+
+	_, err := driver.CheckCredentials()
+
+	assert.Equal(t, errorMissingCredentials, err)
+}
@@ -0,0 +1,51 @@
+package amazonec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+type kmsClient interface {
+	DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error)
+}
+
+// unencryptedInstanceFamilies are the previous generation instance families
+// which don't support encrypted EBS volumes.
+var unencryptedInstanceFamilies = []string{"c1", "cc2", "cg1", "cr1", "hi1", "hs1", "m1", "m2", "t1"}
+
+// checkEncryption checks that the EBS volumes can be encrypted: the instance
+// type supports it and the KMS key, if any, is enabled and usable with the
+// credentials.
+func (d *Driver) checkEncryption() error {
+	if !d.EncryptEbsVolume {
+		if d.KmsKeyId != "" {
+			return fmt.Errorf("the KMS key %s (--amazonec2-kms-key) requires --amazonec2-encrypt-ebs-volume", d.KmsKeyId)
+		}
+		return nil
+	}
+
+	family, _, _ := strings.Cut(d.InstanceType, ".")
+	for _, unsupported := range unencryptedInstanceFamilies {
+		if family == unsupported {
+			return fmt.Errorf("the instance type %s doesn't support encrypted EBS volumes", d.InstanceType)
+		}
+	}
+
+	if d.KmsKeyId == "" {
+		return nil
+	}
+
+	key, err := d.kmsClientFactory().DescribeKey(&kms.DescribeKeyInput{KeyId: aws.String(d.KmsKeyId)})
+	if err != nil {
+		return fmt.Errorf("unable to access the KMS key %s in region %s: %s", d.KmsKeyId, d.Region, err)
+	}
+
+	if state := aws.StringValue(key.KeyMetadata.KeyState); state != kms.KeyStateEnabled {
+		return fmt.Errorf("the KMS key %s is %s, it must be Enabled to encrypt the EBS volumes", d.KmsKeyId, state)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEncryptionDisabled(t *testing.T) {
+	driver := NewTestDriver()
+
+	assert.NoError(t, driver.checkEncryption())
+}
+
+func TestCheckEncryptionKeyWithoutEncryption(t *testing.T) {
+	driver := NewTestDriver()
+	driver.KmsKeyId = "alias/machines"
+
+	assert.EqualError(t, driver.checkEncryption(), "the KMS key alias/machines (--amazonec2-kms-key) requires --amazonec2-encrypt-ebs-volume")
+}
+
+func TestCheckEncryptionUnsupportedInstanceType(t *testing.T) {
+	driver := NewTestDriver()
+	driver.EncryptEbsVolume = true
+	driver.InstanceType = "m1.small"
+
+	assert.EqualError(t, driver.checkEncryption(), "the instance type m1.small doesn't support encrypted EBS volumes")
+}
+
+func TestCheckEncryptionKey(t *testing.T) {
+	driver := NewTestDriver()
+	driver.EncryptEbsVolume = true
+	driver.InstanceType = "t3.micro"
+	driver.KmsKeyId = "alias/machines"
+	driver.kmsClientFactory = func() kmsClient {
+		return &fakeKMS{state: kms.KeyStateEnabled}
+	}
+
+	assert.NoError(t, driver.checkEncryption())
+}
+
+func TestCheckEncryptionKeyDisabled(t *testing.T) {
+	driver := NewTestDriver()
+	driver.EncryptEbsVolume = true
+	driver.KmsKeyId = "alias/machines"
+	driver.kmsClientFactory = func() kmsClient {
+		return &fakeKMS{state: kms.KeyStatePendingDeletion}
+	}
+
+	assert.EqualError(t, driver.checkEncryption(), "the KMS key alias/machines is PendingDeletion, it must be Enabled to encrypt the EBS volumes")
+}
+
+func TestCheckEncryptionKeyInaccessible(t *testing.T) {
+	driver := NewTestDriver()
+	driver.EncryptEbsVolume = true
+	driver.Region = "us-east-1"
+	driver.KmsKeyId = "alias/machines"
+	driver.kmsClientFactory = func() kmsClient {
+		return &fakeKMS{err: errors.New("AccessDeniedException")}
+	}
+
+	assert.EqualError(t, driver.checkEncryption(), "unable to access the KMS key alias/machines in region us-east-1: AccessDeniedException")
+}
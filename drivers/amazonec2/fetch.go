@@ -0,0 +1,92 @@
+package amazonec2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/drivers/driverutil"
+	"github.com/rancher/machine/libmachine/drivers"
+)
+
+// Fetch returns the current type, IP, tags and open ports of the instance.
+func (d *Driver) Fetch() (*drivers.InstanceAttributes, error) {
+	inst, err := d.getInstance()
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := &drivers.InstanceAttributes{
+		InstanceType: aws.StringValue(inst.InstanceType),
+		Tags:         map[string]string{},
+	}
+	attributes.IP, _ = d.instanceIP(inst)
+
+	for _, tag := range inst.Tags {
+		// The aws: tags are set by AWS, not by the users.
+		if key := aws.StringValue(tag.Key); !strings.HasPrefix(key, "aws:") {
+			attributes.Tags[key] = aws.StringValue(tag.Value)
+		}
+	}
+
+	if len(d.OpenPorts) > 0 && len(inst.SecurityGroups) > 0 {
+		groupIds := []*string{}
+		for _, group := range inst.SecurityGroups {
+			groupIds = append(groupIds, group.GroupId)
+		}
+
+		groups, err := d.getClient().DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: groupIds})
+		if err != nil {
+			return nil, classifyError(err)
+		}
+
+		permissions := []*ec2.IpPermission{}
+		for _, group := range groups.SecurityGroups {
+			permissions = append(permissions, group.IpPermissions...)
+		}
+		attributes.OpenPorts = openPorts(permissions, d.OpenPorts)
+	}
+
+	return attributes, nil
+}
+
+// StoredAttributes returns the type, IP, tags and open ports the instance was
+// created with.
+func (d *Driver) StoredAttributes() (*drivers.InstanceAttributes, error) {
+	attributes := &drivers.InstanceAttributes{
+		InstanceType: d.InstanceType,
+		IP:           d.IPAddress,
+		Tags:         map[string]string{"Name": d.MachineName},
+		OpenPorts:    d.OpenPorts,
+	}
+
+	for _, tag := range buildEC2Tags(d.Tags) {
+		attributes.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return attributes, nil
+}
+
+// openPorts returns the ports of the given port/protocol list allowed by the
+// ingress permissions.
+func openPorts(permissions []*ec2.IpPermission, ports []string) []string {
+	open := []string{}
+	for _, p := range ports {
+		port, protocol := driverutil.SplitPortProto(p)
+		portNum, err := strconv.ParseInt(port, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		for _, permission := range permissions {
+			ipProtocol := aws.StringValue(permission.IpProtocol)
+			if ipProtocol == "-1" || (ipProtocol == protocol && aws.Int64Value(permission.FromPort) <= portNum && portNum <= aws.Int64Value(permission.ToPort)) {
+				open = append(open, p)
+				break
+			}
+		}
+	}
+
+	return open
+}
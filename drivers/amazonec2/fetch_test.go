@@ -0,0 +1,31 @@
+package amazonec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenPorts(t *testing.T) {
+	permissions := []*ec2.IpPermission{
+		{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(8000), ToPort: aws.Int64(8100)},
+		{IpProtocol: aws.String("udp"), FromPort: aws.Int64(53), ToPort: aws.Int64(53)},
+	}
+
+	assert.Equal(t, []string{"8080", "53/udp"}, openPorts(permissions, []string{"8080", "9000/tcp", "53/udp", "53/tcp"}))
+	assert.Equal(t, []string{"9000"}, openPorts([]*ec2.IpPermission{{IpProtocol: aws.String("-1")}}, []string{"9000"}))
+}
+
+func TestStoredAttributes(t *testing.T) {
+	driver := NewDriver("foo", "path")
+	driver.InstanceType = "t3.small"
+	driver.Tags = "env,prod"
+
+	attributes, err := driver.StoredAttributes()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "t3.small", attributes.InstanceType)
+	assert.Equal(t, map[string]string{"Name": "foo", "env": "prod"}, attributes.Tags)
+}
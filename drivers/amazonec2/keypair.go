@@ -0,0 +1,117 @@
+package amazonec2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// keyPairMachineTag tags the key pairs imported by machine with the name of
+// their machine, which is how the key pairs left behind are found.
+const keyPairMachineTag = "rancher-machine-name"
+
+// keyPairName returns the name of the key pair imported for a machine, the
+// name of the machine followed by a digest of its public key so that a retried
+// create finds the key pair it imported.
+func keyPairName(machineName string, publicKey []byte) string {
+	sum := sha256.Sum256(bytes.TrimSpace(publicKey))
+	return machineName + "-" + hex.EncodeToString(sum[:4])
+}
+
+// keyPairTagSpecifications returns the tags of the imported key pair: the
+// tags of the machine, its name and the tag marking it as imported by
+// machine.
+func (d *Driver) keyPairTagSpecifications() []*ec2.TagSpecification {
+	tags := append(buildEC2Tags(d.Tags),
+		&ec2.Tag{Key: aws.String("Name"), Value: aws.String(d.MachineName)},
+		&ec2.Tag{Key: aws.String(keyPairMachineTag), Value: aws.String(d.MachineName)},
+	)
+
+	return []*ec2.TagSpecification{{
+		ResourceType: aws.String(ec2KeyPairResource),
+		Tags:         tags,
+	}}
+}
+
+// MachineSSHKeys lists the key pairs of the region imported by machine, with
+// whether an instance that isn't terminated still uses them.
+func (d *Driver) MachineSSHKeys() ([]drivers.MachineSSHKey, error) {
+	output, err := d.getClient().DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(keyPairMachineTag)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	keys := []drivers.MachineSSHKey{}
+	for _, keyPair := range output.KeyPairs {
+		key := drivers.MachineSSHKey{
+			ID:   aws.StringValue(keyPair.KeyPairId),
+			Name: aws.StringValue(keyPair.KeyName),
+		}
+		for _, tag := range keyPair.Tags {
+			if aws.StringValue(tag.Key) == keyPairMachineTag {
+				key.MachineName = aws.StringValue(tag.Value)
+			}
+		}
+
+		if key.InUse, err = d.keyPairInUse(key.Name); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (d *Driver) keyPairInUse(keyName string) (bool, error) {
+	instances, err := d.getClient().DescribeInstances(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key-name"),
+				Values: []*string{aws.String(keyName)},
+			},
+			{
+				Name: aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{
+					ec2.InstanceStateNamePending,
+					ec2.InstanceStateNameRunning,
+					ec2.InstanceStateNameStopping,
+					ec2.InstanceStateNameStopped,
+				}),
+			},
+		},
+	})
+	if err != nil {
+		return false, classifyError(err)
+	}
+
+	for _, reservation := range instances.Reservations {
+		if len(reservation.Instances) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// DeleteSSHKey deletes a key pair listed by MachineSSHKeys.
+func (d *Driver) DeleteSSHKey(id string) error {
+	log.Debugf("Deleting key pair: %s", id)
+
+	_, err := d.getClient().DeleteKeyPair(&ec2.DeleteKeyPairInput{
+		KeyPairId: aws.String(id),
+	})
+	return classifyError(err)
+}
@@ -0,0 +1,63 @@
+package amazonec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPairName(t *testing.T) {
+	name := keyPairName("dev", []byte("ssh-rsa AAAA machine\n"))
+
+	assert.Regexp(t, "^dev-[0-9a-f]{8}$", name)
+	assert.Equal(t, name, keyPairName("dev", []byte("ssh-rsa AAAA machine")))
+	assert.NotEqual(t, name, keyPairName("dev", []byte("ssh-rsa BBBB machine")))
+}
+
+func TestKeyPairTagSpecifications(t *testing.T) {
+	driver := NewTestDriver()
+	driver.MachineName = "dev"
+	driver.Tags = "team,ops"
+
+	specs := driver.keyPairTagSpecifications()
+
+	assert.Len(t, specs, 1)
+	assert.Equal(t, "key-pair", aws.StringValue(specs[0].ResourceType))
+	tags := map[string]string{}
+	for _, tag := range specs[0].Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	assert.Equal(t, map[string]string{"team": "ops", "Name": "dev", keyPairMachineTag: "dev"}, tags)
+}
+
+func TestMachineSSHKeys(t *testing.T) {
+	keyPair := func(id, name, machineName string) *ec2.KeyPairInfo {
+		return &ec2.KeyPairInfo{
+			KeyPairId: aws.String(id),
+			KeyName:   aws.String(name),
+			Tags:      []*ec2.Tag{{Key: aws.String(keyPairMachineTag), Value: aws.String(machineName)}},
+		}
+	}
+	client := &fakeEC2KeyPairs{
+		keyPairs: []*ec2.KeyPairInfo{
+			keyPair("key-1", "dev-0a1b2c3d", "dev"),
+			keyPair("key-2", "old-4e5f6a7b", "old"),
+		},
+		inUse: map[string]bool{"dev-0a1b2c3d": true},
+	}
+	driver := NewCustomTestDriver(client)
+
+	keys, err := driver.MachineSSHKeys()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []drivers.MachineSSHKey{
+		{ID: "key-1", Name: "dev-0a1b2c3d", MachineName: "dev", InUse: true},
+		{ID: "key-2", Name: "old-4e5f6a7b", MachineName: "old"},
+	}, keys)
+
+	assert.NoError(t, driver.DeleteSSHKey("key-2"))
+	assert.Equal(t, []string{"key-2"}, client.deleted)
+}
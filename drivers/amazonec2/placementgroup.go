@@ -0,0 +1,184 @@
+package amazonec2
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/mcnutils"
+	"github.com/rancher/machine/version"
+)
+
+const (
+	ec2PlacementGroupResource = "placement-group"
+	placementGroupDuplicate   = "InvalidPlacementGroup.Duplicate"
+	placementGroupWaitTries   = 60
+)
+
+// placementGroupWaitInterval is the delay between two checks that the
+// instance is terminated before its placement group is deleted.
+var placementGroupWaitInterval = 5 * time.Second
+
+func validPlacementStrategy(strategy string) bool {
+	switch strategy {
+	case ec2.PlacementStrategyCluster, ec2.PlacementStrategySpread, ec2.PlacementStrategyPartition:
+		return true
+	}
+	return false
+}
+
+// placement returns the placement of the instance in the given availability
+// zone and in its placement group, if any.
+func (d *Driver) placement(regionZone string) *ec2.Placement {
+	placement := &ec2.Placement{
+		AvailabilityZone: &regionZone,
+	}
+	if d.PlacementGroup != "" {
+		placement.GroupName = aws.String(d.PlacementGroup)
+	}
+	return placement
+}
+
+func (d *Driver) describePlacementGroup() (*ec2.PlacementGroup, error) {
+	groups, err := d.getClient().DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("group-name"),
+				Values: []*string{aws.String(d.PlacementGroup)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups.PlacementGroups {
+		if aws.StringValue(group.GroupName) == d.PlacementGroup {
+			return group, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ensurePlacementGroup creates the placement group of the instance when it
+// doesn't exist yet. The created group is tagged so that it is deleted along
+// with the last of its machines.
+func (d *Driver) ensurePlacementGroup() error {
+	if d.PlacementGroup == "" {
+		return nil
+	}
+
+	group, err := d.describePlacementGroup()
+	if err != nil {
+		return fmt.Errorf("unable to look up placement group %s: %s", d.PlacementGroup, err)
+	}
+
+	if group != nil {
+		if strategy := aws.StringValue(group.Strategy); strategy != d.PlacementGroupStrategy {
+			log.Warnf("Placement group %s exists with strategy %s, ignoring strategy %s", d.PlacementGroup, strategy, d.PlacementGroupStrategy)
+		}
+		return nil
+	}
+
+	log.Infof("Creating placement group %s with strategy %s", d.PlacementGroup, d.PlacementGroupStrategy)
+	_, err = d.getClient().CreatePlacementGroup(&ec2.CreatePlacementGroupInput{
+		GroupName: aws.String(d.PlacementGroup),
+		Strategy:  aws.String(d.PlacementGroupStrategy),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2PlacementGroupResource),
+			Tags: []*ec2.Tag{{
+				Key:   aws.String(machineTag),
+				Value: aws.String(version.Version),
+			}},
+		}},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == placementGroupDuplicate {
+		// Another machine created it meanwhile.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create placement group %s: %s", d.PlacementGroup, err)
+	}
+
+	return nil
+}
+
+// removePlacementGroup deletes the placement group of the instance if it was
+// created by rancher-machine and no other instance is left in it. The
+// terminated instance must be gone before the group can be deleted.
+func (d *Driver) removePlacementGroup() error {
+	if d.PlacementGroup == "" {
+		return nil
+	}
+
+	group, err := d.describePlacementGroup()
+	if err != nil {
+		return fmt.Errorf("unable to look up placement group %s: %s", d.PlacementGroup, err)
+	}
+	if group == nil || !hasTagKey(group.Tags, machineTag) {
+		return nil
+	}
+
+	members, err := d.getClient().DescribeInstances(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("placement-group-name"),
+				Values: []*string{aws.String(d.PlacementGroup)},
+			},
+			{
+				Name: aws.String("instance-state-name"),
+				Values: []*string{
+					aws.String(ec2.InstanceStateNamePending),
+					aws.String(ec2.InstanceStateNameRunning),
+					aws.String(ec2.InstanceStateNameStopping),
+					aws.String(ec2.InstanceStateNameStopped),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list the instances of placement group %s: %s", d.PlacementGroup, err)
+	}
+
+	for _, reservation := range members.Reservations {
+		for _, instance := range reservation.Instances {
+			if aws.StringValue(instance.InstanceId) != d.InstanceId {
+				log.Debugf("Keeping placement group %s, instance %s is still in it", d.PlacementGroup, aws.StringValue(instance.InstanceId))
+				return nil
+			}
+		}
+	}
+
+	if d.InstanceId != "" {
+		log.Infof("Waiting for the instance to terminate to delete placement group %s...", d.PlacementGroup)
+		if err := mcnutils.WaitForSpecificOrError(d.instanceIsTerminated, placementGroupWaitTries, placementGroupWaitInterval); err != nil {
+			return fmt.Errorf("unable to delete placement group %s: %s", d.PlacementGroup, err)
+		}
+	}
+
+	log.Infof("Deleting placement group %s", d.PlacementGroup)
+	if _, err := d.getClient().DeletePlacementGroup(&ec2.DeletePlacementGroupInput{
+		GroupName: aws.String(d.PlacementGroup),
+	}); err != nil {
+		return fmt.Errorf("unable to delete placement group %s: %s", d.PlacementGroup, err)
+	}
+
+	return nil
+}
+
+func (d *Driver) instanceIsTerminated() (bool, error) {
+	instance, err := d.getInstance()
+	if errors.Is(err, mcnerror.ErrInstanceNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return aws.StringValue(instance.State.Name) == ec2.InstanceStateNameTerminated, nil
+}
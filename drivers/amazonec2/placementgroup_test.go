@@ -0,0 +1,149 @@
+package amazonec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func placementGroupLookup(name string) *ec2.DescribePlacementGroupsInput {
+	return &ec2.DescribePlacementGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("group-name"),
+				Values: []*string{aws.String(name)},
+			},
+		},
+	}
+}
+
+func matchMembersLookup(input *ec2.DescribeInstancesInput) bool {
+	return len(input.Filters) > 0 && *input.Filters[0].Name == "placement-group-name"
+}
+
+func matchInstanceLookup(input *ec2.DescribeInstancesInput) bool {
+	return len(input.InstanceIds) == 1
+}
+
+func TestValidPlacementStrategy(t *testing.T) {
+	assert.True(t, validPlacementStrategy("cluster"))
+	assert.True(t, validPlacementStrategy("spread"))
+	assert.True(t, validPlacementStrategy("partition"))
+	assert.False(t, validPlacementStrategy("compact"))
+	assert.False(t, validPlacementStrategy(""))
+}
+
+func TestPlacement(t *testing.T) {
+	driver := NewTestDriver()
+
+	placement := driver.placement("us-east-1a")
+	assert.Equal(t, "us-east-1a", *placement.AvailabilityZone)
+	assert.Nil(t, placement.GroupName)
+
+	driver.PlacementGroup = "pg"
+	placement = driver.placement("us-east-1a")
+	assert.Equal(t, "pg", *placement.GroupName)
+}
+
+func TestEnsurePlacementGroupCreates(t *testing.T) {
+	recorder := fakeEC2PlacementGroupTestRecorder{}
+	recorder.On("DescribePlacementGroups", placementGroupLookup("pg")).Return(
+		&ec2.DescribePlacementGroupsOutput{}, nil)
+	recorder.On("CreatePlacementGroup", &ec2.CreatePlacementGroupInput{
+		GroupName: aws.String("pg"),
+		Strategy:  aws.String("spread"),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String("placement-group"),
+			Tags: []*ec2.Tag{{
+				Key:   aws.String(machineTag),
+				Value: aws.String(version.Version),
+			}},
+		}},
+	}).Return(&ec2.CreatePlacementGroupOutput{}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.PlacementGroup = "pg"
+	driver.PlacementGroupStrategy = "spread"
+
+	assert.NoError(t, driver.ensurePlacementGroup())
+	recorder.AssertExpectations(t)
+}
+
+func TestEnsurePlacementGroupExisting(t *testing.T) {
+	recorder := fakeEC2PlacementGroupTestRecorder{}
+	recorder.On("DescribePlacementGroups", placementGroupLookup("pg")).Return(
+		&ec2.DescribePlacementGroupsOutput{PlacementGroups: []*ec2.PlacementGroup{
+			{GroupName: aws.String("pg"), Strategy: aws.String("cluster")},
+		}}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.PlacementGroup = "pg"
+	driver.PlacementGroupStrategy = "cluster"
+
+	assert.NoError(t, driver.ensurePlacementGroup())
+	recorder.AssertExpectations(t)
+}
+
+func TestRemovePlacementGroupNotCreatedByMachine(t *testing.T) {
+	recorder := fakeEC2PlacementGroupTestRecorder{}
+	recorder.On("DescribePlacementGroups", placementGroupLookup("pg")).Return(
+		&ec2.DescribePlacementGroupsOutput{PlacementGroups: []*ec2.PlacementGroup{
+			{GroupName: aws.String("pg")},
+		}}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.PlacementGroup = "pg"
+	driver.InstanceId = "i-1"
+
+	assert.NoError(t, driver.removePlacementGroup())
+	recorder.AssertExpectations(t)
+}
+
+func TestRemovePlacementGroupWithOtherMembers(t *testing.T) {
+	recorder := fakeEC2PlacementGroupTestRecorder{}
+	recorder.On("DescribePlacementGroups", placementGroupLookup("pg")).Return(
+		&ec2.DescribePlacementGroupsOutput{PlacementGroups: []*ec2.PlacementGroup{
+			{GroupName: aws.String("pg"), Tags: []*ec2.Tag{{Key: aws.String(machineTag), Value: aws.String("")}}},
+		}}, nil)
+	recorder.On("DescribeInstances", mock.MatchedBy(matchMembersLookup)).Return(
+		&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{
+			{InstanceId: aws.String("i-1")},
+			{InstanceId: aws.String("i-2")},
+		}}}}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.PlacementGroup = "pg"
+	driver.InstanceId = "i-1"
+
+	assert.NoError(t, driver.removePlacementGroup())
+	recorder.AssertExpectations(t)
+}
+
+func TestRemovePlacementGroupLastMember(t *testing.T) {
+	recorder := fakeEC2PlacementGroupTestRecorder{}
+	recorder.On("DescribePlacementGroups", placementGroupLookup("pg")).Return(
+		&ec2.DescribePlacementGroupsOutput{PlacementGroups: []*ec2.PlacementGroup{
+			{GroupName: aws.String("pg"), Tags: []*ec2.Tag{{Key: aws.String(machineTag), Value: aws.String("")}}},
+		}}, nil)
+	recorder.On("DescribeInstances", mock.MatchedBy(matchMembersLookup)).Return(
+		&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{
+			{InstanceId: aws.String("i-1")},
+		}}}}, nil)
+	recorder.On("DescribeInstances", mock.MatchedBy(matchInstanceLookup)).Return(
+		&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{
+			{InstanceId: aws.String("i-1"), State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)}},
+		}}}}, nil)
+	recorder.On("DeletePlacementGroup", &ec2.DeletePlacementGroupInput{GroupName: aws.String("pg")}).Return(
+		&ec2.DeletePlacementGroupOutput{}, nil)
+
+	driver := NewCustomTestDriver(&recorder)
+	driver.PlacementGroup = "pg"
+	driver.InstanceId = "i-1"
+
+	assert.NoError(t, driver.removePlacementGroup())
+	recorder.AssertExpectations(t)
+}
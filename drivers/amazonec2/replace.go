@@ -0,0 +1,35 @@
+package amazonec2
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// Replace terminates the spot instance of the machine, e.g. when it can't be
+// started again for lack of spot capacity, and requests a new one with the
+// same configuration. The data of the old instance is lost.
+func (d *Driver) Replace() error {
+	if !d.RequestSpotInstance {
+		return fmt.Errorf("instance %s is not a spot instance, only spot instances are replaced", d.InstanceId)
+	}
+
+	old := d.InstanceId
+	log.Infof("Terminating spot instance %s...", old)
+	if err := d.terminate(); err != nil {
+		return err
+	}
+
+	// The instance is gone even if no other one can be launched, and the
+	// token of the old one would only return it again.
+	d.InstanceId = ""
+	d.IPAddress, d.PrivateIPAddress, d.IPv6Address = "", "", ""
+	d.ClientToken = newClientToken()
+
+	if err := d.launchInstanceWithFallback(); err != nil {
+		return fmt.Errorf("error replacing spot instance %s: %s", old, err)
+	}
+
+	log.Infof("Replaced spot instance %s with %s", old, d.InstanceId)
+	return nil
+}
@@ -0,0 +1,35 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartInsufficientCapacity(t *testing.T) {
+	instance := &fakeEC2Instance{
+		state:    ec2.InstanceStateNameStopped,
+		startErr: awserr.New("InsufficientInstanceCapacity", "no spot capacity", nil),
+	}
+	driver := NewCustomTestDriver(instance)
+	driver.InstanceId = "i-1234"
+
+	err := driver.Start()
+
+	assert.True(t, errors.Is(err, mcnerror.ErrCapacity))
+	assert.True(t, mcnerror.IsTransient(err))
+}
+
+func TestReplaceOnDemandInstance(t *testing.T) {
+	driver := NewTestDriver()
+	driver.InstanceId = "i-1234"
+
+	err := driver.Replace()
+
+	assert.EqualError(t, err, "instance i-1234 is not a spot instance, only spot instances are replaced")
+	assert.Equal(t, "i-1234", driver.InstanceId)
+}
@@ -0,0 +1,62 @@
+package amazonec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnutils"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+// Resize changes the type of the instance. The type of an instance can only
+// be changed while it is stopped, so it is stopped first and started again
+// once its type is changed.
+func (d *Driver) Resize(size string) error {
+	if d.RequestSpotInstance {
+		return fmt.Errorf("the type of a spot instance can't be changed")
+	}
+
+	inst, err := d.getInstance()
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(inst.InstanceType) == size {
+		log.Infof("Instance %s is already of type %s", d.InstanceId, size)
+		d.InstanceType = size
+		return nil
+	}
+
+	if aws.StringValue(inst.State.Name) != ec2.InstanceStateNameStopped {
+		log.Infof("Stopping instance %s to change its type...", d.InstanceId)
+		if err := d.Stop(); err != nil {
+			return err
+		}
+		if err := mcnutils.WaitFor(drivers.MachineInState(d, state.Stopped)); err != nil {
+			return fmt.Errorf("error waiting for instance %s to stop: %s", d.InstanceId, err)
+		}
+	}
+
+	log.Infof("Changing the type of instance %s from %s to %s...", d.InstanceId, aws.StringValue(inst.InstanceType), size)
+	_, modifyErr := d.getClient().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId:   &d.InstanceId,
+		InstanceType: &ec2.AttributeValue{Value: aws.String(size)},
+	})
+	if modifyErr == nil {
+		d.InstanceType = size
+	}
+
+	// The instance is started again even if its type couldn't be changed,
+	// not to leave it stopped.
+	if err := d.Start(); err != nil {
+		return err
+	}
+	if modifyErr != nil {
+		return fmt.Errorf("error changing the type of instance %s to %s: %w", d.InstanceId, size, classifyError(modifyErr))
+	}
+
+	d.IPAddress, err = d.GetIP()
+	return err
+}
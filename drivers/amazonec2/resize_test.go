@@ -0,0 +1,52 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	instance := &fakeEC2Instance{instanceType: "t3.small", state: ec2.InstanceStateNameRunning}
+	driver := NewCustomTestDriver(instance)
+	driver.InstanceId = "i-1234"
+	driver.InstanceType = "t3.small"
+
+	err := driver.Resize("t3.large")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stop", "modify t3.large", "start"}, instance.calls)
+	assert.Equal(t, "t3.large", driver.InstanceType)
+	assert.Equal(t, "1.2.3.4", driver.IPAddress)
+}
+
+func TestResizeSameType(t *testing.T) {
+	instance := &fakeEC2Instance{instanceType: "t3.small", state: ec2.InstanceStateNameRunning}
+	driver := NewCustomTestDriver(instance)
+	driver.InstanceId = "i-1234"
+
+	assert.NoError(t, driver.Resize("t3.small"))
+	assert.Empty(t, instance.calls)
+}
+
+func TestResizeModifyError(t *testing.T) {
+	instance := &fakeEC2Instance{instanceType: "t3.small", state: ec2.InstanceStateNameRunning, modifyErr: errors.New("unsupported type")}
+	driver := NewCustomTestDriver(instance)
+	driver.InstanceId = "i-1234"
+	driver.InstanceType = "t3.small"
+
+	err := driver.Resize("x9.huge")
+
+	assert.EqualError(t, err, "error changing the type of instance i-1234 to x9.huge: unsupported type")
+	assert.Equal(t, []string{"stop", "modify x9.huge", "start"}, instance.calls)
+	assert.Equal(t, "t3.small", driver.InstanceType)
+}
+
+func TestResizeSpotInstance(t *testing.T) {
+	driver := NewTestDriver()
+	driver.RequestSpotInstance = true
+
+	assert.EqualError(t, driver.Resize("t3.large"), "the type of a spot instance can't be changed")
+}
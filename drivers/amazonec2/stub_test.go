@@ -6,6 +6,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/sts"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -177,3 +179,190 @@ func NewCustomTestDriver(ec2Client Ec2Client) *Driver {
 	}
 	return driver
 }
+
+type fakeEC2PlacementGroupTestRecorder struct {
+	*fakeEC2
+	mock.Mock
+}
+
+func (f *fakeEC2PlacementGroupTestRecorder) DescribePlacementGroups(input *ec2.DescribePlacementGroupsInput) (*ec2.DescribePlacementGroupsOutput, error) {
+	result := f.Called(input)
+	err := result.Error(1)
+	value, ok := result.Get(0).(*ec2.DescribePlacementGroupsOutput)
+	if !ok && err == nil {
+		return nil, errors.New("type assertion to DescribePlacementGroupsOutput failed")
+	}
+	return value, err
+}
+
+func (f *fakeEC2PlacementGroupTestRecorder) CreatePlacementGroup(input *ec2.CreatePlacementGroupInput) (*ec2.CreatePlacementGroupOutput, error) {
+	result := f.Called(input)
+	err := result.Error(1)
+	value, ok := result.Get(0).(*ec2.CreatePlacementGroupOutput)
+	if !ok && err == nil {
+		return nil, errors.New("type assertion to CreatePlacementGroupOutput failed")
+	}
+	return value, err
+}
+
+func (f *fakeEC2PlacementGroupTestRecorder) DeletePlacementGroup(input *ec2.DeletePlacementGroupInput) (*ec2.DeletePlacementGroupOutput, error) {
+	result := f.Called(input)
+	err := result.Error(1)
+	value, ok := result.Get(0).(*ec2.DeletePlacementGroupOutput)
+	if !ok && err == nil {
+		return nil, errors.New("type assertion to DeletePlacementGroupOutput failed")
+	}
+	return value, err
+}
+
+func (f *fakeEC2PlacementGroupTestRecorder) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	result := f.Called(input)
+	err := result.Error(1)
+	value, ok := result.Get(0).(*ec2.DescribeInstancesOutput)
+	if !ok && err == nil {
+		return nil, errors.New("type assertion to DescribeInstancesOutput failed")
+	}
+	return value, err
+}
+
+type fakeEC2WithRegions struct {
+	*fakeEC2
+	err error
+}
+
+func (f *fakeEC2WithRegions) DescribeRegions(input *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ec2.DescribeRegionsOutput{
+		Regions: []*ec2.Region{{RegionName: input.RegionNames[0]}},
+	}, nil
+}
+
+type fakeSTS struct {
+	arn string
+}
+
+func (f *fakeSTS) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(f.arn)}, nil
+}
+
+type fakeKMS struct {
+	state string
+	err   error
+}
+
+func (f *fakeKMS) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: &kms.KeyMetadata{KeyId: input.KeyId, KeyState: aws.String(f.state)}}, nil
+}
+
+// fakeEC2Instance is a single instance that stops, starts and changes type
+// on request.
+type fakeEC2Instance struct {
+	*fakeEC2
+	instanceType string
+	state        string
+	modifyErr    error
+	startErr     error
+	calls        []string
+}
+
+func (f *fakeEC2Instance) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+			InstanceId:      input.InstanceIds[0],
+			InstanceType:    aws.String(f.instanceType),
+			State:           &ec2.InstanceState{Name: aws.String(f.state)},
+			PublicIpAddress: aws.String("1.2.3.4"),
+		}}}},
+	}, nil
+}
+
+func (f *fakeEC2Instance) StopInstances(input *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+	f.calls = append(f.calls, "stop")
+	f.state = ec2.InstanceStateNameStopped
+	return &ec2.StopInstancesOutput{}, nil
+}
+
+func (f *fakeEC2Instance) StartInstances(input *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+	f.calls = append(f.calls, "start")
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	f.state = ec2.InstanceStateNameRunning
+	return &ec2.StartInstancesOutput{}, nil
+}
+
+func (f *fakeEC2Instance) ModifyInstanceAttribute(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+	f.calls = append(f.calls, "modify "+aws.StringValue(input.InstanceType.Value))
+	if f.modifyErr != nil {
+		return nil, f.modifyErr
+	}
+	f.instanceType = aws.StringValue(input.InstanceType.Value)
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+// fakeEC2Subnets is a VPC with subnets in several zones, launching running
+// instances unless their zone is out of capacity.
+type fakeEC2Subnets struct {
+	*fakeEC2Instance
+	subnets  []*ec2.Subnet
+	runErrs  map[string]error
+	launched []string
+}
+
+func (f *fakeEC2Subnets) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	subnets := []*ec2.Subnet{}
+	for _, subnet := range f.subnets {
+		match := true
+		for _, filter := range input.Filters {
+			value := subnet.SubnetId
+			if aws.StringValue(filter.Name) == "vpc-id" {
+				value = subnet.VpcId
+			}
+			match = match && aws.StringValue(value) == aws.StringValue(filter.Values[0])
+		}
+		if match {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+}
+
+func (f *fakeEC2Subnets) RunInstances(input *ec2.RunInstancesInput) (*ec2.Reservation, error) {
+	az := aws.StringValue(input.Placement.AvailabilityZone)
+	f.launched = append(f.launched, az+" "+aws.StringValue(input.NetworkInterfaces[0].SubnetId))
+	if err := f.runErrs[az]; err != nil {
+		return nil, err
+	}
+	return &ec2.Reservation{Instances: []*ec2.Instance{{InstanceId: aws.String("i-" + az)}}}, nil
+}
+
+// fakeEC2KeyPairs has key pairs imported by machine, the ones listed in
+// inUse being the key pairs of an instance.
+type fakeEC2KeyPairs struct {
+	*fakeEC2
+	keyPairs []*ec2.KeyPairInfo
+	inUse    map[string]bool
+	deleted  []string
+}
+
+func (f *fakeEC2KeyPairs) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: f.keyPairs}, nil
+}
+
+func (f *fakeEC2KeyPairs) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	output := &ec2.DescribeInstancesOutput{}
+	if f.inUse[aws.StringValue(input.Filters[0].Values[0])] {
+		output.Reservations = []*ec2.Reservation{{Instances: []*ec2.Instance{{}}}}
+	}
+	return output, nil
+}
+
+func (f *fakeEC2KeyPairs) DeleteKeyPair(input *ec2.DeleteKeyPairInput) (*ec2.DeleteKeyPairOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValue(input.KeyPairId))
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
@@ -0,0 +1,239 @@
+package amazonec2
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+const (
+	assignPublicIPTrue   = "true"
+	assignPublicIPFalse  = "false"
+	assignPublicIPSubnet = "subnet"
+)
+
+// spotCapacityCodes are the status codes of the spot requests not fulfilled
+// for lack of capacity in their zone.
+var spotCapacityCodes = map[string]bool{
+	"capacity-not-available":  true,
+	"capacity-oversubscribed": true,
+}
+
+func (d *Driver) validateAssignPublicIP() error {
+	switch d.AssignPublicIP {
+	case "", assignPublicIPSubnet:
+	case assignPublicIPTrue:
+		if d.PrivateIPOnly {
+			return errors.New("--amazonec2-assign-public-ip true conflicts with --amazonec2-private-address-only")
+		}
+		if d.Ipv6AddressOnly {
+			return errors.New("--amazonec2-assign-public-ip true conflicts with --amazonec2-ipv6-address-only, IPv6-only subnets have no public IPv4 address")
+		}
+	case assignPublicIPFalse:
+		// The machine would wait for a public IP address it never gets.
+		if !d.PrivateIPOnly && !d.UsePrivateIP && !d.Ipv6AddressOnly {
+			return errors.New("--amazonec2-assign-public-ip false requires --amazonec2-private-address-only or --amazonec2-use-private-address")
+		}
+	default:
+		return fmt.Errorf("invalid --amazonec2-assign-public-ip %q, must be true, false or subnet", d.AssignPublicIP)
+	}
+
+	return nil
+}
+
+// associatePublicIPAddress returns whether the network interface of the
+// instance gets a public IPv4 address, nil for the setting of the subnet.
+func (d *Driver) associatePublicIPAddress() *bool {
+	switch {
+	case d.Ipv6AddressOnly:
+		// We cannot assign public IPv4 address in IPv6-only subnet
+		return aws.Bool(false)
+	case d.AssignPublicIP == assignPublicIPSubnet:
+		return nil
+	case d.AssignPublicIP != "":
+		return aws.Bool(d.AssignPublicIP == assignPublicIPTrue)
+	default:
+		return aws.Bool(!d.PrivateIPOnly)
+	}
+}
+
+// zoneName returns the name of an availability zone given as a letter, like
+// --amazonec2-zone, or as a full name.
+func (d *Driver) zoneName(zone string) string {
+	if d.Endpoint != "" || strings.HasPrefix(zone, d.Region) {
+		return zone
+	}
+	return d.Region + zone
+}
+
+// setZone sets the zone of the machine, stored like --amazonec2-zone, from
+// the name of an availability zone.
+func (d *Driver) setZone(az string) {
+	if d.Endpoint == "" {
+		d.Zone = strings.TrimPrefix(az, d.Region)
+		return
+	}
+	d.Zone = az
+}
+
+func (d *Driver) vpcSubnets() ([]*ec2.Subnet, error) {
+	subnets, err := d.getClient().DescribeSubnets(&ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{&d.VpcId},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subnets.Subnets, nil
+}
+
+// preferredZones returns the zones with a subnet, the ones of AZPreference
+// first in order of preference followed, with all, by the others.
+func (d *Driver) preferredZones(subnets []*ec2.Subnet, all bool) []string {
+	zones := map[string]bool{}
+	for _, subnet := range subnets {
+		zones[aws.StringValue(subnet.AvailabilityZone)] = true
+	}
+
+	preferred := []string{}
+	seen := map[string]bool{}
+	for _, zone := range d.AZPreference {
+		az := d.zoneName(zone)
+		if zones[az] && !seen[az] {
+			preferred = append(preferred, az)
+			seen[az] = true
+		}
+	}
+
+	if all {
+		others := []string{}
+		for az := range zones {
+			if !seen[az] {
+				others = append(others, az)
+			}
+		}
+		sort.Strings(others)
+		preferred = append(preferred, others...)
+	}
+
+	return preferred
+}
+
+// zoneSubnet returns the subnet of a zone to launch the instance in: the
+// default one of the zone if any, else the one with the most free addresses.
+func zoneSubnet(subnets []*ec2.Subnet, az string) *ec2.Subnet {
+	var best *ec2.Subnet
+	for _, subnet := range subnets {
+		if aws.StringValue(subnet.AvailabilityZone) != az {
+			continue
+		}
+		if aws.BoolValue(subnet.DefaultForAz) {
+			return subnet
+		}
+		if best == nil || aws.Int64Value(subnet.AvailableIpAddressCount) > aws.Int64Value(best.AvailableIpAddressCount) {
+			best = subnet
+		}
+	}
+	return best
+}
+
+func (d *Driver) useSubnet(subnets []*ec2.Subnet, az string) {
+	d.SubnetId = aws.StringValue(zoneSubnet(subnets, az).SubnetId)
+	d.setZone(az)
+	log.Infof("Using subnet %s in zone %s", d.SubnetId, az)
+}
+
+// checkPreferredSubnet picks the subnet of the VPC in the most preferred zone
+// of AZPreference.
+func (d *Driver) checkPreferredSubnet() error {
+	subnets, err := d.vpcSubnets()
+	if err != nil {
+		return err
+	}
+
+	zones := d.preferredZones(subnets, false)
+	if len(zones) == 0 {
+		return fmt.Errorf("unable to find a subnet of VPC %s in the zones: %s", d.VpcId, strings.Join(d.AZPreference, ", "))
+	}
+
+	d.useSubnet(subnets, zones[0])
+	return nil
+}
+
+// launchInstanceWithFallback launches the instance and, with AZFallback,
+// retries in the other zones of the VPC with a subnet as long as the zones are
+// out of capacity, the preferred zones first.
+func (d *Driver) launchInstanceWithFallback() error {
+	err := d.launchInstance()
+	if !d.shouldFallback(err) {
+		return err
+	}
+
+	subnets, listErr := d.vpcSubnets()
+	if listErr != nil {
+		log.Warnf("Couldn't list the subnets of VPC %s to retry in another zone: %s", d.VpcId, listErr)
+		return err
+	}
+
+	tried := map[string]bool{d.getRegionZone(): true}
+	for _, az := range d.preferredZones(subnets, true) {
+		if tried[az] {
+			continue
+		}
+		tried[az] = true
+
+		// The unfulfilled request could still launch an instance later.
+		if d.spotInstanceRequestId != "" {
+			if cancelErr := d.cancelSpotInstanceRequest(); cancelErr != nil {
+				log.Warnf("Couldn't cancel spot request %s: %s", d.spotInstanceRequestId, cancelErr)
+			}
+			d.spotInstanceRequestId = ""
+		}
+
+		log.Warnf("Zone %s is out of capacity, retrying in zone %s: %s", d.getRegionZone(), az, err)
+		d.useSubnet(subnets, az)
+		// The token of the failed launch would only return its error.
+		d.ClientToken = newClientToken()
+
+		if err = d.launchInstance(); !d.shouldFallback(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// shouldFallback tells whether a launch failed for lack of capacity in its
+// zone, before any instance was launched.
+func (d *Driver) shouldFallback(err error) bool {
+	return err != nil && d.AZFallback && d.InstanceId == "" && errors.Is(err, mcnerror.ErrCapacity)
+}
+
+// spotRequestError classifies the error of the wait for the spot request to
+// be fulfilled, as out of capacity when its status says so.
+func (d *Driver) spotRequestError(err error) error {
+	requests, descErr := d.getClient().DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []*string{&d.spotInstanceRequestId},
+	})
+	if descErr != nil || len(requests.SpotInstanceRequests) == 0 || requests.SpotInstanceRequests[0].Status == nil {
+		return err
+	}
+
+	status := requests.SpotInstanceRequests[0].Status
+	if !spotCapacityCodes[aws.StringValue(status.Code)] {
+		return err
+	}
+
+	return mcnerror.Capacity(fmt.Errorf("%v, request status %s: %s", err, aws.StringValue(status.Code), aws.StringValue(status.Message)))
+}
@@ -0,0 +1,194 @@
+package amazonec2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/rancher/machine/commands/commandstest"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeEC2Subnets() *fakeEC2Subnets {
+	subnet := func(id, az string, defaultForAz bool, free int64) *ec2.Subnet {
+		return &ec2.Subnet{
+			SubnetId:                aws.String(id),
+			VpcId:                   aws.String("vpc-1"),
+			AvailabilityZone:        aws.String(az),
+			DefaultForAz:            aws.Bool(defaultForAz),
+			AvailableIpAddressCount: aws.Int64(free),
+		}
+	}
+
+	return &fakeEC2Subnets{
+		fakeEC2Instance: &fakeEC2Instance{state: ec2.InstanceStateNameRunning},
+		subnets: []*ec2.Subnet{
+			subnet("subnet-a", "us-east-1a", true, 10),
+			subnet("subnet-b1", "us-east-1b", false, 10),
+			subnet("subnet-b2", "us-east-1b", false, 200),
+			subnet("subnet-c", "us-east-1c", true, 10),
+		},
+		runErrs: map[string]error{},
+	}
+}
+
+func newSubnetTestDriver(client *fakeEC2Subnets) *Driver {
+	driver := NewCustomTestDriver(client)
+	driver.Region = "us-east-1"
+	driver.VpcId = "vpc-1"
+	return driver
+}
+
+func TestCheckSubnetAZPreference(t *testing.T) {
+	driver := newSubnetTestDriver(newFakeEC2Subnets())
+	driver.AZPreference = []string{"d", "us-east-1b", "c"}
+
+	err := driver.checkSubnet()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "subnet-b2", driver.SubnetId)
+	assert.Equal(t, "b", driver.Zone)
+	assert.Equal(t, "us-east-1b", driver.getRegionZone())
+}
+
+func TestCheckSubnetAZPreferenceNotFound(t *testing.T) {
+	driver := newSubnetTestDriver(newFakeEC2Subnets())
+	driver.AZPreference = []string{"d", "e"}
+
+	err := driver.checkSubnet()
+
+	assert.EqualError(t, err, "unable to find a subnet of VPC vpc-1 in the zones: d, e")
+}
+
+func TestLaunchInstanceAZFallback(t *testing.T) {
+	client := newFakeEC2Subnets()
+	client.runErrs["us-east-1a"] = awserr.New("InsufficientInstanceCapacity", "no capacity", nil)
+	client.runErrs["us-east-1c"] = awserr.New("InsufficientInstanceCapacity", "no capacity", nil)
+	driver := newSubnetTestDriver(client)
+	driver.SubnetId, driver.Zone = "subnet-a", "a"
+	driver.AZPreference = []string{"c"}
+	driver.AZFallback = true
+	driver.ClientToken = "token"
+
+	err := driver.launchInstanceWithFallback()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1a subnet-a", "us-east-1c subnet-c", "us-east-1b subnet-b2"}, client.launched)
+	assert.Equal(t, "i-us-east-1b", driver.InstanceId)
+	assert.Equal(t, "subnet-b2", driver.SubnetId)
+	assert.Equal(t, "b", driver.Zone)
+	assert.NotEqual(t, "token", driver.ClientToken)
+}
+
+func TestLaunchInstanceAZFallbackExhausted(t *testing.T) {
+	client := newFakeEC2Subnets()
+	for _, az := range []string{"us-east-1a", "us-east-1b", "us-east-1c"} {
+		client.runErrs[az] = awserr.New("InsufficientInstanceCapacity", "no capacity", nil)
+	}
+	driver := newSubnetTestDriver(client)
+	driver.SubnetId, driver.Zone = "subnet-a", "a"
+	driver.AZFallback = true
+
+	err := driver.launchInstanceWithFallback()
+
+	assert.True(t, errors.Is(err, mcnerror.ErrCapacity))
+	assert.Len(t, client.launched, 3)
+	assert.Empty(t, driver.InstanceId)
+}
+
+func TestLaunchInstanceNoAZFallback(t *testing.T) {
+	client := newFakeEC2Subnets()
+	client.runErrs["us-east-1a"] = awserr.New("InsufficientInstanceCapacity", "no capacity", nil)
+	client.runErrs["us-east-1b"] = awserr.New("Unsupported", "instance type not supported in zone", nil)
+	driver := newSubnetTestDriver(client)
+	driver.SubnetId, driver.Zone = "subnet-a", "a"
+
+	err := driver.launchInstanceWithFallback()
+	assert.True(t, errors.Is(err, mcnerror.ErrCapacity))
+	assert.Equal(t, []string{"us-east-1a subnet-a"}, client.launched)
+
+	// Only the capacity errors fall back to another zone.
+	client.launched = nil
+	driver.AZFallback = true
+	err = driver.launchInstanceWithFallback()
+	assert.EqualError(t, err, "error launching instance: Unsupported: instance type not supported in zone")
+	assert.Equal(t, []string{"us-east-1a subnet-a", "us-east-1b subnet-b2"}, client.launched)
+}
+
+func TestSubnetSetsVPCAndZone(t *testing.T) {
+	driver := NewCustomTestDriver(newFakeEC2Subnets())
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	options := &commandstest.FakeFlagger{
+		Data: map[string]interface{}{
+			"name":                "test",
+			"amazonec2-region":    "us-east-1",
+			"amazonec2-zone":      "a",
+			"amazonec2-subnet-id": "subnet-c",
+		},
+	}
+
+	err := driver.SetConfigFromFlags(options)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vpc-1", driver.VpcId)
+	assert.Equal(t, "c", driver.Zone)
+}
+
+func TestSubnetOfAnotherVPC(t *testing.T) {
+	driver := NewCustomTestDriver(newFakeEC2Subnets())
+	driver.awsCredentialsFactory = NewValidAwsCredentials
+	options := &commandstest.FakeFlagger{
+		Data: map[string]interface{}{
+			"name":                "test",
+			"amazonec2-region":    "us-east-1",
+			"amazonec2-vpc-id":    "vpc-2",
+			"amazonec2-subnet-id": "subnet-c",
+		},
+	}
+
+	err := driver.SetConfigFromFlags(options)
+
+	assert.EqualError(t, err, "SubnetId: subnet-c does not belong to VpcId: vpc-2")
+}
+
+func TestValidateAssignPublicIP(t *testing.T) {
+	driver := NewTestDriver()
+	for _, value := range []string{"", "true", "subnet"} {
+		driver.AssignPublicIP = value
+		assert.NoError(t, driver.validateAssignPublicIP(), value)
+	}
+
+	driver.AssignPublicIP = "false"
+	assert.EqualError(t, driver.validateAssignPublicIP(), "--amazonec2-assign-public-ip false requires --amazonec2-private-address-only or --amazonec2-use-private-address")
+	driver.UsePrivateIP = true
+	assert.NoError(t, driver.validateAssignPublicIP())
+
+	driver.AssignPublicIP = "yes"
+	assert.EqualError(t, driver.validateAssignPublicIP(), `invalid --amazonec2-assign-public-ip "yes", must be true, false or subnet`)
+
+	driver.AssignPublicIP = "true"
+	driver.PrivateIPOnly = true
+	assert.EqualError(t, driver.validateAssignPublicIP(), "--amazonec2-assign-public-ip true conflicts with --amazonec2-private-address-only")
+}
+
+func TestAssociatePublicIPAddress(t *testing.T) {
+	driver := NewTestDriver()
+	assert.Equal(t, aws.Bool(true), driver.associatePublicIPAddress())
+
+	driver.PrivateIPOnly = true
+	assert.Equal(t, aws.Bool(false), driver.associatePublicIPAddress())
+
+	driver.AssignPublicIP = "subnet"
+	assert.Nil(t, driver.associatePublicIPAddress())
+
+	driver.PrivateIPOnly = false
+	driver.AssignPublicIP = "false"
+	assert.Equal(t, aws.Bool(false), driver.associatePublicIPAddress())
+
+	driver.AssignPublicIP = "true"
+	driver.Ipv6AddressOnly = true
+	assert.Equal(t, aws.Bool(false), driver.associatePublicIPAddress())
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/rancher/machine/drivers/azure/azureutil"
@@ -34,6 +35,7 @@ const (
 	defaultAzureSubnetPrefix    = "192.168.0.0/16"
 	defaultStorageType          = string(storage.StandardLRS)
 	defaultAzureAvailabilitySet = "docker-machine"
+	defaultAzurePPGType         = string(compute.Standard)
 )
 
 const (
@@ -51,6 +53,7 @@ const (
 	flAzureSubnetPrefix              = "azure-subnet-prefix"
 	flAzureAvailabilitySet           = "azure-availability-set"
 	flAzureManagedDisks              = "azure-managed-disks"
+	flAzureDiskEncryptionSet         = "azure-disk-encryption-set"
 	flAzureFaultDomainCount          = "azure-fault-domain-count"
 	flAzureUpdateDomainCount         = "azure-update-domain-count"
 	flAzureDiskSize                  = "azure-disk-size"
@@ -70,6 +73,8 @@ const (
 	flAzureAcceleratedNetworking     = "azure-accelerated-networking"
 	flAzureEnablePublicIPStandardSKU = "azure-enable-public-ip-standard-sku"
 	flAzureAvailabilityZones         = "azure-availability-zone"
+	flAzureProximityPlacementGroup   = "azure-proximity-placement-group"
+	flAzurePPGType                   = "azure-proximity-placement-group-type"
 )
 
 const (
@@ -100,6 +105,7 @@ type Driver struct {
 	NSG                       string
 	Plan                      string
 	ManagedDisks              bool
+	DiskEncryptionSet         string
 	FaultCount                int
 	UpdateCount               int
 	DiskSize                  int
@@ -109,6 +115,12 @@ type Driver struct {
 	AvailabilityZone          string
 	EnablePublicIPStandardSKU bool
 
+	// ProximityPlacementGroup is the proximity placement group of the
+	// virtual machine and of its availability set, created with
+	// ProximityPlacementGroupType if it doesn't exist.
+	ProximityPlacementGroup     string
+	ProximityPlacementGroupType string
+
 	OpenPorts      []string
 	PrivateIPAddr  string
 	UsePrivateIP   bool
@@ -219,6 +231,17 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			EnvVar: "AZURE_AVAILABILITY_SET",
 			Value:  defaultAzureAvailabilitySet,
 		},
+		mcnflag.StringFlag{
+			Name:   flAzureProximityPlacementGroup,
+			Usage:  "Azure Proximity Placement Group to place the virtual machine and its availability set into, created if it doesn't exist",
+			EnvVar: "AZURE_PROXIMITY_PLACEMENT_GROUP",
+		},
+		mcnflag.StringFlag{
+			Name:   flAzurePPGType,
+			Usage:  "Type of a created Azure Proximity Placement Group",
+			EnvVar: "AZURE_PROXIMITY_PLACEMENT_GROUP_TYPE",
+			Value:  defaultAzurePPGType,
+		},
 		mcnflag.StringFlag{
 			Name:   flAzureNSG,
 			Usage:  "Azure Network Security Group to assign this node to (accepts either a name or resource ID, default is to create a new NSG for each machine)",
@@ -234,6 +257,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Configures VM and availability set for managed disks",
 			EnvVar: "AZURE_MANAGED_DISKS",
 		},
+		mcnflag.StringFlag{
+			Name:   flAzureDiskEncryptionSet,
+			Usage:  "Resource ID of a disk encryption set in the location of the VM, encrypting its managed disks with customer-managed keys",
+			EnvVar: "AZURE_DISK_ENCRYPTION_SET",
+		},
 		mcnflag.IntFlag{
 			Name:   flAzureFaultDomainCount,
 			Usage:  "Fault domain count to use for availability set",
@@ -405,11 +433,20 @@ func (d *Driver) SetConfigFromFlags(fl drivers.DriverOptions) error {
 	d.DNSLabel = fl.String(flAzureDNSLabel)
 	d.CustomDataFile = fl.String(flAzureCustomData)
 	d.ManagedDisks = fl.Bool(flAzureManagedDisks)
+	d.DiskEncryptionSet = fl.String(flAzureDiskEncryptionSet)
 	d.FaultCount = fl.Int(flAzureFaultDomainCount)
 	d.UpdateCount = fl.Int(flAzureUpdateDomainCount)
 	d.DiskSize = fl.Int(flAzureDiskSize)
 	d.NSG = fl.String(flAzureNSG)
 	d.Plan = fl.String(flAzurePlan)
+	d.ProximityPlacementGroup = fl.String(flAzureProximityPlacementGroup)
+	if d.ProximityPlacementGroup != "" {
+		ppgType, err := parseProximityPlacementGroupType(fl.String(flAzurePPGType))
+		if err != nil {
+			return fmt.Errorf("%v (--%s)", err, flAzurePPGType)
+		}
+		d.ProximityPlacementGroupType = string(ppgType)
+	}
 
 	d.ClientID = fl.String(flAzureClientID)
 	d.ClientSecret = fl.String(flAzureClientSecret)
@@ -434,6 +471,10 @@ func (d *Driver) PreCreateCheck() (err error) {
 		}
 	}
 
+	if d.DiskEncryptionSet != "" && !d.ManagedDisks {
+		return fmt.Errorf("Managed Disks must be used to encrypt the disks with a disk encryption set (--azure-managed-disks)")
+	}
+
 	if d.AvailabilityZone != "" {
 		if !d.ManagedDisks {
 			return fmt.Errorf("Managed Disks must be used when creating resources in specific Availability Zones (--azure-managed-disks)")
@@ -464,6 +505,12 @@ func (d *Driver) PreCreateCheck() (err error) {
 		return err
 	}
 
+	if d.DiskEncryptionSet != "" {
+		if err := c.CheckDiskEncryptionSet(ctx, d.DiskEncryptionSet, d.Location); err != nil {
+			return err
+		}
+	}
+
 	// Validate if firewall rules can be read correctly
 	d.deploymentCtx.FirewallRules, err = d.getSecurityRules(d.OpenPorts)
 	if err != nil {
@@ -519,6 +566,11 @@ func (d *Driver) Create() error {
 	if err := c.CreateResourceGroup(ctx, d.ResourceGroup, d.Location); err != nil {
 		return err
 	}
+	if d.ProximityPlacementGroup != "" {
+		if err := c.CreateProximityPlacementGroupIfNotExists(ctx, d.deploymentCtx, d.ResourceGroup, d.ProximityPlacementGroup, d.Location, d.ProximityPlacementGroupType); err != nil {
+			return err
+		}
+	}
 	// availability sets and availability zones cannot be used together. The presence of an Availability Zone indicates that an Availability set should not be created / used
 	if d.AvailabilityZone == "" {
 		if err := c.CreateAvailabilitySetIfNotExists(ctx, d.deploymentCtx, d.ResourceGroup, d.AvailabilitySet, d.Location, d.ManagedDisks, int32(d.FaultCount), int32(d.UpdateCount)); err != nil {
@@ -555,9 +607,9 @@ func (d *Driver) Create() error {
 	if err := d.generateSSHKey(d.deploymentCtx); err != nil {
 		return err
 	}
-	if err := c.CreateVirtualMachine(ctx, d.ResourceGroup, d.naming().VM(), d.Location, d.Size, d.deploymentCtx.AvailabilitySetID,
+	if err := c.CreateVirtualMachine(ctx, d.ResourceGroup, d.naming().VM(), d.Location, d.Size, d.deploymentCtx.AvailabilitySetID, d.deploymentCtx.ProximityPlacementGroupID,
 		d.deploymentCtx.NetworkInterfaceID, d.BaseDriver.SSHUser, d.deploymentCtx.SSHPublicKey, d.Image, d.Plan, customData, d.deploymentCtx.StorageAccount,
-		d.ManagedDisks, d.StorageType, int32(d.DiskSize), d.Tags, d.AvailabilityZone); err != nil {
+		d.ManagedDisks, d.StorageType, int32(d.DiskSize), d.Tags, d.AvailabilityZone, d.DiskEncryptionSet); err != nil {
 		return err
 	}
 	ip, err := d.GetIP()
@@ -610,6 +662,11 @@ func (d *Driver) Remove() error {
 			return err
 		}
 	}
+	if d.ProximityPlacementGroup != "" {
+		if err := c.CleanupProximityPlacementGroupIfExists(ctx, d.ResourceGroup, d.ProximityPlacementGroup); err != nil {
+			return err
+		}
+	}
 	if err := c.CleanupSubnetIfExists(ctx, d.ResourceGroup, d.VirtualNetwork, d.SubnetName); err != nil {
 		return err
 	}
@@ -732,6 +789,12 @@ func (d *Driver) Restart() error {
 	return c.RestartVirtualMachine(ctx, d.ResourceGroup, d.naming().VM())
 }
 
+// Reboot reboots the VM with the restart operation of Azure, which Restart
+// already uses.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
 // Kill stops the virtual machine role instance.
 func (d *Driver) Kill() error {
 	if err := d.checkLegacyDriver(true); err != nil {
@@ -456,6 +456,27 @@ func (a AzureClient) VirtualMachineExists(ctx context.Context, resourceGroup, na
 	return checkResourceExistsFromError(err)
 }
 
+// CheckDiskEncryptionSet checks that the disk encryption set with the given
+// resource ID can be read and is in the location of the VM, which is required
+// to encrypt its disks with it.
+func (a AzureClient) CheckDiskEncryptionSet(ctx context.Context, id, location string) error {
+	resource, err := azure.ParseResourceID(id)
+	if err != nil {
+		return fmt.Errorf("unable to parse the resource ID of the disk encryption set: %s", id)
+	}
+
+	des, err := a.diskEncryptionSetsClient().Get(ctx, resource.ResourceGroup, resource.ResourceName)
+	if err != nil {
+		return fmt.Errorf("the disk encryption set %s is not accessible: %s", id, err)
+	}
+
+	if desLocation := to.String(des.Location); !strings.EqualFold(desLocation, location) {
+		return fmt.Errorf("the disk encryption set %s is in %s, it can only encrypt the disks of VMs in the same location, not %s", id, desLocation, location)
+	}
+
+	return nil
+}
+
 // DeleteVirtualMachineIfExists checks to see if a VM exists and deletes it accordingly
 // It then
 func (a AzureClient) DeleteVirtualMachineIfExists(ctx context.Context, resourceGroup, name string) error {
@@ -562,9 +583,9 @@ func (a AzureClient) removeOSDiskBlob(ctx context.Context, resourceGroup, vmName
 }
 
 // CreateVirtualMachine creates a VM according to the specifications and adds an SSH key to access the VM
-func (a AzureClient) CreateVirtualMachine(ctx context.Context, resourceGroup, name, location, size, availabilitySetID, networkInterfaceID,
+func (a AzureClient) CreateVirtualMachine(ctx context.Context, resourceGroup, name, location, size, availabilitySetID, proximityPlacementGroupID, networkInterfaceID,
 	username, sshPublicKey, imageName, imagePlan, customData string, storageAccount *storage.AccountProperties, isManaged bool,
-	storageType string, diskSize int32, tags map[string]*string, availabilityZone string) error {
+	storageType string, diskSize int32, tags map[string]*string, availabilityZone, diskEncryptionSetID string) error {
 	// TODO: "VM created from Image cannot have blob based disks. All disks have to be managed disks."
 	imgReference, err := a.getImageReference(ctx, imageName, location)
 	if err != nil {
@@ -630,7 +651,7 @@ func (a AzureClient) CreateVirtualMachine(ctx context.Context, resourceGroup, na
 			OsProfile: osProfile,
 			StorageProfile: &compute.StorageProfile{
 				ImageReference: imgReference,
-				OsDisk:         getOSDisk(name, storageAccount, isManaged, storageType, diskSize),
+				OsDisk:         getOSDisk(name, storageAccount, isManaged, storageType, diskSize, diskEncryptionSetID),
 			},
 		},
 		Plan: imagePurchasePlan,
@@ -648,6 +669,12 @@ func (a AzureClient) CreateVirtualMachine(ctx context.Context, resourceGroup, na
 		vm.Zones = to.StringSlicePtr([]string{availabilityZone})
 	}
 
+	if proximityPlacementGroupID != "" {
+		vm.VirtualMachineProperties.ProximityPlacementGroup = &compute.SubResource{
+			ID: to.StringPtr(proximityPlacementGroupID),
+		}
+	}
+
 	future, err := virtualMachinesClient.CreateOrUpdate(ctx, resourceGroup, name, vm)
 	if err != nil {
 		return err
@@ -692,8 +719,8 @@ func (a AzureClient) getImagePurchasePlan(ctx context.Context, plan string) (*co
 }
 
 // GetOSDisk creates and returns pointer to a disk that is configured for either managed or unmanaged disks depending
-// on setting.
-func getOSDisk(name string, account *storage.AccountProperties, isManaged bool, storageType string, diskSize int32) *compute.OSDisk {
+// on setting. Managed disks are encrypted with the customer-managed keys of the disk encryption set, if any.
+func getOSDisk(name string, account *storage.AccountProperties, isManaged bool, storageType string, diskSize int32, diskEncryptionSetID string) *compute.OSDisk {
 	var osdisk *compute.OSDisk
 	if isManaged {
 		osdisk = &compute.OSDisk{
@@ -705,6 +732,11 @@ func getOSDisk(name string, account *storage.AccountProperties, isManaged bool,
 			},
 			DiskSizeGB: to.Int32Ptr(diskSize),
 		}
+		if diskEncryptionSetID != "" {
+			osdisk.ManagedDisk.DiskEncryptionSet = &compute.DiskEncryptionSetParameters{
+				ID: to.StringPtr(diskEncryptionSetID),
+			}
+		}
 	} else {
 		osDiskBlobURL := osDiskStorageBlobURL(account, name)
 		log.Debugf("OS disk blob will be placed at: %s", osDiskBlobURL)
@@ -752,17 +784,24 @@ func (a AzureClient) CreateAvailabilitySetIfNotExists(ctx context.Context, deplo
 		if isManaged {
 			skuName = "Aligned"
 		}
-		avSet, err = a.availabilitySetsClient().CreateOrUpdate(ctx, resourceGroup, name,
-			compute.AvailabilitySet{
-				Location: to.StringPtr(location),
-				AvailabilitySetProperties: &compute.AvailabilitySetProperties{
-					PlatformFaultDomainCount:  to.Int32Ptr(faultCount),
-					PlatformUpdateDomainCount: to.Int32Ptr(updateCount),
-				},
-				Sku: &compute.Sku{
-					Name: to.StringPtr(skuName),
-				},
-			})
+		avSet = compute.AvailabilitySet{
+			Location: to.StringPtr(location),
+			AvailabilitySetProperties: &compute.AvailabilitySetProperties{
+				PlatformFaultDomainCount:  to.Int32Ptr(faultCount),
+				PlatformUpdateDomainCount: to.Int32Ptr(updateCount),
+			},
+			Sku: &compute.Sku{
+				Name: to.StringPtr(skuName),
+			},
+		}
+		// the availability set of a virtual machine must be in the same
+		// proximity placement group as the virtual machine
+		if deploymentCtx.ProximityPlacementGroupID != "" {
+			avSet.AvailabilitySetProperties.ProximityPlacementGroup = &compute.SubResource{
+				ID: to.StringPtr(deploymentCtx.ProximityPlacementGroupID),
+			}
+		}
+		avSet, err = a.availabilitySetsClient().CreateOrUpdate(ctx, resourceGroup, name, avSet)
 		if err != nil {
 			return err
 		}
@@ -779,12 +818,61 @@ func (a AzureClient) CreateAvailabilitySetIfNotExists(ctx context.Context, deplo
 		if !isManaged && to.String(avSet.Sku.Name) != "Classic" {
 			return fmt.Errorf("cannot convert managed availability set %s to non-managed availability set", name)
 		}
+		var ppgID string
+		if avSet.AvailabilitySetProperties != nil && avSet.AvailabilitySetProperties.ProximityPlacementGroup != nil {
+			ppgID = to.String(avSet.AvailabilitySetProperties.ProximityPlacementGroup.ID)
+		}
+		if !strings.EqualFold(ppgID, deploymentCtx.ProximityPlacementGroupID) {
+			return fmt.Errorf("availability set %s is not in the proximity placement group of the virtual machine", name)
+		}
 	}
 
 	deploymentCtx.AvailabilitySetID = to.String(avSet.ID)
 	return nil
 }
 
+// CreateProximityPlacementGroupIfNotExists creates the proximity placement
+// group if it does not already exist. A created group is tagged as managed by
+// rancher so that it is removed along with its last virtual machine.
+func (a AzureClient) CreateProximityPlacementGroupIfNotExists(ctx context.Context, deploymentCtx *DeploymentContext, resourceGroup, name, location, groupType string) error {
+	f := logutil.Fields{"name": name}
+	log.Info("Configuring proximity placement group.", f)
+
+	ppg, err := a.proximityPlacementGroupsClient().Get(ctx, resourceGroup, name, "")
+	exists, err := checkResourceExistsFromError(err)
+	if err != nil {
+		return fmt.Errorf("error getting proximity placement group: %v", err)
+	}
+	if exists {
+		log.Infof("Proximity placement group [%s] exists, will ignore configured type", name)
+	} else {
+		log.Info("Creating proximity placement group.", f)
+		ppg, err = a.proximityPlacementGroupsClient().CreateOrUpdate(ctx, resourceGroup, name,
+			compute.ProximityPlacementGroup{
+				Location: to.StringPtr(location),
+				ProximityPlacementGroupProperties: &compute.ProximityPlacementGroupProperties{
+					ProximityPlacementGroupType: compute.ProximityPlacementGroupType(groupType),
+				},
+				Tags: map[string]*string{
+					ManagedByRancherKey: to.StringPtr("true"),
+				},
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	deploymentCtx.ProximityPlacementGroupID = to.String(ppg.ID)
+	return nil
+}
+
+// CleanupProximityPlacementGroupIfExists removes a proximity placement group
+// created by rancher if there are no virtual machines or availability sets in
+// it anymore.
+func (a AzureClient) CleanupProximityPlacementGroupIfExists(ctx context.Context, resourceGroup, name string) error {
+	return a.cleanupResourceIfExists(ctx, &ppgCleanup{rg: resourceGroup, name: name})
+}
+
 // CleanupAvailabilitySetIfExists removes an availability set if there are no
 // virtual machines attached to it. Note that this method is not safe for
 // multiple concurrent writers, in case of races, deployment of a machine could
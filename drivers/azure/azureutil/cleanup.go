@@ -154,6 +154,42 @@ func (c *avSetCleanup) CanBeDeleted(ctx context.Context, a AzureClient) bool {
 	return c.ref.AvailabilitySetProperties.VirtualMachines == nil || len(*c.ref.AvailabilitySetProperties.VirtualMachines) == 0
 }
 
+// ppgCleanup manages cleanup of Proximity Placement Group resources.
+type ppgCleanup struct {
+	rg, name string
+	ref      compute.ProximityPlacementGroup
+}
+
+func (c *ppgCleanup) Get(ctx context.Context, a AzureClient) (err error) {
+	serviceClient := a.proximityPlacementGroupsClient()
+	c.ref, err = serviceClient.Get(ctx, c.rg, c.name, "")
+	return err
+}
+
+func (c *ppgCleanup) Delete(ctx context.Context, a AzureClient) error {
+	serviceClient := a.proximityPlacementGroupsClient()
+	_, err := serviceClient.Delete(ctx, c.rg, c.name)
+	return err
+}
+
+func (c *ppgCleanup) ResourceType() string { return "Proximity Placement Group" }
+
+func (c *ppgCleanup) LogFields() logutil.Fields { return logutil.Fields{"name": c.name} }
+
+func (c *ppgCleanup) CanBeDeleted(ctx context.Context, a AzureClient) bool {
+	c.Get(ctx, a) // updates c.ref
+	if _, ok := c.ref.Tags[ManagedByRancherKey]; !ok {
+		return false
+	}
+	props := c.ref.ProximityPlacementGroupProperties
+	if props == nil {
+		return true
+	}
+	return (props.VirtualMachines == nil || len(*props.VirtualMachines) == 0) &&
+		(props.AvailabilitySets == nil || len(*props.AvailabilitySets) == 0) &&
+		(props.VirtualMachineScaleSets == nil || len(*props.VirtualMachineScaleSets) == 0)
+}
+
 type nsgCleanup struct {
 	rg, name   string
 	usedInPool bool
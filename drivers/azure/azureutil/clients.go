@@ -140,6 +140,26 @@ func (a AzureClient) virtualMachinesClient() compute.VirtualMachinesClient {
 	return c
 }
 
+func (a AzureClient) diskEncryptionSetsClient() compute.DiskEncryptionSetsClient {
+	c := compute.NewDiskEncryptionSetsClientWithBaseURI(a.env.ResourceManagerEndpoint, a.subscriptionID)
+	c.Authorizer = a.auth
+	c.Client.UserAgent += fmt.Sprintf(";docker-machine/%s", version.Version)
+	c.RequestInspector = withInspection()
+	c.ResponseInspector = byInspecting()
+	c.PollingDelay = defaultClientPollingDelay
+	return c
+}
+
+func (a AzureClient) proximityPlacementGroupsClient() compute.ProximityPlacementGroupsClient {
+	c := compute.NewProximityPlacementGroupsClientWithBaseURI(a.env.ResourceManagerEndpoint, a.subscriptionID)
+	c.Authorizer = a.auth
+	c.Client.UserAgent += fmt.Sprintf(";docker-machine/%s", version.Version)
+	c.RequestInspector = withInspection()
+	c.ResponseInspector = byInspecting()
+	c.PollingDelay = defaultClientPollingDelay
+	return c
+}
+
 func (a AzureClient) availabilitySetsClient() compute.AvailabilitySetsClient {
 	c := compute.NewAvailabilitySetsClientWithBaseURI(a.env.ResourceManagerEndpoint, a.subscriptionID)
 	c.Authorizer = a.auth
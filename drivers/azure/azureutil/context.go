@@ -17,4 +17,8 @@ type DeploymentContext struct {
 	SSHPublicKey           string
 	AvailabilitySetID      string
 	FirewallRules          *[]network.SecurityRule
+
+	// ProximityPlacementGroupID is empty unless the machine is placed in
+	// a proximity placement group.
+	ProximityPlacementGroupID string
 }
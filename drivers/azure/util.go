@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-12-01/network"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -232,3 +233,14 @@ func parseSecurityRuleProtocol(proto string) (network.SecurityRuleProtocol, erro
 		return "", fmt.Errorf("invalid protocol %s", proto)
 	}
 }
+
+// parseProximityPlacementGroupType parses a proximity placement group type
+// string and returns error if the type is not supported
+func parseProximityPlacementGroupType(ppgType string) (compute.ProximityPlacementGroupType, error) {
+	for _, t := range compute.PossibleProximityPlacementGroupTypeValues() {
+		if strings.EqualFold(ppgType, string(t)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid proximity placement group type %s", ppgType)
+}
@@ -3,6 +3,7 @@ package azure
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-12-01/network"
 	"github.com/stretchr/testify/assert"
 )
@@ -29,3 +30,26 @@ func TestParseSecurityRuleProtocol(t *testing.T) {
 		}
 	}
 }
+
+func TestParseProximityPlacementGroupType(t *testing.T) {
+	tests := []struct {
+		raw          string
+		expectedType compute.ProximityPlacementGroupType
+		expectedErr  bool
+	}{
+		{"Standard", compute.Standard, false},
+		{"standard", compute.Standard, false},
+		{"Ultra", compute.Ultra, false},
+		{"cluster", "", true},
+	}
+
+	for _, tc := range tests {
+		ppgType, err := parseProximityPlacementGroupType(tc.raw)
+		assert.Equal(t, tc.expectedType, ppgType)
+		if tc.expectedErr {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package digitalocean
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// billedHoursPerMonth is the number of hours after which a droplet is billed
+// its monthly price.
+const billedHoursPerMonth = 672
+
+// monthlyPrices are the monthly prices in USD of the basic droplet plans,
+// which are the same in all the regions.
+var monthlyPrices = map[string]float64{
+	"s-1vcpu-512mb-10gb": 4,
+	"s-1vcpu-1gb":        6,
+	"s-1vcpu-2gb":        12,
+	"s-2vcpu-2gb":        18,
+	"s-2vcpu-4gb":        24,
+	"s-4vcpu-8gb":        48,
+	"s-8vcpu-16gb":       96,
+	"g-2vcpu-8gb":        63,
+	"c-2":                42,
+	"c-4":                84,
+	"m-2vcpu-16gb":       84,
+}
+
+// EstimateCost estimates the cost of the droplet from the fixed price of its
+// plan.
+func (d *Driver) EstimateCost() (*drivers.CostEstimate, error) {
+	monthly, ok := monthlyPrices[d.Size]
+	if !ok {
+		return nil, mcnerror.NotSupported(fmt.Errorf("no pricing data for size %s", d.Size))
+	}
+
+	return &drivers.CostEstimate{
+		Hourly:      monthly / billedHoursPerMonth,
+		Monthly:     monthly,
+		Currency:    "USD",
+		Description: fmt.Sprintf("%s in %s", d.Size, d.Region),
+	}, nil
+}
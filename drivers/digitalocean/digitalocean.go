@@ -3,6 +3,7 @@ package digitalocean
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,9 +13,11 @@ import (
 	"time"
 
 	"github.com/digitalocean/godo"
+	"github.com/rancher/machine/drivers/driverutil"
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/mcnutils"
 	"github.com/rancher/machine/libmachine/ssh"
@@ -28,6 +31,8 @@ type Driver struct {
 	DropletID         int
 	DropletName       string
 	Image             string
+	ImageName         string
+	ImageID           int
 	Region            string
 	SSHKeyID          int
 	SSHKeyFingerprint string
@@ -48,6 +53,8 @@ const (
 	defaultImage   = "ubuntu-20-04-x64"
 	defaultRegion  = "nyc3"
 	defaultSize    = "s-1vcpu-1gb"
+
+	maxUserDataSize = 64 << 10
 )
 
 // GetCreateFlags registers the flags this driver adds to
@@ -87,6 +94,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Digital Ocean Image",
 			Value:  defaultImage,
 		},
+		mcnflag.StringFlag{
+			EnvVar: "DIGITALOCEAN_IMAGE_NAME",
+			Name:   "digitalocean-image-name",
+			Usage:  "Name of a custom image or snapshot of the account, resolved to its id at create time",
+		},
 		mcnflag.StringFlag{
 			EnvVar: "DIGITALOCEAN_REGION",
 			Name:   "digitalocean-region",
@@ -112,7 +124,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		mcnflag.BoolFlag{
 			EnvVar: "DIGITALOCEAN_BACKUPS",
 			Name:   "digitalocean-backups",
-			Usage:  "enable backups for droplet",
+			Usage:  "enable automated backups for droplet, a paid feature",
 		},
 		mcnflag.StringFlag{
 			EnvVar: "DIGITALOCEAN_USERDATA",
@@ -122,7 +134,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 		mcnflag.BoolFlag{
 			EnvVar: "DIGITALOCEAN_MONITORING",
 			Name:   "digitalocean-monitoring",
-			Usage:  "enable monitoring for droplet",
+			Usage:  "enable the monitoring agent for droplet",
 		},
 		mcnflag.StringFlag{
 			EnvVar: "DIGITALOCEAN_TAGS",
@@ -183,6 +195,7 @@ func (d *Driver) UnmarshalJSON(data []byte) error {
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.AccessToken = flags.String("digitalocean-access-token")
 	d.Image = flags.String("digitalocean-image")
+	d.ImageName = flags.String("digitalocean-image-name")
 	d.Region = flags.String("digitalocean-region")
 	d.Size = flags.String("digitalocean-size")
 	d.IPv6 = flags.Bool("digitalocean-ipv6")
@@ -198,10 +211,20 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 
 	d.SetSwarmConfigFromFlags(flags)
 
+	if d.UserDataFile != "" {
+		if err := driverutil.CheckUserDataSize(d.UserDataFile, maxUserDataSize, false); err != nil {
+			return err
+		}
+	}
+
 	if d.AccessToken == "" {
 		return fmt.Errorf("digitalocean driver requires the --digitalocean-access-token option")
 	}
 
+	if d.ImageName != "" && d.Image != defaultImage {
+		return fmt.Errorf("--digitalocean-image and --digitalocean-image-name are mutually exclusive")
+	}
+
 	return nil
 }
 
@@ -236,6 +259,17 @@ func (d *Driver) PreCreateCheck() error {
 	return fmt.Errorf("digitalocean requires a valid region")
 }
 
+// CheckCredentials checks the access token by reading the account it belongs
+// to, and returns its email.
+func (d *Driver) CheckCredentials() (string, error) {
+	account, resp, err := d.getClient().Account.Get(context.TODO())
+	if err != nil {
+		return "", classifyError(resp, err)
+	}
+
+	return account.Email, nil
+}
+
 func (d *Driver) Create() error {
 	var userdata string
 	if d.UserDataFile != "" {
@@ -246,6 +280,10 @@ func (d *Driver) Create() error {
 		userdata = string(buf)
 	}
 
+	if err := d.resolveImageName(); err != nil {
+		return err
+	}
+
 	log.Infof("Creating SSH key...")
 
 	key, err := d.createSSHKey()
@@ -260,7 +298,7 @@ func (d *Driver) Create() error {
 	client := d.getClient()
 
 	createRequest := &godo.DropletCreateRequest{
-		Image:             godo.DropletCreateImage{Slug: d.Image},
+		Image:             d.createImage(),
 		Name:              d.MachineName,
 		Region:            d.Region,
 		Size:              d.Size,
@@ -273,9 +311,13 @@ func (d *Driver) Create() error {
 		Tags:              d.getTags(),
 	}
 
-	newDroplet, _, err := client.Droplets.Create(context.TODO(), createRequest)
+	newDroplet, resp, err := client.Droplets.Create(context.TODO(), createRequest)
 	if err != nil {
-		return err
+		// The machine isn't saved, the key would be left behind.
+		if deleteErr := d.deleteSSHKey(); deleteErr != nil {
+			log.Warnf("Failed to delete the SSH key %d: %s", d.SSHKeyID, deleteErr)
+		}
+		return createDropletError(resp, err, d.Backups)
 	}
 
 	d.DropletID = newDroplet.ID
@@ -362,6 +404,24 @@ func (d *Driver) createSSHKey() (*godo.Key, error) {
 	return key, nil
 }
 
+// deleteSSHKey deletes the SSH key uploaded for the machine, the keys given
+// by fingerprint being the user's.
+func (d *Driver) deleteSSHKey() error {
+	if d.SSHKeyFingerprint != "" || d.SSHKeyID == 0 {
+		return nil
+	}
+
+	if resp, err := d.getClient().Keys.DeleteByID(context.TODO(), d.SSHKeyID); err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			log.Infof("Digital Ocean SSH key doesn't exist, assuming it is already deleted")
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (d *Driver) GetURL() (string, error) {
 	if err := drivers.MustBeRunning(d); err != nil {
 		return "", err
@@ -378,10 +438,11 @@ func (d *Driver) GetURL() (string, error) {
 func (d *Driver) GetState() (state.State, error) {
 	droplet, resp, err := d.getClient().Droplets.Get(context.TODO(), d.DropletID)
 	if err != nil {
-		if resp == nil || resp.StatusCode != http.StatusNotFound {
-			return state.Error, err
+		err = classifyError(resp, err)
+		if errors.Is(err, mcnerror.ErrInstanceNotFound) {
+			return state.NotFound, err
 		}
-		return state.None, fmt.Errorf("machine %v not found", d.MachineName)
+		return state.Error, err
 	}
 
 	switch droplet.Status {
@@ -410,6 +471,12 @@ func (d *Driver) Restart() error {
 	return err
 }
 
+// Reboot reboots the droplet with the reboot action, which Restart already
+// uses.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
 func (d *Driver) Kill() error {
 	_, _, err := d.getClient().DropletActions.PowerOff(context.TODO(), d.DropletID)
 	return err
@@ -417,14 +484,8 @@ func (d *Driver) Kill() error {
 
 func (d *Driver) Remove() error {
 	client := d.getClient()
-	if d.SSHKeyFingerprint == "" && d.SSHKeyID != 0 {
-		if resp, err := client.Keys.DeleteByID(context.TODO(), d.SSHKeyID); err != nil {
-			if resp != nil && resp.StatusCode == 404 {
-				log.Infof("Digital Ocean SSH key doesn't exist, assuming it is already deleted")
-			} else {
-				return err
-			}
-		}
+	if err := d.deleteSSHKey(); err != nil {
+		return err
 	}
 	if resp, err := client.Droplets.Delete(context.TODO(), d.DropletID); err != nil {
 		if resp != nil && resp.StatusCode == 404 {
@@ -437,6 +498,35 @@ func (d *Driver) Remove() error {
 	return nil
 }
 
+// classifyError wraps the API errors with the matching libmachine error kind.
+func classifyError(resp *godo.Response, err error) error {
+	if resp == nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return mcnerror.NotFound(err)
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return mcnerror.MarkTransient(err)
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return mcnerror.Unauthorized(err)
+	}
+
+	return err
+}
+
+// createDropletError explains the refusal of the API to enable backups, which
+// accounts without billing set up can't use.
+func createDropletError(resp *godo.Response, err error, backups bool) error {
+	var errResp *godo.ErrorResponse
+	if backups && errors.As(err, &errResp) && strings.Contains(strings.ToLower(errResp.Message), "backup") {
+		return fmt.Errorf("error enabling backups for droplet, check that the account can use this paid feature or create it without --digitalocean-backups: %w", err)
+	}
+
+	return classifyError(resp, err)
+}
+
 func (d *Driver) getClient() *godo.Client {
 	token := &oauth2.Token{AccessToken: d.AccessToken}
 	tokenSource := oauth2.StaticTokenSource(token)
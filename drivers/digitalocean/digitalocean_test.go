@@ -2,9 +2,13 @@ package digitalocean
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
 	"os"
 	"testing"
 
+	"github.com/digitalocean/godo"
 	"github.com/rancher/machine/libmachine/drivers"
 	"github.com/stretchr/testify/assert"
 )
@@ -129,3 +133,33 @@ func TestTagsEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Nil(t, driver.getTags())
 }
+
+func TestCreateDropletError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Request:    &http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/v2/droplets"}},
+	}
+	backupsErr := &godo.ErrorResponse{Response: resp, Message: "Backups are not available for this account"}
+	sizeErr := &godo.ErrorResponse{Response: resp, Message: "Size is not available in this region"}
+
+	err := createDropletError(&godo.Response{Response: resp}, backupsErr, true)
+	assert.Contains(t, err.Error(), "--digitalocean-backups")
+	assert.True(t, errors.Is(err, backupsErr))
+
+	assert.Equal(t, sizeErr, createDropletError(&godo.Response{Response: resp}, sizeErr, true))
+	assert.Equal(t, backupsErr, createDropletError(&godo.Response{Response: resp}, backupsErr, false))
+}
+
+func TestEstimateCost(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	estimate, err := driver.EstimateCost()
+	assert.NoError(t, err)
+	assert.Equal(t, 6.0, estimate.Monthly)
+	assert.InDelta(t, 0.00893, estimate.Hourly, 0.00001)
+	assert.Equal(t, "s-1vcpu-1gb in nyc3", estimate.Description)
+
+	driver.Size = "so-32vcpu-256gb"
+	_, err = driver.EstimateCost()
+	assert.Error(t, err)
+}
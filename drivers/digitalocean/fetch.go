@@ -0,0 +1,44 @@
+package digitalocean
+
+import (
+	"context"
+
+	"github.com/rancher/machine/libmachine/drivers"
+)
+
+// Fetch returns the current size, IP and tags of the droplet. The tags have
+// no values.
+func (d *Driver) Fetch() (*drivers.InstanceAttributes, error) {
+	droplet, resp, err := d.getClient().Droplets.Get(context.TODO(), d.DropletID)
+	if err != nil {
+		return nil, classifyError(resp, err)
+	}
+
+	attributes := &drivers.InstanceAttributes{
+		InstanceType: droplet.SizeSlug,
+		Tags:         map[string]string{},
+	}
+	attributes.IP, _ = droplet.PublicIPv4()
+
+	for _, tag := range droplet.Tags {
+		attributes.Tags[tag] = ""
+	}
+
+	return attributes, nil
+}
+
+// StoredAttributes returns the size, IP and tags the droplet was created
+// with.
+func (d *Driver) StoredAttributes() (*drivers.InstanceAttributes, error) {
+	attributes := &drivers.InstanceAttributes{
+		InstanceType: d.Size,
+		IP:           d.IPAddress,
+		Tags:         map[string]string{},
+	}
+
+	for _, tag := range d.getTags() {
+		attributes.Tags[tag] = ""
+	}
+
+	return attributes, nil
+}
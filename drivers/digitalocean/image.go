@@ -0,0 +1,96 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// imagesPerPage is the size of the pages of the listing of the images of the
+// account.
+const imagesPerPage = 200
+
+type imageLister func(opt *godo.ListOptions) ([]godo.Image, *godo.Response, error)
+
+// resolveImageName sets ImageID to the id of the custom image or snapshot of
+// the account named ImageName. The id is kept so that a machine rebuilt from
+// its config uses the same image even if another one was given the name.
+func (d *Driver) resolveImageName() error {
+	if d.ImageName == "" || d.ImageID != 0 {
+		return nil
+	}
+
+	client := d.getClient()
+	image, err := findImageByName(func(opt *godo.ListOptions) ([]godo.Image, *godo.Response, error) {
+		return client.Images.ListUser(context.TODO(), opt)
+	}, d.ImageName)
+	if err != nil {
+		return err
+	}
+
+	if !imageInRegion(image, d.Region) {
+		return fmt.Errorf("image %q (%d) is not available in region %s, it is in: %s", d.ImageName, image.ID, d.Region, strings.Join(image.Regions, ", "))
+	}
+
+	log.Infof("Using image %q (%d)", d.ImageName, image.ID)
+	d.ImageID = image.ID
+	return nil
+}
+
+// findImageByName returns the only image listed by list named name.
+func findImageByName(list imageLister, name string) (*godo.Image, error) {
+	matches := []godo.Image{}
+	opt := &godo.ListOptions{Page: 1, PerPage: imagesPerPage}
+	for {
+		images, resp, err := list(opt)
+		if err != nil {
+			return nil, classifyError(resp, err)
+		}
+
+		for _, image := range images {
+			if image.Name == name {
+				matches = append(matches, image)
+			}
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no custom image or snapshot named %q", name)
+	case 1:
+		return &matches[0], nil
+	}
+
+	ids := []string{}
+	for _, image := range matches {
+		ids = append(ids, strconv.Itoa(image.ID))
+	}
+	return nil, fmt.Errorf("%d images are named %q, use --digitalocean-image with the id of one of: %s", len(matches), name, strings.Join(ids, ", "))
+}
+
+func imageInRegion(image *godo.Image, region string) bool {
+	for _, r := range image.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// createImage returns the image of the droplet, the one resolved from
+// ImageName if any.
+func (d *Driver) createImage() godo.DropletCreateImage {
+	if d.ImageID != 0 {
+		return godo.DropletCreateImage{ID: d.ImageID}
+	}
+	return godo.DropletCreateImage{Slug: d.Image}
+}
@@ -0,0 +1,63 @@
+package digitalocean
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeImagePages lists the pages of images, linking each page to the next.
+func fakeImagePages(pages ...[]godo.Image) imageLister {
+	return func(opt *godo.ListOptions) ([]godo.Image, *godo.Response, error) {
+		resp := &godo.Response{Links: &godo.Links{}}
+		if opt.Page < len(pages) {
+			resp.Links.Pages = &godo.Pages{Next: "https://api.digitalocean.com/v2/images?page=2", Last: "https://api.digitalocean.com/v2/images?page=2"}
+		}
+		return pages[opt.Page-1], resp, nil
+	}
+}
+
+func TestFindImageByName(t *testing.T) {
+	list := fakeImagePages(
+		[]godo.Image{{ID: 1, Name: "base"}, {ID: 2, Name: "golden"}},
+		[]godo.Image{{ID: 3, Name: "golden-old"}, {ID: 4, Name: "web"}, {ID: 5, Name: "web"}},
+	)
+
+	image, err := findImageByName(list, "golden")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, image.ID)
+
+	_, err = findImageByName(list, "db")
+	assert.EqualError(t, err, `no custom image or snapshot named "db"`)
+
+	_, err = findImageByName(list, "web")
+	assert.EqualError(t, err, `2 images are named "web", use --digitalocean-image with the id of one of: 4, 5`)
+}
+
+func TestImageNameConflictsWithImage(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"digitalocean-access-token": "TOKEN",
+			"digitalocean-image":        "debian-12-x64",
+			"digitalocean-image-name":   "golden",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, "--digitalocean-image and --digitalocean-image-name are mutually exclusive")
+}
+
+func TestCreateImage(t *testing.T) {
+	driver := NewDriver("default", "path")
+	assert.Equal(t, godo.DropletCreateImage{Slug: defaultImage}, driver.createImage())
+
+	driver.ImageName, driver.ImageID = "golden", 42
+	assert.NoError(t, driver.resolveImageName())
+	assert.Equal(t, godo.DropletCreateImage{ID: 42}, driver.createImage())
+}
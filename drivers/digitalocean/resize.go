@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnutils"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+// actionPollInterval is the delay between two queries of an action that is
+// still in progress.
+const actionPollInterval = 5 * time.Second
+
+// Resize changes the size of the droplet. A droplet can only be resized
+// while it is powered off, so it is shut down first and powered on again once
+// it is resized. Its disk is kept so that it can be resized down later.
+func (d *Driver) Resize(size string) error {
+	client := d.getClient()
+
+	droplet, resp, err := client.Droplets.Get(context.TODO(), d.DropletID)
+	if err != nil {
+		return classifyError(resp, err)
+	}
+	if droplet.SizeSlug == size {
+		log.Infof("Droplet %d is already of size %s", d.DropletID, size)
+		d.Size = size
+		return nil
+	}
+
+	if droplet.Status != "off" {
+		log.Infof("Shutting down droplet %d to resize it...", d.DropletID)
+		if err := d.Stop(); err != nil {
+			return err
+		}
+		if err := mcnutils.WaitFor(drivers.MachineInState(d, state.Stopped)); err != nil {
+			return fmt.Errorf("error waiting for droplet %d to shut down: %s", d.DropletID, err)
+		}
+	}
+
+	log.Infof("Resizing droplet %d from %s to %s...", d.DropletID, droplet.SizeSlug, size)
+	action, resp, resizeErr := client.DropletActions.Resize(context.TODO(), d.DropletID, size, false)
+	if resizeErr != nil {
+		resizeErr = classifyError(resp, resizeErr)
+	} else {
+		resizeErr = d.waitForAction(action)
+	}
+	if resizeErr == nil {
+		d.Size = size
+	}
+
+	// The droplet is powered on again even if it couldn't be resized, not
+	// to leave it off.
+	if err := d.Start(); err != nil {
+		return err
+	}
+	if err := mcnutils.WaitFor(drivers.MachineInState(d, state.Running)); err != nil {
+		return fmt.Errorf("error waiting for droplet %d to power on: %s", d.DropletID, err)
+	}
+	if resizeErr != nil {
+		return fmt.Errorf("error resizing droplet %d to %s: %w", d.DropletID, size, resizeErr)
+	}
+
+	return nil
+}
+
+// waitForAction waits for the action on the droplet to complete.
+func (d *Driver) waitForAction(action *godo.Action) error {
+	for {
+		switch action.Status {
+		case godo.ActionCompleted:
+			return nil
+		case "errored":
+			return fmt.Errorf("the %s action errored", action.Type)
+		}
+
+		time.Sleep(actionPollInterval)
+
+		var resp *godo.Response
+		var err error
+		action, resp, err = d.getClient().DropletActions.Get(context.TODO(), d.DropletID, action.ID)
+		if err != nil {
+			return classifyError(resp, err)
+		}
+	}
+}
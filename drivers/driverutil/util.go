@@ -1,6 +1,11 @@
 package driverutil
 
-import "strings"
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
 
 // SplitPortProto splits a string in the format port/protocol, defaulting
 // protocol to "tcp" if not provided.
@@ -11,3 +16,26 @@ func SplitPortProto(raw string) (port string, protocol string) {
 	}
 	return parts[0], parts[1]
 }
+
+// CheckUserDataSize checks that the user data file at path is at most limit
+// bytes, once base64 encoded if the provider limits the encoded size.
+func CheckUserDataSize(path string, limit int64, encoded bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to read user data file %s: %v", path, err)
+	}
+
+	size := info.Size()
+	if encoded {
+		size = int64(base64.StdEncoding.EncodedLen(int(size)))
+	}
+
+	if size > limit {
+		if encoded {
+			return fmt.Errorf("user data file %s is too large: %d bytes once base64 encoded, the limit is %d", path, size, limit)
+		}
+		return fmt.Errorf("user data file %s is too large: %d bytes, the limit is %d", path, size, limit)
+	}
+
+	return nil
+}
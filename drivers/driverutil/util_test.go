@@ -1,6 +1,8 @@
 package driverutil
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,3 +25,14 @@ func TestSplitPortProtocol(t *testing.T) {
 		assert.Equal(t, tc.expectedProto, proto)
 	}
 }
+
+func TestCheckUserDataSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user-data")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 300), 0600))
+
+	assert.NoError(t, CheckUserDataSize(path, 300, false))
+	assert.NoError(t, CheckUserDataSize(path, 400, true))
+	assert.EqualError(t, CheckUserDataSize(path, 299, false), "user data file "+path+" is too large: 300 bytes, the limit is 299")
+	assert.EqualError(t, CheckUserDataSize(path, 300, true), "user data file "+path+" is too large: 400 bytes once base64 encoded, the limit is 300")
+	assert.Error(t, CheckUserDataSize(path+"-missing", 300, false))
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/mcnutils"
 	"github.com/rancher/machine/libmachine/ssh"
@@ -302,13 +303,35 @@ func (d *Driver) getInstance() (*v3.Instance, error) {
 		return nil, err
 	}
 
-	return client.GetInstance(ctx, d.ID)
+	instance, err := client.GetInstance(ctx, d.ID)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	return instance, nil
+}
+
+// classifyError wraps the API errors with the matching libmachine error kind.
+func classifyError(err error) error {
+	switch {
+	case errors.Is(err, v3.ErrNotFound):
+		return mcnerror.NotFound(err)
+	case errors.Is(err, v3.ErrTooManyRequests), errors.Is(err, v3.ErrServiceUnavailable), errors.Is(err, v3.ErrGatewayTimeout):
+		return mcnerror.MarkTransient(err)
+	case errors.Is(err, v3.ErrUnauthorized), errors.Is(err, v3.ErrForbidden):
+		return mcnerror.Unauthorized(err)
+	}
+
+	return err
 }
 
 // GetState returns a github.com/machine/libmachine/state.State representing the state of the host (running, stopped, etc.)
 func (d *Driver) GetState() (state.State, error) {
 	instance, err := d.getInstance()
 	if err != nil {
+		if errors.Is(err, v3.ErrNotFound) {
+			return state.NotFound, err
+		}
 		return state.Error, err
 	}
 	switch instance.State {
@@ -894,6 +917,12 @@ func (d *Driver) Restart() error {
 	return err
 }
 
+// Reboot reboots the instance with the reboot operation, which Restart
+// already uses.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
 // Kill stops a host forcefully (same as Stop)
 func (d *Driver) Kill() error {
 	return d.Stop()
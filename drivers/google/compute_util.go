@@ -15,6 +15,7 @@ import (
 
 	"github.com/rancher/machine/drivers/driverutil"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/wrangler/v3/pkg/name"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -34,6 +35,7 @@ type ComputeUtil struct {
 	network                    string
 	subnetwork                 string
 	preemptible                bool
+	provisioningModel          string
 	useInternalIP              bool
 	useInternalIPOnly          bool
 	service                    *raw.Service
@@ -44,6 +46,12 @@ type ComputeUtil struct {
 	openPorts                  []string
 	externalFirewallRulePrefix string
 	internalFirewallRulePrefix string
+	placementPolicy            string
+	placementPolicyType        string
+	nodeGroup                  string
+	acceleratorType            string
+	acceleratorCount           int
+	kmsKey                     string
 }
 
 const (
@@ -52,6 +60,17 @@ const (
 	internalFirewallRuleSuffix   = "internal-rancher-nodes"
 	externalFirewallRuleLabelKey = "rancher-external-fw-rule"
 	internalFirewallRuleLabelKey = "rancher-internal-fw-rule"
+	placementPolicyDescription   = "rancher-machine placement policy"
+	nodeGroupAffinityKey         = "compute.googleapis.com/node-group-name"
+
+	// insertAttempts is the number of attempts to insert an instance, spaced
+	// by a multiple of insertRetryDelay.
+	insertAttempts   = 3
+	insertRetryDelay = 5 * time.Second
+
+	// spreadAvailabilityDomains is the number of availability domains the
+	// instances of a spread placement policy are distributed across.
+	spreadAvailabilityDomains = 2
 )
 
 // NewComputeUtil creates and initializes a ComputeUtil.
@@ -94,6 +113,7 @@ func newComputeUtil(driver *Driver) (*ComputeUtil, error) {
 		network:                    driver.Network,
 		subnetwork:                 driver.Subnetwork,
 		preemptible:                driver.Preemptible,
+		provisioningModel:          driver.ProvisioningModel,
 		useInternalIP:              driver.UseInternalIP,
 		useInternalIPOnly:          driver.UseInternalIPOnly,
 		service:                    service,
@@ -104,6 +124,12 @@ func newComputeUtil(driver *Driver) (*ComputeUtil, error) {
 		openPorts:                  driver.OpenPorts,
 		externalFirewallRulePrefix: driver.ExternalFirewallRulePrefix,
 		internalFirewallRulePrefix: driver.InternalFirewallRulePrefix,
+		placementPolicy:            driver.PlacementPolicy,
+		placementPolicyType:        driver.PlacementPolicyType,
+		nodeGroup:                  driver.NodeGroup,
+		acceleratorType:            driver.AcceleratorType,
+		acceleratorCount:           driver.AcceleratorCount,
+		kmsKey:                     driver.KMSKey,
 	}, nil
 }
 
@@ -162,6 +188,92 @@ func (c *ComputeUtil) region() string {
 	return c.zone[:len(c.zone)-2]
 }
 
+// placementPolicyResource returns the resource policy of the given placement
+// type: compact places the instances close to each other, spread places them
+// on distinct hardware.
+func placementPolicyResource(name, placementType string) *raw.ResourcePolicy {
+	groupPlacement := &raw.ResourcePolicyGroupPlacementPolicy{}
+	if placementType == placementCompact {
+		groupPlacement.Collocation = "COLLOCATED"
+	} else {
+		groupPlacement.AvailabilityDomainCount = spreadAvailabilityDomains
+	}
+
+	return &raw.ResourcePolicy{
+		Name:                 name,
+		Description:          placementPolicyDescription,
+		GroupPlacementPolicy: groupPlacement,
+	}
+}
+
+// ensurePlacementPolicy creates the placement policy of the instance in its
+// region if it doesn't exist yet, and returns its URL.
+func (c *ComputeUtil) ensurePlacementPolicy() (string, error) {
+	policy, err := c.service.ResourcePolicies.Get(c.project, c.region(), c.placementPolicy).Do()
+	if err == nil {
+		return policy.SelfLink, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to get placement policy %q: %w", c.placementPolicy, err)
+	}
+
+	log.Infof("Creating %s placement policy %q", c.placementPolicyType, c.placementPolicy)
+	op, err := c.service.ResourcePolicies.Insert(c.project, c.region(), placementPolicyResource(c.placementPolicy, c.placementPolicyType)).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create placement policy %q: %w", c.placementPolicy, err)
+	}
+
+	if err := c.waitForRegionOp(op.Name); err != nil {
+		return "", err
+	}
+
+	return apiURL + c.project + "/regions/" + c.region() + "/resourcePolicies/" + c.placementPolicy, nil
+}
+
+// cleanUpPlacementPolicy deletes the placement policy of the instance if it
+// was created by rancher-machine and no instance uses it anymore.
+func (c *ComputeUtil) cleanUpPlacementPolicy() error {
+	policy, err := c.service.ResourcePolicies.Get(c.project, c.region(), c.placementPolicy).Do()
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if policy.Description != placementPolicyDescription {
+		return nil
+	}
+
+	op, err := c.service.ResourcePolicies.Delete(c.project, c.region(), c.placementPolicy).Do()
+	if isInUse(err) {
+		log.Debugf("placement policy '%s' is still used by other instances", c.placementPolicy)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Deleting placement policy '%s'", c.placementPolicy)
+	return c.waitForRegionOp(op.Name)
+}
+
+// nodeAffinities returns the affinities scheduling the instance on the
+// sole-tenant nodes of the given node group.
+func nodeAffinities(nodeGroup string) []*raw.SchedulingNodeAffinity {
+	if nodeGroup == "" {
+		return nil
+	}
+
+	return []*raw.SchedulingNodeAffinity{
+		{
+			Key:      nodeGroupAffinityKey,
+			Operator: "IN",
+			Values:   []string{nodeGroup},
+		},
+	}
+}
+
 func (c *ComputeUtil) externalFirewallRule() (*raw.Firewall, error) {
 	return c.service.Firewalls.Get(c.project, c.externalFirewallRuleName()).Do()
 }
@@ -425,7 +537,8 @@ func (c *ComputeUtil) CleanUpFirewallRule(rule *raw.Firewall, labelKey string) e
 
 // instance retrieves the instance.
 func (c *ComputeUtil) instance() (*raw.Instance, error) {
-	return c.service.Instances.Get(c.project, c.zone, c.instanceName).Do()
+	instance, err := c.service.Instances.Get(c.project, c.zone, c.instanceName).Do()
+	return instance, classifyError(err)
 }
 
 // createInstance creates a GCE VM instance.
@@ -466,9 +579,21 @@ func (c *ComputeUtil) createInstance(d *Driver) error {
 				Scopes: strings.Split(d.Scopes, ","),
 			},
 		},
-		Scheduling: &raw.Scheduling{
-			Preemptible: c.preemptible,
-		},
+		Scheduling: scheduling(c.preemptible, c.provisioningModel, c.nodeGroup),
+	}
+
+	if c.acceleratorType != "" {
+		instance.GuestAccelerators = guestAccelerators(c.zoneURL, c.acceleratorType, c.acceleratorCount)
+		// Instances with GPUs can't be live migrated.
+		instance.Scheduling.OnHostMaintenance = "TERMINATE"
+	}
+
+	if c.placementPolicy != "" {
+		policy, err := c.ensurePlacementPolicy()
+		if err != nil {
+			return err
+		}
+		instance.ResourcePolicies = []string{policy}
 	}
 
 	// This is a workaround to a known issue in the GCE API which prevents the standard .List() function from filtering
@@ -513,17 +638,20 @@ func (c *ComputeUtil) createInstance(d *Driver) error {
 			DiskSizeGb: int64(d.DiskSize),
 			DiskType:   c.diskType(),
 		}
+		if c.kmsKey != "" {
+			instance.Disks[0].DiskEncryptionKey = &raw.CustomerEncryptionKey{KmsKeyName: c.kmsKey}
+		}
 	} else {
 		instance.Disks[0].Source = c.zoneURL + "/disks/" + c.instanceName + "-disk"
 	}
 
-	op, err := c.service.Instances.Insert(c.project, c.zone, instance).Do()
+	op, err := c.insertInstance(instance, d.InsertRequestID)
 	if err != nil {
-		return err
+		return c.kmsKeyError(err)
 	}
 
 	if err = c.waitForRegionalOp(op.Name); err != nil {
-		return err
+		return c.kmsKeyError(err)
 	}
 
 	instance, err = c.instance()
@@ -531,7 +659,44 @@ func (c *ComputeUtil) createInstance(d *Driver) error {
 		return err
 	}
 
-	return c.uploadSSHKeyAndUserdata(instance, d.GetSSHKeyPath(), d.Userdata)
+	return c.uploadSSHKeyAndUserdata(instance, d.GetSSHKeyPath(), d.Userdata, d.Metadata)
+}
+
+// insertInstance inserts the instance, retrying on transient errors. The
+// retries reuse the request id, so that a retry whose previous attempt reached
+// the API returns the operation of that attempt instead of inserting another
+// instance.
+func (c *ComputeUtil) insertInstance(instance *raw.Instance, requestID string) (*raw.Operation, error) {
+	var (
+		op  *raw.Operation
+		err error
+	)
+	for attempt := 1; attempt <= insertAttempts; attempt++ {
+		call := c.service.Instances.Insert(c.project, c.zone, instance)
+		if requestID != "" {
+			call = call.RequestId(requestID)
+		}
+
+		op, err = call.Do()
+		if err == nil || requestID == "" || !isRetryableInsertError(err) {
+			return op, err
+		}
+
+		log.Warnf("Error inserting instance %s (attempt %d/%d): %v", c.instanceName, attempt, insertAttempts, err)
+		time.Sleep(time.Duration(attempt) * insertRetryDelay)
+	}
+
+	return nil, err
+}
+
+// isRetryableInsertError returns whether an insertion failed on the network
+// or on the server side, its response being possibly lost.
+func isRetryableInsertError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= http.StatusInternalServerError || apiErr.Code == http.StatusTooManyRequests
+	}
+	return true
 }
 
 // configureInstance configures an existing instance for use with Docker Machine.
@@ -547,7 +712,7 @@ func (c *ComputeUtil) configureInstance(d *Driver) error {
 		}
 	}
 
-	return c.uploadSSHKeyAndUserdata(instance, d.GetSSHKeyPath(), d.Userdata)
+	return c.uploadSSHKeyAndUserdata(instance, d.GetSSHKeyPath(), d.Userdata, d.Metadata)
 }
 
 // addFirewallTag adds a tag to the instance to match the firewall rule.
@@ -569,8 +734,9 @@ func (c *ComputeUtil) addFirewallTag(instance *raw.Instance) error {
 	return c.waitForRegionalOp(op.Name)
 }
 
-// uploadSSHKeyUserdata updates the instance metadata with the given ssh key and userdata.
-func (c *ComputeUtil) uploadSSHKeyAndUserdata(instance *raw.Instance, sshKeyPath, userdata string) error {
+// uploadSSHKeyUserdata updates the instance metadata with the given ssh key, userdata and
+// key=value metadata entries.
+func (c *ComputeUtil) uploadSSHKeyAndUserdata(instance *raw.Instance, sshKeyPath, userdata string, entries []string) error {
 	log.Infof("Uploading SSH Key and userdata")
 
 	sshKey, err := os.ReadFile(sshKeyPath + ".pub")
@@ -581,24 +747,106 @@ func (c *ComputeUtil) uploadSSHKeyAndUserdata(instance *raw.Instance, sshKeyPath
 	metaDataValue := fmt.Sprintf("%s:%s %s\n", c.userName, strings.TrimSpace(string(sshKey)), c.userName)
 	metadata := &raw.Metadata{
 		Fingerprint: instance.Metadata.Fingerprint,
-		Items: []*raw.MetadataItems{
-			{
-				Key:   "sshKeys",
-				Value: &metaDataValue,
-			},
-		},
+		Items:       metadataItems(instance.Metadata.Items, entries, metaDataValue, userdata),
+	}
+
+	op, err := c.service.Instances.SetMetadata(c.project, c.zone, c.instanceName, metadata).Do()
+	if err != nil {
+		return err
+	}
+
+	return c.waitForRegionalOp(op.Name)
+}
+
+// metadataItems layers the metadata of the machine over the existing items
+// of the instance and the key=value entries of the user. The SSH key of the
+// machine is appended to the sshKeys value of the user instead of replacing
+// it.
+func metadataItems(existing []*raw.MetadataItems, entries []string, sshKey, userdata string) []*raw.MetadataItems {
+	items := []*raw.MetadataItems{}
+	index := map[string]int{}
+	set := func(key, value string) {
+		if i, ok := index[key]; ok {
+			items[i].Value = &value
+			return
+		}
+		index[key] = len(items)
+		items = append(items, &raw.MetadataItems{Key: key, Value: &value})
+	}
+
+	for _, item := range existing {
+		if item.Value != nil {
+			set(item.Key, *item.Value)
+		}
+	}
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "=")
+		set(key, value)
+	}
+
+	if i, ok := index["sshKeys"]; ok && strings.TrimSpace(*items[i].Value) != "" {
+		sshKey = strings.TrimRight(*items[i].Value, "\n") + "\n" + sshKey
 	}
+	set("sshKeys", sshKey)
 
 	if userdata != "" {
-		metadata.Items = append(metadata.Items, &raw.MetadataItems{
-			Key:   "user-data",
-			Value: &userdata,
-		})
+		set("user-data", userdata)
 	}
 
-	op, err := c.service.Instances.SetMetadata(c.project, c.zone, c.instanceName, metadata).Do()
+	return items
+}
 
-	return c.waitForRegionalOp(op.Name)
+var metadataKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,128}$`)
+
+// validateMetadata checks the key=value metadata entries, the user data
+// being set with --google-userdata.
+func validateMetadata(entries []string) error {
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !metadataKeyRegexp.MatchString(key) {
+			return fmt.Errorf("invalid metadata %q (--google-metadata), must be key=value with a key of up to 128 letters, digits, - or _", entry)
+		}
+		if key == "user-data" {
+			return fmt.Errorf("invalid metadata %q (--google-metadata), the user data is set with --google-userdata", entry)
+		}
+		if len(value) > maxMetadataValueSize {
+			return fmt.Errorf("invalid metadata %q (--google-metadata), the value is larger than %d bytes", key, maxMetadataValueSize)
+		}
+	}
+
+	return nil
+}
+
+var kmsKeyRegexp = regexp.MustCompile(`^projects/[^/]+/locations/([^/]+)/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// validateKMSKey checks the Cloud KMS key of --google-kms-key is a key name in
+// the region of the zone or global, where the disks of the zone can use it.
+func validateKMSKey(key, zone string) error {
+	if key == "" {
+		return nil
+	}
+
+	matches := kmsKeyRegexp.FindStringSubmatch(key)
+	if matches == nil {
+		return fmt.Errorf("invalid KMS key %q (--google-kms-key), must be projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY", key)
+	}
+
+	if location := matches[1]; location != "global" && !strings.HasPrefix(zone, location+"-") {
+		return fmt.Errorf("the KMS key %q (--google-kms-key) is in %s, it can't encrypt the disks of the zone %s", key, location, zone)
+	}
+
+	return nil
+}
+
+// kmsKeyError adds to an error inserting the instance that the KMS key must
+// be usable by the Compute Engine service agent, the usual cause of the
+// failures of an instance with a KMS key.
+func (c *ComputeUtil) kmsKeyError(err error) error {
+	if c.kmsKey == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w (the KMS key %s must exist, be enabled and grant roles/cloudkms.cryptoKeyEncrypterDecrypter to the Compute Engine service agent of the project)", err, c.kmsKey)
 }
 
 // parseTags computes the tags for the instance.
@@ -673,17 +921,97 @@ func (c *ComputeUtil) stopInstance() error {
 	return c.waitForRegionalOp(op.Name)
 }
 
+// guestAccelerators returns the GPUs attached to the instance.
+func guestAccelerators(zoneURL, acceleratorType string, count int) []*raw.AcceleratorConfig {
+	return []*raw.AcceleratorConfig{
+		{
+			AcceleratorType:  zoneURL + "/acceleratorTypes/" + acceleratorType,
+			AcceleratorCount: int64(count),
+		},
+	}
+}
+
+// scheduling returns the scheduling options of the instance. Preemptible and
+// spot instances can't be migrated on host maintenance nor restarted
+// automatically by GCE, and spot instances are stopped, not deleted, when
+// they are preempted.
+func scheduling(preemptible bool, provisioningModel, nodeGroup string) *raw.Scheduling {
+	s := &raw.Scheduling{
+		Preemptible:       preemptible,
+		ProvisioningModel: provisioningModel,
+		NodeAffinities:    nodeAffinities(nodeGroup),
+	}
+
+	if isPreemptible(s) {
+		automaticRestart := false
+		s.AutomaticRestart = &automaticRestart
+		s.OnHostMaintenance = "TERMINATE"
+	}
+	if provisioningModel == provisioningSpot {
+		s.InstanceTerminationAction = "STOP"
+	}
+
+	return s
+}
+
+// isPreemptible returns true if the instance scheduled with s can be
+// preempted.
+func isPreemptible(s *raw.Scheduling) bool {
+	return s != nil && (s.Preemptible || s.ProvisioningModel == provisioningSpot)
+}
+
+// preempted returns true if the instance was preempted since it was last
+// started.
+func (c *ComputeUtil) preempted(instance *raw.Instance) (bool, error) {
+	filter := fmt.Sprintf(`(operationType = "compute.instances.preempted") AND (targetLink = "%s")`, instance.SelfLink)
+	ops, err := c.service.ZoneOperations.List(c.project, c.zone).Filter(filter).Do()
+	if err != nil {
+		return false, err
+	}
+
+	return preemptedSince(ops.Items, instance.LastStartTimestamp), nil
+}
+
+// preemptedSince returns true if one of the preemption operations ops was
+// inserted after lastStart.
+func preemptedSince(ops []*raw.Operation, lastStart string) bool {
+	start, err := time.Parse(time.RFC3339, lastStart)
+	for _, op := range ops {
+		if err != nil {
+			return true
+		}
+		inserted, opErr := time.Parse(time.RFC3339, op.InsertTime)
+		if opErr != nil || inserted.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
 // startInstance starts the instance.
 func (c *ComputeUtil) startInstance() error {
 	op, err := c.service.Instances.Start(c.project, c.zone, c.instanceName).Do()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	log.Infof("Waiting for instance to start.")
 	return c.waitForRegionalOp(op.Name)
 }
 
+// setMachineType changes the machine type of the stopped instance.
+func (c *ComputeUtil) setMachineType(machineType string) error {
+	op, err := c.service.Instances.SetMachineType(c.project, c.zone, c.instanceName, &raw.InstancesSetMachineTypeRequest{
+		MachineType: c.zoneURL + "/machineTypes/" + machineType,
+	}).Do()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Waiting for the machine type to change.")
+	return c.waitForRegionalOp(op.Name)
+}
+
 // waitForOp waits for the operation to finish.
 func (c *ComputeUtil) waitForOp(opGetter func() (*raw.Operation, error)) error {
 	for {
@@ -695,7 +1023,7 @@ func (c *ComputeUtil) waitForOp(opGetter func() (*raw.Operation, error)) error {
 		log.Debugf("Operation %q status: %s", op.Name, op.Status)
 		if op.Status == "DONE" {
 			if op.Error != nil {
-				return fmt.Errorf("Operation error: %v", *op.Error.Errors[0])
+				return operationError(op.Error.Errors[0])
 			}
 			break
 		}
@@ -704,6 +1032,16 @@ func (c *ComputeUtil) waitForOp(opGetter func() (*raw.Operation, error)) error {
 	return nil
 }
 
+// operationError returns the error of a failed operation, classified as an
+// ErrCapacity when the zone lacks the resources for the instance.
+func operationError(opErr *raw.OperationErrorErrors) error {
+	err := fmt.Errorf("Operation error: %v", *opErr)
+	if strings.HasPrefix(opErr.Code, "ZONE_RESOURCE_POOL_EXHAUSTED") {
+		return mcnerror.Capacity(err)
+	}
+	return err
+}
+
 // waitForRegionalOp waits for the regional operation to finish.
 func (c *ComputeUtil) waitForRegionalOp(name string) error {
 	return c.waitForOp(func() (*raw.Operation, error) {
@@ -711,6 +1049,14 @@ func (c *ComputeUtil) waitForRegionalOp(name string) error {
 	})
 }
 
+// waitForRegionOp waits for the operation in the region of the instance to
+// finish.
+func (c *ComputeUtil) waitForRegionOp(name string) error {
+	return c.waitForOp(func() (*raw.Operation, error) {
+		return c.service.RegionOperations.Get(c.project, c.region(), name).Do()
+	})
+}
+
 // waitForGlobalOp waits for the global operation to finish.
 func (c *ComputeUtil) waitForGlobalOp(name string) error {
 	return c.waitForOp(func() (*raw.Operation, error) {
@@ -725,15 +1071,21 @@ func (c *ComputeUtil) ip() (string, error) {
 		return "", unwrapGoogleError(err)
 	}
 
+	return c.instanceIP(instance), nil
+}
+
+// instanceIP returns the IP address the machine is reached at.
+func (c *ComputeUtil) instanceIP(instance *raw.Instance) string {
 	nic := instance.NetworkInterfaces[0]
 	if c.useInternalIP {
-		return nic.NetworkIP, nil
+		return nic.NetworkIP
 	}
-	return nic.AccessConfigs[0].NatIP, nil
+	return nic.AccessConfigs[0].NatIP
 }
 
 func unwrapGoogleError(err error) error {
-	if googleErr, ok := err.(*googleapi.Error); ok {
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
 		return errors.New(googleErr.Message)
 	}
 
@@ -741,8 +1093,8 @@ func unwrapGoogleError(err error) error {
 }
 
 func isNotFound(err error) bool {
-	googleErr, ok := err.(*googleapi.Error)
-	if !ok {
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) {
 		return false
 	}
 
@@ -752,3 +1104,39 @@ func isNotFound(err error) bool {
 
 	return false
 }
+
+// isInUse returns true if the resource can't be deleted because another
+// resource uses it.
+func isInUse(err error) bool {
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) {
+		return false
+	}
+
+	for _, item := range googleErr.Errors {
+		if item.Reason == "resourceInUseByAnotherResource" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classifyError wraps the API errors with the matching libmachine error kind.
+func classifyError(err error) error {
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) {
+		return err
+	}
+
+	switch {
+	case googleErr.Code == http.StatusNotFound:
+		return mcnerror.NotFound(err)
+	case googleErr.Code == http.StatusTooManyRequests, googleErr.Code >= http.StatusInternalServerError:
+		return mcnerror.MarkTransient(err)
+	case googleErr.Code == http.StatusUnauthorized, googleErr.Code == http.StatusForbidden:
+		return mcnerror.Unauthorized(err)
+	}
+
+	return err
+}
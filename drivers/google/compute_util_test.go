@@ -1,6 +1,8 @@
 package google
 
 import (
+	"errors"
+	"net/http"
 	"slices"
 	"testing"
 
@@ -8,6 +10,7 @@ import (
 	"github.com/rancher/wrangler/v3/pkg/name"
 	"github.com/stretchr/testify/assert"
 	raw "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 )
 
 func TestAllTagTypes(t *testing.T) {
@@ -212,3 +215,114 @@ func TestParseLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestPlacementPolicyResource(t *testing.T) {
+	compact := placementPolicyResource("pp", placementCompact)
+	assert.Equal(t, "pp", compact.Name)
+	assert.Equal(t, placementPolicyDescription, compact.Description)
+	assert.Equal(t, "COLLOCATED", compact.GroupPlacementPolicy.Collocation)
+
+	spread := placementPolicyResource("pp", placementSpread)
+	assert.Empty(t, spread.GroupPlacementPolicy.Collocation)
+	assert.Equal(t, int64(spreadAvailabilityDomains), spread.GroupPlacementPolicy.AvailabilityDomainCount)
+}
+
+func TestNodeAffinities(t *testing.T) {
+	assert.Nil(t, nodeAffinities(""))
+	assert.Equal(t, []*raw.SchedulingNodeAffinity{
+		{Key: nodeGroupAffinityKey, Operator: "IN", Values: []string{"group-a"}},
+	}, nodeAffinities("group-a"))
+}
+
+func TestScheduling(t *testing.T) {
+	standard := scheduling(false, provisioningStandard, "")
+	assert.Nil(t, standard.AutomaticRestart)
+	assert.Empty(t, standard.OnHostMaintenance)
+	assert.Empty(t, standard.InstanceTerminationAction)
+
+	preemptible := scheduling(true, provisioningStandard, "")
+	assert.False(t, *preemptible.AutomaticRestart)
+	assert.Equal(t, "TERMINATE", preemptible.OnHostMaintenance)
+	assert.Empty(t, preemptible.InstanceTerminationAction)
+
+	spot := scheduling(false, provisioningSpot, "")
+	assert.Equal(t, provisioningSpot, spot.ProvisioningModel)
+	assert.False(t, *spot.AutomaticRestart)
+	assert.Equal(t, "TERMINATE", spot.OnHostMaintenance)
+	assert.Equal(t, "STOP", spot.InstanceTerminationAction)
+}
+
+func TestPreemptedSince(t *testing.T) {
+	lastStart := "2024-01-01T10:00:00.000-08:00"
+
+	assert.False(t, preemptedSince(nil, lastStart))
+	assert.False(t, preemptedSince([]*raw.Operation{{InsertTime: "2024-01-01T09:00:00.000-08:00"}}, lastStart))
+	assert.True(t, preemptedSince([]*raw.Operation{{InsertTime: "2024-01-01T11:00:00.000-08:00"}}, lastStart))
+	assert.True(t, preemptedSince([]*raw.Operation{{InsertTime: "2024-01-01T09:00:00.000-08:00"}}, ""))
+}
+
+func TestIsRetryableInsertError(t *testing.T) {
+	assert.True(t, isRetryableInsertError(errors.New("connection reset by peer")))
+	assert.True(t, isRetryableInsertError(&googleapi.Error{Code: http.StatusServiceUnavailable}))
+	assert.True(t, isRetryableInsertError(&googleapi.Error{Code: http.StatusTooManyRequests}))
+	assert.False(t, isRetryableInsertError(&googleapi.Error{Code: http.StatusBadRequest}))
+	assert.False(t, isRetryableInsertError(&googleapi.Error{Code: http.StatusConflict}))
+}
+
+func TestMetadataItems(t *testing.T) {
+	startup := "echo hello"
+	existing := []*raw.MetadataItems{{Key: "startup-script", Value: &startup}}
+
+	items := metadataItems(existing, []string{"sshKeys=user:ssh-rsa AAAA user", "team=ops"}, "docker-user:ssh-rsa BBBB docker-user\n", "#cloud-config")
+
+	values := map[string]string{}
+	keys := []string{}
+	for _, item := range items {
+		keys = append(keys, item.Key)
+		values[item.Key] = *item.Value
+	}
+	assert.Equal(t, []string{"startup-script", "sshKeys", "team", "user-data"}, keys)
+	assert.Equal(t, "echo hello", values["startup-script"])
+	assert.Equal(t, "user:ssh-rsa AAAA user\ndocker-user:ssh-rsa BBBB docker-user\n", values["sshKeys"])
+	assert.Equal(t, "ops", values["team"])
+	assert.Equal(t, "#cloud-config", values["user-data"])
+}
+
+func TestValidateMetadata(t *testing.T) {
+	assert.NoError(t, validateMetadata([]string{"team=ops", "empty="}))
+	assert.EqualError(t, validateMetadata([]string{"bad key=1"}), `invalid metadata "bad key=1" (--google-metadata), must be key=value with a key of up to 128 letters, digits, - or _`)
+	assert.EqualError(t, validateMetadata([]string{"user-data=x"}), `invalid metadata "user-data=x" (--google-metadata), the user data is set with --google-userdata`)
+}
+
+func TestValidateKMSKey(t *testing.T) {
+	assert.NoError(t, validateKMSKey("", "us-central1-a"))
+	assert.NoError(t, validateKMSKey("projects/p/locations/us-central1/keyRings/r/cryptoKeys/k", "us-central1-a"))
+	assert.NoError(t, validateKMSKey("projects/p/locations/global/keyRings/r/cryptoKeys/k", "europe-west1-b"))
+	assert.EqualError(t, validateKMSKey("projects/p/keyRings/r/cryptoKeys/k", "us-central1-a"), `invalid KMS key "projects/p/keyRings/r/cryptoKeys/k" (--google-kms-key), must be projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY`)
+	assert.EqualError(t, validateKMSKey("projects/p/locations/us-east1/keyRings/r/cryptoKeys/k", "us-central1-a"), `the KMS key "projects/p/locations/us-east1/keyRings/r/cryptoKeys/k" (--google-kms-key) is in us-east1, it can't encrypt the disks of the zone us-central1-a`)
+}
+
+func TestKMSKeyError(t *testing.T) {
+	err := errors.New("forbidden")
+
+	assert.Equal(t, err, (&ComputeUtil{}).kmsKeyError(err))
+	assert.ErrorIs(t, (&ComputeUtil{kmsKey: "projects/p/locations/global/keyRings/r/cryptoKeys/k"}).kmsKeyError(err), err)
+}
+
+func TestGuestAccelerators(t *testing.T) {
+	assert.Equal(t, []*raw.AcceleratorConfig{
+		{AcceleratorType: apiURL + "project/zones/us-central1-a/acceleratorTypes/nvidia-tesla-t4", AcceleratorCount: 2},
+	}, guestAccelerators(apiURL+"project/zones/us-central1-a", "nvidia-tesla-t4", 2))
+}
+
+func TestAllowedPorts(t *testing.T) {
+	rule := &raw.Firewall{
+		Allowed: []*raw.FirewallAllowed{
+			{IPProtocol: "tcp", Ports: []string{"8080", "443"}},
+			{IPProtocol: "udp", Ports: []string{"53"}},
+		},
+	}
+
+	assert.Equal(t, []string{"8080", "53/udp"}, allowedPorts(rule, []string{"8080", "9000", "53/udp"}))
+	assert.Empty(t, allowedPorts(nil, []string{"8080"}))
+}
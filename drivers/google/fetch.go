@@ -0,0 +1,84 @@
+package google
+
+import (
+	"path"
+
+	"github.com/rancher/machine/drivers/driverutil"
+	"github.com/rancher/machine/libmachine/drivers"
+	raw "google.golang.org/api/compute/v1"
+)
+
+// Fetch returns the current machine type, IP, labels and open ports of the
+// instance.
+func (d *Driver) Fetch() (*drivers.InstanceAttributes, error) {
+	c, err := newComputeUtil(d)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := c.instance()
+	if err != nil {
+		return nil, unwrapGoogleError(err)
+	}
+
+	attributes := &drivers.InstanceAttributes{
+		InstanceType: path.Base(instance.MachineType),
+		IP:           c.instanceIP(instance),
+		Tags:         map[string]string{},
+	}
+
+	for key, value := range instance.Labels {
+		// The firewall rule labels are set by the driver.
+		if key != externalFirewallRuleLabelKey && key != internalFirewallRuleLabelKey {
+			attributes.Tags[key] = value
+		}
+	}
+
+	if len(d.OpenPorts) > 0 {
+		rule, err := c.externalFirewallRule()
+		if err != nil && !isNotFound(err) {
+			return nil, unwrapGoogleError(err)
+		}
+		attributes.OpenPorts = allowedPorts(rule, d.OpenPorts)
+	}
+
+	return attributes, nil
+}
+
+// StoredAttributes returns the machine type, IP, labels and open ports the
+// instance was created with.
+func (d *Driver) StoredAttributes() (*drivers.InstanceAttributes, error) {
+	return &drivers.InstanceAttributes{
+		InstanceType: d.MachineType,
+		IP:           d.IPAddress,
+		Tags:         parseLabels(d.Labels),
+		OpenPorts:    d.OpenPorts,
+	}, nil
+}
+
+// allowedPorts returns the ports of the given port/protocol list allowed by
+// the firewall rule, none when the rule is nil.
+func allowedPorts(rule *raw.Firewall, ports []string) []string {
+	allowed := []string{}
+	if rule == nil {
+		return allowed
+	}
+
+	for _, p := range ports {
+		port, protocol := driverutil.SplitPortProto(p)
+	rules:
+		for _, a := range rule.Allowed {
+			if a.IPProtocol != protocol {
+				continue
+			}
+			for _, allowedPort := range a.Ports {
+				if allowedPort == port {
+					allowed = append(allowed, p)
+					break rules
+				}
+			}
+		}
+	}
+
+	return allowed
+}
@@ -8,9 +8,12 @@ import (
 	"os"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/rancher/machine/drivers/driverutil"
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/ssh"
 	"github.com/rancher/machine/libmachine/state"
@@ -40,6 +43,39 @@ type Driver struct {
 	ExternalFirewallRulePrefix string
 	InternalFirewallRulePrefix string
 	Userdata                   string
+
+	// PlacementPolicy is the resource policy placing the instance, created
+	// with PlacementPolicyType when it doesn't exist. NodeGroup is the
+	// sole-tenant node group the instance runs on.
+	PlacementPolicy     string
+	PlacementPolicyType string
+	NodeGroup           string
+
+	// AcceleratorType is the type of the GPUs attached to the instance, e.g.
+	// nvidia-tesla-t4, AcceleratorCount their number.
+	AcceleratorType  string
+	AcceleratorCount int
+
+	// ProvisioningModel is STANDARD or SPOT. Unlike preemptible instances,
+	// spot instances have no maximum run time. Both are stopped when they
+	// are preempted, and AutoRestartOnPreemption starts them again the next
+	// time their state is queried.
+	ProvisioningModel       string
+	AutoRestartOnPreemption bool
+
+	// InsertRequestID is the request id deduplicating the retries of the
+	// insertion of the instance. It is generated before the machine is first
+	// saved, so that a retried create, even by another process, references
+	// the instance already inserted.
+	InsertRequestID string
+
+	// Metadata holds the key=value instance metadata entries set beside the
+	// SSH key and the user data of the machine.
+	Metadata []string
+
+	// KMSKey is the Cloud KMS key encrypting the boot disk of the instance,
+	// instead of a key managed by Google.
+	KMSKey string
 }
 
 const (
@@ -52,6 +88,16 @@ const (
 	defaultDiskSize    = 10
 	defaultNetwork     = "default"
 	defaultSubnetwork  = ""
+
+	placementCompact = "compact"
+	placementSpread  = "spread"
+
+	provisioningStandard = "STANDARD"
+	provisioningSpot     = "SPOT"
+
+	// maxMetadataValueSize is the limit of GCE on a metadata value, the user
+	// data included.
+	maxMetadataValueSize = 256 << 10
 )
 
 // GetCreateFlags registers the flags this driver adds to
@@ -132,6 +178,17 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "GCE Instance Preemptibility",
 			EnvVar: "GOOGLE_PREEMPTIBLE",
 		},
+		mcnflag.StringFlag{
+			Name:   "google-provisioning-model",
+			Usage:  "GCE Instance provisioning model (STANDARD or SPOT), unlike preemptible instances spot instances have no maximum run time",
+			EnvVar: "GOOGLE_PROVISIONING_MODEL",
+			Value:  provisioningStandard,
+		},
+		mcnflag.BoolFlag{
+			Name:   "google-auto-restart-on-preemption",
+			Usage:  "Start a preempted preemptible or spot instance again when its state is queried",
+			EnvVar: "GOOGLE_AUTO_RESTART_ON_PREEMPTION",
+		},
 		mcnflag.StringFlag{
 			Name:   "google-tags",
 			Usage:  "GCE Instance Tags (comma-separated)",
@@ -174,12 +231,49 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			EnvVar: "GOOGLE_USERDATA",
 			Value:  "",
 		},
+		mcnflag.StringSliceFlag{
+			Name:   "google-metadata",
+			Usage:  "Instance metadata in the form key=value, an sshKeys or ssh-keys value is kept beside the key of the machine",
+			EnvVar: "GOOGLE_METADATA",
+		},
 		mcnflag.StringFlag{
 			Name:   "google-vm-labels",
 			Usage:  "labels to add onto the created virtual machine",
 			EnvVar: "GOOGLE_VM_LABELS",
 			Value:  "",
 		},
+		mcnflag.StringFlag{
+			Name:   "google-placement-policy",
+			Usage:  "GCE resource policy placing the instance, created in the region if it doesn't exist",
+			EnvVar: "GOOGLE_PLACEMENT_POLICY",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-placement-policy-type",
+			Usage:  "Type of a created placement policy: compact or spread",
+			EnvVar: "GOOGLE_PLACEMENT_POLICY_TYPE",
+			Value:  placementSpread,
+		},
+		mcnflag.StringFlag{
+			Name:   "google-node-group",
+			Usage:  "Existing sole-tenant node group to run the instance on",
+			EnvVar: "GOOGLE_NODE_GROUP",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-kms-key",
+			Usage:  "Cloud KMS key encrypting the boot disk, as projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY",
+			EnvVar: "GOOGLE_KMS_KEY",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-accelerator-type",
+			Usage:  "Type of the GPUs attached to the instance, e.g. nvidia-tesla-t4, see --engine-nvidia-runtime to use them from containers",
+			EnvVar: "GOOGLE_ACCELERATOR_TYPE",
+		},
+		mcnflag.IntFlag{
+			Name:   "google-accelerator-count",
+			Usage:  "Number of GPUs of --google-accelerator-type attached to the instance",
+			EnvVar: "GOOGLE_ACCELERATOR_COUNT",
+			Value:  1,
+		},
 	}
 }
 
@@ -267,6 +361,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		d.Network = flags.String("google-network")
 		d.Subnetwork = flags.String("google-subnetwork")
 		d.Preemptible = flags.Bool("google-preemptible")
+		d.ProvisioningModel = strings.ToUpper(flags.String("google-provisioning-model"))
 		d.UseInternalIP = flags.Bool("google-use-internal-ip") || flags.Bool("google-use-internal-ip-only")
 		d.UseInternalIPOnly = flags.Bool("google-use-internal-ip-only")
 		d.Scopes = flags.String("google-scopes")
@@ -275,10 +370,38 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		d.ExternalFirewallRulePrefix = flags.String("google-external-firewall-rule-prefix")
 		d.InternalFirewallRulePrefix = flags.String("google-internal-firewall-rule-prefix")
 		d.Labels = flags.String("google-vm-labels")
+		d.PlacementPolicy = flags.String("google-placement-policy")
+		d.PlacementPolicyType = flags.String("google-placement-policy-type")
+		d.NodeGroup = flags.String("google-node-group")
+		d.AcceleratorType = flags.String("google-accelerator-type")
+		d.AcceleratorCount = flags.Int("google-accelerator-count")
+		d.KMSKey = flags.String("google-kms-key")
+		if err := validateKMSKey(d.KMSKey, d.Zone); err != nil {
+			return err
+		}
+		if d.AcceleratorType != "" && d.AcceleratorCount < 1 {
+			return fmt.Errorf("invalid accelerator count %d (--google-accelerator-count), must be at least 1", d.AcceleratorCount)
+		}
+		if d.PlacementPolicyType != placementCompact && d.PlacementPolicyType != placementSpread {
+			return fmt.Errorf("invalid placement policy type %q (--google-placement-policy-type), must be %s or %s", d.PlacementPolicyType, placementCompact, placementSpread)
+		}
+		if d.ProvisioningModel != provisioningStandard && d.ProvisioningModel != provisioningSpot {
+			return fmt.Errorf("invalid provisioning model %q (--google-provisioning-model), must be %s or %s", d.ProvisioningModel, provisioningStandard, provisioningSpot)
+		}
 	}
 	d.SSHUser = flags.String("google-username")
 	d.SSHPort = 22
 	d.Userdata = flags.String("google-userdata")
+	d.Metadata = flags.StringSlice("google-metadata")
+	if err := validateMetadata(d.Metadata); err != nil {
+		return err
+	}
+	if d.Userdata != "" {
+		if err := driverutil.CheckUserDataSize(d.Userdata, maxMetadataValueSize, false); err != nil {
+			return err
+		}
+	}
+	d.AutoRestartOnPreemption = flags.Bool("google-auto-restart-on-preemption")
 	d.SetSwarmConfigFromFlags(flags)
 
 	return nil
@@ -312,6 +435,9 @@ func (d *Driver) PreCreateCheck() error {
 		if instance != nil {
 			return fmt.Errorf("instance %q already exists in zone %q", d.MachineName, d.Zone)
 		}
+		if d.InsertRequestID == "" {
+			d.InsertRequestID = uuid.NewString()
+		}
 	}
 
 	if d.Userdata != "" {
@@ -402,8 +528,8 @@ func (d *Driver) GetState() (state.State, error) {
 	// There will be no error if disk is not nil.
 	instance, err := c.instance()
 	if instance == nil {
-		if err != nil && strings.Contains(err.Error(), "not found") {
-			return state.NotFound, nil
+		if errors.Is(err, mcnerror.ErrInstanceNotFound) {
+			return state.NotFound, err
 		}
 		disk, _ := c.disk()
 		if disk == nil {
@@ -417,8 +543,28 @@ func (d *Driver) GetState() (state.State, error) {
 		return state.Starting, nil
 	case "RUNNING":
 		return state.Running, nil
-	case "STOPPING", "STOPPED", "TERMINATED":
+	case "STOPPING", "STOPPED":
 		return state.Stopped, nil
+	case "TERMINATED":
+		if !isPreemptible(instance.Scheduling) {
+			return state.Stopped, nil
+		}
+		preempted, err := c.preempted(instance)
+		if err != nil {
+			log.Warnf("Unable to check if instance %s was preempted: %s", d.MachineName, err)
+			return state.Stopped, nil
+		}
+		if !preempted {
+			return state.Stopped, nil
+		}
+		if !d.AutoRestartOnPreemption {
+			return state.Preempted, nil
+		}
+		log.Infof("Instance %s was preempted, starting it again", d.MachineName)
+		if err := c.startInstance(); err != nil {
+			return state.Preempted, err
+		}
+		return state.Running, nil
 	}
 	return state.None, nil
 }
@@ -533,5 +679,12 @@ func (d *Driver) Remove() error {
 		}
 	}
 
+	if d.PlacementPolicy != "" {
+		if err := c.cleanUpPlacementPolicy(); err != nil {
+			log.Errorf("failed remove placement policy '%s': %v", d.PlacementPolicy, err)
+			errs = append(errs, err)
+		}
+	}
+
 	return errors.Join(errs...)
 }
@@ -38,3 +38,36 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsInvalidPlacementPolicyType(t *testing.T) {
+	driver := NewDriver("", "")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"google-project":               "PROJECT",
+			"google-placement-policy":      "pp",
+			"google-placement-policy-type": "cluster",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, `invalid placement policy type "cluster" (--google-placement-policy-type), must be compact or spread`)
+}
+
+func TestSetConfigFromFlagsInvalidProvisioningModel(t *testing.T) {
+	driver := NewDriver("", "")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"google-project":            "PROJECT",
+			"google-provisioning-model": "cheap",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, `invalid provisioning model "CHEAP" (--google-provisioning-model), must be STANDARD or SPOT`)
+}
@@ -0,0 +1,54 @@
+package google
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// Resize changes the machine type of the instance. The machine type of an
+// instance can only be changed while it is stopped, so it is stopped first
+// and started again once its machine type is changed.
+func (d *Driver) Resize(size string) error {
+	c, err := newComputeUtil(d)
+	if err != nil {
+		return err
+	}
+
+	instance, err := c.instance()
+	if err != nil {
+		return unwrapGoogleError(err)
+	}
+	current := path.Base(instance.MachineType)
+	if current == size {
+		log.Infof("Instance %s is already of machine type %s", d.MachineName, size)
+		d.MachineType = size
+		return nil
+	}
+
+	if instance.Status != "TERMINATED" {
+		log.Infof("Stopping instance %s to change its machine type...", d.MachineName)
+		if err := c.stopInstance(); err != nil {
+			return unwrapGoogleError(err)
+		}
+	}
+
+	log.Infof("Changing the machine type of instance %s from %s to %s...", d.MachineName, current, size)
+	setErr := c.setMachineType(size)
+	if setErr == nil {
+		d.MachineType = size
+	}
+
+	// The instance is started again even if its machine type couldn't be
+	// changed, not to leave it stopped.
+	if err := c.startInstance(); err != nil {
+		return unwrapGoogleError(err)
+	}
+	if setErr != nil {
+		return fmt.Errorf("error changing the machine type of instance %s to %s: %w", d.MachineName, size, unwrapGoogleError(setErr))
+	}
+
+	d.IPAddress, err = d.GetIP()
+	return err
+}
@@ -17,6 +17,7 @@ import (
 type Driver struct {
 	*drivers.BaseDriver
 	Boot2DockerURL       string
+	Boot2DockerCacheDir  string
 	VSwitch              string
 	DiskSize             int
 	MemSize              int
@@ -57,6 +58,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "URL of the boot2docker ISO. Defaults to the latest available version.",
 			EnvVar: "HYPERV_BOOT2DOCKER_URL",
 		},
+		mcnflag.StringFlag{
+			Name:   "hyperv-boot2docker-cache-dir",
+			Usage:  "Directory the boot2docker images are cached in. Defaults to the cache directory of the storage path.",
+			EnvVar: "HYPERV_BOOT2DOCKER_CACHE_DIR",
+		},
 		mcnflag.StringFlag{
 			Name:   "hyperv-virtual-switch",
 			Usage:  "Virtual switch name. Defaults to first found.",
@@ -101,6 +107,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Boot2DockerURL = flags.String("hyperv-boot2docker-url")
+	d.Boot2DockerCacheDir = flags.String("hyperv-boot2docker-cache-dir")
 	d.VSwitch = flags.String("hyperv-virtual-switch")
 	d.DiskSize = flags.Int("hyperv-disk-size")
 	d.MemSize = flags.Int("hyperv-memory")
@@ -185,13 +192,13 @@ func (d *Driver) PreCreateCheck() error {
 
 	// Downloading boot2docker to cache should be done here to make sure
 	// that a download failure will not leave a machine half created.
-	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	b2dutils := mcnutils.NewB2dUtilsWithCacheDir(d.StorePath, d.Boot2DockerCacheDir)
 	err = b2dutils.UpdateISOCache(d.Boot2DockerURL)
 	return err
 }
 
 func (d *Driver) Create() error {
-	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	b2dutils := mcnutils.NewB2dUtilsWithCacheDir(d.StorePath, d.Boot2DockerCacheDir)
 	if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, d.MachineName); err != nil {
 		return err
 	}
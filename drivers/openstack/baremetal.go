@@ -0,0 +1,175 @@
+package openstack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnutils"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+const (
+	// defaultBareMetalTimeout is the time given to a bare-metal node to be
+	// deployed, which includes booting the ramdisk and writing the image.
+	defaultBareMetalTimeout = 3600
+
+	bareMetalPollInterval = 15 * time.Second
+
+	// bareMetalResourcePrefix is the prefix of the extra specs of the
+	// flavors scheduling instances on the Ironic nodes of a resource class.
+	bareMetalResourcePrefix = "resources:CUSTOM_"
+)
+
+func (c *GenericClient) InitBareMetalClient(d *Driver) error {
+	if c.BareMetal != nil {
+		return nil
+	}
+
+	bareMetal, err := openstack.NewBareMetalV1(c.Provider, gophercloud.EndpointOpts{
+		Region:       d.Region,
+		Availability: c.getEndpointType(d),
+	})
+	if err != nil {
+		return err
+	}
+	c.BareMetal = bareMetal
+	return nil
+}
+
+// GetBareMetalNodeState returns the provision state of the Ironic node the
+// instance is deployed on, or an empty string before a node is picked.
+func (c *GenericClient) GetBareMetalNodeState(d *Driver) (string, error) {
+	provisionState := ""
+
+	err := nodes.List(c.BareMetal, nodes.ListOpts{InstanceUUID: d.MachineId}).EachPage(func(page pagination.Page) (bool, error) {
+		nodeList, err := nodes.ExtractNodes(page)
+		if err != nil {
+			return false, err
+		}
+
+		if len(nodeList) > 0 {
+			provisionState = nodeList[0].ProvisionState
+			return false, nil
+		}
+
+		return true, nil
+	})
+
+	return provisionState, err
+}
+
+// IsBareMetalFlavor returns true if the flavor schedules the instances on
+// Ironic nodes.
+func (c *GenericClient) IsBareMetalFlavor(d *Driver) (bool, error) {
+	specs, err := flavors.ListExtraSpecs(c.Compute, d.FlavorId).Extract()
+	if err != nil {
+		return false, err
+	}
+
+	for key := range specs {
+		if strings.HasPrefix(key, bareMetalResourcePrefix) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (d *Driver) initBareMetal() error {
+	if err := d.client.Authenticate(d); err != nil {
+		return err
+	}
+	if err := d.client.InitBareMetalClient(d); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkBareMetalFlavor warns when the flavor doesn't look like a bare-metal
+// one, as nova would then create a virtual machine.
+func (d *Driver) checkBareMetalFlavor() {
+	bareMetal, err := d.client.IsBareMetalFlavor(d)
+	if err != nil {
+		log.Debugf("Unable to read the extra specs of flavor %s: %s", d.FlavorId, err)
+		return
+	}
+
+	if !bareMetal {
+		log.Warnf("Flavor %s has no %s* extra spec, the instance may not be scheduled on a bare-metal node", d.FlavorId, bareMetalResourcePrefix)
+	}
+}
+
+// bareMetalNodeState returns the provision state of the Ironic node of the
+// instance. The bare-metal API is usually restricted to admins, so an empty
+// state is returned when it can't be read.
+func (d *Driver) bareMetalNodeState() string {
+	if err := d.initBareMetal(); err != nil {
+		log.Debugf("Unable to reach the bare-metal API: %s", err)
+		return ""
+	}
+
+	provisionState, err := d.client.GetBareMetalNodeState(d)
+	if err != nil {
+		log.Debugf("Unable to read the state of the bare-metal node: %s", err)
+		return ""
+	}
+
+	return provisionState
+}
+
+// waitForBareMetalActive waits for the instance to be ACTIVE, reporting the
+// progress of the deployment of its node, which takes much longer than the
+// boot of a virtual machine.
+func (d *Driver) waitForBareMetalActive() error {
+	log.Infof("Waiting up to %ds for the bare-metal node to be deployed...", d.BareMetalTimeout)
+
+	previous := ""
+	return mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		status, err := d.client.GetInstanceState(d)
+		if err != nil {
+			return true, err
+		}
+
+		provisionState := d.bareMetalNodeState()
+		if provisionState != "" && provisionState != previous {
+			log.Infof("Bare-metal node is %s", provisionState)
+			previous = provisionState
+		}
+
+		if st, ok := bareMetalState(provisionState); ok && st == state.Error {
+			return true, fmt.Errorf("Bare-metal node deployment failed, node is in %q state", provisionState)
+		}
+
+		switch status {
+		case "ACTIVE":
+			return true, nil
+		case "ERROR":
+			return true, fmt.Errorf("Instance creation failed. Instance is in ERROR state")
+		}
+
+		return false, nil
+	}, d.BareMetalTimeout/int(bareMetalPollInterval/time.Second), bareMetalPollInterval)
+}
+
+// bareMetalState maps the provision state of an Ironic node to the state of
+// its machine, for the states of the deployment and cleaning that nova
+// doesn't tell apart.
+func bareMetalState(provisionState string) (state.State, bool) {
+	switch nodes.ProvisionState(provisionState) {
+	case nodes.Deploying, nodes.DeployWait, nodes.DeployDone, nodes.Rebuild:
+		return state.Starting, true
+	case nodes.Deleting, nodes.Cleaning, nodes.CleanWait:
+		return state.Stopping, true
+	case nodes.DeployFail, nodes.CleanFail, nodes.Error:
+		return state.Error, true
+	}
+
+	return state.None, false
+}
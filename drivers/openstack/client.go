@@ -35,6 +35,7 @@ type Client interface {
 	InitComputeClient(d *Driver) error
 	InitNetworkClient(d *Driver) error
 	InitBlockStorageClient(d *Driver) error
+	InitBareMetalClient(d *Driver) error
 
 	CreateInstance(d *Driver) (string, error)
 	GetInstanceState(d *Driver) (string, error)
@@ -60,6 +61,8 @@ type Client interface {
 	VolumeCreate(d *Driver) (string, error)
 	WaitForVolumeStatus(d *Driver, status string) error
 	VolumeAttach(d *Driver) (string, error)
+	GetBareMetalNodeState(d *Driver) (string, error)
+	IsBareMetalFlavor(d *Driver) (bool, error)
 }
 
 type GenericClient struct {
@@ -67,6 +70,7 @@ type GenericClient struct {
 	Compute      *gophercloud.ServiceClient
 	Network      *gophercloud.ServiceClient
 	BlockStorage *gophercloud.ServiceClient
+	BareMetal    *gophercloud.ServiceClient
 }
 
 func (c *GenericClient) CreateInstance(d *Driver) (string, error) {
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rancher/machine/drivers/driverutil"
 	"github.com/rancher/machine/libmachine/drivers"
 	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
 	"github.com/rancher/machine/libmachine/log"
@@ -69,6 +70,8 @@ type Driver struct {
 	VolumeId                    string
 	VolumeType                  string
 	VolumeSize                  int
+	BareMetal                   bool
+	BareMetalTimeout            int
 	client                      Client
 	// ExistingKey keeps track of whether the key was created by us or we used an existing one. If an existing one was used, we shouldn't delete it when the machine is deleted.
 	ExistingKey bool
@@ -78,6 +81,9 @@ const (
 	defaultSSHUser       = "root"
 	defaultSSHPort       = 22
 	defaultActiveTimeout = 200
+
+	// maxUserDataSize is the limit of nova on the base64 encoded user data.
+	maxUserDataSize = 65535
 )
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -317,6 +323,15 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:  "openstack-boot-from-volume",
 			Usage: "Enables Openstack instance to boot from volume as ROOT",
 		},
+		mcnflag.BoolFlag{
+			Name:  "openstack-bare-metal",
+			Usage: "Create the instance on a bare-metal node provisioned by Ironic, with a bare-metal flavor",
+		},
+		mcnflag.IntFlag{
+			Name:  "openstack-bare-metal-timeout",
+			Usage: "Timeout in seconds of the deployment of the bare-metal node",
+			Value: defaultBareMetalTimeout,
+		},
 		mcnflag.StringFlag{
 			Name:  "openstack-volume-name",
 			Usage: "OpenStack volume name (creating); Default: 'rancher-machine-name'",
@@ -351,8 +366,9 @@ func NewDriver(hostName, storePath string) drivers.Driver {
 
 func NewDerivedDriver(hostName, storePath string) *Driver {
 	return &Driver{
-		client:        &GenericClient{},
-		ActiveTimeout: defaultActiveTimeout,
+		client:           &GenericClient{},
+		ActiveTimeout:    defaultActiveTimeout,
+		BareMetalTimeout: defaultBareMetalTimeout,
 		BaseDriver: &drivers.BaseDriver{
 			SSHUser:     defaultSSHUser,
 			SSHPort:     defaultSSHPort,
@@ -464,7 +480,13 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.VolumeType = flags.String("openstack-volume-type")
 	d.VolumeSize = flags.Int("openstack-volume-size")
 
+	d.BareMetal = flags.Bool("openstack-bare-metal")
+	d.BareMetalTimeout = flags.Int("openstack-bare-metal-timeout")
+
 	if flags.String("openstack-user-data-file") != "" {
+		if err := driverutil.CheckUserDataSize(flags.String("openstack-user-data-file"), maxUserDataSize, true); err != nil {
+			return err
+		}
 		userData, err := os.ReadFile(flags.String("openstack-user-data-file"))
 		if err == nil {
 			d.UserData = userData
@@ -551,10 +573,21 @@ func (d *Driver) GetState() (state.State, error) {
 		return state.Saved, nil
 	case "SHUTOFF":
 		return state.Stopped, nil
-	case "BUILDING":
+	case "BUILD", "BUILDING":
+		if d.BareMetal {
+			if st, ok := bareMetalState(d.bareMetalNodeState()); ok {
+				return st, nil
+			}
+		}
 		return state.Starting, nil
 	case "ERROR":
 		return state.Error, nil
+	case "DELETED", "SOFT_DELETED":
+		if d.BareMetal {
+			if st, ok := bareMetalState(d.bareMetalNodeState()); ok {
+				return st, nil
+			}
+		}
 	}
 	return state.None, nil
 }
@@ -585,6 +618,9 @@ func (d *Driver) Create() error {
 			return err
 		}
 	}
+	if d.BareMetal {
+		d.checkBareMetalFlavor()
+	}
 	if err := d.createMachine(); err != nil {
 		return err
 	}
@@ -634,6 +670,11 @@ func (d *Driver) Restart() error {
 	return d.client.RestartInstance(d)
 }
 
+// Reboot soft reboots the instance, like Restart.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
 func (d *Driver) Kill() error {
 	return d.Stop()
 }
@@ -689,6 +730,8 @@ const (
 	errorExclusiveOptions       string = "Either %s or %s must be specified, not both"
 	errorBothOptions            string = "Both %s and %s must be specified"
 	errorWrongEndpointType      string = "Endpoint type must be 'publicURL', 'adminURL' or 'internalURL'"
+	errorBareMetalVolume        string = "Volumes can't be used with bare-metal instances"
+	errorBareMetalTimeout       string = "The bare-metal timeout must be at least %d seconds"
 	errorUnknownFlavorName      string = "Unable to find flavor named %s"
 	errorUnknownImageName       string = "Unable to find image named %s"
 	errorUnknownServerGroupName string = "Unable to find server group named %s"
@@ -750,6 +793,12 @@ func (d *Driver) checkConfig() error {
 	if (d.KeyPairName != "" && d.PrivateKeyFile == "") || (d.KeyPairName == "" && d.PrivateKeyFile != "") {
 		return fmt.Errorf(errorBothOptions, "KeyPairName", "PrivateKeyFile")
 	}
+	if d.BareMetal && d.requiresBlockStorage() {
+		return fmt.Errorf(errorBareMetalVolume)
+	}
+	if d.BareMetal && d.BareMetalTimeout < int(bareMetalPollInterval/time.Second) {
+		return fmt.Errorf(errorBareMetalTimeout, int(bareMetalPollInterval/time.Second))
+	}
 	return nil
 }
 
@@ -1035,6 +1084,10 @@ func (d *Driver) assignFloatingIP() error {
 }
 
 func (d *Driver) waitForInstanceActive() error {
+	if d.BareMetal {
+		return d.waitForBareMetalActive()
+	}
+
 	log.Debug("Waiting for the OpenStack instance to be ACTIVE...", map[string]string{"MachineId": d.MachineId})
 	if err := d.client.WaitForInstanceStatus(d, "ACTIVE"); err != nil {
 		return err
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/rancher/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,3 +50,48 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsBareMetalVolume(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"openstack-auth-url":    "http://url",
+			"openstack-username":    "user",
+			"openstack-password":    "pwd",
+			"openstack-tenant-id":   "ID",
+			"openstack-flavor-id":   "ID",
+			"openstack-image-id":    "ID",
+			"openstack-bare-metal":  true,
+			"openstack-volume-size": 10,
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, errorBareMetalVolume)
+}
+
+func TestBareMetalState(t *testing.T) {
+	tests := []struct {
+		provisionState string
+		expected       state.State
+		known          bool
+	}{
+		{"deploying", state.Starting, true},
+		{"wait call-back", state.Starting, true},
+		{"cleaning", state.Stopping, true},
+		{"clean wait", state.Stopping, true},
+		{"deploy failed", state.Error, true},
+		{"error", state.Error, true},
+		{"active", state.None, false},
+		{"", state.None, false},
+	}
+
+	for _, test := range tests {
+		st, known := bareMetalState(test.provisionState)
+		assert.Equal(t, test.expected, st, test.provisionState)
+		assert.Equal(t, test.known, known, test.provisionState)
+	}
+}
@@ -565,6 +565,12 @@ func (d *Driver) Restart() error {
 	return d.getClient().VirtualGuest().Reboot(d.Id)
 }
 
+// Reboot reboots the virtual guest with the reboot API, which Restart
+// already uses.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
 func (d *Driver) Kill() error {
 	return d.Stop()
 }
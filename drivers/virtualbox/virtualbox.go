@@ -59,6 +59,7 @@ type Driver struct {
 	DiskSize            int
 	NatNicType          string
 	Boot2DockerURL      string
+	Boot2DockerCacheDir string
 	Boot2DockerImportVM string
 	HostDNSResolver     bool
 	HostOnlyCIDR        string
@@ -130,6 +131,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Value:  defaultBoot2DockerURL,
 			EnvVar: "VIRTUALBOX_BOOT2DOCKER_URL",
 		},
+		mcnflag.StringFlag{
+			Name:   "virtualbox-boot2docker-cache-dir",
+			Usage:  "The directory the boot2docker images are cached in. Defaults to the cache directory of the storage path",
+			EnvVar: "VIRTUALBOX_BOOT2DOCKER_CACHE_DIR",
+		},
 		mcnflag.StringFlag{
 			Name:   "virtualbox-import-boot2docker-vm",
 			Usage:  "The name of a Boot2Docker VM to import",
@@ -232,6 +238,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Memory = flags.Int("virtualbox-memory")
 	d.DiskSize = flags.Int("virtualbox-disk-size")
 	d.Boot2DockerURL = flags.String("virtualbox-boot2docker-url")
+	d.Boot2DockerCacheDir = flags.String("virtualbox-boot2docker-cache-dir")
 	d.SetSwarmConfigFromFlags(flags)
 	d.SSHUser = "docker"
 	d.Boot2DockerImportVM = flags.String("virtualbox-import-boot2docker-vm")
@@ -275,7 +282,7 @@ func (d *Driver) PreCreateCheck() error {
 
 	// Downloading boot2docker to cache should be done here to make sure
 	// that a download failure will not leave a machine half created.
-	if err := d.b2dUpdater.UpdateISOCache(d.StorePath, d.Boot2DockerURL); err != nil {
+	if err := d.b2dUpdater.UpdateISOCache(d.StorePath, d.Boot2DockerCacheDir, d.Boot2DockerURL); err != nil {
 		return err
 	}
 
@@ -297,7 +304,7 @@ func (d *Driver) Create() error {
 }
 
 func (d *Driver) CreateVM() error {
-	if err := d.b2dUpdater.CopyIsoToMachineDir(d.StorePath, d.MachineName, d.Boot2DockerURL); err != nil {
+	if err := d.b2dUpdater.CopyIsoToMachineDir(d.StorePath, d.Boot2DockerCacheDir, d.MachineName, d.Boot2DockerURL); err != nil {
 		return err
 	}
 
@@ -423,13 +423,13 @@ func (v *MockCreateOperations) vbmOutErr(args ...string) (string, string, error)
 	return output, "", err
 }
 
-func (v *MockCreateOperations) UpdateISOCache(storePath, isoURL string) error {
-	_, err := v.doCall("UpdateISOCache " + storePath + " " + isoURL)
+func (v *MockCreateOperations) UpdateISOCache(storePath, cacheDir, isoURL string) error {
+	_, err := v.doCall("UpdateISOCache " + storePath + " " + cacheDir + " " + isoURL)
 	return err
 }
 
-func (v *MockCreateOperations) CopyIsoToMachineDir(storePath, machineName, isoURL string) error {
-	_, err := v.doCall("CopyIsoToMachineDir " + storePath + " " + machineName + " " + isoURL)
+func (v *MockCreateOperations) CopyIsoToMachineDir(storePath, cacheDir, machineName, isoURL string) error {
+	_, err := v.doCall("CopyIsoToMachineDir " + storePath + " " + cacheDir + " " + machineName + " " + isoURL)
 	return err
 }
 
@@ -502,6 +502,7 @@ func mockCalls(t *testing.T, driver *Driver, expectedCalls []Call) {
 	}
 
 	driver.Boot2DockerURL = "http://b2d.org"
+	driver.Boot2DockerCacheDir = "cache"
 	driver.VBoxManager = mockOperations
 	driver.b2dUpdater = mockOperations
 	driver.sshKeyGenerator = mockOperations
@@ -523,7 +524,7 @@ func TestCreateVM(t *testing.T) {
 
 	driver := NewDriver("default", "path")
 	mockCalls(t, driver, []Call{
-		{"CopyIsoToMachineDir path default http://b2d.org", "", nil},
+		{"CopyIsoToMachineDir path cache default http://b2d.org", "", nil},
 		{"Generate path/machines/default/id_rsa", "", nil},
 		{"Create 20000 path/machines/default/id_rsa.pub path/machines/default/disk.vmdk", "", nil},
 		{"vbm createvm --basefolder path/machines/default --name default --register", "", nil},
@@ -554,7 +555,7 @@ func TestCreateVMWithSpecificNatNicType(t *testing.T) {
 	driver := NewDriver("default", "path")
 	driver.NatNicType = "Am79C973"
 	mockCalls(t, driver, []Call{
-		{"CopyIsoToMachineDir path default http://b2d.org", "", nil},
+		{"CopyIsoToMachineDir path cache default http://b2d.org", "", nil},
 		{"Generate path/machines/default/id_rsa", "", nil},
 		{"Create 20000 path/machines/default/id_rsa.pub path/machines/default/disk.vmdk", "", nil},
 		{"vbm createvm --basefolder path/machines/default --name default --register", "", nil},
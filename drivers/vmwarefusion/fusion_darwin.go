@@ -39,11 +39,12 @@ const (
 // Driver for VMware Fusion
 type Driver struct {
 	*drivers.BaseDriver
-	Memory         int
-	DiskSize       int
-	CPU            int
-	ISO            string
-	Boot2DockerURL string
+	Memory              int
+	DiskSize            int
+	CPU                 int
+	ISO                 string
+	Boot2DockerURL      string
+	Boot2DockerCacheDir string
 
 	SSHPassword    string
 	ConfigDriveISO string
@@ -69,6 +70,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Fusion URL for boot2docker image",
 			Value:  "",
 		},
+		mcnflag.StringFlag{
+			EnvVar: "FUSION_BOOT2DOCKER_CACHE_DIR",
+			Name:   "vmwarefusion-boot2docker-cache-dir",
+			Usage:  "Directory the boot2docker images are cached in, defaults to the cache directory of the storage path",
+		},
 		mcnflag.StringFlag{
 			EnvVar: "FUSION_CONFIGDRIVE_URL",
 			Name:   "vmwarefusion-configdrive-url",
@@ -180,6 +186,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.CPU = flags.Int("vmwarefusion-cpu-count")
 	d.DiskSize = flags.Int("vmwarefusion-disk-size")
 	d.Boot2DockerURL = flags.String("vmwarefusion-boot2docker-url")
+	d.Boot2DockerCacheDir = flags.String("vmwarefusion-boot2docker-cache-dir")
 	d.ConfigDriveURL = flags.String("vmwarefusion-configdrive-url")
 	d.ISO = d.ResolveStorePath(isoFilename)
 	d.ConfigDriveISO = d.ResolveStorePath(isoConfigDrive)
@@ -257,13 +264,13 @@ func (d *Driver) GetState() (state.State, error) {
 func (d *Driver) PreCreateCheck() error {
 	// Downloading boot2docker to cache should be done here to make sure
 	// that a download failure will not leave a machine half created.
-	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	b2dutils := mcnutils.NewB2dUtilsWithCacheDir(d.StorePath, d.Boot2DockerCacheDir)
 
 	return b2dutils.UpdateISOCache(d.Boot2DockerURL)
 }
 
 func (d *Driver) Create() error {
-	b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+	b2dutils := mcnutils.NewB2dUtilsWithCacheDir(d.StorePath, d.Boot2DockerCacheDir)
 	if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, d.MachineName); err != nil {
 		return err
 	}
@@ -51,6 +51,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "vmwarevsphere-boot2docker-url",
 			Usage:  "vSphere URL for boot2docker image",
 		},
+		mcnflag.StringFlag{
+			EnvVar: "VSPHERE_BOOT2DOCKER_CACHE_DIR",
+			Name:   "vmwarevsphere-boot2docker-cache-dir",
+			Usage:  "Directory the boot2docker images are cached in, defaults to the cache directory of the storage path",
+		},
 		mcnflag.StringFlag{
 			EnvVar: "VSPHERE_VCENTER",
 			Name:   "vmwarevsphere-vcenter",
@@ -258,6 +263,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Memory = flags.Int("vmwarevsphere-memory-size")
 	d.DiskSize = flags.Int("vmwarevsphere-disk-size")
 	d.Boot2DockerURL = flags.String("vmwarevsphere-boot2docker-url")
+	d.Boot2DockerCacheDir = flags.String("vmwarevsphere-boot2docker-cache-dir")
 	d.IP = flags.String("vmwarevsphere-vcenter")
 	d.Port = flags.Int("vmwarevsphere-vcenter-port")
 	d.Username = flags.String("vmwarevsphere-username")
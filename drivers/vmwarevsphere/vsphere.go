@@ -40,13 +40,14 @@ const (
 
 type Driver struct {
 	*drivers.BaseDriver
-	Memory         int
-	DiskSize       int
-	CPU            int
-	ISO            string
-	Boot2DockerURL string
-	CPUS           int
-	MachineId      string
+	Memory              int
+	DiskSize            int
+	CPU                 int
+	ISO                 string
+	Boot2DockerURL      string
+	Boot2DockerCacheDir string
+	CPUS                int
+	MachineId           string
 
 	IP                      string
 	Port                    int
@@ -320,7 +321,7 @@ func (d *Driver) Create() error {
 	switch d.CreationType {
 	case "legacy":
 		log.Infof("creating VM %s", d.MachineName)
-		b2dutils := mcnutils.NewB2dUtils(d.StorePath)
+		b2dutils := mcnutils.NewB2dUtilsWithCacheDir(d.StorePath, d.Boot2DockerCacheDir)
 		if err := b2dutils.CopyIsoToMachineDir(d.Boot2DockerURL, d.MachineName); err != nil {
 			return err
 		}
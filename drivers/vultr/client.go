@@ -0,0 +1,245 @@
+package vultr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/rancher/machine/libmachine/mcnutils"
+)
+
+const (
+	defaultAPIURL = "https://api.vultr.com/v2"
+
+	// Number of attempts made for a request answered with a rate-limit or
+	// transient server error before giving up.
+	maxAttempts = 6
+
+	// Number of polls of a new instance, activePollInterval apart, before
+	// giving up waiting for it to be active.
+	activePollAttempts = 120
+)
+
+var (
+	retryBaseDelay     = time.Second
+	activePollInterval = 5 * time.Second
+)
+
+// apiError is returned for any non-2xx answer of the API.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("vultr API returned %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, mcnerror.ErrInstanceNotFound)
+}
+
+type instance struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	MainIP      string `json:"main_ip"`
+	V6MainIP    string `json:"v6_main_ip"`
+	InternalIP  string `json:"internal_ip"`
+	Status      string `json:"status"`
+	PowerStatus string `json:"power_status"`
+	// ServerStatus is "ok" once the OS has finished booting.
+	ServerStatus string `json:"server_status"`
+}
+
+type instanceCreateRequest struct {
+	Region     string   `json:"region"`
+	Plan       string   `json:"plan"`
+	OsID       int      `json:"os_id"`
+	Label      string   `json:"label"`
+	Hostname   string   `json:"hostname"`
+	SSHKeyIDs  []string `json:"sshkey_id"`
+	EnableIPv6 bool     `json:"enable_ipv6"`
+	AttachVPC  []string `json:"attach_vpc,omitempty"`
+	UserData   string   `json:"user_data,omitempty"`
+}
+
+type sshKey struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	SSHKey string `json:"ssh_key"`
+}
+
+type region struct {
+	ID string `json:"id"`
+}
+
+// client is a minimal client of the Vultr v2 API covering what the driver
+// needs.
+type client struct {
+	apiURL     string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newClient(apiKey string) *client {
+	return &client{
+		apiURL: defaultAPIURL,
+		apiKey: apiKey,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+func (c *client) createInstance(req *instanceCreateRequest) (*instance, error) {
+	var resp struct {
+		Instance instance `json:"instance"`
+	}
+	if err := c.do(http.MethodPost, "/instances", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Instance, nil
+}
+
+func (c *client) getInstance(id string) (*instance, error) {
+	var resp struct {
+		Instance instance `json:"instance"`
+	}
+	if err := c.do(http.MethodGet, "/instances/"+id, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Instance, nil
+}
+
+// waitForActive polls the instance until it is active and has its main IP,
+// failing with an ErrTimeout error when it still isn't after
+// activePollAttempts polls.
+func (c *client) waitForActive(id string) (*instance, error) {
+	var inst *instance
+	err := mcnutils.WaitForSpecificOrError(func() (bool, error) {
+		var err error
+		if inst, err = c.getInstance(id); err != nil {
+			return false, err
+		}
+		return inst.Status == "active" && inst.MainIP != "" && inst.MainIP != "0.0.0.0", nil
+	}, activePollAttempts, activePollInterval)
+	if errors.Is(err, mcnerror.ErrTimeout) {
+		return nil, fmt.Errorf("timed out waiting for the instance %s to be active: %w", id, err)
+	}
+
+	return inst, err
+}
+
+func (c *client) deleteInstance(id string) error {
+	return c.do(http.MethodDelete, "/instances/"+id, nil, nil)
+}
+
+func (c *client) startInstance(id string) error {
+	return c.do(http.MethodPost, "/instances/"+id+"/start", nil, nil)
+}
+
+func (c *client) haltInstance(id string) error {
+	return c.do(http.MethodPost, "/instances/"+id+"/halt", nil, nil)
+}
+
+func (c *client) rebootInstance(id string) error {
+	return c.do(http.MethodPost, "/instances/"+id+"/reboot", nil, nil)
+}
+
+func (c *client) createSSHKey(name, publicKey string) (*sshKey, error) {
+	var resp struct {
+		SSHKey sshKey `json:"ssh_key"`
+	}
+	req := &sshKey{Name: name, SSHKey: publicKey}
+	if err := c.do(http.MethodPost, "/ssh-keys", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.SSHKey, nil
+}
+
+func (c *client) deleteSSHKey(id string) error {
+	return c.do(http.MethodDelete, "/ssh-keys/"+id, nil, nil)
+}
+
+func (c *client) listRegions() ([]region, error) {
+	var resp struct {
+		Regions []region `json:"regions"`
+	}
+	if err := c.do(http.MethodGet, "/regions?per_page=500", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Regions, nil
+}
+
+// do sends a request to the API and decodes the answer in out, if not nil.
+// Rate-limited (429) and unavailable (503) answers are retried with an
+// exponential backoff.
+func (c *client) do(method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, c.apiURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < maxAttempts {
+			log.Debugf("Vultr API answered %d to %s %s, retrying in %s", resp.StatusCode, method, path, delay)
+			time.Sleep(delay)
+			delay *= 2
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			var body struct {
+				Error string `json:"error"`
+			}
+			message := string(data)
+			if json.Unmarshal(data, &body) == nil && body.Error != "" {
+				message = body.Error
+			}
+			apiErr := &apiError{StatusCode: resp.StatusCode, Message: message}
+			switch {
+			case resp.StatusCode == http.StatusNotFound:
+				return mcnerror.NotFound(apiErr)
+			case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+				return mcnerror.MarkTransient(apiErr)
+			}
+			return apiErr
+		}
+
+		if out == nil || len(data) == 0 {
+			return nil
+		}
+
+		return json.Unmarshal(data, out)
+	}
+}
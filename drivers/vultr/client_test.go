@@ -0,0 +1,94 @@
+package vultr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (*client, func()) {
+	server := httptest.NewServer(handler)
+	c := newClient("KEY")
+	c.apiURL = server.URL
+	return c, server.Close
+}
+
+func TestClientRetriesRateLimitedRequests(t *testing.T) {
+	defer func(delay time.Duration) { retryBaseDelay = delay }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	calls := 0
+	c, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "Bearer KEY", r.Header.Get("Authorization"))
+		if calls < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"instance": {"id": "abc", "status": "active", "power_status": "running"}}`)
+	})
+	defer closeServer()
+
+	inst, err := c.getInstance("abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "abc", inst.ID)
+	assert.Equal(t, "running", inst.PowerStatus)
+}
+
+func TestClientNotFound(t *testing.T) {
+	c, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": "instance not found"}`)
+	})
+	defer closeServer()
+
+	_, err := c.getInstance("abc")
+
+	assert.True(t, isNotFound(err))
+	assert.EqualError(t, err, "vultr API returned 404: instance not found")
+}
+
+func TestClientWaitForActive(t *testing.T) {
+	defer func(interval time.Duration) { activePollInterval = interval }(activePollInterval)
+	activePollInterval = time.Millisecond
+
+	calls := 0
+	c, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			fmt.Fprint(w, `{"instance": {"id": "abc", "status": "pending", "main_ip": "0.0.0.0"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"instance": {"id": "abc", "status": "active", "main_ip": "203.0.113.10"}}`)
+	})
+	defer closeServer()
+
+	inst, err := c.waitForActive("abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "203.0.113.10", inst.MainIP)
+}
+
+func TestClientWaitForActiveTimeout(t *testing.T) {
+	defer func(interval time.Duration) { activePollInterval = interval }(activePollInterval)
+	activePollInterval = time.Millisecond
+
+	c, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"instance": {"id": "abc", "status": "pending"}}`)
+	})
+	defer closeServer()
+
+	_, err := c.waitForActive("abc")
+
+	assert.True(t, errors.Is(err, mcnerror.ErrTimeout))
+	assert.Contains(t, err.Error(), "timed out waiting for the instance abc to be active")
+}
@@ -0,0 +1,357 @@
+package vultr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/rancher/machine/libmachine/drivers"
+	rpcdriver "github.com/rancher/machine/libmachine/drivers/rpc"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnflag"
+	"github.com/rancher/machine/libmachine/ssh"
+	"github.com/rancher/machine/libmachine/state"
+)
+
+type Driver struct {
+	*drivers.BaseDriver
+	APIKey       string
+	InstanceID   string
+	SSHKeyID     string
+	Region       string
+	Plan         string
+	OsID         int
+	VPCID        string
+	IPv6         bool
+	UserDataFile string
+}
+
+const (
+	defaultSSHPort = 22
+	defaultSSHUser = "root"
+	defaultRegion  = "ewr"
+	defaultPlan    = "vc2-1c-1gb"
+	// Ubuntu 22.04 LTS x64
+	defaultOsID = 1743
+)
+
+// GetCreateFlags registers the flags this driver adds to
+// "docker hosts create"
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_API_KEY",
+			Name:   "vultr-api-key",
+			Usage:  "Vultr API key",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_REGION",
+			Name:   "vultr-region",
+			Usage:  "Vultr region id",
+			Value:  defaultRegion,
+		},
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_PLAN",
+			Name:   "vultr-plan",
+			Usage:  "Vultr plan id",
+			Value:  defaultPlan,
+		},
+		mcnflag.IntFlag{
+			EnvVar: "VULTR_OS_ID",
+			Name:   "vultr-os-id",
+			Usage:  "Vultr operating system id",
+			Value:  defaultOsID,
+		},
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_VPC_ID",
+			Name:   "vultr-vpc-id",
+			Usage:  "Id of the VPC to attach the instance to",
+		},
+		mcnflag.BoolFlag{
+			EnvVar: "VULTR_IPV6",
+			Name:   "vultr-ipv6",
+			Usage:  "enable ipv6 for the instance",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_USERDATA",
+			Name:   "vultr-userdata",
+			Usage:  "path to file with cloud-init user-data",
+		},
+		mcnflag.StringFlag{
+			EnvVar: "VULTR_SSH_USER",
+			Name:   "vultr-ssh-user",
+			Usage:  "SSH username",
+			Value:  defaultSSHUser,
+		},
+		mcnflag.IntFlag{
+			EnvVar: "VULTR_SSH_PORT",
+			Name:   "vultr-ssh-port",
+			Usage:  "SSH port",
+			Value:  defaultSSHPort,
+		},
+	}
+}
+
+func NewDriver(hostName, storePath string) *Driver {
+	return &Driver{
+		Region: defaultRegion,
+		Plan:   defaultPlan,
+		OsID:   defaultOsID,
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return d.GetIP()
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return "vultr"
+}
+
+// UnmarshalJSON loads driver config from JSON. This function is used by the RPCServerDriver that wraps
+// all drivers as a means of populating an already-initialized driver with new configuration.
+// See `RPCServerDriver.SetConfigRaw`.
+func (d *Driver) UnmarshalJSON(data []byte) error {
+	// Unmarshal driver config into an aliased type to prevent infinite recursion on UnmarshalJSON.
+	type targetDriver Driver
+
+	// Copy data from `d` to `target` before unmarshalling. This will ensure that already-initialized values
+	// from `d` that are left untouched during unmarshal (like functions) are preserved.
+	target := targetDriver(*d)
+
+	if err := json.Unmarshal(data, &target); err != nil {
+		return fmt.Errorf("error unmarshalling driver config from JSON: %w", err)
+	}
+
+	// Copy unmarshalled data back to `d`.
+	*d = Driver(target)
+
+	// Make sure to reload values that are subject to change from envvars and os.Args.
+	driverOpts := rpcdriver.GetDriverOpts(d.GetCreateFlags(), os.Args)
+	if _, ok := driverOpts.Values["vultr-api-key"]; ok {
+		d.APIKey = driverOpts.String("vultr-api-key")
+	}
+
+	return nil
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.APIKey = flags.String("vultr-api-key")
+	d.Region = flags.String("vultr-region")
+	d.Plan = flags.String("vultr-plan")
+	d.OsID = flags.Int("vultr-os-id")
+	d.VPCID = flags.String("vultr-vpc-id")
+	d.IPv6 = flags.Bool("vultr-ipv6")
+	d.UserDataFile = flags.String("vultr-userdata")
+	d.SSHUser = flags.String("vultr-ssh-user")
+	d.SSHPort = flags.Int("vultr-ssh-port")
+
+	d.SetSwarmConfigFromFlags(flags)
+
+	if d.APIKey == "" {
+		return fmt.Errorf("vultr driver requires the --vultr-api-key option")
+	}
+
+	return nil
+}
+
+func (d *Driver) PreCreateCheck() error {
+	if d.UserDataFile != "" {
+		if _, err := os.Stat(d.UserDataFile); os.IsNotExist(err) {
+			return fmt.Errorf("user-data file %s could not be found", d.UserDataFile)
+		}
+	}
+
+	regions, err := d.getClient().listRegions()
+	if err != nil {
+		return err
+	}
+	for _, r := range regions {
+		if r.ID == d.Region {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("vultr requires a valid region, %q is not one", d.Region)
+}
+
+func (d *Driver) Create() error {
+	var userdata string
+	if d.UserDataFile != "" {
+		buf, err := os.ReadFile(d.UserDataFile)
+		if err != nil {
+			return err
+		}
+		userdata = base64.StdEncoding.EncodeToString(buf)
+	}
+
+	log.Infof("Creating SSH key...")
+
+	key, err := d.createSSHKey()
+	if err != nil {
+		return err
+	}
+
+	d.SSHKeyID = key.ID
+
+	log.Infof("Creating Vultr instance...")
+
+	client := d.getClient()
+
+	createRequest := &instanceCreateRequest{
+		Region:     d.Region,
+		Plan:       d.Plan,
+		OsID:       d.OsID,
+		Label:      d.MachineName,
+		Hostname:   d.MachineName,
+		SSHKeyIDs:  []string{d.SSHKeyID},
+		EnableIPv6: d.IPv6,
+		UserData:   userdata,
+	}
+	if d.VPCID != "" {
+		createRequest.AttachVPC = []string{d.VPCID}
+	}
+
+	newInstance, err := client.createInstance(createRequest)
+	if err != nil {
+		return err
+	}
+
+	d.InstanceID = newInstance.ID
+
+	log.Info("Waiting for the Vultr instance to be active...")
+	newInstance, err = client.waitForActive(d.InstanceID)
+	if err != nil {
+		if removeErr := d.Remove(); removeErr != nil {
+			return fmt.Errorf("failed to create machine due to error: %v. Removing instance: %v", err, removeErr)
+		}
+		return err
+	}
+
+	d.IPAddress = newInstance.MainIP
+	if d.IPv6 {
+		d.IPv6Address = newInstance.V6MainIP
+	}
+
+	log.Debugf("Created instance ID %s, IP address %s, IPv6 address %s",
+		d.InstanceID,
+		d.IPAddress,
+		d.IPv6Address)
+
+	log.Info("Waiting for SSH to be available...")
+	return drivers.WaitForSSH(d)
+}
+
+func (d *Driver) createSSHKey() (*sshKey, error) {
+	d.SSHKeyPath = d.GetSSHKeyPath()
+
+	if err := ssh.GenerateSSHKey(d.SSHKeyPath); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := os.ReadFile(d.publicSSHKeyPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return d.getClient().createSSHKey(d.MachineName, string(publicKey))
+}
+
+func (d *Driver) GetURL() (string, error) {
+	if err := drivers.MustBeRunning(d); err != nil {
+		return "", err
+	}
+
+	ip, err := d.GetIP()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	inst, err := d.getClient().getInstance(d.InstanceID)
+	if err != nil {
+		if isNotFound(err) {
+			return state.NotFound, err
+		}
+		return state.Error, err
+	}
+
+	switch inst.Status {
+	case "pending":
+		return state.Starting, nil
+	case "suspended":
+		return state.Stopped, nil
+	case "active":
+		switch inst.PowerStatus {
+		case "running":
+			return state.Running, nil
+		case "stopped":
+			return state.Stopped, nil
+		}
+	}
+	return state.None, nil
+}
+
+func (d *Driver) Start() error {
+	return d.getClient().startInstance(d.InstanceID)
+}
+
+// Stop halts the instance, the Vultr API has no graceful shutdown.
+func (d *Driver) Stop() error {
+	return d.getClient().haltInstance(d.InstanceID)
+}
+
+func (d *Driver) Restart() error {
+	return d.getClient().rebootInstance(d.InstanceID)
+}
+
+// Reboot reboots the instance with the reboot API, which Restart already
+// uses.
+func (d *Driver) Reboot() error {
+	return d.Restart()
+}
+
+func (d *Driver) Kill() error {
+	return d.getClient().haltInstance(d.InstanceID)
+}
+
+func (d *Driver) Remove() error {
+	client := d.getClient()
+	if d.SSHKeyID != "" {
+		if err := client.deleteSSHKey(d.SSHKeyID); err != nil {
+			if isNotFound(err) {
+				log.Infof("Vultr SSH key doesn't exist, assuming it is already deleted")
+			} else {
+				return err
+			}
+		}
+	}
+	if d.InstanceID != "" {
+		if err := client.deleteInstance(d.InstanceID); err != nil {
+			if isNotFound(err) {
+				log.Infof("Vultr instance doesn't exist, assuming it is already deleted")
+			} else {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Driver) getClient() *client {
+	return newClient(d.APIKey)
+}
+
+func (d *Driver) publicSSHKeyPath() string {
+	return d.GetSSHKeyPath() + ".pub"
+}
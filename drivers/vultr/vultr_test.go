@@ -0,0 +1,42 @@
+package vultr
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigFromFlags(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"vultr-api-key": "KEY",
+			"vultr-vpc-id":  "vpc-1",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, checkFlags.InvalidFlags)
+	assert.Equal(t, "KEY", driver.APIKey)
+	assert.Equal(t, "vpc-1", driver.VPCID)
+	assert.Equal(t, defaultRegion, driver.Region)
+	assert.Equal(t, defaultOsID, driver.OsID)
+}
+
+func TestSetConfigFromFlagsRequiresAPIKey(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.EqualError(t, err, "vultr driver requires the --vultr-api-key option")
+}
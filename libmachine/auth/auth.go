@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 type Options struct {
 	CertDir              string
 	CaCertPath           string
@@ -12,6 +14,19 @@ type Options struct {
 	ServerKeyRemotePath  string
 	ClientCertPath       string
 	ServerCertSANs       []string
+	// TLSMinVersion is the minimum TLS version ("1.2" or "1.3") used to
+	// talk to the daemon. Empty means "1.2".
+	TLSMinVersion string
+	// TLSCipherSuites are the names of the TLS 1.2 cipher suites used to
+	// talk to the daemon. Empty means the default AEAD suites.
+	TLSCipherSuites []string `json:",omitempty"`
+	// CertDuration and CADuration are the validity of the generated client
+	// and server certs and of the CA. Zero means cert.DefaultCertDuration.
+	CertDuration time.Duration
+	CADuration   time.Duration
+	// CAName is the named CA of the machine, whose CA and client cert are
+	// in CertDir. Empty means the default CA of the store.
+	CAName string `json:",omitempty"`
 	// StorePath is left in for historical reasons, but not really meant to
 	// be used directly.
 	StorePath string
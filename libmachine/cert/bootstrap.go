@@ -1,6 +1,8 @@
 package cert
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
@@ -21,7 +23,7 @@ func createCACert(authOptions *auth.Options, caOrg string, bits int) error {
 		return errors.New("certificate authority key already exists")
 	}
 
-	if err := GenerateCACertificate(caCertPath, caPrivateKeyPath, caOrg, bits); err != nil {
+	if err := GenerateCACertificateWithDuration(caCertPath, caPrivateKeyPath, caOrg, bits, authOptions.CADuration); err != nil {
 		return fmt.Errorf("generating CA certificate failed: %s", err)
 	}
 
@@ -62,6 +64,7 @@ func createCert(authOptions *auth.Options, org string, bits int) error {
 		Org:         org,
 		Bits:        bits,
 		SwarmMaster: false,
+		Duration:    authOptions.CertDuration,
 	}
 
 	if err := GenerateCert(certOptions); err != nil {
@@ -134,3 +137,66 @@ func BootstrapCertificates(authOptions *auth.Options) error {
 
 	return nil
 }
+
+// RegenerateClientCert reissues the client cert with the existing CA, which
+// must be current, and checks that the new cert verifies against it. The
+// daemons trusting the CA accept the new cert without being reconfigured.
+func RegenerateClientCert(authOptions *auth.Options) error {
+	current, err := CheckCertificateDate(authOptions.CaCertPath)
+	if err != nil {
+		return fmt.Errorf("reading CA certificate failed: %s", err)
+	}
+	if !current {
+		return errors.New("CA certificate is outdated, all the certificates must be regenerated")
+	}
+
+	org := mcnutils.GetUsername() + ".<bootstrap>"
+
+	if err := os.Remove(authOptions.ClientKeyPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := createCert(authOptions, org, 2048); err != nil {
+		return err
+	}
+
+	return VerifyClientCert(authOptions)
+}
+
+// VerifyClientCert checks that the client cert is signed by the CA and can be
+// used for client authentication.
+func VerifyClientCert(authOptions *auth.Options) error {
+	caCert, err := readCertificate(authOptions.CaCertPath)
+	if err != nil {
+		return err
+	}
+	clientCert, err := readCertificate(authOptions.ClientCertPath)
+	if err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	if _, err := clientCert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("client certificate doesn't verify against the CA: %s", err)
+	}
+
+	return nil
+}
+
+func readCertificate(certPath string) (*x509.Certificate, error) {
+	certBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pemBlock, _ := pem.Decode(certBytes)
+	if pemBlock == nil {
+		return nil, fmt.Errorf("failed to decode PEM data of %s", certPath)
+	}
+
+	return x509.ParseCertificate(pemBlock.Bytes)
+}
@@ -0,0 +1,57 @@
+package cert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegenerateClientCert(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	authOptions := &auth.Options{
+		CertDir:          tmpDir,
+		CaCertPath:       filepath.Join(tmpDir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(tmpDir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(tmpDir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(tmpDir, "key.pem"),
+	}
+	assert.NoError(t, BootstrapCertificates(authOptions))
+
+	caBefore, err := os.ReadFile(authOptions.CaCertPath)
+	assert.NoError(t, err)
+	certBefore, err := os.ReadFile(authOptions.ClientCertPath)
+	assert.NoError(t, err)
+
+	assert.NoError(t, RegenerateClientCert(authOptions))
+
+	caAfter, err := os.ReadFile(authOptions.CaCertPath)
+	assert.NoError(t, err)
+	certAfter, err := os.ReadFile(authOptions.ClientCertPath)
+	assert.NoError(t, err)
+
+	assert.Equal(t, caBefore, caAfter)
+	assert.NotEqual(t, certBefore, certAfter)
+}
+
+func TestVerifyClientCertOtherCA(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	authOptions := &auth.Options{
+		CertDir:          tmpDir,
+		CaCertPath:       filepath.Join(tmpDir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(tmpDir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(tmpDir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(tmpDir, "key.pem"),
+	}
+	assert.NoError(t, BootstrapCertificates(authOptions))
+
+	otherCA := filepath.Join(tmpDir, "other-ca.pem")
+	assert.NoError(t, GenerateCACertificate(otherCA, filepath.Join(tmpDir, "other-ca-key.pem"), "other", 2048))
+
+	authOptions.CaCertPath = otherCA
+	assert.Error(t, VerifyClientCert(authOptions))
+}
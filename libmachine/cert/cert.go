@@ -3,15 +3,18 @@ package cert
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"math/big"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rancher/machine/libmachine/auth"
@@ -20,11 +23,37 @@ import (
 
 var defaultGenerator = NewX509CertGenerator()
 
+const DefaultTLSMinVersion = "1.2"
+
+// DefaultCertDuration is the validity of the generated certificates when no
+// duration is given.
+const DefaultCertDuration = 1080 * 24 * time.Hour
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// defaultTLSCipherSuites restricts TLS 1.2 to AEAD ciphers with forward
+// secrecy when no suites are given. TLS 1.3 suites are not configurable and
+// are all considered secure.
+var defaultTLSCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
 type Options struct {
 	Hosts                                     []string
 	CertFile, KeyFile, CAFile, CAKeyFile, Org string
 	Bits                                      int
 	SwarmMaster                               bool
+	// Duration is the validity of the certificate, DefaultCertDuration if
+	// zero.
+	Duration time.Duration
 }
 
 type Generator interface {
@@ -34,6 +63,12 @@ type Generator interface {
 	ValidateCertificate(addr string, authOptions *auth.Options) (bool, error)
 }
 
+// CADurationGenerator is implemented by the generators able to give the
+// certificate authorities they generate a validity.
+type CADurationGenerator interface {
+	GenerateCACertificateWithDuration(certFile, keyFile, org string, bits int, duration time.Duration) error
+}
+
 type X509CertGenerator struct{}
 
 func NewX509CertGenerator() Generator {
@@ -44,6 +79,20 @@ func GenerateCACertificate(certFile, keyFile, org string, bits int) error {
 	return defaultGenerator.GenerateCACertificate(certFile, keyFile, org, bits)
 }
 
+// GenerateCACertificateWithDuration generates a certificate authority valid
+// for duration, DefaultCertDuration if zero. Generators not implementing
+// CADurationGenerator generate it with their own validity.
+func GenerateCACertificateWithDuration(certFile, keyFile, org string, bits int, duration time.Duration) error {
+	if cg, ok := defaultGenerator.(CADurationGenerator); ok {
+		return cg.GenerateCACertificateWithDuration(certFile, keyFile, org, bits, duration)
+	}
+
+	if duration != 0 {
+		log.Warnf("The certificate generator does not support a CA duration, ignoring %s", duration)
+	}
+	return defaultGenerator.GenerateCACertificate(certFile, keyFile, org, bits)
+}
+
 func GenerateCert(opts *Options) error {
 	return defaultGenerator.GenerateCert(opts)
 }
@@ -60,10 +109,64 @@ func SetCertGenerator(cg Generator) {
 	defaultGenerator = cg
 }
 
-func (xcg *X509CertGenerator) getTLSConfig(caCert, cert, key []byte, allowInsecure bool) (*tls.Config, error) {
+// TLSVersion converts a version as given on the command line ("1.2" or
+// "1.3") to its crypto/tls value. An empty version means the default one.
+func TLSVersion(version string) (uint16, error) {
+	if version == "" {
+		version = DefaultTLSMinVersion
+	}
+
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, must be one of 1.2 or 1.3", version)
+	}
+
+	return v, nil
+}
+
+// TLSCipherSuites converts TLS 1.2 cipher suite names as given on the
+// command line, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, to their
+// crypto/tls values. No names means the default suites.
+func TLSCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return defaultTLSCipherSuites, nil
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := tls12CipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS 1.2 cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
+
+// tls12CipherSuite looks a suite up among the secure ones crypto/tls
+// implements for TLS 1.2.
+func tls12CipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name != name {
+			continue
+		}
+		for _, v := range suite.SupportedVersions {
+			if v == tls.VersionTLS12 {
+				return suite.ID, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func (xcg *X509CertGenerator) getTLSConfig(caCert, cert, key []byte, allowInsecure bool, minVersion uint16, cipherSuites []uint16) (*tls.Config, error) {
 	// TLS config
 	var tlsConfig tls.Config
 	tlsConfig.InsecureSkipVerify = allowInsecure
+	tlsConfig.MinVersion = minVersion
+	tlsConfig.CipherSuites = cipherSuites
 	certPool := x509.NewCertPool()
 
 	ok := certPool.AppendCertsFromPEM(caCert)
@@ -81,12 +184,16 @@ func (xcg *X509CertGenerator) getTLSConfig(caCert, cert, key []byte, allowInsecu
 	return &tlsConfig, nil
 }
 
-func (xcg *X509CertGenerator) newCertificate(org string) (*x509.Certificate, error) {
+func (xcg *X509CertGenerator) newCertificate(org string, duration time.Duration) (*x509.Certificate, error) {
+	if duration <= 0 {
+		duration = DefaultCertDuration
+	}
+
 	now := time.Now()
 	// need to set notBefore slightly in the past to account for time
 	// skew in the VMs otherwise the certs sometimes are not yet valid
 	notBefore := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()-5, 0, 0, time.Local)
-	notAfter := notBefore.Add(time.Hour * 24 * 1080)
+	notAfter := notBefore.Add(duration)
 
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
@@ -102,6 +209,7 @@ func (xcg *X509CertGenerator) newCertificate(org string) (*x509.Certificate, err
 		NotBefore: notBefore,
 		NotAfter:  notAfter,
 
+		// DigitalSignature is required by the ECDHE and TLS 1.3 handshakes.
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
 		BasicConstraintsValid: true,
 	}, nil
@@ -112,7 +220,13 @@ func (xcg *X509CertGenerator) newCertificate(org string) (*x509.Certificate, err
 // and bit size and stores the resulting certificate and key file
 // in the arguments.
 func (xcg *X509CertGenerator) GenerateCACertificate(certFile, keyFile, org string, bits int) error {
-	template, err := xcg.newCertificate(org)
+	return xcg.GenerateCACertificateWithDuration(certFile, keyFile, org, bits, 0)
+}
+
+// GenerateCACertificateWithDuration is GenerateCACertificate with the validity
+// of the certificate authority, DefaultCertDuration if zero.
+func (xcg *X509CertGenerator) GenerateCACertificateWithDuration(certFile, keyFile, org string, bits int, duration time.Duration) error {
+	template, err := xcg.newCertificate(org, duration)
 	if err != nil {
 		return err
 	}
@@ -157,7 +271,7 @@ func (xcg *X509CertGenerator) GenerateCACertificate(certFile, keyFile, org strin
 // file and key provided.  The provided host names are set to the
 // appropriate certificate fields.
 func (xcg *X509CertGenerator) GenerateCert(opts *Options) error {
-	template, err := xcg.newCertificate(opts.Org)
+	template, err := xcg.newCertificate(opts.Org, opts.Duration)
 	if err != nil {
 		return err
 	}
@@ -230,6 +344,16 @@ func (xcg *X509CertGenerator) ReadTLSConfig(addr string, authOptions *auth.Optio
 	clientCertPath := authOptions.ClientCertPath
 	clientKeyPath := authOptions.ClientKeyPath
 
+	minVersion, err := TLSVersion(authOptions.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := TLSCipherSuites(authOptions.TLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Debugf("Reading CA certificate from %s", caCertPath)
 	caCert, err := os.ReadFile(caCertPath)
 	if err != nil {
@@ -248,7 +372,7 @@ func (xcg *X509CertGenerator) ReadTLSConfig(addr string, authOptions *auth.Optio
 		return nil, err
 	}
 
-	return xcg.getTLSConfig(caCert, clientCert, clientKey, false)
+	return xcg.getTLSConfig(caCert, clientCert, clientKey, false, minVersion, cipherSuites)
 }
 
 // ValidateCertificate validate the certificate installed on the vm.
@@ -298,3 +422,25 @@ func CheckCertificateDate(certPath string) (bool, error) {
 
 	return true, nil
 }
+
+// Fingerprint returns the SHA-256 fingerprint of the PEM certificate at
+// certPath, as hex encoded bytes separated by colons.
+func Fingerprint(certPath string) (string, error) {
+	certBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", err
+	}
+
+	pemBlock, _ := pem.Decode(certBytes)
+	if pemBlock == nil {
+		return "", errors.New("Failed to decode PEM data")
+	}
+
+	sum := sha256.Sum256(pemBlock.Bytes)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":"), nil
+}
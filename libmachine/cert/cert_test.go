@@ -1,9 +1,15 @@
 package cert
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGenerateCACertificate(t *testing.T) {
@@ -78,3 +84,137 @@ func TestGenerateCert(t *testing.T) {
 		t.Fatalf("key not created at %s", keyPath)
 	}
 }
+
+func TestGenerateCertIPv6SANs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+	caKeyPath := filepath.Join(tmpDir, "key.pem")
+	certPath := filepath.Join(tmpDir, "cert.pem")
+	keyPath := filepath.Join(tmpDir, "cert-key.pem")
+	if err := GenerateCACertificate(caCertPath, caKeyPath, "test-org", 2048); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &Options{
+		Hosts:     []string{"2001:db8::10", "192.0.2.10", "localhost"},
+		CertFile:  certPath,
+		CAKeyFile: caKeyPath,
+		CAFile:    caCertPath,
+		KeyFile:   keyPath,
+		Org:       "test-org",
+		Bits:      2048,
+	}
+	if err := GenerateCert(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	generated, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(generated.IPAddresses) != 2 || !generated.IPAddresses[0].Equal(net.ParseIP("2001:db8::10")) {
+		t.Fatalf("expected the IPv6 and IPv4 addresses as IP SANs, got %v", generated.IPAddresses)
+	}
+	if len(generated.DNSNames) != 1 || generated.DNSNames[0] != "localhost" {
+		t.Fatalf("expected localhost as the only DNS SAN, got %v", generated.DNSNames)
+	}
+	if err := generated.VerifyHostname("2001:db8::10"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSVersion(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected uint16
+		err      bool
+	}{
+		{"", tls.VersionTLS12, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.1", 0, true},
+	}
+
+	for _, c := range cases {
+		v, err := TLSVersion(c.version)
+		if c.err != (err != nil) {
+			t.Fatalf("unexpected error for %q: %v", c.version, err)
+		}
+		if v != c.expected {
+			t.Fatalf("expected %d for %q, got %d", c.expected, c.version, v)
+		}
+	}
+}
+
+func TestTLSCipherSuites(t *testing.T) {
+	suites, err := TLSCipherSuites(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(suites, defaultTLSCipherSuites) {
+		t.Fatalf("expected the default suites, got %v", suites)
+	}
+
+	suites, err = TLSCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(suites, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}) {
+		t.Fatalf("unexpected suites %v", suites)
+	}
+
+	for _, name := range []string{"TLS_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA", "bogus"} {
+		if _, err := TLSCipherSuites([]string{name}); err == nil {
+			t.Fatalf("expected an error for %q", name)
+		}
+	}
+}
+
+func TestNewCertificateDuration(t *testing.T) {
+	xcg := &X509CertGenerator{}
+
+	template, err := xcg.newCertificate("test-org", 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validity := template.NotAfter.Sub(template.NotBefore); validity != 48*time.Hour {
+		t.Fatalf("expected a validity of 48h, got %s", validity)
+	}
+
+	template, err = xcg.newCertificate("test-org", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validity := template.NotAfter.Sub(template.NotBefore); validity != DefaultCertDuration {
+		t.Fatalf("expected the default validity, got %s", validity)
+	}
+}
+
+func TestGenerateCACertificateWithDuration(t *testing.T) {
+	tmpDir := t.TempDir()
+	caCertPath := filepath.Join(tmpDir, "ca.pem")
+
+	if err := GenerateCACertificateWithDuration(caCertPath, filepath.Join(tmpDir, "key.pem"), "test-org", 2048, 48*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(caCertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(content)
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validity := ca.NotAfter.Sub(ca.NotBefore); validity != 48*time.Hour {
+		t.Fatalf("expected a validity of 48h, got %s", validity)
+	}
+}
@@ -3,8 +3,8 @@ package check
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
-	"strings"
 
 	"github.com/rancher/machine/libmachine/auth"
 	"github.com/rancher/machine/libmachine/cert"
@@ -40,11 +40,19 @@ type ConnChecker interface {
 type MachineConnChecker struct{}
 
 func (mcc *MachineConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Options, error) {
-	dockerHost, err := h.Driver.GetURL()
+	dockerHost, err := h.URL()
 	if err != nil {
 		return "", &auth.Options{}, err
 	}
 
+	// There are no certs to check, the docker client connects over SSH.
+	if h.DaemonSSHTransport() {
+		if swarm {
+			return "", &auth.Options{}, fmt.Errorf("%q reaches its daemon over SSH, it can't be a swarm master", h.Name)
+		}
+		return dockerHost, h.AuthOptions(), nil
+	}
+
 	dockerURL := dockerHost
 	if swarm {
 		dockerURL, err = parseSwarm(dockerHost, h)
@@ -73,6 +81,12 @@ func (mcc *MachineConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Op
 		return "", &auth.Options{}, fmt.Errorf("Error checking and/or regenerating the certs: %s", err)
 	}
 
+	// The certs are checked on the machine, the clients are given the URL of
+	// the load balancer in front of it.
+	if externalURL := h.DaemonExternalURL(); externalURL != "" && !swarm {
+		return externalURL, authOptions, nil
+	}
+
 	return dockerURL, authOptions, nil
 }
 
@@ -100,8 +114,7 @@ func parseSwarm(hostURL string, h *host.Host) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("There was an error parsing the url: %s", err)
 	}
-	parts := strings.Split(u.Host, ":")
-	swarmPort := parts[1]
+	swarmPort := u.Port()
 
 	// get IP of machine to replace in case swarm host is 0.0.0.0
 	mURL, err := url.Parse(hostURL)
@@ -109,10 +122,9 @@ func parseSwarm(hostURL string, h *host.Host) (string, error) {
 		return "", fmt.Errorf("There was an error parsing the url: %s", err)
 	}
 
-	mParts := strings.Split(mURL.Host, ":")
-	machineIP := mParts[0]
+	machineIP := mURL.Hostname()
 
-	hostURL = fmt.Sprintf("tcp://%s:%s", machineIP, swarmPort)
+	hostURL = fmt.Sprintf("tcp://%s", net.JoinHostPort(machineIP, swarmPort))
 
 	return hostURL, nil
 }
@@ -8,6 +8,8 @@ import (
 
 	"github.com/rancher/machine/libmachine/auth"
 	"github.com/rancher/machine/libmachine/cert"
+	"github.com/rancher/machine/libmachine/host"
+	"github.com/rancher/machine/libmachine/swarm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,3 +60,20 @@ func TestCheckCert(t *testing.T) {
 		assert.Equal(t, c.expectedErr, err)
 	}
 }
+
+func TestParseSwarmIPv6(t *testing.T) {
+	h := &host.Host{
+		Name: "swarm-master",
+		HostOptions: &host.Options{
+			SwarmOptions: &swarm.Options{Master: true, Host: "tcp://0.0.0.0:3376"},
+		},
+	}
+
+	hostURL, err := parseSwarm("tcp://[2001:db8::10]:2376", h)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://[2001:db8::10]:3376", hostURL)
+
+	hostURL, err = parseSwarm("tcp://192.0.2.10:2376", h)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://192.0.2.10:3376", hostURL)
+}
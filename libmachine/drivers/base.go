@@ -24,6 +24,12 @@ type BaseDriver struct {
 	SwarmMaster    bool
 	SwarmHost      string
 	SwarmDiscovery string
+	// SSHPortProbe probes the SSH port of the machine before each attempt of
+	// the waits for SSH, see WaitForSSH.
+	SSHPortProbe bool `json:",omitempty"`
+
+	// sshAgentForward is set for the command being run only.
+	sshAgentForward bool
 }
 
 // DriverName returns the name of the driver
@@ -39,7 +45,7 @@ func (d *BaseDriver) GetMachineName() string {
 // GetIP returns the IPv4 address
 func (d *BaseDriver) GetIP() (string, error) {
 	if d.IPAddress == "" {
-		return "", errors.New("IPv4 address is not set")
+		return "", errors.New("IP address is not set")
 	}
 	return d.IPAddress, nil
 }
@@ -77,6 +83,23 @@ func (d *BaseDriver) GetSSHUsername() string {
 	return d.SSHUser
 }
 
+// SetSSHUser sets the ssh username
+func (d *BaseDriver) SetSSHUser(user string) {
+	d.SSHUser = user
+}
+
+// SetSSHAgentForward sets whether the SSH clients of the machine forward the
+// local SSH agent.
+func (d *BaseDriver) SetSSHAgentForward(enabled bool) {
+	d.sshAgentForward = enabled
+}
+
+// SSHAgentForward returns true if the SSH clients of the machine forward the
+// local SSH agent.
+func (d *BaseDriver) SSHAgentForward() bool {
+	return d != nil && d.sshAgentForward
+}
+
 // PreCreateCheck is called to enforce pre-creation steps
 func (d *BaseDriver) PreCreateCheck() error {
 	return nil
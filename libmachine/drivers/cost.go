@@ -0,0 +1,42 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// HoursPerMonth is the average number of hours in a month, used to estimate
+// the monthly cost from an hourly price.
+const HoursPerMonth = 730
+
+// CostEstimate is the estimated on-demand cost of an instance.
+type CostEstimate struct {
+	Hourly   float64
+	Monthly  float64
+	Currency string
+	// Description tells what the estimate is for, e.g. the instance type
+	// and the region.
+	Description string
+}
+
+// CostEstimator is implemented by the drivers able to estimate the cost of
+// the instance they are configured to create.
+type CostEstimator interface {
+	EstimateCost() (*CostEstimate, error)
+}
+
+// EstimateCost estimates the cost of the instance of d, returning an
+// ErrNotSupported error if the driver has no pricing data.
+func EstimateCost(d Driver) (*CostEstimate, error) {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	estimator, ok := d.(CostEstimator)
+	if !ok {
+		return nil, mcnerror.NotSupported(fmt.Errorf("the %s driver has no pricing data", d.DriverName()))
+	}
+
+	return estimator.EstimateCost()
+}
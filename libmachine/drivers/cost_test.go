@@ -0,0 +1,41 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockCostDriver struct {
+	MockDriver
+	estimate *CostEstimate
+}
+
+func (d *mockCostDriver) EstimateCost() (*CostEstimate, error) {
+	return d.estimate, nil
+}
+
+func TestEstimateCost(t *testing.T) {
+	driver := &mockCostDriver{
+		MockDriver: MockDriver{calls: &CallRecorder{}},
+		estimate:   &CostEstimate{Hourly: 0.0116, Monthly: 8.468, Currency: "USD"},
+	}
+
+	estimate, err := EstimateCost(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, driver.estimate, estimate)
+
+	estimate, err = EstimateCost(newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}}))
+	assert.NoError(t, err)
+	assert.Equal(t, driver.estimate, estimate)
+}
+
+func TestEstimateCostNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	_, err := EstimateCost(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver has no pricing data")
+}
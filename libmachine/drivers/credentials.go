@@ -0,0 +1,31 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// CredentialsChecker is implemented by the drivers able to check their
+// credentials with a read-only call to their provider, without creating
+// anything.
+type CredentialsChecker interface {
+	// CheckCredentials returns the identity the credentials map to, or an
+	// error if the provider rejects them.
+	CheckCredentials() (string, error)
+}
+
+// CheckCredentials checks the credentials of d, returning an ErrNotSupported
+// error if the driver can't.
+func CheckCredentials(d Driver) (string, error) {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	checker, ok := d.(CredentialsChecker)
+	if !ok {
+		return "", mcnerror.NotSupported(fmt.Errorf("the %s driver can't check its credentials", d.DriverName()))
+	}
+
+	return checker.CheckCredentials()
+}
@@ -0,0 +1,41 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockCredentialsDriver struct {
+	MockDriver
+	identity string
+}
+
+func (d *mockCredentialsDriver) CheckCredentials() (string, error) {
+	return d.identity, nil
+}
+
+func TestCheckCredentials(t *testing.T) {
+	driver := &mockCredentialsDriver{
+		MockDriver: MockDriver{calls: &CallRecorder{}},
+		identity:   "arn:aws:iam::123456789012:user/ci",
+	}
+
+	identity, err := CheckCredentials(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/ci", identity)
+
+	identity, err = CheckCredentials(newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}}))
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/ci", identity)
+}
+
+func TestCheckCredentialsNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	_, err := CheckCredentials(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't check its credentials")
+}
@@ -0,0 +1,59 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseExpectedIP parses an address or a CIDR block the IP of a machine is
+// expected to match. A single address is returned as a host network.
+func ParseExpectedIP(expected string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(expected); err == nil {
+		return network, nil
+	}
+
+	ip := net.ParseIP(expected)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is neither an IP address nor a CIDR block", expected)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 8 * net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+type ipGetter interface {
+	GetIP() (string, error)
+	GetIPv6() (string, error)
+}
+
+// CheckExpectedIP returns an error if neither the IP nor the IPv6 address
+// reported by the driver is the expected address or in the expected CIDR
+// block.
+func CheckExpectedIP(d ipGetter, expected string) error {
+	network, err := ParseExpectedIP(expected)
+	if err != nil {
+		return err
+	}
+
+	addresses := MachineIPs(d)
+	if len(addresses) == 0 {
+		if _, err := MachineIP(d, false); err != nil {
+			return err
+		}
+		return fmt.Errorf("the machine has no IP address, expected %s", expected)
+	}
+
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip != nil && network.Contains(ip) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the machine came up with IP %s, expected %s", strings.Join(addresses, ", "), expected)
+}
@@ -0,0 +1,38 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpectedIP(t *testing.T) {
+	network, err := ParseExpectedIP("10.0.0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5/32", network.String())
+
+	network, err = ParseExpectedIP("10.0.0.0/24")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/24", network.String())
+
+	network, err = ParseExpectedIP("2001:db8::1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::1/128", network.String())
+
+	_, err = ParseExpectedIP("not-an-ip")
+	assert.Error(t, err)
+}
+
+func TestCheckExpectedIP(t *testing.T) {
+	d := &BaseDriver{IPAddress: "10.0.0.5", IPv6Address: "2001:db8::5"}
+
+	assert.NoError(t, CheckExpectedIP(d, "10.0.0.5"))
+	assert.NoError(t, CheckExpectedIP(d, "10.0.0.0/24"))
+	assert.NoError(t, CheckExpectedIP(d, "2001:db8::/64"))
+	assert.EqualError(t, CheckExpectedIP(d, "10.0.1.0/24"), "the machine came up with IP 10.0.0.5, 2001:db8::5, expected 10.0.1.0/24")
+	assert.Error(t, CheckExpectedIP(&BaseDriver{}, "10.0.0.5"))
+
+	ipv6Only := &BaseDriver{IPv6Address: "2001:db8::5"}
+	assert.NoError(t, CheckExpectedIP(ipv6Only, "2001:db8::/64"))
+	assert.EqualError(t, CheckExpectedIP(ipv6Only, "10.0.0.0/24"), "the machine came up with IP 2001:db8::5, expected 10.0.0.0/24")
+}
@@ -0,0 +1,63 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// InstanceAttributes are the attributes of an instance compared by diff to
+// find the changes made outside of machine.
+type InstanceAttributes struct {
+	InstanceType string            `json:",omitempty"`
+	IP           string            `json:",omitempty"`
+	Tags         map[string]string `json:",omitempty"`
+	// OpenPorts are the port/protocol the driver opens for the instance.
+	// The fetched ones are those still open at the provider.
+	OpenPorts []string `json:",omitempty"`
+}
+
+// Fetcher is implemented by the drivers able to read the attributes of their
+// instance from their provider.
+type Fetcher interface {
+	// Fetch returns the current attributes of the instance.
+	Fetch() (*InstanceAttributes, error)
+	// StoredAttributes returns the attributes recorded in the driver config
+	// when the instance was created.
+	StoredAttributes() (*InstanceAttributes, error)
+}
+
+func fetcher(d Driver) (Fetcher, error) {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	f, ok := d.(Fetcher)
+	if !ok {
+		return nil, mcnerror.NotSupported(fmt.Errorf("the %s driver can't fetch the attributes of its instance", d.DriverName()))
+	}
+
+	return f, nil
+}
+
+// Fetch returns the current attributes of the instance of d, or an
+// ErrNotSupported error if the driver can't fetch them.
+func Fetch(d Driver) (*InstanceAttributes, error) {
+	f, err := fetcher(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Fetch()
+}
+
+// StoredAttributes returns the attributes of the instance of d recorded in
+// its config, or an ErrNotSupported error if the driver can't fetch them.
+func StoredAttributes(d Driver) (*InstanceAttributes, error) {
+	f, err := fetcher(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.StoredAttributes()
+}
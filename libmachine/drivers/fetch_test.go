@@ -0,0 +1,41 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockFetchDriver struct {
+	MockDriver
+}
+
+func (d *mockFetchDriver) Fetch() (*InstanceAttributes, error) {
+	return &InstanceAttributes{InstanceType: "t3.large"}, nil
+}
+
+func (d *mockFetchDriver) StoredAttributes() (*InstanceAttributes, error) {
+	return &InstanceAttributes{InstanceType: "t3.small"}, nil
+}
+
+func TestFetch(t *testing.T) {
+	driver := newSerialDriverWithLock(&mockFetchDriver{MockDriver: MockDriver{calls: &CallRecorder{}}}, &MockLocker{calls: &CallRecorder{}})
+
+	live, err := Fetch(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, "t3.large", live.InstanceType)
+
+	stored, err := StoredAttributes(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, "t3.small", stored.InstanceType)
+}
+
+func TestFetchNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	_, err := Fetch(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't fetch the attributes of its instance")
+}
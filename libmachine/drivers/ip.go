@@ -0,0 +1,118 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// MachineIP returns the address a machine is reached at. It is the IPv4
+// address of the machine, or its IPv6 address when it has no IPv4 address,
+// or when preferIPv6 is set and it has one.
+func MachineIP(d ipGetter, preferIPv6 bool) (string, error) {
+	if preferIPv6 {
+		if ip, err := d.GetIPv6(); err == nil && ip != "" {
+			return ip, nil
+		}
+	}
+
+	ip, err := d.GetIP()
+	if err == nil && ip != "" {
+		return ip, nil
+	}
+
+	ipv6, err6 := d.GetIPv6()
+	if err6 == nil && ipv6 != "" {
+		return ipv6, nil
+	}
+
+	// Drivers of local engines have no IP address
+	if err == nil {
+		return ip, nil
+	}
+
+	return "", fmt.Errorf("error getting the IP address of the machine: %v, %v", err, err6)
+}
+
+// MachineIPs returns the IPv4 and IPv6 addresses a machine has.
+func MachineIPs(d ipGetter) []string {
+	ips := []string{}
+	if ip, err := d.GetIP(); err == nil && ip != "" {
+		ips = append(ips, ip)
+	}
+	if ip, err := d.GetIPv6(); err == nil && ip != "" && (len(ips) == 0 || ips[0] != ip) {
+		ips = append(ips, ip)
+	}
+
+	return ips
+}
+
+// URLWithIP returns rawURL with its host replaced by ip, keeping its port.
+// IPv6 literals are bracketed.
+func URLWithIP(rawURL, ip string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(ip, port)
+	} else if net.ParseIP(ip).To4() == nil && net.ParseIP(ip) != nil {
+		u.Host = "[" + ip + "]"
+	} else {
+		u.Host = ip
+	}
+
+	return u.String(), nil
+}
+
+// URLWithPort returns rawURL with its port replaced by port.
+func URLWithPort(rawURL string, port int) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+
+	return u.String(), nil
+}
+
+// privateIPKeys are the config fields the drivers record the private IP of
+// their instance in.
+var privateIPKeys = []string{"PrivateIPAddress", "PrivateIPAddr"}
+
+// PrivateIPFromConfig returns the private IP recorded in the JSON config of a
+// driver, or an empty string if the driver reports none.
+func PrivateIPFromConfig(rawDriver []byte) string {
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(rawDriver, &config); err != nil {
+		return ""
+	}
+
+	for _, key := range privateIPKeys {
+		if ip, ok := config[key].(string); ok && ip != "" {
+			return ip
+		}
+	}
+
+	return ""
+}
+
+// PrivateIP returns the private IP of the instance of d, for the drivers
+// reporting it beside the address the machine is reached at over SSH.
+func PrivateIP(d Driver) (string, error) {
+	rawDriver, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+
+	ip := PrivateIPFromConfig(rawDriver)
+	if ip == "" {
+		return "", fmt.Errorf("the %s driver reports no private IP address for the machine", d.DriverName())
+	}
+
+	return ip, nil
+}
@@ -0,0 +1,87 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineIP(t *testing.T) {
+	dualStack := &BaseDriver{IPAddress: "192.0.2.10", IPv6Address: "2001:db8::10"}
+	ipv6Only := &BaseDriver{IPv6Address: "2001:db8::10"}
+	ipv4Only := &BaseDriver{IPAddress: "192.0.2.10"}
+
+	ip, err := MachineIP(dualStack, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.10", ip)
+
+	ip, err = MachineIP(dualStack, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::10", ip)
+
+	ip, err = MachineIP(ipv6Only, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::10", ip)
+
+	ip, err = MachineIP(ipv4Only, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.10", ip)
+
+	_, err = MachineIP(&BaseDriver{}, false)
+	assert.Error(t, err)
+}
+
+func TestMachineIPs(t *testing.T) {
+	assert.Equal(t, []string{"192.0.2.10", "2001:db8::10"}, MachineIPs(&BaseDriver{IPAddress: "192.0.2.10", IPv6Address: "2001:db8::10"}))
+	assert.Equal(t, []string{"2001:db8::10"}, MachineIPs(&BaseDriver{IPv6Address: "2001:db8::10"}))
+	assert.Empty(t, MachineIPs(&BaseDriver{}))
+}
+
+func TestURLWithIP(t *testing.T) {
+	u, err := URLWithIP("tcp://192.0.2.10:2376", "2001:db8::10")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://[2001:db8::10]:2376", u)
+
+	u, err = URLWithIP("tcp://[2001:db8::10]:2376", "192.0.2.10")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://192.0.2.10:2376", u)
+
+	u, err = URLWithIP("tcp://192.0.2.10", "2001:db8::10")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://[2001:db8::10]", u)
+}
+
+func TestURLWithPort(t *testing.T) {
+	u, err := URLWithPort("tcp://192.0.2.10:2376", 12376)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://192.0.2.10:12376", u)
+
+	u, err = URLWithPort("tcp://[2001:db8::10]:2376", 12376)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://[2001:db8::10]:12376", u)
+
+	u, err = URLWithPort("tcp://192.0.2.10", 12376)
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://192.0.2.10:12376", u)
+}
+
+type privateIPDriver struct {
+	MockDriver
+	PrivateIPAddress string
+}
+
+func TestPrivateIP(t *testing.T) {
+	ip, err := PrivateIP(&privateIPDriver{MockDriver: MockDriver{calls: &CallRecorder{}}, PrivateIPAddress: "10.0.0.5"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", ip)
+
+	_, err = PrivateIP(&MockDriver{calls: &CallRecorder{}, driverName: "mock"})
+	assert.EqualError(t, err, "the mock driver reports no private IP address for the machine")
+}
+
+func TestPrivateIPFromConfig(t *testing.T) {
+	assert.Equal(t, "10.0.0.5", PrivateIPFromConfig([]byte(`{"PrivateIPAddress": "10.0.0.5"}`)))
+	assert.Equal(t, "10.1.0.4", PrivateIPFromConfig([]byte(`{"PrivateIPAddr": "10.1.0.4"}`)))
+	assert.Empty(t, PrivateIPFromConfig([]byte(`{"IPAddress": "192.0.2.10"}`)))
+	assert.Empty(t, PrivateIPFromConfig(nil))
+}
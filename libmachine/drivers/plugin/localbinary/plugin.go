@@ -34,6 +34,7 @@ var (
 		"vmwarefusion",
 		"vmwarevcloudair",
 		"vmwarevsphere",
+		"vultr",
 		"pod",
 		"noop",
 	}
@@ -0,0 +1,31 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// Rebooter is implemented by the drivers able to reboot their instance with
+// the API of their provider. Unlike a stop and start, a reboot keeps the
+// instance, e.g. on the same host with its instance store or spot capacity.
+type Rebooter interface {
+	// Reboot asks the provider to reboot the running instance. It may
+	// return before the instance is back up.
+	Reboot() error
+}
+
+// Reboot reboots the instance of d with the API of its provider, or returns
+// an ErrNotSupported error if the driver has no reboot API.
+func Reboot(d Driver) error {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	rebooter, ok := d.(Rebooter)
+	if !ok {
+		return mcnerror.NotSupported(fmt.Errorf("the %s driver can't reboot its instance", d.DriverName()))
+	}
+
+	return rebooter.Reboot()
+}
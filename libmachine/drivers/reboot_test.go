@@ -0,0 +1,35 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRebootDriver struct {
+	MockDriver
+	rebooted int
+}
+
+func (d *mockRebootDriver) Reboot() error {
+	d.rebooted++
+	return nil
+}
+
+func TestReboot(t *testing.T) {
+	driver := &mockRebootDriver{MockDriver: MockDriver{calls: &CallRecorder{}}}
+
+	assert.NoError(t, Reboot(driver))
+	assert.NoError(t, Reboot(newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}})))
+	assert.Equal(t, 2, driver.rebooted)
+}
+
+func TestRebootNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	err := Reboot(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't reboot its instance")
+}
@@ -0,0 +1,33 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// Replacer is implemented by the drivers able to replace an instance that
+// can't be started, e.g. a spot instance, by a new one with the same
+// configuration.
+type Replacer interface {
+	// Replace terminates the instance and launches a new one, recording it
+	// in the driver config. The data of the old instance is lost and the
+	// new one has to be provisioned. The new instance is running when it
+	// returns without error.
+	Replace() error
+}
+
+// Replace replaces the instance of d by a new one, or returns an
+// ErrNotSupported error if the driver can't replace its instance.
+func Replace(d Driver) error {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	replacer, ok := d.(Replacer)
+	if !ok {
+		return mcnerror.NotSupported(fmt.Errorf("the %s driver can't replace its instance", d.DriverName()))
+	}
+
+	return replacer.Replace()
+}
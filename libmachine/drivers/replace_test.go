@@ -0,0 +1,35 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockReplaceDriver struct {
+	MockDriver
+	replaced int
+}
+
+func (d *mockReplaceDriver) Replace() error {
+	d.replaced++
+	return nil
+}
+
+func TestReplace(t *testing.T) {
+	driver := &mockReplaceDriver{MockDriver: MockDriver{calls: &CallRecorder{}}}
+
+	assert.NoError(t, Replace(driver))
+	assert.NoError(t, Replace(newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}})))
+	assert.Equal(t, 2, driver.replaced)
+}
+
+func TestReplaceNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	err := Replace(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't replace its instance")
+}
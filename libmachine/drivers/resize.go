@@ -0,0 +1,31 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// Resizer is implemented by the drivers able to change the size of their
+// instance, e.g. its instance type.
+type Resizer interface {
+	// Resize changes the size of the instance, stopping it first if needed,
+	// and records the new size in the driver config. The instance is
+	// running when it returns without error.
+	Resize(size string) error
+}
+
+// Resize changes the size of the instance of d, or returns an
+// ErrNotSupported error if the driver can't resize its instance.
+func Resize(d Driver, size string) error {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	resizer, ok := d.(Resizer)
+	if !ok {
+		return mcnerror.NotSupported(fmt.Errorf("the %s driver can't resize its instance", d.DriverName()))
+	}
+
+	return resizer.Resize(size)
+}
@@ -0,0 +1,37 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResizeDriver struct {
+	MockDriver
+	size string
+}
+
+func (d *mockResizeDriver) Resize(size string) error {
+	d.size = size
+	return nil
+}
+
+func TestResize(t *testing.T) {
+	driver := &mockResizeDriver{MockDriver: MockDriver{calls: &CallRecorder{}}}
+
+	assert.NoError(t, Resize(driver, "t3.large"))
+	assert.Equal(t, "t3.large", driver.size)
+
+	assert.NoError(t, Resize(newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}}), "t3.xlarge"))
+	assert.Equal(t, "t3.xlarge", driver.size)
+}
+
+func TestResizeNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	err := Resize(driver, "t3.large")
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't resize its instance")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/rancher/machine/libmachine/drivers"
 	"github.com/rancher/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/state"
 	"github.com/rancher/machine/libmachine/version"
@@ -40,6 +41,7 @@ type RPCClientDriver struct {
 	plugin          localbinary.DriverPlugin
 	heartbeatDoneCh chan bool
 	Client          *InternalClient
+	sshAgentForward bool
 }
 
 type RPCCall struct {
@@ -83,13 +85,23 @@ const (
 	RestartMethod            = `.Restart`
 	KillMethod               = `.Kill`
 	UpgradeMethod            = `.Upgrade`
+	CheckCredentialsMethod   = `.CheckCredentials`
+	FetchMethod              = `.Fetch`
+	StoredAttributesMethod   = `.StoredAttributes`
+	EstimateCostMethod       = `.EstimateCost`
+	ResizeMethod             = `.Resize`
+	ReplaceMethod            = `.Replace`
+	RebootMethod             = `.Reboot`
+	MachineSSHKeysMethod     = `.MachineSSHKeys`
+	DeleteSSHKeyMethod       = `.DeleteSSHKey`
 )
 
 func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
 	if serviceMethod != HeartbeatMethod {
 		log.Debugf("(%s) Calling %+v", ic.MachineName, serviceMethod)
 	}
-	return ic.RPCClient.Call(ic.rpcServiceName+serviceMethod, args, reply)
+	// Errors only keep their message over RPC, restore their kind.
+	return mcnerror.FromMessage(ic.RPCClient.Call(ic.rpcServiceName+serviceMethod, args, reply))
 }
 
 func (ic *InternalClient) switchToV0() {
@@ -369,3 +381,94 @@ func (c *RPCClientDriver) Kill() error {
 func (c *RPCClientDriver) Upgrade() error {
 	return c.Client.Call(UpgradeMethod, struct{}{}, nil)
 }
+
+// CheckCredentials checks the credentials of the plugin driver, which
+// returns an ErrNotSupported error if it can't.
+func (c *RPCClientDriver) CheckCredentials() (string, error) {
+	return c.rpcStringCall(CheckCredentialsMethod)
+}
+
+// Fetch returns the current attributes of the instance of the plugin driver,
+// which returns an ErrNotSupported error if it can't fetch them.
+func (c *RPCClientDriver) Fetch() (*drivers.InstanceAttributes, error) {
+	var attributes drivers.InstanceAttributes
+
+	if err := c.Client.Call(FetchMethod, struct{}{}, &attributes); err != nil {
+		return nil, err
+	}
+
+	return &attributes, nil
+}
+
+// StoredAttributes returns the attributes of the instance recorded in the
+// config of the plugin driver.
+func (c *RPCClientDriver) StoredAttributes() (*drivers.InstanceAttributes, error) {
+	var attributes drivers.InstanceAttributes
+
+	if err := c.Client.Call(StoredAttributesMethod, struct{}{}, &attributes); err != nil {
+		return nil, err
+	}
+
+	return &attributes, nil
+}
+
+// EstimateCost estimates the cost of the instance of the plugin driver, which
+// returns an ErrNotSupported error if it has no pricing data.
+func (c *RPCClientDriver) EstimateCost() (*drivers.CostEstimate, error) {
+	var estimate drivers.CostEstimate
+
+	if err := c.Client.Call(EstimateCostMethod, struct{}{}, &estimate); err != nil {
+		return nil, err
+	}
+
+	return &estimate, nil
+}
+
+// Resize changes the size of the instance of the plugin driver, which
+// returns an ErrNotSupported error if it can't.
+func (c *RPCClientDriver) Resize(size string) error {
+	return c.Client.Call(ResizeMethod, size, nil)
+}
+
+// Replace replaces the instance of the plugin driver by a new one, which
+// returns an ErrNotSupported error if it can't.
+func (c *RPCClientDriver) Replace() error {
+	return c.Client.Call(ReplaceMethod, struct{}{}, nil)
+}
+
+// Reboot reboots the instance of the plugin driver with the API of its
+// provider, the plugin returning an ErrNotSupported error if it can't.
+func (c *RPCClientDriver) Reboot() error {
+	return c.Client.Call(RebootMethod, struct{}{}, nil)
+}
+
+// SetSSHAgentForward sets whether the SSH clients of the machine forward the
+// local SSH agent. The clients are created in this process, the plugin isn't
+// called.
+func (c *RPCClientDriver) SetSSHAgentForward(enabled bool) {
+	c.sshAgentForward = enabled
+}
+
+// SSHAgentForward returns true if the SSH clients of the machine forward the
+// local SSH agent.
+func (c *RPCClientDriver) SSHAgentForward() bool {
+	return c.sshAgentForward
+}
+
+// MachineSSHKeys lists the SSH key resources uploaded by machine with the
+// account of the plugin driver, which returns an ErrNotSupported error if it
+// can't.
+func (c *RPCClientDriver) MachineSSHKeys() ([]drivers.MachineSSHKey, error) {
+	var keys []drivers.MachineSSHKey
+
+	if err := c.Client.Call(MachineSSHKeysMethod, struct{}{}, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteSSHKey deletes an SSH key resource listed by MachineSSHKeys.
+func (c *RPCClientDriver) DeleteSSHKey(id string) error {
+	return c.Client.Call(DeleteSSHKeyMethod, id, nil)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/rancher/machine/libmachine/drivers"
 	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/rancher/machine/libmachine/mcnflag"
 	"github.com/rancher/machine/libmachine/state"
 	"github.com/rancher/machine/libmachine/version"
@@ -107,7 +108,7 @@ func (r *RPCServerDriver) GetVersion(_ *struct{}, reply *int) error {
 func (r *RPCServerDriver) GetConfigRaw(_ *struct{}, reply *[]byte) error {
 	driverData, err := json.Marshal(r.ActualDriver)
 	if err != nil {
-		return err
+		return mcnerror.ToMessage(err)
 	}
 
 	*reply = driverData
@@ -139,7 +140,7 @@ func (r *RPCServerDriver) Create(_, _ *struct{}) (err error) {
 
 	err = r.ActualDriver.Create()
 
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) DriverName(_ *struct{}, reply *string) error {
@@ -150,13 +151,13 @@ func (r *RPCServerDriver) DriverName(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetIP(_ *struct{}, reply *string) error {
 	ip, err := r.ActualDriver.GetIP()
 	*reply = ip
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) GetIPv6(_ *struct{}, reply *string) error {
 	ip, err := r.ActualDriver.GetIPv6()
 	*reply = ip
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) GetMachineName(_ *struct{}, reply *string) error {
@@ -167,7 +168,7 @@ func (r *RPCServerDriver) GetMachineName(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetSSHHostname(_ *struct{}, reply *string) error {
 	hostname, err := r.ActualDriver.GetSSHHostname()
 	*reply = hostname
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) GetSSHKeyPath(_ *struct{}, reply *string) error {
@@ -179,7 +180,7 @@ func (r *RPCServerDriver) GetSSHKeyPath(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetSSHPort(_ *struct{}, reply *int) error {
 	port, err := r.ActualDriver.GetSSHPort()
 	*reply = port
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) GetSSHUsername(_ *struct{}, reply *string) error {
@@ -190,41 +191,99 @@ func (r *RPCServerDriver) GetSSHUsername(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetURL(_ *struct{}, reply *string) error {
 	info, err := r.ActualDriver.GetURL()
 	*reply = info
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) GetState(_ *struct{}, reply *state.State) error {
 	s, err := r.ActualDriver.GetState()
 	*reply = s
-	return err
+	return mcnerror.ToMessage(err)
 }
 
 func (r *RPCServerDriver) Kill(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Kill()
+	return mcnerror.ToMessage(r.ActualDriver.Kill())
 }
 
 func (r *RPCServerDriver) PreCreateCheck(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.PreCreateCheck()
+	return mcnerror.ToMessage(r.ActualDriver.PreCreateCheck())
 }
 
 func (r *RPCServerDriver) Remove(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Remove()
+	return mcnerror.ToMessage(r.ActualDriver.Remove())
 }
 
 func (r *RPCServerDriver) Restart(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Restart()
+	return mcnerror.ToMessage(r.ActualDriver.Restart())
 }
 
 func (r *RPCServerDriver) SetConfigFromFlags(flags *drivers.DriverOptions, _ *struct{}) error {
-	return r.ActualDriver.SetConfigFromFlags(*flags)
+	return mcnerror.ToMessage(r.ActualDriver.SetConfigFromFlags(*flags))
 }
 
 func (r *RPCServerDriver) Start(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Start()
+	return mcnerror.ToMessage(r.ActualDriver.Start())
 }
 
 func (r *RPCServerDriver) Stop(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Stop()
+	return mcnerror.ToMessage(r.ActualDriver.Stop())
+}
+
+func (r *RPCServerDriver) CheckCredentials(_ *struct{}, reply *string) error {
+	identity, err := drivers.CheckCredentials(r.ActualDriver)
+	*reply = identity
+	return mcnerror.ToMessage(err)
+}
+
+func (r *RPCServerDriver) Fetch(_ *struct{}, reply *drivers.InstanceAttributes) error {
+	attributes, err := drivers.Fetch(r.ActualDriver)
+	if err != nil {
+		return mcnerror.ToMessage(err)
+	}
+	*reply = *attributes
+	return nil
+}
+
+func (r *RPCServerDriver) StoredAttributes(_ *struct{}, reply *drivers.InstanceAttributes) error {
+	attributes, err := drivers.StoredAttributes(r.ActualDriver)
+	if err != nil {
+		return mcnerror.ToMessage(err)
+	}
+	*reply = *attributes
+	return nil
+}
+
+func (r *RPCServerDriver) EstimateCost(_ *struct{}, reply *drivers.CostEstimate) error {
+	estimate, err := drivers.EstimateCost(r.ActualDriver)
+	if err != nil {
+		return mcnerror.ToMessage(err)
+	}
+	*reply = *estimate
+	return nil
+}
+
+func (r *RPCServerDriver) Resize(size string, _ *struct{}) error {
+	return mcnerror.ToMessage(drivers.Resize(r.ActualDriver, size))
+}
+
+func (r *RPCServerDriver) Replace(_ *struct{}, _ *struct{}) error {
+	return mcnerror.ToMessage(drivers.Replace(r.ActualDriver))
+}
+
+func (r *RPCServerDriver) Reboot(_ *struct{}, _ *struct{}) error {
+	return mcnerror.ToMessage(drivers.Reboot(r.ActualDriver))
+}
+
+func (r *RPCServerDriver) MachineSSHKeys(_ *struct{}, reply *[]drivers.MachineSSHKey) error {
+	keys, err := drivers.MachineSSHKeys(r.ActualDriver)
+	if err != nil {
+		return mcnerror.ToMessage(err)
+	}
+	*reply = keys
+	return nil
+}
+
+func (r *RPCServerDriver) DeleteSSHKey(id string, _ *struct{}) error {
+	return mcnerror.ToMessage(drivers.DeleteSSHKey(r.ActualDriver, id))
 }
 
 func (r *RPCServerDriver) Heartbeat(_ *struct{}, _ *struct{}) error {
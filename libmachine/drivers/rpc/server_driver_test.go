@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/mcnerror"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,3 +74,17 @@ func TestRPCServerDriverCreate(t *testing.T) {
 		assert.Equal(t, tc.expectedErr, tc.serverDriver.Create(nil, nil))
 	}
 }
+
+func TestRPCServerDriverCreateKeepsErrorKind(t *testing.T) {
+	serverDriver := &RPCServerDriver{
+		ActualDriver: &panicDriver{
+			returnErr: mcnerror.MarkTransient(errors.New("API not available")),
+		},
+	}
+
+	// Only the message of the error goes through RPC.
+	err := mcnerror.FromMessage(errors.New(serverDriver.Create(nil, nil).Error()))
+
+	assert.True(t, errors.Is(err, mcnerror.ErrTransient))
+	assert.EqualError(t, err, "API not available")
+}
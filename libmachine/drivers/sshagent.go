@@ -0,0 +1,34 @@
+package drivers
+
+// sshAgentForwarder is implemented by BaseDriver and the plugin drivers,
+// whose SSH clients can forward the local SSH agent.
+type sshAgentForwarder interface {
+	SetSSHAgentForward(enabled bool)
+	SSHAgentForward() bool
+}
+
+// SetSSHAgentForward forwards the local SSH agent, found with SSH_AUTH_SOCK,
+// to the sessions of the SSH clients created afterwards for the machine of d.
+// It isn't saved with the machine and is off by default, as anyone with root
+// access to the machine can then use the agent to authenticate as the local
+// user while a session is open.
+func SetSSHAgentForward(d Driver, enabled bool) {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	if forwarder, ok := d.(sshAgentForwarder); ok {
+		forwarder.SetSSHAgentForward(enabled)
+	}
+}
+
+// SSHAgentForward returns true if the SSH clients of the machine of d forward
+// the local SSH agent.
+func SSHAgentForward(d Driver) bool {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	forwarder, ok := d.(sshAgentForwarder)
+	return ok && forwarder.SSHAgentForward()
+}
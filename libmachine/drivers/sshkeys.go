@@ -0,0 +1,68 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+)
+
+// MachineSSHKey is an SSH key resource uploaded to a provider for a machine,
+// e.g. an EC2 key pair.
+type MachineSSHKey struct {
+	// ID identifies the key for DeleteSSHKey.
+	ID string
+	// Name is the name of the key at the provider.
+	Name string
+	// MachineName is the name of the machine the key was uploaded for.
+	MachineName string
+	// InUse tells whether an instance of the provider still uses the key.
+	InUse bool
+}
+
+// SSHKeyManager is implemented by the drivers uploading an SSH key resource
+// to their provider for each machine, so that the keys left behind by
+// machines removed outside of machine can be found and deleted.
+type SSHKeyManager interface {
+	// MachineSSHKeys lists the SSH key resources uploaded by machine, in
+	// the account and region of the driver.
+	MachineSSHKeys() ([]MachineSSHKey, error)
+
+	// DeleteSSHKey deletes an SSH key resource listed by MachineSSHKeys.
+	DeleteSSHKey(id string) error
+}
+
+// MachineSSHKeys lists the SSH key resources uploaded by machine with the
+// account of d, or returns an ErrNotSupported error if the driver doesn't
+// upload SSH keys or can't find them.
+func MachineSSHKeys(d Driver) ([]MachineSSHKey, error) {
+	manager, err := sshKeyManager(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.MachineSSHKeys()
+}
+
+// DeleteSSHKey deletes an SSH key resource listed by MachineSSHKeys with the
+// account of d.
+func DeleteSSHKey(d Driver, id string) error {
+	manager, err := sshKeyManager(d)
+	if err != nil {
+		return err
+	}
+
+	return manager.DeleteSSHKey(id)
+}
+
+func sshKeyManager(d Driver) (SSHKeyManager, error) {
+	if serial, ok := d.(*SerialDriver); ok {
+		d = serial.Driver
+	}
+
+	manager, ok := d.(SSHKeyManager)
+	if !ok {
+		return nil, mcnerror.NotSupported(fmt.Errorf("the %s driver can't list the SSH keys it uploaded", d.DriverName()))
+	}
+
+	return manager, nil
+}
@@ -0,0 +1,50 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSSHKeyDriver struct {
+	MockDriver
+	keys    []MachineSSHKey
+	deleted []string
+}
+
+func (d *mockSSHKeyDriver) MachineSSHKeys() ([]MachineSSHKey, error) {
+	return d.keys, nil
+}
+
+func (d *mockSSHKeyDriver) DeleteSSHKey(id string) error {
+	d.deleted = append(d.deleted, id)
+	return nil
+}
+
+func TestMachineSSHKeys(t *testing.T) {
+	driver := &mockSSHKeyDriver{
+		MockDriver: MockDriver{calls: &CallRecorder{}},
+		keys:       []MachineSSHKey{{ID: "key-1", Name: "dev-1a2b3c4d", MachineName: "dev"}},
+	}
+	serial := newSerialDriverWithLock(driver, &MockLocker{calls: &CallRecorder{}})
+
+	keys, err := MachineSSHKeys(serial)
+	assert.NoError(t, err)
+	assert.Equal(t, driver.keys, keys)
+
+	assert.NoError(t, DeleteSSHKey(serial, "key-1"))
+	assert.Equal(t, []string{"key-1"}, driver.deleted)
+}
+
+func TestMachineSSHKeysNotSupported(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+
+	_, err := MachineSSHKeys(driver)
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+	assert.EqualError(t, err, "the mock driver can't list the SSH keys it uploaded")
+
+	err = DeleteSSHKey(driver, "key-1")
+	assert.True(t, errors.Is(err, mcnerror.ErrNotSupported))
+}
@@ -0,0 +1,152 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	sshProbeTimeout = 3 * time.Second
+
+	// maxUnroutableProbes is the number of probes in a row reporting no
+	// route to the machine after which waiting for SSH is given up.
+	maxUnroutableProbes = 20
+
+	// sshPortProbeEnv enables the probe for every machine.
+	sshPortProbeEnv = "MACHINE_SSH_PORT_PROBE"
+)
+
+// sshPortProbeEnabled returns true if WaitForSSH and WaitForSSHDetectingUser
+// make a TCP connect probe of the SSH port of the machine of d before each SSH
+// attempt, with the SSHPortProbe of its BaseDriver, which the plugins get in
+// their config, or with MACHINE_SSH_PORT_PROBE set to a true value, as
+// strconv.ParseBool reads it. ICMP is often blocked, the probe tells a
+// machine without a route to it, or behind a closed firewall, from a machine
+// whose SSH server is not ready yet.
+func sshPortProbeEnabled(d Driver) bool {
+	if value := os.Getenv(sshPortProbeEnv); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Warnf("Ignoring %s=%q: %s", sshPortProbeEnv, value, err)
+		} else if enabled {
+			return true
+		}
+	}
+
+	rawDriver, err := json.Marshal(d)
+	if err != nil {
+		return false
+	}
+
+	config := struct{ SSHPortProbe bool }{}
+	return json.Unmarshal(rawDriver, &config) == nil && config.SSHPortProbe
+}
+
+var errSSHUnroutable = errors.New("no route to the SSH port of the machine")
+
+type reachability int
+
+const (
+	portOpen reachability = iota
+	portClosed
+	portFiltered
+	hostUnroutable
+)
+
+func (r reachability) String() string {
+	switch r {
+	case portOpen:
+		return "SSH port is open, waiting for SSH to be ready"
+	case portClosed:
+		return "machine is reachable, SSH is not listening yet"
+	case portFiltered:
+		return "no answer from the SSH port, the machine may still be booting or a firewall or security group may be dropping the traffic"
+	default:
+		return "machine is not routable, check the network route to it"
+	}
+}
+
+// classifyDialError tells why a TCP connection to the SSH port failed.
+func classifyDialError(err error) reachability {
+	if err == nil {
+		return portOpen
+	}
+
+	if errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return hostUnroutable
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return portClosed
+	}
+
+	return portFiltered
+}
+
+// sshProber keeps the state of the probes of one wait for SSH.
+type sshProber struct {
+	enabled    bool
+	last       reachability
+	probed     bool
+	unroutable int
+}
+
+func newSSHProber(d Driver) *sshProber {
+	return &sshProber{enabled: sshPortProbeEnabled(d)}
+}
+
+// check returns nil when SSH can be attempted. It fails with
+// errSSHUnroutable once the machine had no route for too long.
+func (p *sshProber) check(d Driver) error {
+	if !p.enabled {
+		return nil
+	}
+
+	address, err := d.GetSSHHostname()
+	if err != nil {
+		return fmt.Errorf("SSH address is not known yet: %s", err)
+	}
+
+	port, err := d.GetSSHPort()
+	if err != nil {
+		return fmt.Errorf("SSH port is not known yet: %s", err)
+	}
+
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+
+	conn, dialErr := net.DialTimeout("tcp", addr, sshProbeTimeout)
+	if dialErr == nil {
+		conn.Close()
+	}
+
+	r := classifyDialError(dialErr)
+	if !p.probed || r != p.last {
+		log.Infof("Probing %s: %s", addr, r)
+	}
+	p.probed = true
+	p.last = r
+
+	if r == hostUnroutable {
+		p.unroutable++
+	} else {
+		p.unroutable = 0
+	}
+
+	if p.unroutable >= maxUnroutableProbes {
+		return fmt.Errorf("%w %s after %d attempts: %s", errSSHUnroutable, addr, p.unroutable, dialErr)
+	}
+
+	if dialErr != nil {
+		return fmt.Errorf("%s: %s", r, dialErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package drivers
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type probeFakeDriver struct {
+	Driver
+	address string
+	port    int
+}
+
+func (d *probeFakeDriver) GetSSHHostname() (string, error) {
+	return d.address, nil
+}
+
+func (d *probeFakeDriver) GetSSHPort() (int, error) {
+	return d.port, nil
+}
+
+type probeConfigDriver struct {
+	Driver       `json:"-"`
+	SSHPortProbe bool
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func dialError(err error) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: err}
+}
+
+func TestClassifyDialError(t *testing.T) {
+	assert.Equal(t, portOpen, classifyDialError(nil))
+	assert.Equal(t, portClosed, classifyDialError(dialError(os.NewSyscallError("connect", syscall.ECONNREFUSED))))
+	assert.Equal(t, hostUnroutable, classifyDialError(dialError(os.NewSyscallError("connect", syscall.EHOSTUNREACH))))
+	assert.Equal(t, hostUnroutable, classifyDialError(dialError(os.NewSyscallError("connect", syscall.ENETUNREACH))))
+	assert.Equal(t, portFiltered, classifyDialError(dialError(timeoutError{})))
+}
+
+func TestSSHProberCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	d := &probeFakeDriver{address: "127.0.0.1", port: port}
+
+	prober := &sshProber{enabled: true}
+	assert.NoError(t, prober.check(d))
+
+	listener.Close()
+	err = prober.check(d)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, errSSHUnroutable))
+	assert.Equal(t, portClosed, prober.last)
+
+	assert.NoError(t, (&sshProber{}).check(d))
+}
+
+func TestSSHPortProbeEnabled(t *testing.T) {
+	t.Setenv(sshPortProbeEnv, "")
+
+	assert.True(t, sshPortProbeEnabled(&probeConfigDriver{SSHPortProbe: true}))
+	assert.False(t, sshPortProbeEnabled(&probeConfigDriver{}))
+
+	for _, value := range []string{"1", "true", "TRUE"} {
+		t.Setenv(sshPortProbeEnv, value)
+		assert.True(t, sshPortProbeEnabled(&probeConfigDriver{}), value)
+	}
+
+	for _, value := range []string{"0", "false", "yes"} {
+		t.Setenv(sshPortProbeEnv, value)
+		assert.False(t, sshPortProbeEnabled(&probeConfigDriver{}), value)
+		assert.True(t, sshPortProbeEnabled(&probeConfigDriver{SSHPortProbe: true}), value)
+	}
+}
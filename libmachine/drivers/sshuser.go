@@ -0,0 +1,177 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/ssh"
+)
+
+// DefaultSSHUsers lists, per driver, the users the common images of that
+// provider ship with. They are tried in order when the configured user is
+// refused by the machine.
+var DefaultSSHUsers = map[string][]string{
+	"amazonec2":    {"ubuntu", "ec2-user", "admin", "centos", "rocky", "core"},
+	"openstack":    {"ubuntu", "cloud-user", "centos", "debian", "fedora", "core"},
+	"digitalocean": {"root", "core", "rancher"},
+}
+
+// commonSSHUsers is the fallback list for drivers without an entry in
+// DefaultSSHUsers.
+var commonSSHUsers = []string{"ubuntu", "ec2-user", "admin", "core", "root"}
+
+type sshUserSetter interface {
+	SetSSHUser(user string)
+}
+
+type rawConfigDriver interface {
+	GetConfigRaw() ([]byte, error)
+	SetConfigRaw(data []byte) error
+}
+
+// SSHUserCandidates returns the users to try for the given driver, starting
+// with the configured one and without duplicates.
+func SSHUserCandidates(driverName, configured string) []string {
+	users, ok := DefaultSSHUsers[driverName]
+	if !ok {
+		users = commonSSHUsers
+	}
+
+	candidates := []string{}
+	seen := map[string]bool{}
+	for _, user := range append([]string{configured}, users...) {
+		if user == "" || seen[user] {
+			continue
+		}
+		seen[user] = true
+		candidates = append(candidates, user)
+	}
+
+	return candidates
+}
+
+// SetSSHUser changes the SSH user of the driver. Drivers running as plugins
+// are updated through their raw configuration.
+func SetSSHUser(d Driver, user string) error {
+	switch t := d.(type) {
+	case sshUserSetter:
+		t.SetSSHUser(user)
+		return nil
+	case rawConfigDriver:
+		data, err := t.GetConfigRaw()
+		if err != nil {
+			return err
+		}
+
+		config := map[string]interface{}{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+
+		if _, ok := config["SSHUser"]; !ok {
+			break
+		}
+		config["SSHUser"] = user
+
+		if data, err = json.Marshal(config); err != nil {
+			return err
+		}
+
+		return t.SetConfigRaw(data)
+	}
+
+	return fmt.Errorf("driver %q does not support changing the SSH user", d.DriverName())
+}
+
+// WaitForSSHDetectingUser works like WaitForSSH, but when the SSH port is
+// open and the configured user is refused, the other candidate users of the
+// driver are tried. The first user that succeeds is stored in the driver.
+func WaitForSSHDetectingUser(d Driver) error {
+	configured := d.GetSSHUsername()
+	candidates := SSHUserCandidates(d.DriverName(), configured)
+
+	var lastErr error
+	prober := newSSHProber(d)
+	for i := 0; i < 60; i++ {
+		if lastErr = prober.check(d); lastErr != nil {
+			if errors.Is(lastErr, errSSHUnroutable) {
+				return lastErr
+			}
+
+			log.Debugf("Error probing SSH: %s", lastErr)
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		for _, user := range candidates {
+			if _, lastErr = runSSHCommandAs(d, user, "exit 0"); lastErr == nil {
+				if user == configured {
+					return nil
+				}
+
+				log.Infof("SSH user %q was refused, using detected user %q", configured, user)
+				return SetSSHUser(d, user)
+			}
+
+			log.Debugf("Error getting SSH command 'exit 0' as %q : %s", user, lastErr)
+
+			// Until sshd answers there is no point in trying other users.
+			if !sshPortOpen(d) {
+				break
+			}
+		}
+
+		time.Sleep(3 * time.Second)
+	}
+
+	return fmt.Errorf("Too many retries waiting for SSH to be available with any of the users %v. Last error: %w", candidates, lastErr)
+}
+
+func runSSHCommandAs(d Driver, user, command string) (string, error) {
+	address, err := d.GetSSHHostname()
+	if err != nil {
+		return "", err
+	}
+
+	port, err := d.GetSSHPort()
+	if err != nil {
+		return "", err
+	}
+
+	auth := &ssh.Auth{}
+	if d.GetSSHKeyPath() != "" {
+		auth.Keys = []string{d.GetSSHKeyPath()}
+	}
+
+	client, err := ssh.NewClient(user, address, port, auth)
+	if err != nil {
+		return "", err
+	}
+
+	return client.Output(command)
+}
+
+func sshPortOpen(d Driver) bool {
+	address, err := d.GetSSHHostname()
+	if err != nil {
+		return false
+	}
+
+	port, err := d.GetSSHPort()
+	if err != nil {
+		return false
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(port)), 5*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
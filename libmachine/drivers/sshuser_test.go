@@ -0,0 +1,63 @@
+package drivers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rawConfigFakeDriver behaves like a plugin driver: its configuration can
+// only be changed through the raw JSON.
+type rawConfigFakeDriver struct {
+	Driver
+	raw []byte
+}
+
+func (d *rawConfigFakeDriver) GetConfigRaw() ([]byte, error) {
+	return d.raw, nil
+}
+
+func (d *rawConfigFakeDriver) SetConfigRaw(data []byte) error {
+	d.raw = data
+	return nil
+}
+
+// sshUserFakeDriver sets its SSH user like the drivers embedding BaseDriver.
+type sshUserFakeDriver struct {
+	Driver
+	user string
+}
+
+func (d *sshUserFakeDriver) GetSSHUsername() string {
+	return d.user
+}
+
+func (d *sshUserFakeDriver) SetSSHUser(user string) {
+	d.user = user
+}
+
+func TestSSHUserCandidates(t *testing.T) {
+	assert.Equal(t, []string{"ubuntu", "ec2-user", "admin", "centos", "rocky", "core"}, SSHUserCandidates("amazonec2", "ubuntu"))
+	assert.Equal(t, []string{"fedora", "ubuntu", "ec2-user", "admin", "centos", "rocky", "core"}, SSHUserCandidates("amazonec2", "fedora"))
+	assert.Equal(t, []string{"ubuntu", "ec2-user", "admin", "core", "root"}, SSHUserCandidates("unknown", ""))
+}
+
+func TestSetSSHUser(t *testing.T) {
+	d := &sshUserFakeDriver{user: "ubuntu"}
+
+	assert.NoError(t, SetSSHUser(d, "admin"))
+	assert.Equal(t, "admin", d.GetSSHUsername())
+}
+
+func TestSetSSHUserRawConfig(t *testing.T) {
+	raw, _ := json.Marshal(&BaseDriver{SSHUser: "ubuntu", MachineName: "foo"})
+	d := &rawConfigFakeDriver{raw: raw}
+
+	assert.NoError(t, SetSSHUser(d, "ec2-user"))
+
+	config := BaseDriver{}
+	assert.NoError(t, json.Unmarshal(d.raw, &config))
+	assert.Equal(t, "ec2-user", config.SSHUser)
+	assert.Equal(t, "foo", config.MachineName)
+}
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -27,6 +28,7 @@ func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
 			Keys: []string{d.GetSSHKeyPath()},
 		}
 	}
+	auth.ForwardAgent = SSHAgentForward(d)
 
 	client, err := ssh.NewClient(d.GetSSHUsername(), address, port, auth)
 	return client, err
@@ -55,9 +57,14 @@ func RunSSHCommandFromDriver(d Driver, command string) (string, error) {
 // attempt, the error will be returned.
 func WaitForSSH(d Driver) error {
 	var lastErr error
+	prober := newSSHProber(d)
 	for i := 0; i < 60; i++ {
 		log.Debug("Getting to WaitForSSH function...")
-		if _, lastErr = RunSSHCommandFromDriver(d, "exit 0"); lastErr == nil {
+		if lastErr = prober.check(d); lastErr != nil {
+			if errors.Is(lastErr, errSSHUnroutable) {
+				return lastErr
+			}
+		} else if _, lastErr = RunSSHCommandFromDriver(d, "exit 0"); lastErr == nil {
 			return nil
 		}
 
@@ -18,4 +18,107 @@ type Options struct {
 	TLSVerify        bool `json:"TlsVerify"`
 	RegistryMirror   []string
 	InstallURL       string
+	// InstallURLSHA256 is the expected SHA-256 of the script served at
+	// InstallURL. When set, the script is verified before being run.
+	InstallURLSHA256 string `json:",omitempty"`
+	// InstallScriptFile is a local install script used instead of
+	// downloading one from InstallURL.
+	InstallScriptFile string `json:",omitempty"`
+	// SystemdDropIns are KEY=VALUE directives of the [Service] section of
+	// the docker unit, written to a drop-in by the systemd based
+	// provisioners.
+	SystemdDropIns []string `json:",omitempty"`
+	// SystemdDropInFile is a local drop-in file for the docker unit.
+	SystemdDropInFile string `json:",omitempty"`
+	// FlagFile is a local file of daemon flags, one per line, passed to the
+	// daemon before ArbitraryFlags.
+	FlagFile string `json:",omitempty"`
+	// MetricsAddr is the host:port the daemon serves its Prometheus
+	// metrics on.
+	MetricsAddr string `json:",omitempty"`
+	// Hostname is the OS hostname set by the provisioners. The machine name
+	// is used when empty.
+	Hostname string `json:",omitempty"`
+	// KeepHostname leaves the hostname set by the provider untouched.
+	KeepHostname bool `json:",omitempty"`
+	// DNSSearch are the search domains written to the resolver of the
+	// machine along with the DNS servers, and passed to the daemon.
+	DNSSearch []string `json:",omitempty"`
+	// Sysctls are key=value kernel parameters written to /etc/sysctl.d on
+	// the machine and applied.
+	Sysctls []string `json:",omitempty"`
+	// TimeZone is the tz database name of the time zone set on the machine.
+	TimeZone string `json:",omitempty"`
+	// NTPServers are the servers the NTP client of the machine, chrony or
+	// systemd-timesyncd, synchronizes the clock with.
+	NTPServers []string `json:",omitempty"`
+	// Rootless runs the daemon as the SSH user with rootless Docker, on the
+	// systemd based provisioners only.
+	Rootless bool `json:",omitempty"`
+	// PreferIPv6 makes the daemon URL, the swarm and ip of dual-stack
+	// machines use their IPv6 address. SSH uses the address of the driver.
+	PreferIPv6 bool `json:",omitempty"`
+	// DaemonHostname is the DNS name of the machine used in the daemon URL
+	// instead of its IP, and added to the SANs of the server cert.
+	DaemonHostname string `json:",omitempty"`
+	// DaemonUsePrivateIP makes the daemon reached at the private IP of the
+	// machine, for the drivers reporting one, while SSH still uses the
+	// address reported by the driver.
+	DaemonUsePrivateIP bool `json:",omitempty"`
+	// SSHTransport makes the daemon reached with the ssh:// protocol of the
+	// docker client as the SSH user, the daemon listening on its socket only
+	// and no TLS certs being set up. Systemd based provisioners only.
+	SSHTransport bool `json:",omitempty"`
+	// DaemonHostnameNoVerify skips checking that DaemonHostname resolves
+	// and reaches the daemon, e.g. when it is registered after creation.
+	DaemonHostnameNoVerify bool `json:",omitempty"`
+	// RegistryCAFiles are <registry>=<file> entries of the local CA files
+	// trusted by the daemon for pulls from the registry.
+	RegistryCAFiles []string `json:",omitempty"`
+	// DaemonExternalURL is the URL of a load balancer in front of the daemon,
+	// given to the clients instead of the URL of the machine. The machine is
+	// still managed at its own address.
+	DaemonExternalURL string `json:",omitempty"`
+	// Port is the TCP port the daemon listens on, instead of the port of
+	// the URL of the driver, DefaultPort for most drivers.
+	Port int `json:",omitempty"`
+	// CgroupDriver is the cgroup driver of the daemon, systemd or cgroupfs.
+	// When empty, systemd is set on the cgroup v2 machines managed by
+	// systemd and the default of the daemon is kept on the others.
+	CgroupDriver string `json:",omitempty"`
+	// NvidiaRuntime installs the NVIDIA container toolkit and registers the
+	// nvidia runtime, as the default runtime when "default" or beside runc
+	// when "available".
+	NvidiaRuntime string `json:",omitempty"`
+	// ContainerdConfigFile is a local containerd config file merged into
+	// /etc/containerd/config.toml by the systemd based provisioners.
+	ContainerdConfigFile string `json:",omitempty"`
+	// NetworkPlugin is a built-in network plugin setup, or plugin:<reference>
+	// of a managed plugin, set up once the daemon is up.
+	NetworkPlugin string `json:",omitempty"`
+	// PackageMirror is the URL of a mirror of the distribution packages,
+	// configured before the packages and Docker are installed.
+	PackageMirror string `json:",omitempty"`
+	// PackageMirrorAuth are the user:password credentials of the package
+	// mirror. They are not stored with the machine.
+	PackageMirrorAuth string `json:"-"`
+	// PackageMirrorEphemeral removes the credentials of the package mirror
+	// from the machine once it is provisioned.
+	PackageMirrorEphemeral bool `json:",omitempty"`
+	// MTU is the MTU of the default bridge and of the bridge networks
+	// created by the daemon, written to daemon.json.
+	MTU int `json:",omitempty"`
+	// DefaultUlimits are the name=soft[:hard] default ulimits of the
+	// containers, written to daemon.json.
+	DefaultUlimits []string `json:",omitempty"`
+	// LimitNOFILE is the LimitNOFILE of the daemon process, set with the
+	// systemd drop-in of the docker unit.
+	LimitNOFILE string `json:",omitempty"`
+	// NetworkPluginScript is a local script run as root on the machine once
+	// the daemon is up, to set up a custom network plugin.
+	NetworkPluginScript string `json:",omitempty"`
+	// Boot2DockerProfileFile is a local script installed as the bootlocal.sh
+	// of the persistence partition of boot2docker machines, run at every
+	// boot.
+	Boot2DockerProfileFile string `json:",omitempty"`
 }
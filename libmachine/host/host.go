@@ -1,7 +1,14 @@
 package host
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rancher/machine/libmachine/auth"
 	"github.com/rancher/machine/libmachine/cert"
@@ -20,7 +27,17 @@ import (
 	"github.com/rancher/machine/libmachine/versioncmp"
 )
 
-const noDockerError = "Docker was not provisioned on machine %s, %s"
+const (
+	noDockerError = "Docker was not provisioned on machine %s, %s"
+
+	// bootIDCommand prints the ID the kernel generates on each boot.
+	bootIDCommand = "cat /proc/sys/kernel/random/boot_id"
+
+	// guestRebootCommand reboots a machine from the guest once the SSH
+	// command returned, so that it isn't reported as failed when the
+	// connection drops.
+	guestRebootCommand = "sudo sh -c '(sleep 2; reboot) > /dev/null 2>&1 &'"
+)
 
 var (
 	validHostNamePattern                  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\.]*$`)
@@ -46,8 +63,27 @@ type Host struct {
 	HostOptions   *Options
 	Name          string
 	RawDriver     []byte `json:"-"`
+	// ProvisioningStatus is the phase of creation or provisioning the host
+	// reached, empty for hosts created before it was recorded.
+	ProvisioningStatus ProvisioningStatus `json:",omitempty"`
 }
 
+// ProvisioningStatus is the phase of creation or provisioning of a host.
+type ProvisioningStatus string
+
+const (
+	// ProvisioningCreated is the status of a host whose instance was
+	// created but not provisioned yet.
+	ProvisioningCreated ProvisioningStatus = "created"
+	// ProvisioningInProgress is the status of a host being provisioned.
+	ProvisioningInProgress ProvisioningStatus = "provisioning"
+	// Provisioned is the status of a host ready to be used.
+	Provisioned ProvisioningStatus = "provisioned"
+	// ProvisioningFailed is the status of a host whose creation or
+	// provisioning failed after its instance was created.
+	ProvisioningFailed ProvisioningStatus = "failed"
+)
+
 type Options struct {
 	Driver              string
 	Memory              int
@@ -55,9 +91,37 @@ type Options struct {
 	CustomInstallScript string
 	HostnameOverride    string
 	MachineOS           string
+	DetectSSHUser       bool
+	ExpectIP            string
 	EngineOptions       *engine.Options
 	SwarmOptions        *swarm.Options
 	AuthOptions         *auth.Options
+	// DriverOptions are the driver flag values the machine was created
+	// with, kept so that it can be cloned.
+	DriverOptions map[string]interface{} `json:",omitempty"`
+	// Created is when the machine was created, zero for machines created
+	// before it was recorded.
+	Created time.Time
+	// NoProvision skips the provisioning of the machine, its image being
+	// expected to run a Docker daemon listening with TLS on the engine port,
+	// with a server cert signed by the CA of AuthOptions and verifying the
+	// client certs signed by it. Only the client certs are copied to the
+	// machine directory.
+	NoProvision bool `json:",omitempty"`
+	// SkipSSHWait, with NoProvision, doesn't wait for SSH either, for the
+	// images configured by cloud-init from the userdata.
+	SkipSSHWait bool `json:",omitempty"`
+	// PreRemoveScript and PostRemoveScript are local scripts run by rm
+	// before and after the instance is removed, with the name and the IP of
+	// the machine as arguments.
+	PreRemoveScript  string `json:",omitempty"`
+	PostRemoveScript string `json:",omitempty"`
+	// EncryptVolumes records that the disks of the instance were encrypted
+	// with --encrypt-volumes, the key being in the driver options.
+	EncryptVolumes bool `json:",omitempty"`
+	// WaitCloudInitTimeout, when set, is the number of seconds to wait for
+	// cloud-init to be done before the machine is provisioned.
+	WaitCloudInitTimeout int `json:",omitempty"`
 }
 
 type Metadata struct {
@@ -89,7 +153,7 @@ func (creator *StandardSSHClientCreator) CreateSSHClient(d drivers.Driver) (ssh.
 		return &ssh.ExternalClient{}, err
 	}
 
-	auth := &ssh.Auth{}
+	auth := &ssh.Auth{ForwardAgent: drivers.SSHAgentForward(d)}
 	if d.GetSSHKeyPath() != "" {
 		auth.Keys = []string{d.GetSSHKeyPath()}
 	}
@@ -118,7 +182,12 @@ func (h *Host) WaitForDocker() error {
 		return err
 	}
 
-	return provision.WaitForDocker(provisioner, engine.DefaultPort)
+	port := engine.DefaultPort
+	if enginePort := h.EnginePort(); enginePort != 0 {
+		port = enginePort
+	}
+
+	return provision.WaitForDocker(provisioner, port)
 }
 
 func (h *Host) Start() error {
@@ -154,7 +223,7 @@ func (h *Host) Kill() error {
 
 func (h *Host) Restart() error {
 	log.Infof("Restarting %q...", h.Name)
-	if drivers.MachineInState(h.Driver, state.Stopped)() {
+	if drivers.MachineInState(h.Driver, state.Stopped)() || drivers.MachineInState(h.Driver, state.Preempted)() {
 		if err := h.Start(); err != nil {
 			return err
 		}
@@ -170,8 +239,71 @@ func (h *Host) Restart() error {
 	return h.WaitForDocker()
 }
 
+// Reboot reboots the running instance of the machine, which is kept unlike
+// with a stop and start. The reboot API of the provider is used when the
+// driver has one, else the machine is rebooted from the guest over SSH.
+func (h *Host) Reboot() error {
+	if !drivers.MachineInState(h.Driver, state.Running)() {
+		return fmt.Errorf("machine %q is not running, only running machines can be rebooted", h.Name)
+	}
+
+	// The instance may keep the running state during the reboot, which is
+	// only known to be done once the kernel booted again.
+	bootID, err := h.RunSSHCommand(bootIDCommand)
+	if err != nil {
+		log.Debugf("Couldn't read the boot ID of %q, not waiting for the reboot to be done: %s", h.Name, err)
+		bootID = ""
+	}
+	bootID = strings.TrimSpace(bootID)
+
+	log.Infof("Rebooting %q...", h.Name)
+	err = drivers.Reboot(h.Driver)
+	if errors.Is(err, mcnerror.ErrNotSupported) {
+		log.Infof("The %s driver has no reboot API, rebooting %q from the guest...", h.DriverName, h.Name)
+		_, err = h.RunSSHCommand(guestRebootCommand)
+	}
+	if err != nil {
+		return err
+	}
+
+	if bootID != "" {
+		if err := mcnutils.WaitFor(h.bootedAgain(bootID)); err != nil {
+			return fmt.Errorf("machine %q was not rebooted: %s", h.Name, err)
+		}
+	}
+
+	if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running)); err != nil {
+		return err
+	}
+
+	log.Infof("Machine %q was rebooted.", h.Name)
+
+	return h.WaitForDocker()
+}
+
+// bootedAgain returns a func telling whether the machine answers over SSH
+// with a boot ID other than the given one.
+func (h *Host) bootedAgain(bootID string) func() bool {
+	return func() bool {
+		current, err := h.RunSSHCommand(bootIDCommand)
+		if err != nil {
+			log.Debugf("Machine %q is not back up yet: %s", h.Name, err)
+			return false
+		}
+		return strings.TrimSpace(current) != bootID
+	}
+}
+
 func (h *Host) DockerVersion() (string, error) {
-	url, err := h.Driver.GetURL()
+	if h.DaemonSSHTransport() {
+		output, err := h.RunSSHCommand("docker version --format '{{.Server.Version}}'")
+		if err != nil {
+			return "", fmt.Errorf("Unable to query docker version: %s", err)
+		}
+		return strings.TrimSpace(output), nil
+	}
+
+	url, err := h.URL()
 	if err != nil {
 		return "", err
 	}
@@ -253,8 +385,131 @@ func (h *Host) Upgrade() error {
 	return provisioner.Service("docker", serviceaction.Restart)
 }
 
+// URL returns the URL of the daemon of the machine, at its daemon hostname
+// if it has one, or at its IPv6 address if the machine prefers it, and at its
+// engine port if it has one.
 func (h *Host) URL() (string, error) {
-	return h.Driver.GetURL()
+	if h.DaemonSSHTransport() {
+		return h.sshTransportURL()
+	}
+
+	u, err := h.daemonURL()
+	if err != nil {
+		return u, err
+	}
+
+	if port := h.EnginePort(); port != 0 {
+		return drivers.URLWithPort(u, port)
+	}
+
+	return u, nil
+}
+
+// EnginePort returns the port the daemon of the machine listens on when it
+// isn't the one of the URL of the driver, else 0.
+func (h *Host) EnginePort() int {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return 0
+	}
+	return h.HostOptions.EngineOptions.Port
+}
+
+func (h *Host) daemonURL() (string, error) {
+	u, err := h.Driver.GetURL()
+	if err != nil {
+		return u, err
+	}
+
+	if hostname := h.DaemonHostname(); hostname != "" {
+		return drivers.URLWithIP(u, hostname)
+	}
+
+	if h.DaemonUsePrivateIP() {
+		ip, err := drivers.PrivateIP(h.Driver)
+		if err != nil {
+			return "", err
+		}
+		return drivers.URLWithIP(u, ip)
+	}
+
+	if !h.PreferIPv6() {
+		return u, nil
+	}
+
+	ipv6, err := h.Driver.GetIPv6()
+	if err != nil || ipv6 == "" {
+		log.Debugf("Machine %s has no IPv6 address, using %s", h.Name, u)
+		return u, nil
+	}
+
+	return drivers.URLWithIP(u, ipv6)
+}
+
+// ClientURL returns the URL given to the Docker clients, the external URL of
+// the daemon if it has one, or its URL.
+func (h *Host) ClientURL() (string, error) {
+	if externalURL := h.DaemonExternalURL(); externalURL != "" {
+		return externalURL, nil
+	}
+
+	return h.URL()
+}
+
+// DaemonExternalURL returns the URL of the load balancer in front of the
+// daemon of the machine, if any.
+func (h *Host) DaemonExternalURL() string {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return ""
+	}
+	return h.HostOptions.EngineOptions.DaemonExternalURL
+}
+
+// PreferIPv6 returns true if the daemon of the machine should be reached at
+// its IPv6 address. SSH still uses the address reported by the driver.
+func (h *Host) PreferIPv6() bool {
+	return h.HostOptions != nil && h.HostOptions.EngineOptions != nil && h.HostOptions.EngineOptions.PreferIPv6
+}
+
+// DaemonHostname returns the DNS name the daemon of the machine is reached
+// at, if any.
+func (h *Host) DaemonHostname() string {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return ""
+	}
+	return h.HostOptions.EngineOptions.DaemonHostname
+}
+
+// DaemonSSHTransport returns true if the daemon of the machine is reached
+// over SSH instead of TLS.
+func (h *Host) DaemonSSHTransport() bool {
+	return h.HostOptions != nil && h.HostOptions.EngineOptions != nil && h.HostOptions.EngineOptions.SSHTransport
+}
+
+// sshTransportURL returns the ssh:// URL the docker client reaches the daemon
+// at, as the SSH user of the machine.
+func (h *Host) sshTransportURL() (string, error) {
+	hostname, err := h.Driver.GetSSHHostname()
+	if err != nil {
+		return "", err
+	}
+
+	port, err := h.Driver.GetSSHPort()
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{
+		Scheme: "ssh",
+		User:   url.User(h.Driver.GetSSHUsername()),
+		Host:   net.JoinHostPort(hostname, strconv.Itoa(port)),
+	}
+	return u.String(), nil
+}
+
+// DaemonUsePrivateIP returns true if the daemon of the machine is reached at
+// its private IP.
+func (h *Host) DaemonUsePrivateIP() bool {
+	return h.HostOptions != nil && h.HostOptions.EngineOptions != nil && h.HostOptions.EngineOptions.DaemonUsePrivateIP
 }
 
 func (h *Host) AuthOptions() *auth.Options {
@@ -296,7 +551,36 @@ func (h *Host) ConfigureAllAuth() error {
 	return h.ConfigureAuth()
 }
 
+// ConfigureClientAuth copies the CA and the client cert, which must have been
+// reissued with cert.RegenerateClientCert, to the machine directory. The
+// daemon is untouched.
+func (h *Host) ConfigureClientAuth() error {
+	if h.HostOptions.AuthOptions == nil {
+		log.Warnf(noDockerError, h.Name, "cannot configure auth")
+		return nil
+	}
+
+	return provision.CopyClientCerts(*h.HostOptions.AuthOptions)
+}
+
+// IsProvisioned returns true if the host is ready to be used. Hosts created
+// before the provisioning status was recorded are considered provisioned.
+func (h *Host) IsProvisioned() bool {
+	return h.ProvisioningStatus == "" || h.ProvisioningStatus == Provisioned
+}
+
 func (h *Host) Provision() error {
+	h.ProvisioningStatus = ProvisioningInProgress
+	if err := h.provision(); err != nil {
+		h.ProvisioningStatus = ProvisioningFailed
+		return err
+	}
+
+	h.ProvisioningStatus = Provisioned
+	return nil
+}
+
+func (h *Host) provision() error {
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return err
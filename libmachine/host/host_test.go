@@ -5,6 +5,7 @@ import (
 
 	"github.com/rancher/machine/drivers/fakedriver"
 	_ "github.com/rancher/machine/drivers/none"
+	"github.com/rancher/machine/libmachine/engine"
 	"github.com/rancher/machine/libmachine/provision"
 	"github.com/rancher/machine/libmachine/state"
 )
@@ -55,3 +56,181 @@ func TestStart(t *testing.T) {
 		t.Fatalf("Expected no error but got one: %s", err)
 	}
 }
+
+func TestURLDaemonHostname(t *testing.T) {
+	host := &Host{
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+			MockIP:    "10.0.0.1",
+		},
+		HostOptions: &Options{
+			EngineOptions: &engine.Options{
+				DaemonHostname: "foo.example.com",
+			},
+		},
+	}
+
+	url, err := host.URL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "tcp://foo.example.com:2376" {
+		t.Fatalf("Expected the daemon hostname in the URL, got %s", url)
+	}
+}
+
+type privateIPDriver struct {
+	*fakedriver.Driver
+	PrivateIPAddress string
+}
+
+func TestURLDaemonUsePrivateIP(t *testing.T) {
+	host := &Host{
+		Driver: &privateIPDriver{
+			Driver: &fakedriver.Driver{
+				MockState: state.Running,
+				MockIP:    "203.0.113.7",
+			},
+			PrivateIPAddress: "10.0.0.5",
+		},
+		HostOptions: &Options{
+			EngineOptions: &engine.Options{
+				DaemonUsePrivateIP: true,
+			},
+		},
+	}
+
+	url, err := host.URL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "tcp://10.0.0.5:2376" {
+		t.Fatalf("Expected the private IP in the URL, got %s", url)
+	}
+
+	ip, err := host.Driver.GetIP()
+	if err != nil || ip != "203.0.113.7" {
+		t.Fatalf("Expected SSH to still use the public IP, got %s (%v)", ip, err)
+	}
+}
+
+type sshTransportDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *sshTransportDriver) GetSSHHostname() (string, error) {
+	return "203.0.113.7", nil
+}
+
+func (d *sshTransportDriver) GetSSHPort() (int, error) {
+	return 2222, nil
+}
+
+func (d *sshTransportDriver) GetSSHUsername() string {
+	return "ubuntu"
+}
+
+func TestURLDaemonSSHTransport(t *testing.T) {
+	host := &Host{
+		Driver: &sshTransportDriver{
+			Driver: &fakedriver.Driver{
+				MockState: state.Running,
+				MockIP:    "203.0.113.7",
+			},
+		},
+		HostOptions: &Options{
+			EngineOptions: &engine.Options{
+				SSHTransport: true,
+			},
+		},
+	}
+
+	url, err := host.URL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "ssh://ubuntu@203.0.113.7:2222" {
+		t.Fatalf("Expected the SSH URL of the machine, got %s", url)
+	}
+}
+
+func TestURLEnginePort(t *testing.T) {
+	host := &Host{
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+			MockIP:    "10.0.0.1",
+		},
+		HostOptions: &Options{
+			EngineOptions: &engine.Options{
+				Port: 12376,
+			},
+		},
+	}
+
+	url, err := host.URL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "tcp://10.0.0.1:12376" {
+		t.Fatalf("Expected the URL at the engine port, got %s", url)
+	}
+}
+
+func TestClientURLDaemonExternalURL(t *testing.T) {
+	host := &Host{
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+			MockIP:    "10.0.0.1",
+		},
+		HostOptions: &Options{
+			EngineOptions: &engine.Options{
+				DaemonExternalURL: "tcp://lb.example.com:2376",
+			},
+		},
+	}
+
+	url, err := host.ClientURL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "tcp://lb.example.com:2376" {
+		t.Fatalf("Expected the external URL, got %s", url)
+	}
+
+	url, err = host.URL()
+	if err != nil {
+		t.Fatalf("Expected no error but got one: %s", err)
+	}
+	if url != "tcp://10.0.0.1:2376" {
+		t.Fatalf("Expected the URL of the machine, got %s", url)
+	}
+}
+
+func TestIsProvisioned(t *testing.T) {
+	for status, expected := range map[ProvisioningStatus]bool{
+		"":                     true,
+		Provisioned:            true,
+		ProvisioningCreated:    false,
+		ProvisioningInProgress: false,
+		ProvisioningFailed:     false,
+	} {
+		h := &Host{ProvisioningStatus: status}
+		if h.IsProvisioned() != expected {
+			t.Fatalf("expected IsProvisioned to be %t for status %q", expected, status)
+		}
+	}
+}
+
+func TestRebootNotRunning(t *testing.T) {
+	host := &Host{
+		Name: "dev",
+		Driver: &fakedriver.Driver{
+			MockState: state.Stopped,
+		},
+	}
+
+	err := host.Reboot()
+	if err == nil || err.Error() != `machine "dev" is not running, only running machines can be rebooted` {
+		t.Fatalf("Expected the machine not to be rebooted, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"time"
 
 	"github.com/rancher/machine/drivers/errdriver"
 	"github.com/rancher/machine/libmachine/auth"
@@ -129,6 +130,8 @@ func (api *Client) Create(h *host.Host) error {
 		}
 	}
 
+	h.HostOptions.Created = time.Now()
+
 	if err := api.Save(h); err != nil {
 		return fmt.Errorf("error saving host to store before attempting creation: %s", err)
 	}
@@ -136,16 +139,23 @@ func (api *Client) Create(h *host.Host) error {
 	log.Info("Creating machine...")
 
 	if err := api.performCreate(h); err != nil {
-		if h.Driver.DriverName() == "vmwarevsphere" {
+		if h.ProvisioningStatus != "" {
+			h.ProvisioningStatus = host.ProvisioningFailed
+		}
+		if h.Driver.DriverName() == "vmwarevsphere" || h.ProvisioningStatus != "" {
 			// it is possible that the VM is instantiated but fails to bootstrap,
 			// save the machine to the store, so the VM and associated resources can be found and destroyed later
-			if err := api.Save(h); err != nil {
-				log.Warnf("Error saving host to store after creation fails: %s", err)
+			if exists, _ := api.Exists(h.Name); exists {
+				if err := api.Save(h); err != nil {
+					log.Warnf("Error saving host to store after creation fails: %s", err)
+				}
 			}
 		}
 		return fmt.Errorf("error creating machine: %s", err)
 	}
 
+	h.ProvisioningStatus = host.Provisioned
+
 	log.Debug("Reticulating splines...")
 
 	return nil
@@ -156,6 +166,7 @@ func (api *Client) performCreate(h *host.Host) error {
 		return fmt.Errorf("error in driver during machine creation: %s", err)
 	}
 
+	h.ProvisioningStatus = host.ProvisioningCreated
 	if err := api.Save(h); err != nil {
 		return fmt.Errorf("error saving host to store after attempting creation: %s", err)
 	}
@@ -170,17 +181,52 @@ func (api *Client) performCreate(h *host.Host) error {
 		return fmt.Errorf("error waiting for machine to be running: %s", err)
 	}
 
+	if h.HostOptions.ExpectIP != "" {
+		if err := drivers.CheckExpectedIP(h.Driver, h.HostOptions.ExpectIP); err != nil {
+			log.Infof("Removing %s, its IP doesn't match the expected one...", h.Name)
+			if removeErr := h.Driver.Remove(); removeErr != nil {
+				return fmt.Errorf("%s, removing the instance failed: %s", err, removeErr)
+			}
+			if removeErr := api.Remove(h.Name); removeErr != nil {
+				return fmt.Errorf("%s, removing the local entry failed: %s", err, removeErr)
+			}
+			return err
+		}
+	}
+
+	if h.HostOptions.NoProvision {
+		return skipProvisioning(h)
+	}
+
 	if h.HostOptions.CustomInstallScript != "" && drivers.DriverUserdataFlag(h.Driver) != "" {
 		log.Infof("Custom install script was sent via userdata, provisioning complete...")
 		return nil
 	}
 
+	if h.HostOptions.DetectSSHUser {
+		log.Info("Detecting SSH user of created instance...")
+		if err := drivers.WaitForSSHDetectingUser(h.Driver); err != nil {
+			return fmt.Errorf("error detecting SSH user: %s", err)
+		}
+	}
+
+	h.ProvisioningStatus = host.ProvisioningInProgress
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("error saving host to store before provisioning: %s", err)
+	}
+
 	log.Info("Detecting operating system of created instance...")
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return fmt.Errorf("error detecting OS: %s", err)
 	}
 
+	if h.HostOptions.WaitCloudInitTimeout > 0 {
+		if err := provision.WaitForCloudInit(provisioner, h.HostOptions.WaitCloudInitTimeout); err != nil {
+			return err
+		}
+	}
+
 	log.Infof("Provisioning with %s...", provisioner.String())
 	if h.HostOptions.CustomInstallScript != "" {
 		log.Infof("Provisioning with custom install script via SSH, not installing Docker...")
@@ -194,6 +240,10 @@ func (api *Client) performCreate(h *host.Host) error {
 	// We should check the connection to docker here
 	log.Info("Checking connection to Docker...")
 	if _, _, err = check.DefaultConnChecker.Check(h, false); err != nil {
+		if h.HostOptions.EngineOptions.DaemonHostnameNoVerify {
+			log.Warnf("Docker is not reachable at %s yet, make sure it resolves to the machine: %s", h.HostOptions.EngineOptions.DaemonHostname, err)
+			return nil
+		}
 		return fmt.Errorf("error checking the host: %s", err)
 	}
 
@@ -201,6 +251,30 @@ func (api *Client) performCreate(h *host.Host) error {
 	return nil
 }
 
+// skipProvisioning waits for SSH, unless SkipSSHWait is set, then copies the
+// client certs of a machine whose image runs the Docker daemon itself.
+func skipProvisioning(h *host.Host) error {
+	if h.HostOptions.SkipSSHWait {
+		log.Info("Not waiting for SSH, the image is expected to start Docker with the machine certs...")
+	} else {
+		log.Info("Waiting for SSH to be available...")
+		waitForSSH := drivers.WaitForSSH
+		if h.HostOptions.DetectSSHUser {
+			waitForSSH = drivers.WaitForSSHDetectingUser
+		}
+		if err := waitForSSH(h.Driver); err != nil {
+			return fmt.Errorf("error waiting for SSH: %s", err)
+		}
+	}
+
+	if err := provision.CopyClientCerts(*h.HostOptions.AuthOptions); err != nil {
+		return err
+	}
+
+	log.Info("Not provisioning the machine, Docker is expected to be installed by its image")
+	return nil
+}
+
 func (api *Client) Close() error {
 	return api.clientDriverFactory.Close()
 }
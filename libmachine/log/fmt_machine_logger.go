@@ -10,6 +10,7 @@ type FmtMachineLogger struct {
 	outWriter io.Writer
 	errWriter io.Writer
 	debug     bool
+	quiet     bool
 	history   *HistoryRecorder
 }
 
@@ -27,6 +28,10 @@ func (ml *FmtMachineLogger) SetDebug(debug bool) {
 	ml.debug = debug
 }
 
+func (ml *FmtMachineLogger) SetQuiet(quiet bool) {
+	ml.quiet = quiet
+}
+
 func (ml *FmtMachineLogger) SetOutWriter(out io.Writer) {
 	ml.outWriter = out
 }
@@ -37,46 +42,50 @@ func (ml *FmtMachineLogger) SetErrWriter(err io.Writer) {
 
 func (ml *FmtMachineLogger) Debug(args ...interface{}) {
 	ml.history.Record(args...)
-	if ml.debug {
-		fmt.Fprintln(ml.outWriter, args...)
+	if ml.debug && !ml.quiet {
+		fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintln(args...))))
 	}
 }
 
 func (ml *FmtMachineLogger) Debugf(fmtString string, args ...interface{}) {
 	ml.history.Recordf(fmtString, args...)
-	if ml.debug {
-		fmt.Fprintf(ml.outWriter, fmtString+"\n", args...)
+	if ml.debug && !ml.quiet {
+		fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintf(fmtString+"\n", args...))))
 	}
 }
 
 func (ml *FmtMachineLogger) Error(args ...interface{}) {
 	ml.history.Record(args...)
-	fmt.Fprintln(ml.errWriter, args...)
+	fmt.Fprint(ml.errWriter, prefixLines(redactSecrets(fmt.Sprintln(args...))))
 }
 
 func (ml *FmtMachineLogger) Errorf(fmtString string, args ...interface{}) {
 	ml.history.Recordf(fmtString, args...)
-	fmt.Fprintf(ml.errWriter, fmtString+"\n", args...)
+	fmt.Fprint(ml.errWriter, prefixLines(redactSecrets(fmt.Sprintf(fmtString+"\n", args...))))
 }
 
 func (ml *FmtMachineLogger) Info(args ...interface{}) {
 	ml.history.Record(args...)
-	fmt.Fprintln(ml.outWriter, args...)
+	if !ml.quiet {
+		fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintln(args...))))
+	}
 }
 
 func (ml *FmtMachineLogger) Infof(fmtString string, args ...interface{}) {
 	ml.history.Recordf(fmtString, args...)
-	fmt.Fprintf(ml.outWriter, fmtString+"\n", args...)
+	if !ml.quiet {
+		fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintf(fmtString+"\n", args...))))
+	}
 }
 
 func (ml *FmtMachineLogger) Warn(args ...interface{}) {
 	ml.history.Record(args...)
-	fmt.Fprintln(ml.outWriter, args...)
+	fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintln(args...))))
 }
 
 func (ml *FmtMachineLogger) Warnf(fmtString string, args ...interface{}) {
 	ml.history.Recordf(fmtString, args...)
-	fmt.Fprintf(ml.outWriter, fmtString+"\n", args...)
+	fmt.Fprint(ml.outWriter, prefixLines(redactSecrets(fmt.Sprintf(fmtString+"\n", args...))))
 }
 
 func (ml *FmtMachineLogger) History() []string {
@@ -76,6 +76,20 @@ func TestWarn(t *testing.T) {
 	assert.Equal(t, result, "warn")
 }
 
+func TestQuiet(t *testing.T) {
+	testLogger := NewFmtMachineLogger()
+	testLogger.SetQuiet(true)
+
+	result := captureOutput(testLogger, func() {
+		testLogger.Info("info")
+		testLogger.Debug("debug")
+		testLogger.Warn("warn")
+	})
+
+	assert.Equal(t, result, "warn")
+	assert.Equal(t, 3, len(testLogger.History()))
+}
+
 func TestError(t *testing.T) {
 	testLogger := NewFmtMachineLogger()
 
@@ -89,3 +89,15 @@ CAlTWS3abehlCERn6Ze9IfZBtpI=
 		assert.Equal(t, tc.expected, stripSecrets(tc.input))
 	}
 }
+
+func TestRegisterSecret(t *testing.T) {
+	RegisterSecret("")
+	RegisterSecret("hunter2")
+
+	assert.Equal(t, []string{"password <REDACTED> for user"}, stripSecrets([]string{"password hunter2 for user"}))
+	assert.Equal(t, "nothing to hide", redactSecrets("nothing to hide"))
+
+	testLogger := NewFmtMachineLogger()
+	result := captureOutput(testLogger, func() { testLogger.Infof("login with %s", "hunter2") })
+	assert.Equal(t, "login with <REDACTED>", result)
+}
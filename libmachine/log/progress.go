@@ -0,0 +1,195 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// linePrefixes are the machine names prefixing the log lines written by the
+// goroutines running an operation, keyed by goroutine id.
+var linePrefixes = struct {
+	sync.Mutex
+	byGoroutine map[uint64]string
+}{byGoroutine: map[uint64]string{}}
+
+// Progress renders the status of an operation run on several machines at
+// once. On a terminal the statuses are a table kept below the log output and
+// updated in place. Otherwise every status change is printed as a line
+// prefixed with the machine name.
+type Progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	names    []string
+	width    int
+	statuses map[string]string
+	drawn    int
+	partial  string
+}
+
+// NewProgress creates a Progress for the given machines, rendered to out.
+func NewProgress(out io.Writer, tty bool, names []string) *Progress {
+	p := &Progress{
+		out:      out,
+		tty:      tty,
+		names:    names,
+		statuses: map[string]string{},
+	}
+
+	for _, name := range names {
+		if len(name) > p.width {
+			p.width = len(name)
+		}
+	}
+
+	return p
+}
+
+// Update sets the status of a machine. Only the first line of status is
+// shown.
+func (p *Progress) Update(name, status string) {
+	status = strings.SplitN(strings.TrimSpace(status), "\n", 2)[0]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statuses[name] == status {
+		return
+	}
+	p.statuses[name] = status
+
+	if !p.tty {
+		fmt.Fprintf(p.out, "(%s) %s\n", name, status)
+		return
+	}
+
+	p.clear()
+	p.draw()
+}
+
+// Run calls fn for the operation on a machine. When the Progress renders
+// several machines, the log lines written by the calling goroutine meanwhile
+// are prefixed with the machine name.
+func (p *Progress) Run(name string, fn func() error) error {
+	if len(p.names) < 2 {
+		return fn()
+	}
+
+	id := goroutineID()
+
+	linePrefixes.Lock()
+	linePrefixes.byGoroutine[id] = name
+	linePrefixes.Unlock()
+
+	defer func() {
+		linePrefixes.Lock()
+		delete(linePrefixes.byGoroutine, id)
+		linePrefixes.Unlock()
+	}()
+
+	return fn()
+}
+
+// Write prints log lines above the status table, so that the output of
+// concurrent operations doesn't tear it. Incomplete lines are held until
+// their end is written.
+func (p *Progress) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lines := strings.Split(p.partial+string(data), "\n")
+	p.partial = lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+
+	if len(lines) == 0 {
+		return len(data), nil
+	}
+
+	p.clear()
+	for _, line := range lines {
+		fmt.Fprintln(p.out, line)
+	}
+	p.draw()
+
+	return len(data), nil
+}
+
+// Close prints what is left of the log output and leaves the last statuses
+// on screen.
+func (p *Progress) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.partial != "" {
+		p.clear()
+		fmt.Fprintln(p.out, p.partial)
+		p.partial = ""
+		p.draw()
+	}
+
+	p.drawn = 0
+	return nil
+}
+
+func (p *Progress) clear() {
+	if p.drawn > 0 {
+		// Move the cursor to the first line of the table and erase
+		// everything below it.
+		fmt.Fprintf(p.out, "\033[%dA\033[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+func (p *Progress) draw() {
+	if !p.tty {
+		return
+	}
+
+	for _, name := range p.names {
+		fmt.Fprintf(p.out, "%-*s  %s\n", p.width, name, p.statuses[name])
+	}
+	p.drawn = len(p.names)
+}
+
+// prefixLines prefixes the lines of a log message with the machine name of
+// the operation run by the calling goroutine, if any.
+func prefixLines(msg string) string {
+	linePrefixes.Lock()
+	name, ok := "", false
+	if len(linePrefixes.byGoroutine) > 0 {
+		name, ok = linePrefixes.byGoroutine[goroutineID()]
+	}
+	linePrefixes.Unlock()
+
+	if !ok {
+		return msg
+	}
+
+	lines := strings.SplitAfter(msg, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "(" + name + ") " + line
+		}
+	}
+
+	return strings.Join(lines, "")
+}
+
+// goroutineID returns the id of the calling goroutine, read from the header
+// of its stack trace, e.g. "goroutine 42 [running]:".
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewProgress(out, false, []string{"web-1", "web-2"})
+
+	p.Update("web-1", "running")
+	p.Update("web-1", "running")
+	fmt.Fprint(p, "(web-1) Creating")
+	fmt.Fprint(p, " instance...\n")
+	p.Update("web-2", "error: quota exceeded\ndetails")
+	assert.NoError(t, p.Close())
+
+	assert.Equal(t, "(web-1) running\n(web-1) Creating instance...\n(web-2) error: quota exceeded\n", out.String())
+}
+
+func TestProgressTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewProgress(out, true, []string{"a", "web-2"})
+
+	p.Update("a", "running")
+	fmt.Fprintln(p, "log line")
+	assert.NoError(t, p.Close())
+
+	assert.Equal(t, "a      running\nweb-2  \n"+
+		"\033[2A\033[J"+"log line\n"+"a      running\nweb-2  \n", out.String())
+}
+
+func TestProgressRunPrefixesLogLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	testLogger := NewFmtMachineLogger()
+	testLogger.SetOutWriter(out)
+
+	p := NewProgress(&bytes.Buffer{}, false, []string{"web-1", "web-2"})
+	assert.NoError(t, p.Run("web-1", func() error {
+		testLogger.Info("Creating instance...\ndone")
+		return nil
+	}))
+	testLogger.Info("after")
+
+	single := NewProgress(&bytes.Buffer{}, false, []string{"web-1"})
+	assert.NoError(t, single.Run("web-1", func() error {
+		testLogger.Info("alone")
+		return nil
+	}))
+
+	assert.Equal(t, "(web-1) Creating instance...\n(web-1) done\nafter\nalone\n", out.String())
+}
@@ -2,6 +2,8 @@ package mcndockerclient
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -11,10 +13,57 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/rancher/machine/libmachine/cert"
+	"github.com/rancher/machine/libmachine/log"
 )
 
+// ClientOptions tunes the connections of the docker clients.
+type ClientOptions struct {
+	// DialTimeout is the timeout of the connection to the daemon.
+	DialTimeout time.Duration
+	// RequestTimeout is the timeout of a request, reading the response
+	// included.
+	RequestTimeout time.Duration
+	// Retries is the number of retries of a request which failed to
+	// connect, negative to disable them.
+	Retries int
+	// RetryDelay is the delay before the first retry, doubled for each
+	// next one.
+	RetryDelay time.Duration
+}
+
+// DefaultClientOptions are the options used for the zero fields of the
+// options of a client.
+var DefaultClientOptions = ClientOptions{
+	DialTimeout:    30 * time.Second,
+	RequestTimeout: 30 * time.Second,
+	Retries:        2,
+	RetryDelay:     time.Second,
+}
+
+func (o ClientOptions) withDefaults() ClientOptions {
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = DefaultClientOptions.DialTimeout
+	}
+	if o.RequestTimeout <= 0 {
+		o.RequestTimeout = DefaultClientOptions.RequestTimeout
+	}
+	if o.Retries == 0 {
+		o.Retries = DefaultClientOptions.Retries
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = DefaultClientOptions.RetryDelay
+	}
+	return o
+}
+
 // DockerClient creates a docker client for a given host.
 func DockerClient(dockerHost DockerHost) (*client.Client, error) {
+	return NewDockerClient(dockerHost, DefaultClientOptions)
+}
+
+// NewDockerClient creates a docker client for a given host with the given
+// timeouts and retries.
+func NewDockerClient(dockerHost DockerHost, options ClientOptions) (*client.Client, error) {
 	url, err := dockerHost.URL()
 	if err != nil {
 		return nil, err
@@ -25,24 +74,80 @@ func DockerClient(dockerHost DockerHost) (*client.Client, error) {
 		return nil, fmt.Errorf("unable to read TLS config: %s", err)
 	}
 
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-		},
-	}
-
 	return client.NewClientWithOpts(
 		client.WithHost(url),
-		client.WithHTTPClient(httpClient),
+		client.WithHTTPClient(newHTTPClient(tlsConfig, options)),
 		client.WithAPIVersionNegotiation(),
 	)
 }
 
+func newHTTPClient(tlsConfig *tls.Config, options ClientOptions) *http.Client {
+	options = options.withDefaults()
+
+	return &http.Client{
+		Timeout: options.RequestTimeout,
+		Transport: &retryTransport{
+			RoundTripper: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				DialContext: (&net.Dialer{
+					Timeout:   options.DialTimeout,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+			},
+			retries: options.Retries,
+			delay:   options.RetryDelay,
+		},
+	}
+}
+
+// retryTransport retries the requests which failed to connect to the daemon,
+// with an exponential backoff. The requests which reached the daemon are not
+// retried, as they may not be idempotent.
+type retryTransport struct {
+	http.RoundTripper
+	retries int
+	delay   time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.delay
+	for attempt := 0; ; attempt++ {
+		resp, err := t.RoundTripper.RoundTrip(req)
+		if err == nil || attempt >= t.retries || !isDialError(err) {
+			return resp, err
+		}
+
+		// The transport closes the body of the failed request.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		log.Debugf("Error connecting to the daemon at %s, retrying in %s: %s", req.URL.Host, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, err
+		}
+		delay *= 2
+	}
+}
+
+// isDialError returns whether an error happened while connecting, before the
+// request was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
 // CreateContainer creates a docker container.
 func CreateContainer(dockerHost DockerHost, config *container.Config, hostConfig *container.HostConfig, name string) error {
 	cli, err := DockerClient(dockerHost)
@@ -67,3 +172,19 @@ func CreateContainer(dockerHost DockerHost, config *container.Config, hostConfig
 
 	return nil
 }
+
+// DaemonInfo queries the system info of the daemon of a host, it fails until
+// the daemon is ready to serve requests.
+func DaemonInfo(dockerHost DockerHost, options ClientOptions) error {
+	cli, err := NewDockerClient(dockerHost, options)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	if _, err := cli.Info(context.Background()); err != nil {
+		return fmt.Errorf("unable to query docker info: %s", err)
+	}
+
+	return nil
+}
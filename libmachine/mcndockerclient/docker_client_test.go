@@ -0,0 +1,64 @@
+package mcndockerclient
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// closedPort returns the address of a port nothing listens on.
+func closedPort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	return addr
+}
+
+type countingTransport struct {
+	http.RoundTripper
+	attempts int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.attempts++
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func TestClientRetriesConnectionErrors(t *testing.T) {
+	client := newHTTPClient(nil, ClientOptions{Retries: 2, RetryDelay: 10 * time.Millisecond})
+	retry := client.Transport.(*retryTransport)
+	counting := &countingTransport{RoundTripper: retry.RoundTripper}
+	retry.RoundTripper = counting
+
+	_, err := client.Get("http://" + closedPort(t) + "/info")
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, counting.attempts)
+}
+
+func TestClientFailsWithinTimeout(t *testing.T) {
+	// The listener accepts the connections but never answers.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	client := newHTTPClient(nil, ClientOptions{RequestTimeout: 200 * time.Millisecond, Retries: -1})
+
+	start := time.Now()
+	_, err = client.Get("http://" + listener.Addr().String() + "/info")
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestIsDialError(t *testing.T) {
+	_, err := net.DialTimeout("tcp", closedPort(t), time.Second)
+
+	assert.True(t, isDialError(err))
+	assert.False(t, isDialError(&net.OpError{Op: "read", Err: err}))
+}
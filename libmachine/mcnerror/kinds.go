@@ -0,0 +1,160 @@
+package mcnerror
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInstanceNotFound is the kind of the errors of a provider reporting
+	// that the instance of a machine doesn't exist.
+	ErrInstanceNotFound = errors.New("instance not found")
+
+	// ErrTimeout is the kind of the errors of operations that didn't
+	// complete in time.
+	ErrTimeout = errors.New("timeout")
+
+	// ErrTransient is the kind of the errors of operations that may succeed
+	// if retried, e.g. rate limiting or an unavailable provider API.
+	ErrTransient = errors.New("transient error")
+
+	// ErrCapacity is the kind of the errors of a provider lacking the
+	// capacity to run an instance, e.g. of its type in its zone. Like the
+	// transient errors, retrying later may succeed.
+	ErrCapacity = errors.New("insufficient capacity")
+
+	// ErrNotSupported is the kind of the errors of optional operations a
+	// driver doesn't implement.
+	ErrNotSupported = errors.New("not supported")
+
+	// ErrUnauthorized is the kind of the errors of a provider rejecting the
+	// credentials, or not allowing them to perform an operation.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	kinds = []error{ErrInstanceNotFound, ErrTimeout, ErrTransient, ErrCapacity, ErrNotSupported, ErrUnauthorized}
+)
+
+// Transient is implemented by errors telling whether retrying the operation
+// may succeed.
+type Transient interface {
+	Transient() bool
+}
+
+// kindError is a provider error classified with one of the kinds above. Its
+// message is the one of its cause.
+type kindError struct {
+	kind  error
+	cause error
+}
+
+func (e *kindError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.cause}
+}
+
+func (e *kindError) Transient() bool {
+	return e.kind == ErrTransient || e.kind == ErrTimeout || e.kind == ErrCapacity
+}
+
+func wrapKind(kind, cause error) error {
+	if cause == nil {
+		return nil
+	}
+
+	if errors.Is(cause, kind) {
+		return cause
+	}
+
+	return &kindError{kind: kind, cause: cause}
+}
+
+// NotFound classifies err as an ErrInstanceNotFound.
+func NotFound(err error) error {
+	return wrapKind(ErrInstanceNotFound, err)
+}
+
+// Timeout classifies err as an ErrTimeout.
+func Timeout(err error) error {
+	return wrapKind(ErrTimeout, err)
+}
+
+// NotSupported classifies err as an ErrNotSupported.
+func NotSupported(err error) error {
+	return wrapKind(ErrNotSupported, err)
+}
+
+// Unauthorized classifies err as an ErrUnauthorized.
+func Unauthorized(err error) error {
+	return wrapKind(ErrUnauthorized, err)
+}
+
+// MarkTransient classifies err as an ErrTransient.
+func MarkTransient(err error) error {
+	return wrapKind(ErrTransient, err)
+}
+
+// Capacity classifies err as an ErrCapacity.
+func Capacity(err error) error {
+	return wrapKind(ErrCapacity, err)
+}
+
+// IsTransient returns true if retrying the operation that returned err may
+// succeed.
+func IsTransient(err error) bool {
+	var t Transient
+	return errors.As(err, &t) && t.Transient()
+}
+
+// kindMessage is the form of a classified error over the RPC transport
+// between machine and the driver plugins, which only keeps error messages.
+type kindMessage struct {
+	Kind    string
+	Message string
+}
+
+// ToMessage returns an error whose message carries the kind of err next to
+// its message, for FromMessage to restore it on the other side of the RPC
+// transport. Errors without a kind are returned as is.
+func ToMessage(err error) error {
+	var ke *kindError
+	if !errors.As(err, &ke) {
+		return err
+	}
+
+	data, jsonErr := json.Marshal(kindMessage{Kind: ke.kind.Error(), Message: err.Error()})
+	if jsonErr != nil {
+		return err
+	}
+
+	return errors.New(string(data))
+}
+
+// FromMessage restores the kind of an error that went through the RPC
+// transport, see ToMessage.
+func FromMessage(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "{") {
+		return err
+	}
+
+	var km kindMessage
+	if json.Unmarshal([]byte(msg), &km) != nil {
+		return err
+	}
+
+	for _, kind := range kinds {
+		if km.Kind == kind.Error() {
+			return &kindError{kind: kind, cause: errors.New(km.Message)}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,59 @@
+package mcnerror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinds(t *testing.T) {
+	cause := errors.New("404 from the provider")
+
+	err := NotFound(cause)
+	assert.True(t, errors.Is(err, ErrInstanceNotFound))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, IsTransient(err))
+	assert.EqualError(t, err, "404 from the provider")
+
+	assert.True(t, IsTransient(MarkTransient(cause)))
+	assert.True(t, IsTransient(fmt.Errorf("wrapped: %w", Timeout(cause))))
+	assert.True(t, IsTransient(Capacity(cause)))
+	assert.False(t, IsTransient(cause))
+
+	assert.Nil(t, NotFound(nil))
+	assert.Equal(t, err, NotFound(err))
+}
+
+func TestFromMessage(t *testing.T) {
+	err := FromMessage(errors.New(ToMessage(MarkTransient(errors.New("rate limited"))).Error()))
+	assert.True(t, errors.Is(err, ErrTransient))
+	assert.True(t, IsTransient(err))
+	assert.EqualError(t, err, "rate limited")
+
+	err = FromMessage(errors.New(ToMessage(NotSupported(errors.New("no credentials check"))).Error()))
+	assert.True(t, errors.Is(err, ErrNotSupported))
+	assert.False(t, IsTransient(err))
+
+	err = FromMessage(errors.New(ToMessage(Unauthorized(errors.New("invalid token"))).Error()))
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, IsTransient(err))
+
+	err = FromMessage(errors.New(ToMessage(Capacity(errors.New("no m5.large in us-east-1a"))).Error()))
+	assert.True(t, errors.Is(err, ErrCapacity))
+	assert.True(t, IsTransient(err))
+
+	wrapped := fmt.Errorf("Error creating machine: %w", Timeout(errors.New("no SSH")))
+	err = FromMessage(errors.New(ToMessage(wrapped).Error()))
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.EqualError(t, err, "Error creating machine: no SSH")
+
+	plain := errors.New("boom")
+	assert.Equal(t, plain, ToMessage(plain))
+	assert.Equal(t, plain, FromMessage(plain))
+
+	notKind := errors.New(`{"Kind": "unknown", "Message": "boom"}`)
+	assert.Equal(t, notKind, FromMessage(notKind))
+	assert.Nil(t, FromMessage(nil))
+}
@@ -403,28 +403,23 @@ func (b *B2dUtils) DownloadISOFromURL(latestReleaseURL string) error {
 }
 
 func (b *B2dUtils) UpdateISOCache(isoURL string) error {
-	// recreate the cache dir if it has been manually deleted
-	if _, err := os.Stat(b.imgCachePath); os.IsNotExist(err) {
-		log.Infof("Image cache directory does not exist, creating it at %s...", b.imgCachePath)
-		if err := os.Mkdir(b.imgCachePath, 0700); err != nil {
-			return err
-		}
+	if isoURL != "" {
+		// Warn that the b2d iso won't be updated to the latest release if isoURL is set
+		log.Warnf("Boot2Docker URL was explicitly set to %q at create time, so Docker Machine cannot upgrade this machine to the latest version.", isoURL)
+		_, err := b.cacheISOFromURL(isoURL)
+		return err
 	}
 
-	exists := b.exists()
-
-	if isoURL != "" {
-		if exists {
-			// Warn that the b2d iso won't be updated if isoURL is set
-			log.Warnf("Boot2Docker URL was explicitly set to %q at create time, so Docker Machine cannot upgrade this machine to the latest version.", isoURL)
-		}
-		// Non-default B2D are not cached
-		return nil
+	if err := b.ensureCacheDir(); err != nil {
+		return err
 	}
 
-	if !exists {
+	if !b.exists() {
 		log.Info("No default Boot2Docker ISO found locally, downloading the latest release...")
-		return b.DownloadLatestBoot2Docker("")
+		if err := b.DownloadLatestBoot2Docker(""); err != nil {
+			return fmt.Errorf("unable to download the Boot2Docker ISO and no cached copy exists in %s: %s", b.imgCachePath, err)
+		}
+		return nil
 	}
 
 	latest := b.isLatest()
@@ -437,27 +432,28 @@ func (b *B2dUtils) UpdateISOCache(isoURL string) error {
 }
 
 func (b *B2dUtils) CopyIsoToMachineDir(isoURL, machineName string) error {
-	if err := b.UpdateISOCache(isoURL); err != nil {
-		return err
-	}
-
 	// TODO: This is a bit off-color.
 	machineDir := filepath.Join(b.storePath, "machines", machineName)
 	machineIsoPath := filepath.Join(machineDir, b.filename())
 
 	// By default just copy the existing "cached" iso to the machine's directory...
 	if isoURL == "" {
+		if err := b.UpdateISOCache(isoURL); err != nil {
+			return err
+		}
+
 		log.Infof("Copying %s to %s...", b.path(), machineIsoPath)
 		return CopyFile(b.path(), machineIsoPath)
 	}
 
-	// if ISO is specified, check if it matches a github releases url or fallback to a direct download
-	downloadURL, err := b.getReleaseURL(isoURL)
+	// if ISO is specified, it is cached from a github releases url or a direct download
+	cachedPath, err := b.cacheISOFromURL(isoURL)
 	if err != nil {
 		return err
 	}
 
-	return b.DownloadISO(machineDir, b.filename(), downloadURL)
+	log.Infof("Copying %s to %s...", cachedPath, machineIsoPath)
+	return CopyFile(cachedPath, machineIsoPath)
 }
 
 // isLatest checks the latest release tag and
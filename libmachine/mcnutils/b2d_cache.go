@@ -0,0 +1,179 @@
+package mcnutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// checksumSuffix is appended to the URL of an ISO to get its published
+// SHA-256 checksum, in the format of sha256sum.
+const checksumSuffix = ".sha256"
+
+// NewB2dUtilsWithCacheDir returns a B2dUtils caching the ISOs in cacheDir
+// instead of the cache directory of the store, when cacheDir is set.
+func NewB2dUtilsWithCacheDir(storePath, cacheDir string) *B2dUtils {
+	b := NewB2dUtils(storePath)
+	if cacheDir == "" {
+		return b
+	}
+
+	b.imgCachePath = cacheDir
+	b.iso = &b2dISO{
+		commonIsoPath:  filepath.Join(cacheDir, defaultISOFilename),
+		volumeIDOffset: defaultVolumeIDOffset,
+		volumeIDLength: defaultVolumeIDLength,
+	}
+
+	return b
+}
+
+func (b *B2dUtils) ensureCacheDir() error {
+	// recreate the cache dir if it has been manually deleted
+	if _, err := os.Stat(b.imgCachePath); os.IsNotExist(err) {
+		log.Infof("Image cache directory does not exist, creating it at %s...", b.imgCachePath)
+		if err := os.MkdirAll(b.imgCachePath, 0700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// urlCacheFilename returns the name of the cached copy of the ISO at isoURL.
+func urlCacheFilename(isoURL string) string {
+	sum := sha256.Sum256([]byte(isoURL))
+	return fmt.Sprintf("boot2docker-%s.iso", hex.EncodeToString(sum[:8]))
+}
+
+// cacheISOFromURL caches the ISO at isoURL and returns the path of the cached
+// copy. The ISO is downloaded again only when its download URL or its
+// published checksum changed, and the cached copy is used as is when isoURL
+// can't be reached.
+func (b *B2dUtils) cacheISOFromURL(isoURL string) (string, error) {
+	if err := b.ensureCacheDir(); err != nil {
+		return "", err
+	}
+
+	file := urlCacheFilename(isoURL)
+	cachedPath := filepath.Join(b.imgCachePath, file)
+	sourcePath := cachedPath + ".source"
+
+	_, statErr := os.Stat(cachedPath)
+	cached := statErr == nil
+
+	offline := func(err error) (string, error) {
+		if cached {
+			log.Warnf("Unable to reach %s, using the cached Boot2Docker ISO %s: %s", isoURL, cachedPath, err)
+			return cachedPath, nil
+		}
+		return "", fmt.Errorf("unable to get the Boot2Docker ISO from %s and no cached copy exists in %s: %s", isoURL, b.imgCachePath, err)
+	}
+
+	downloadURL, err := b.getReleaseURL(isoURL)
+	if err != nil {
+		return offline(err)
+	}
+
+	checksum, err := publishedChecksum(downloadURL)
+	if err != nil {
+		return offline(err)
+	}
+
+	if cached {
+		source, _ := os.ReadFile(sourcePath)
+		if string(source) == downloadURL {
+			if checksum == "" {
+				log.Infof("Using the cached Boot2Docker ISO of %s, no checksum is published to detect changes", downloadURL)
+				return cachedPath, nil
+			}
+
+			if current, err := fileChecksum(cachedPath); err == nil && current == checksum {
+				log.Infof("Using the cached Boot2Docker ISO of %s", downloadURL)
+				return cachedPath, nil
+			}
+		}
+	}
+
+	if err := b.DownloadISO(b.imgCachePath, file, downloadURL); err != nil {
+		return offline(err)
+	}
+
+	if checksum != "" {
+		current, err := fileChecksum(cachedPath)
+		if err != nil {
+			return "", err
+		}
+		if current != checksum {
+			if err := removeFileIfExists(cachedPath); err != nil {
+				log.Warnf("Error removing file: %s", err)
+			}
+			return "", fmt.Errorf("checksum mismatch for the Boot2Docker ISO downloaded from %s: expected %s, got %s", downloadURL, checksum, current)
+		}
+	}
+
+	if err := os.WriteFile(sourcePath, []byte(downloadURL), 0600); err != nil {
+		return "", err
+	}
+
+	return cachedPath, nil
+}
+
+// publishedChecksum returns the SHA-256 checksum published next to the ISO
+// at isoURL, or an empty string if there is none. The checksum of local
+// files is computed.
+func publishedChecksum(isoURL string) (string, error) {
+	u, err := url.Parse(isoURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme == "file" || u.Scheme == "" {
+		return fileChecksum(u.Path)
+	}
+
+	rsp, err := getClient().Get(isoURL + checksumSuffix)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		log.Debugf("No checksum published for %s: %s", isoURL, rsp.Status)
+		return "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum published at %s%s", isoURL, checksumSuffix)
+	}
+
+	return strings.ToLower(fields[0]), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
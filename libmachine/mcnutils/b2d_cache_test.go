@@ -0,0 +1,114 @@
+package mcnutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newISOServer serves an ISO with the given content and, if it is not
+// empty, its checksum. It counts the downloads of the ISO.
+func newISOServer(content *string, checksum *string, downloads *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/boot2docker.iso":
+			*downloads++
+			w.Write([]byte(*content))
+		case "/boot2docker.iso.sha256":
+			if *checksum == "" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(*checksum + "  boot2docker.iso\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCacheISOFromURL(t *testing.T) {
+	content, checksum, downloads := "iso-v1", sha256Hex("iso-v1"), 0
+	ts := newISOServer(&content, &checksum, &downloads)
+	defer ts.Close()
+
+	cacheDir, err := os.MkdirTemp("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	b := NewB2dUtilsWithCacheDir("/tmp/artifacts", cacheDir)
+	isoURL := ts.URL + "/boot2docker.iso"
+
+	path, err := b.cacheISOFromURL(isoURL)
+	assert.NoError(t, err)
+	assert.Equal(t, cacheDir, filepath.Dir(path))
+	assert.Equal(t, 1, downloads)
+
+	// Unchanged checksum, the cached copy is used.
+	_, err = b.cacheISOFromURL(isoURL)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, downloads)
+
+	// Changed checksum, the ISO is downloaded again.
+	content, checksum = "iso-v2", sha256Hex("iso-v2")
+	_, err = b.cacheISOFromURL(isoURL)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, downloads)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "iso-v2", string(data))
+}
+
+func TestCacheISOFromURLChecksumMismatch(t *testing.T) {
+	content, checksum, downloads := "tampered", sha256Hex("iso-v1"), 0
+	ts := newISOServer(&content, &checksum, &downloads)
+	defer ts.Close()
+
+	cacheDir, err := os.MkdirTemp("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	b := NewB2dUtilsWithCacheDir("/tmp/artifacts", cacheDir)
+	isoURL := ts.URL + "/boot2docker.iso"
+
+	_, err = b.cacheISOFromURL(isoURL)
+	assert.Error(t, err)
+
+	_, statErr := os.Stat(filepath.Join(cacheDir, urlCacheFilename(isoURL)))
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestCacheISOFromURLOffline(t *testing.T) {
+	content, checksum, downloads := "iso-v1", "", 0
+	ts := newISOServer(&content, &checksum, &downloads)
+
+	cacheDir, err := os.MkdirTemp("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	b := NewB2dUtilsWithCacheDir("/tmp/artifacts", cacheDir)
+	isoURL := ts.URL + "/boot2docker.iso"
+
+	cachedPath, err := b.cacheISOFromURL(isoURL)
+	assert.NoError(t, err)
+
+	ts.Close()
+
+	path, err := b.cacheISOFromURL(isoURL)
+	assert.NoError(t, err)
+	assert.Equal(t, cachedPath, path)
+
+	_, err = b.cacheISOFromURL(ts.URL + "/other.iso")
+	assert.Error(t, err)
+}
@@ -9,6 +9,9 @@ import (
 	"runtime"
 	"strconv"
 	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnerror"
 )
 
 type MultiError struct {
@@ -82,14 +85,16 @@ func WaitForSpecificOrError(f func() (bool, error), maxAttempts int, waitInterva
 	for i := 0; i < maxAttempts; i++ {
 		stop, err := f()
 		if err != nil {
-			return err
-		}
-		if stop {
+			if !mcnerror.IsTransient(err) {
+				return err
+			}
+			log.Debugf("Retrying after transient error: %s", err)
+		} else if stop {
 			return nil
 		}
 		time.Sleep(waitInterval)
 	}
-	return fmt.Errorf("Maximum number of retries (%d) exceeded", maxAttempts)
+	return mcnerror.Timeout(fmt.Errorf("Maximum number of retries (%d) exceeded", maxAttempts))
 }
 
 func WaitForSpecific(f func() bool, maxAttempts int, waitInterval time.Duration) error {
@@ -0,0 +1,272 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	// LockStaleAfter is the age after which a lock taken from another host
+	// sharing the store, whose process can't be checked, is considered
+	// abandoned.
+	LockStaleAfter = time.Hour
+
+	lockRetryInterval = 500 * time.Millisecond
+)
+
+// ErrLockTimeout is returned when a machine stays locked by another process
+// for longer than the lock timeout.
+type ErrLockTimeout struct {
+	Name  string
+	Owner LockOwner
+}
+
+func (e ErrLockTimeout) Error() string {
+	return fmt.Sprintf("machine %q is locked by process %d on %s since %s", e.Name, e.Owner.PID, e.Owner.Hostname, e.Owner.Created.Format(time.RFC3339))
+}
+
+// LockOwner is written in the lock file to identify the process holding it.
+type LockOwner struct {
+	PID      int
+	Hostname string
+	Created  time.Time
+	// Operation is the command holding the lock, e.g. create.
+	Operation string `json:",omitempty"`
+}
+
+// LockedMachine is a machine whose lock is held by a live process.
+type LockedMachine struct {
+	Name  string
+	Owner LockOwner
+}
+
+// Lock is an exclusive lock on a machine of a file store.
+type Lock struct {
+	path       string
+	cancelPath string
+}
+
+// LockPath returns the path of the lock file of a machine. It lives next to
+// the machine directory so that it can be taken before the machine exists
+// and survives its removal.
+func LockPath(machinesDir, name string) string {
+	return filepath.Join(machinesDir, "."+name+".lock")
+}
+
+// CancelPath returns the path of the file requesting the process holding the
+// lock of a machine to cancel its operation.
+func CancelPath(machinesDir, name string) string {
+	return filepath.Join(machinesDir, "."+name+".cancel")
+}
+
+// AcquireLock takes the lock of a machine, waiting up to timeout for another
+// process to release it. Locks whose process is gone, or taken from another
+// host more than LockStaleAfter ago, are broken.
+func AcquireLock(machinesDir, name string, timeout time.Duration) (*Lock, error) {
+	return AcquireOperationLock(machinesDir, name, "", timeout)
+}
+
+// AcquireOperationLock works like AcquireLock, recording the operation the
+// lock is taken for so that it is listed by ListLocks.
+func AcquireOperationLock(machinesDir, name, operation string, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(machinesDir, 0700); err != nil {
+		return nil, err
+	}
+
+	path := LockPath(machinesDir, name)
+	hostname, _ := os.Hostname()
+	deadline := time.Now().Add(timeout)
+
+	for {
+		owner := LockOwner{
+			PID:       os.Getpid(),
+			Hostname:  hostname,
+			Created:   time.Now(),
+			Operation: operation,
+		}
+
+		err := writeLock(path, owner)
+		if err == nil {
+			// A cancellation left by a previous operation doesn't apply.
+			cancelPath := CancelPath(machinesDir, name)
+			if err := os.Remove(cancelPath); err != nil && !os.IsNotExist(err) {
+				log.Debugf("Error removing %s: %s", cancelPath, err)
+			}
+			return &Lock{path: path, cancelPath: cancelPath}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		current, err := readLock(path)
+		if err != nil {
+			// The owner may not have written its details yet.
+			log.Debugf("Error reading lock %s: %s", path, err)
+		} else if current.isStale(hostname) {
+			broken, err := breakStaleLock(path, hostname)
+			if err != nil {
+				return nil, err
+			}
+			if broken {
+				log.Warnf("Breaking the stale lock of %s taken by process %d on %s", name, current.PID, current.Hostname)
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout{
+				Name:  name,
+				Owner: current,
+			}
+		}
+
+		log.Debugf("Waiting for the lock of %s", name)
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Remove(l.cancelPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Cancelled returns true once the cancellation of the operation holding the
+// lock was requested with RequestCancel.
+func (l *Lock) Cancelled() bool {
+	_, err := os.Stat(l.cancelPath)
+	return err == nil
+}
+
+// ListLocks returns the machines of a store locked by live processes, sorted
+// by name. The stale locks are skipped.
+func ListLocks(machinesDir string) ([]LockedMachine, error) {
+	paths, err := filepath.Glob(filepath.Join(machinesDir, ".*.lock"))
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	locked := []LockedMachine{}
+	for _, path := range paths {
+		owner, err := readLock(path)
+		if err != nil {
+			log.Debugf("Error reading lock %s: %s", path, err)
+			continue
+		}
+		if owner.isStale(hostname) {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "."), ".lock")
+		locked = append(locked, LockedMachine{Name: name, Owner: owner})
+	}
+
+	return locked, nil
+}
+
+// RequestCancel asks the process holding the lock of a machine to cancel its
+// operation. The process may run on another host sharing the store, it checks
+// for the request while it holds the lock.
+func RequestCancel(machinesDir, name string) (LockOwner, error) {
+	hostname, _ := os.Hostname()
+	owner, err := readLock(LockPath(machinesDir, name))
+	if err != nil || owner.isStale(hostname) {
+		return owner, fmt.Errorf("no operation is in progress on %q", name)
+	}
+
+	if err := os.WriteFile(CancelPath(machinesDir, name), []byte{}, 0600); err != nil {
+		return owner, err
+	}
+
+	return owner, nil
+}
+
+// breakStaleLock removes the lock at path if it is still stale. The check and
+// the removal are done holding a second lock, so that a process which found
+// the same stale lock can't remove the one taken after it was broken.
+func breakStaleLock(path, hostname string) (bool, error) {
+	breakPath := path + ".break"
+	if err := writeLock(breakPath, LockOwner{PID: os.Getpid(), Hostname: hostname, Created: time.Now()}); err != nil {
+		if !os.IsExist(err) {
+			return false, err
+		}
+		// Another process is breaking the lock, unless it died doing so.
+		if owner, err := readLock(breakPath); err == nil && owner.isStale(hostname) {
+			os.Remove(breakPath)
+		}
+		return false, nil
+	}
+	defer os.Remove(breakPath)
+
+	current, err := readLock(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil || !current.isStale(hostname) {
+		return false, nil
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// isStale returns true if the process holding the lock is gone. The locks of
+// the other hosts are only known to be abandoned by their age, the long
+// operations of this host keep their lock for as long as they run.
+func (o LockOwner) isStale(hostname string) bool {
+	if o.Hostname == hostname {
+		return !processExists(o.PID)
+	}
+
+	return time.Since(o.Created) > LockStaleAfter
+}
+
+func writeLock(path string, owner LockOwner) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(file).Encode(owner); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return file.Close()
+}
+
+func readLock(path string) (LockOwner, error) {
+	var owner LockOwner
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+
+	if len(data) == 0 {
+		return owner, errors.New("empty lock file")
+	}
+
+	err = json.Unmarshal(data, &owner)
+	return owner, err
+}
@@ -0,0 +1,137 @@
+package persist
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := AcquireLock(dir, "foo", time.Second)
+	assert.NoError(t, err)
+
+	_, err = AcquireLock(dir, "foo", 0)
+	assert.IsType(t, ErrLockTimeout{}, err)
+
+	other, err := AcquireLock(dir, "bar", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, other.Release())
+
+	assert.NoError(t, lock.Release())
+
+	lock, err = AcquireLock(dir, "foo", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestAcquireLockBreaksStaleLocks(t *testing.T) {
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	assert.NoError(t, writeLock(LockPath(dir, "old"), LockOwner{
+		PID:      os.Getpid(),
+		Hostname: "elsewhere",
+		Created:  time.Now().Add(-2 * LockStaleAfter),
+	}))
+
+	lock, err := AcquireLock(dir, "old", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+
+	live := LockOwner{PID: os.Getpid(), Hostname: hostname, Created: time.Now()}
+	assert.False(t, live.isStale(hostname))
+	assert.False(t, live.isStale("elsewhere"))
+
+	// A long operation of this host keeps its lock.
+	long := LockOwner{PID: os.Getpid(), Hostname: hostname, Created: time.Now().Add(-2 * LockStaleAfter)}
+	assert.False(t, long.isStale(hostname))
+	assert.True(t, long.isStale("elsewhere"))
+}
+
+func TestBreakStaleLockKeepsRetakenLock(t *testing.T) {
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+	path := LockPath(dir, "foo")
+
+	// The stale lock was broken and taken again by another process since it
+	// was read.
+	assert.NoError(t, writeLock(path, LockOwner{PID: os.Getpid(), Hostname: hostname, Created: time.Now()}))
+
+	broken, err := breakStaleLock(path, hostname)
+	assert.NoError(t, err)
+	assert.False(t, broken)
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, path+".break")
+}
+
+func TestBreakStaleLockWaitsForOtherBreaker(t *testing.T) {
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+	path := LockPath(dir, "foo")
+
+	assert.NoError(t, writeLock(path, LockOwner{
+		PID:      os.Getpid(),
+		Hostname: "elsewhere",
+		Created:  time.Now().Add(-2 * LockStaleAfter),
+	}))
+	assert.NoError(t, writeLock(path+".break", LockOwner{PID: os.Getpid(), Hostname: hostname, Created: time.Now()}))
+
+	broken, err := breakStaleLock(path, hostname)
+	assert.NoError(t, err)
+	assert.False(t, broken)
+	assert.FileExists(t, path)
+
+	assert.NoError(t, os.Remove(path+".break"))
+
+	broken, err = breakStaleLock(path, hostname)
+	assert.NoError(t, err)
+	assert.True(t, broken)
+	assert.NoFileExists(t, path)
+}
+
+func TestListLocks(t *testing.T) {
+	dir := t.TempDir()
+	hostname, _ := os.Hostname()
+
+	lock, err := AcquireOperationLock(dir, "foo", "create", 0)
+	assert.NoError(t, err)
+	defer lock.Release()
+
+	assert.NoError(t, writeLock(LockPath(dir, "old"), LockOwner{
+		PID:      os.Getpid(),
+		Hostname: "elsewhere",
+		Created:  time.Now().Add(-2 * LockStaleAfter),
+	}))
+
+	locked, err := ListLocks(dir)
+	assert.NoError(t, err)
+	assert.Len(t, locked, 1)
+	assert.Equal(t, "foo", locked[0].Name)
+	assert.Equal(t, "create", locked[0].Owner.Operation)
+	assert.Equal(t, os.Getpid(), locked[0].Owner.PID)
+	assert.Equal(t, hostname, locked[0].Owner.Hostname)
+}
+
+func TestRequestCancel(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := RequestCancel(dir, "foo")
+	assert.EqualError(t, err, `no operation is in progress on "foo"`)
+
+	lock, err := AcquireOperationLock(dir, "foo", "upgrade", 0)
+	assert.NoError(t, err)
+	assert.False(t, lock.Cancelled())
+
+	owner, err := RequestCancel(dir, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "upgrade", owner.Operation)
+	assert.True(t, lock.Cancelled())
+
+	assert.NoError(t, lock.Release())
+	_, err = os.Stat(CancelPath(dir, "foo"))
+	assert.True(t, os.IsNotExist(err))
+}
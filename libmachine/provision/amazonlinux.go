@@ -84,10 +84,15 @@ func (provisioner *AmazonLinuxProvisioner) Provision(swarmOptions swarm.Options,
 	}
 	provisioner.EngineOptions.StorageDriver = storageDriver
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
+	if err := configurePackageMirror(provisioner, engineOptions); err != nil {
+		return err
+	}
+	defer removePackageMirrorAuth(provisioner, engineOptions)
+
 	for _, pkg := range provisioner.Packages {
 		log.Debugf("installing base package: name=%s", pkg)
 		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
@@ -114,12 +119,20 @@ func (provisioner *AmazonLinuxProvisioner) Provision(swarmOptions swarm.Options,
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	if err := ConfigureAuth(provisioner); err != nil {
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	err = configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
 }
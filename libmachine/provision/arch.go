@@ -107,7 +107,7 @@ func (provisioner *ArchProvisioner) Provision(swarmOptions swarm.Options, authOp
 	}
 
 	log.Debug("Setting hostname")
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -133,6 +133,10 @@ func (provisioner *ArchProvisioner) Provision(swarmOptions swarm.Options, authOp
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	log.Debug("Configuring auth")
@@ -140,6 +144,10 @@ func (provisioner *ArchProvisioner) Provision(swarmOptions swarm.Options, authOp
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debug("Configuring swarm")
 	if err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions); err != nil {
 		return err
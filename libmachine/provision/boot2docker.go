@@ -132,6 +132,10 @@ func (provisioner *Boot2DockerProvisioner) GetSwarmOptions() swarm.Options {
 	return provisioner.SwarmOptions
 }
 
+func (provisioner *Boot2DockerProvisioner) GetEngineOptions() engine.Options {
+	return provisioner.EngineOptions
+}
+
 func (provisioner *Boot2DockerProvisioner) GenerateDockerOptions(dockerPort int) (*DockerOptions, error) {
 	var (
 		engineCfg bytes.Buffer
@@ -145,14 +149,18 @@ EXTRA_ARGS='
 {{ range .EngineOptions.Labels }}--label {{.}}
 {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}}
 {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}}
+{{ end }}{{ if .EngineOptions.MetricsAddr }}--metrics-addr {{.EngineOptions.MetricsAddr}}
 {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}}
 {{ end }}
 '
 CACERT={{.AuthOptions.CaCertRemotePath}}
-DOCKER_HOST='-H tcp://0.0.0.0:{{.DockerPort}}'
+{{ if .EngineOptions.SSHTransport }}DOCKER_HOST='-H unix:///var/run/docker.sock'
+DOCKER_STORAGE={{.EngineOptions.StorageDriver}}
+DOCKER_TLS=no
+{{ else }}DOCKER_HOST='-H tcp://0.0.0.0:{{.DockerPort}}'
 DOCKER_STORAGE={{.EngineOptions.StorageDriver}}
 DOCKER_TLS=auto
-SERVERKEY={{.AuthOptions.ServerKeyRemotePath}}
+{{ end }}SERVERKEY={{.AuthOptions.ServerKeyRemotePath}}
 SERVERCERT={{.AuthOptions.ServerCertRemotePath}}
 
 {{range .EngineOptions.Env}}export \"{{ printf "%q" . }}\"
@@ -209,7 +217,7 @@ You may need to add the route manually, or use another related workaround.
 
 This could be due to a VPN, proxy, or host file configuration issue.
 
-You also might want to clear any VirtualBox host only interfaces you are not using.`, engine.DefaultPort)
+You also might want to clear any VirtualBox host only interfaces you are not using.`, dockerPort)
 	} else {
 		conn.Close()
 	}
@@ -222,7 +230,11 @@ func (provisioner *Boot2DockerProvisioner) Provision(swarmOptions swarm.Options,
 
 	defer func() {
 		if err == nil {
-			provisioner.AttemptIPContact(engine.DefaultPort)
+			port := engine.DefaultPort
+			if engineOptions.Port != 0 {
+				port = engineOptions.Port
+			}
+			provisioner.AttemptIPContact(port)
 		}
 	}()
 
@@ -235,13 +247,20 @@ func (provisioner *Boot2DockerProvisioner) Provision(swarmOptions swarm.Options,
 		provisioner.EngineOptions.StorageDriver = DefaultStorageDriver
 	}
 
-	if err = provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err = setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
 	// b2d hosts need to wait for the daemon to be up
-	// before continuing with provisioning
-	if err = WaitForDocker(provisioner, engine.DefaultPort); err != nil {
+	// before continuing with provisioning. It listens on the default port
+	// until ConfigureAuth first restarts it on the engine port.
+	daemonUp := checkDaemonUp(provisioner, engine.DefaultPort)
+	if engineOptions.Port != 0 && engineOptions.Port != engine.DefaultPort {
+		defaultUp, engineUp := daemonUp, checkDaemonUp(provisioner, engineOptions.Port)
+		daemonUp = func() bool { return defaultUp() || engineUp() }
+	}
+	if err = mcnutils.WaitForSpecific(daemonUp, 10, 3*time.Second); err != nil {
+		err = NewErrDaemonAvailable(err)
 		return err
 	}
 
@@ -249,12 +268,26 @@ func (provisioner *Boot2DockerProvisioner) Provision(swarmOptions swarm.Options,
 		return err
 	}
 
+	// Before the daemon is restarted by ConfigureAuth, so that it starts
+	// with the customizations as it does at boot.
+	if err = configureBoot2DockerProfile(provisioner, engineOptions.Boot2DockerProfileFile); err != nil {
+		return err
+	}
+
+	if err = configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	if err = ConfigureAuth(provisioner); err != nil {
 		return err
 	}
 
+	if err = configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	err = configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
 }
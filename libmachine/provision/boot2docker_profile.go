@@ -0,0 +1,78 @@
+package provision
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	// boot2dockerBootlocal is the script of the persistence partition that
+	// boot2docker runs at the end of every boot.
+	boot2dockerBootlocal = "/var/lib/boot2docker/bootlocal.sh"
+
+	boot2dockerProfileMaxSize = 64 * 1024
+)
+
+// ValidateBoot2DockerProfileFile checks that the local file of
+// --boot2docker-profile-file is a shell script that can be installed.
+func ValidateBoot2DockerProfileFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("%s is empty", path)
+	}
+	if info.Size() > boot2dockerProfileMaxSize {
+		return fmt.Errorf("%s is larger than %d bytes", path, boot2dockerProfileMaxSize)
+	}
+
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.IndexByte(script, 0) >= 0 {
+		return fmt.Errorf("%s is not a shell script", path)
+	}
+
+	return nil
+}
+
+// configureBoot2DockerProfile installs the profile script as the bootlocal.sh
+// of the persistence partition, so that the customizations of the machine
+// survive its reboots, and runs it once so that they apply right away. The
+// script is checked with sh -n on the machine first. An existing bootlocal.sh
+// is left untouched when there is no script.
+func configureBoot2DockerProfile(p SSHCommander, scriptFile string) error {
+	if scriptFile == "" {
+		return nil
+	}
+
+	script, err := os.ReadFile(scriptFile)
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %v", scriptFile, err)
+	}
+	quoted := strings.ReplaceAll(string(script), "'", `'\''`)
+
+	if output, err := p.SSHCommand(fmt.Sprintf("printf '%%s' '%s' | sh -n", quoted)); err != nil {
+		return fmt.Errorf("the boot2docker profile script %s is invalid: %s: %s", scriptFile, err, strings.TrimSpace(output))
+	}
+
+	log.Infof("Installing the boot2docker profile script %s...", scriptFile)
+	if output, err := p.SSHCommand(fmt.Sprintf("printf '%%s' '%s' | sudo sh -c 'cat > %[2]s && chmod 755 %[2]s'", quoted, boot2dockerBootlocal)); err != nil {
+		return fmt.Errorf("error installing the boot2docker profile script: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	if output, err := p.SSHCommand(fmt.Sprintf("sudo sh %s", boot2dockerBootlocal)); err != nil {
+		return fmt.Errorf("error running the boot2docker profile script: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package provision
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeProfile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "profile.sh")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestValidateBoot2DockerProfileFile(t *testing.T) {
+	assert.NoError(t, ValidateBoot2DockerProfileFile(writeProfile(t, "modprobe nbd\n")))
+
+	empty := writeProfile(t, "")
+	assert.EqualError(t, ValidateBoot2DockerProfileFile(empty), empty+" is empty")
+
+	binary := writeProfile(t, "\x7fELF\x00\x01")
+	assert.EqualError(t, ValidateBoot2DockerProfileFile(binary), binary+" is not a shell script")
+
+	dir := t.TempDir()
+	assert.EqualError(t, ValidateBoot2DockerProfileFile(dir), dir+" is not a regular file")
+
+	assert.Error(t, ValidateBoot2DockerProfileFile(filepath.Join(dir, "missing.sh")))
+}
+
+func TestConfigureBoot2DockerProfile(t *testing.T) {
+	script := writeProfile(t, "modprobe nbd\necho 'mounted' > /tmp/done\n")
+	quoted := `modprobe nbd
+echo '\''mounted'\'' > /tmp/done
+`
+	commander := &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"printf '%s' '" + quoted + "' | sh -n": "",
+			"printf '%s' '" + quoted + "' | sudo sh -c 'cat > /var/lib/boot2docker/bootlocal.sh && chmod 755 /var/lib/boot2docker/bootlocal.sh'": "",
+			"sudo sh /var/lib/boot2docker/bootlocal.sh": "",
+		},
+	}
+
+	assert.NoError(t, configureBoot2DockerProfile(commander, script))
+}
+
+func TestConfigureBoot2DockerProfileInvalidScript(t *testing.T) {
+	script := writeProfile(t, "if true; then\n")
+
+	err := configureBoot2DockerProfile(&provisiontest.FakeSSHCommander{}, script)
+
+	assert.EqualError(t, err, "the boot2docker profile script "+script+" is invalid: Command not registered in FakeSSHCommander: ")
+}
+
+func TestConfigureBoot2DockerProfileNone(t *testing.T) {
+	assert.NoError(t, configureBoot2DockerProfile(&provisiontest.FakeSSHCommander{}, ""))
+}
@@ -43,11 +43,16 @@ func (provisioner *CentosProvisioner) Provision(swarmOptions swarm.Options, auth
 	}
 	provisioner.EngineOptions.StorageDriver = storageDriver
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
-	if err := installDockerGeneric(provisioner, provisioner.EngineOptions.InstallURL); err != nil {
+	if err := configurePackageMirror(provisioner, engineOptions); err != nil {
+		return err
+	}
+	defer removePackageMirrorAuth(provisioner, engineOptions)
+
+	if err := installDockerGeneric(provisioner, provisioner.EngineOptions); err != nil {
 		return err
 	} else if err == nil {
 		if err := provisioner.Service("docker", serviceaction.Restart); err != nil {
@@ -73,12 +78,20 @@ func (provisioner *CentosProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	if err := ConfigureAuth(provisioner); err != nil {
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	err = configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
 }
@@ -0,0 +1,147 @@
+package provision
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	cgroupDriverSystemd  = "systemd"
+	cgroupDriverCgroupfs = "cgroupfs"
+
+	cgroupDriverExecOpt = "native.cgroupdriver"
+)
+
+// cgroupDriverFlagged is implemented by the provisioners passing the cgroup
+// driver to the daemon in its flags rather than in daemon.json.
+type cgroupDriverFlagged interface {
+	flagsCgroupDriver() bool
+}
+
+func (provisioner *FedoraCoreOSProvisioner) flagsCgroupDriver() bool {
+	return true
+}
+
+func (provisioner *PhotonOSProvisioner) flagsCgroupDriver() bool {
+	return true
+}
+
+// ValidateCgroupDriver checks the value of --engine-cgroup-driver, and that
+// the exec options of the daemon aren't also set in its flags, with
+// --engine-opt or the engine flag file, the daemon refusing them in both
+// daemon.json and its flags.
+func ValidateCgroupDriver(driver string, engineOpts []string) error {
+	if driver == "" {
+		return nil
+	}
+
+	if driver != cgroupDriverSystemd && driver != cgroupDriverCgroupfs {
+		return fmt.Errorf("invalid cgroup driver %q, must be %s or %s", driver, cgroupDriverSystemd, cgroupDriverCgroupfs)
+	}
+
+	for _, opt := range engineOpts {
+		if name, _, _ := strings.Cut(opt, "="); name == "exec-opt" {
+			return fmt.Errorf("the cgroup driver can't be set with both --engine-cgroup-driver and the daemon flag --%s", opt)
+		}
+	}
+
+	return nil
+}
+
+// cgroupV2 returns true if the machine mounts the unified cgroup v2 hierarchy.
+func cgroupV2(p Provisioner) (bool, error) {
+	output, err := p.SSHCommand("stat -fc %T /sys/fs/cgroup/")
+	if err != nil {
+		return false, fmt.Errorf("error detecting the cgroup version: %s", err)
+	}
+
+	return strings.TrimSpace(output) == "cgroup2fs", nil
+}
+
+// cgroupDriver returns the cgroup driver the daemon is configured with: the
+// one of the engine options, or systemd on the cgroup v2 machines managed by
+// systemd, where the cgroupfs driver leaves the resource limits of the
+// containers unapplied. It is empty when the default of the daemon is kept,
+// and for the rootless daemons, which don't read /etc/docker/daemon.json.
+func cgroupDriver(p Provisioner, engineOptions engine.Options) (string, error) {
+	if engineOptions.CgroupDriver != "" {
+		return engineOptions.CgroupDriver, nil
+	}
+
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() || engineOptions.Rootless {
+		return "", nil
+	}
+
+	for _, flag := range engineOptions.ArbitraryFlags {
+		if name, _, _ := strings.Cut(flag, "="); name == "exec-opt" {
+			log.Debugf("Not setting the cgroup driver, the exec options are set in the daemon flags")
+			return "", nil
+		}
+	}
+
+	v2, err := cgroupV2(p)
+	if err != nil || !v2 {
+		return "", err
+	}
+
+	return cgroupDriverSystemd, nil
+}
+
+// configureCgroupDriver sets the cgroup driver of the daemon in daemon.json.
+func configureCgroupDriver(p Provisioner, engineOptions engine.Options) error {
+	driver, err := cgroupDriver(p, engineOptions)
+	if err != nil || driver == "" {
+		return err
+	}
+
+	if sp, ok := p.(systemdManaged); driver == cgroupDriverSystemd && (!ok || !sp.usesSystemd()) {
+		return fmt.Errorf("the systemd cgroup driver is not supported on %s", p.String())
+	}
+
+	if fp, ok := p.(cgroupDriverFlagged); ok && fp.flagsCgroupDriver() {
+		return nil
+	}
+
+	log.Infof("Setting the cgroup driver of the Docker daemon to %s...", driver)
+
+	return updateDaemonConfig(p, daemonCgroupDriver(driver))
+}
+
+// daemonCgroupDriver returns the update of daemon.json setting the cgroup
+// driver in its exec options, over the one it already has.
+func daemonCgroupDriver(driver string) func(config map[string]interface{}) {
+	return func(config map[string]interface{}) {
+		execOpts := []interface{}{}
+		current, _ := config["exec-opts"].([]interface{})
+		for _, opt := range current {
+			if s, ok := opt.(string); ok && strings.HasPrefix(s, cgroupDriverExecOpt+"=") {
+				continue
+			}
+			execOpts = append(execOpts, opt)
+		}
+		config["exec-opts"] = append(execOpts, cgroupDriverExecOpt+"="+driver)
+	}
+}
+
+// checkCgroupDriver checks that the restarted daemon reports the cgroup driver
+// it was configured with.
+func checkCgroupDriver(p Provisioner, engineOptions engine.Options) error {
+	driver, err := cgroupDriver(p, engineOptions)
+	if err != nil || driver == "" {
+		return err
+	}
+
+	output, err := p.SSHCommand("sudo docker info --format '{{.CgroupDriver}}'")
+	if err != nil {
+		return fmt.Errorf("error checking the cgroup driver of the daemon: %s", err)
+	}
+
+	if reported := strings.TrimSpace(output); reported != driver {
+		return fmt.Errorf("the daemon uses the %q cgroup driver instead of %s", reported, driver)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCgroupDriver(t *testing.T) {
+	assert.NoError(t, ValidateCgroupDriver("", []string{"exec-opt=native.cgroupdriver=cgroupfs"}))
+	assert.NoError(t, ValidateCgroupDriver("systemd", []string{"log-level=debug"}))
+	assert.NoError(t, ValidateCgroupDriver("cgroupfs", nil))
+	assert.EqualError(t, ValidateCgroupDriver("cgroup2", nil), `invalid cgroup driver "cgroup2", must be systemd or cgroupfs`)
+	assert.EqualError(t, ValidateCgroupDriver("systemd", []string{"exec-opt=native.cgroupdriver=cgroupfs"}), "the cgroup driver can't be set with both --engine-cgroup-driver and the daemon flag --exec-opt=native.cgroupdriver=cgroupfs")
+}
+
+func TestDaemonCgroupDriver(t *testing.T) {
+	config, err := updatedDaemonConfig(`{"mtu": 1400, "exec-opts": ["native.cgroupdriver=cgroupfs", "isolation=default"]}`, daemonCgroupDriver("systemd"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"mtu": 1400, "exec-opts": ["isolation=default", "native.cgroupdriver=systemd"]}`, config)
+
+	config, err = updatedDaemonConfig("", daemonCgroupDriver("cgroupfs"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"exec-opts": ["native.cgroupdriver=cgroupfs"]}`, config)
+
+	_, err = updatedDaemonConfig("{", daemonCgroupDriver("systemd"))
+	assert.Error(t, err)
+}
+
+func newCgroupTestProvisioner(fsType string) *UbuntuSystemdProvisioner {
+	p := NewUbuntuSystemdProvisioner(&fakedriver.Driver{}).(*UbuntuSystemdProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"stat -fc %T /sys/fs/cgroup/": fsType + "\n",
+		},
+	}
+	return p
+}
+
+func TestCgroupDriver(t *testing.T) {
+	driver, err := cgroupDriver(newCgroupTestProvisioner("cgroup2fs"), engine.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "systemd", driver)
+
+	driver, err = cgroupDriver(newCgroupTestProvisioner("tmpfs"), engine.Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, driver)
+
+	driver, err = cgroupDriver(newCgroupTestProvisioner("cgroup2fs"), engine.Options{CgroupDriver: "cgroupfs"})
+	assert.NoError(t, err)
+	assert.Equal(t, "cgroupfs", driver)
+
+	driver, err = cgroupDriver(newCgroupTestProvisioner("cgroup2fs"), engine.Options{ArbitraryFlags: []string{"exec-opt=native.cgroupdriver=cgroupfs"}})
+	assert.NoError(t, err)
+	assert.Empty(t, driver)
+
+	driver, err = cgroupDriver(newCgroupTestProvisioner("cgroup2fs"), engine.Options{Rootless: true})
+	assert.NoError(t, err)
+	assert.Empty(t, driver)
+}
+
+func TestCheckCgroupDriver(t *testing.T) {
+	p := newCgroupTestProvisioner("cgroup2fs")
+	p.SSHCommander.(*provisiontest.FakeSSHCommander).Responses["sudo docker info --format '{{.CgroupDriver}}'"] = "cgroupfs\n"
+
+	assert.EqualError(t, checkCgroupDriver(p, engine.Options{}), `the daemon uses the "cgroupfs" cgroup driver instead of systemd`)
+	assert.NoError(t, checkCgroupDriver(p, engine.Options{CgroupDriver: "cgroupfs"}))
+}
@@ -0,0 +1,40 @@
+package provision
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// cloudInitWaitCmd waits for cloud-init to be done, printing absent on the
+// machines without cloud-init and the exit status of the wait otherwise.
+const cloudInitWaitCmd = `if ! command -v cloud-init >/dev/null 2>&1; then echo absent; exit 0; fi; sudo timeout %d cloud-init status --wait >/dev/null 2>&1; echo "status $?"`
+
+// WaitForCloudInit waits up to timeout seconds for cloud-init to be done on
+// the machine, so that the install of the daemon doesn't compete with it for
+// the package manager locks. The machines without cloud-init are skipped.
+func WaitForCloudInit(p SSHCommander, timeout int) error {
+	log.Info("Waiting for cloud-init to be done...")
+
+	output, err := p.SSHCommand(fmt.Sprintf(cloudInitWaitCmd, timeout))
+	if err != nil {
+		return fmt.Errorf("error waiting for cloud-init: %s", err)
+	}
+
+	switch status := strings.TrimSpace(output); status {
+	case "absent":
+		log.Info("cloud-init is not installed, not waiting for it")
+		return nil
+	case "status 0":
+		return nil
+	case "status 2":
+		// cloud-init is done with recoverable errors.
+		log.Warn("cloud-init is done with recoverable errors, see `sudo cloud-init status --long` on the machine")
+		return nil
+	case "status 124":
+		return fmt.Errorf("cloud-init was still running after %ds", timeout)
+	default:
+		return fmt.Errorf("cloud-init failed (%s), see `sudo cloud-init status --long` on the machine", status)
+	}
+}
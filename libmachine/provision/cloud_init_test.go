@@ -0,0 +1,36 @@
+package provision
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForCloudInit(t *testing.T) {
+	tests := []struct {
+		output string
+		err    string
+	}{
+		{output: "absent\n"},
+		{output: "status 0\n"},
+		{output: "status 2\n"},
+		{output: "status 124\n", err: "cloud-init was still running after 300s"},
+		{output: "status 1\n", err: "cloud-init failed (status 1), see `sudo cloud-init status --long` on the machine"},
+	}
+
+	for _, test := range tests {
+		commander := &provisiontest.FakeSSHCommander{
+			Responses: map[string]string{fmt.Sprintf(cloudInitWaitCmd, 300): test.output},
+		}
+
+		err := WaitForCloudInit(commander, 300)
+
+		if test.err == "" {
+			assert.NoError(t, err, test.output)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package provision
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
@@ -9,7 +10,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
 	"github.com/rancher/machine/libmachine/auth"
-	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/drivers"
 	"github.com/rancher/machine/libmachine/log"
 	"github.com/rancher/machine/libmachine/mcndockerclient"
 	"github.com/rancher/machine/libmachine/swarm"
@@ -22,7 +23,7 @@ func configureSwarm(p Provisioner, swarmOptions swarm.Options, authOptions auth.
 
 	log.Info("Configuring swarm...")
 
-	ip, err := p.GetDriver().GetIP()
+	ip, err := drivers.MachineIP(p.GetDriver(), preferIPv6(p))
 	if err != nil {
 		return err
 	}
@@ -32,33 +33,22 @@ func configureSwarm(p Provisioner, swarmOptions swarm.Options, authOptions auth.
 		return err
 	}
 
-	enginePort := engine.DefaultPort
-	engineURL, err := p.GetDriver().GetURL()
+	dockerPort, err := enginePort(p)
 	if err != nil {
 		return err
 	}
 
-	parts := strings.Split(engineURL, ":")
-	if len(parts) == 3 {
-		dPort, err := strconv.Atoi(parts[2])
-		if err != nil {
-			return err
-		}
-		enginePort = dPort
-	}
-
-	parts = strings.Split(u.Host, ":")
-	port := parts[1]
+	port := u.Port()
 
 	dockerDir := p.GetDockerOptionsDir()
+	advertiseInfo := net.JoinHostPort(ip, strconv.Itoa(dockerPort))
 	dockerHost := &mcndockerclient.RemoteDocker{
-		HostURL:    fmt.Sprintf("tcp://%s:%d", ip, enginePort),
+		HostURL:    "tcp://" + advertiseInfo,
 		AuthOption: &authOptions,
 	}
-	advertiseInfo := fmt.Sprintf("%s:%d", ip, enginePort)
 
 	if swarmOptions.Master {
-		advertiseMasterInfo := fmt.Sprintf("%s:%s", ip, "3376")
+		advertiseMasterInfo := net.JoinHostPort(ip, "3376")
 		cmd := fmt.Sprintf("manage --tlsverify --tlscacert=%s --tlscert=%s --tlskey=%s -H %s --strategy %s --advertise %s",
 			authOptions.CaCertRemotePath,
 			authOptions.ServerCertRemotePath,
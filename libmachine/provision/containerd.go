@@ -0,0 +1,143 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/provision/serviceaction"
+)
+
+const (
+	containerdConfigPath = "/etc/containerd/config.toml"
+	// containerdConfigBackupPath keeps the config replaced by the merged
+	// one until containerd restarted with it.
+	containerdConfigBackupPath = containerdConfigPath + ".machine-backup"
+)
+
+// ValidateContainerdConfig checks the content of a containerd config file is
+// valid TOML.
+func ValidateContainerdConfig(content string) error {
+	config := map[string]interface{}{}
+	if err := toml.Unmarshal([]byte(content), &config); err != nil {
+		return fmt.Errorf("invalid TOML: %s", err)
+	}
+
+	return nil
+}
+
+// configureContainerdConfig merges the containerd config file of the engine
+// options into the config of the machine and restarts containerd with it,
+// restoring the previous config when containerd fails to restart. The daemon
+// picks it up when it is restarted afterwards.
+func configureContainerdConfig(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.ContainerdConfigFile == "" {
+		return nil
+	}
+
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return fmt.Errorf("a containerd config file is not supported on %s", p.String())
+	}
+
+	custom, err := os.ReadFile(engineOptions.ContainerdConfigFile)
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %v", engineOptions.ContainerdConfigFile, err)
+	}
+
+	current, err := p.SSHCommand(fmt.Sprintf("sudo cat %s 2>/dev/null || true", containerdConfigPath))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", containerdConfigPath, err)
+	}
+
+	config, err := mergeContainerdConfig(current, string(custom))
+	if err != nil {
+		return err
+	}
+	// The config of the machine is compared once formatted as the merged one.
+	if unchanged, _ := mergeContainerdConfig(current, ""); config == unchanged {
+		log.Info("The containerd config is unchanged")
+		return nil
+	}
+
+	log.Infof("Setting the containerd config from %s...", engineOptions.ContainerdConfigFile)
+
+	backup := fmt.Sprintf("if [ -f %[1]s ]; then sudo cp %[1]s %[2]s; else sudo rm -f %[2]s; fi", containerdConfigPath, containerdConfigBackupPath)
+	if _, err := p.SSHCommand(backup); err != nil {
+		return fmt.Errorf("error saving %s: %s", containerdConfigPath, err)
+	}
+
+	cmd := fmt.Sprintf("sudo mkdir -p /etc/containerd && printf '%%s' '%s' | sudo tee %s", strings.ReplaceAll(config, "'", `'\''`), containerdConfigPath)
+	if output, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error writing %s: %s: %s", containerdConfigPath, err, output)
+	}
+
+	if err := p.Service("containerd", serviceaction.Restart); err != nil {
+		return rollbackContainerdConfig(p, err)
+	}
+
+	return nil
+}
+
+// mergeContainerdConfig returns the config of the machine with the tables and
+// keys of the custom config set over it. The custom config is used as is when
+// the machine has none.
+func mergeContainerdConfig(current, custom string) (string, error) {
+	base := map[string]interface{}{}
+	if err := toml.Unmarshal([]byte(current), &base); err != nil {
+		log.Warnf("Replacing %s, it isn't valid TOML: %s", containerdConfigPath, err)
+		base = map[string]interface{}{}
+	}
+
+	overrides := map[string]interface{}{}
+	if err := toml.Unmarshal([]byte(custom), &overrides); err != nil {
+		return "", fmt.Errorf("invalid containerd config: %s", err)
+	}
+
+	mergeTOMLTables(base, overrides)
+
+	data, err := toml.Marshal(base)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// mergeTOMLTables sets the keys of overrides in base, merging the tables
+// present in both.
+func mergeTOMLTables(base, overrides map[string]interface{}) {
+	for key, value := range overrides {
+		table, isTable := value.(map[string]interface{})
+		baseTable, baseIsTable := base[key].(map[string]interface{})
+		if isTable && baseIsTable {
+			mergeTOMLTables(baseTable, table)
+			continue
+		}
+		base[key] = value
+	}
+}
+
+// rollbackContainerdConfig restores the previous containerd config after
+// containerd failed to restart with the merged one, and restarts it again.
+func rollbackContainerdConfig(p Provisioner, cause error) error {
+	journal, _ := p.SSHCommand("sudo journalctl -u containerd -n 20 --no-pager")
+	if journal = strings.TrimSpace(journal); journal != "" {
+		cause = fmt.Errorf("%s:\n%s", cause, journal)
+	}
+
+	log.Warnf("containerd failed to restart with the new config, restoring the previous one...")
+
+	restore := fmt.Sprintf("if [ -f %[2]s ]; then sudo mv %[2]s %[1]s; else sudo rm -f %[1]s; fi", containerdConfigPath, containerdConfigBackupPath)
+	if _, err := p.SSHCommand(restore); err != nil {
+		return fmt.Errorf("containerd failed to restart with the config file (%s) and the previous config could not be restored: %s", cause, err)
+	}
+
+	if err := p.Service("containerd", serviceaction.Restart); err != nil {
+		return fmt.Errorf("containerd failed to restart with the config file (%s) and again after restoring the previous config: %s", cause, err)
+	}
+
+	return fmt.Errorf("containerd failed to restart with the config file, the previous config was restored: %s", cause)
+}
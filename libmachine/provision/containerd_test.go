@@ -0,0 +1,93 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+const currentContainerdConfig = `disabled_plugins = ["cri"]
+
+[plugins]
+[plugins.opt]
+path = '/opt/containerd'
+`
+
+func TestValidateContainerdConfig(t *testing.T) {
+	assert.NoError(t, ValidateContainerdConfig("version = 2\n[plugins]\n"))
+	assert.ErrorContains(t, ValidateContainerdConfig("version = \n"), "invalid TOML")
+}
+
+func TestMergeContainerdConfig(t *testing.T) {
+	config, err := mergeContainerdConfig(currentContainerdConfig, `disabled_plugins = []
+
+[plugins.cri.registry.mirrors."docker.io"]
+endpoint = ["https://mirror.example.com"]
+`)
+
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateContainerdConfig(config))
+	assert.Contains(t, config, "disabled_plugins = []")
+	assert.Contains(t, config, "path = '/opt/containerd'")
+	assert.Contains(t, config, "endpoint = ['https://mirror.example.com']")
+
+	config, err = mergeContainerdConfig("", "version = 2\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "version = 2\n", config)
+}
+
+func newContainerdTestProvisioner(t *testing.T, custom string) (*DebianProvisioner, engine.Options, string) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(configFile, []byte(custom), 0644))
+
+	config, err := mergeContainerdConfig(currentContainerdConfig, custom)
+	assert.NoError(t, err)
+
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"sudo cat /etc/containerd/config.toml 2>/dev/null || true": currentContainerdConfig,
+			"if [ -f /etc/containerd/config.toml ]; then sudo cp /etc/containerd/config.toml /etc/containerd/config.toml.machine-backup; else sudo rm -f /etc/containerd/config.toml.machine-backup; fi": "",
+			fmt.Sprintf("sudo mkdir -p /etc/containerd && printf '%%s' '%s' | sudo tee /etc/containerd/config.toml", strings.ReplaceAll(config, "'", `'\''`)):                                            "",
+			"if [ -f /etc/containerd/config.toml.machine-backup ]; then sudo mv /etc/containerd/config.toml.machine-backup /etc/containerd/config.toml; else sudo rm -f /etc/containerd/config.toml; fi": "",
+			"sudo systemctl daemon-reload": "",
+		},
+	}
+
+	return p, engine.Options{ContainerdConfigFile: configFile}, config
+}
+
+func TestConfigureContainerdConfig(t *testing.T) {
+	p, engineOptions, _ := newContainerdTestProvisioner(t, "version = 2\n")
+	p.SSHCommander.(*provisiontest.FakeSSHCommander).Responses["sudo systemctl -f restart containerd"] = ""
+
+	assert.NoError(t, configureContainerdConfig(p, engineOptions))
+	assert.NoError(t, configureContainerdConfig(p, engine.Options{}))
+}
+
+func TestConfigureContainerdConfigRollback(t *testing.T) {
+	p, engineOptions, _ := newContainerdTestProvisioner(t, "version = 2\n")
+
+	err := configureContainerdConfig(p, engineOptions)
+
+	assert.ErrorContains(t, err, "containerd failed to restart with the config file")
+	assert.ErrorContains(t, err, "again after restoring the previous config")
+}
+
+func TestConfigureContainerdConfigUnchanged(t *testing.T) {
+	p, engineOptions, _ := newContainerdTestProvisioner(t, "[plugins.opt]\npath = '/opt/containerd'\n")
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"sudo cat /etc/containerd/config.toml 2>/dev/null || true": currentContainerdConfig,
+		},
+	}
+
+	assert.NoError(t, configureContainerdConfig(p, engineOptions))
+}
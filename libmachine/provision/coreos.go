@@ -80,11 +80,14 @@ func (provisioner *CoreOSProvisioner) GenerateDockerOptions(dockerPort int) (*Do
 		arg = ""
 	}
 
-	engineConfigTmpl := `[Service]
+	engineConfigTmpl := `{{ if .EngineOptions.GraphDir }}[Unit]
+RequiresMountsFor={{.EngineOptions.GraphDir}}
+
+{{ end }}[Service]
 Environment=TMPDIR=/var/tmp
 ExecStart=
-ExecStart=/usr/lib/coreos/dockerd ` + arg + ` --host=unix:///var/run/docker.sock --host=tcp://0.0.0.0:{{.DockerPort}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ range .EngineOptions.Labels }} --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} --{{.}}{{ end }} \$DOCKER_OPTS \$DOCKER_OPT_BIP \$DOCKER_OPT_MTU \$DOCKER_OPT_IPMASQ
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+ExecStart=/usr/lib/coreos/dockerd ` + arg + ` --host=unix:///var/run/docker.sock{{ if not .EngineOptions.SSHTransport }} --host=tcp://0.0.0.0:{{.DockerPort}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ end }}{{ if .EngineOptions.MetricsAddr }} --metrics-addr {{.EngineOptions.MetricsAddr}}{{ end }}{{ range .EngineOptions.Labels }} --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} --{{.}}{{ end }} \$DOCKER_OPTS \$DOCKER_OPT_BIP \$DOCKER_OPT_MTU \$DOCKER_OPT_IPMASQ
+EnvironmentFile=-` + engineEnvFile + `
 `
 
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
@@ -115,7 +118,7 @@ func (provisioner *CoreOSProvisioner) Provision(swarmOptions swarm.Options, auth
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -123,6 +126,10 @@ func (provisioner *CoreOSProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debugf("Preparing certificates")
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
@@ -131,6 +138,10 @@ func (provisioner *CoreOSProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debug("Configuring swarm")
 	if err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions); err != nil {
 		return err
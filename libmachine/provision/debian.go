@@ -99,10 +99,15 @@ func (provisioner *DebianProvisioner) Provision(swarmOptions swarm.Options, auth
 	}
 
 	log.Debug("setting hostname")
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
+	if err := configurePackageMirror(provisioner, engineOptions); err != nil {
+		return err
+	}
+	defer removePackageMirrorAuth(provisioner, engineOptions)
+
 	log.Debug("installing base packages")
 	for _, pkg := range provisioner.Packages {
 		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
@@ -110,7 +115,7 @@ func (provisioner *DebianProvisioner) Provision(swarmOptions swarm.Options, auth
 		}
 	}
 
-	if err := installDockerGeneric(provisioner, provisioner.EngineOptions.InstallURL); err != nil {
+	if err := installDockerGeneric(provisioner, provisioner.EngineOptions); err != nil {
 		return err
 	}
 
@@ -119,6 +124,10 @@ func (provisioner *DebianProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	log.Debug("configuring auth")
@@ -126,6 +135,10 @@ func (provisioner *DebianProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debug("configuring swarm")
 	if err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions); err != nil {
 		return err
@@ -0,0 +1,173 @@
+package provision
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	resolvedDropInFile = "/etc/systemd/resolved.conf.d/99-machine-dns.conf"
+	networkManagerFile = "/etc/NetworkManager/conf.d/99-machine-dns.conf"
+	dhclientConfFile   = "/etc/dhcp/dhclient.conf"
+
+	// dhclientBlockStart and dhclientBlockEnd enclose the lines added to the
+	// dhclient configuration, replaced when the machine is provisioned again.
+	dhclientBlockStart = "# machine dns"
+	dhclientBlockEnd   = "# end of machine dns"
+)
+
+// ValidateDNSServers checks that the DNS servers are IP addresses, and that
+// they aren't also set in the daemon flags, with --engine-opt or the engine
+// flag file, which the daemon refuses.
+func ValidateDNSServers(servers []string, engineOpts []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("DNS server %q is not an IP address", server)
+		}
+	}
+
+	return validateDNSEngineOpts("the DNS servers", "dns-server", "dns", engineOpts)
+}
+
+// ValidateDNSSearch checks that the DNS search domains are valid DNS names,
+// and that they aren't also set in the daemon flags.
+func ValidateDNSSearch(search []string, engineOpts []string) error {
+	if len(search) == 0 {
+		return nil
+	}
+
+	for _, domain := range search {
+		if err := ValidateHostname(domain); err != nil {
+			return fmt.Errorf("DNS search domain %q is invalid, each dot separated label must be 1 to 63 letters, digits or hyphens and can't start or end with a hyphen", domain)
+		}
+	}
+
+	return validateDNSEngineOpts("the DNS search domains", "dns-search", "dns-search", engineOpts)
+}
+
+func validateDNSEngineOpts(what, option, flag string, engineOpts []string) error {
+	for _, opt := range engineOpts {
+		if name, _, _ := strings.Cut(opt, "="); name == flag {
+			return fmt.Errorf("%s can't be set with both --provision-%s and the daemon flag --%s", what, option, opt)
+		}
+	}
+
+	return nil
+}
+
+// configureDNS makes the resolver of the machine use the DNS servers and
+// search domains of the engine options. It configures the mechanism owning
+// the resolver of the OS, so that the settings survive a reboot.
+func configureDNS(p Provisioner, engineOptions engine.Options) error {
+	servers, search := engineOptions.DNS, engineOptions.DNSSearch
+	if len(servers) == 0 && len(search) == 0 {
+		return nil
+	}
+
+	var cmd string
+	switch {
+	case serviceActive(p, "systemd-resolved"):
+		log.Info("Configuring the DNS servers of systemd-resolved...")
+		cmd = fmt.Sprintf("sudo mkdir -p %s && printf '%%s' '%s' | sudo tee %s && sudo systemctl restart systemd-resolved",
+			path.Dir(resolvedDropInFile), resolvedConf(servers, search), resolvedDropInFile)
+	case serviceActive(p, "NetworkManager"):
+		log.Info("Configuring the DNS servers of the machine, NetworkManager won't manage /etc/resolv.conf anymore...")
+		cmd = fmt.Sprintf("printf '%%s' '[main]\ndns=none\n' | sudo tee %s && sudo rm -f /etc/resolv.conf && printf '%%s' '%s' | sudo tee /etc/resolv.conf && sudo systemctl reload NetworkManager",
+			networkManagerFile, resolvConf(servers, search))
+	default:
+		log.Info("Configuring the DNS servers in /etc/resolv.conf...")
+		cmd = fmt.Sprintf("sudo rm -f /etc/resolv.conf && printf '%%s' '%[1]s' | sudo tee /etc/resolv.conf && if [ -f %[3]s ]; then sudo sed -i '/^%[4]s$/,/^%[5]s$/d' %[3]s && printf '%%s' '%[2]s' | sudo tee -a %[3]s; fi",
+			resolvConf(servers, search), dhclientSupersede(servers, search), dhclientConfFile, dhclientBlockStart, dhclientBlockEnd)
+	}
+
+	if output, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error configuring the DNS servers: %s: %s", err, output)
+	}
+
+	return nil
+}
+
+func serviceActive(p Provisioner, service string) bool {
+	_, err := p.SSHCommand(fmt.Sprintf("systemctl is-active --quiet %s", service))
+	return err == nil
+}
+
+// configureEngineDNS makes the containers use the DNS servers and search
+// domains of the engine options, in daemon.json.
+func configureEngineDNS(p Provisioner, engineOptions engine.Options) error {
+	if len(engineOptions.DNS) == 0 && len(engineOptions.DNSSearch) == 0 {
+		return nil
+	}
+
+	log.Info("Setting the DNS servers of the Docker containers...")
+
+	return updateDaemonConfig(p, daemonDNS(engineOptions.DNS, engineOptions.DNSSearch))
+}
+
+// daemonDNS returns the update of daemon.json setting the DNS servers and
+// search domains, the ones left empty being kept.
+func daemonDNS(servers, search []string) func(config map[string]interface{}) {
+	return func(config map[string]interface{}) {
+		if len(servers) > 0 {
+			config["dns"] = servers
+		}
+		if len(search) > 0 {
+			config["dns-search"] = search
+		}
+	}
+}
+
+// resolvedConf is a systemd-resolved drop-in setting the DNS servers and
+// search domains.
+func resolvedConf(servers, search []string) string {
+	conf := "[Resolve]\n"
+	if len(servers) > 0 {
+		conf += "DNS=" + strings.Join(servers, " ") + "\n"
+	}
+	if len(search) > 0 {
+		conf += "Domains=" + strings.Join(search, " ") + "\n"
+	}
+
+	return conf
+}
+
+// resolvConf is a resolv.conf with the DNS servers and search domains.
+func resolvConf(servers, search []string) string {
+	conf := ""
+	if len(search) > 0 {
+		conf += "search " + strings.Join(search, " ") + "\n"
+	}
+	for _, server := range servers {
+		conf += "nameserver " + server + "\n"
+	}
+
+	return conf
+}
+
+// dhclientSupersede keeps DHCP leases from replacing the DNS servers and
+// search domains in resolv.conf. The lines are enclosed in a block that
+// configureDNS replaces.
+func dhclientSupersede(servers, search []string) string {
+	conf := dhclientBlockStart + "\n"
+	if len(servers) > 0 {
+		conf += "supersede domain-name-servers " + strings.Join(servers, ", ") + ";\n"
+	}
+	if len(search) > 0 {
+		quoted := []string{}
+		for _, domain := range search {
+			quoted = append(quoted, fmt.Sprintf("%q", domain))
+		}
+		conf += "supersede domain-search " + strings.Join(quoted, ", ") + ";\n"
+	}
+
+	return conf + dhclientBlockEnd + "\n"
+}
@@ -0,0 +1,37 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDNSServers(t *testing.T) {
+	assert.NoError(t, ValidateDNSServers([]string{"10.0.0.2", "2001:db8::53"}, []string{"dns-search=example.com"}))
+	assert.NoError(t, ValidateDNSServers(nil, []string{"dns=10.0.0.4"}))
+	assert.EqualError(t, ValidateDNSServers([]string{"10.0.0.2", "dns.example.com"}, nil), `DNS server "dns.example.com" is not an IP address`)
+	assert.EqualError(t, ValidateDNSServers([]string{"10.0.0.2"}, []string{"dns=10.0.0.4"}), "the DNS servers can't be set with both --provision-dns-server and the daemon flag --dns=10.0.0.4")
+}
+
+func TestValidateDNSSearch(t *testing.T) {
+	assert.NoError(t, ValidateDNSSearch([]string{"corp.example.com", "example"}, nil))
+	assert.Error(t, ValidateDNSSearch([]string{"corp.example.com", "it's.example.com"}, nil))
+	assert.Error(t, ValidateDNSSearch([]string{"-corp.example.com"}, nil))
+	assert.EqualError(t, ValidateDNSSearch([]string{"corp.example.com"}, []string{"dns-search=example.com"}), "the DNS search domains can't be set with both --provision-dns-search and the daemon flag --dns-search=example.com")
+}
+
+func TestDaemonDNS(t *testing.T) {
+	config, err := updatedDaemonConfig(`{"dns-search": ["example.com"], "mtu": 1400}`, daemonDNS([]string{"10.0.0.2", "10.0.0.3"}, nil))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"dns": ["10.0.0.2", "10.0.0.3"], "dns-search": ["example.com"], "mtu": 1400}`, config)
+}
+
+func TestResolverConfigs(t *testing.T) {
+	servers := []string{"10.0.0.2", "10.0.0.3"}
+	search := []string{"corp.example.com", "example.com"}
+
+	assert.Equal(t, "[Resolve]\nDNS=10.0.0.2 10.0.0.3\nDomains=corp.example.com example.com\n", resolvedConf(servers, search))
+	assert.Equal(t, "search corp.example.com example.com\nnameserver 10.0.0.2\nnameserver 10.0.0.3\n", resolvConf(servers, search))
+	assert.Equal(t, "# machine dns\nsupersede domain-name-servers 10.0.0.2, 10.0.0.3;\nsupersede domain-search \"corp.example.com\", \"example.com\";\n# end of machine dns\n", dhclientSupersede(servers, search))
+	assert.Equal(t, "[Resolve]\nDNS=10.0.0.2 10.0.0.3\n", resolvedConf(servers, nil))
+}
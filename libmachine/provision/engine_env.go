@@ -0,0 +1,97 @@
+package provision
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// engineEnvFile is the environment file of the docker unit, loaded by the
+// EnvironmentFile directive of the systemd based provisioners. It is only
+// readable by root as the values may be credentials.
+const engineEnvFile = "/etc/docker/machine.env"
+
+var (
+	engineEnvKeyRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// secretEnvKeyRE matches the names of the variables whose values are
+	// redacted from the logs, e.g. AWS_SECRET_ACCESS_KEY or REGISTRY_TOKEN.
+	secretEnvKeyRE = regexp.MustCompile(`(?i)(^|_)(SECRET|PASSWORD|PASSWD|PASS|TOKEN|KEY|APIKEY|CREDENTIALS?|AUTH)(_|$)`)
+)
+
+// ValidateEngineEnv checks the KEY=value variables of --engine-env.
+func ValidateEngineEnv(env []string) error {
+	for _, variable := range env {
+		key, _, found := strings.Cut(variable, "=")
+		if !found {
+			return fmt.Errorf("%q is not a KEY=value variable", variable)
+		}
+		if !engineEnvKeyRE.MatchString(key) {
+			return fmt.Errorf("invalid variable name %q, it must start with a letter or an underscore followed by letters, digits or underscores", key)
+		}
+		if strings.ContainsAny(variable, "\n\r") {
+			return fmt.Errorf("the value of %s can't span several lines", key)
+		}
+	}
+
+	return nil
+}
+
+// registerEngineEnvSecrets redacts the values of the variables looking like
+// credentials from the logs.
+func registerEngineEnvSecrets(env []string) {
+	for _, variable := range env {
+		if key, value, _ := strings.Cut(variable, "="); secretEnvKeyRE.MatchString(key) {
+			log.RegisterSecret(value)
+		}
+	}
+}
+
+// engineEnvFileContent returns the environment file of the variables, one
+// per line and double quoted. A variable set several times keeps its last
+// value, at the place of its first occurrence.
+func engineEnvFileContent(env []string) string {
+	keys := []string{}
+	values := map[string]string{}
+	for _, variable := range env {
+		key, value, _ := strings.Cut(variable, "=")
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+
+	var content strings.Builder
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(values[key])
+		fmt.Fprintf(&content, "%s=\"%s\"\n", key, value)
+	}
+	return content.String()
+}
+
+// configureEngineEnv writes the environment file of the docker unit, which
+// the next restart of the daemon loads. The file is replaced as a whole, so
+// provisioning again leaves exactly the configured variables, and removed
+// when there are none. The other provisioners export the variables from
+// their daemon options file instead.
+func configureEngineEnv(p Provisioner, engineOptions engine.Options) error {
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return nil
+	}
+
+	if len(engineOptions.Env) == 0 {
+		_, err := p.SSHCommand(fmt.Sprintf("sudo rm -f %s", engineEnvFile))
+		return err
+	}
+
+	log.Info("Setting the environment of the Docker daemon...")
+
+	content := engineEnvFileContent(engineOptions.Env)
+	if _, err := p.SSHCommand(fmt.Sprintf("printf '%%s' '%s' | sudo sh -c 'umask 077 && mkdir -p /etc/docker && cat > %s'", strings.ReplaceAll(content, "'", `'\''`), engineEnvFile)); err != nil {
+		return fmt.Errorf("error writing %s: %s", engineEnvFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEngineEnv(t *testing.T) {
+	assert.NoError(t, ValidateEngineEnv([]string{"HTTP_PROXY=http://proxy:3128", "_DEBUG=", "OPTS=a=b"}))
+	assert.EqualError(t, ValidateEngineEnv([]string{"DEBUG"}), `"DEBUG" is not a KEY=value variable`)
+	assert.EqualError(t, ValidateEngineEnv([]string{"1KEY=value"}), `invalid variable name "1KEY", it must start with a letter or an underscore followed by letters, digits or underscores`)
+	assert.EqualError(t, ValidateEngineEnv([]string{"MY-KEY=value"}), `invalid variable name "MY-KEY", it must start with a letter or an underscore followed by letters, digits or underscores`)
+	assert.EqualError(t, ValidateEngineEnv([]string{"KEY=a\nb"}), "the value of KEY can't span several lines")
+}
+
+func TestEngineEnvFileContent(t *testing.T) {
+	content := engineEnvFileContent([]string{"DEBUG=1", `MOTD=say "hi" \o/`, "DEBUG=2", "EMPTY="})
+
+	assert.Equal(t, "DEBUG=\"2\"\nMOTD=\"say \\\"hi\\\" \\\\o/\"\nEMPTY=\"\"\n", content)
+}
+
+func TestSecretEnvKey(t *testing.T) {
+	for _, key := range []string{"AWS_SECRET_ACCESS_KEY", "REGISTRY_TOKEN", "password", "DB_PASS", "API_KEY", "GIT_CREDENTIALS"} {
+		assert.True(t, secretEnvKeyRE.MatchString(key), key)
+	}
+	for _, key := range []string{"HTTP_PROXY", "KEYBOARD", "PASSENGER_MODE", "DEBUG"} {
+		assert.False(t, secretEnvKeyRE.MatchString(key), key)
+	}
+}
+
+func TestConfigureEngineEnv(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			`printf '%s' 'DEBUG="1"
+NAME="it'\''s"
+' | sudo sh -c 'umask 077 && mkdir -p /etc/docker && cat > /etc/docker/machine.env'`: "",
+			"sudo rm -f /etc/docker/machine.env": "",
+		},
+	}
+
+	assert.NoError(t, configureEngineEnv(p, engine.Options{Env: []string{"DEBUG=1", "NAME=it's"}}))
+	assert.NoError(t, configureEngineEnv(p, engine.Options{}))
+}
+
+func TestConfigureEngineEnvWithoutSystemd(t *testing.T) {
+	p := NewBoot2DockerProvisioner(&fakedriver.Driver{}).(*Boot2DockerProvisioner)
+
+	assert.NoError(t, configureEngineEnv(p, engine.Options{Env: []string{"DEBUG=1"}}))
+}
@@ -0,0 +1,116 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// managedEngineFlags are the daemon flags set by machine, which the flags of
+// an engine flag file can't override.
+var managedEngineFlags = map[string]bool{
+	"H":         true,
+	"host":      true,
+	"tls":       true,
+	"tlsverify": true,
+	"tlscacert": true,
+	"tlscert":   true,
+	"tlskey":    true,
+}
+
+// engineFlagsSetter is implemented by the provisioners whose daemon flags
+// can be replaced before the daemon options are generated.
+type engineFlagsSetter interface {
+	setEngineFlags(flags []string)
+}
+
+// setEngineFlags replaces the daemon flags with the merged ones. The flag file
+// is cleared so that they aren't merged twice.
+func (provisioner *GenericProvisioner) setEngineFlags(flags []string) {
+	provisioner.EngineOptions.ArbitraryFlags = flags
+	provisioner.EngineOptions.FlagFile = ""
+}
+
+// ParseEngineFlagFile parses the content of an engine flag file, one
+// --flag, --flag=value or --flag value per line, ignoring the blank lines and
+// the # comments. The flags are returned in the flag=value form of the
+// --engine-opt flags.
+func ParseEngineFlagFile(content string) ([]string, error) {
+	flags := []string{}
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") || len(line) == 2 {
+			return nil, fmt.Errorf("line %d: %q is not a --flag[=value] daemon flag", i+1, line)
+		}
+
+		flag := strings.TrimPrefix(line, "--")
+		if name, value, found := strings.Cut(flag, " "); found && !strings.Contains(name, "=") {
+			flag = name + "=" + strings.TrimSpace(value)
+		}
+		if strings.ContainsAny(flag, " \t") {
+			return nil, fmt.Errorf("line %d: the value of %q can't contain spaces", i+1, line)
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
+
+// engineFlags returns the flags of the engine flag file, without the flags
+// managed by machine, followed by the --engine-opt flags.
+func engineFlags(engineOptions engine.Options) ([]string, error) {
+	if engineOptions.FlagFile == "" {
+		return engineOptions.ArbitraryFlags, nil
+	}
+
+	content, err := os.ReadFile(engineOptions.FlagFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %v", engineOptions.FlagFile, err)
+	}
+
+	fileFlags, err := ParseEngineFlagFile(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine flag file %s: %s", engineOptions.FlagFile, err)
+	}
+
+	flags := []string{}
+	for _, flag := range fileFlags {
+		name, _, _ := strings.Cut(flag, "=")
+		if managedEngineFlags[name] {
+			log.Warnf("Ignoring --%s of the engine flag file, it is set by machine", name)
+			continue
+		}
+		flags = append(flags, flag)
+	}
+
+	return append(flags, engineOptions.ArbitraryFlags...), nil
+}
+
+// configureEngineFlags merges the flags of the engine flag file into the
+// daemon flags of the provisioner.
+func configureEngineFlags(p Provisioner) error {
+	ep, ok := p.(engineOptionsProvisioner)
+	if !ok {
+		return nil
+	}
+
+	flags, err := engineFlags(ep.GetEngineOptions())
+	if err != nil {
+		return err
+	}
+
+	if fs, ok := p.(engineFlagsSetter); ok {
+		fs.setEngineFlags(flags)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package provision
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEngineFlagFile(t *testing.T) {
+	flags, err := ParseEngineFlagFile(`# logging
+--log-level=debug
+--debug
+
+--default-ulimit nofile=1024:2048
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"log-level=debug", "debug", "default-ulimit=nofile=1024:2048"}, flags)
+}
+
+func TestParseEngineFlagFileErrors(t *testing.T) {
+	_, err := ParseEngineFlagFile("--debug\nlog-level=debug\n")
+	assert.EqualError(t, err, `line 2: "log-level=debug" is not a --flag[=value] daemon flag`)
+
+	_, err = ParseEngineFlagFile("--label a b\n")
+	assert.EqualError(t, err, `line 1: the value of "--label a b" can't contain spaces`)
+}
+
+func TestEngineFlags(t *testing.T) {
+	flagFile := filepath.Join(t.TempDir(), "flags")
+	assert.NoError(t, os.WriteFile(flagFile, []byte("--debug\n--tlsverify=false\n--host tcp://0.0.0.0:2375\n"), 0600))
+
+	flags, err := engineFlags(engine.Options{
+		FlagFile:       flagFile,
+		ArbitraryFlags: []string{"log-level=warn"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"debug", "log-level=warn"}, flags)
+}
+
+func TestEngineFlagsWithoutFile(t *testing.T) {
+	flags, err := engineFlags(engine.Options{ArbitraryFlags: []string{"debug"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"debug"}, flags)
+}
+
+func TestConfigureEngineFlagsMergesOnce(t *testing.T) {
+	flagFile := filepath.Join(t.TempDir(), "flags")
+	assert.NoError(t, os.WriteFile(flagFile, []byte("--exec-opt native.cgroupdriver=cgroupfs\n"), 0600))
+
+	p := NewUbuntuSystemdProvisioner(&fakedriver.Driver{}).(*UbuntuSystemdProvisioner)
+	p.EngineOptions = engine.Options{FlagFile: flagFile, ArbitraryFlags: []string{"debug"}}
+
+	assert.NoError(t, configureEngineFlags(p))
+	assert.NoError(t, configureEngineFlags(p))
+
+	assert.Equal(t, []string{"exec-opt=native.cgroupdriver=cgroupfs", "debug"}, p.EngineOptions.ArbitraryFlags)
+
+	driver, err := cgroupDriver(p, p.EngineOptions)
+	assert.NoError(t, err)
+	assert.Empty(t, driver)
+}
@@ -58,22 +58,26 @@ func (provisioner *FedoraCoreOSProvisioner) GenerateDockerOptions(dockerPort int
 	driverNameLabel := fmt.Sprintf("provider=%s", provisioner.Driver.DriverName())
 	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
 
-	engineConfigTmpl := `[Service]
+	engineConfigTmpl := `{{ if .EngineOptions.GraphDir }}[Unit]
+RequiresMountsFor={{.EngineOptions.GraphDir}}
+
+{{ end }}[Service]
 ExecStart=
 ExecStart=/usr/bin/dockerd \\
           --host=fd:// \\
-          --exec-opt native.cgroupdriver=systemd \\
+          --exec-opt native.cgroupdriver={{ or .EngineOptions.CgroupDriver "systemd" }}{{ if not .EngineOptions.SSHTransport }} \\
           --host=tcp://0.0.0.0:{{.DockerPort}} \\
           --tlsverify \\
           --tlscacert {{.AuthOptions.CaCertRemotePath}} \\
           --tlscert {{.AuthOptions.ServerCertRemotePath}} \\
-          --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ range .EngineOptions.Labels }} \\
+          --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ end }}{{ if .EngineOptions.MetricsAddr }} \\
+          --metrics-addr {{.EngineOptions.MetricsAddr}}{{ end }}{{ range .EngineOptions.Labels }} \\
           --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} \\
           --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} \\
           --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} \\
           -{{.}}{{ end }} \\
           \$OPTIONS
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+EnvironmentFile=-` + engineEnvFile + `
 `
 
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
@@ -115,7 +119,7 @@ func (provisioner *FedoraCoreOSProvisioner) Provision(swarmOptions swarm.Options
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -123,6 +127,10 @@ func (provisioner *FedoraCoreOSProvisioner) Provision(swarmOptions swarm.Options
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debugf("Preparing certificates")
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
@@ -131,6 +139,10 @@ func (provisioner *FedoraCoreOSProvisioner) Provision(swarmOptions swarm.Options
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debug("Configuring swarm")
 	err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
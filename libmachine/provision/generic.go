@@ -80,6 +80,10 @@ func (provisioner *GenericProvisioner) GetSwarmOptions() swarm.Options {
 	return provisioner.SwarmOptions
 }
 
+func (provisioner *GenericProvisioner) GetEngineOptions() engine.Options {
+	return provisioner.EngineOptions
+}
+
 func (provisioner *GenericProvisioner) SetOsReleaseInfo(info *OsRelease) {
 	provisioner.OsReleaseInfo = info
 }
@@ -98,14 +102,15 @@ func (provisioner *GenericProvisioner) GenerateDockerOptions(dockerPort int) (*D
 
 	engineConfigTmpl := `
 DOCKER_OPTS='
--H tcp://0.0.0.0:{{.DockerPort}}
--H unix:///var/run/docker.sock
+{{ if not .EngineOptions.SSHTransport }}-H tcp://0.0.0.0:{{.DockerPort}}
+{{ end }}-H unix:///var/run/docker.sock
 --storage-driver {{.EngineOptions.StorageDriver}}
---tlsverify
+{{ if .EngineOptions.MetricsAddr }}--metrics-addr {{.EngineOptions.MetricsAddr}}
+{{ end }}{{ if not .EngineOptions.SSHTransport }}--tlsverify
 --tlscacert {{.AuthOptions.CaCertRemotePath}}
 --tlscert {{.AuthOptions.ServerCertRemotePath}}
 --tlskey {{.AuthOptions.ServerKeyRemotePath}}
-{{ range .EngineOptions.Labels }}--label {{.}}
+{{ end }}{{ range .EngineOptions.Labels }}--label {{.}}
 {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}}
 {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}}
 {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}}
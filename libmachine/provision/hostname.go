@@ -0,0 +1,49 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+var hostnameLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// ValidateHostname checks that hostname is a valid RFC 1123 host name.
+func ValidateHostname(hostname string) error {
+	if hostname == "" {
+		return errors.New("hostname is empty")
+	}
+
+	if len(hostname) > 253 {
+		return fmt.Errorf("hostname %q is longer than 253 characters", hostname)
+	}
+
+	for _, label := range strings.Split(hostname, ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return fmt.Errorf("hostname %q is invalid, each dot separated label must be 1 to 63 letters, digits or hyphens and can't start or end with a hyphen", hostname)
+		}
+	}
+
+	return nil
+}
+
+// setHostname sets the hostname of the machine to the one of the engine
+// options, or to the machine name.
+func setHostname(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.KeepHostname {
+		log.Debug("Keeping the hostname set by the provider")
+		return nil
+	}
+
+	hostname := engineOptions.Hostname
+	if hostname == "" {
+		hostname = p.GetDriver().GetMachineName()
+	}
+
+	log.Debugf("Setting hostname %s", hostname)
+	return p.SetHostname(hostname)
+}
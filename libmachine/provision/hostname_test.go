@@ -0,0 +1,22 @@
+package provision
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHostname(t *testing.T) {
+	assert.NoError(t, ValidateHostname("web-1"))
+	assert.NoError(t, ValidateHostname("web-1.internal.example.com"))
+	assert.NoError(t, ValidateHostname("1web"))
+
+	assert.Error(t, ValidateHostname(""))
+	assert.Error(t, ValidateHostname("-web"))
+	assert.Error(t, ValidateHostname("web-"))
+	assert.Error(t, ValidateHostname("web_1"))
+	assert.Error(t, ValidateHostname("web..example.com"))
+	assert.Error(t, ValidateHostname(strings.Repeat("a", 64)))
+	assert.Error(t, ValidateHostname(strings.Repeat("a.", 127)+"ab"))
+}
@@ -0,0 +1,70 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	dockerLogFile        = "/var/log/docker.log"
+	dockerUpstartLogFile = "/var/log/upstart/docker.log"
+)
+
+type logBackend int
+
+const (
+	logBackendFile logBackend = iota
+	logBackendJournald
+	logBackendUpstart
+)
+
+// dockerLogBackender is implemented by the provisioners whose init system
+// doesn't write the daemon logs to /var/log/docker.log.
+type dockerLogBackender interface {
+	dockerLogBackend() logBackend
+}
+
+func (provisioner *SystemdProvisioner) dockerLogBackend() logBackend {
+	return logBackendJournald
+}
+
+func (provisioner *UbuntuProvisioner) dockerLogBackend() logBackend {
+	return logBackendUpstart
+}
+
+// DockerLogsCommand returns the command printing the logs of the docker
+// daemon on the host of the provisioner. When since is not zero, only the
+// entries of that last window are printed, which needs journald.
+func DockerLogsCommand(p Provisioner, follow bool, since time.Duration) (string, error) {
+	backend := logBackendFile
+	if b, ok := p.(dockerLogBackender); ok {
+		backend = b.dockerLogBackend()
+	}
+
+	if backend == logBackendJournald {
+		cmd := "sudo journalctl -u docker --no-pager"
+		if since > 0 {
+			cmd += fmt.Sprintf(" --since \"-%ds\"", int64(since.Seconds()))
+		}
+		if follow {
+			cmd += " -f"
+		}
+		return cmd, nil
+	}
+
+	if since > 0 {
+		return "", errors.New("filtering logs by time is only supported on hosts using journald")
+	}
+
+	logFile := dockerLogFile
+	if backend == logBackendUpstart {
+		logFile = dockerUpstartLogFile
+	}
+
+	if follow {
+		return fmt.Sprintf("sudo tail -n +1 -f %s", logFile), nil
+	}
+
+	return fmt.Sprintf("sudo cat %s", logFile), nil
+}
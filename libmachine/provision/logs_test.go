@@ -0,0 +1,40 @@
+package provision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerLogsCommandJournald(t *testing.T) {
+	p := NewUbuntuSystemdProvisioner(&fakedriver.Driver{})
+
+	cmd, err := DockerLogsCommand(p, false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo journalctl -u docker --no-pager", cmd)
+
+	cmd, err = DockerLogsCommand(p, true, 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo journalctl -u docker --no-pager --since \"-600s\" -f", cmd)
+}
+
+func TestDockerLogsCommandUpstart(t *testing.T) {
+	p := NewUbuntuProvisioner(&fakedriver.Driver{})
+
+	cmd, err := DockerLogsCommand(p, true, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo tail -n +1 -f /var/log/upstart/docker.log", cmd)
+
+	_, err = DockerLogsCommand(p, false, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestDockerLogsCommandFile(t *testing.T) {
+	p := NewBoot2DockerProvisioner(&fakedriver.Driver{})
+
+	cmd, err := DockerLogsCommand(p, false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "sudo cat /var/log/docker.log", cmd)
+}
@@ -0,0 +1,157 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	daemonConfigFile = "/etc/docker/daemon.json"
+
+	// MinEngineMTU is the minimum size of the IPv4 datagrams every host must
+	// accept.
+	MinEngineMTU = 576
+	// MaxEngineMTU is the largest jumbo frame size of the common NICs.
+	MaxEngineMTU = 9216
+
+	bridgeMTUOption = "com.docker.network.driver.mtu"
+
+	// defaultNetworkOptsVersion is the first engine version supporting the
+	// default-network-opts option of daemon.json.
+	defaultNetworkOptsVersion = 24
+)
+
+// ValidateEngineMTU checks the value of --engine-mtu, and that the MTU isn't
+// also set in the daemon flags, with --engine-opt or the engine flag file,
+// which the daemon refuses.
+func ValidateEngineMTU(mtu int, engineOpts []string) error {
+	if mtu == 0 {
+		return nil
+	}
+
+	if mtu < MinEngineMTU || mtu > MaxEngineMTU {
+		return fmt.Errorf("invalid MTU %d, must be between %d and %d", mtu, MinEngineMTU, MaxEngineMTU)
+	}
+
+	for _, opt := range engineOpts {
+		if name, _, _ := strings.Cut(opt, "="); name == "mtu" {
+			return fmt.Errorf("the MTU can't be set with both --engine-mtu and the daemon flag --%s", opt)
+		}
+	}
+
+	return nil
+}
+
+// updateDaemonConfig applies update to the options of daemon.json on the
+// machine, keeping the other ones. The next restart of the daemon loads it.
+func updateDaemonConfig(p Provisioner, update func(config map[string]interface{})) error {
+	output, err := p.SSHCommand(fmt.Sprintf("sudo cat %s 2>/dev/null || true", daemonConfigFile))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", daemonConfigFile, err)
+	}
+
+	config, err := updatedDaemonConfig(output, update)
+	if err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("sudo mkdir -p /etc/docker && printf '%%s' '%s' | sudo tee %s", strings.ReplaceAll(config, "'", `'\''`), daemonConfigFile)
+	if output, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error writing %s: %s: %s", daemonConfigFile, err, output)
+	}
+
+	return nil
+}
+
+// updatedDaemonConfig returns the daemon.json content with update applied.
+func updatedDaemonConfig(content string, update func(config map[string]interface{})) (string, error) {
+	config := map[string]interface{}{}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &config); err != nil {
+			return "", fmt.Errorf("invalid %s on the machine: %s", daemonConfigFile, err)
+		}
+	}
+
+	update(config)
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data) + "\n", nil
+}
+
+// configureEngineMTU sets the MTU of the default bridge in daemon.json, and
+// the default MTU of the bridge networks created afterwards on the engines
+// supporting it.
+func configureEngineMTU(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.MTU == 0 {
+		return nil
+	}
+
+	log.Infof("Setting the MTU of the Docker networks to %d...", engineOptions.MTU)
+
+	version, _ := p.SSHCommand("docker version --format '{{.Client.Version}}'")
+
+	return updateDaemonConfig(p, daemonMTU(engineOptions.MTU, engineMajorVersion(version) >= defaultNetworkOptsVersion))
+}
+
+// daemonMTU returns the update of daemon.json setting the MTU.
+func daemonMTU(mtu int, defaultNetworkOpts bool) func(config map[string]interface{}) {
+	return func(config map[string]interface{}) {
+		config["mtu"] = mtu
+
+		if !defaultNetworkOpts {
+			return
+		}
+
+		opts, _ := config["default-network-opts"].(map[string]interface{})
+		if opts == nil {
+			opts = map[string]interface{}{}
+		}
+		bridge, _ := opts["bridge"].(map[string]interface{})
+		if bridge == nil {
+			bridge = map[string]interface{}{}
+		}
+		bridge[bridgeMTUOption] = strconv.Itoa(mtu)
+		opts["bridge"] = bridge
+		config["default-network-opts"] = opts
+	}
+}
+
+// engineMajorVersion returns the major version of an engine version, or 0
+// when it can't be parsed.
+func engineMajorVersion(version string) int {
+	major, _, _ := strings.Cut(strings.TrimSpace(version), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// checkEngineMTU checks that the default bridge uses the MTU once the daemon
+// is up.
+func checkEngineMTU(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.MTU == 0 {
+		return nil
+	}
+
+	output, err := p.SSHCommand(fmt.Sprintf("sudo docker network inspect bridge --format '{{index .Options %q}}'", bridgeMTUOption))
+	if err != nil {
+		return fmt.Errorf("error checking the MTU of the bridge network: %s", err)
+	}
+
+	if mtu := strings.TrimSpace(output); mtu != strconv.Itoa(engineOptions.MTU) {
+		return fmt.Errorf("the MTU of the bridge network is %q instead of %d", mtu, engineOptions.MTU)
+	}
+
+	return nil
+}
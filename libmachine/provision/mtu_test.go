@@ -0,0 +1,54 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEngineMTU(t *testing.T) {
+	assert.NoError(t, ValidateEngineMTU(0, nil))
+	assert.NoError(t, ValidateEngineMTU(1400, []string{"log-level=debug"}))
+	assert.EqualError(t, ValidateEngineMTU(100, nil), "invalid MTU 100, must be between 576 and 9216")
+	assert.EqualError(t, ValidateEngineMTU(10000, nil), "invalid MTU 10000, must be between 576 and 9216")
+	assert.EqualError(t, ValidateEngineMTU(1400, []string{"mtu=1450"}), "the MTU can't be set with both --engine-mtu and the daemon flag --mtu=1450")
+}
+
+func TestDaemonMTU(t *testing.T) {
+	config, err := updatedDaemonConfig("", daemonMTU(1400, false))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"mtu\": 1400\n}\n", config)
+
+	config, err = updatedDaemonConfig(`{"runtimes": {"nvidia": {"path": "nvidia-container-runtime"}}, "mtu": 1500}`, daemonMTU(1400, true))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"runtimes": {"nvidia": {"path": "nvidia-container-runtime"}},
+		"mtu": 1400,
+		"default-network-opts": {"bridge": {"com.docker.network.driver.mtu": "1400"}}
+	}`, config)
+
+	_, err = updatedDaemonConfig("{", daemonMTU(1400, false))
+	assert.Error(t, err)
+}
+
+func TestEngineMajorVersion(t *testing.T) {
+	assert.Equal(t, 24, engineMajorVersion("24.0.7\n"))
+	assert.Equal(t, 20, engineMajorVersion("20.10.24"))
+	assert.Equal(t, 0, engineMajorVersion(""))
+}
+
+func TestCheckEngineMTU(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			`sudo docker network inspect bridge --format '{{index .Options "com.docker.network.driver.mtu"}}'`: "1400\n",
+		},
+	}
+
+	assert.NoError(t, checkEngineMTU(p, engine.Options{MTU: 1400}))
+	assert.EqualError(t, checkEngineMTU(p, engine.Options{MTU: 1300}), `the MTU of the bridge network is "1400" instead of 1300`)
+	assert.NoError(t, checkEngineMTU(p, engine.Options{}))
+}
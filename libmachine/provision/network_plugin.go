@@ -0,0 +1,231 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	// networkPluginManagedPrefix prefixes the reference of any Docker managed
+	// network plugin given to --engine-network-plugin.
+	networkPluginManagedPrefix = "plugin:"
+
+	networkPluginScriptPath = "/tmp/machine_network_plugin.sh"
+)
+
+// networkPluginSetup is a built-in setup of --engine-network-plugin, either a
+// Docker managed plugin or distribution packages.
+type networkPluginSetup struct {
+	// plugin is the reference of the managed plugin to install.
+	plugin string
+	// network is a network the plugin creates, checked with docker network ls.
+	network string
+	// packages are the packages to install, by package manager.
+	packages map[string]string
+	// services are the services of the packages to enable, by package manager.
+	services map[string]string
+}
+
+var networkPluginSetups = map[string]networkPluginSetup{
+	"weave": {
+		plugin:  "weaveworks/net-plugin:latest_release",
+		network: "weave",
+	},
+	"openvswitch": {
+		packages: map[string]string{
+			"apt-get": "openvswitch-switch",
+			"dnf":     "openvswitch",
+			"yum":     "openvswitch",
+			"zypper":  "openvswitch",
+		},
+		services: map[string]string{
+			"apt-get": "openvswitch-switch",
+			"dnf":     "openvswitch",
+			"yum":     "openvswitch",
+			"zypper":  "openvswitch",
+		},
+	},
+}
+
+// packageInstallCommands install a package, by package manager.
+var packageInstallCommands = []struct {
+	packageManager string
+	command        string
+}{
+	{"apt-get", "sudo apt-get update && sudo DEBIAN_FRONTEND=noninteractive apt-get install -y %s"},
+	{"dnf", "sudo dnf install -y %s"},
+	{"yum", "sudo yum install -y %s"},
+	{"zypper", "sudo zypper --non-interactive install %s"},
+}
+
+// NetworkPlugins returns the names of the built-in setups of
+// --engine-network-plugin.
+func NetworkPlugins() []string {
+	names := []string{}
+	for name := range networkPluginSetups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ValidateNetworkPlugin checks the value of --engine-network-plugin, a
+// built-in setup or plugin:<reference> of a managed plugin.
+func ValidateNetworkPlugin(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if ref, ok := strings.CutPrefix(name, networkPluginManagedPrefix); ok {
+		if ref == "" || strings.ContainsAny(ref, " '\"\n") {
+			return fmt.Errorf("invalid network plugin reference %q", ref)
+		}
+		return nil
+	}
+
+	if _, ok := networkPluginSetups[name]; !ok {
+		return fmt.Errorf("unknown network plugin %q, must be %s or %s<reference>", name, strings.Join(NetworkPlugins(), ", "), networkPluginManagedPrefix)
+	}
+
+	return nil
+}
+
+func networkPluginSetupOf(name string) networkPluginSetup {
+	if ref, ok := strings.CutPrefix(name, networkPluginManagedPrefix); ok {
+		return networkPluginSetup{plugin: ref}
+	}
+
+	return networkPluginSetups[name]
+}
+
+// configureNetworkPlugin sets up the network plugin and runs the network
+// plugin script once the daemon is up, then checks that the daemon still
+// lists its networks.
+func configureNetworkPlugin(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.NetworkPlugin == "" && engineOptions.NetworkPluginScript == "" {
+		return nil
+	}
+
+	if engineOptions.NetworkPlugin != "" {
+		log.Infof("Setting up the %s network plugin...", engineOptions.NetworkPlugin)
+		if err := setupNetworkPlugin(p, networkPluginSetupOf(engineOptions.NetworkPlugin)); err != nil {
+			return err
+		}
+	}
+
+	if engineOptions.NetworkPluginScript != "" {
+		log.Infof("Running the network plugin script %s...", engineOptions.NetworkPluginScript)
+		if err := runNetworkPluginScript(p, engineOptions.NetworkPluginScript); err != nil {
+			return err
+		}
+	}
+
+	return checkNetworkPlugin(p, networkPluginSetupOf(engineOptions.NetworkPlugin))
+}
+
+func setupNetworkPlugin(p Provisioner, setup networkPluginSetup) error {
+	if len(setup.packages) > 0 {
+		if err := installNetworkPluginPackages(p, setup); err != nil {
+			return err
+		}
+	}
+
+	if setup.plugin != "" {
+		if _, err := p.SSHCommand(fmt.Sprintf("sudo docker plugin inspect %s", setup.plugin)); err == nil {
+			log.Debugf("Network plugin %s is already installed", setup.plugin)
+			return nil
+		}
+
+		if output, err := p.SSHCommand(fmt.Sprintf("sudo docker plugin install --grant-all-permissions %s", setup.plugin)); err != nil {
+			return fmt.Errorf("error installing the network plugin %s: %s: %s", setup.plugin, err, strings.TrimSpace(output))
+		}
+	}
+
+	return nil
+}
+
+func installNetworkPluginPackages(p Provisioner, setup networkPluginSetup) error {
+	for _, install := range packageInstallCommands {
+		pkg, ok := setup.packages[install.packageManager]
+		if !ok {
+			continue
+		}
+		if _, err := p.SSHCommand("command -v " + install.packageManager); err != nil {
+			continue
+		}
+
+		if output, err := p.SSHCommand(fmt.Sprintf(install.command, pkg)); err != nil {
+			return fmt.Errorf("error installing %s: %s: %s", pkg, err, strings.TrimSpace(output))
+		}
+
+		if service, ok := setup.services[install.packageManager]; ok {
+			if output, err := p.SSHCommand(fmt.Sprintf("sudo systemctl enable --now %s", service)); err != nil {
+				return fmt.Errorf("error starting %s: %s: %s", service, err, strings.TrimSpace(output))
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("the network plugin can't be set up on %s, it has none of the supported package managers", p.String())
+}
+
+func runNetworkPluginScript(p Provisioner, scriptFile string) error {
+	script, err := os.ReadFile(scriptFile)
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %v", scriptFile, err)
+	}
+
+	if output, err := p.SSHCommand(fmt.Sprintf("cat <<'OEOF' >%s\n%s\nOEOF", networkPluginScriptPath, string(script))); err != nil {
+		return fmt.Errorf("error uploading the network plugin script: %s: %s", err, output)
+	}
+
+	output, err := p.SSHCommand(fmt.Sprintf("sudo sh %[1]s; status=$?; rm -f %[1]s; exit $status", networkPluginScriptPath))
+	if err != nil {
+		return fmt.Errorf("error running the network plugin script: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// checkNetworkPlugin checks that the daemon lists its networks, including
+// the network created by the plugin when it creates one, and that a managed
+// plugin is enabled.
+func checkNetworkPlugin(p Provisioner, setup networkPluginSetup) error {
+	output, err := p.SSHCommand("sudo docker network ls --format '{{.Name}}'")
+	if err != nil {
+		return fmt.Errorf("error listing the networks after setting up the network plugin: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	if setup.network != "" && !containsLine(output, setup.network) {
+		return fmt.Errorf("the %s network is not listed by docker network ls", setup.network)
+	}
+
+	if setup.plugin != "" {
+		enabled, err := p.SSHCommand(fmt.Sprintf("sudo docker plugin inspect --format '{{.Enabled}}' %s", setup.plugin))
+		if err != nil {
+			return fmt.Errorf("error checking the network plugin %s: %s", setup.plugin, err)
+		}
+		if strings.TrimSpace(enabled) != "true" {
+			return fmt.Errorf("the network plugin %s is not enabled", setup.plugin)
+		}
+	}
+
+	log.Info("The network plugin is set up")
+	return nil
+}
+
+func containsLine(output, line string) bool {
+	for _, l := range strings.Split(output, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+
+	return false
+}
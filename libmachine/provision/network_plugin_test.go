@@ -0,0 +1,69 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+const networkLsCommand = "sudo docker network ls --format '{{.Name}}'"
+
+func TestValidateNetworkPlugin(t *testing.T) {
+	assert.NoError(t, ValidateNetworkPlugin(""))
+	assert.NoError(t, ValidateNetworkPlugin("weave"))
+	assert.NoError(t, ValidateNetworkPlugin("plugin:vieux/sshfs:latest"))
+	assert.EqualError(t, ValidateNetworkPlugin("plugin:"), `invalid network plugin reference ""`)
+	assert.EqualError(t, ValidateNetworkPlugin("flannel"), `unknown network plugin "flannel", must be openvswitch, weave or plugin:<reference>`)
+}
+
+func TestConfigureNetworkPluginWeave(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"sudo docker plugin install --grant-all-permissions weaveworks/net-plugin:latest_release": "",
+			"sudo docker plugin inspect --format '{{.Enabled}}' weaveworks/net-plugin:latest_release": "true\n",
+			networkLsCommand: "bridge\nhost\nnone\nweave\n",
+		},
+	}
+
+	assert.NoError(t, configureNetworkPlugin(p, engine.Options{NetworkPlugin: "weave"}))
+}
+
+func TestConfigureNetworkPluginOpenvswitch(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"command -v apt-get": "/usr/bin/apt-get\n",
+			"sudo apt-get update && sudo DEBIAN_FRONTEND=noninteractive apt-get install -y openvswitch-switch": "",
+			"sudo systemctl enable --now openvswitch-switch":                                                   "",
+			networkLsCommand: "bridge\nhost\nnone\n",
+		},
+	}
+
+	assert.NoError(t, configureNetworkPlugin(p, engine.Options{NetworkPlugin: "openvswitch"}))
+}
+
+func TestCheckNetworkPluginMissingNetwork(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{networkLsCommand: "bridge\nhost\nnone\n"},
+	}
+
+	err := checkNetworkPlugin(p, networkPluginSetupOf("weave"))
+
+	assert.EqualError(t, err, "the weave network is not listed by docker network ls")
+	assert.NoError(t, configureNetworkPlugin(p, engine.Options{}))
+}
+
+func TestConfigureRunningEngine(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{networkLsCommand: "bridge\nhost\nnone\n"},
+	}
+
+	assert.Error(t, configureRunningEngine(p, engine.Options{NetworkPlugin: "weave"}))
+	assert.NoError(t, configureRunningEngine(p, engine.Options{NetworkPlugin: "weave", Rootless: true}))
+}
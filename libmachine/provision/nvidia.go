@@ -0,0 +1,116 @@
+package provision
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	// NvidiaRuntimeAvailable registers the nvidia runtime beside runc.
+	NvidiaRuntimeAvailable = "available"
+	// NvidiaRuntimeDefault registers the nvidia runtime as the default one.
+	NvidiaRuntimeDefault = "default"
+
+	nvidiaRepoURL = "https://nvidia.github.io/libnvidia-container"
+)
+
+// nvidiaToolkitInstallCommands are the commands adding the NVIDIA repository
+// and installing nvidia-container-toolkit, by package manager.
+var nvidiaToolkitInstallCommands = []struct {
+	packageManager string
+	command        string
+}{
+	{"apt-get", fmt.Sprintf("curl -fsSL %[1]s/gpgkey | sudo gpg --batch --yes --dearmor -o /usr/share/keyrings/nvidia-container-toolkit-keyring.gpg && "+
+		"curl -fsSL %[1]s/stable/deb/nvidia-container-toolkit.list | sed 's#deb https://#deb [signed-by=/usr/share/keyrings/nvidia-container-toolkit-keyring.gpg] https://#g' | sudo tee /etc/apt/sources.list.d/nvidia-container-toolkit.list && "+
+		"sudo apt-get update && sudo DEBIAN_FRONTEND=noninteractive apt-get install -y nvidia-container-toolkit", nvidiaRepoURL)},
+	{"dnf", fmt.Sprintf("curl -fsSL %s/stable/rpm/nvidia-container-toolkit.repo | sudo tee /etc/yum.repos.d/nvidia-container-toolkit.repo && sudo dnf install -y nvidia-container-toolkit", nvidiaRepoURL)},
+	{"yum", fmt.Sprintf("curl -fsSL %s/stable/rpm/nvidia-container-toolkit.repo | sudo tee /etc/yum.repos.d/nvidia-container-toolkit.repo && sudo yum install -y nvidia-container-toolkit", nvidiaRepoURL)},
+	{"zypper", fmt.Sprintf("sudo zypper --non-interactive ar -f %s/stable/rpm/nvidia-container-toolkit.repo && sudo zypper --non-interactive --gpg-auto-import-keys install nvidia-container-toolkit", nvidiaRepoURL)},
+}
+
+// ValidateNvidiaRuntime checks the value of --engine-nvidia-runtime.
+func ValidateNvidiaRuntime(mode string) error {
+	if mode != "" && mode != NvidiaRuntimeAvailable && mode != NvidiaRuntimeDefault {
+		return fmt.Errorf("invalid nvidia runtime %q, must be %s or %s", mode, NvidiaRuntimeAvailable, NvidiaRuntimeDefault)
+	}
+
+	return nil
+}
+
+// configureNvidiaRuntime installs nvidia-container-toolkit and registers the
+// nvidia runtime in daemon.json, which the next restart of the daemon loads.
+// The NVIDIA driver must be installed by the image of the machine.
+func configureNvidiaRuntime(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.NvidiaRuntime == "" {
+		return nil
+	}
+
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return fmt.Errorf("the nvidia runtime is not supported on %s", p.String())
+	}
+
+	if output, err := p.SSHCommand("nvidia-smi -L"); err != nil {
+		return fmt.Errorf("the nvidia runtime needs the NVIDIA driver, nvidia-smi failed on the machine, use a GPU instance with an image including the driver: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	if _, err := p.SSHCommand("command -v nvidia-ctk"); err != nil {
+		log.Info("Installing the NVIDIA container toolkit...")
+		if err := installNvidiaToolkit(p); err != nil {
+			return err
+		}
+	}
+
+	command := "sudo nvidia-ctk runtime configure --runtime=docker"
+	if engineOptions.NvidiaRuntime == NvidiaRuntimeDefault {
+		command += " --set-as-default"
+	}
+	if output, err := p.SSHCommand(command); err != nil {
+		return fmt.Errorf("error registering the nvidia runtime: %s: %s", err, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+func installNvidiaToolkit(p Provisioner) error {
+	for _, install := range nvidiaToolkitInstallCommands {
+		if _, err := p.SSHCommand("command -v " + install.packageManager); err != nil {
+			continue
+		}
+
+		if output, err := p.SSHCommand(install.command); err != nil {
+			return fmt.Errorf("error installing the NVIDIA container toolkit: %s: %s", err, strings.TrimSpace(output))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("the NVIDIA container toolkit can't be installed on %s, it has none of the supported package managers", p.String())
+}
+
+// checkNvidiaRuntime checks that the daemon lists the nvidia runtime, as its
+// default runtime when requested.
+func checkNvidiaRuntime(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.NvidiaRuntime == "" {
+		return nil
+	}
+
+	output, err := p.SSHCommand("sudo docker info --format '{{range $name, $runtime := .Runtimes}}{{$name}} {{end}}/{{.DefaultRuntime}}'")
+	if err != nil {
+		return fmt.Errorf("error checking the nvidia runtime: %s", err)
+	}
+
+	list, defaultRuntime, _ := strings.Cut(strings.TrimSpace(output), "/")
+	runtimes := strings.Fields(list)
+	if !slices.Contains(runtimes, "nvidia") {
+		return fmt.Errorf("the nvidia runtime is not listed by docker info, the runtimes are: %s", strings.Join(runtimes, ", "))
+	}
+	if engineOptions.NvidiaRuntime == NvidiaRuntimeDefault && defaultRuntime != "nvidia" {
+		return fmt.Errorf("the default runtime is %s instead of nvidia", defaultRuntime)
+	}
+
+	log.Info("The nvidia runtime is available")
+	return nil
+}
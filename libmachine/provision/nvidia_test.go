@@ -0,0 +1,57 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+const nvidiaDockerInfoCommand = "sudo docker info --format '{{range $name, $runtime := .Runtimes}}{{$name}} {{end}}/{{.DefaultRuntime}}'"
+
+func TestValidateNvidiaRuntime(t *testing.T) {
+	assert.NoError(t, ValidateNvidiaRuntime(""))
+	assert.NoError(t, ValidateNvidiaRuntime(NvidiaRuntimeDefault))
+	assert.EqualError(t, ValidateNvidiaRuntime("nvidia"), `invalid nvidia runtime "nvidia", must be available or default`)
+}
+
+func TestConfigureNvidiaRuntime(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			"nvidia-smi -L":                         "GPU 0: Tesla T4\n",
+			"command -v apt-get":                    "/usr/bin/apt-get\n",
+			nvidiaToolkitInstallCommands[0].command: "",
+			"sudo nvidia-ctk runtime configure --runtime=docker --set-as-default": "",
+		},
+	}
+
+	assert.NoError(t, configureNvidiaRuntime(p, engine.Options{NvidiaRuntime: NvidiaRuntimeDefault}))
+}
+
+func TestConfigureNvidiaRuntimeWithoutDriver(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{}
+
+	err := configureNvidiaRuntime(p, engine.Options{NvidiaRuntime: NvidiaRuntimeAvailable})
+
+	assert.ErrorContains(t, err, "the nvidia runtime needs the NVIDIA driver")
+	assert.NoError(t, configureNvidiaRuntime(p, engine.Options{}))
+}
+
+func TestCheckNvidiaRuntime(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{nvidiaDockerInfoCommand: "nvidia runc /runc\n"},
+	}
+
+	assert.NoError(t, checkNvidiaRuntime(p, engine.Options{NvidiaRuntime: NvidiaRuntimeAvailable}))
+	assert.EqualError(t, checkNvidiaRuntime(p, engine.Options{NvidiaRuntime: NvidiaRuntimeDefault}), "the default runtime is runc instead of nvidia")
+
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{nvidiaDockerInfoCommand: "io.containerd.runc.v2 runc /runc\n"},
+	}
+	assert.EqualError(t, checkNvidiaRuntime(p, engine.Options{NvidiaRuntime: NvidiaRuntimeAvailable}), "the nvidia runtime is not listed by docker info, the runtimes are: io.containerd.runc.v2, runc")
+}
@@ -0,0 +1,189 @@
+package provision
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const (
+	aptMirrorAuthFile = "/etc/apt/auth.conf.d/90-machine-mirror.conf"
+
+	// aptMirrorSources are the sources of the default repositories of Debian
+	// and Ubuntu.
+	aptMirrorSources = "/etc/apt/sources.list /etc/apt/sources.list.d/*.list /etc/apt/sources.list.d/*.sources"
+
+	// aptRepositoryHosts matches the scheme and host of the default
+	// repositories of Debian and Ubuntu.
+	aptRepositoryHosts = `https\?://\(deb\.debian\.org\|security\.debian\.org\|\([a-z.]*\.\)\?archive\.ubuntu\.com\|security\.ubuntu\.com\)`
+
+	// yumRepositoryURLs matches the scheme and host of the commented out base
+	// URLs of the default repositories of the RPM based distributions, which
+	// use mirror lists instead.
+	yumRepositoryURLs = `^#\?baseurl=https\?://[^/]*`
+
+	// yumMirrorAuthMarker precedes the credentials added to the [main]
+	// section of the dnf/yum configuration.
+	yumMirrorAuthMarker = "# machine package mirror"
+)
+
+// ValidatePackageMirror checks the URL of the package mirror and its
+// user:password credentials.
+func ValidatePackageMirror(mirror, auth string) error {
+	if mirror == "" {
+		if auth != "" {
+			return fmt.Errorf("the package mirror credentials need a package mirror")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(mirror)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return fmt.Errorf("invalid package mirror %q, must be an http(s)://host[:port][/path] URL without credentials", mirror)
+	}
+	if strings.ContainsAny(mirror, " '\"#\\\n") {
+		return fmt.Errorf("invalid package mirror %q, it can't contain quotes, spaces, # or backslashes", mirror)
+	}
+
+	if auth != "" {
+		user, password, ok := strings.Cut(auth, ":")
+		if !ok || user == "" || password == "" {
+			return fmt.Errorf("invalid package mirror credentials, must be user:password")
+		}
+		if strings.ContainsAny(auth, " '\"\\\n") {
+			return fmt.Errorf("invalid package mirror credentials, they can't contain quotes, spaces or backslashes")
+		}
+	}
+
+	return nil
+}
+
+// configurePackageMirror makes apt or dnf/yum get the packages of the
+// distribution from the package mirror, with its credentials. The mirror
+// replaces the host of the default repositories, their paths are kept.
+func configurePackageMirror(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.PackageMirror == "" {
+		return nil
+	}
+
+	log.RegisterSecret(mirrorPassword(engineOptions.PackageMirrorAuth))
+	log.RegisterSecret(sedEscape(mirrorPassword(engineOptions.PackageMirrorAuth)))
+	log.Infof("Configuring the package mirror %s...", engineOptions.PackageMirror)
+
+	mirror := strings.TrimSuffix(engineOptions.PackageMirror, "/")
+
+	switch packageManager(p) {
+	case "apt-get":
+		return runMirrorCommands(p, aptMirrorCommands(mirror, engineOptions.PackageMirrorAuth))
+	case "dnf", "yum":
+		return runMirrorCommands(p, yumMirrorCommands(mirror, engineOptions.PackageMirrorAuth, yumConfFile(p)))
+	}
+
+	return fmt.Errorf("the package mirror is not supported on %s, it has neither apt nor dnf/yum", p.String())
+}
+
+// removePackageMirrorAuth removes the credentials of the package mirror from
+// the machine once it is provisioned, when they are ephemeral.
+func removePackageMirrorAuth(p Provisioner, engineOptions engine.Options) {
+	if engineOptions.PackageMirror == "" || engineOptions.PackageMirrorAuth == "" || !engineOptions.PackageMirrorEphemeral {
+		return
+	}
+
+	log.Info("Removing the package mirror credentials...")
+
+	var cmd string
+	switch packageManager(p) {
+	case "apt-get":
+		cmd = fmt.Sprintf("sudo rm -f %s", aptMirrorAuthFile)
+	case "dnf", "yum":
+		cmd = fmt.Sprintf(`sudo sed -i '/^%s$/,+2d' %s`, yumMirrorAuthMarker, yumConfFile(p))
+	default:
+		return
+	}
+
+	if _, err := p.SSHCommand(cmd); err != nil {
+		log.Warnf("Unable to remove the package mirror credentials, remove them from the machine: %s", err)
+	}
+}
+
+func packageManager(p Provisioner) string {
+	for _, pm := range []string{"apt-get", "dnf", "yum"} {
+		if _, err := p.SSHCommand("command -v " + pm); err == nil {
+			return pm
+		}
+	}
+
+	return ""
+}
+
+func yumConfFile(p Provisioner) string {
+	if _, err := p.SSHCommand("command -v dnf"); err == nil {
+		return "/etc/dnf/dnf.conf"
+	}
+
+	return "/etc/yum.conf"
+}
+
+func aptMirrorCommands(mirror, auth string) []string {
+	commands := []string{
+		fmt.Sprintf(`for f in %s; do [ -f "$f" ] && sudo sed -i 's#%s#%s#g' "$f"; done; true`, aptMirrorSources, aptRepositoryHosts, sedEscape(mirror)),
+	}
+
+	if auth != "" {
+		user, password, _ := strings.Cut(auth, ":")
+		host := mirrorHost(mirror)
+		commands = append(commands, fmt.Sprintf("sudo mkdir -p /etc/apt/auth.conf.d && printf 'machine %%s login %%s password %%s\\n' '%s' '%s' '%s' | sudo tee %s >/dev/null && sudo chmod 600 %s",
+			host, user, password, aptMirrorAuthFile, aptMirrorAuthFile))
+	}
+
+	return commands
+}
+
+func yumMirrorCommands(mirror, auth, confFile string) []string {
+	commands := []string{
+		fmt.Sprintf(`for f in /etc/yum.repos.d/*.repo; do [ -f "$f" ] && sudo sed -i -e 's/^\(mirrorlist\|metalink\)=/#&/' -e 's#%s#baseurl=%s#' "$f"; done; true`, yumRepositoryURLs, sedEscape(mirror)),
+	}
+
+	if auth != "" {
+		user, password, _ := strings.Cut(auth, ":")
+		commands = append(commands, fmt.Sprintf(`sudo sed -i '/^\[main\]/a %s\nusername=%s\npassword=%s' %s`, yumMirrorAuthMarker, sedEscape(user), sedEscape(password), confFile))
+	}
+
+	return commands
+}
+
+func runMirrorCommands(p Provisioner, commands []string) error {
+	for _, cmd := range commands {
+		// The error holds the command and its credentials, it is only
+		// logged with them redacted.
+		if _, err := p.SSHCommand(cmd); err != nil {
+			log.Debugf("Error configuring the package mirror: %s", err)
+			return fmt.Errorf("error configuring the package mirror, run with --debug for details")
+		}
+	}
+
+	return nil
+}
+
+func mirrorHost(mirror string) string {
+	u, err := url.Parse(mirror)
+	if err != nil {
+		return mirror
+	}
+
+	return u.Host
+}
+
+func mirrorPassword(auth string) string {
+	_, password, _ := strings.Cut(auth, ":")
+	return password
+}
+
+// sedEscape escapes the characters special in the replacements of the sed
+// commands of the mirror.
+func sedEscape(s string) string {
+	return strings.NewReplacer("&", `\&`, "#", `\#`).Replace(s)
+}
@@ -70,22 +70,26 @@ func (provisioner *PhotonOSProvisioner) GenerateDockerOptions(dockerPort int) (*
 	driverNameLabel := fmt.Sprintf("provider=%s", provisioner.Driver.DriverName())
 	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
 
-	engineConfigTmpl := `[Service]
+	engineConfigTmpl := `{{ if .EngineOptions.GraphDir }}[Unit]
+RequiresMountsFor={{.EngineOptions.GraphDir}}
+
+{{ end }}[Service]
 ExecStart=
 ExecStart=/usr/bin/dockerd \\
           --host=fd:// \\
-          --exec-opt native.cgroupdriver=systemd \\
+          --exec-opt native.cgroupdriver={{ or .EngineOptions.CgroupDriver "systemd" }}{{ if not .EngineOptions.SSHTransport }} \\
           --host=tcp://0.0.0.0:{{.DockerPort}} \\
           --tlsverify \\
           --tlscacert {{.AuthOptions.CaCertRemotePath}} \\
           --tlscert {{.AuthOptions.ServerCertRemotePath}} \\
-          --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ range .EngineOptions.Labels }} \\
+          --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ end }}{{ if .EngineOptions.MetricsAddr }} \\
+          --metrics-addr {{.EngineOptions.MetricsAddr}}{{ end }}{{ range .EngineOptions.Labels }} \\
           --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} \\
           --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} \\
           --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} \\
           -{{.}}{{ end }} \\
           \$OPTIONS
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+EnvironmentFile=-` + engineEnvFile + `
 `
 
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
@@ -138,7 +142,7 @@ func (provisioner *PhotonOSProvisioner) Provision(swarmOptions swarm.Options, au
 	provisioner.AuthOptions = authOptions
 	provisioner.EngineOptions = engineOptions
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -161,6 +165,10 @@ func (provisioner *PhotonOSProvisioner) Provision(swarmOptions swarm.Options, au
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debugf("Preparing certificates")
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
@@ -169,6 +177,10 @@ func (provisioner *PhotonOSProvisioner) Provision(swarmOptions swarm.Options, au
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debug("Configuring swarm")
 	err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 
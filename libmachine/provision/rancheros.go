@@ -106,8 +106,7 @@ func (provisioner *RancherProvisioner) Provision(swarmOptions swarm.Options, aut
 		return fmt.Errorf("Unsupported storage driver: %s", provisioner.EngineOptions.StorageDriver)
 	}
 
-	log.Debugf("Setting hostname %s", provisioner.Driver.GetMachineName())
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
@@ -127,6 +126,10 @@ func (provisioner *RancherProvisioner) Provision(swarmOptions swarm.Options, aut
 		}
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debugf("Preparing certificates")
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
@@ -135,6 +138,10 @@ func (provisioner *RancherProvisioner) Provision(swarmOptions swarm.Options, aut
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	log.Debugf("Configuring swarm")
 	err := configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
@@ -19,10 +19,13 @@ import (
 
 var (
 	ErrUnknownYumOsRelease = errors.New("unknown OS for Yum repository")
-	engineConfigTemplate   = `[Service]
+	engineConfigTemplate   = `{{ if .EngineOptions.GraphDir }}[Unit]
+RequiresMountsFor={{.EngineOptions.GraphDir}}
+
+{{ end }}[Service]
 ExecStart=
-ExecStart=/usr/bin/dockerd -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+ExecStart=/usr/bin/dockerd{{ if not .EngineOptions.SSHTransport }} -H tcp://0.0.0.0:{{.DockerPort}}{{ end }} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} {{ if .EngineOptions.MetricsAddr }}--metrics-addr {{.EngineOptions.MetricsAddr}} {{ end }}{{ if not .EngineOptions.SSHTransport }}--tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ end }}{{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+EnvironmentFile=-` + engineEnvFile + `
 `
 	majorVersionRE = regexp.MustCompile(`^(\d+)(\..*)?`)
 )
@@ -155,10 +158,15 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 	}
 	provisioner.EngineOptions.StorageDriver = storageDriver
 
-	if err := provisioner.SetHostname(provisioner.Driver.GetMachineName()); err != nil {
+	if err := setHostname(provisioner, engineOptions); err != nil {
 		return err
 	}
 
+	if err := configurePackageMirror(provisioner, engineOptions); err != nil {
+		return err
+	}
+	defer removePackageMirrorAuth(provisioner, engineOptions)
+
 	for _, pkg := range provisioner.Packages {
 		log.Debugf("installing base package: name=%s", pkg)
 		if err := provisioner.Package(pkg, pkgaction.Install); err != nil {
@@ -166,7 +174,7 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 		}
 	}
 
-	if err := installDockerGeneric(provisioner, provisioner.EngineOptions.InstallURL); err != nil {
+	if err := installDockerGeneric(provisioner, provisioner.EngineOptions); err != nil {
 		return err
 	} else if err == nil {
 		if err := provisioner.Service("docker", serviceaction.Restart); err != nil {
@@ -185,12 +193,20 @@ func (provisioner *RedHatProvisioner) Provision(swarmOptions swarm.Options, auth
 		return err
 	}
 
+	if err := configureHost(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	provisioner.AuthOptions = setRemoteAuthOptions(provisioner)
 
 	if err := ConfigureAuth(provisioner); err != nil {
 		return err
 	}
 
+	if err := configureRunningEngine(provisioner, provisioner.EngineOptions); err != nil {
+		return err
+	}
+
 	err = configureSwarm(provisioner, swarmOptions, provisioner.AuthOptions)
 	return err
 }
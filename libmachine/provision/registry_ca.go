@@ -0,0 +1,147 @@
+package provision
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const registryCertsDir = "/etc/docker/certs.d"
+
+// ParseRegistryCAFiles parses the <registry>=<file> entries of the CA files
+// trusted for pulls from a registry. It checks that the files hold PEM
+// certs, and returns the entries with the registry reduced to its host[:port]
+// and the absolute path of the file.
+func ParseRegistryCAFiles(entries []string) ([]string, error) {
+	parsed := []string{}
+	for _, entry := range entries {
+		registry, file, err := splitRegistryCAFile(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err = filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s: %v", file, err)
+		}
+		if err := validatePEMCerts(content); err != nil {
+			return nil, fmt.Errorf("invalid CA file %s: %v", file, err)
+		}
+
+		parsed = append(parsed, registry+"="+file)
+	}
+
+	return parsed, nil
+}
+
+// splitRegistryCAFile splits a <registry>=<file> entry, the registry being
+// a host[:port] optionally prefixed by a scheme.
+func splitRegistryCAFile(entry string) (string, string, error) {
+	registry, file, ok := strings.Cut(entry, "=")
+	if !ok || registry == "" || file == "" {
+		return "", "", fmt.Errorf("invalid registry CA file %q, must be <registry>=<file>", entry)
+	}
+
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry = strings.TrimSuffix(registry, "/")
+	if registry == "" || strings.ContainsAny(registry, "/ '") {
+		return "", "", fmt.Errorf("invalid registry %q in %q, must be a host[:port]", registry, entry)
+	}
+
+	return registry, file, nil
+}
+
+// validatePEMCerts checks that content holds only PEM encoded certs.
+func validatePEMCerts(content []byte) error {
+	found := false
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block %s", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return nil
+}
+
+// registryCABundles returns the CA bundle of each registry, concatenating
+// the files given for the same registry.
+func registryCABundles(entries []string) (map[string]string, error) {
+	bundles := map[string]string{}
+	for _, entry := range entries {
+		registry, file, err := splitRegistryCAFile(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s: %v", file, err)
+		}
+
+		bundle := bundles[registry]
+		if bundle != "" && !strings.HasSuffix(bundle, "\n") {
+			bundle += "\n"
+		}
+		bundles[registry] = bundle + string(content)
+	}
+
+	return bundles, nil
+}
+
+// configureRegistryCAs writes the CA bundle of each registry to the certs.d
+// directory of the daemon. The bundles replace the ones written by a
+// previous provisioning.
+func configureRegistryCAs(p Provisioner, engineOptions engine.Options) error {
+	if len(engineOptions.RegistryCAFiles) == 0 {
+		return nil
+	}
+
+	bundles, err := registryCABundles(engineOptions.RegistryCAFiles)
+	if err != nil {
+		return err
+	}
+
+	registries := []string{}
+	for registry := range bundles {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+
+	for _, registry := range registries {
+		caPath := path.Join(registryCertsDir, registry, "ca.crt")
+		log.Infof("Copying the CA of registry %s to %s...", registry, caPath)
+
+		cmd := fmt.Sprintf("sudo mkdir -p %s && printf '%%s' '%s' | sudo tee %s", path.Dir(caPath), bundles[registry], caPath)
+		if output, err := p.SSHCommand(cmd); err != nil {
+			return fmt.Errorf("error copying the CA of registry %s: %s: %s", registry, err, output)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package provision
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestCA(t *testing.T, dir, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	file := filepath.Join(dir, name+".pem")
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	return file
+}
+
+func TestParseRegistryCAFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestCA(t, dir, "ca")
+
+	parsed, err := ParseRegistryCAFiles([]string{"https://registry.example.com:5000/=" + ca})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"registry.example.com:5000=" + ca}, parsed)
+
+	_, err = ParseRegistryCAFiles([]string{ca})
+	assert.EqualError(t, err, `invalid registry CA file "`+ca+`", must be <registry>=<file>`)
+
+	_, err = ParseRegistryCAFiles([]string{"registry.example.com/path=" + ca})
+	assert.EqualError(t, err, `invalid registry "registry.example.com/path" in "registry.example.com/path=`+ca+`", must be a host[:port]`)
+
+	notPEM := filepath.Join(dir, "not.pem")
+	assert.NoError(t, os.WriteFile(notPEM, []byte("not a cert"), 0600))
+	_, err = ParseRegistryCAFiles([]string{"registry.example.com=" + notPEM})
+	assert.EqualError(t, err, "invalid CA file "+notPEM+": no PEM encoded certificate found")
+}
+
+func TestRegistryCABundles(t *testing.T) {
+	dir := t.TempDir()
+	ca1 := writeTestCA(t, dir, "ca1")
+	ca2 := writeTestCA(t, dir, "ca2")
+
+	content1, err := os.ReadFile(ca1)
+	assert.NoError(t, err)
+	content2, err := os.ReadFile(ca2)
+	assert.NoError(t, err)
+
+	bundles, err := registryCABundles([]string{
+		"registry.example.com=" + ca1,
+		"other.example.com:5000=" + ca2,
+		"registry.example.com=" + ca2,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"registry.example.com":   string(content1) + string(content2),
+		"other.example.com:5000": string(content2),
+	}, bundles)
+}
@@ -0,0 +1,158 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/provision/pkgaction"
+	"github.com/rancher/machine/libmachine/provision/serviceaction"
+)
+
+// Rootless Docker runs the daemon of the SSH user as a systemd user service,
+// exposed on the Docker port by the port forwarder of rootlesskit. Its
+// limitations apply: no privileged containers with full capabilities, no
+// AppArmor, no binding of ports below 1024 in containers, and the overlay2
+// storage driver needs a recent kernel.
+const (
+	rootlessInstallURL = "https://get.docker.com/rootless"
+
+	// rootlessCertsDir holds the copies of the certs readable by the
+	// daemon, relative to the home of the SSH user.
+	rootlessCertsDir = ".config/docker/machine"
+
+	rootlessDropInPath = ".config/systemd/user/docker.service.d/10-machine.conf"
+
+	// rootlessEnv is the environment systemctl --user needs in an SSH
+	// session without a login manager.
+	rootlessEnv = "export XDG_RUNTIME_DIR=/run/user/$(id -u) PATH=$HOME/bin:$PATH"
+)
+
+var errRootlessUser = errors.New("rootless Docker needs an SSH user other than root")
+
+// checkRootlessSupport fails when rootless Docker can't run on the machine.
+func checkRootlessSupport(p Provisioner) error {
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return fmt.Errorf("rootless Docker is not supported on %s, it needs an OS running systemd", p.String())
+	}
+
+	if output, err := p.SSHCommand("id -u"); err != nil {
+		return err
+	} else if strings.TrimSpace(output) == "0" {
+		return errRootlessUser
+	}
+
+	if _, err := p.SSHCommand(`[ "$(cat /proc/sys/kernel/unprivileged_userns_clone 2>/dev/null || echo 1)" = 1 ] && [ "$(cat /proc/sys/user/max_user_namespaces 2>/dev/null || echo 1)" != 0 ]`); err != nil {
+		return errors.New("rootless Docker is not supported, the kernel of the machine doesn't allow unprivileged user namespaces")
+	}
+
+	return nil
+}
+
+// configureRootless replaces the system daemon with rootless Docker for the
+// SSH user, configured to serve the Docker port with the certs of the
+// machine.
+func configureRootless(p Provisioner, engineOptions engine.Options, authOptions auth.Options, dockerPort int) error {
+	if err := checkRootlessSupport(p); err != nil {
+		return err
+	}
+
+	log.Info("Setting up rootless Docker...")
+
+	if _, err := p.SSHCommand("command -v newuidmap && command -v newgidmap"); err != nil {
+		if err := p.Package("uidmap", pkgaction.Install); err != nil {
+			return fmt.Errorf("rootless Docker needs newuidmap and newgidmap: %s", err)
+		}
+	}
+
+	commands := []string{
+		// Give the user a range of subordinate ids for its user namespace.
+		`user=$(id -un); for f in /etc/subuid /etc/subgid; do grep -q "^$user:" $f 2>/dev/null || echo "$user:100000:65536" | sudo tee -a $f; done`,
+		"sudo systemctl disable --now docker.service docker.socket",
+		"sudo loginctl enable-linger $(id -un)",
+		fmt.Sprintf("%s; if ! command -v dockerd-rootless-setuptool.sh; then curl -fsSL %s | FORCE_ROOTLESS_INSTALL=1 sh; fi", rootlessEnv, rootlessInstallURL),
+		fmt.Sprintf("%s; dockerd-rootless-setuptool.sh install --force --skip-iptables", rootlessEnv),
+		fmt.Sprintf("mkdir -p ~/%[1]s && sudo install -m 0600 -o $(id -un) %[2]s %[3]s %[4]s ~/%[1]s/",
+			rootlessCertsDir, authOptions.CaCertRemotePath, authOptions.ServerCertRemotePath, authOptions.ServerKeyRemotePath),
+		`grep -q DOCKER_HOST= ~/.profile 2>/dev/null || echo "export DOCKER_HOST=unix:///run/user/$(id -u)/docker.sock" >> ~/.profile`,
+	}
+
+	for _, command := range commands {
+		if output, err := p.SSHCommand(command); err != nil {
+			return fmt.Errorf("error setting up rootless Docker: %s: %s", err, output)
+		}
+	}
+
+	binary, err := p.SSHCommand(fmt.Sprintf("%s; command -v dockerd-rootless.sh", rootlessEnv))
+	if err != nil {
+		return fmt.Errorf("error setting up rootless Docker: dockerd-rootless.sh not found: %s", err)
+	}
+
+	dropIn := rootlessDropIn(strings.TrimSpace(binary), engineOptions, authOptions, dockerPort)
+	if _, err := p.SSHCommand(fmt.Sprintf("mkdir -p ~/%s && printf '%%s' '%s' > ~/%s",
+		path.Dir(rootlessDropInPath), strings.ReplaceAll(dropIn, "'", `'\''`), rootlessDropInPath)); err != nil {
+		return err
+	}
+
+	return p.Service("docker", serviceaction.Restart)
+}
+
+// rootlessDropIn is the drop-in of the docker user unit making the daemon
+// serve the Docker port with TLS.
+func rootlessDropIn(binary string, engineOptions engine.Options, authOptions auth.Options, dockerPort int) string {
+	cert := func(remotePath string) string {
+		return "%h/" + rootlessCertsDir + "/" + path.Base(remotePath)
+	}
+
+	args := []string{
+		binary,
+		"-H unix://%t/docker.sock",
+		fmt.Sprintf("-H tcp://0.0.0.0:%d", dockerPort),
+		"--tlsverify",
+		"--tlscacert " + cert(authOptions.CaCertRemotePath),
+		"--tlscert " + cert(authOptions.ServerCertRemotePath),
+		"--tlskey " + cert(authOptions.ServerKeyRemotePath),
+	}
+	for _, label := range engineOptions.Labels {
+		args = append(args, "--label "+label)
+	}
+	for _, registry := range engineOptions.InsecureRegistry {
+		args = append(args, "--insecure-registry "+registry)
+	}
+	for _, mirror := range engineOptions.RegistryMirror {
+		args = append(args, "--registry-mirror "+mirror)
+	}
+	for _, flag := range engineOptions.ArbitraryFlags {
+		args = append(args, "--"+flag)
+	}
+
+	dropIn := "[Service]\n"
+	dropIn += "Environment=PATH=%h/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin\n"
+	dropIn += fmt.Sprintf("Environment=\"DOCKERD_ROOTLESS_ROOTLESSKIT_FLAGS=-p 0.0.0.0:%[1]d:%[1]d/tcp\"\n", dockerPort)
+	for _, env := range engineOptions.Env {
+		dropIn += fmt.Sprintf("Environment=%q\n", env)
+	}
+	dropIn += "ExecStart=\n"
+	dropIn += "ExecStart=" + strings.Join(args, " ") + "\n"
+
+	return dropIn
+}
+
+// rootlessService runs a systemctl action on the docker user unit. Stopping a
+// unit that is not installed yet is not an error.
+func rootlessService(p SSHCommander, action serviceaction.ServiceAction) error {
+	command := fmt.Sprintf("%s; systemctl --user daemon-reload && systemctl --user %s docker", rootlessEnv, action.String())
+	if action == serviceaction.Stop {
+		command = fmt.Sprintf("%s; if systemctl --user cat docker >/dev/null 2>&1; then systemctl --user stop docker; fi", rootlessEnv)
+	}
+
+	if _, err := p.SSHCommand(command); err != nil {
+		return err
+	}
+
+	return nil
+}
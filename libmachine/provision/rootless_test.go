@@ -0,0 +1,55 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/rancher/machine/drivers/fakedriver"
+	"github.com/rancher/machine/libmachine/auth"
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/rancher/machine/libmachine/provision/serviceaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootlessDropIn(t *testing.T) {
+	dropIn := rootlessDropIn("/usr/bin/dockerd-rootless.sh", engine.Options{
+		Labels:         []string{"env=ci"},
+		ArbitraryFlags: []string{"dns 10.0.0.2"},
+		Env:            []string{"HTTP_PROXY=http://proxy:3128"},
+	}, auth.Options{
+		CaCertRemotePath:     "/etc/docker/ca.pem",
+		ServerCertRemotePath: "/etc/docker/server.pem",
+		ServerKeyRemotePath:  "/etc/docker/server-key.pem",
+	}, 2376)
+
+	assert.Equal(t, `[Service]
+Environment=PATH=%h/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin
+Environment="DOCKERD_ROOTLESS_ROOTLESSKIT_FLAGS=-p 0.0.0.0:2376:2376/tcp"
+Environment="HTTP_PROXY=http://proxy:3128"
+ExecStart=
+ExecStart=/usr/bin/dockerd-rootless.sh -H unix://%t/docker.sock -H tcp://0.0.0.0:2376 --tlsverify --tlscacert %h/.config/docker/machine/ca.pem --tlscert %h/.config/docker/machine/server.pem --tlskey %h/.config/docker/machine/server-key.pem --label env=ci --dns 10.0.0.2
+`, dropIn)
+}
+
+func TestCheckRootlessSupport(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{"id -u": "0\n"},
+	}
+	assert.Equal(t, errRootlessUser, checkRootlessSupport(p))
+
+	assert.EqualError(t, checkRootlessSupport(&FakeProvisioner{}), "rootless Docker is not supported on fakeprovisioner, it needs an OS running systemd")
+}
+
+func TestRootlessService(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.EngineOptions.Rootless = true
+	p.SSHCommander = &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{
+			rootlessEnv + "; systemctl --user daemon-reload && systemctl --user restart docker": "",
+		},
+	}
+
+	assert.NoError(t, p.Service("docker", serviceaction.Restart))
+	assert.Error(t, p.Service("docker", serviceaction.Enable))
+}
@@ -0,0 +1,41 @@
+package provision
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnutils"
+)
+
+// configureSSHTransport lets the SSH user of the machine use the socket of the
+// daemon, which the docker client reaches with ssh:// instead of TLS.
+func configureSSHTransport(p Provisioner) error {
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return fmt.Errorf("the SSH transport is not supported on %s", p.String())
+	}
+
+	user := p.GetDriver().GetSSHUsername()
+	log.Infof("Giving %s access to the Docker daemon socket...", user)
+
+	if _, err := p.SSHCommand(fmt.Sprintf("sudo groupadd -f docker && sudo usermod -aG docker %s", user)); err != nil {
+		return fmt.Errorf("error adding %s to the docker group: %s", user, err)
+	}
+
+	return nil
+}
+
+// waitForDockerSocket waits for the daemon to answer on its socket, as it
+// doesn't listen on a TCP port with the SSH transport.
+func waitForDockerSocket(p Provisioner, _ int) error {
+	checkSocket := func() bool {
+		_, err := p.SSHCommand("sudo docker version")
+		return err == nil
+	}
+
+	if err := mcnutils.WaitForSpecific(checkSocket, 10, 3*time.Second); err != nil {
+		return NewErrDaemonAvailable(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package provision
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+const sysctlFile = "/etc/sysctl.d/99-machine.conf"
+
+var sysctlKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+(\.[a-zA-Z0-9_-]+)+$`)
+
+// ValidateSysctls checks that the sysctls are key=value with a dotted key,
+// e.g. vm.max_map_count=262144.
+func ValidateSysctls(sysctls []string) error {
+	for _, sysctl := range sysctls {
+		key, value, ok := strings.Cut(sysctl, "=")
+		if !ok {
+			return fmt.Errorf("sysctl %q is not in the form key=value", sysctl)
+		}
+		if !sysctlKeyRegexp.MatchString(key) {
+			return fmt.Errorf("sysctl key %q is invalid", key)
+		}
+		if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "'\n") {
+			return fmt.Errorf("value of sysctl %s is empty or contains a quote or a newline", key)
+		}
+	}
+
+	return nil
+}
+
+// configureSysctls writes the sysctls of the engine options to a file of
+// /etc/sysctl.d, so that they survive a reboot, and applies them.
+func configureSysctls(p Provisioner, engineOptions engine.Options) error {
+	if len(engineOptions.Sysctls) == 0 {
+		return nil
+	}
+
+	log.Info("Configuring the sysctls of the machine...")
+
+	cmd := fmt.Sprintf("printf '%%s' '%s' | sudo tee %s && sudo sysctl -p %s", sysctlConf(engineOptions.Sysctls), sysctlFile, sysctlFile)
+	if output, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error configuring the sysctls: %s: %s", err, output)
+	}
+
+	return nil
+}
+
+// sysctlConf is a sysctl.d file setting the sysctls.
+func sysctlConf(sysctls []string) string {
+	conf := ""
+	for _, sysctl := range sysctls {
+		key, value, _ := strings.Cut(sysctl, "=")
+		conf += fmt.Sprintf("%s = %s\n", key, value)
+	}
+
+	return conf
+}
@@ -0,0 +1,21 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSysctls(t *testing.T) {
+	assert.NoError(t, ValidateSysctls([]string{"vm.max_map_count=262144", "net.ipv4.tcp_rmem=4096 87380 6291456", "net.ipv4.conf.eth-0.forwarding=1"}))
+
+	assert.EqualError(t, ValidateSysctls([]string{"vm.max_map_count"}), `sysctl "vm.max_map_count" is not in the form key=value`)
+	assert.EqualError(t, ValidateSysctls([]string{"swappiness=10"}), `sysctl key "swappiness" is invalid`)
+	assert.EqualError(t, ValidateSysctls([]string{"vm.swappiness; reboot=10"}), `sysctl key "vm.swappiness; reboot" is invalid`)
+	assert.EqualError(t, ValidateSysctls([]string{"kernel.hostname='x'"}), "value of sysctl kernel.hostname is empty or contains a quote or a newline")
+	assert.EqualError(t, ValidateSysctls([]string{"vm.swappiness="}), "value of sysctl vm.swappiness is empty or contains a quote or a newline")
+}
+
+func TestSysctlConf(t *testing.T) {
+	assert.Equal(t, "vm.max_map_count = 262144\nnet.core.somaxconn = 1024\n", sysctlConf([]string{"vm.max_map_count=262144", "net.core.somaxconn=1024"}))
+}
@@ -51,10 +51,13 @@ func (p *SystemdProvisioner) GenerateDockerOptions(dockerPort int) (*DockerOptio
 		arg = "docker daemon"
 	}
 
-	engineConfigTmpl := `[Service]
+	engineConfigTmpl := `{{ if .EngineOptions.GraphDir }}[Unit]
+RequiresMountsFor={{.EngineOptions.GraphDir}}
+
+{{ end }}[Service]
 ExecStart=
-ExecStart=/usr/bin/` + arg + ` -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+ExecStart=/usr/bin/` + arg + `{{ if not .EngineOptions.SSHTransport }} -H tcp://0.0.0.0:{{.DockerPort}}{{ end }} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} {{ if .EngineOptions.MetricsAddr }}--metrics-addr {{.EngineOptions.MetricsAddr}} {{ end }}{{ if not .EngineOptions.SSHTransport }}--tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ end }}{{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+EnvironmentFile=-` + engineEnvFile + `
 `
 	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
 	if err != nil {
@@ -76,6 +79,10 @@ Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
 }
 
 func (p *SystemdProvisioner) Service(name string, action serviceaction.ServiceAction) error {
+	if name == "docker" && p.EngineOptions.Rootless {
+		return rootlessService(p, action)
+	}
+
 	reloadDaemon := false
 	switch action {
 	case serviceaction.Start, serviceaction.Restart:
@@ -0,0 +1,160 @@
+package provision
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/provision/serviceaction"
+)
+
+// systemdDropInPath is the drop-in holding the user's directives. It sorts
+// after 10-machine.conf so that it can override the generated settings.
+const systemdDropInPath = "/etc/systemd/system/docker.service.d/20-machine-custom.conf"
+
+var (
+	systemdSectionRE   = regexp.MustCompile(`^\[[A-Za-z][A-Za-z0-9 _-]*\]$`)
+	systemdDirectiveRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*=`)
+)
+
+// systemdManaged is implemented by the provisioners running docker as a
+// systemd unit.
+type systemdManaged interface {
+	usesSystemd() bool
+}
+
+func (p *SystemdProvisioner) usesSystemd() bool {
+	return true
+}
+
+// ValidateSystemdDirective checks a KEY=VALUE directive given on the command
+// line.
+func ValidateSystemdDirective(directive string) error {
+	if !systemdDirectiveRE.MatchString(directive) || strings.Contains(directive, "\n") {
+		return fmt.Errorf("%q is not a KEY=VALUE systemd directive", directive)
+	}
+
+	return nil
+}
+
+// ValidateSystemdDropIn checks the content of a drop-in is well-formed: only
+// comments, section headers and directives inside a section.
+func ValidateSystemdDropIn(content string) error {
+	inSection := false
+	continuation := false
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		wasContinuation := continuation
+		continuation = strings.HasSuffix(line, "\\")
+		if wasContinuation {
+			continue
+		}
+
+		switch {
+		case line == "", strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+			continuation = false
+		case systemdSectionRE.MatchString(line):
+			inSection = true
+		case systemdDirectiveRE.MatchString(line):
+			if !inSection {
+				return fmt.Errorf("line %d: directive outside of a section", i+1)
+			}
+		default:
+			return fmt.Errorf("line %d: expected a [Section] or a KEY=VALUE directive", i+1)
+		}
+	}
+
+	return nil
+}
+
+// systemdDropIn returns the content of the custom drop-in for the engine
+// options, or an empty string when no drop-in is configured.
+func systemdDropIn(engineOptions engine.Options) (string, error) {
+	content := ""
+
+	if engineOptions.SystemdDropInFile != "" {
+		file, err := os.ReadFile(engineOptions.SystemdDropInFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read file %s: %v", engineOptions.SystemdDropInFile, err)
+		}
+		content = strings.TrimRight(string(file), "\n") + "\n"
+	}
+
+	directives := append(append([]string{}, engineOptions.SystemdDropIns...), limitNOFILEDirectives(engineOptions)...)
+	if len(directives) > 0 {
+		if content != "" {
+			content += "\n"
+		}
+		content += "[Service]\n"
+		for _, directive := range directives {
+			if err := ValidateSystemdDirective(directive); err != nil {
+				return "", err
+			}
+			content += directive + "\n"
+		}
+	}
+
+	if err := ValidateSystemdDropIn(content); err != nil {
+		return "", fmt.Errorf("invalid systemd drop-in: %s", err)
+	}
+
+	return content, nil
+}
+
+// configureSystemdDropIn writes the custom drop-in of the docker unit, or
+// removes a previous one when none is configured anymore. It returns true if
+// a drop-in was written. The daemon is reloaded by the next restart.
+func configureSystemdDropIn(p Provisioner) (bool, error) {
+	ep, ok := p.(engineOptionsProvisioner)
+	if !ok {
+		return false, nil
+	}
+
+	content, err := systemdDropIn(ep.GetEngineOptions())
+	if err != nil {
+		return false, err
+	}
+
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		if content != "" {
+			log.Warnf("Ignoring the systemd drop-in, %s doesn't manage docker with systemd", p.String())
+		}
+		return false, nil
+	}
+
+	if content == "" {
+		if _, err := p.SSHCommand(fmt.Sprintf("sudo rm -f %s", systemdDropInPath)); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	log.Info("Setting the systemd drop-in of the docker unit...")
+
+	if _, err := p.SSHCommand(fmt.Sprintf("printf '%%s' '%s' | sudo tee %s", strings.ReplaceAll(content, "'", `'\''`), systemdDropInPath)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// rollbackSystemdDropIn removes the custom drop-in after the daemon failed to
+// restart with it, and restarts the daemon without it.
+func rollbackSystemdDropIn(p Provisioner, cause error) error {
+	log.Warnf("Docker failed to restart with the systemd drop-in, removing it...")
+
+	if _, err := p.SSHCommand(fmt.Sprintf("sudo rm -f %s", systemdDropInPath)); err != nil {
+		return fmt.Errorf("docker failed to restart with the systemd drop-in (%s) and the drop-in could not be removed: %s", cause, err)
+	}
+
+	if err := p.Service("docker", serviceaction.Restart); err != nil {
+		return fmt.Errorf("docker failed to restart with the systemd drop-in (%s) and again after removing it: %s", cause, err)
+	}
+
+	return fmt.Errorf("docker failed to restart with the systemd drop-in, it was removed: %s", cause)
+}
@@ -0,0 +1,51 @@
+package provision
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSystemdDropIn(t *testing.T) {
+	assert.NoError(t, ValidateSystemdDropIn(""))
+	assert.NoError(t, ValidateSystemdDropIn("# limits\n[Service]\nLimitNOFILE=1048576\nExecStartPost=/bin/sh -c \\\n  'echo ok'\n\n[Unit]\nAfter=network-online.target\n"))
+
+	assert.Error(t, ValidateSystemdDropIn("LimitNOFILE=1048576\n"))
+	assert.Error(t, ValidateSystemdDropIn("[Service\nLimitNOFILE=1048576\n"))
+	assert.Error(t, ValidateSystemdDropIn("[Service]\nLimitNOFILE\n"))
+}
+
+func TestValidateSystemdDirective(t *testing.T) {
+	assert.NoError(t, ValidateSystemdDirective("Restart=always"))
+	assert.Error(t, ValidateSystemdDirective("Restart"))
+	assert.Error(t, ValidateSystemdDirective("=always"))
+}
+
+func TestSystemdDropIn(t *testing.T) {
+	content, err := systemdDropIn(engine.Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+
+	file := filepath.Join(t.TempDir(), "limits.conf")
+	assert.NoError(t, os.WriteFile(file, []byte("[Unit]\nAfter=network-online.target\n"), 0644))
+
+	content, err = systemdDropIn(engine.Options{
+		SystemdDropInFile: file,
+		SystemdDropIns:    []string{"LimitNOFILE=1048576", "Restart=always"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "[Unit]\nAfter=network-online.target\n\n[Service]\nLimitNOFILE=1048576\nRestart=always\n", content)
+
+	content, err = systemdDropIn(engine.Options{
+		SystemdDropIns: []string{"Restart=always"},
+		LimitNOFILE:    "infinity",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "[Service]\nRestart=always\nLimitNOFILE=infinity\n", content)
+
+	_, err = systemdDropIn(engine.Options{SystemdDropIns: []string{"LimitNOFILE"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package provision
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+	"github.com/rancher/machine/libmachine/mcnutils"
+)
+
+const (
+	timesyncdDropInFile = "/etc/systemd/timesyncd.conf.d/99-machine-ntp.conf"
+	// maxClockSkew is the skew between the local clock and the clock of the
+	// machine above which a warning is shown. The certs are backdated by a
+	// few minutes only, a machine further behind refuses them.
+	maxClockSkew = time.Minute
+)
+
+var timeZonePattern = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+var (
+	clockSyncAttempts = 30
+	clockSyncInterval = 2 * time.Second
+)
+
+// ValidateTimeZone checks that timeZone is a name of the tz database, e.g.
+// Europe/Paris or UTC. Whether the machine knows it is checked when it is set.
+func ValidateTimeZone(timeZone string) error {
+	if timeZone != "" && !timeZonePattern.MatchString(timeZone) {
+		return fmt.Errorf("time zone %q is invalid, expected a name like Europe/Paris or UTC", timeZone)
+	}
+
+	return nil
+}
+
+// ValidateNTPServers checks that the NTP servers are IP addresses or host
+// names.
+func ValidateNTPServers(servers []string) error {
+	for _, server := range servers {
+		if net.ParseIP(server) != nil {
+			continue
+		}
+		if err := ValidateHostname(server); err != nil {
+			return fmt.Errorf("NTP server %q is not an IP address or a host name", server)
+		}
+	}
+
+	return nil
+}
+
+// configureTime sets the time zone of the machine and makes its NTP client
+// use the NTP servers of the engine options, waiting for the clock to be
+// synchronized with them.
+func configureTime(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.TimeZone == "" && len(engineOptions.NTPServers) == 0 {
+		return nil
+	}
+
+	if sp, ok := p.(systemdManaged); !ok || !sp.usesSystemd() {
+		return fmt.Errorf("setting the time zone and the NTP servers is not supported on %s", p.String())
+	}
+
+	if engineOptions.TimeZone != "" {
+		log.Infof("Setting the time zone to %s...", engineOptions.TimeZone)
+		if output, err := p.SSHCommand(fmt.Sprintf("sudo timedatectl set-timezone %s", engineOptions.TimeZone)); err != nil {
+			return fmt.Errorf("error setting the time zone to %s: %s: %s", engineOptions.TimeZone, err, output)
+		}
+	}
+
+	if len(engineOptions.NTPServers) == 0 {
+		return nil
+	}
+
+	if err := configureNTP(p, engineOptions.NTPServers); err != nil {
+		return err
+	}
+
+	return waitForClockSync(p, engineOptions.NTPServers)
+}
+
+// configureNTP makes chrony use the NTP servers when it runs on the machine,
+// and systemd-timesyncd otherwise.
+func configureNTP(p Provisioner, servers []string) error {
+	var cmd string
+	switch chrony := chronyService(p); {
+	case chrony != "":
+		log.Info("Configuring the NTP servers of chrony...")
+		cmd = fmt.Sprintf("conf=/etc/chrony/chrony.conf; [ -f $conf ] || conf=/etc/chrony.conf; sudo sed -i -E '/^(server|pool) /d' $conf && printf '%%s' '%s' | sudo tee -a $conf && sudo systemctl restart %s",
+			chronyConf(servers), chrony)
+	default:
+		log.Info("Configuring the NTP servers of systemd-timesyncd...")
+		cmd = fmt.Sprintf("sudo mkdir -p %s && printf '%%s' '%s' | sudo tee %s && sudo timedatectl set-ntp true && sudo systemctl restart systemd-timesyncd",
+			path.Dir(timesyncdDropInFile), timesyncdConf(servers), timesyncdDropInFile)
+	}
+
+	if output, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error configuring the NTP servers: %s: %s", err, output)
+	}
+
+	return nil
+}
+
+// chronyService returns the name of the chrony service running on the
+// machine, chronyd on Red Hat like distributions and chrony on Debian like
+// ones, or an empty string.
+func chronyService(p Provisioner) string {
+	for _, service := range []string{"chronyd", "chrony"} {
+		if serviceActive(p, service) {
+			return service
+		}
+	}
+
+	return ""
+}
+
+// chronyConf are the chrony.conf lines using the NTP servers.
+func chronyConf(servers []string) string {
+	conf := ""
+	for _, server := range servers {
+		conf += "server " + server + " iburst\n"
+	}
+
+	return conf
+}
+
+// timesyncdConf is a systemd-timesyncd drop-in setting the NTP servers.
+func timesyncdConf(servers []string) string {
+	return "[Time]\nNTP=" + strings.Join(servers, " ") + "\n"
+}
+
+func waitForClockSync(p Provisioner, servers []string) error {
+	log.Info("Waiting for the clock to be synchronized...")
+
+	// Older versions of timedatectl print "NTP synchronized" instead of
+	// "System clock synchronized".
+	synchronized := func() bool {
+		output, err := p.SSHCommand("timedatectl status")
+		return err == nil && strings.Contains(output, "synchronized: yes")
+	}
+
+	if err := mcnutils.WaitForSpecific(synchronized, clockSyncAttempts, clockSyncInterval); err != nil {
+		return fmt.Errorf("the clock of the machine isn't synchronized with %s after %s", strings.Join(servers, ", "), time.Duration(clockSyncAttempts)*clockSyncInterval)
+	}
+
+	return nil
+}
+
+// clockSkew returns how far the clock of the machine is ahead of the local
+// clock, to the second.
+func clockSkew(p SSHCommander) (time.Duration, error) {
+	output, err := p.SSHCommand("date +%s")
+	if err != nil {
+		return 0, err
+	}
+
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected date output %q", output)
+	}
+
+	return time.Unix(seconds, 0).Sub(time.Now()).Round(time.Second), nil
+}
+
+// checkClockSkew warns when the clock of the machine is too far from the
+// local clock, which frequently breaks TLS to the daemon.
+func checkClockSkew(p SSHCommander) {
+	skew, err := clockSkew(p)
+	if err != nil {
+		log.Debugf("Error reading the clock of the machine: %s", err)
+		return
+	}
+
+	switch {
+	case skew < -maxClockSkew:
+		log.Warnf("The clock of the machine is %s behind the local clock, TLS connections to the daemon may fail with \"x509: certificate is not yet valid\" until it is synchronized, see --provision-ntp-server", -skew)
+	case skew > maxClockSkew:
+		log.Warnf("The clock of the machine is %s ahead of the local clock, TLS connections to the daemon may fail until it is synchronized, see --provision-ntp-server", skew)
+	}
+}
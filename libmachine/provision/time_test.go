@@ -0,0 +1,62 @@
+package provision
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/provision/provisiontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTimeZone(t *testing.T) {
+	assert.NoError(t, ValidateTimeZone(""))
+	assert.NoError(t, ValidateTimeZone("UTC"))
+	assert.NoError(t, ValidateTimeZone("America/Argentina/Buenos_Aires"))
+	assert.NoError(t, ValidateTimeZone("Etc/GMT+3"))
+
+	assert.EqualError(t, ValidateTimeZone("Europe/Paris; reboot"), `time zone "Europe/Paris; reboot" is invalid, expected a name like Europe/Paris or UTC`)
+	assert.EqualError(t, ValidateTimeZone("/etc/localtime"), `time zone "/etc/localtime" is invalid, expected a name like Europe/Paris or UTC`)
+}
+
+func TestValidateNTPServers(t *testing.T) {
+	assert.NoError(t, ValidateNTPServers([]string{"10.0.0.123", "2001:db8::123", "ntp.example.com"}))
+	assert.EqualError(t, ValidateNTPServers([]string{"ntp.example.com", "ntp_1"}), `NTP server "ntp_1" is not an IP address or a host name`)
+}
+
+func TestNTPConfigs(t *testing.T) {
+	servers := []string{"10.0.0.123", "ntp.example.com"}
+
+	assert.Equal(t, "server 10.0.0.123 iburst\nserver ntp.example.com iburst\n", chronyConf(servers))
+	assert.Equal(t, "[Time]\nNTP=10.0.0.123 ntp.example.com\n", timesyncdConf(servers))
+}
+
+func TestConfigureTimeNotSupported(t *testing.T) {
+	err := configureTime(&FakeProvisioner{}, engine.Options{TimeZone: "UTC"})
+
+	assert.EqualError(t, err, "setting the time zone and the NTP servers is not supported on fakeprovisioner")
+	assert.NoError(t, configureTime(&FakeProvisioner{}, engine.Options{}))
+}
+
+func TestClockSkew(t *testing.T) {
+	behind := time.Now().Add(-10 * time.Minute).Unix()
+	sshCmder := &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{"date +%s": fmt.Sprintf("%d\n", behind)},
+	}
+
+	skew, err := clockSkew(sshCmder)
+
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(-10*time.Minute), float64(skew), float64(2*time.Second))
+}
+
+func TestClockSkewUnexpectedOutput(t *testing.T) {
+	sshCmder := &provisiontest.FakeSSHCommander{
+		Responses: map[string]string{"date +%s": "%s\n"},
+	}
+
+	_, err := clockSkew(sshCmder)
+
+	assert.EqualError(t, err, `unexpected date output "%s\n"`)
+}
@@ -0,0 +1,156 @@
+package provision
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/engine"
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// ulimitNames are the resources a ulimit of the daemon can be set for.
+var ulimitNames = map[string]bool{
+	"core":       true,
+	"cpu":        true,
+	"data":       true,
+	"fsize":      true,
+	"locks":      true,
+	"memlock":    true,
+	"msgqueue":   true,
+	"nice":       true,
+	"nofile":     true,
+	"nproc":      true,
+	"rss":        true,
+	"rtprio":     true,
+	"rttime":     true,
+	"sigpending": true,
+	"stack":      true,
+}
+
+// daemonUlimit is a ulimit as written in the default-ulimits option of
+// daemon.json.
+type daemonUlimit struct {
+	Name string
+	Hard int64
+	Soft int64
+}
+
+// parseUlimit parses a name=soft[:hard] ulimit, the hard limit being the soft
+// one when it is omitted. -1 is unlimited.
+func parseUlimit(ulimit string) (*daemonUlimit, error) {
+	name, limits, ok := strings.Cut(ulimit, "=")
+	if !ok {
+		return nil, fmt.Errorf("%q is not a name=soft[:hard] ulimit", ulimit)
+	}
+	if !ulimitNames[name] {
+		return nil, fmt.Errorf("invalid ulimit %q, the resource %q is unknown", ulimit, name)
+	}
+
+	softValue, hardValue, hasHard := strings.Cut(limits, ":")
+	soft, err := strconv.ParseInt(softValue, 10, 64)
+	if err != nil || soft < -1 {
+		return nil, fmt.Errorf("invalid soft limit of ulimit %q", ulimit)
+	}
+
+	hard := soft
+	if hasHard {
+		if hard, err = strconv.ParseInt(hardValue, 10, 64); err != nil || hard < -1 {
+			return nil, fmt.Errorf("invalid hard limit of ulimit %q", ulimit)
+		}
+	}
+
+	if hard != -1 && (soft == -1 || soft > hard) {
+		return nil, fmt.Errorf("invalid ulimit %q, the soft limit is above the hard limit", ulimit)
+	}
+
+	return &daemonUlimit{Name: name, Hard: hard, Soft: soft}, nil
+}
+
+// ValidateDefaultUlimits checks the values of --engine-default-ulimit, and
+// that the default ulimits aren't also set in the daemon flags, with
+// --engine-opt or the engine flag file, which the daemon refuses.
+func ValidateDefaultUlimits(ulimits, engineOpts []string) error {
+	if len(ulimits) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, ulimit := range ulimits {
+		u, err := parseUlimit(ulimit)
+		if err != nil {
+			return err
+		}
+		if seen[u.Name] {
+			return fmt.Errorf("the ulimit %s is set more than once", u.Name)
+		}
+		seen[u.Name] = true
+	}
+
+	for _, opt := range engineOpts {
+		if name, _, _ := strings.Cut(opt, "="); name == "default-ulimit" {
+			return fmt.Errorf("the default ulimits can't be set with both --engine-default-ulimit and the daemon flag --%s", opt)
+		}
+	}
+
+	return nil
+}
+
+// ValidateLimitNOFILE checks the value of --engine-limit-nofile, a number of
+// files or infinity.
+func ValidateLimitNOFILE(limit string) error {
+	if limit == "" || limit == "infinity" {
+		return nil
+	}
+
+	if n, err := strconv.ParseUint(limit, 10, 64); err != nil || n == 0 {
+		return fmt.Errorf("invalid LimitNOFILE %q, expected a number of files or infinity", limit)
+	}
+
+	return nil
+}
+
+// configureDefaultUlimits sets the default ulimits of the containers in
+// daemon.json.
+func configureDefaultUlimits(p Provisioner, engineOptions engine.Options) error {
+	if len(engineOptions.DefaultUlimits) == 0 {
+		return nil
+	}
+
+	log.Infof("Setting the default ulimits of the containers: %s...", strings.Join(engineOptions.DefaultUlimits, ", "))
+
+	ulimits := make([]*daemonUlimit, 0, len(engineOptions.DefaultUlimits))
+	for _, ulimit := range engineOptions.DefaultUlimits {
+		u, err := parseUlimit(ulimit)
+		if err != nil {
+			return err
+		}
+		ulimits = append(ulimits, u)
+	}
+
+	return updateDaemonConfig(p, daemonUlimits(ulimits))
+}
+
+// daemonUlimits returns the update of daemon.json setting the default
+// ulimits, over the ones it already has.
+func daemonUlimits(ulimits []*daemonUlimit) func(config map[string]interface{}) {
+	return func(config map[string]interface{}) {
+		defaults, _ := config["default-ulimits"].(map[string]interface{})
+		if defaults == nil {
+			defaults = map[string]interface{}{}
+		}
+		for _, u := range ulimits {
+			defaults[u.Name] = u
+		}
+		config["default-ulimits"] = defaults
+	}
+}
+
+// limitNOFILEDirectives returns the directives of the systemd drop-in setting
+// the LimitNOFILE of the daemon process.
+func limitNOFILEDirectives(engineOptions engine.Options) []string {
+	if engineOptions.LimitNOFILE == "" {
+		return nil
+	}
+	return []string{"LimitNOFILE=" + engineOptions.LimitNOFILE}
+}
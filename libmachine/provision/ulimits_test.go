@@ -0,0 +1,60 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUlimit(t *testing.T) {
+	u, err := parseUlimit("nofile=1024:65536")
+	assert.NoError(t, err)
+	assert.Equal(t, &daemonUlimit{Name: "nofile", Soft: 1024, Hard: 65536}, u)
+
+	u, err = parseUlimit("memlock=-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &daemonUlimit{Name: "memlock", Soft: -1, Hard: -1}, u)
+
+	u, err = parseUlimit("core=0:-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &daemonUlimit{Name: "core", Soft: 0, Hard: -1}, u)
+
+	for _, ulimit := range []string{"nofile", "files=1024", "nofile=many", "nofile=1024:x", "nofile=65536:1024", "nofile=-1:1024", "nofile=-2"} {
+		_, err := parseUlimit(ulimit)
+		assert.Error(t, err, ulimit)
+	}
+}
+
+func TestValidateDefaultUlimits(t *testing.T) {
+	assert.NoError(t, ValidateDefaultUlimits(nil, []string{"default-ulimit=nofile=1024"}))
+	assert.NoError(t, ValidateDefaultUlimits([]string{"nofile=65536", "nproc=4096:8192"}, []string{"log-level=debug"}))
+	assert.EqualError(t, ValidateDefaultUlimits([]string{"nofile=1024", "nofile=2048"}, nil), "the ulimit nofile is set more than once")
+	assert.EqualError(t, ValidateDefaultUlimits([]string{"nofile=1024"}, []string{"default-ulimit=nproc=10"}), "the default ulimits can't be set with both --engine-default-ulimit and the daemon flag --default-ulimit=nproc=10")
+}
+
+func TestValidateLimitNOFILE(t *testing.T) {
+	assert.NoError(t, ValidateLimitNOFILE(""))
+	assert.NoError(t, ValidateLimitNOFILE("infinity"))
+	assert.NoError(t, ValidateLimitNOFILE("1048576"))
+	assert.Error(t, ValidateLimitNOFILE("0"))
+	assert.Error(t, ValidateLimitNOFILE("-1"))
+	assert.Error(t, ValidateLimitNOFILE("unlimited"))
+}
+
+func TestDaemonUlimits(t *testing.T) {
+	nofile, err := parseUlimit("nofile=65536")
+	assert.NoError(t, err)
+
+	config, err := updatedDaemonConfig(`{"mtu": 1400, "default-ulimits": {"nproc": {"Name": "nproc", "Hard": 10, "Soft": 10}, "nofile": {"Name": "nofile", "Hard": 1, "Soft": 1}}}`, daemonUlimits([]*daemonUlimit{nofile}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"mtu": 1400,
+		"default-ulimits": {
+			"nproc": {"Name": "nproc", "Hard": 10, "Soft": 10},
+			"nofile": {"Name": "nofile", "Hard": 65536, "Soft": 65536}
+		}
+	}`, config)
+
+	_, err = updatedDaemonConfig("{", daemonUlimits([]*daemonUlimit{nofile}))
+	assert.Error(t, err)
+}
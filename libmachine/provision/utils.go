@@ -1,7 +1,13 @@
 package provision
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
@@ -13,6 +19,7 @@ import (
 
 	"github.com/rancher/machine/libmachine/auth"
 	"github.com/rancher/machine/libmachine/cert"
+	"github.com/rancher/machine/libmachine/drivers"
 	"github.com/rancher/machine/libmachine/engine"
 	"github.com/rancher/machine/libmachine/log"
 	"github.com/rancher/machine/libmachine/mcnutils"
@@ -24,21 +31,95 @@ type DockerOptions struct {
 	EngineOptionsPath string
 }
 
-func installDockerGeneric(p Provisioner, baseURL string) error {
-	if strings.EqualFold(baseURL, "none") {
+func installDockerGeneric(p Provisioner, engineOptions engine.Options) error {
+	// Fail before installing anything when rootless Docker can't be set up
+	// later on.
+	if engineOptions.Rootless {
+		if err := checkRootlessSupport(p); err != nil {
+			return err
+		}
+	}
+
+	baseURL := engineOptions.InstallURL
+	if engineOptions.InstallScriptFile == "" && strings.EqualFold(baseURL, "none") {
 		log.Info("Skipping Docker installation")
 		return nil
 	}
-	// install docker - until cloudinit we use ubuntu everywhere so we
-	// just install it using the docker repos
-	log.Infof("Installing Docker from: %s", baseURL)
-	if output, err := p.SSHCommand(fmt.Sprintf("if ! type docker; then curl -sSL %s | sh -; fi", baseURL)); err != nil {
+
+	if engineOptions.InstallScriptFile == "" && engineOptions.InstallURLSHA256 == "" {
+		// install docker - until cloudinit we use ubuntu everywhere so we
+		// just install it using the docker repos
+		log.Infof("Installing Docker from: %s", baseURL)
+		if output, err := p.SSHCommand(fmt.Sprintf("if ! type docker; then curl -sSL %s | sh -; fi", baseURL)); err != nil {
+			return fmt.Errorf("error installing Docker: %s", output)
+		}
+
+		return nil
+	}
+
+	script, err := getInstallScript(engineOptions)
+	if err != nil {
+		return err
+	}
+
+	if output, err := p.SSHCommand(fmt.Sprintf("cat <<'OEOF' >/tmp/docker_install_script.sh\n%s\nOEOF", string(script))); err != nil {
+		return fmt.Errorf("error uploading Docker install script: output: %s, error: %s", output, err)
+	}
+
+	if output, err := p.SSHCommand("if ! type docker; then sh /tmp/docker_install_script.sh; fi; rm -f /tmp/docker_install_script.sh"); err != nil {
 		return fmt.Errorf("error installing Docker: %s", output)
 	}
 
 	return nil
 }
 
+// getInstallScript returns the local install script if one is configured,
+// otherwise it downloads the script from the install URL and checks it
+// against the expected SHA-256.
+func getInstallScript(engineOptions engine.Options) ([]byte, error) {
+	if engineOptions.InstallScriptFile != "" {
+		log.Infof("Installing Docker with local script: %s", engineOptions.InstallScriptFile)
+		script, err := os.ReadFile(engineOptions.InstallScriptFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file %s: %v", engineOptions.InstallScriptFile, err)
+		}
+
+		return script, nil
+	}
+
+	log.Infof("Installing Docker from: %s (verifying SHA-256)", engineOptions.InstallURL)
+	resp, err := http.Get(engineOptions.InstallURL)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading Docker install script: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading Docker install script: %s returned %s", engineOptions.InstallURL, resp.Status)
+	}
+
+	script, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading Docker install script: %s", err)
+	}
+
+	if err := verifySHA256(script, engineOptions.InstallURLSHA256); err != nil {
+		return nil, fmt.Errorf("refusing to run Docker install script from %s: %s", engineOptions.InstallURL, err)
+	}
+
+	return script, nil
+}
+
+func verifySHA256(data []byte, expected string) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("SHA-256 mismatch, expected %s but got %s", expected, actual)
+	}
+
+	return nil
+}
+
 func makeDockerOptionsDir(p Provisioner) error {
 	dockerDir := p.GetDockerOptionsDir()
 	if _, err := p.SSHCommand(fmt.Sprintf("sudo mkdir -p %s", dockerDir)); err != nil {
@@ -61,29 +142,120 @@ func setRemoteAuthOptions(p Provisioner) auth.Options {
 	return authOptions
 }
 
-func ConfigureAuth(p Provisioner) error {
-	var (
-		err error
-	)
+// defaultDataRoot is the data root used by the daemon when none is configured.
+const defaultDataRoot = "/var/lib/docker"
 
-	driver := p.GetDriver()
-	machineName := driver.GetMachineName()
-	authOptions := p.GetAuthOptions()
-	swarmOptions := p.GetSwarmOptions()
-	org := mcnutils.GetUsername() + "." + machineName
-	bits := 2048
+// engineOptionsProvisioner is implemented by the provisioners embedding
+// GenericProvisioner, and by Boot2DockerProvisioner.
+type engineOptionsProvisioner interface {
+	GetEngineOptions() engine.Options
+}
+
+// preferIPv6 returns true if the daemon of the machine should be reached at
+// its IPv6 address.
+func preferIPv6(p Provisioner) bool {
+	ep, ok := p.(engineOptionsProvisioner)
+	return ok && ep.GetEngineOptions().PreferIPv6
+}
 
-	// preference: IPv4 address, then IPv6 address
-	ip, err := driver.GetIP()
+// enginePort returns the port the daemon listens on: the one of the engine
+// options when set, else the one of the URL of the driver.
+func enginePort(p Provisioner) (int, error) {
+	if ep, ok := p.(engineOptionsProvisioner); ok && ep.GetEngineOptions().Port != 0 {
+		return ep.GetEngineOptions().Port, nil
+	}
+
+	dockerURL, err := p.GetDriver().GetURL()
 	if err != nil {
-		log.Warnf("Error getting IPv4 address: %s", err)
-		log.Debug("Getting IPv6 address")
-		ip, err = driver.GetIPv6()
-		if err != nil {
-			return fmt.Errorf("error getting IPv6 address: %s", err)
+		return 0, err
+	}
+
+	u, err := url.Parse(dockerURL)
+	if err != nil {
+		return 0, err
+	}
+
+	if u.Port() == "" {
+		return engine.DefaultPort, nil
+	}
+
+	return strconv.Atoi(u.Port())
+}
+
+// serverCertHosts returns the SANs of the server cert of a machine: the
+// configured ones, the IPs of the machine and localhost.
+func serverCertHosts(sans []string, ips []string) []string {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, h := range append(append(append([]string{}, sans...), ips...), "localhost") {
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
 		}
 	}
 
+	return hosts
+}
+
+// relocateDataRoot creates the data root of the daemon and sets it in
+// daemon.json. If Docker was already installed and the new data root is still
+// empty, the existing data is moved from the data root configured so far. The
+// daemon must be stopped.
+func relocateDataRoot(p Provisioner, dataRoot string) error {
+	log.Infof("Preparing the Docker data root %s...", dataRoot)
+
+	output, err := p.SSHCommand(fmt.Sprintf("sudo cat %s 2>/dev/null || true", daemonConfigFile))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", daemonConfigFile, err)
+	}
+
+	current, err := configuredDataRoot(output)
+	if err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("sudo mkdir -p %s", dataRoot)
+	if current != dataRoot {
+		cmd = fmt.Sprintf(`if [ -d %[1]s ] && [ ! -L %[1]s ] && [ -n "$(sudo ls -A %[1]s)" ] && [ -z "$(sudo ls -A %[2]s 2>/dev/null)" ]; then sudo mkdir -p %[2]s && sudo cp -a %[1]s/. %[2]s/ && sudo rm -rf %[1]s; fi; sudo mkdir -p %[2]s`, current, dataRoot)
+	}
+	if _, err := p.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("error moving the Docker data to %s: %s", dataRoot, err)
+	}
+
+	return updateDaemonConfig(p, daemonDataRoot(dataRoot))
+}
+
+// configuredDataRoot returns the data root set in the daemon.json content,
+// with the data-root option or the older graph one, or the default one.
+func configuredDataRoot(content string) (string, error) {
+	config := map[string]interface{}{}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &config); err != nil {
+			return "", fmt.Errorf("invalid %s on the machine: %s", daemonConfigFile, err)
+		}
+	}
+
+	for _, name := range []string{"data-root", "graph"} {
+		if dataRoot, ok := config[name].(string); ok && dataRoot != "" {
+			return dataRoot, nil
+		}
+	}
+
+	return defaultDataRoot, nil
+}
+
+// daemonDataRoot returns the update of daemon.json setting the data root. The
+// graph option is removed, the daemon refusing both.
+func daemonDataRoot(dataRoot string) func(config map[string]interface{}) {
+	return func(config map[string]interface{}) {
+		delete(config, "graph")
+		config["data-root"] = dataRoot
+	}
+}
+
+// CopyClientCerts copies the CA and the client cert and key to the machine
+// directory, where env and the docker client look for them.
+func CopyClientCerts(authOptions auth.Options) error {
 	log.Info("Copying certs to the local machine directory...")
 
 	if err := mcnutils.CopyFile(authOptions.CaCertPath, filepath.Join(authOptions.StorePath, "ca.pem")); err != nil {
@@ -98,91 +270,138 @@ func ConfigureAuth(p Provisioner) error {
 		return fmt.Errorf("copying key.pem to machine dir failed: %s", err)
 	}
 
-	// The Host IP is always added to the certificate's SANs list
-	hosts := append(authOptions.ServerCertSANs, ip, "localhost")
-	log.Debugf("Generating server cert: %s ca-key=%s private-key=%s org=%s san=%s",
-		authOptions.ServerCertPath,
-		authOptions.CaCertPath,
-		authOptions.CaPrivateKeyPath,
-		org,
-		hosts,
-	)
-
-	// TODO: Switch to passing just authOptions to this func
-	// instead of all these individual fields
-	err = cert.GenerateCert(&cert.Options{
-		Hosts:       hosts,
-		CertFile:    authOptions.ServerCertPath,
-		KeyFile:     authOptions.ServerKeyPath,
-		CAFile:      authOptions.CaCertPath,
-		CAKeyFile:   authOptions.CaPrivateKeyPath,
-		Org:         org,
-		Bits:        bits,
-		SwarmMaster: swarmOptions.Master,
-	})
+	return nil
+}
 
-	if err != nil {
-		return fmt.Errorf("error generating server cert: %s", err)
+// configureHost applies the host settings of the engine options, the data
+// root, DNS, sysctls, time, registry CAs and NVIDIA runtime, when the machine
+// is provisioned. The daemon is restarted with them by ConfigureAuth, which
+// regenerate-certs runs again without touching the host.
+func configureHost(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.GraphDir != "" {
+		if err := p.Service("docker", serviceaction.Stop); err != nil {
+			return err
+		}
+		if err := relocateDataRoot(p, engineOptions.GraphDir); err != nil {
+			return err
+		}
 	}
 
-	if err := p.Service("docker", serviceaction.Stop); err != nil {
+	if err := configureDNS(p, engineOptions); err != nil {
 		return err
 	}
-
-	if _, err := p.SSHCommand(`if [ ! -z "$(ip link show docker0)" ]; then sudo ip link delete docker0; fi`); err != nil {
+	if err := configureSysctls(p, engineOptions); err != nil {
 		return err
 	}
-
-	// upload certs and configure TLS auth
-	caCert, err := os.ReadFile(authOptions.CaCertPath)
-	if err != nil {
+	if err := configureTime(p, engineOptions); err != nil {
 		return err
 	}
-
-	serverCert, err := os.ReadFile(authOptions.ServerCertPath)
-	if err != nil {
+	if err := configureRegistryCAs(p, engineOptions); err != nil {
 		return err
 	}
-	serverKey, err := os.ReadFile(authOptions.ServerKeyPath)
-	if err != nil {
+
+	return configureNvidiaRuntime(p, engineOptions)
+}
+
+// configureRunningEngine checks the NVIDIA runtime and sets up the network
+// plugin on the daemon restarted by ConfigureAuth, when the machine is
+// provisioned. The rootless daemons support neither.
+func configureRunningEngine(p Provisioner, engineOptions engine.Options) error {
+	if engineOptions.Rootless {
+		return nil
+	}
+
+	if err := checkNvidiaRuntime(p, engineOptions); err != nil {
 		return err
 	}
 
-	log.Info("Copying certs to the remote machine...")
+	return configureNetworkPlugin(p, engineOptions)
+}
 
-	// printf will choke if we don't pass a format string because of the
-	// dashes, so that's the reason for the '%%s'
-	certTransferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
+func ConfigureAuth(p Provisioner) error {
+	driver := p.GetDriver()
+	authOptions := p.GetAuthOptions()
 
-	// These ones are for Jessie and Mike <3 <3 <3
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(caCert), authOptions.CaCertRemotePath)); err != nil {
+	ips := drivers.MachineIPs(driver)
+	if len(ips) == 0 {
+		return errors.New("error getting the IP address of the machine: it has neither an IPv4 nor an IPv6 address")
+	}
+
+	// The daemon is reached at the private IP, which the driver doesn't
+	// report as the IP of the machine.
+	if ep, ok := p.(engineOptionsProvisioner); ok && ep.GetEngineOptions().DaemonUsePrivateIP {
+		ip, err := drivers.PrivateIP(driver)
+		if err != nil {
+			return err
+		}
+		ips = append(ips, ip)
+	}
+
+	sshTransport := false
+	if ep, ok := p.(engineOptionsProvisioner); ok && ep.GetEngineOptions().SSHTransport {
+		if err := configureSSHTransport(p); err != nil {
+			return err
+		}
+		sshTransport = true
+	} else if err := generateServerCert(p, ips); err != nil {
 		return err
 	}
 
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverCert), authOptions.ServerCertRemotePath)); err != nil {
+	if err := p.Service("docker", serviceaction.Stop); err != nil {
 		return err
 	}
 
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverKey), authOptions.ServerKeyRemotePath)); err != nil {
+	if _, err := p.SSHCommand(`if [ ! -z "$(ip link show docker0)" ]; then sudo ip link delete docker0; fi`); err != nil {
 		return err
 	}
 
-	dockerURL, err := driver.GetURL()
-	if err != nil {
+	// Before daemon.json is written, so that the settings also set by the
+	// flags of the engine flag file are left to them.
+	if err := configureEngineFlags(p); err != nil {
 		return err
 	}
-	u, err := url.Parse(dockerURL)
+
+	if ep, ok := p.(engineOptionsProvisioner); ok {
+		registerEngineEnvSecrets(ep.GetEngineOptions().Env)
+		if err := configureEngineEnv(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		if err := configureEngineMTU(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		if err := configureEngineDNS(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		if err := configureDefaultUlimits(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		if err := configureCgroupDriver(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		if err := configureContainerdConfig(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+	}
+
+	checkClockSkew(p)
+
+	if !sshTransport {
+		if err := copyServerCerts(p); err != nil {
+			return err
+		}
+	}
+
+	dockerPort, err := enginePort(p)
 	if err != nil {
 		return err
 	}
-	dockerPort := engine.DefaultPort
-	parts := strings.Split(u.Host, ":")
-	if len(parts) == 2 {
-		dPort, err := strconv.Atoi(parts[1])
-		if err != nil {
+
+	if ep, ok := p.(engineOptionsProvisioner); ok && ep.GetEngineOptions().Rootless {
+		if err := configureRootless(p, ep.GetEngineOptions(), authOptions, dockerPort); err != nil {
 			return err
 		}
-		dockerPort = dPort
+
+		return WaitForDocker(p, dockerPort)
 	}
 
 	dkrcfg, err := p.GenerateDockerOptions(dockerPort)
@@ -190,17 +409,53 @@ func ConfigureAuth(p Provisioner) error {
 		return err
 	}
 
+	log.Debugf("Docker daemon configuration:\n%s", dkrcfg.EngineOptions)
+
+	if previous, err := p.SSHCommand(fmt.Sprintf("sudo cat %s 2>/dev/null || true", dkrcfg.EngineOptionsPath)); err == nil && previous != "" {
+		if previous == dkrcfg.EngineOptions {
+			log.Info("The Docker daemon flags are unchanged, restarting it for the new certs...")
+		} else {
+			log.Info("The Docker daemon flags changed")
+		}
+	}
+
 	log.Info("Setting Docker configuration on the remote daemon...")
 
 	if _, err = p.SSHCommand(fmt.Sprintf("sudo mkdir -p %s && printf %%s \"%s\" | sudo tee %s", path.Dir(dkrcfg.EngineOptionsPath), dkrcfg.EngineOptions, dkrcfg.EngineOptionsPath)); err != nil {
 		return err
 	}
 
+	dropInWritten, err := configureSystemdDropIn(p)
+	if err != nil {
+		return err
+	}
+
 	if err := p.Service("docker", serviceaction.Restart); err != nil {
+		if dropInWritten {
+			return rollbackSystemdDropIn(p, err)
+		}
 		return err
 	}
 
-	return WaitForDocker(p, dockerPort)
+	waitForDocker := WaitForDocker
+	if sshTransport {
+		waitForDocker = waitForDockerSocket
+	}
+	if err := waitForDocker(p, dockerPort); err != nil {
+		if dropInWritten {
+			return rollbackSystemdDropIn(p, err)
+		}
+		return err
+	}
+
+	if ep, ok := p.(engineOptionsProvisioner); ok {
+		if err := checkEngineMTU(p, ep.GetEngineOptions()); err != nil {
+			return err
+		}
+		return checkCgroupDriver(p, ep.GetEngineOptions())
+	}
+
+	return nil
 }
 
 func matchNetstatOut(reDaemonListening, netstatOut string) bool {
@@ -262,6 +517,90 @@ func getFilesystemType(p Provisioner, directory string) (string, error) {
 	return fstype, nil
 }
 
+// generateServerCert generates the server cert of the daemon, valid for ips
+// and the SANs of the auth options, along with the client certs.
+func generateServerCert(p Provisioner, ips []string) error {
+	authOptions := p.GetAuthOptions()
+	swarmOptions := p.GetSwarmOptions()
+	org := mcnutils.GetUsername() + "." + p.GetDriver().GetMachineName()
+	bits := 2048
+
+	if err := CopyClientCerts(authOptions); err != nil {
+		return err
+	}
+
+	// The Host IPs are always added to the certificate's SANs list
+	hosts := serverCertHosts(authOptions.ServerCertSANs, ips)
+	log.Debugf("Generating server cert: %s ca-key=%s private-key=%s org=%s san=%s",
+		authOptions.ServerCertPath,
+		authOptions.CaCertPath,
+		authOptions.CaPrivateKeyPath,
+		org,
+		hosts,
+	)
+
+	// TODO: Switch to passing just authOptions to this func
+	// instead of all these individual fields
+	err := cert.GenerateCert(&cert.Options{
+		Hosts:       hosts,
+		CertFile:    authOptions.ServerCertPath,
+		KeyFile:     authOptions.ServerKeyPath,
+		CAFile:      authOptions.CaCertPath,
+		CAKeyFile:   authOptions.CaPrivateKeyPath,
+		Org:         org,
+		Bits:        bits,
+		SwarmMaster: swarmOptions.Master,
+		Duration:    authOptions.CertDuration,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error generating server cert: %s", err)
+	}
+
+	return nil
+}
+
+// copyServerCerts uploads the CA and the server cert of the daemon to the
+// machine.
+func copyServerCerts(p Provisioner) error {
+	authOptions := p.GetAuthOptions()
+
+	caCert, err := os.ReadFile(authOptions.CaCertPath)
+	if err != nil {
+		return err
+	}
+
+	serverCert, err := os.ReadFile(authOptions.ServerCertPath)
+	if err != nil {
+		return err
+	}
+	serverKey, err := os.ReadFile(authOptions.ServerKeyPath)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Copying certs to the remote machine...")
+
+	// printf will choke if we don't pass a format string because of the
+	// dashes, so that's the reason for the '%%s'
+	certTransferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
+
+	// These ones are for Jessie and Mike <3 <3 <3
+	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(caCert), authOptions.CaCertRemotePath)); err != nil {
+		return err
+	}
+
+	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverCert), authOptions.ServerCertRemotePath)); err != nil {
+		return err
+	}
+
+	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverKey), authOptions.ServerKeyRemotePath)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func checkDaemonUp(p Provisioner, dockerPort int) func() bool {
 	reDaemonListening := fmt.Sprintf(":%d\\s+.*:.*", dockerPort)
 	return func() bool {
@@ -2,6 +2,10 @@ package provision
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -12,6 +16,7 @@ import (
 	"github.com/rancher/machine/libmachine/provision/pkgaction"
 	"github.com/rancher/machine/libmachine/provision/provisiontest"
 	"github.com/rancher/machine/libmachine/provision/serviceaction"
+	"github.com/rancher/machine/libmachine/state"
 	"github.com/rancher/machine/libmachine/swarm"
 	"github.com/stretchr/testify/assert"
 )
@@ -101,6 +106,114 @@ func TestGenerateDockerOptionsBoot2Docker(t *testing.T) {
 	}
 }
 
+func TestGenerateDockerOptionsEngineSettings(t *testing.T) {
+	p := NewFedoraCoreOSProvisioner(&fakedriver.Driver{}).(*FedoraCoreOSProvisioner)
+	p.EngineOptions.GraphDir = "/mnt/docker"
+	p.EngineOptions.MetricsAddr = "0.0.0.0:9323"
+
+	dockerCfg, err := p.GenerateDockerOptions(engine.DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(dockerCfg.EngineOptions, "[Unit]\nRequiresMountsFor=/mnt/docker\n") {
+		t.Fatalf("expected a mount dependency on the data root; received %s", dockerCfg.EngineOptions)
+	}
+
+	if strings.Contains(dockerCfg.EngineOptions, "--data-root") {
+		t.Fatalf("expected the data root in daemon.json rather than a flag; received %s", dockerCfg.EngineOptions)
+	}
+
+	if !strings.Contains(dockerCfg.EngineOptions, "--metrics-addr 0.0.0.0:9323") {
+		t.Fatalf("expected --metrics-addr 0.0.0.0:9323; received %s", dockerCfg.EngineOptions)
+	}
+}
+
+func TestConfiguredDataRoot(t *testing.T) {
+	dataRoot, err := configuredDataRoot("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/lib/docker", dataRoot)
+
+	dataRoot, err = configuredDataRoot(`{"data-root": "/mnt/docker", "mtu": 1400}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/mnt/docker", dataRoot)
+
+	dataRoot, err = configuredDataRoot(`{"graph": "/srv/docker"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/srv/docker", dataRoot)
+
+	_, err = configuredDataRoot("{")
+	assert.Error(t, err)
+}
+
+func TestDaemonDataRoot(t *testing.T) {
+	config, err := updatedDaemonConfig(`{"graph": "/srv/docker", "mtu": 1400}`, daemonDataRoot("/mnt/docker"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data-root": "/mnt/docker", "mtu": 1400}`, config)
+}
+
+func TestGenerateDockerOptionsSSHTransport(t *testing.T) {
+	p := NewFedoraCoreOSProvisioner(&fakedriver.Driver{}).(*FedoraCoreOSProvisioner)
+	p.AuthOptions.CaCertRemotePath = "/test/ca-cert"
+	p.EngineOptions.SSHTransport = true
+
+	dockerCfg, err := p.GenerateDockerOptions(engine.DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flag := range []string{"--host=tcp://", "--tlsverify", "/test/ca-cert"} {
+		if strings.Contains(dockerCfg.EngineOptions, flag) {
+			t.Fatalf("expected no %s with the SSH transport; received %s", flag, dockerCfg.EngineOptions)
+		}
+	}
+
+	if !strings.Contains(dockerCfg.EngineOptions, "--host=fd://") {
+		t.Fatalf("expected the daemon to still listen on its socket; received %s", dockerCfg.EngineOptions)
+	}
+}
+
+func TestGenerateDockerOptionsSSHTransportGeneric(t *testing.T) {
+	p := NewUbuntuProvisioner(&fakedriver.Driver{}).(*UbuntuProvisioner)
+	p.AuthOptions.CaCertRemotePath = "/test/ca-cert"
+	p.EngineOptions.SSHTransport = true
+
+	dockerCfg, err := p.GenerateDockerOptions(engine.DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flag := range []string{"-H tcp://", "--tlsverify", "/test/ca-cert"} {
+		if strings.Contains(dockerCfg.EngineOptions, flag) {
+			t.Fatalf("expected no %s with the SSH transport; received %s", flag, dockerCfg.EngineOptions)
+		}
+	}
+
+	if !strings.Contains(dockerCfg.EngineOptions, "-H unix:///var/run/docker.sock") {
+		t.Fatalf("expected the daemon to still listen on its socket; received %s", dockerCfg.EngineOptions)
+	}
+}
+
+func TestGenerateDockerOptionsSSHTransportBoot2Docker(t *testing.T) {
+	p := &Boot2DockerProvisioner{
+		Driver: &fakedriver.Driver{},
+	}
+	p.EngineOptions.SSHTransport = true
+
+	dockerCfg, err := p.GenerateDockerOptions(engine.DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(dockerCfg.EngineOptions, "-H tcp://") {
+		t.Fatalf("expected no TCP listener with the SSH transport; received %s", dockerCfg.EngineOptions)
+	}
+
+	if !strings.Contains(dockerCfg.EngineOptions, "DOCKER_TLS=no") {
+		t.Fatalf("expected DOCKER_TLS=no with the SSH transport; received %s", dockerCfg.EngineOptions)
+	}
+}
+
 func TestMachinePortBoot2Docker(t *testing.T) {
 	p := &Boot2DockerProvisioner{
 		Driver: &fakedriver.Driver{},
@@ -282,3 +395,71 @@ func TestDockerClientVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestGetInstallScriptVerifiesSHA256(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "echo install")
+	}))
+	defer server.Close()
+
+	// sha256 of "echo install"
+	sum := "424a58dd8fea57fd984ec5d0614f5665a4c91d493221b5e8f5da0ab7950ea634"
+
+	_, err := getInstallScript(engine.Options{
+		InstallURL:       server.URL,
+		InstallURLSHA256: "0000",
+	})
+	assert.Error(t, err)
+
+	script, err := getInstallScript(engine.Options{
+		InstallURL:       server.URL,
+		InstallURLSHA256: sum,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "echo install", string(script))
+}
+
+func TestServerCertHosts(t *testing.T) {
+	assert.Equal(t, []string{"example.com", "192.0.2.10", "2001:db8::10", "localhost"},
+		serverCertHosts([]string{"example.com"}, []string{"192.0.2.10", "2001:db8::10"}))
+	assert.Equal(t, []string{"2001:db8::10", "localhost"},
+		serverCertHosts([]string{"2001:db8::10"}, []string{"2001:db8::10"}))
+}
+
+func TestCopyClientCerts(t *testing.T) {
+	certDir := t.TempDir()
+	storePath := t.TempDir()
+	for _, name := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(certDir, name), []byte(name), 0600))
+	}
+
+	err := CopyClientCerts(auth.Options{
+		CaCertPath:     filepath.Join(certDir, "ca.pem"),
+		ClientCertPath: filepath.Join(certDir, "cert.pem"),
+		ClientKeyPath:  filepath.Join(certDir, "key.pem"),
+		StorePath:      storePath,
+	})
+
+	assert.NoError(t, err)
+	for _, name := range []string{"ca.pem", "cert.pem", "key.pem"} {
+		content, err := os.ReadFile(filepath.Join(storePath, name))
+		assert.NoError(t, err)
+		assert.Equal(t, name, string(content))
+	}
+}
+
+func TestEnginePort(t *testing.T) {
+	p := NewUbuntuSystemdProvisioner(&fakedriver.Driver{MockState: state.Running, MockIP: "10.0.0.1"}).(*UbuntuSystemdProvisioner)
+
+	port, err := enginePort(p)
+	if err != nil || port != engine.DefaultPort {
+		t.Fatalf("Expected the port of the driver URL, got %d (%v)", port, err)
+	}
+
+	p.EngineOptions.Port = 12376
+
+	port, err = enginePort(p)
+	if err != nil || port != 12376 {
+		t.Fatalf("Expected the engine port, got %d (%v)", port, err)
+	}
+}
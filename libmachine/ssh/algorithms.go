@@ -0,0 +1,132 @@
+package ssh
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithms are the algorithms the SSH clients offer, in preference order.
+// An empty list keeps the default ones.
+type Algorithms struct {
+	Ciphers      []string
+	KeyExchanges []string
+	MACs         []string
+}
+
+// DefaultAlgorithms are the algorithms the native client offers when none
+// are configured. They leave out the ones with known weaknesses, e.g. CBC
+// ciphers, SHA-1 key exchanges and SHA-1 MACs. The external client keeps the
+// defaults of the installed OpenSSH, which are just as strict.
+var DefaultAlgorithms = Algorithms{
+	Ciphers: []string{
+		ssh.CipherChaCha20Poly1305,
+		ssh.CipherAES256GCM,
+		ssh.CipherAES128GCM,
+		ssh.CipherAES256CTR,
+		ssh.CipherAES192CTR,
+		ssh.CipherAES128CTR,
+	},
+	KeyExchanges: []string{
+		ssh.KeyExchangeMLKEM768X25519,
+		ssh.KeyExchangeCurve25519,
+		ssh.KeyExchangeECDHP256,
+		ssh.KeyExchangeECDHP384,
+		ssh.KeyExchangeECDHP521,
+		ssh.KeyExchangeDH16SHA512,
+		ssh.KeyExchangeDH14SHA256,
+	},
+	MACs: []string{
+		ssh.HMACSHA256ETM,
+		ssh.HMACSHA512ETM,
+		ssh.HMACSHA256,
+		ssh.HMACSHA512,
+	},
+}
+
+var algorithms Algorithms
+
+// ParseAlgorithmList returns the algorithms of a comma separated list, as
+// given to the --ssh-ciphers, --ssh-kex and --ssh-macs flags.
+func ParseAlgorithmList(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetAlgorithms restricts the algorithms offered by the clients created
+// afterwards, e.g. to the ones allowed on FIPS or hardened hosts. The names
+// must be known to the native client, using an insecure one only logs a
+// warning as some legacy hosts still require them.
+func SetAlgorithms(a Algorithms) error {
+	supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+
+	checks := []struct {
+		kind      string
+		names     []string
+		supported []string
+		insecure  []string
+	}{
+		{"cipher", a.Ciphers, supported.Ciphers, insecure.Ciphers},
+		{"key exchange", a.KeyExchanges, supported.KeyExchanges, insecure.KeyExchanges},
+		{"MAC", a.MACs, supported.MACs, insecure.MACs},
+	}
+
+	for _, check := range checks {
+		for _, name := range check.names {
+			switch {
+			case slices.Contains(check.supported, name):
+			case slices.Contains(check.insecure, name):
+				log.Warnf("The SSH %s %s is insecure", check.kind, name)
+			default:
+				return fmt.Errorf("unsupported SSH %s %q, the supported ones are %s", check.kind, name, strings.Join(check.supported, ", "))
+			}
+		}
+	}
+
+	algorithms = a
+	return nil
+}
+
+// AlgorithmArgs returns the -o options restricting the algorithms of the
+// OpenSSH clients, ssh, scp and sshfs, to the configured ones.
+func AlgorithmArgs() []string {
+	args := []string{}
+	if len(algorithms.Ciphers) > 0 {
+		args = append(args, "-o", "Ciphers="+strings.Join(algorithms.Ciphers, ","))
+	}
+	if len(algorithms.KeyExchanges) > 0 {
+		args = append(args, "-o", "KexAlgorithms="+strings.Join(algorithms.KeyExchanges, ","))
+	}
+	if len(algorithms.MACs) > 0 {
+		args = append(args, "-o", "MACs="+strings.Join(algorithms.MACs, ","))
+	}
+	return args
+}
+
+// nativeAlgorithms returns the algorithms offered by the native client, the
+// configured ones or the default ones.
+func nativeAlgorithms() ssh.Config {
+	config := ssh.Config{
+		Ciphers:      DefaultAlgorithms.Ciphers,
+		KeyExchanges: DefaultAlgorithms.KeyExchanges,
+		MACs:         DefaultAlgorithms.MACs,
+	}
+	if len(algorithms.Ciphers) > 0 {
+		config.Ciphers = algorithms.Ciphers
+	}
+	if len(algorithms.KeyExchanges) > 0 {
+		config.KeyExchanges = algorithms.KeyExchanges
+	}
+	if len(algorithms.MACs) > 0 {
+		config.MACs = algorithms.MACs
+	}
+	return config
+}
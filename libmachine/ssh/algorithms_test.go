@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseAlgorithmList(t *testing.T) {
+	assert.Equal(t, []string{"aes256-ctr", "aes128-ctr"}, ParseAlgorithmList(" aes256-ctr, aes128-ctr,"))
+	assert.Empty(t, ParseAlgorithmList(""))
+}
+
+func TestSetAlgorithmsUnsupported(t *testing.T) {
+	defer SetAlgorithms(Algorithms{})
+
+	err := SetAlgorithms(Algorithms{MACs: []string{"hmac-md5"}})
+
+	assert.ErrorContains(t, err, `unsupported SSH MAC "hmac-md5", the supported ones are `)
+	assert.Empty(t, AlgorithmArgs())
+}
+
+func TestAlgorithmArgs(t *testing.T) {
+	defer SetAlgorithms(Algorithms{})
+
+	assert.Empty(t, AlgorithmArgs())
+
+	assert.NoError(t, SetAlgorithms(Algorithms{
+		Ciphers:      []string{"aes256-gcm@openssh.com", "aes256-ctr"},
+		KeyExchanges: []string{"ecdh-sha2-nistp384"},
+		MACs:         []string{"hmac-sha2-512"},
+	}))
+
+	assert.Equal(t, []string{
+		"-o", "Ciphers=aes256-gcm@openssh.com,aes256-ctr",
+		"-o", "KexAlgorithms=ecdh-sha2-nistp384",
+		"-o", "MACs=hmac-sha2-512",
+	}, AlgorithmArgs())
+
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "127.0.0.1", 22, &Auth{})
+	assert.NoError(t, err)
+	assert.Contains(t, client.BaseArgs, "KexAlgorithms=ecdh-sha2-nistp384")
+}
+
+// restrictedAlgorithms are the only algorithms accepted by a hardened
+// server, none of which is the preferred one of the native client.
+var restrictedAlgorithms = ssh.Config{
+	Ciphers:      []string{ssh.CipherAES256CTR},
+	KeyExchanges: []string{ssh.KeyExchangeECDHP384},
+	MACs:         []string{ssh.HMACSHA512},
+}
+
+func TestNativeClientDefaultAlgorithmsRestrictedServer(t *testing.T) {
+	client := startTestServerWithAlgorithms(t, 0, restrictedAlgorithms)
+
+	output, err := client.Output("echo ok")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", output)
+}
+
+func TestNativeClientRestrictedServer(t *testing.T) {
+	defer SetAlgorithms(Algorithms{})
+
+	assert.NoError(t, SetAlgorithms(Algorithms{
+		Ciphers:      []string{ssh.CipherAES256CTR},
+		KeyExchanges: []string{ssh.KeyExchangeECDHP384},
+		MACs:         []string{ssh.HMACSHA512},
+	}))
+	client := startTestServerWithAlgorithms(t, 0, restrictedAlgorithms)
+
+	output, err := client.Output("echo ok")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", output)
+}
+
+func TestNativeClientRestrictedServerMismatch(t *testing.T) {
+	defer SetAlgorithms(Algorithms{})
+
+	assert.NoError(t, SetAlgorithms(Algorithms{Ciphers: []string{ssh.CipherAES128GCM}}))
+	client := startTestServerWithAlgorithms(t, 0, restrictedAlgorithms)
+
+	_, err := ssh.Dial("tcp", net.JoinHostPort(client.Hostname, strconv.Itoa(client.Port)), &client.Config)
+
+	assert.ErrorContains(t, err, "no common algorithm for client to server cipher")
+}
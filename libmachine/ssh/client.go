@@ -16,6 +16,7 @@ import (
 	"github.com/rancher/machine/libmachine/mcnutils"
 	"github.com/rancher/machine/libmachine/util"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -48,11 +49,21 @@ type NativeClient struct {
 	Port        int
 	openSession *ssh.Session
 	openClient  *ssh.Client
+	// Transfer tunes the data transfers, DefaultTransferOptions are used
+	// for its zero fields.
+	Transfer TransferOptions
+	// ForwardAgent forwards the local SSH agent to the sessions.
+	ForwardAgent bool
 }
 
 type Auth struct {
 	Passwords []string
 	Keys      []string
+	// ForwardAgent forwards the local SSH agent, found with SSH_AUTH_SOCK,
+	// to the sessions of the client. It is off by default as anyone with root
+	// access to the machine can then use the agent to authenticate as the
+	// local user while a session is open.
+	ForwardAgent bool
 }
 
 type ClientType string
@@ -95,6 +106,26 @@ func SetDefaultClient(clientType ClientType) {
 	}
 }
 
+// forwardAgent forwards the local SSH agent to the session when agent
+// forwarding is enabled.
+func (client *NativeClient) forwardAgent(conn *ssh.Client, session *ssh.Session) error {
+	if !client.ForwardAgent {
+		return nil
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		log.Warn("Not forwarding the SSH agent, SSH_AUTH_SOCK is not set")
+		return nil
+	}
+
+	if err := agent.ForwardToRemote(conn, socket); err != nil {
+		return fmt.Errorf("error forwarding the SSH agent: %s", err)
+	}
+
+	return agent.RequestAgentForwarding(session)
+}
+
 func NewClient(user string, host string, port int, auth *Auth) (Client, error) {
 	sshBinaryPath, err := exec.LookPath("ssh")
 	if err != nil {
@@ -125,9 +156,10 @@ func NewNativeClient(user, host string, port int, auth *Auth) (Client, error) {
 	}
 
 	return &NativeClient{
-		Config:   config,
-		Hostname: host,
-		Port:     port,
+		Config:       config,
+		Hostname:     host,
+		Port:         port,
+		ForwardAgent: auth.ForwardAgent,
 	}, nil
 }
 
@@ -155,6 +187,7 @@ func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 	}
 
 	return ssh.ClientConfig{
+		Config:          nativeAlgorithms(),
 		User:            user,
 		Auth:            authMethods,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
@@ -171,18 +204,30 @@ func (client *NativeClient) dialSuccess() bool {
 	return true
 }
 
-func (client *NativeClient) session(command string) (*ssh.Client, *ssh.Session, error) {
+func (client *NativeClient) dial() (*ssh.Client, error) {
 	if err := mcnutils.WaitFor(client.dialSuccess); err != nil {
-		return nil, nil, fmt.Errorf("error attempting SSH client dial: %s", err)
+		return nil, fmt.Errorf("error attempting SSH client dial: %s", err)
 	}
 
 	conn, err := ssh.Dial("tcp", net.JoinHostPort(client.Hostname, strconv.Itoa(client.Port)), &client.Config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("mysterious error dialing TCP for SSH (we already succeeded at least once) : %s", err)
+		return nil, fmt.Errorf("mysterious error dialing TCP for SSH (we already succeeded at least once) : %s", err)
+	}
+
+	return conn, nil
+}
+
+func (client *NativeClient) session(command string) (*ssh.Client, *ssh.Session, error) {
+	conn, err := client.dial()
+	if err != nil {
+		return nil, nil, err
 	}
 	session, err := conn.NewSession()
+	if err != nil {
+		return conn, nil, err
+	}
 
-	return conn, session, err
+	return conn, session, client.forwardAgent(conn, session)
 }
 
 func (client *NativeClient) Output(command string) (string, error) {
@@ -271,6 +316,20 @@ func (client *NativeClient) Wait() error {
 	return nil
 }
 
+// Stream runs a command with stdin and stdout connected to the given reader
+// and writer, either can be nil. The standard error of the command is
+// returned in the error when it fails.
+func (client *NativeClient) Stream(command string, stdin io.Reader, stdout io.Writer) error {
+	conn, session, err := client.session(command)
+	if err != nil {
+		return err
+	}
+	defer closeConn(conn)
+	defer session.Close()
+
+	return runStream(session, command, stdin, stdout, client.transferOptions().BufferSize)
+}
+
 func (client *NativeClient) Shell(args ...string) error {
 	var (
 		termWidth, termHeight int
@@ -288,6 +347,10 @@ func (client *NativeClient) Shell(args ...string) error {
 
 	defer session.Close()
 
+	if err := client.forwardAgent(conn, session); err != nil {
+		return err
+	}
+
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
 	session.Stdin = os.Stdin
@@ -403,6 +466,13 @@ func NewExternalClient(sshBinaryPath, user, host string, port int, auth *Auth) (
 		}
 	}
 
+	// Restrict the algorithms offered to the configured ones.
+	args = append(args, AlgorithmArgs()...)
+
+	if auth.ForwardAgent {
+		args = append(args, "-A")
+	}
+
 	// Set which port to use for SSH.
 	args = append(args, "-p", fmt.Sprintf("%d", port))
 
@@ -103,3 +103,26 @@ func TestNewExternalClient(t *testing.T) {
 		}
 	}
 }
+
+func TestExternalClientAgentForwarding(t *testing.T) {
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "127.0.0.1", 22, &Auth{ForwardAgent: true})
+
+	assert.NoError(t, err)
+	assert.Contains(t, client.BaseArgs, "-A")
+
+	client, err = NewExternalClient("/usr/bin/ssh", "docker", "127.0.0.1", 22, &Auth{})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, client.BaseArgs, "-A")
+}
+
+func TestNativeClientAgentForwardingWithoutAgent(t *testing.T) {
+	client := startTestServer(t, 0)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	client.ForwardAgent = true
+
+	output, err := client.Output("echo ok")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", output)
+}
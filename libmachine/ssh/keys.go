@@ -1,6 +1,7 @@
 package ssh
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
@@ -122,3 +123,74 @@ func GenerateSSHKey(path string) error {
 
 	return nil
 }
+
+// ImportSSHKey copies an existing private key, which must be unencrypted, to
+// the path where GenerateSSHKey would generate a new keypair, so that it is
+// used instead. The public key is copied from the ".pub" file next to the
+// private key, or derived from it when there is none.
+func ImportSSHKey(src, path string) error {
+	privateKey, publicKey, err := readKeyPair(src)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		File  string
+		Value []byte
+	}{
+		{File: path, Value: privateKey},
+		{File: fmt.Sprintf("%s.pub", path), Value: publicKey},
+	}
+	for _, v := range files {
+		if err := os.WriteFile(v.File, v.Value, 0600); err != nil {
+			return fmt.Errorf("Error writing keys to file(s): %s", err)
+		}
+
+		// windows does not support chmod, which also fixes the mode of
+		// existing files
+		switch runtime.GOOS {
+		case "darwin", "freebsd", "linux", "openbsd":
+			if err := os.Chmod(v.File, 0600); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateSSHKey checks that a private key can be imported by ImportSSHKey.
+func ValidateSSHKey(path string) error {
+	_, _, err := readKeyPair(path)
+	return err
+}
+
+func readKeyPair(path string) ([]byte, []byte, error) {
+	privateKey, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := gossh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is not a usable SSH private key: %s", path, err)
+	}
+
+	pub, err := os.ReadFile(path + ".pub")
+	if os.IsNotExist(err) {
+		return privateKey, gossh.MarshalAuthorizedKey(signer.PublicKey()), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, _, _, _, err := gossh.ParseAuthorizedKey(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s.pub is not a valid SSH public key: %s", path, err)
+	}
+	if !bytes.Equal(publicKey.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, nil, fmt.Errorf("%s.pub is not the public key of %s", path, path)
+	}
+
+	return privateKey, pub, nil
+}
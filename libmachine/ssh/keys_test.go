@@ -2,7 +2,12 @@ package ssh
 
 import (
 	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewKeyPair(t *testing.T) {
@@ -19,3 +24,48 @@ func TestNewKeyPair(t *testing.T) {
 		t.Fatal("Unable to generate fingerprint")
 	}
 }
+
+func TestImportSSHKey(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fleet")
+	assert.NoError(t, GenerateSSHKey(src))
+	pub, err := os.ReadFile(src + ".pub")
+	assert.NoError(t, err)
+
+	dst := filepath.Join(dir, "id_rsa")
+	assert.NoError(t, ImportSSHKey(src, dst))
+
+	imported, err := os.ReadFile(dst + ".pub")
+	assert.NoError(t, err)
+	assert.Equal(t, pub, imported)
+	if runtime.GOOS == "linux" {
+		info, err := os.Stat(dst)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// Without the public key, it is derived from the private key.
+	assert.NoError(t, os.Remove(src+".pub"))
+	assert.NoError(t, os.Remove(dst+".pub"))
+	assert.NoError(t, ImportSSHKey(src, dst))
+	imported, err = os.ReadFile(dst + ".pub")
+	assert.NoError(t, err)
+	assert.Equal(t, pub, imported)
+}
+
+func TestValidateSSHKey(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "fleet")
+	assert.NoError(t, GenerateSSHKey(src))
+	assert.NoError(t, ValidateSSHKey(src))
+
+	other := filepath.Join(dir, "other")
+	assert.NoError(t, GenerateSSHKey(other))
+	assert.NoError(t, os.Rename(other+".pub", src+".pub"))
+	assert.EqualError(t, ValidateSSHKey(src), src+".pub is not the public key of "+src)
+
+	assert.NoError(t, os.WriteFile(other, []byte("not a key"), 0600))
+	assert.Error(t, ValidateSSHKey(other))
+
+	assert.Error(t, ValidateSSHKey(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,229 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// transferBlockSize is the block size of the dd commands copying the chunks
+// of a file, the chunks are aligned on it.
+const transferBlockSize = 1 << 20
+
+// TransferOptions tunes the data transfers of the native client.
+type TransferOptions struct {
+	// BufferSize is the size of the buffers copying the data to and from a
+	// channel.
+	BufferSize int
+	// Streams is the number of channels of a single connection a file is
+	// transferred over concurrently.
+	Streams int
+	// ChunkSize is the size of the ranges of a file copied over a channel,
+	// rounded up to a multiple of 1MiB.
+	ChunkSize int64
+}
+
+// DefaultTransferOptions are the transfer options used for the zero fields
+// of NativeClient.Transfer. BenchmarkTransfer compares them to the 32KiB
+// buffers and the single channel used by io.Copy.
+var DefaultTransferOptions = TransferOptions{
+	BufferSize: 256 << 10,
+	Streams:    4,
+	ChunkSize:  16 << 20,
+}
+
+func (client *NativeClient) transferOptions() TransferOptions {
+	options := client.Transfer
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultTransferOptions.BufferSize
+	}
+	if options.Streams <= 0 {
+		options.Streams = DefaultTransferOptions.Streams
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = DefaultTransferOptions.ChunkSize
+	}
+	options.ChunkSize = (options.ChunkSize + transferBlockSize - 1) / transferBlockSize * transferBlockSize
+
+	return options
+}
+
+// runStream runs a command on the session with its stdin and stdout copied
+// from and to the given reader and writer through buffers of bufferSize.
+func runStream(session *ssh.Session, command string, stdin io.Reader, stdout io.Writer, bufferSize int) error {
+	var stderr strings.Builder
+	session.Stderr = &stderr
+
+	var (
+		in  io.WriteCloser
+		out io.Reader
+		err error
+	)
+	if stdin != nil {
+		if in, err = session.StdinPipe(); err != nil {
+			return err
+		}
+	}
+	if stdout != nil {
+		if out, err = session.StdoutPipe(); err != nil {
+			return err
+		}
+	}
+
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	// The readers and writers are wrapped so that io.CopyBuffer uses the
+	// buffer instead of their ReadFrom or WriteTo methods.
+	copies := make(chan error, 2)
+	pending := 0
+	if in != nil {
+		pending++
+		go func() {
+			_, err := io.CopyBuffer(in, struct{ io.Reader }{stdin}, make([]byte, bufferSize))
+			in.Close()
+			copies <- err
+		}()
+	}
+	if out != nil {
+		pending++
+		go func() {
+			_, err := io.CopyBuffer(struct{ io.Writer }{stdout}, out, make([]byte, bufferSize))
+			copies <- err
+		}()
+	}
+
+	var copyErr error
+	for ; pending > 0; pending-- {
+		if err := <-copies; err != nil && copyErr == nil {
+			copyErr = err
+		}
+	}
+
+	if err := session.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", err, msg)
+		}
+		return err
+	}
+
+	return copyErr
+}
+
+// Upload copies the size bytes of src to remotePath on the machine, the
+// chunks of the file being copied concurrently over several channels.
+func (client *NativeClient) Upload(src io.ReaderAt, size int64, remotePath string) error {
+	options := client.transferOptions()
+	quoted := shellQuote(remotePath)
+
+	return client.transfer(fmt.Sprintf(": > %s", quoted), size, options, func(session *ssh.Session, offset, length int64) error {
+		command := fmt.Sprintf("dd of=%s bs=%d seek=%d conv=notrunc", quoted, transferBlockSize, offset/transferBlockSize)
+		return runStream(session, command, io.NewSectionReader(src, offset, length), nil, options.BufferSize)
+	})
+}
+
+// Download copies the size bytes of remotePath on the machine to dst, the
+// chunks of the file being copied concurrently over several channels.
+func (client *NativeClient) Download(remotePath string, dst io.WriterAt, size int64) error {
+	options := client.transferOptions()
+	quoted := shellQuote(remotePath)
+
+	return client.transfer("", size, options, func(session *ssh.Session, offset, length int64) error {
+		command := fmt.Sprintf("dd if=%s bs=%d skip=%d count=%d", quoted, transferBlockSize, offset/transferBlockSize, (length+transferBlockSize-1)/transferBlockSize)
+		return runStream(session, command, nil, io.NewOffsetWriter(dst, offset), options.BufferSize)
+	})
+}
+
+// transfer runs prepare, if set, then copyChunk for each chunk of a file of
+// size bytes, on up to options.Streams sessions of a single connection at a
+// time. Each session has its own channel window, so that more data is in
+// flight on links with a high latency.
+func (client *NativeClient) transfer(prepare string, size int64, options TransferOptions, copyChunk func(session *ssh.Session, offset, length int64) error) error {
+	conn, err := client.dial()
+	if err != nil {
+		return err
+	}
+	defer closeConn(conn)
+
+	if prepare != "" {
+		session, err := conn.NewSession()
+		if err != nil {
+			return err
+		}
+		err = runStream(session, prepare, nil, nil, options.BufferSize)
+		session.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	streams := options.Streams
+	if chunks := int((size + options.ChunkSize - 1) / options.ChunkSize); chunks < streams {
+		streams = chunks
+	}
+
+	offsets := make(chan int64)
+	go func() {
+		defer close(offsets)
+		for offset := int64(0); offset < size; offset += options.ChunkSize {
+			offsets <- offset
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for offset := range offsets {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+
+				length := options.ChunkSize
+				if offset+length > size {
+					length = size - offset
+				}
+
+				err := func() error {
+					session, err := conn.NewSession()
+					if err != nil {
+						return err
+					}
+					defer session.Close()
+					return copyChunk(session, offset, length)
+				}()
+				if err != nil {
+					fail(fmt.Errorf("error copying bytes %d to %d: %s", offset, offset+length, err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
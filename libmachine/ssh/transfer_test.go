@@ -0,0 +1,262 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+const testPassword = "machine"
+
+// startTestServer starts a loopback SSH server running the exec requests
+// with sh, and returns a native client connected to it. The data sent by the
+// server is delayed by latency.
+func startTestServer(tb testing.TB, latency time.Duration) *NativeClient {
+	return startTestServerWithAlgorithms(tb, latency, ssh.Config{})
+}
+
+// startTestServerWithAlgorithms starts a test server only accepting the
+// given algorithms, the default ones for its empty fields.
+func startTestServerWithAlgorithms(tb testing.TB, latency time.Duration, algorithms ssh.Config) *NativeClient {
+	if runtime.GOOS == "windows" {
+		tb.Skip("the test server runs commands with sh")
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		Config: algorithms,
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != testPassword {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if latency > 0 {
+				conn = newDelayedConn(conn, latency)
+			}
+			go serveTestConn(conn, config)
+		}
+	}()
+
+	client, err := NewNativeClient("docker", "127.0.0.1", listener.Addr().(*net.TCPAddr).Port, &Auth{Passwords: []string{testPassword}})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return client.(*NativeClient)
+}
+
+// delayedConn delays the data written to a connection without limiting its
+// throughput, like a link with a high latency.
+type delayedConn struct {
+	net.Conn
+	latency time.Duration
+	writes  chan delayedWrite
+}
+
+type delayedWrite struct {
+	data []byte
+	due  time.Time
+}
+
+func newDelayedConn(conn net.Conn, latency time.Duration) net.Conn {
+	c := &delayedConn{Conn: conn, latency: latency, writes: make(chan delayedWrite, 4096)}
+	go func() {
+		for w := range c.writes {
+			time.Sleep(time.Until(w.due))
+			if _, err := c.Conn.Write(w.data); err != nil {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *delayedConn) Write(p []byte) (int, error) {
+	c.writes <- delayedWrite{data: append([]byte{}, p...), due: time.Now().Add(c.latency)}
+	return len(p), nil
+}
+
+func serveTestConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+
+				command := string(req.Payload[4:])
+				cmd := exec.Command("sh", "-c", command)
+				cmd.Stdin = channel
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+
+				status := uint32(0)
+				if err := cmd.Run(); err != nil {
+					status = 1
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						status = uint32(exitErr.ExitCode())
+					}
+				}
+
+				payload := make([]byte, 4)
+				binary.BigEndian.PutUint32(payload, status)
+				channel.SendRequest("exit-status", false, payload)
+				channel.Close()
+				return
+			}
+		}()
+	}
+}
+
+func randomData(tb testing.TB, size int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestUploadDownload(t *testing.T) {
+	client := startTestServer(t, 0)
+	client.Transfer = TransferOptions{BufferSize: 4096, Streams: 3, ChunkSize: transferBlockSize}
+
+	dir := t.TempDir()
+	remotePath := filepath.Join(dir, "remote file")
+	data := randomData(t, 5*transferBlockSize+1234)
+
+	assert.NoError(t, client.Upload(bytes.NewReader(data), int64(len(data)), remotePath))
+
+	uploaded, err := os.ReadFile(remotePath)
+	assert.NoError(t, err)
+	assert.Equal(t, data, uploaded)
+
+	local, err := os.Create(filepath.Join(dir, "local"))
+	assert.NoError(t, err)
+	defer local.Close()
+
+	assert.NoError(t, client.Download(remotePath, local, int64(len(data))))
+
+	downloaded, err := os.ReadFile(local.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, data, downloaded)
+}
+
+func TestUploadTruncates(t *testing.T) {
+	client := startTestServer(t, 0)
+
+	remotePath := filepath.Join(t.TempDir(), "remote")
+	assert.NoError(t, os.WriteFile(remotePath, randomData(t, 2*transferBlockSize), 0600))
+
+	data := randomData(t, 1000)
+	assert.NoError(t, client.Upload(bytes.NewReader(data), int64(len(data)), remotePath))
+
+	uploaded, err := os.ReadFile(remotePath)
+	assert.NoError(t, err)
+	assert.Equal(t, data, uploaded)
+}
+
+func TestDownloadError(t *testing.T) {
+	client := startTestServer(t, 0)
+	dir := t.TempDir()
+
+	local, err := os.Create(filepath.Join(dir, "local"))
+	assert.NoError(t, err)
+	defer local.Close()
+
+	err = client.Download(filepath.Join(dir, "missing"), local, 10)
+
+	assert.Error(t, err)
+}
+
+func TestStream(t *testing.T) {
+	client := startTestServer(t, 0)
+
+	var out bytes.Buffer
+	assert.NoError(t, client.Stream("cat", bytes.NewReader([]byte("hello")), &out))
+	assert.Equal(t, "hello", out.String())
+
+	err := client.Stream("echo oops >&2; exit 3", nil, nil)
+	assert.EqualError(t, err, "Process exited with status 3: oops")
+}
+
+// BenchmarkTransfer uploads a file to a loopback server with a 20ms latency,
+// with the default transfer options and with the 32KiB buffers and the single
+// channel of io.Copy as a baseline.
+func BenchmarkTransfer(b *testing.B) {
+	client := startTestServer(b, 20*time.Millisecond)
+	data := randomData(b, 64*transferBlockSize)
+	remotePath := filepath.Join(b.TempDir(), "remote")
+
+	for _, bench := range []struct {
+		name    string
+		options TransferOptions
+	}{
+		{"baseline", TransferOptions{BufferSize: 32 << 10, Streams: 1, ChunkSize: int64(len(data))}},
+		{"default", DefaultTransferOptions},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			client.Transfer = bench.options
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := client.Upload(bytes.NewReader(data), int64(len(data)), remotePath); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
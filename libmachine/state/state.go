@@ -14,6 +14,7 @@ const (
 	Error
 	Timeout
 	NotFound
+	Preempted
 )
 
 var states = []string{
@@ -27,6 +28,7 @@ var states = []string{
 	"Error",
 	"Timeout",
 	"Not Found",
+	"Preempted",
 }
 
 // Given a State type, returns its string representation